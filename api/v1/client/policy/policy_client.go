@@ -170,6 +170,34 @@ func (a *Client) GetPolicyResolve(params *GetPolicyResolveParams) (*GetPolicyRes
 
 }
 
+/*
+PostPolicyPreview previews the impact of policy rules on local endpoints
+*/
+func (a *Client) PostPolicyPreview(params *PostPolicyPreviewParams) (*PostPolicyPreviewOK, error) {
+	// TODO: Validate the params before sending
+	if params == nil {
+		params = NewPostPolicyPreviewParams()
+	}
+
+	result, err := a.transport.Submit(&runtime.ClientOperation{
+		ID:                 "PostPolicyPreview",
+		Method:             "POST",
+		PathPattern:        "/policy/preview",
+		ProducesMediaTypes: []string{"application/json"},
+		ConsumesMediaTypes: []string{"application/json"},
+		Schemes:            []string{"http"},
+		Params:             params,
+		Reader:             &PostPolicyPreviewReader{formats: a.formats},
+		Context:            params.Context,
+		Client:             params.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*PostPolicyPreviewOK), nil
+
+}
+
 /*
 PutPolicy creates or update a policy sub tree
 */
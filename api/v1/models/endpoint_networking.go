@@ -22,6 +22,9 @@ type EndpointNetworking struct {
 	// IP4/6 addresses assigned to this Endpoint
 	Addressing []*AddressPair `json:"addressing"`
 
+	// Egress bandwidth limit in bits per second, unset if not limited
+	EgressBandwidth int64 `json:"egress-bandwidth,omitempty"`
+
 	// host addressing
 	HostAddressing *NodeAddressing `json:"host-addressing,omitempty"`
 
@@ -40,6 +43,8 @@ type EndpointNetworking struct {
 
 /* polymorph EndpointNetworking addressing false */
 
+/* polymorph EndpointNetworking egress-bandwidth false */
+
 /* polymorph EndpointNetworking host-addressing false */
 
 /* polymorph EndpointNetworking host-mac false */
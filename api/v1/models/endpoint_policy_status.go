@@ -19,6 +19,9 @@ import (
 
 type EndpointPolicyStatus struct {
 
+	// Statistics of packets which would have been dropped by policy in policy audit mode
+	AuditStatistics *PolicyAuditStatistics `json:"audit-statistics,omitempty"`
+
 	// The policy revision currently enforced in the proxy for this endpoint
 	ProxyPolicyRevision int64 `json:"proxy-policy-revision,omitempty"`
 
@@ -32,6 +35,8 @@ type EndpointPolicyStatus struct {
 	Spec *EndpointPolicy `json:"spec,omitempty"`
 }
 
+/* polymorph EndpointPolicyStatus audit-statistics false */
+
 /* polymorph EndpointPolicyStatus proxy-policy-revision false */
 
 /* polymorph EndpointPolicyStatus proxy-statistics false */
@@ -44,6 +49,11 @@ type EndpointPolicyStatus struct {
 func (m *EndpointPolicyStatus) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateAuditStatistics(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateProxyStatistics(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -65,6 +75,25 @@ func (m *EndpointPolicyStatus) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *EndpointPolicyStatus) validateAuditStatistics(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.AuditStatistics) { // not required
+		return nil
+	}
+
+	if m.AuditStatistics != nil {
+
+		if err := m.AuditStatistics.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("audit-statistics")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (m *EndpointPolicyStatus) validateProxyStatistics(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.ProxyStatistics) { // not required
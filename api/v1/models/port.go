@@ -20,6 +20,11 @@ import (
 
 type Port struct {
 
+	// Last Layer 4 port number of a port range starting at port. Only
+	// used when tracing policy for a range of ports.
+	//
+	EndPort uint16 `json:"end-port,omitempty"`
+
 	// Layer 4 port number
 	Port uint16 `json:"port,omitempty"`
 
@@ -27,6 +32,8 @@ type Port struct {
 	Protocol string `json:"protocol,omitempty"`
 }
 
+/* polymorph Port end-port false */
+
 /* polymorph Port port false */
 
 /* polymorph Port protocol false */
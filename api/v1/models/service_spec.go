@@ -6,6 +6,7 @@ package models
 // Editing this file might prove futile when you re-run the swagger generate command
 
 import (
+	"encoding/json"
 	"strconv"
 
 	strfmt "github.com/go-openapi/strfmt"
@@ -160,22 +161,77 @@ type ServiceSpecFlags struct {
 
 	// Perform direct server return
 	DirectServerReturn bool `json:"direct-server-return,omitempty"`
+
+	// Service type
+	Type string `json:"type,omitempty"`
 }
 
 /* polymorph ServiceSpecFlags active-frontend false */
 
 /* polymorph ServiceSpecFlags direct-server-return false */
 
+/* polymorph ServiceSpecFlags type false */
+
 // Validate validates this service spec flags
 func (m *ServiceSpecFlags) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateType(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
 	return nil
 }
 
+var serviceSpecFlagsTypeTypePropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["ClusterIP","NodePort","ExternalIPs","LoadBalancer"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		serviceSpecFlagsTypeTypePropEnum = append(serviceSpecFlagsTypeTypePropEnum, v)
+	}
+}
+
+const (
+	// ServiceSpecFlagsTypeClusterIP captures enum value "ClusterIP"
+	ServiceSpecFlagsTypeClusterIP string = "ClusterIP"
+	// ServiceSpecFlagsTypeNodePort captures enum value "NodePort"
+	ServiceSpecFlagsTypeNodePort string = "NodePort"
+	// ServiceSpecFlagsTypeExternalIPs captures enum value "ExternalIPs"
+	ServiceSpecFlagsTypeExternalIPs string = "ExternalIPs"
+	// ServiceSpecFlagsTypeLoadBalancer captures enum value "LoadBalancer"
+	ServiceSpecFlagsTypeLoadBalancer string = "LoadBalancer"
+)
+
+// prop value enum
+func (m *ServiceSpecFlags) validateTypeEnum(path, location string, value string) error {
+	if err := validate.Enum(path, location, value, serviceSpecFlagsTypeTypePropEnum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *ServiceSpecFlags) validateType(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Type) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateTypeEnum("flags"+"."+"type", "body", m.Type); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // MarshalBinary interface implementation
 func (m *ServiceSpecFlags) MarshalBinary() ([]byte, error) {
 	if m == nil {
@@ -29,6 +29,9 @@ type StatusResponse struct {
 	// Status of all endpoint controllers
 	Controllers ControllerStatuses `json:"controllers"`
 
+	// Status of transparent encryption
+	Encryption *EncryptionStatus `json:"encryption,omitempty"`
+
 	// Status of IP address management
 	IPAM *IPAMStatus `json:"ipam,omitempty"`
 
@@ -44,6 +47,9 @@ type StatusResponse struct {
 	// Status of proxy
 	Proxy *ProxyStatus `json:"proxy,omitempty"`
 
+	// Status of sockmap acceleration
+	Sockmap *Status `json:"sockmap,omitempty"`
+
 	// List of stale information in the status
 	Stale map[string]strfmt.DateTime `json:"stale,omitempty"`
 }
@@ -56,6 +62,8 @@ type StatusResponse struct {
 
 /* polymorph StatusResponse controllers false */
 
+/* polymorph StatusResponse encryption false */
+
 /* polymorph StatusResponse ipam false */
 
 /* polymorph StatusResponse kubernetes false */
@@ -66,6 +74,8 @@ type StatusResponse struct {
 
 /* polymorph StatusResponse proxy false */
 
+/* polymorph StatusResponse sockmap false */
+
 /* polymorph StatusResponse stale false */
 
 // Validate validates this status response
@@ -87,6 +97,11 @@ func (m *StatusResponse) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateEncryption(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if err := m.validateIPAM(formats); err != nil {
 		// prop
 		res = append(res, err)
@@ -112,6 +127,11 @@ func (m *StatusResponse) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateSockmap(formats); err != nil {
+		// prop
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
@@ -175,6 +195,25 @@ func (m *StatusResponse) validateContainerRuntime(formats strfmt.Registry) error
 	return nil
 }
 
+func (m *StatusResponse) validateEncryption(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Encryption) { // not required
+		return nil
+	}
+
+	if m.Encryption != nil {
+
+		if err := m.Encryption.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("encryption")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (m *StatusResponse) validateIPAM(formats strfmt.Registry) error {
 
 	if swag.IsZero(m.IPAM) { // not required
@@ -270,6 +309,25 @@ func (m *StatusResponse) validateProxy(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *StatusResponse) validateSockmap(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.Sockmap) { // not required
+		return nil
+	}
+
+	if m.Sockmap != nil {
+
+		if err := m.Sockmap.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("sockmap")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
 // MarshalBinary interface implementation
 func (m *StatusResponse) MarshalBinary() ([]byte, error) {
 	if m == nil {
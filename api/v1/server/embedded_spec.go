@@ -822,6 +822,42 @@ func init() {
         }
       }
     },
+    "/policy/preview": {
+      "post": {
+        "description": "Resolves the policy of all local endpoints as if the given policy\nrules were imported, without importing them, and returns per\nendpoint which identities and ports would be newly allowed or newly\ndenied compared to the policy currently enforced.\n",
+        "tags": [
+          "policy"
+        ],
+        "summary": "Preview the impact of policy rules on local endpoints",
+        "parameters": [
+          {
+            "$ref": "#/parameters/policy-rules"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Success",
+            "schema": {
+              "$ref": "#/definitions/PolicyPreview"
+            }
+          },
+          "400": {
+            "description": "Invalid policy",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "InvalidPolicy"
+          },
+          "500": {
+            "description": "Policy preview failed",
+            "schema": {
+              "$ref": "#/definitions/Error"
+            },
+            "x-go-name": "Failure"
+          }
+        }
+      }
+    },
     "/policy/resolve": {
       "get": {
         "tags": [
@@ -1366,6 +1402,24 @@ func init() {
         }
       }
     },
+    "EncryptionStatus": {
+      "description": "Status of transparent encryption",
+      "type": "object",
+      "properties": {
+        "mode": {
+          "description": "Encryption mode",
+          "type": "string",
+          "enum": [
+            "Disabled",
+            "IPsec"
+          ]
+        },
+        "msg": {
+          "description": "Human readable status/error/warning message",
+          "type": "string"
+        }
+      }
+    },
     "Endpoint": {
       "description": "An endpoint is a namespaced network interface to which cilium applies policies",
       "type": "object",
@@ -1556,6 +1610,10 @@ func init() {
             "$ref": "#/definitions/AddressPair"
           }
         },
+        "egress-bandwidth": {
+          "description": "Egress bandwidth limit in bits per second, unset if not limited",
+          "type": "integer"
+        },
         "host-addressing": {
           "$ref": "#/definitions/NodeAddressing"
         },
@@ -1629,10 +1687,42 @@ func init() {
         "both"
       ]
     },
+    "EndpointPolicyPreview": {
+      "description": "Policy changes of an endpoint if policy rules were imported",
+      "type": "object",
+      "properties": {
+        "error": {
+          "description": "Error resolving the policy of the endpoint with the policy rules",
+          "type": "string"
+        },
+        "id": {
+          "description": "Local endpoint ID",
+          "type": "integer"
+        },
+        "newly-allowed": {
+          "description": "Policy entries which would be newly allowed",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/PolicyPreviewEntry"
+          }
+        },
+        "newly-denied": {
+          "description": "Policy entries which would be newly denied",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/PolicyPreviewEntry"
+          }
+        }
+      }
+    },
     "EndpointPolicyStatus": {
       "description": "Policy information of an endpoint",
       "type": "object",
       "properties": {
+        "audit-statistics": {
+          "description": "Statistics of packets which would have been dropped by policy in policy audit mode",
+          "$ref": "#/definitions/PolicyAuditStatistics"
+        },
         "proxy-policy-revision": {
           "description": "The policy revision currently enforced in the proxy for this endpoint",
           "type": "integer"
@@ -2083,6 +2173,67 @@ func init() {
         }
       }
     },
+    "PolicyAuditStatistics": {
+      "description": "Number of packets of an endpoint which would have been dropped by policy",
+      "type": "object",
+      "properties": {
+        "egress-drops": {
+          "description": "Number of packets from the endpoint which would have been dropped",
+          "type": "integer"
+        },
+        "ingress-drops": {
+          "description": "Number of packets to the endpoint which would have been dropped",
+          "type": "integer"
+        }
+      }
+    },
+    "PolicyPreview": {
+      "description": "Impact of policy rules on the policy of local endpoints",
+      "type": "object",
+      "properties": {
+        "endpoints": {
+          "description": "Policy changes of local endpoints",
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/EndpointPolicyPreview"
+          }
+        },
+        "revision": {
+          "description": "Revision of the policy repository the preview was computed against",
+          "type": "integer"
+        }
+      }
+    },
+    "PolicyPreviewEntry": {
+      "description": "Identity and port of a policy entry of an endpoint",
+      "type": "object",
+      "properties": {
+        "direction": {
+          "description": "Direction of the traffic",
+          "type": "string",
+          "enum": [
+            "ingress",
+            "egress"
+          ]
+        },
+        "end-port": {
+          "description": "Last destination port of a range of ports starting at port, 0 if\nthe entry does not match a range of ports\n",
+          "type": "integer"
+        },
+        "identity": {
+          "description": "Security identity of the peer",
+          "type": "integer"
+        },
+        "port": {
+          "description": "Destination port, 0 for all ports",
+          "type": "integer"
+        },
+        "protocol": {
+          "description": "Layer 4 protocol, empty for all protocols",
+          "type": "string"
+        }
+      }
+    },
     "PolicyRule": {
       "description": "A policy rule including the rule labels it derives from",
       "properties": {
@@ -2118,6 +2269,11 @@ func init() {
       "description": "Layer 4 port / protocol pair",
       "type": "object",
       "properties": {
+        "end-port": {
+          "description": "Last Layer 4 port number of a port range starting at port. Only\nused when tracing policy for a range of ports.\n",
+          "type": "integer",
+          "format": "uint16"
+        },
         "port": {
           "description": "Layer 4 port number",
           "type": "integer",
@@ -2263,6 +2419,16 @@ func init() {
             "direct-server-return": {
               "description": "Perform direct server return",
               "type": "boolean"
+            },
+            "type": {
+              "description": "Service type",
+              "type": "string",
+              "enum": [
+                "ClusterIP",
+                "NodePort",
+                "ExternalIPs",
+                "LoadBalancer"
+              ]
             }
           }
         },
@@ -2325,6 +2491,10 @@ func init() {
           "description": "Status of all endpoint controllers",
           "$ref": "#/definitions/ControllerStatuses"
         },
+        "encryption": {
+          "description": "Status of transparent encryption",
+          "$ref": "#/definitions/EncryptionStatus"
+        },
         "ipam": {
           "description": "Status of IP address management",
           "$ref": "#/definitions/IPAMStatus"
@@ -2345,6 +2515,10 @@ func init() {
           "description": "Status of proxy",
           "$ref": "#/definitions/ProxyStatus"
         },
+        "sockmap": {
+          "description": "Status of sockmap acceleration",
+          "$ref": "#/definitions/Status"
+        },
         "stale": {
           "description": "List of stale information in the status",
           "type": "object",
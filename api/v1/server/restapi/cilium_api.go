@@ -133,6 +133,9 @@ func NewCiliumAPI(spec *loads.Document) *CiliumAPI {
 		IPAMPostIPAMIPHandler: ipam.PostIPAMIPHandlerFunc(func(params ipam.PostIPAMIPParams) middleware.Responder {
 			return middleware.NotImplemented("operation IPAMPostIPAMIP has not yet been implemented")
 		}),
+		PolicyPostPolicyPreviewHandler: policy.PostPolicyPreviewHandlerFunc(func(params policy.PostPolicyPreviewParams) middleware.Responder {
+			return middleware.NotImplemented("operation PolicyPostPolicyPreview has not yet been implemented")
+		}),
 		EndpointPutEndpointIDHandler: endpoint.PutEndpointIDHandlerFunc(func(params endpoint.PutEndpointIDParams) middleware.Responder {
 			return middleware.NotImplemented("operation EndpointPutEndpointID has not yet been implemented")
 		}),
@@ -231,6 +234,8 @@ type CiliumAPI struct {
 	IPAMPostIPAMHandler ipam.PostIPAMHandler
 	// IPAMPostIPAMIPHandler sets the operation handler for the post IP a m IP operation
 	IPAMPostIPAMIPHandler ipam.PostIPAMIPHandler
+	// PolicyPostPolicyPreviewHandler sets the operation handler for the post policy preview operation
+	PolicyPostPolicyPreviewHandler policy.PostPolicyPreviewHandler
 	// EndpointPutEndpointIDHandler sets the operation handler for the put endpoint ID operation
 	EndpointPutEndpointIDHandler endpoint.PutEndpointIDHandler
 	// PolicyPutPolicyHandler sets the operation handler for the put policy operation
@@ -420,6 +425,10 @@ func (o *CiliumAPI) Validate() error {
 		unregistered = append(unregistered, "ipam.PostIPAMIPHandler")
 	}
 
+	if o.PolicyPostPolicyPreviewHandler == nil {
+		unregistered = append(unregistered, "policy.PostPolicyPreviewHandler")
+	}
+
 	if o.EndpointPutEndpointIDHandler == nil {
 		unregistered = append(unregistered, "endpoint.PutEndpointIDHandler")
 	}
@@ -672,6 +681,11 @@ func (o *CiliumAPI) initHandlerCache() {
 	}
 	o.handlers["POST"]["/ipam/{ip}"] = ipam.NewPostIPAMIP(o.context, o.IPAMPostIPAMIPHandler)
 
+	if o.handlers["POST"] == nil {
+		o.handlers["POST"] = make(map[string]http.Handler)
+	}
+	o.handlers["POST"]["/policy/preview"] = policy.NewPostPolicyPreview(o.context, o.PolicyPostPolicyPreviewHandler)
+
 	if o.handlers["PUT"] == nil {
 		o.handlers["PUT"] = make(map[string]http.Handler)
 	}
@@ -47,6 +47,9 @@
 #include "lib/csum.h"
 #include "lib/conntrack.h"
 #include "lib/encap.h"
+#include "lib/encrypt.h"
+#include "lib/bandwidth.h"
+#include "lib/egress_gateway.h"
 
 #define POLICY_ID ((LXC_ID << 16) | SECLABEL)
 
@@ -231,7 +234,7 @@ skip_service_lookup:
 	/* If the packet is in the establishing direction and it's destined
 	 * within the cluster, it must match policy or be dropped. If it's
 	 * bound for the host/outside, perform the CIDR policy check. */
-	verdict = policy_can_egress6(skb, tuple, *dstID,
+	verdict = policy_can_egress6(skb, tuple, l4_off, *dstID,
 				     ipv6_ct_tuple_get_daddr(tuple));
 	if (ret != CT_REPLY && ret != CT_RELATED && verdict < 0) {
 		/* If the connection was previously known and packet is now
@@ -328,6 +331,18 @@ skip_service_lookup:
 	}
 
 	/* The packet goes to a peer not managed by this agent instance */
+#ifdef ENABLE_IPSEC
+	/* Traffic to endpoints on remote nodes is passed to the stack with the
+	 * encryption mark set, the xfrm policies installed by the agent then
+	 * encrypt and forward it to the remote node.
+	 */
+	if (tunnel_endpoint) {
+		ret = set_encrypt_mark(skb);
+		if (IS_ERR(ret))
+			return ret;
+		goto pass_to_stack;
+	}
+#endif
 #ifdef ENCAP_IFINDEX
 	if (tunnel_endpoint) {
 		return encap_and_redirect_with_nodeid(skb, tunnel_endpoint,
@@ -533,7 +548,8 @@ skip_service_lookup:
 	/* If the packet is in the establishing direction and it's destined
 	 * within the cluster, it must match policy or be dropped. If it's
 	 * bound for the host/outside, perform the CIDR policy check. */
-	verdict = policy_can_egress4(skb, &tuple, *dstID, ipv4_ct_tuple_get_daddr(&tuple));
+	verdict = policy_can_egress4(skb, &tuple, l4_off, *dstID,
+				     ipv4_ct_tuple_get_daddr(&tuple));
 	if (ret != CT_REPLY && ret != CT_RELATED && verdict < 0) {
 		/* If the connection was previously known and packet is now
 		 * denied, remove the connection tracking entry */
@@ -626,7 +642,33 @@ skip_service_lookup:
 		return ipv4_local_delivery(skb, l3_off, l4_off, SECLABEL, ip4, ep, METRIC_EGRESS);
 	}
 
+#ifdef ENABLE_IPSEC
+	/* Traffic to endpoints on remote nodes is passed to the stack with the
+	 * encryption mark set, the xfrm policies installed by the agent then
+	 * encrypt and forward it to the remote node.
+	 */
+	if (tunnel_endpoint) {
+		ret = set_encrypt_mark(skb);
+		if (IS_ERR(ret))
+			return ret;
+		goto pass_to_stack;
+	}
+#endif
 #ifdef ENCAP_IFINDEX
+#ifdef ENABLE_EGRESS_GATEWAY
+	/* Traffic leaving the cluster which is selected by an egress NAT
+	 * policy is encapsulated to the gateway node of the policy. The
+	 * gateway node masquerades it with the egress IP of the policy.
+	 */
+	if (!tunnel_endpoint) {
+		struct egress_info *info;
+
+		info = lookup_ip4_egress_gateway(ip4->saddr, orig_dip);
+		if (info && info->tunnel_endpoint)
+			return encap_and_redirect_with_nodeid(skb, info->tunnel_endpoint,
+							      SECLABEL, monitor);
+	}
+#endif
 	if (tunnel_endpoint) {
 		return encap_and_redirect_with_nodeid(skb, tunnel_endpoint,
 						      SECLABEL, monitor);
@@ -723,6 +765,11 @@ int handle_ingress(struct __sk_buff *skb)
 	send_trace_notify(skb, TRACE_FROM_LXC, SECLABEL, 0, 0, 0, 0,
 			  TRACE_PAYLOAD_LEN);
 
+	/* Packets are paced by the native device according to the egress
+	 * bandwidth limit of the endpoint, if any.
+	 */
+	edt_set_aggregate(skb, LXC_ID);
+
 	switch (skb->protocol) {
 #ifdef ENABLE_IPV6
 	case bpf_htons(ETH_P_IPV6):
@@ -825,7 +872,8 @@ ipv6_policy(struct __sk_buff *skb, int ifindex, __u32 src_label, int *forwarding
 	}
 
 	if (!(cfg->flags & EP_F_SKIP_POLICY_INGRESS))
-		verdict = policy_can_access_ingress(skb, src_label, tuple.dport,
+		verdict = policy_can_access_ingress(skb, src_label,
+				policy_icmp_dport(skb, l4_off, tuple.nexthdr, tuple.dport),
 				tuple.nexthdr, sizeof(tuple.saddr),
 				&tuple.saddr, false);
 	else
@@ -969,7 +1017,9 @@ ipv4_policy(struct __sk_buff *skb, int ifindex, __u32 src_label, int *forwarding
 	}
 
 	if (!(cfg->flags & EP_F_SKIP_POLICY_INGRESS))
-		verdict = policy_can_access_ingress(skb, src_label, tuple.dport,
+		verdict = policy_can_access_ingress(skb, src_label,
+						    policy_icmp_dport(skb, l4_off, tuple.nexthdr,
+								      tuple.dport),
 						    tuple.nexthdr,
 						    sizeof(orig_sip),
 						    &orig_sip, is_fragment);
@@ -16,7 +16,13 @@
  *  Foundation, Inc., 51 Franklin St, Fifth Floor, Boston, MA  02110-1301  USA
  */
 #include <node_config.h>
+#ifdef HOST_ENDPOINT
+/* When loaded as the datapath of the host endpoint, the configuration of
+ * the host endpoint replaces the netdev configuration. */
+#include <lxc_config.h>
+#else
 #include <netdev_config.h>
+#endif
 
 /* These are configuartion options which have a default value in their
  * respective header files and must thus be defined beforehand:
@@ -46,6 +52,9 @@
 #include "lib/policy.h"
 #include "lib/drop.h"
 #include "lib/encap.h"
+#include "lib/host_firewall.h"
+#include "lib/bandwidth.h"
+#include "lib/nodeport.h"
 
 
 #if defined FROM_HOST && (defined ENABLE_IPV4 || defined ENABLE_IPV6)
@@ -211,12 +220,27 @@ static inline int handle_ipv6(struct __sk_buff *skb, __u32 src_identity)
 		return DROP_INVALID;
 #endif
 
+#if defined ENABLE_NODEPORT && !defined FROM_HOST
+	if (1) {
+		int ret;
+
+		ret = nodeport_lb6(skb, nexthdr, l4_off);
+		/* DIRECT PACKET READ INVALID */
+		if (IS_ERR(ret))
+			return ret;
+	}
+
+	if (!revalidate_data(skb, &data, &data_end, &ip6))
+		return DROP_INVALID;
+#endif
+
 	/* Lookup IPv4 address in list of local endpoints */
 	if ((ep = lookup_ip6_endpoint(ip6)) != NULL) {
 		/* Let through packets to the node-ip so they are
-		 * processed by the local ip stack */
+		 * processed by the local ip stack, subject to the
+		 * policy of the host endpoint if any */
 		if (ep->flags & ENDPOINT_F_HOST)
-			return TC_ACT_OK;
+			return ipv6_host_policy_ingress(skb, src_identity);
 
 		return ipv6_local_delivery(skb, l3_off, l4_off, flowlabel, ip6, nexthdr, ep, METRIC_INGRESS);
 	}
@@ -394,12 +418,27 @@ static inline int handle_ipv4(struct __sk_buff *skb, __u32 src_identity)
 		return DROP_INVALID;
 #endif
 
+#if defined ENABLE_NODEPORT && !defined FROM_HOST
+	if (1) {
+		int ret;
+
+		ret = nodeport_lb4(skb, l4_off);
+		/* DIRECT PACKET READ INVALID */
+		if (IS_ERR(ret))
+			return ret;
+	}
+
+	if (!revalidate_data(skb, &data, &data_end, &ip4))
+		return DROP_INVALID;
+#endif
+
 	/* Lookup IPv4 address in list of local endpoints and host IPs */
 	if ((ep = lookup_ip4_endpoint(ip4)) != NULL) {
 		/* Let through packets to the node-ip so they are
-		 * processed by the local ip stack */
+		 * processed by the local ip stack, subject to the
+		 * policy of the host endpoint if any */
 		if (ep->flags & ENDPOINT_F_HOST)
-			return TC_ACT_OK;
+			return ipv4_host_policy_ingress(skb, src_identity);
 
 		return ipv4_local_delivery(skb, ETH_HLEN, l4_off, secctx, ip4, ep, METRIC_INGRESS);
 	}
@@ -537,4 +576,70 @@ int from_netdev(struct __sk_buff *skb)
 	return ret;
 }
 
+#if defined(HOST_ENDPOINT) || defined(ENABLE_BANDWIDTH_MANAGER) || defined(ENABLE_NODEPORT)
+/* Attached to the egress of the native device by the host endpoint to track
+ * connections initiated by the host, and by the agent to enforce the egress
+ * bandwidth limit of endpoints and to translate the replies of service
+ * backends to external clients. */
+__section("to-netdev")
+int to_netdev(struct __sk_buff *skb)
+{
+	int ret = TC_ACT_OK;
+
+#ifdef ENABLE_NODEPORT
+	switch (skb->protocol) {
+#ifdef ENABLE_IPV6
+	case bpf_htons(ETH_P_IPV6):
+		ret = nodeport_rev_nat6(skb);
+		break;
+#endif
+
+#ifdef ENABLE_IPV4
+	case bpf_htons(ETH_P_IP):
+		ret = nodeport_rev_nat4(skb);
+		break;
+#endif
+
+	default:
+		ret = TC_ACT_OK;
+	}
+
+	if (IS_ERR(ret))
+		return send_drop_notify_error(skb, ret, TC_ACT_SHOT, METRIC_EGRESS);
+#endif
+
+#ifdef ENABLE_BANDWIDTH_MANAGER
+	ret = edt_sched_departure(skb);
+	if (IS_ERR(ret))
+		return send_drop_notify_error(skb, ret, TC_ACT_SHOT, METRIC_EGRESS);
+#endif
+
+#ifdef HOST_ENDPOINT
+	switch (skb->protocol) {
+#ifdef ENABLE_IPV6
+	case bpf_htons(ETH_P_IPV6):
+		ret = ipv6_host_track_egress(skb);
+		break;
+#endif
+
+#ifdef ENABLE_IPV4
+	case bpf_htons(ETH_P_IP):
+		ret = ipv4_host_track_egress(skb);
+		break;
+#endif
+
+	default:
+		ret = TC_ACT_OK;
+	}
+
+	/* Failing to track a connection must not prevent the host from
+	 * sending traffic. */
+	if (IS_ERR(ret))
+		return send_drop_notify_error(skb, ret, TC_ACT_OK, METRIC_EGRESS);
+#endif /* HOST_ENDPOINT */
+
+	return ret;
+}
+#endif /* HOST_ENDPOINT || ENABLE_BANDWIDTH_MANAGER || ENABLE_NODEPORT */
+
 BPF_LICENSE("GPL");
@@ -24,6 +24,8 @@ NATIVE_DEV=$6
 XDP_DEV=$7
 XDP_MODE=$8
 MTU=$9
+BANDWIDTH_MANAGER=${10}
+NODE_PORT=${11}
 
 ID_HOST=1
 ID_WORLD=2
@@ -346,6 +348,18 @@ if [ "$MODE" = "direct" ]; then
 		OPTS="-DSECLABEL=${ID_WORLD} -DPOLICY_MAP=${POLICY_MAP}"
 		bpf_load $NATIVE_DEV "$OPTS" "ingress" bpf_netdev.c bpf_netdev.o from-netdev $CALLS_MAP
 
+		if [ "$BANDWIDTH_MANAGER" = "true" ]; then
+			# The fq qdisc paces packets according to the departure
+			# time set by to-netdev
+			tc qdisc replace dev $NATIVE_DEV root fq
+		fi
+
+		if [ "$BANDWIDTH_MANAGER" = "true" -o "$NODE_PORT" = "true" ]; then
+			# bpf_load resets the clsact qdisc, attach the already
+			# compiled object without removing from-netdev
+			tc filter replace dev $NATIVE_DEV egress prio 1 handle 1 bpf da obj bpf_netdev.o sec to-netdev
+		fi
+
 		echo "$NATIVE_DEV" > $RUNDIR/device.state
 	fi
 elif [ "$MODE" = "lb" ]; then
@@ -0,0 +1,98 @@
+/*
+ *  Copyright (C) 2019 Authors of Cilium
+ *
+ *  This program is free software; you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation; either version 2 of the License, or
+ *  (at your option) any later version.
+ *
+ *  This program is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with this program; if not, write to the Free Software
+ *  Foundation, Inc., 51 Franklin St, Fifth Floor, Boston, MA  02110-1301  USA
+ */
+#ifndef __LIB_BANDWIDTH_H_
+#define __LIB_BANDWIDTH_H_
+
+#include "common.h"
+#include "maps.h"
+#include "utils.h"
+
+#ifdef ENABLE_BANDWIDTH_MANAGER
+/* The egress bandwidth limit of endpoints is enforced by shaping rather than
+ * policing: the departure time (EDT) of each packet is set in skb->tstamp
+ * when it leaves the node, and the fq qdisc of the native device holds the
+ * packet back until then. The endpoint is recorded in skb->queue_mapping by
+ * the endpoint's program, as the packet carries no other reference to it
+ * once it has been forwarded to the native device.
+ */
+static inline void __inline__ edt_set_aggregate(struct __sk_buff *skb,
+						 __u32 aggregate)
+{
+	skb->queue_mapping = aggregate;
+}
+
+static inline __u32 __inline__ edt_get_aggregate(struct __sk_buff *skb)
+{
+	__u32 aggregate = skb->queue_mapping;
+
+	/* The queue mapping is picked by the stack after the egress hook,
+	 * reset it so that it is not mistaken for a TX queue.
+	 */
+	skb->queue_mapping = 0;
+	return aggregate;
+}
+
+/* edt_sched_departure schedules the departure of the packet so that the
+ * endpoint which sent it does not exceed its egress bandwidth limit. Packets
+ * which would have to be delayed by more than the drop horizon of the
+ * endpoint are dropped, which bounds the queue built up in the qdisc.
+ *
+ * The state is shared by all CPUs without synchronization. Concurrent
+ * updates may let slightly more traffic pass than configured.
+ */
+static inline int __inline__ edt_sched_departure(struct __sk_buff *skb)
+{
+	struct throttle_key key = {};
+	struct throttle_value *val;
+	__u64 delay, now, t, t_next;
+
+	key.lxc_id = edt_get_aggregate(skb);
+	if (!key.lxc_id)
+		return 0;
+
+	val = map_lookup_elem(&cilium_throttle, &key);
+	if (!val || !val->rate)
+		return 0;
+
+	now = bpf_ktime_get_nsec();
+	t = skb->tstamp;
+	if (t < now)
+		t = now;
+
+	delay = (__u64)skb->len * NSEC_PER_SEC / val->rate;
+	t_next = val->t_last + delay;
+	if (t_next <= t) {
+		val->t_last = t;
+		return 0;
+	}
+
+	if (t_next - now >= val->t_horizon_drop)
+		return DROP_BANDWIDTH;
+
+	val->t_last = t_next;
+	skb->tstamp = t_next;
+	return 0;
+}
+#else
+static inline void __inline__ edt_set_aggregate(struct __sk_buff *skb,
+						 __u32 aggregate)
+{
+}
+#endif /* ENABLE_BANDWIDTH_MANAGER */
+
+#endif /* __LIB_BANDWIDTH_H_ */
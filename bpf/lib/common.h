@@ -192,12 +192,17 @@ struct policy_key {
 	__u16		dport;
 	__u8		protocol;
 	__u8		egress:1,
-			pad:7;
+			dport_wildcard:4, /* Number of ignored low-order dport bits */
+			pad:3;
 };
 
 struct policy_entry {
 	__be16		proxy_port;
-	__u16		pad[3];
+	__u8		deny:1,
+			pad:7;
+	__u8		pad0;
+	__u16		pad1;
+	__u16		pad2;
 	__u64		packets;
 	__u64		bytes;
 };
@@ -215,6 +220,75 @@ struct metrics_value {
      __u64	bytes;
 };
 
+/* policy_audit_key corresponds to the Key object in pkg/maps/policyauditmap. */
+struct policy_audit_key {
+	__u16	lxc_id;
+	__u8	dir;		/* 1: ingress 2: egress */
+	__u8	pad;
+};
+
+struct policy_audit_value {
+	__u64	count;
+	__u64	bytes;
+};
+
+/* throttle_key corresponds to the Key object in pkg/maps/throttlemap. */
+struct throttle_key {
+	__u16	lxc_id;
+	__u16	pad;
+};
+
+struct throttle_value {
+	__u64	rate;		/* Egress bandwidth limit in bytes per second */
+	__u64	t_last;		/* Departure time of the last packet in ns */
+	__u64	t_horizon_drop;	/* Maximum delay of a packet in ns */
+};
+
+/* egress_key corresponds to the Key object in pkg/maps/egressmap. */
+struct egress_key {
+	struct bpf_lpm_trie_key lpm_key;
+	__be32	sip;
+	__be32	dip;
+};
+
+/* egress_info corresponds to the Value object in pkg/maps/egressmap. */
+struct egress_info {
+	__be32	egress_ip;
+	__be32	tunnel_endpoint;
+};
+
+/* nodeport_key4 identifies one direction of a connection to a service
+ * frontend received on the native device. Forward keys are made of the
+ * client and the frontend, reverse keys of the backend and the client.
+ */
+struct nodeport_key4 {
+	__be32	saddr;
+	__be32	daddr;
+	__be16	sport;
+	__be16	dport;
+	__u8	nexthdr;
+	__u8	flags;
+	__u16	pad;
+};
+
+struct nodeport_key6 {
+	union v6addr	saddr;
+	union v6addr	daddr;
+	__be16		sport;
+	__be16		dport;
+	__u8		nexthdr;
+	__u8		flags;
+	__u16		pad;
+};
+
+#define NODEPORT_F_REVERSE	1
+
+struct nodeport_entry {
+	__u16	rev_nat_index;
+	__u16	slave;		/* Selected backend, forward entries only */
+	__u32	pad;
+};
+
 
 enum {
 	CILIUM_NOTIFY_UNSPEC,
@@ -280,6 +354,9 @@ enum {
 #define DROP_UNKNOWN_CT			-163
 #define DROP_HOST_UNREACHABLE		-164
 #define DROP_NO_CONFIG		-165
+#define DROP_POLICY_DENY	-166
+#define DROP_BANDWIDTH		-167
+#define DROP_NO_ENCRYPT_KEY	-168
 
 /* Cilium metrics reason for forwarding packet.
  * If reason > 0 then this is a drop reason and value corresponds to -(DROP_*)
@@ -304,6 +381,12 @@ enum {
 #define MARK_MAGIC_PROXY_INGRESS	0xA00
 #define MARK_MAGIC_PROXY_EGRESS		0xB00
 #define MARK_MAGIC_HOST			0xC00
+#define MARK_MAGIC_ENCRYPT		0xE00
+
+/* The four bits above MARK_MAGIC_ENCRYPT carry the SPI of the IPsec key to
+ * encrypt the packet with.
+ */
+#define MARK_MAGIC_KEY_SHIFT		12
 
 /**
  * get_identity_via_proxy - returns source identity as specified by the proxy
@@ -425,7 +508,12 @@ struct lb6_key {
 } __attribute__((packed));
 
 struct lb6_service {
-	union v6addr target;
+	union {
+		union v6addr target;
+		/* Master only: session affinity timeout in seconds, 0 if
+		 * session affinity is disabled */
+		__u32 affinity_timeout;
+	};
 	__be16 port;
 	__u16 count;
 	__u16 rev_nat_index;
@@ -444,7 +532,12 @@ struct lb4_key {
 } __attribute__((packed));
 
 struct lb4_service {
-	__be32 target;
+	union {
+		__be32 target;
+		/* Master only: session affinity timeout in seconds, 0 if
+		 * session affinity is disabled */
+		__u32 affinity_timeout;
+	};
 	__be16 port;
 	__u16 count;
 	__u16 rev_nat_index;
@@ -456,6 +549,33 @@ struct lb4_reverse_nat {
 	__be16 port;
 } __attribute__((packed));
 
+struct lb6_affinity_key {
+	union v6addr client_ip;
+	__u16 rev_nat_id;
+	__u16 pad;
+} __attribute__((packed));
+
+struct lb4_affinity_key {
+	__be32 client_ip;
+	__u16 rev_nat_id;
+	__u16 pad;
+} __attribute__((packed));
+
+struct lb_affinity_val {
+	__u32 lifetime;		/* Expiry in seconds, see bpf_ktime_get_sec() */
+	__u16 slave;		/* Backend slot selected for the client */
+	__u16 pad;
+};
+
+// LB_MAGLEV_LUT_SIZE generated by daemon in node_config.h
+struct lb_maglev_lut {
+	__u16 backends[LB_MAGLEV_LUT_SIZE];	/* Backend slot for each hash */
+};
+
+struct encrypt_config {
+	__u8 encrypt_key;	/* SPI of the key used to encrypt traffic */
+} __attribute__((packed));
+
 // LB_RR_MAX_SEQ generated by daemon in node_config.h
 struct lb_sequence {
 	__u16 count;
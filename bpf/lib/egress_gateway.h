@@ -0,0 +1,47 @@
+/*
+ *  Copyright (C) 2019 Authors of Cilium
+ *
+ *  This program is free software; you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation; either version 2 of the License, or
+ *  (at your option) any later version.
+ *
+ *  This program is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with this program; if not, write to the Free Software
+ *  Foundation, Inc., 51 Franklin St, Fifth Floor, Boston, MA  02110-1301  USA
+ */
+#ifndef __LIB_EGRESS_GATEWAY_H_
+#define __LIB_EGRESS_GATEWAY_H_
+
+#include "common.h"
+#include "maps.h"
+
+#ifdef ENABLE_EGRESS_GATEWAY
+/* The source address is always matched in full, the prefix length of the
+ * key covers the source address followed by the destination prefix.
+ */
+#define EGRESS_STATIC_PREFIX (sizeof(__be32) * 8)
+#define EGRESS_PREFIX_LEN(PREFIX) (EGRESS_STATIC_PREFIX + (PREFIX))
+
+/* Returns the gateway node the traffic from saddr to daddr must be steered
+ * through, or NULL if it is not subject to any egress NAT policy.
+ */
+static __always_inline struct egress_info *
+lookup_ip4_egress_gateway(__be32 saddr, __be32 daddr)
+{
+	struct egress_key key = {
+		.lpm_key = { EGRESS_PREFIX_LEN(32) },
+		.sip = saddr,
+		.dip = daddr,
+	};
+
+	return map_lookup_elem(&cilium_egress_v4, &key);
+}
+#endif /* ENABLE_EGRESS_GATEWAY */
+
+#endif /* __LIB_EGRESS_GATEWAY_H_ */
@@ -0,0 +1,46 @@
+/*
+ *  Copyright (C) 2019 Authors of Cilium
+ *
+ *  This program is free software; you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation; either version 2 of the License, or
+ *  (at your option) any later version.
+ *
+ *  This program is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with this program; if not, write to the Free Software
+ *  Foundation, Inc., 51 Franklin St, Fifth Floor, Boston, MA  02110-1301  USA
+ */
+#ifndef __LIB_ENCRYPT_H_
+#define __LIB_ENCRYPT_H_
+
+#include "common.h"
+#include "maps.h"
+
+#ifdef ENABLE_IPSEC
+/* set_encrypt_mark marks the packet for encryption by the xfrm policies
+ * installed by the agent with the key currently configured in
+ * cilium_encrypt_state. Returns DROP_NO_ENCRYPT_KEY if no key has been
+ * configured yet, the packet must then be dropped rather than be sent
+ * unencrypted.
+ */
+static inline int __inline__ set_encrypt_mark(struct __sk_buff *skb)
+{
+	struct encrypt_config *cfg;
+	__u32 key = 0;
+
+	cfg = map_lookup_elem(&cilium_encrypt_state, &key);
+	if (!cfg || !cfg->encrypt_key)
+		return DROP_NO_ENCRYPT_KEY;
+
+	skb->mark = MARK_MAGIC_ENCRYPT |
+		    ((__u32) cfg->encrypt_key << MARK_MAGIC_KEY_SHIFT);
+	return 0;
+}
+#endif /* ENABLE_IPSEC */
+
+#endif /* __LIB_ENCRYPT_H_ */
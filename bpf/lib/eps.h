@@ -24,16 +24,22 @@
 #include "maps.h"
 
 static __always_inline struct endpoint_info *
-lookup_ip6_endpoint(struct ipv6hdr *ip6)
+__lookup_ip6_endpoint(union v6addr *ip6)
 {
 	struct endpoint_key key = {};
 
-	key.ip6 = *((union v6addr *) &ip6->daddr);
+	key.ip6 = *ip6;
 	key.family = ENDPOINT_KEY_IPV6;
 
 	return map_lookup_elem(&cilium_lxc, &key);
 }
 
+static __always_inline struct endpoint_info *
+lookup_ip6_endpoint(struct ipv6hdr *ip6)
+{
+	return __lookup_ip6_endpoint((union v6addr *) &ip6->daddr);
+}
+
 static __always_inline struct endpoint_info *
 __lookup_ip4_endpoint(uint32_t ip)
 {
@@ -0,0 +1,369 @@
+/*
+ *  Copyright (C) 2019 Authors of Cilium
+ *
+ *  This program is free software; you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation; either version 2 of the License, or
+ *  (at your option) any later version.
+ *
+ *  This program is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with this program; if not, write to the Free Software
+ *  Foundation, Inc., 51 Franklin St, Fifth Floor, Boston, MA  02110-1301  USA
+ */
+#ifndef __LIB_HOST_FIREWALL_H_
+#define __LIB_HOST_FIREWALL_H_
+
+#include "common.h"
+#include "maps.h"
+#include "eps.h"
+#include "conntrack.h"
+#include "policy.h"
+#include "drop.h"
+#include "trace.h"
+
+#ifdef HOST_ENDPOINT
+/* The host endpoint enforces the ingress policy of the node on packets
+ * received on the native device and destined to one of the node's own
+ * addresses. Connections initiated by the node are tracked on egress of the
+ * native device so that their replies are accepted without requiring an
+ * explicit ingress rule. Egress policy is not enforced.
+ */
+
+#ifdef HAVE_LRU_MAP_TYPE
+#define CT_MAP_TYPE BPF_MAP_TYPE_LRU_HASH
+#else
+#define CT_MAP_TYPE BPF_MAP_TYPE_HASH
+#endif
+
+#ifdef ENABLE_IPV6
+struct bpf_elf_map __section_maps CT_MAP_TCP6 = {
+	.type		= CT_MAP_TYPE,
+	.size_key	= sizeof(struct ipv6_ct_tuple),
+	.size_value	= sizeof(struct ct_entry),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CT_MAP_SIZE_TCP,
+#ifndef HAVE_LRU_MAP_TYPE
+	.flags		= CONDITIONAL_PREALLOC,
+#endif
+};
+
+struct bpf_elf_map __section_maps CT_MAP_ANY6 = {
+	.type		= CT_MAP_TYPE,
+	.size_key	= sizeof(struct ipv6_ct_tuple),
+	.size_value	= sizeof(struct ct_entry),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CT_MAP_SIZE_ANY,
+#ifndef HAVE_LRU_MAP_TYPE
+	.flags		= CONDITIONAL_PREALLOC,
+#endif
+};
+
+static inline struct bpf_elf_map *
+get_ct_map6(struct ipv6_ct_tuple *tuple)
+{
+	if (tuple->nexthdr == IPPROTO_TCP) {
+		return &CT_MAP_TCP6;
+	}
+	return &CT_MAP_ANY6;
+}
+
+static inline bool __inline__ ipv6_is_host_addr(union v6addr *addr)
+{
+	struct endpoint_key key = {};
+	struct endpoint_info *ep;
+
+	key.ip6 = *addr;
+	key.family = ENDPOINT_KEY_IPV6;
+
+	ep = map_lookup_elem(&cilium_lxc, &key);
+	return ep && (ep->flags & ENDPOINT_F_HOST);
+}
+
+static inline int __inline__
+ipv6_host_policy_ingress(struct __sk_buff *skb, __u32 src_identity)
+{
+	struct ipv6_ct_tuple tuple = {};
+	struct ct_state ct_state = {};
+	struct ct_state ct_state_new = {};
+	struct ep_config *cfg;
+	void *data, *data_end;
+	struct ipv6hdr *ip6;
+	int ret, verdict, reason, l4_off, hdrlen;
+	__u32 monitor = 0;
+
+	cfg = lookup_ep_config();
+	if (!cfg)
+		return DROP_NO_CONFIG;
+
+	if (!revalidate_data(skb, &data, &data_end, &ip6))
+		return DROP_INVALID;
+
+	if (!src_identity)
+		src_identity = WORLD_ID;
+
+	tuple.nexthdr = ip6->nexthdr;
+	ipv6_addr_copy(&tuple.daddr, (union v6addr *) &ip6->daddr);
+	ipv6_addr_copy(&tuple.saddr, (union v6addr *) &ip6->saddr);
+
+	hdrlen = ipv6_hdrlen(skb, ETH_HLEN, &tuple.nexthdr);
+	if (hdrlen < 0)
+		return hdrlen;
+
+	l4_off = ETH_HLEN + hdrlen;
+
+	ret = ct_lookup6(get_ct_map6(&tuple), &tuple, skb, l4_off, CT_INGRESS,
+			 &ct_state, &monitor);
+	if (ret < 0)
+		return ret;
+
+	reason = ret;
+
+	if (!(cfg->flags & EP_F_SKIP_POLICY_INGRESS))
+		verdict = policy_can_access_ingress(skb, src_identity,
+				policy_icmp_dport(skb, l4_off, tuple.nexthdr, tuple.dport),
+				tuple.nexthdr, sizeof(tuple.saddr),
+				&tuple.saddr, false);
+	else
+		verdict = TC_ACT_OK;
+
+	/* Reply packets and related packets are allowed, all others must be
+	 * permitted by policy */
+	if (ret != CT_REPLY && ret != CT_RELATED && verdict < 0) {
+		if (ret == CT_ESTABLISHED)
+			ct_delete6(get_ct_map6(&tuple), &tuple, skb);
+
+		return send_drop_notify(skb, src_identity, SECLABEL, LXC_ID,
+					0, verdict, TC_ACT_SHOT, METRIC_INGRESS);
+	}
+
+	if (ret == CT_NEW) {
+		ct_state_new.orig_dport = tuple.dport;
+		ct_state_new.src_sec_id = src_identity;
+		ret = ct_create6(get_ct_map6(&tuple), &tuple, skb, CT_INGRESS,
+				 &ct_state_new);
+		if (IS_ERR(ret))
+			return ret;
+	}
+
+	send_trace_notify(skb, TRACE_TO_HOST, src_identity, SECLABEL, LXC_ID,
+			  0, reason, monitor);
+
+	return TC_ACT_OK;
+}
+
+static inline int __inline__ ipv6_host_track_egress(struct __sk_buff *skb)
+{
+	struct ipv6_ct_tuple tuple = {};
+	struct ct_state ct_state = {};
+	struct ct_state ct_state_new = {};
+	void *data, *data_end;
+	struct ipv6hdr *ip6;
+	int ret, l4_off, hdrlen;
+	__u32 monitor = 0;
+
+	if (!revalidate_data(skb, &data, &data_end, &ip6))
+		return DROP_INVALID;
+
+	ipv6_addr_copy(&tuple.saddr, (union v6addr *) &ip6->saddr);
+	if (!ipv6_is_host_addr(&tuple.saddr))
+		return TC_ACT_OK;
+
+	tuple.nexthdr = ip6->nexthdr;
+	ipv6_addr_copy(&tuple.daddr, (union v6addr *) &ip6->daddr);
+
+	hdrlen = ipv6_hdrlen(skb, ETH_HLEN, &tuple.nexthdr);
+	if (hdrlen < 0)
+		return hdrlen;
+
+	l4_off = ETH_HLEN + hdrlen;
+
+	ret = ct_lookup6(get_ct_map6(&tuple), &tuple, skb, l4_off, CT_EGRESS,
+			 &ct_state, &monitor);
+	if (ret < 0)
+		return ret;
+
+	if (ret == CT_NEW) {
+		ct_state_new.src_sec_id = SECLABEL;
+		ret = ct_create6(get_ct_map6(&tuple), &tuple, skb, CT_EGRESS,
+				 &ct_state_new);
+		if (IS_ERR(ret))
+			return ret;
+	}
+
+	return TC_ACT_OK;
+}
+#endif /* ENABLE_IPV6 */
+
+#ifdef ENABLE_IPV4
+struct bpf_elf_map __section_maps CT_MAP_TCP4 = {
+	.type		= CT_MAP_TYPE,
+	.size_key	= sizeof(struct ipv4_ct_tuple),
+	.size_value	= sizeof(struct ct_entry),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CT_MAP_SIZE_TCP,
+#ifndef HAVE_LRU_MAP_TYPE
+	.flags		= CONDITIONAL_PREALLOC,
+#endif
+};
+
+struct bpf_elf_map __section_maps CT_MAP_ANY4 = {
+	.type		= CT_MAP_TYPE,
+	.size_key	= sizeof(struct ipv4_ct_tuple),
+	.size_value	= sizeof(struct ct_entry),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CT_MAP_SIZE_ANY,
+#ifndef HAVE_LRU_MAP_TYPE
+	.flags		= CONDITIONAL_PREALLOC,
+#endif
+};
+
+static inline struct bpf_elf_map *
+get_ct_map4(struct ipv4_ct_tuple *tuple)
+{
+	if (tuple->nexthdr == IPPROTO_TCP) {
+		return &CT_MAP_TCP4;
+	}
+	return &CT_MAP_ANY4;
+}
+
+static inline int __inline__
+ipv4_host_policy_ingress(struct __sk_buff *skb, __u32 src_identity)
+{
+	struct ipv4_ct_tuple tuple = {};
+	struct ct_state ct_state = {};
+	struct ct_state ct_state_new = {};
+	struct ep_config *cfg;
+	void *data, *data_end;
+	struct iphdr *ip4;
+	int ret, verdict, reason, l4_off;
+	bool is_fragment = false;
+	__be32 orig_sip;
+	__u32 monitor = 0;
+
+	cfg = lookup_ep_config();
+	if (!cfg)
+		return DROP_NO_CONFIG;
+
+	if (!revalidate_data(skb, &data, &data_end, &ip4))
+		return DROP_INVALID;
+
+	if (!src_identity)
+		src_identity = WORLD_ID;
+
+	tuple.nexthdr = ip4->protocol;
+	tuple.daddr = ip4->daddr;
+	tuple.saddr = ip4->saddr;
+	orig_sip = ip4->saddr;
+
+	l4_off = ETH_HLEN + ipv4_hdrlen(ip4);
+	is_fragment = ipv4_is_fragment(ip4);
+
+	ret = ct_lookup4(get_ct_map4(&tuple), &tuple, skb, l4_off, CT_INGRESS,
+			 &ct_state, &monitor);
+	if (ret < 0)
+		return ret;
+
+	reason = ret;
+
+	if (!(cfg->flags & EP_F_SKIP_POLICY_INGRESS))
+		verdict = policy_can_access_ingress(skb, src_identity,
+						    policy_icmp_dport(skb, l4_off, tuple.nexthdr,
+								      tuple.dport),
+						    tuple.nexthdr,
+						    sizeof(orig_sip),
+						    &orig_sip, is_fragment);
+	else
+		verdict = TC_ACT_OK;
+
+	/* Reply packets and related packets are allowed, all others must be
+	 * permitted by policy */
+	if (ret != CT_REPLY && ret != CT_RELATED && verdict < 0) {
+		if (ret == CT_ESTABLISHED)
+			ct_delete4(get_ct_map4(&tuple), &tuple, skb);
+
+		return send_drop_notify(skb, src_identity, SECLABEL, LXC_ID,
+					0, verdict, TC_ACT_SHOT, METRIC_INGRESS);
+	}
+
+	if (ret == CT_NEW) {
+		ct_state_new.orig_dport = tuple.dport;
+		ct_state_new.src_sec_id = src_identity;
+		ret = ct_create4(get_ct_map4(&tuple), &tuple, skb, CT_INGRESS,
+				 &ct_state_new);
+		if (IS_ERR(ret))
+			return ret;
+	}
+
+	send_trace_notify(skb, TRACE_TO_HOST, src_identity, SECLABEL, LXC_ID,
+			  0, reason, monitor);
+
+	return TC_ACT_OK;
+}
+
+static inline int __inline__ ipv4_host_track_egress(struct __sk_buff *skb)
+{
+	struct ipv4_ct_tuple tuple = {};
+	struct ct_state ct_state = {};
+	struct ct_state ct_state_new = {};
+	struct endpoint_info *ep;
+	void *data, *data_end;
+	struct iphdr *ip4;
+	int ret, l4_off;
+	__u32 monitor = 0;
+
+	if (!revalidate_data(skb, &data, &data_end, &ip4))
+		return DROP_INVALID;
+
+	ep = __lookup_ip4_endpoint(ip4->saddr);
+	if (!ep || !(ep->flags & ENDPOINT_F_HOST))
+		return TC_ACT_OK;
+
+	tuple.nexthdr = ip4->protocol;
+	tuple.daddr = ip4->daddr;
+	tuple.saddr = ip4->saddr;
+
+	l4_off = ETH_HLEN + ipv4_hdrlen(ip4);
+
+	ret = ct_lookup4(get_ct_map4(&tuple), &tuple, skb, l4_off, CT_EGRESS,
+			 &ct_state, &monitor);
+	if (ret < 0)
+		return ret;
+
+	if (ret == CT_NEW) {
+		ct_state_new.src_sec_id = SECLABEL;
+		ret = ct_create4(get_ct_map4(&tuple), &tuple, skb, CT_EGRESS,
+				 &ct_state_new);
+		if (IS_ERR(ret))
+			return ret;
+	}
+
+	return TC_ACT_OK;
+}
+#endif /* ENABLE_IPV4 */
+
+#else /* HOST_ENDPOINT */
+
+#ifdef ENABLE_IPV6
+static inline int __inline__
+ipv6_host_policy_ingress(struct __sk_buff *skb, __u32 src_identity)
+{
+	return TC_ACT_OK;
+}
+#endif /* ENABLE_IPV6 */
+
+#ifdef ENABLE_IPV4
+static inline int __inline__
+ipv4_host_policy_ingress(struct __sk_buff *skb, __u32 src_identity)
+{
+	return TC_ACT_OK;
+}
+#endif /* ENABLE_IPV4 */
+
+#endif /* HOST_ENDPOINT */
+
+#endif /* __LIB_HOST_FIREWALL_H_ */
@@ -32,6 +32,7 @@
 
 #include "csum.h"
 #include "conntrack.h"
+#include "jhash.h"
 
 #define CILIUM_LB_MAP_MAX_FE		256
 
@@ -62,6 +63,24 @@ struct bpf_elf_map __section_maps cilium_lb6_rr_seq = {
 	.max_elem       = CILIUM_LB_MAP_MAX_FE,
 	.flags		= CONDITIONAL_PREALLOC,
 };
+
+struct bpf_elf_map __section_maps cilium_lb6_maglev = {
+	.type		= BPF_MAP_TYPE_HASH,
+	.size_key	= sizeof(struct lb6_key),
+	.size_value	= sizeof(struct lb_maglev_lut),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= LB_MAGLEV_MAP_MAX_ENTRIES,
+	.flags		= CONDITIONAL_PREALLOC,
+};
+
+struct bpf_elf_map __section_maps cilium_lb6_affinity = {
+	.type		= BPF_MAP_TYPE_HASH,
+	.size_key	= sizeof(struct lb6_affinity_key),
+	.size_value	= sizeof(struct lb_affinity_val),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CILIUM_LB_MAP_MAX_ENTRIES,
+	.flags		= CONDITIONAL_PREALLOC,
+};
 #endif /* ENABLE_IPV6 */
 
 #ifdef ENABLE_IPV4
@@ -91,6 +110,24 @@ struct bpf_elf_map __section_maps cilium_lb4_rr_seq = {
 	.max_elem       = CILIUM_LB_MAP_MAX_FE,
 	.flags		= CONDITIONAL_PREALLOC,
 };
+
+struct bpf_elf_map __section_maps cilium_lb4_maglev = {
+	.type		= BPF_MAP_TYPE_HASH,
+	.size_key	= sizeof(struct lb4_key),
+	.size_value	= sizeof(struct lb_maglev_lut),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= LB_MAGLEV_MAP_MAX_ENTRIES,
+	.flags		= CONDITIONAL_PREALLOC,
+};
+
+struct bpf_elf_map __section_maps cilium_lb4_affinity = {
+	.type		= BPF_MAP_TYPE_HASH,
+	.size_key	= sizeof(struct lb4_affinity_key),
+	.size_value	= sizeof(struct lb_affinity_val),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= CILIUM_LB_MAP_MAX_ENTRIES,
+	.flags		= CONDITIONAL_PREALLOC,
+};
 #endif /* ENABLE_IPV4 */
 
 
@@ -436,6 +473,68 @@ static inline int __inline__ lb6_xlate(struct __sk_buff *skb, union v6addr *new_
 	return TC_ACT_OK;
 }
 
+/* Returns the backend slot selected by the Maglev lookup table of the
+ * service, or 0 if the service does not use Maglev.
+ */
+static inline __u16 __inline__ lb6_maglev_slave(struct lb6_key *key,
+						struct ipv6_ct_tuple *tuple,
+						__u16 count)
+{
+	struct lb_maglev_lut *lut;
+	__u32 hash, saddr, daddr;
+	__u16 slave;
+
+	lut = map_lookup_elem(&cilium_lb6_maglev, key);
+	if (!lut)
+		return 0;
+
+	saddr = tuple->saddr.p1 ^ tuple->saddr.p2 ^ tuple->saddr.p3 ^ tuple->saddr.p4;
+	daddr = tuple->daddr.p1 ^ tuple->daddr.p2 ^ tuple->daddr.p3 ^ tuple->daddr.p4;
+	hash = jhash_3words(saddr, daddr,
+			    ((__u32) tuple->sport << 16) | tuple->dport,
+			    tuple->nexthdr);
+	slave = lut->backends[hash % LB_MAGLEV_LUT_SIZE];
+	if (slave > count)
+		return 0;
+
+	return slave;
+}
+
+/* Returns the backend slot previously selected for the client if it has not
+ * expired and is still valid for the service, 0 otherwise.
+ */
+static inline __u16 __inline__ lb6_affinity_slave(union v6addr *client, __u16 rev_nat_id,
+						  __u16 count)
+{
+	struct lb6_affinity_key key = {
+		.rev_nat_id = rev_nat_id,
+	};
+	struct lb_affinity_val *val;
+
+	ipv6_addr_copy(&key.client_ip, client);
+	val = map_lookup_elem(&cilium_lb6_affinity, &key);
+	if (val && val->lifetime >= bpf_ktime_get_sec() &&
+	    val->slave != 0 && val->slave <= count)
+		return val->slave;
+
+	return 0;
+}
+
+static inline void __inline__ lb6_update_affinity(union v6addr *client, __u16 rev_nat_id,
+						   __u16 slave, __u32 timeout)
+{
+	struct lb6_affinity_key key = {
+		.rev_nat_id = rev_nat_id,
+	};
+	struct lb_affinity_val val = {
+		.lifetime = bpf_ktime_get_sec() + timeout,
+		.slave = slave,
+	};
+
+	ipv6_addr_copy(&key.client_ip, client);
+	map_update_elem(&cilium_lb6_affinity, &key, &val, 0);
+}
+
 static inline int __inline__ lb6_local(void *map, struct __sk_buff *skb, int l3_off, int l4_off,
 				       struct csum_offset *csum_off, struct lb6_key *key,
 				       struct ipv6_ct_tuple *tuple, struct lb6_service *svc,
@@ -449,7 +548,17 @@ static inline int __inline__ lb6_local(void *map, struct __sk_buff *skb, int l3_
 	ret = ct_lookup6(map, tuple, skb, l4_off, CT_SERVICE, state, &monitor);
 	switch(ret) {
 	case CT_NEW:
-		state->slave = lb6_select_slave(skb, key, svc->count, svc->weight);
+		state->slave = 0;
+		if (svc->affinity_timeout)
+			state->slave = lb6_affinity_slave(&tuple->saddr, svc->rev_nat_index,
+							  svc->count);
+		if (state->slave == 0)
+			state->slave = lb6_maglev_slave(key, tuple, svc->count);
+		if (state->slave == 0)
+			state->slave = lb6_select_slave(skb, key, svc->count, svc->weight);
+		if (svc->affinity_timeout)
+			lb6_update_affinity(&tuple->saddr, svc->rev_nat_index,
+					    state->slave, svc->affinity_timeout);
 		ret = ct_create6(map, tuple, skb, CT_SERVICE, state);
 		/* Fail closed, if the conntrack entry create fails drop
 		 * service lookup.
@@ -718,6 +827,66 @@ lb4_xlate(struct __sk_buff *skb, __be32 *new_daddr, __be32 *new_saddr,
 	return TC_ACT_OK;
 }
 
+/* Returns the backend slot selected by the Maglev lookup table of the
+ * service, or 0 if the service does not use Maglev.
+ */
+static inline __u16 __inline__ lb4_maglev_slave(struct lb4_key *key,
+						struct ipv4_ct_tuple *tuple,
+						__u16 count)
+{
+	struct lb_maglev_lut *lut;
+	__u32 hash;
+	__u16 slave;
+
+	lut = map_lookup_elem(&cilium_lb4_maglev, key);
+	if (!lut)
+		return 0;
+
+	hash = jhash_3words(tuple->saddr, tuple->daddr,
+			    ((__u32) tuple->sport << 16) | tuple->dport,
+			    tuple->nexthdr);
+	slave = lut->backends[hash % LB_MAGLEV_LUT_SIZE];
+	if (slave > count)
+		return 0;
+
+	return slave;
+}
+
+/* Returns the backend slot previously selected for the client if it has not
+ * expired and is still valid for the service, 0 otherwise.
+ */
+static inline __u16 __inline__ lb4_affinity_slave(__be32 client, __u16 rev_nat_id,
+						  __u16 count)
+{
+	struct lb4_affinity_key key = {
+		.client_ip = client,
+		.rev_nat_id = rev_nat_id,
+	};
+	struct lb_affinity_val *val;
+
+	val = map_lookup_elem(&cilium_lb4_affinity, &key);
+	if (val && val->lifetime >= bpf_ktime_get_sec() &&
+	    val->slave != 0 && val->slave <= count)
+		return val->slave;
+
+	return 0;
+}
+
+static inline void __inline__ lb4_update_affinity(__be32 client, __u16 rev_nat_id,
+						   __u16 slave, __u32 timeout)
+{
+	struct lb4_affinity_key key = {
+		.client_ip = client,
+		.rev_nat_id = rev_nat_id,
+	};
+	struct lb_affinity_val val = {
+		.lifetime = bpf_ktime_get_sec() + timeout,
+		.slave = slave,
+	};
+
+	map_update_elem(&cilium_lb4_affinity, &key, &val, 0);
+}
+
 static inline int __inline__ lb4_local(void *map, struct __sk_buff *skb,
 				       int l3_off, int l4_off,
 				       struct csum_offset *csum_off, struct lb4_key *key,
@@ -732,7 +901,17 @@ static inline int __inline__ lb4_local(void *map, struct __sk_buff *skb,
 	ret = ct_lookup4(map, tuple, skb, l4_off, CT_SERVICE, state, &monitor);
 	switch(ret) {
 	case CT_NEW:
-		state->slave = lb4_select_slave(skb, key, svc->count, svc->weight);
+		state->slave = 0;
+		if (svc->affinity_timeout)
+			state->slave = lb4_affinity_slave(saddr, svc->rev_nat_index,
+							  svc->count);
+		if (state->slave == 0)
+			state->slave = lb4_maglev_slave(key, tuple, svc->count);
+		if (state->slave == 0)
+			state->slave = lb4_select_slave(skb, key, svc->count, svc->weight);
+		if (svc->affinity_timeout)
+			lb4_update_affinity(saddr, svc->rev_nat_index, state->slave,
+					    svc->affinity_timeout);
 		ret = ct_create4(map, tuple, skb, CT_SERVICE, state);
 		/* Fail closed, if the conntrack entry create fails drop
 		 * service lookup.
@@ -42,6 +42,42 @@ struct bpf_elf_map __section_maps cilium_metrics = {
 	.flags		= CONDITIONAL_PREALLOC,
 };
 
+#ifdef POLICY_AUDIT_MODE
+/* Packets which would have been dropped by policy per endpoint */
+struct bpf_elf_map __section_maps cilium_policy_audit = {
+	.type		= BPF_MAP_TYPE_PERCPU_HASH,
+	.size_key	= sizeof(struct policy_audit_key),
+	.size_value	= sizeof(struct policy_audit_value),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= POLICY_AUDIT_MAP_SIZE,
+	.flags		= CONDITIONAL_PREALLOC,
+};
+#endif
+
+#ifdef ENABLE_BANDWIDTH_MANAGER
+/* Egress bandwidth limit and departure time state of endpoints */
+struct bpf_elf_map __section_maps cilium_throttle = {
+	.type		= BPF_MAP_TYPE_HASH,
+	.size_key	= sizeof(struct throttle_key),
+	.size_value	= sizeof(struct throttle_value),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= THROTTLE_MAP_SIZE,
+	.flags		= CONDITIONAL_PREALLOC,
+};
+#endif
+
+#ifdef ENABLE_EGRESS_GATEWAY
+/* Pod source IP and destination prefix -> gateway node of egress NAT policies */
+struct bpf_elf_map __section_maps cilium_egress_v4 = {
+	.type		= BPF_MAP_TYPE_LPM_TRIE,
+	.size_key	= sizeof(struct egress_key),
+	.size_value	= sizeof(struct egress_info),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= EGRESS_MAP_SIZE,
+	.flags		= BPF_F_NO_PREALLOC,
+};
+#endif
+
 /* Global map to jump into policy enforcement of receiving endpoint */
 struct bpf_elf_map __section_maps cilium_policy = {
 	.type		= BPF_MAP_TYPE_PROG_ARRAY,
@@ -132,6 +168,16 @@ struct bpf_elf_map __section_maps cilium_tunnel_map = {
 
 #endif
 
+#ifdef ENABLE_IPSEC
+struct bpf_elf_map __section_maps cilium_encrypt_state = {
+	.type		= BPF_MAP_TYPE_ARRAY,
+	.size_key	= sizeof(__u32),
+	.size_value	= sizeof(struct encrypt_config),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= 1,
+};
+#endif
+
 #ifdef HAVE_LPM_MAP_TYPE
 #define LPM_MAP_TYPE BPF_MAP_TYPE_LPM_TRIE
 #else
@@ -0,0 +1,356 @@
+/*
+ *  Copyright (C) 2019 Authors of Cilium
+ *
+ *  This program is free software; you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation; either version 2 of the License, or
+ *  (at your option) any later version.
+ *
+ *  This program is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with this program; if not, write to the Free Software
+ *  Foundation, Inc., 51 Franklin St, Fifth Floor, Boston, MA  02110-1301  USA
+ */
+#ifndef __LIB_NODEPORT_H_
+#define __LIB_NODEPORT_H_
+
+#ifdef ENABLE_NODEPORT
+/* NodePort, externalIPs and LoadBalancer frontends always carry a port */
+#ifndef LB_L4
+#define LB_L4
+#endif
+#endif
+
+#include "common.h"
+#include "maps.h"
+#include "eps.h"
+#include "lb.h"
+
+#ifdef ENABLE_NODEPORT
+/* Connections received on the native device for a NodePort, externalIPs or
+ * LoadBalancer frontend are translated to a backend running on this node,
+ * which preserves the source IP of the client. Replies are translated back
+ * to the frontend on egress of the native device. If a backend on another
+ * node is selected, the packet is passed to the stack untranslated, as the
+ * reply of the backend would not return via this node without SNAT.
+ *
+ * Each translated connection has a forward entry, keyed by the client and
+ * the frontend, which records the selected backend, and a reverse entry,
+ * keyed by the backend and the client, which records the reverse NAT index
+ * of the frontend.
+ */
+#ifdef HAVE_LRU_MAP_TYPE
+#define NODEPORT_MAP_TYPE BPF_MAP_TYPE_LRU_HASH
+#else
+#define NODEPORT_MAP_TYPE BPF_MAP_TYPE_HASH
+#endif
+
+#ifdef ENABLE_IPV6
+struct bpf_elf_map __section_maps cilium_lb6_nodeport = {
+	.type		= NODEPORT_MAP_TYPE,
+	.size_key	= sizeof(struct nodeport_key6),
+	.size_value	= sizeof(struct nodeport_entry),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= NODEPORT_MAP_SIZE,
+#ifndef HAVE_LRU_MAP_TYPE
+	.flags		= CONDITIONAL_PREALLOC,
+#endif
+};
+
+/* nodeport_lb6 translates a packet received on the native device for a
+ * service frontend to a backend on this node. Packets of other traffic are
+ * left untouched.
+ */
+static inline int __inline__ nodeport_lb6(struct __sk_buff *skb, __u8 nexthdr,
+					  int l4_off)
+{
+	struct nodeport_key6 fwd = {}, rev = {};
+	struct nodeport_entry new_entry = {};
+	struct nodeport_entry *entry;
+	struct ipv6_ct_tuple tuple = {};
+	struct csum_offset csum_off = {};
+	struct lb6_service *svc, *slave_svc;
+	struct lb6_key key = {};
+	union v6addr new_daddr;
+	void *data, *data_end;
+	struct ipv6hdr *ip6;
+	__u16 slave = 0;
+	int ret;
+
+	if (nexthdr != IPPROTO_TCP && nexthdr != IPPROTO_UDP)
+		return TC_ACT_OK;
+
+	if (!revalidate_data(skb, &data, &data_end, &ip6))
+		return DROP_INVALID;
+
+	tuple.nexthdr = nexthdr;
+	ipv6_addr_copy(&tuple.saddr, (union v6addr *) &ip6->saddr);
+	ipv6_addr_copy(&tuple.daddr, (union v6addr *) &ip6->daddr);
+
+	ret = lb6_extract_key(skb, &tuple, l4_off, &key, &csum_off, CT_EGRESS);
+	if (IS_ERR(ret))
+		return ret;
+
+	svc = lb6_lookup_service(skb, &key);
+	if (svc == NULL)
+		return TC_ACT_OK;
+
+	ipv6_addr_copy(&fwd.saddr, &tuple.saddr);
+	ipv6_addr_copy(&fwd.daddr, &tuple.daddr);
+	fwd.nexthdr = nexthdr;
+
+	/* load sport + dport */
+	if (skb_load_bytes(skb, l4_off, &fwd.sport, 4) < 0)
+		return DROP_INVALID;
+
+	tuple.sport = fwd.sport;
+	tuple.dport = fwd.dport;
+
+	entry = map_lookup_elem(&cilium_lb6_nodeport, &fwd);
+	if (entry && entry->rev_nat_index == svc->rev_nat_index)
+		slave = entry->slave;
+	if (slave == 0 && svc->affinity_timeout)
+		slave = lb6_affinity_slave(&fwd.saddr, svc->rev_nat_index,
+					   svc->count);
+	if (slave == 0)
+		slave = lb6_maglev_slave(&key, &tuple, svc->count);
+	if (slave == 0)
+		slave = lb6_select_slave(skb, &key, svc->count, svc->weight);
+
+	/* If the backend has been removed, fall back to hash */
+	if (!(slave_svc = lb6_lookup_slave(skb, &key, slave))) {
+		slave = lb6_select_slave(skb, &key, svc->count, svc->weight);
+		if (!(slave_svc = lb6_lookup_slave(skb, &key, slave)))
+			return DROP_NO_SERVICE;
+	}
+
+	ipv6_addr_copy(&new_daddr, &slave_svc->target);
+	if (__lookup_ip6_endpoint(&new_daddr) == NULL)
+		return TC_ACT_OK;
+
+	new_entry.rev_nat_index = svc->rev_nat_index;
+	new_entry.slave = slave;
+	if (map_update_elem(&cilium_lb6_nodeport, &fwd, &new_entry, 0) < 0)
+		return TC_ACT_OK;
+
+	ipv6_addr_copy(&rev.saddr, &new_daddr);
+	ipv6_addr_copy(&rev.daddr, &fwd.saddr);
+	rev.sport = slave_svc->port ? slave_svc->port : fwd.dport;
+	rev.dport = fwd.sport;
+	rev.nexthdr = nexthdr;
+	rev.flags = NODEPORT_F_REVERSE;
+	new_entry.slave = 0;
+	if (map_update_elem(&cilium_lb6_nodeport, &rev, &new_entry, 0) < 0)
+		return TC_ACT_OK;
+
+	if (svc->affinity_timeout)
+		lb6_update_affinity(&fwd.saddr, svc->rev_nat_index, slave,
+				    svc->affinity_timeout);
+
+	return lb6_xlate(skb, &new_daddr, nexthdr, ETH_HLEN, l4_off,
+			 &csum_off, &key, slave_svc);
+}
+
+/* nodeport_rev_nat6 translates the source of a reply of a backend on this
+ * node leaving the native device back to the service frontend.
+ */
+static inline int __inline__ nodeport_rev_nat6(struct __sk_buff *skb)
+{
+	struct ipv6_ct_tuple tuple = {};
+	struct csum_offset csum_off = {};
+	struct nodeport_key6 rev = {};
+	struct nodeport_entry *entry;
+	void *data, *data_end;
+	struct ipv6hdr *ip6;
+	int l4_off, hdrlen;
+	__u8 nexthdr;
+
+	if (!revalidate_data(skb, &data, &data_end, &ip6))
+		return DROP_INVALID;
+
+	nexthdr = ip6->nexthdr;
+	hdrlen = ipv6_hdrlen(skb, ETH_HLEN, &nexthdr);
+	/* Packets which can't belong to a translated connection, e.g.
+	 * fragments, must not be dropped */
+	if (hdrlen < 0 || (nexthdr != IPPROTO_TCP && nexthdr != IPPROTO_UDP))
+		return TC_ACT_OK;
+
+	l4_off = ETH_HLEN + hdrlen;
+	ipv6_addr_copy(&rev.saddr, (union v6addr *) &ip6->saddr);
+	ipv6_addr_copy(&rev.daddr, (union v6addr *) &ip6->daddr);
+	rev.nexthdr = nexthdr;
+	rev.flags = NODEPORT_F_REVERSE;
+
+	/* load sport + dport */
+	if (skb_load_bytes(skb, l4_off, &rev.sport, 4) < 0)
+		return DROP_INVALID;
+
+	entry = map_lookup_elem(&cilium_lb6_nodeport, &rev);
+	if (entry == NULL)
+		return TC_ACT_OK;
+
+	tuple.nexthdr = nexthdr;
+	csum_l4_offset_and_flags(nexthdr, &csum_off);
+
+	return lb6_rev_nat(skb, l4_off, &csum_off, entry->rev_nat_index,
+			   &tuple, 0);
+}
+#endif /* ENABLE_IPV6 */
+
+#ifdef ENABLE_IPV4
+struct bpf_elf_map __section_maps cilium_lb4_nodeport = {
+	.type		= NODEPORT_MAP_TYPE,
+	.size_key	= sizeof(struct nodeport_key4),
+	.size_value	= sizeof(struct nodeport_entry),
+	.pinning	= PIN_GLOBAL_NS,
+	.max_elem	= NODEPORT_MAP_SIZE,
+#ifndef HAVE_LRU_MAP_TYPE
+	.flags		= CONDITIONAL_PREALLOC,
+#endif
+};
+
+/* nodeport_lb4 translates a packet received on the native device for a
+ * service frontend to a backend on this node. Packets of other traffic are
+ * left untouched.
+ */
+static inline int __inline__ nodeport_lb4(struct __sk_buff *skb, int l4_off)
+{
+	struct nodeport_key4 fwd = {}, rev = {};
+	struct nodeport_entry new_entry = {};
+	struct nodeport_entry *entry;
+	struct ipv4_ct_tuple tuple = {};
+	struct csum_offset csum_off = {};
+	struct lb4_service *svc, *slave_svc;
+	struct lb4_key key = {};
+	void *data, *data_end;
+	struct iphdr *ip4;
+	__be32 new_daddr;
+	__u16 slave = 0;
+	int ret;
+
+	if (!revalidate_data(skb, &data, &data_end, &ip4))
+		return DROP_INVALID;
+
+	/* Fragments are not translated, only the first one carries the ports */
+	if ((ip4->protocol != IPPROTO_TCP && ip4->protocol != IPPROTO_UDP) ||
+	    ipv4_is_fragment(ip4))
+		return TC_ACT_OK;
+
+	tuple.nexthdr = ip4->protocol;
+	tuple.saddr = ip4->saddr;
+	tuple.daddr = ip4->daddr;
+
+	ret = lb4_extract_key(skb, &tuple, l4_off, &key, &csum_off, CT_EGRESS);
+	if (IS_ERR(ret))
+		return ret;
+
+	svc = lb4_lookup_service(skb, &key);
+	if (svc == NULL)
+		return TC_ACT_OK;
+
+	fwd.saddr = tuple.saddr;
+	fwd.daddr = tuple.daddr;
+	fwd.nexthdr = tuple.nexthdr;
+
+	/* load sport + dport */
+	if (skb_load_bytes(skb, l4_off, &fwd.sport, 4) < 0)
+		return DROP_INVALID;
+
+	tuple.sport = fwd.sport;
+	tuple.dport = fwd.dport;
+
+	entry = map_lookup_elem(&cilium_lb4_nodeport, &fwd);
+	if (entry && entry->rev_nat_index == svc->rev_nat_index)
+		slave = entry->slave;
+	if (slave == 0 && svc->affinity_timeout)
+		slave = lb4_affinity_slave(fwd.saddr, svc->rev_nat_index,
+					   svc->count);
+	if (slave == 0)
+		slave = lb4_maglev_slave(&key, &tuple, svc->count);
+	if (slave == 0)
+		slave = lb4_select_slave(skb, &key, svc->count, svc->weight);
+
+	/* If the backend has been removed, fall back to hash */
+	if (!(slave_svc = lb4_lookup_slave(skb, &key, slave))) {
+		slave = lb4_select_slave(skb, &key, svc->count, svc->weight);
+		if (!(slave_svc = lb4_lookup_slave(skb, &key, slave)))
+			return DROP_NO_SERVICE;
+	}
+
+	new_daddr = slave_svc->target;
+	if (__lookup_ip4_endpoint(new_daddr) == NULL)
+		return TC_ACT_OK;
+
+	new_entry.rev_nat_index = svc->rev_nat_index;
+	new_entry.slave = slave;
+	if (map_update_elem(&cilium_lb4_nodeport, &fwd, &new_entry, 0) < 0)
+		return TC_ACT_OK;
+
+	rev.saddr = new_daddr;
+	rev.daddr = fwd.saddr;
+	rev.sport = slave_svc->port ? slave_svc->port : fwd.dport;
+	rev.dport = fwd.sport;
+	rev.nexthdr = fwd.nexthdr;
+	rev.flags = NODEPORT_F_REVERSE;
+	new_entry.slave = 0;
+	if (map_update_elem(&cilium_lb4_nodeport, &rev, &new_entry, 0) < 0)
+		return TC_ACT_OK;
+
+	if (svc->affinity_timeout)
+		lb4_update_affinity(fwd.saddr, svc->rev_nat_index, slave,
+				    svc->affinity_timeout);
+
+	return lb4_xlate(skb, &new_daddr, NULL, NULL, fwd.nexthdr, ETH_HLEN,
+			 l4_off, &csum_off, &key, slave_svc);
+}
+
+/* nodeport_rev_nat4 translates the source of a reply of a backend on this
+ * node leaving the native device back to the service frontend.
+ */
+static inline int __inline__ nodeport_rev_nat4(struct __sk_buff *skb)
+{
+	struct ipv4_ct_tuple tuple = {};
+	struct csum_offset csum_off = {};
+	struct ct_state ct_state = {};
+	struct nodeport_key4 rev = {};
+	struct nodeport_entry *entry;
+	void *data, *data_end;
+	struct iphdr *ip4;
+	int l4_off;
+
+	if (!revalidate_data(skb, &data, &data_end, &ip4))
+		return DROP_INVALID;
+
+	if ((ip4->protocol != IPPROTO_TCP && ip4->protocol != IPPROTO_UDP) ||
+	    ipv4_is_fragment(ip4))
+		return TC_ACT_OK;
+
+	l4_off = ETH_HLEN + ipv4_hdrlen(ip4);
+	rev.saddr = ip4->saddr;
+	rev.daddr = ip4->daddr;
+	rev.nexthdr = ip4->protocol;
+	rev.flags = NODEPORT_F_REVERSE;
+
+	/* load sport + dport */
+	if (skb_load_bytes(skb, l4_off, &rev.sport, 4) < 0)
+		return DROP_INVALID;
+
+	entry = map_lookup_elem(&cilium_lb4_nodeport, &rev);
+	if (entry == NULL)
+		return TC_ACT_OK;
+
+	tuple.nexthdr = rev.nexthdr;
+	ct_state.rev_nat_index = entry->rev_nat_index;
+	csum_l4_offset_and_flags(rev.nexthdr, &csum_off);
+
+	return lb4_rev_nat(skb, ETH_HLEN, l4_off, &csum_off, &ct_state,
+			   &tuple, 0);
+}
+#endif /* ENABLE_IPV4 */
+#endif /* ENABLE_NODEPORT */
+
+#endif /* __LIB_NODEPORT_H_ */
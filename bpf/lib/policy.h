@@ -21,6 +21,7 @@
 #include "drop.h"
 #include "eps.h"
 #include "maps.h"
+#include "trace.h"
 
 /**
  * identity_is_reserved is used to determine whether an identity is one of the
@@ -43,6 +44,38 @@ static inline bool identity_is_reserved(__u32 identity)
 	return identity < UNMANAGED_ID;
 }
 
+/* Port ranges are split into blocks of 2^n ports aligned to their size, where
+ * n is a multiple of POLICY_PORT_BLOCK_BITS below 16. The policy key of a
+ * block has the n low-order bits of the port cleared and dport_wildcard set
+ * to n.
+ */
+#define POLICY_PORT_BLOCK_BITS	4
+
+/**
+ * Look up the entries of the port blocks containing dport, from the smallest
+ * to the largest block so that the most specific entry takes precedence.
+ * Resets the dport_wildcard of key before returning.
+ */
+static inline struct policy_entry * __inline__
+__policy_lookup_port_block(void *map, struct policy_key *key, __be16 dport)
+{
+	struct policy_entry *policy = NULL;
+	int bits;
+
+#pragma unroll
+	for (bits = POLICY_PORT_BLOCK_BITS; bits < 16;
+	     bits += POLICY_PORT_BLOCK_BITS) {
+		key->dport = bpf_htons(bpf_ntohs(dport) & (0xffff << bits));
+		key->dport_wildcard = bits;
+		policy = map_lookup_elem(map, key);
+		if (policy)
+			break;
+	}
+
+	key->dport_wildcard = 0;
+	return policy;
+}
+
 #ifdef SOCKMAP
 static inline int __inline__
 policy_sk_egress(__u32 identity, __u32 ip,  __u16 dport)
@@ -56,6 +89,7 @@ policy_sk_egress(__u32 identity, __u32 ip,  __u16 dport)
 		.dport = dport,
 		.protocol = proto,
 		.egress = !dir,
+		.dport_wildcard = 0,
 		.pad = 0,
 	};
 
@@ -66,16 +100,40 @@ policy_sk_egress(__u32 identity, __u32 ip,  __u16 dport)
 	if (likely(policy)) {
 		/* FIXME: Need byte counter */
 		__sync_fetch_and_add(&policy->packets, 1);
+		if (unlikely(policy->deny))
+			return DROP_POLICY_DENY;
 		goto get_proxy_port;
 	}
 
-	/* If L4 policy check misses, fall back to L3. */
+	/* Fall back to the entries matching port ranges. */
+	policy = __policy_lookup_port_block(map, &key, dport);
+	if (likely(policy)) {
+		/* FIXME: Need byte counter */
+		__sync_fetch_and_add(&policy->packets, 1);
+		if (unlikely(policy->deny))
+			return DROP_POLICY_DENY;
+		goto get_proxy_port;
+	}
+
+	/* Fall back to the entry matching all ports of the protocol. */
 	key.dport = 0;
+	policy = map_lookup_elem(map, &key);
+	if (likely(policy)) {
+		/* FIXME: Need byte counter */
+		__sync_fetch_and_add(&policy->packets, 1);
+		if (unlikely(policy->deny))
+			return DROP_POLICY_DENY;
+		goto get_proxy_port;
+	}
+
+	/* If L4 policy check misses, fall back to L3. */
 	key.protocol = 0;
 	policy = map_lookup_elem(map, &key);
 	if (likely(policy)) {
 		/* FIXME: Need byte counter */
 		__sync_fetch_and_add(&policy->packets, 1);
+		if (unlikely(policy->deny))
+			return DROP_POLICY_DENY;
 		return TC_ACT_OK;
 	}
 
@@ -86,6 +144,8 @@ policy_sk_egress(__u32 identity, __u32 ip,  __u16 dport)
 	if (likely(policy)) {
 		/* FIXME: Use per cpu counters */
 		__sync_fetch_and_add(&policy->packets, 1);
+		if (unlikely(policy->deny))
+			return DROP_POLICY_DENY;
 		goto get_proxy_port;
 	}
 	return DROP_POLICY;
@@ -97,6 +157,58 @@ get_proxy_port:
 }
 #else
 
+/* ICMP code used in policy keys to match all codes of an ICMP type */
+#define ICMP_CODE_ANY 0xff
+
+/**
+ * For ICMP and ICMPv6, policy is enforced on the ICMP type and code, which
+ * are carried in place of the destination port in the policy key: the type
+ * in the upper and the code in the lower 8 bits. For all other protocols,
+ * the destination port is returned unmodified.
+ */
+static inline __be16 __inline__
+policy_icmp_dport(struct __sk_buff *skb, int l4_off, __u8 proto, __be16 dport)
+{
+	__u8 icmp[2];
+
+	if (proto != IPPROTO_ICMP && proto != IPPROTO_ICMPV6)
+		return dport;
+
+	if (skb_load_bytes(skb, l4_off, icmp, sizeof(icmp)) < 0)
+		return dport;
+
+	return bpf_htons((__u16) icmp[0] << 8 | icmp[1]);
+}
+
+static inline struct policy_entry * __inline__
+__policy_lookup_l4(void *map, struct policy_key *key, __be16 dport, __u8 proto)
+{
+	struct policy_entry *policy;
+
+	key->dport = dport;
+	key->protocol = proto;
+	policy = map_lookup_elem(map, key);
+	if (policy)
+		return policy;
+
+	if (proto == IPPROTO_ICMP || proto == IPPROTO_ICMPV6) {
+		/* Fall back to the entry matching all codes of the ICMP type. */
+		key->dport = dport | bpf_htons(ICMP_CODE_ANY);
+		return map_lookup_elem(map, key);
+	}
+
+	/* Fall back to the entries matching port ranges. */
+	policy = __policy_lookup_port_block(map, key, dport);
+	if (policy)
+		return policy;
+
+	/* Fall back to the entry matching all ports of the protocol, which
+	 * represents port ranges spanning all ports.
+	 */
+	key->dport = 0;
+	return map_lookup_elem(map, key);
+}
+
 static inline int __inline__
 __policy_can_access(void *map, struct __sk_buff *skb, __u32 identity,
 		    __u16 dport, __u8 proto, size_t cidr_addr_size,
@@ -109,11 +221,12 @@ __policy_can_access(void *map, struct __sk_buff *skb, __u32 identity,
 		.dport = dport,
 		.protocol = proto,
 		.egress = !dir,
+		.dport_wildcard = 0,
 		.pad = 0,
 	};
 
 	if (!is_fragment) {
-		policy = map_lookup_elem(map, &key);
+		policy = __policy_lookup_l4(map, &key, dport, proto);
 		if (likely(policy)) {
 			cilium_dbg3(skb, DBG_L4_CREATE, identity, SECLABEL,
 				    dport << 16 | proto);
@@ -121,6 +234,8 @@ __policy_can_access(void *map, struct __sk_buff *skb, __u32 identity,
 			/* FIXME: Use per cpu counters */
 			__sync_fetch_and_add(&policy->packets, 1);
 			__sync_fetch_and_add(&policy->bytes, skb->len);
+			if (unlikely(policy->deny))
+				return DROP_POLICY_DENY;
 			goto get_proxy_port;
 		}
 	}
@@ -133,18 +248,20 @@ __policy_can_access(void *map, struct __sk_buff *skb, __u32 identity,
 		/* FIXME: Use per cpu counters */
 		__sync_fetch_and_add(&policy->packets, 1);
 		__sync_fetch_and_add(&policy->bytes, skb->len);
+		if (unlikely(policy->deny))
+			return DROP_POLICY_DENY;
 		return TC_ACT_OK;
 	}
 
 	if (!is_fragment) {
 		key.sec_label = 0;
-		key.dport = dport;
-		key.protocol = proto;
-		policy = map_lookup_elem(map, &key);
+		policy = __policy_lookup_l4(map, &key, dport, proto);
 		if (likely(policy)) {
 			/* FIXME: Use per cpu counters */
 			__sync_fetch_and_add(&policy->packets, 1);
 			__sync_fetch_and_add(&policy->bytes, skb->len);
+			if (unlikely(policy->deny))
+				return DROP_POLICY_DENY;
 			goto get_proxy_port;
 		}
 	}
@@ -163,6 +280,49 @@ allow:
 	return TC_ACT_OK;
 }
 
+#if defined POLICY_AUDIT_MODE && defined LXC_ID
+/**
+ * policy_audit
+ * @skb:	packet which was denied by policy
+ * @src:	source identity
+ * @dst:	destination identity
+ * @verdict:	policy verdict for this packet
+ * @dir:	METRIC_INGRESS or METRIC_EGRESS
+ *
+ * In policy audit mode, packets denied by policy are accounted for in the
+ * policy audit map and reported via a trace notification, but not dropped.
+ *
+ * Returns TC_ACT_OK if the policy verdict was overridden, otherwise the
+ * original verdict.
+ */
+static inline int __inline__
+policy_audit(struct __sk_buff *skb, __u32 src, __u32 dst, int verdict, __u8 dir)
+{
+	struct policy_audit_value *entry, new_entry = {};
+	struct policy_audit_key key = {
+		.lxc_id = LXC_ID,
+		.dir = dir,
+	};
+
+	if (verdict != DROP_POLICY && verdict != DROP_POLICY_DENY)
+		return verdict;
+
+	if ((entry = map_lookup_elem(&cilium_policy_audit, &key))) {
+		entry->count += 1;
+		entry->bytes += (__u64)skb->len;
+	} else {
+		new_entry.count = 1;
+		new_entry.bytes = (__u64)skb->len;
+		map_update_elem(&cilium_policy_audit, &key, &new_entry, 0);
+	}
+
+	send_trace_notify(skb, TRACE_POLICY_AUDIT, src, dst, 0, 0, -verdict,
+			  TRACE_PAYLOAD_LEN);
+
+	return TC_ACT_OK;
+}
+#endif /* POLICY_AUDIT_MODE && LXC_ID */
+
 /**
  * Determine whether the policy allows this traffic on ingress.
  * @arg skb		Packet to allow or deny
@@ -192,6 +352,10 @@ policy_can_access_ingress(struct __sk_buff *skb, __u32 src_identity,
 
 	cilium_dbg(skb, DBG_POLICY_DENIED, src_identity, SECLABEL);
 
+#if defined POLICY_AUDIT_MODE && defined LXC_ID
+	ret = policy_audit(skb, src_identity, SECLABEL, ret, METRIC_INGRESS);
+#endif
+
 #ifdef IGNORE_DROP
 	ret = TC_ACT_OK;
 #endif
@@ -211,6 +375,10 @@ policy_can_egress(struct __sk_buff *skb, __u32 identity, __u16 dport, __u8 proto
 
 	cilium_dbg(skb, DBG_POLICY_DENIED, SECLABEL, identity);
 
+#ifdef POLICY_AUDIT_MODE
+	ret = policy_audit(skb, SECLABEL, identity, ret, METRIC_EGRESS);
+#endif
+
 #ifdef IGNORE_DROP
 	ret = TC_ACT_OK;
 #endif
@@ -219,31 +387,37 @@ policy_can_egress(struct __sk_buff *skb, __u32 identity, __u16 dport, __u8 proto
 }
 
 static inline int policy_can_egress6(struct __sk_buff *skb,
-				     struct ipv6_ct_tuple *tuple,
+				     struct ipv6_ct_tuple *tuple, int l4_off,
 				     __u32 identity, union v6addr *daddr)
 {
-	return policy_can_egress(skb, identity, tuple->dport, tuple->nexthdr);
+	__be16 dport = policy_icmp_dport(skb, l4_off, tuple->nexthdr,
+					 tuple->dport);
+
+	return policy_can_egress(skb, identity, dport, tuple->nexthdr);
 }
 
 static inline int policy_can_egress4(struct __sk_buff *skb,
-				     struct ipv4_ct_tuple *tuple,
+				     struct ipv4_ct_tuple *tuple, int l4_off,
 				     __u32 identity, __be32 daddr)
 {
-	return policy_can_egress(skb, identity, tuple->dport, tuple->nexthdr);
+	__be16 dport = policy_icmp_dport(skb, l4_off, tuple->nexthdr,
+					 tuple->dport);
+
+	return policy_can_egress(skb, identity, dport, tuple->nexthdr);
 }
 
 #else /* LXC_ID */
 
 static inline int
 policy_can_egress6(struct __sk_buff *skb, struct ipv6_ct_tuple *tuple,
-		   __u32 identity, union v6addr *daddr)
+		   int l4_off, __u32 identity, union v6addr *daddr)
 {
 	return TC_ACT_OK;
 }
 
 static inline int
 policy_can_egress4(struct __sk_buff *skb, struct ipv4_ct_tuple *tuple,
-		   __u32 identity, __be32 daddr)
+		   int l4_off, __u32 identity, __be32 daddr)
 {
 	return TC_ACT_OK;
 }
@@ -45,6 +45,7 @@ enum {
 	TRACE_FROM_HOST,
 	TRACE_FROM_STACK,
 	TRACE_FROM_OVERLAY,
+	TRACE_POLICY_AUDIT,
 };
 
 /* Reasons for forwarding a packet. */
@@ -88,7 +89,8 @@ struct trace_notify {
  * @dst:	destination identity
  * @dst_id:	designated destination endpoint ID
  * @ifindex:	designated destination ifindex
- * @reason:	reason for forwarding the packet (TRACE_REASON_*)
+ * @reason:	reason for forwarding the packet (TRACE_REASON_*), or the
+ *		drop reason which was ignored for TRACE_POLICY_AUDIT
  * @monitor:	length of notification to send (0 means don't send)
  *
  * Generate a notification to indicate a packet was forwarded at an observation point.
@@ -48,9 +48,15 @@
 #define ENABLE_ARP_RESPONDER
 #define NODE_MAC { .addr = { 0xde, 0xad, 0xbe, 0xef, 0xc0, 0xde } }
 #define LB_RR_MAX_SEQ 31
+#define LB_MAGLEV_LUT_SIZE 16381
+#define LB_MAGLEV_MAP_MAX_ENTRIES 256
 #define TUNNEL_ENDPOINT_MAP_SIZE 65536
 #define ENDPOINTS_MAP_SIZE 65536
 #define METRICS_MAP_SIZE 65536
+#define POLICY_AUDIT_MAP_SIZE 16384
+#define THROTTLE_MAP_SIZE 16384
+#define EGRESS_MAP_SIZE 16384
+#define NODEPORT_MAP_SIZE 262144
 #define CILIUM_NET_MAC  { .addr = { 0xce, 0x72, 0xa7, 0x03, 0x88, 0x57 } }
 #define LB_REDIRECT 1
 #define LB_DST_MAC { .addr = { 0xce, 0x72, 0xa7, 0x03, 0x88, 0x58 } }
@@ -0,0 +1,103 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/cilium/cilium/common"
+	"github.com/cilium/cilium/pkg/bpf"
+	"github.com/cilium/cilium/pkg/command"
+	"github.com/cilium/cilium/pkg/maps/egressmap"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	sourceIPTitle        = "SOURCE IP"
+	destinationCIDRTitle = "DESTINATION CIDR"
+	egressIPTitle        = "EGRESS IP"
+	gatewayIPTitle       = "GATEWAY IP"
+)
+
+type egressEntry struct {
+	SourceIP        string `json:"source-ip"`
+	DestinationCIDR string `json:"destination-cidr"`
+	EgressIP        string `json:"egress-ip"`
+	GatewayIP       string `json:"gateway-ip"`
+}
+
+var bpfEgressListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List egress gateway entries",
+	Run: func(cmd *cobra.Command, args []string) {
+		common.RequireRootPrivilege("cilium bpf egress list")
+
+		entries := []egressEntry{}
+		parse := func(key bpf.MapKey, value bpf.MapValue) {
+			k := key.(*egressmap.Key)
+			v := value.(*egressmap.Value)
+			dest := net.IPNet{
+				IP:   net.IP(k.DestIP[:]),
+				Mask: net.CIDRMask(int(k.Prefixlen)-len(k.SourceIP)*8, len(k.DestIP)*8),
+			}
+			entries = append(entries, egressEntry{
+				SourceIP:        k.SourceIP.String(),
+				DestinationCIDR: dest.String(),
+				EgressIP:        v.EgressIP.String(),
+				GatewayIP:       v.TunnelEndpoint.String(),
+			})
+		}
+		if err := egressmap.Egress.DumpWithCallback(parse); err != nil {
+			Fatalf("error dumping contents of map: %s", err)
+		}
+
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].SourceIP != entries[j].SourceIP {
+				return entries[i].SourceIP < entries[j].SourceIP
+			}
+			return entries[i].DestinationCIDR < entries[j].DestinationCIDR
+		})
+
+		if command.OutputJSON() {
+			if err := command.PrintOutput(entries); err != nil {
+				Fatalf("error getting output of map in JSON: %s", err)
+			}
+			return
+		}
+
+		if len(entries) == 0 {
+			fmt.Fprintf(os.Stderr, "No entries found.\n")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 5, 0, 3, ' ', 0)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", sourceIPTitle, destinationCIDRTitle, egressIPTitle, gatewayIPTitle)
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.SourceIP, e.DestinationCIDR, e.EgressIP, e.GatewayIP)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	bpfEgressCmd.AddCommand(bpfEgressListCmd)
+	command.AddJSONOutput(bpfEgressListCmd)
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/cilium/cilium/common"
+	"github.com/cilium/cilium/pkg/command"
+	"github.com/cilium/cilium/pkg/maps/lbmap"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	clientServiceTitle = "CLIENT (SERVICE ID)"
+	affinityTitle      = "AFFINITY"
+)
+
+// bpfLBAffinityListCmd represents the bpf_lb_affinity_list command
+var bpfLBAffinityListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List session affinity entries",
+	Run: func(cmd *cobra.Command, args []string) {
+		common.RequireRootPrivilege("cilium bpf lb affinity list")
+
+		affinityList := make(map[string][]string)
+		if err := lbmap.Affinity4Map.Dump(affinityList); err != nil {
+			os.Exit(1)
+		}
+		if err := lbmap.Affinity6Map.Dump(affinityList); err != nil {
+			os.Exit(1)
+		}
+
+		if command.OutputJSON() {
+			if err := command.PrintOutput(affinityList); err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+
+		TablePrinter(clientServiceTitle, affinityTitle, affinityList)
+	},
+}
+
+func init() {
+	bpfLBAffinityCmd.AddCommand(bpfLBAffinityListCmd)
+	command.AddJSONOutput(bpfLBAffinityListCmd)
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/cilium/cilium/pkg/command"
 	"github.com/cilium/cilium/pkg/identity"
 	"github.com/cilium/cilium/pkg/maps/policymap"
+	"github.com/cilium/cilium/pkg/policy/api"
 	"github.com/cilium/cilium/pkg/policy/trafficdirection"
 	"github.com/cilium/cilium/pkg/u8proto"
 
@@ -158,13 +159,32 @@ func formatMap(w io.Writer, statsMap []policymap.PolicyEntryDump) {
 	}
 	for _, stat := range statsMap {
 		id := identity.NumericIdentity(stat.Key.Identity)
-		trafficDirection := trafficdirection.TrafficDirection(stat.Key.TrafficDirection)
+		trafficDirection := trafficdirection.TrafficDirection(stat.Key.GetDirection())
 		trafficDirectionString := trafficDirection.String()
 		port := models.PortProtocolANY
-		if stat.Key.DestPort != 0 {
+		if stat.Key.DestPort != 0 || stat.Key.Nexthdr != 0 {
 			dport := byteorder.NetworkToHost(stat.Key.DestPort).(uint16)
 			proto := u8proto.U8proto(stat.Key.Nexthdr)
-			port = fmt.Sprintf("%d/%s", dport, proto.String())
+			switch proto {
+			case u8proto.ICMP, u8proto.ICMPv6:
+				// The ICMP type and code are stored in place of the port.
+				icmpType, icmpCode := dport>>8, dport&0xff
+				if icmpCode == api.ICMPCodeAny {
+					port = fmt.Sprintf("%d/%s", icmpType, proto.String())
+				} else {
+					port = fmt.Sprintf("%d:%d/%s", icmpType, icmpCode, proto.String())
+				}
+			default:
+				if dport == 0 {
+					// Port 0 matches all ports of the protocol
+					port = fmt.Sprintf("%s/%s", models.PortProtocolANY, proto.String())
+				} else if wildcard := stat.Key.GetDestPortWildcard(); wildcard != 0 {
+					// The key matches a block of ports
+					port = fmt.Sprintf("%d-%d/%s", dport, uint32(dport)+1<<wildcard-1, proto.String())
+				} else {
+					port = fmt.Sprintf("%d/%s", dport, proto.String())
+				}
+			}
 		}
 		proxyPort := "NONE"
 		if stat.ProxyPort != 0 {
@@ -0,0 +1,202 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/flow"
+	"github.com/cilium/cilium/pkg/defaults"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// observeCmd represents the observe command
+var observeCmd = &cobra.Command{
+	Use:   "observe",
+	Short: "Display flows observed by the node monitor",
+	Long: `Display the flows stored in the flow buffer of the node monitor and
+optionally follow new flows as they are observed. The flow observer must be
+enabled with the --flow-buffer-size option of the agent.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runObserve()
+	},
+}
+
+var observeOpts struct {
+	server         string
+	last           uint64
+	follow         bool
+	jsonOutput     bool
+	fromIP         []string
+	toIP           []string
+	fromPod        []string
+	toPod          []string
+	fromIdentity   []string
+	toIdentity     []string
+	verdicts       []string
+	types          []string
+	connectTimeout time.Duration
+}
+
+func init() {
+	rootCmd.AddCommand(observeCmd)
+	observeCmd.Flags().StringVar(&observeOpts.server, "server", defaults.ObserverSockPath, "Path to the flow observer socket")
+	observeCmd.Flags().Uint64Var(&observeOpts.last, "last", 0, "Number of past flows to show (0 for all flows in the buffer)")
+	observeCmd.Flags().BoolVarP(&observeOpts.follow, "follow", "f", false, "Follow new flows as they are observed")
+	observeCmd.Flags().BoolVarP(&observeOpts.jsonOutput, "json", "j", false, "Enable json output")
+	observeCmd.Flags().StringSliceVar(&observeOpts.fromIP, "from-ip", nil, "Filter by source IP or CIDR")
+	observeCmd.Flags().StringSliceVar(&observeOpts.toIP, "to-ip", nil, "Filter by destination IP or CIDR")
+	observeCmd.Flags().StringSliceVar(&observeOpts.fromPod, "from-pod", nil, "Filter by source pod name prefix ([namespace/]pod)")
+	observeCmd.Flags().StringSliceVar(&observeOpts.toPod, "to-pod", nil, "Filter by destination pod name prefix ([namespace/]pod)")
+	observeCmd.Flags().StringSliceVar(&observeOpts.fromIdentity, "from-identity", nil, "Filter by source security identity")
+	observeCmd.Flags().StringSliceVar(&observeOpts.toIdentity, "to-identity", nil, "Filter by destination security identity")
+	observeCmd.Flags().StringSliceVar(&observeOpts.verdicts, "verdict", nil, "Filter by verdict [FORWARDED DROPPED ERROR AUDIT]")
+	observeCmd.Flags().StringSliceVarP(&observeOpts.types, "type", "t", nil, "Filter by flow type [L3_L4 L7]")
+	observeCmd.Flags().DurationVar(&observeOpts.connectTimeout, "connect-timeout", 5*time.Second, "Timeout for connecting to the flow observer")
+}
+
+func parseIdentities(identities []string) ([]uint64, error) {
+	ids := make([]uint64, 0, len(identities))
+	for _, s := range identities {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity %q", s)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// buildObserveFilter returns the flow filter matching all observe flags.
+func buildObserveFilter() (*flow.FlowFilter, error) {
+	var err error
+
+	ff := &flow.FlowFilter{
+		SourceIp:       observeOpts.fromIP,
+		DestinationIp:  observeOpts.toIP,
+		SourcePod:      observeOpts.fromPod,
+		DestinationPod: observeOpts.toPod,
+	}
+
+	if ff.SourceIdentity, err = parseIdentities(observeOpts.fromIdentity); err != nil {
+		return nil, err
+	}
+	if ff.DestinationIdentity, err = parseIdentities(observeOpts.toIdentity); err != nil {
+		return nil, err
+	}
+
+	for _, s := range observeOpts.verdicts {
+		v, ok := flow.Verdict_value[strings.ToUpper(s)]
+		if !ok {
+			return nil, fmt.Errorf("invalid verdict %q", s)
+		}
+		ff.Verdict = append(ff.Verdict, flow.Verdict(v))
+	}
+
+	for _, s := range observeOpts.types {
+		t, ok := flow.FlowType_value[strings.ToUpper(s)]
+		if !ok {
+			return nil, fmt.Errorf("invalid flow type %q", s)
+		}
+		ff.Type = append(ff.Type, flow.FlowType(t))
+	}
+
+	return ff, nil
+}
+
+func runObserve() {
+	ff, err := buildObserveFilter()
+	if err != nil {
+		Fatalf("%s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), observeOpts.connectTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, observeOpts.server, grpc.WithInsecure(), grpc.WithBlock(),
+		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", addr, timeout)
+		}))
+	if err != nil {
+		Fatalf("Unable to connect to flow observer at %s: %s", observeOpts.server, err)
+	}
+	defer conn.Close()
+
+	req := &flow.GetFlowsRequest{
+		Number:    observeOpts.last,
+		Follow:    observeOpts.follow,
+		Whitelist: []*flow.FlowFilter{ff},
+	}
+	stream, err := flow.NewObserverClient(conn).GetFlows(context.Background(), req)
+	if err != nil {
+		Fatalf("Unable to request flows: %s", err)
+	}
+
+	for {
+		resp, err := stream.Recv()
+		switch {
+		case err == io.EOF:
+			return
+		case err != nil:
+			Fatalf("Unable to receive flows: %s", err)
+		}
+
+		if observeOpts.jsonOutput {
+			b, err := json.Marshal(resp.GetFlow())
+			if err != nil {
+				Fatalf("Unable to marshal flow: %s", err)
+			}
+			fmt.Println(string(b))
+		} else {
+			fmt.Println(formatFlow(resp.GetFlow()))
+		}
+	}
+}
+
+// formatEndpoint returns the pod name or IP of the endpoint, its port if any,
+// and its security identity.
+func formatEndpoint(ep *flow.Endpoint, ip string, port uint32) string {
+	s := ip
+	if ep.GetPodName() != "" {
+		s = ep.GetNamespace() + "/" + ep.GetPodName()
+	}
+	if port != 0 {
+		s += ":" + strconv.FormatUint(uint64(port), 10)
+	}
+	return fmt.Sprintf("%s (identity %d)", s, ep.GetIdentity())
+}
+
+// formatFlow returns a single line summary of f.
+func formatFlow(f *flow.Flow) string {
+	ts := "N/A"
+	if t, err := ptypes.Timestamp(f.GetTime()); err == nil {
+		ts = t.Local().Format(time.StampMilli)
+	}
+
+	return fmt.Sprintf("%s %s: %s -> %s %s %s: %s",
+		ts, f.GetNodeName(),
+		formatEndpoint(f.GetSource(), f.GetIP().GetSource(), f.GetL4().GetSourcePort()),
+		formatEndpoint(f.GetDestination(), f.GetIP().GetDestination(), f.GetL4().GetDestinationPort()),
+		f.GetType(), f.GetVerdict(), f.GetSummary())
+}
@@ -18,20 +18,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"text/tabwriter"
 
+	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/pkg/command"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/spf13/cobra"
 )
 
-var printPolicy bool
+var (
+	printPolicy  bool
+	policyDryRun bool
+)
 
 // policyImportCmd represents the policy_import command
 var policyImportCmd = &cobra.Command{
 	Use:   "import <path>",
 	Short: "Import security policy in JSON format",
 	Example: `  cilium policy import ~/policy.json
-  cilium policy import ./policies/app/`,
+  cilium policy import ./policies/app/
+  cilium policy import --dry-run ~/policy.json`,
 	PreRun: requirePath,
 	Run: func(cmd *cobra.Command, args []string) {
 		path := args[0]
@@ -56,6 +62,10 @@ var policyImportCmd = &cobra.Command{
 			if err != nil {
 				Fatalf("Cannot marshal policy: %s\n", err)
 			}
+			if policyDryRun {
+				previewPolicy(string(jsonPolicy))
+				return
+			}
 			if resp, err := client.PolicyPut(string(jsonPolicy)); err != nil {
 				Fatalf("Cannot import policy: %s\n", err)
 			} else if command.OutputJSON() {
@@ -74,5 +84,62 @@ var policyImportCmd = &cobra.Command{
 func init() {
 	policyCmd.AddCommand(policyImportCmd)
 	policyImportCmd.Flags().BoolVarP(&printPolicy, "print", "", false, "Print policy after import")
+	policyImportCmd.Flags().BoolVarP(&policyDryRun, "dry-run", "", false, "Print the policy changes of local endpoints without importing the policy")
 	command.AddJSONOutput(policyImportCmd)
 }
+
+func previewPolicy(jsonPolicy string) {
+	preview, err := client.PolicyPreview(jsonPolicy)
+	if err != nil {
+		Fatalf("Cannot preview policy: %s\n", err)
+	}
+
+	if command.OutputJSON() {
+		if err := command.PrintOutput(preview); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 5, 0, 3, ' ', 0)
+	printPolicyPreview(w, preview)
+}
+
+func printPolicyPreview(w *tabwriter.Writer, preview *models.PolicyPreview) {
+	changes := 0
+	fmt.Fprintf(w, "ENDPOINT\tCHANGE\tDIRECTION\tIDENTITY\tPORT/PROTO\n")
+	for _, ep := range preview.Endpoints {
+		if ep.Error != "" {
+			fmt.Fprintf(w, "%d\terror: %s\t\t\t\n", ep.ID, ep.Error)
+			changes++
+			continue
+		}
+		for _, entry := range ep.NewlyAllowed {
+			fmt.Fprintf(w, "%d\tallow\t%s\t%d\t%s\n", ep.ID, entry.Direction, entry.Identity, policyPreviewPort(entry))
+			changes++
+		}
+		for _, entry := range ep.NewlyDenied {
+			fmt.Fprintf(w, "%d\tdeny\t%s\t%d\t%s\n", ep.ID, entry.Direction, entry.Identity, policyPreviewPort(entry))
+			changes++
+		}
+	}
+	w.Flush()
+
+	if changes == 0 {
+		fmt.Printf("No policy changes for local endpoints\n")
+	}
+	fmt.Printf("Revision: %d\n", preview.Revision)
+}
+
+func policyPreviewPort(entry *models.PolicyPreviewEntry) string {
+	if entry.Port == 0 && entry.Protocol == "" {
+		return "ANY"
+	}
+	if entry.Port == 0 {
+		return fmt.Sprintf("ANY/%s", entry.Protocol)
+	}
+	if entry.EndPort != 0 {
+		return fmt.Sprintf("%d-%d/%s", entry.Port, entry.EndPort, entry.Protocol)
+	}
+	return fmt.Sprintf("%d/%s", entry.Port, entry.Protocol)
+}
@@ -46,12 +46,12 @@ var srcEndpoint, dstEndpoint, srcK8sPod, dstK8sPod, srcK8sYaml, dstK8sYaml strin
 
 // policyTraceCmd represents the policy_trace command
 var policyTraceCmd = &cobra.Command{
-	Use:   "trace ( -s <label context> | --src-identity <security identity> | --src-endpoint <endpoint ID> | --src-k8s-pod <namespace:pod-name> | --src-k8s-yaml <path to YAML file> ) ( -d <label context> | --dst-identity <security identity> | --dst-endpoint <endpoint ID> | --dst-k8s-pod <namespace:pod-name> | --dst-k8s-yaml <path to YAML file>) [--dport <port>[/<protocol>]",
+	Use:   "trace ( -s <label context> | --src-identity <security identity> | --src-endpoint <endpoint ID> | --src-k8s-pod <namespace:pod-name> | --src-k8s-yaml <path to YAML file> ) ( -d <label context> | --dst-identity <security identity> | --dst-endpoint <endpoint ID> | --dst-k8s-pod <namespace:pod-name> | --dst-k8s-yaml <path to YAML file>) [--dport <port>[-<end port>][/<protocol>]",
 	Short: "Trace a policy decision",
 	Long: `Verifies if the source is allowed to consume
 destination. Source / destination can be provided as endpoint ID, security ID, Kubernetes Pod, YAML file, set of LABELs. LABEL is represented as
 SOURCE:KEY[=VALUE].
-dports can be can be for example: 80/tcp, 53 or 23/udp.
+dports can be can be for example: 80/tcp, 53, 23/udp or a port range such as 30000-32767/tcp.
 If multiple sources and / or destinations are provided, each source is tested whether there is a policy allowing traffic between it and each destination`,
 	Run: func(cmd *cobra.Command, args []string) {
 
@@ -275,8 +275,8 @@ func getSecIDFromK8s(podName string) (string, error) {
 }
 
 // parseL4PortsSlice parses a given `slice` of strings. Each string should be in
-// the form of `<port>[/<protocol>]`, where the `<port>` is an integer and
-// `<protocol>` is an optional layer 4 protocol `tcp` or `udp`. In case
+// the form of `<port>[-<end port>][/<protocol>]`, where the `<port>` and the
+// optional `<end port>` of a port range are integers and `<protocol>` is an optional layer 4 protocol `tcp` or `udp`. In case
 // `protocol` is not present, or is set to `any`, the parsed port will be set to
 // `models.PortProtocolAny`.
 func parseL4PortsSlice(slice []string) ([]*models.Port, error) {
@@ -295,15 +295,30 @@ func parseL4PortsSlice(slice []string) ([]*models.Port, error) {
 				return nil, fmt.Errorf("invalid protocol %q", protoStr)
 			}
 		default:
-			return nil, fmt.Errorf("invalid format %q. Should be <port>[/<protocol>]", v)
+			return nil, fmt.Errorf("invalid format %q. Should be <port>[-<end port>][/<protocol>]", v)
 		}
-		portStr := vSplit[0]
-		port, err := strconv.Atoi(portStr)
+		portSplit := strings.Split(vSplit[0], "-")
+		if len(portSplit) > 2 {
+			return nil, fmt.Errorf("invalid port range %q. Should be <port>-<end port>", vSplit[0])
+		}
+		portStr := portSplit[0]
+		port, err := strconv.ParseUint(portStr, 10, 16)
 		if err != nil {
 			return nil, fmt.Errorf("invalid port %q: %s", portStr, err)
 		}
+		var endPort uint64
+		if len(portSplit) == 2 {
+			endPort, err = strconv.ParseUint(portSplit[1], 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid end port %q: %s", portSplit[1], err)
+			}
+			if endPort < port {
+				return nil, fmt.Errorf("invalid port range %q: end port is smaller than port", vSplit[0])
+			}
+		}
 		l4 := &models.Port{
 			Port:     uint16(port),
+			EndPort:  uint16(endPort),
 			Protocol: protoStr,
 		}
 		rules = append(rules, l4)
@@ -0,0 +1,30 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// preflightCmd groups the checks and migrations to run prior to upgrading
+// or reconfiguring the agents
+var preflightCmd = &cobra.Command{
+	Use:   "preflight",
+	Short: "Checks and migrations to run before upgrading or reconfiguring Cilium",
+}
+
+func init() {
+	rootCmd.AddCommand(preflightCmd)
+}
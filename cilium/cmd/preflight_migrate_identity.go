@@ -0,0 +1,151 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+
+	"github.com/cilium/cilium/pkg/identity/cache"
+	"github.com/cilium/cilium/pkg/k8s"
+	cilium_v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	clientset "github.com/cilium/cilium/pkg/k8s/client/clientset/versioned"
+	"github.com/cilium/cilium/pkg/kvstore"
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/option"
+
+	"github.com/spf13/cobra"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	migrateK8sAPIServer      string
+	migrateK8sKubeConfigPath string
+)
+
+var migrateIdentityCmd = &cobra.Command{
+	Use:   "migrate-identity",
+	Short: "Migrate security identities from the kvstore to CiliumIdentity resources",
+	Long: `Copies all security identities allocated in the kvstore to CiliumIdentity
+resources, preserving the numeric identities. Run this command before switching
+cilium-operator and the agents to --identity-allocation-mode=crd. The command
+can safely be run multiple times.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		migrateIdentities()
+	},
+}
+
+func init() {
+	preflightCmd.AddCommand(migrateIdentityCmd)
+	flags := migrateIdentityCmd.Flags()
+	flags.StringVar(&migrateK8sAPIServer, "k8s-api-server", "", "Kubernetes api address server (for https use --k8s-kubeconfig-path instead)")
+	flags.StringVar(&migrateK8sKubeConfigPath, "k8s-kubeconfig-path", "", "Absolute path of the kubernetes kubeconfig file")
+	flags.StringVar(&kvStore, "kvstore", "", "kvstore type")
+	flags.Var(option.NewNamedMapOptions("kvstore-opts", &kvStoreOpts, nil), "kvstore-opt", "kvstore options")
+}
+
+func migrateIdentities() {
+	setupKvstore()
+
+	k8s.Configure(migrateK8sAPIServer, migrateK8sKubeConfigPath)
+	restConfig, err := k8s.CreateConfig()
+	if err != nil {
+		Fatalf("Unable to create rest configuration: %s", err)
+	}
+
+	apiextensionsclientset, err := apiextensionsclient.NewForConfig(restConfig)
+	if err != nil {
+		Fatalf("Unable to create apiextensions client: %s", err)
+	}
+
+	if err := cilium_v2.CreateCustomResourceDefinitions(apiextensionsclientset); err != nil {
+		Fatalf("Unable to create CustomResourceDefinitions: %s", err)
+	}
+
+	ciliumClient, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		Fatalf("Unable to create cilium client: %s", err)
+	}
+
+	pairs, err := kvstore.ListPrefix(path.Join(cache.IdentitiesPath, "id"))
+	if err != nil {
+		Fatalf("Unable to list identities in the kvstore: %s", err)
+	}
+
+	var migrated, present, failed int
+	for key, value := range pairs {
+		id := path.Base(key)
+
+		b, err := kvstore.Decode(string(value))
+		if err != nil {
+			fmt.Printf("Skipping identity %s: unable to decode labels: %s\n", id, err)
+			failed++
+			continue
+		}
+		lbls := labels.NewLabelsFromSortedList(string(b))
+
+		created, err := migrateIdentity(ciliumClient, id, lbls.StringMap())
+		switch {
+		case err != nil:
+			fmt.Printf("Unable to migrate identity %s (%s): %s\n", id, lbls, err)
+			failed++
+		case created:
+			fmt.Printf("Migrated identity %s (%s)\n", id, lbls)
+			migrated++
+		default:
+			present++
+		}
+	}
+
+	fmt.Printf("Migrated %d identities, %d already present, %d failed\n", migrated, present, failed)
+	if failed > 0 {
+		Fatalf("Not all identities could be migrated")
+	}
+}
+
+// migrateIdentity creates the CiliumIdentity for id with the security labels
+// lbls. Returns true if the CiliumIdentity has been created and false if it
+// already existed with the same labels.
+func migrateIdentity(client clientset.Interface, id string, lbls map[string]string) (bool, error) {
+	identity := &cilium_v2.CiliumIdentity{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: id,
+		},
+		SecurityLabels: lbls,
+	}
+
+	_, err := client.CiliumV2().CiliumIdentities().Create(identity)
+	if err == nil {
+		return true, nil
+	}
+
+	if !k8sErrors.IsAlreadyExists(err) {
+		return false, err
+	}
+
+	existing, err := client.CiliumV2().CiliumIdentities().Get(id, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	if !reflect.DeepEqual(existing.SecurityLabels, lbls) {
+		return false, fmt.Errorf("CiliumIdentity %s already exists with different labels", id)
+	}
+
+	return false, nil
+}
@@ -60,11 +60,12 @@ func listServices(cmd *cobra.Command, args []string) {
 }
 
 func printServiceList(w *tabwriter.Writer, list []*models.Service) {
-	fmt.Fprintln(w, "ID\tFrontend\tBackend\t")
+	fmt.Fprintln(w, "ID\tFrontend\tService Type\tBackend\t")
 
 	type ServiceOutput struct {
 		ID               int64
 		FrontendAddress  string
+		Type             string
 		BackendAddresses []string
 	}
 	svcs := []ServiceOutput{}
@@ -97,9 +98,15 @@ func printServiceList(w *tabwriter.Writer, list []*models.Service) {
 			backendAddresses = append(backendAddresses, str)
 		}
 
+		svcType := ""
+		if svc.Status.Realized.Flags != nil {
+			svcType = svc.Status.Realized.Flags.Type
+		}
+
 		SvcOutput := ServiceOutput{
 			ID:               svc.Status.Realized.ID,
 			FrontendAddress:  feA.String(),
+			Type:             svcType,
 			BackendAddresses: backendAddresses,
 		}
 		svcs = append(svcs, SvcOutput)
@@ -113,19 +120,19 @@ func printServiceList(w *tabwriter.Writer, list []*models.Service) {
 		var str string
 
 		if len(service.BackendAddresses) == 0 {
-			str = fmt.Sprintf("%d\t%s\t\t",
-				service.ID, service.FrontendAddress)
+			str = fmt.Sprintf("%d\t%s\t%s\t\t",
+				service.ID, service.FrontendAddress, service.Type)
 			fmt.Fprintln(w, str)
 			continue
 		}
 
-		str = fmt.Sprintf("%d\t%s\t%s\t",
-			service.ID, service.FrontendAddress,
+		str = fmt.Sprintf("%d\t%s\t%s\t%s\t",
+			service.ID, service.FrontendAddress, service.Type,
 			service.BackendAddresses[0])
 		fmt.Fprintln(w, str)
 
 		for _, bkaddr := range service.BackendAddresses[1:] {
-			str := fmt.Sprintf("\t\t%s\t", bkaddr)
+			str := fmt.Sprintf("\t\t\t%s\t", bkaddr)
 			fmt.Fprintln(w, str)
 		}
 	}
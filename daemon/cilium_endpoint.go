@@ -0,0 +1,69 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/cilium/cilium/pkg/ipcache"
+	"github.com/cilium/cilium/pkg/k8s"
+	cilium_v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/sirupsen/logrus"
+)
+
+// updateCiliumEndpoint maps the IPs of the given CiliumEndpoint to its
+// security identity in the ipcache. CiliumEndpoints are only watched if no
+// kvstore is configured, in which case they replace the IP to identity
+// mappings stored in the kvstore. oldCEP may be nil.
+func (d *Daemon) updateCiliumEndpoint(oldCEP, newCEP *cilium_v2.CiliumEndpoint) {
+	newIPs := map[string]struct{}{}
+	if ipID := k8s.ParseCiliumEndpointIPIdentity(newCEP); ipID != nil {
+		for _, ip := range ipID.IPs {
+			newIPs[ip] = struct{}{}
+			selfOwned := ipcache.IPIdentityCache.Upsert(ip, ipID.HostIP, ipcache.Identity{
+				ID:     ipID.ID,
+				Source: ipcache.FromCustomResource,
+			})
+			if !selfOwned {
+				log.WithFields(logrus.Fields{
+					logfields.IPAddr:       ip,
+					logfields.K8sNamespace: newCEP.Namespace,
+					logfields.K8sPodName:   newCEP.Name,
+				}).Debug("Skipping CiliumEndpoint IP owned by the agent")
+			}
+		}
+	}
+
+	if oldCEP == nil {
+		return
+	}
+	if ipID := k8s.ParseCiliumEndpointIPIdentity(oldCEP); ipID != nil {
+		for _, ip := range ipID.IPs {
+			if _, ok := newIPs[ip]; !ok {
+				ipcache.IPIdentityCache.Delete(ip, ipcache.FromCustomResource)
+			}
+		}
+	}
+}
+
+// deleteCiliumEndpoint removes the IPs of the given CiliumEndpoint from the
+// ipcache
+func (d *Daemon) deleteCiliumEndpoint(cep *cilium_v2.CiliumEndpoint) {
+	if ipID := k8s.ParseCiliumEndpointIPIdentity(cep); ipID != nil {
+		for _, ip := range ipID.IPs {
+			ipcache.IPIdentityCache.Delete(ip, ipcache.FromCustomResource)
+		}
+	}
+}
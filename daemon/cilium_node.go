@@ -0,0 +1,201 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net"
+
+	"github.com/cilium/cilium/pkg/controller"
+	"github.com/cilium/cilium/pkg/datapath/iptables"
+	"github.com/cilium/cilium/pkg/ipam"
+	"github.com/cilium/cilium/pkg/k8s"
+	cilium_v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/node"
+	"github.com/cilium/cilium/pkg/option"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// clusterPoolIPAM consumes the pod CIDRs allocated to the local node by
+// cilium-operator in cluster-pool IPAM mode
+type clusterPoolIPAM struct {
+	// controllers syncs the requested IPv4 pod CIDRs to the CiliumNode
+	controllers *controller.Manager
+
+	// requestMutex protects requestedIPv4PodCIDRs. It is separate from
+	// mutex as it is acquired with the IPAM allocator mutex held.
+	requestMutex lock.Mutex
+
+	// requestedIPv4PodCIDRs is the number of IPv4 pod CIDRs requested
+	// from cilium-operator
+	requestedIPv4PodCIDRs int
+
+	// mutex protects the members below
+	mutex lock.Mutex
+
+	// auxIPv4PodCIDRs are the IPv4 pod CIDRs in use in addition to the
+	// primary allocation range
+	auxIPv4PodCIDRs []*net.IPNet
+
+	// rulesInstalled is true once the iptables rules have been installed
+	// and the rules of additional pod CIDRs can be installed right away
+	rulesInstalled bool
+
+	// remoteIPv4PodCIDRs are the IPv4 pod CIDRs allocated to remote nodes
+	// indexed by node name
+	remoteIPv4PodCIDRs map[string][]*net.IPNet
+}
+
+// initClusterPoolIPAM adds the additional IPv4 pod CIDRs allocated to the
+// local node to IPAM and starts requesting more CIDRs when the available IPv4
+// addresses run low. It must be called after ipam.Init().
+func (d *Daemon) initClusterPoolIPAM() {
+	d.clusterPoolIPAM = &clusterPoolIPAM{
+		controllers:        controller.NewManager(),
+		remoteIPv4PodCIDRs: map[string][]*net.IPNet{},
+	}
+
+	cn, err := k8s.CiliumClient().CiliumV2().CiliumNodes().Get(node.GetName(), metav1.GetOptions{})
+	if err != nil {
+		log.WithError(err).Fatal("Unable to retrieve CiliumNode of local node")
+	}
+
+	d.clusterPoolIPAM.requestedIPv4PodCIDRs = cn.Status.IPAM.RequestedIPv4PodCIDRs
+	d.updateCiliumNode(cn)
+	ipam.SetIPv4RangeRequester(d.requestIPv4PodCIDRs)
+}
+
+// updateCiliumNode adds the IPv4 pod CIDRs allocated to the local node which
+// are not in use yet as additional IPAM allocation ranges. The IPv4 pod CIDRs
+// of remote nodes are installed as their secondary allocation ranges.
+func (d *Daemon) updateCiliumNode(cn *cilium_v2.CiliumNode) {
+	if d.clusterPoolIPAM == nil {
+		return
+	}
+
+	d.clusterPoolIPAM.mutex.Lock()
+	defer d.clusterPoolIPAM.mutex.Unlock()
+
+	ipv4, _ := k8s.ParseCiliumNodePodCIDRs(cn)
+	if cn.Name != node.GetName() {
+		d.clusterPoolIPAM.remoteIPv4PodCIDRs[cn.Name] = ipv4
+		node.SetIPv4SecondaryAllocCIDRs(node.Identity{
+			Name:    cn.Name,
+			Cluster: option.Config.ClusterName,
+		}, ipv4)
+		return
+	}
+
+	for _, cidr := range ipv4 {
+		if !ipam.AddIPv4AllocRange(cidr) {
+			continue
+		}
+
+		scopedLog := log.WithField(logfields.V4Prefix, cidr)
+		scopedLog.Info("Using additional IPv4 pod CIDR allocated by cilium-operator")
+		node.AddIPv4SecondaryAllocCIDR(cidr)
+		d.clusterPoolIPAM.auxIPv4PodCIDRs = append(d.clusterPoolIPAM.auxIPv4PodCIDRs, cidr)
+		if d.clusterPoolIPAM.rulesInstalled {
+			if err := iptables.InstallAuxAllocRangeRules(cidr.String()); err != nil {
+				scopedLog.WithError(err).Warning("Unable to install iptables rules for additional IPv4 pod CIDR")
+			}
+		}
+	}
+}
+
+// deleteCiliumNode forgets the IPv4 pod CIDRs of a removed remote node
+func (d *Daemon) deleteCiliumNode(cn *cilium_v2.CiliumNode) {
+	if d.clusterPoolIPAM == nil || cn.Name == node.GetName() {
+		return
+	}
+
+	d.clusterPoolIPAM.mutex.Lock()
+	delete(d.clusterPoolIPAM.remoteIPv4PodCIDRs, cn.Name)
+	d.clusterPoolIPAM.mutex.Unlock()
+}
+
+// getRemoteIPv4PodCIDRs returns the IPv4 pod CIDRs allocated to the remote
+// node with the given name by cilium-operator
+func (d *Daemon) getRemoteIPv4PodCIDRs(nodeName string) []*net.IPNet {
+	if d.clusterPoolIPAM == nil {
+		return nil
+	}
+
+	d.clusterPoolIPAM.mutex.Lock()
+	defer d.clusterPoolIPAM.mutex.Unlock()
+	return d.clusterPoolIPAM.remoteIPv4PodCIDRs[nodeName]
+}
+
+// reinstallClusterPoolRules installs the iptables rules of all additional
+// IPv4 pod CIDRs. It must be called whenever the iptables rules have been
+// reinstalled.
+func (d *Daemon) reinstallClusterPoolRules() {
+	if d.clusterPoolIPAM == nil {
+		return
+	}
+
+	d.clusterPoolIPAM.mutex.Lock()
+	defer d.clusterPoolIPAM.mutex.Unlock()
+
+	d.clusterPoolIPAM.rulesInstalled = true
+	for _, cidr := range d.clusterPoolIPAM.auxIPv4PodCIDRs {
+		if err := iptables.InstallAuxAllocRangeRules(cidr.String()); err != nil {
+			log.WithError(err).WithField(logfields.V4Prefix, cidr).Warning("Unable to install iptables rules for additional IPv4 pod CIDR")
+		}
+	}
+}
+
+// requestIPv4PodCIDRs requests cilium-operator to allocate the given number
+// of IPv4 pod CIDRs to the local node. It is called by IPAM with the
+// allocator mutex held and therefore only schedules the request.
+func (d *Daemon) requestIPv4PodCIDRs(ranges int) {
+	d.clusterPoolIPAM.requestMutex.Lock()
+	defer d.clusterPoolIPAM.requestMutex.Unlock()
+
+	if ranges <= d.clusterPoolIPAM.requestedIPv4PodCIDRs {
+		return
+	}
+	d.clusterPoolIPAM.requestedIPv4PodCIDRs = ranges
+
+	log.WithField("ranges", ranges).Info("Available IPv4 addresses running low, requesting additional pod CIDR")
+	go d.clusterPoolIPAM.controllers.UpdateController("ipam-cluster-pool-request",
+		controller.ControllerParams{
+			DoFunc: d.syncRequestedIPv4PodCIDRs,
+		})
+}
+
+// syncRequestedIPv4PodCIDRs records the number of requested IPv4 pod CIDRs
+// in the status of the CiliumNode of the local node
+func (d *Daemon) syncRequestedIPv4PodCIDRs() error {
+	d.clusterPoolIPAM.requestMutex.Lock()
+	requested := d.clusterPoolIPAM.requestedIPv4PodCIDRs
+	d.clusterPoolIPAM.requestMutex.Unlock()
+
+	cn, err := k8s.CiliumClient().CiliumV2().CiliumNodes().Get(node.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if cn.Status.IPAM.RequestedIPv4PodCIDRs >= requested {
+		return nil
+	}
+
+	cn = cn.DeepCopy()
+	cn.Status.IPAM.RequestedIPv4PodCIDRs = requested
+	_, err = k8s.CiliumClient().CiliumV2().CiliumNodes().UpdateStatus(cn)
+	return err
+}
@@ -46,6 +46,7 @@ import (
 	"github.com/cilium/cilium/pkg/datapath/prefilter"
 	"github.com/cilium/cilium/pkg/debug"
 	"github.com/cilium/cilium/pkg/defaults"
+	"github.com/cilium/cilium/pkg/egressgateway"
 	"github.com/cilium/cilium/pkg/endpoint"
 	"github.com/cilium/cilium/pkg/endpointmanager"
 	"github.com/cilium/cilium/pkg/envoy"
@@ -62,14 +63,17 @@ import (
 	"github.com/cilium/cilium/pkg/logging"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/maps/ctmap"
+	"github.com/cilium/cilium/pkg/maps/egressmap"
 	"github.com/cilium/cilium/pkg/maps/eppolicymap"
 	ipcachemap "github.com/cilium/cilium/pkg/maps/ipcache"
 	"github.com/cilium/cilium/pkg/maps/lbmap"
 	"github.com/cilium/cilium/pkg/maps/lxcmap"
 	"github.com/cilium/cilium/pkg/maps/metricsmap"
+	"github.com/cilium/cilium/pkg/maps/policyauditmap"
 	"github.com/cilium/cilium/pkg/maps/policymap"
 	"github.com/cilium/cilium/pkg/maps/proxymap"
 	"github.com/cilium/cilium/pkg/maps/sockmap"
+	"github.com/cilium/cilium/pkg/maps/throttlemap"
 	"github.com/cilium/cilium/pkg/maps/tunnel"
 	monitorAPI "github.com/cilium/cilium/pkg/monitor/api"
 	"github.com/cilium/cilium/pkg/mtu"
@@ -82,6 +86,7 @@ import (
 	"github.com/cilium/cilium/pkg/proxy/accesslog"
 	"github.com/cilium/cilium/pkg/proxy/logger"
 	"github.com/cilium/cilium/pkg/revert"
+	"github.com/cilium/cilium/pkg/service/healthserver"
 	"github.com/cilium/cilium/pkg/sockops"
 	"github.com/cilium/cilium/pkg/status"
 	"github.com/cilium/cilium/pkg/trigger"
@@ -110,6 +115,8 @@ const (
 	initArgDevicePreFilter
 	initArgModePreFilter
 	initArgMTU
+	initArgBandwidthManager
+	initArgNodePort
 	initArgMax
 )
 
@@ -168,6 +175,27 @@ type Daemon struct {
 	// k8sSvcCache is a cache of all Kubernetes services and endpoints
 	k8sSvcCache k8s.ServiceCache
 
+	// k8sSvcFrontends records the NodePort, ExternalIPs and LoadBalancer
+	// frontends installed for each Kubernetes service
+	k8sSvcFrontends *k8s.FrontendRegistry
+
+	// svcHealthServer serves the health check NodePort of services with
+	// externalTrafficPolicy=Local, nil if disabled
+	svcHealthServer *healthserver.ServiceHealthServer
+
+	// hostEndpoint enforces the policies selecting the local node, nil if
+	// the host firewall is disabled or not yet initialized
+	hostEndpointMutex lock.RWMutex
+	hostEndpoint      *endpoint.Endpoint
+
+	// egressGatewayManager steers the traffic selected by egress gateway
+	// policies, nil if disabled
+	egressGatewayManager *egressgateway.Manager
+
+	// clusterPoolIPAM consumes the pod CIDRs allocated by cilium-operator,
+	// nil unless running in cluster-pool IPAM mode
+	clusterPoolIPAM *clusterPoolIPAM
+
 	mtuConfig     mtu.Configuration
 	policyTrigger *trigger.Trigger
 }
@@ -462,6 +490,18 @@ func (d *Daemon) compileBase() error {
 
 	args[initArgMTU] = fmt.Sprintf("%d", d.mtuConfig.GetDeviceMTU())
 
+	if option.Config.EnableBandwidthManager {
+		args[initArgBandwidthManager] = "true"
+	} else {
+		args[initArgBandwidthManager] = "false"
+	}
+
+	if option.Config.EnableNodePort {
+		args[initArgNodePort] = "true"
+	} else {
+		args[initArgNodePort] = "false"
+	}
+
 	if option.Config.Device != "undefined" {
 		_, err := netlink.LinkByName(option.Config.Device)
 		if err != nil {
@@ -513,6 +553,10 @@ func (d *Daemon) compileBase() error {
 		if err := iptables.InstallRules(); err != nil {
 			return err
 		}
+		if d.egressGatewayManager != nil {
+			d.egressGatewayManager.ReinstallRules()
+		}
+		d.reinstallClusterPoolRules()
 	}
 
 	log.Info("Setting sysctl net.core.bpf_jit_enable=1")
@@ -552,6 +596,22 @@ func (d *Daemon) init() error {
 			return err
 		}
 
+		if _, err := policyauditmap.PolicyAudit.OpenOrCreate(); err != nil {
+			return err
+		}
+
+		if option.Config.EnableBandwidthManager {
+			if _, err := throttlemap.Throttle.OpenOrCreate(); err != nil {
+				return err
+			}
+		}
+
+		if option.Config.EnableEgressGateway {
+			if _, err := egressmap.Egress.OpenOrCreate(); err != nil {
+				return err
+			}
+		}
+
 		if _, err := tunnel.TunnelMap.OpenOrCreate(); err != nil {
 			return err
 		}
@@ -560,19 +620,26 @@ func (d *Daemon) init() error {
 			log.WithError(err).Fatal("Unable to open service maps")
 		}
 
+		if option.Config.EnableIPSec {
+			if err := initIPSec(); err != nil {
+				log.WithError(err).Fatal("Unable to initialize IPsec encryption")
+			}
+		}
+
 		if err := d.compileBase(); err != nil {
 			return err
 		}
 
 		// Remove any old sockops and re-enable with _new_ programs if flag is set
-		sockops.SockmapDisable()
-		sockops.SkmsgDisable()
+		sockops.Disable()
 
 		if option.Config.SockopsEnable {
 			eppolicymap.CreateEPPolicyMap()
-			sockops.SockmapEnable()
-			sockops.SkmsgEnable()
-			sockmap.SockmapCreate()
+			if err := sockops.Enable(); err != nil {
+				log.WithError(err).Warning("Unable to enable sockmap acceleration")
+			} else {
+				sockmap.SockmapCreate()
+			}
 		}
 
 		// Set up the list of IPCache listeners in the daemon, to be
@@ -606,6 +673,14 @@ func (d *Daemon) init() error {
 				RunInterval: 5 * time.Second,
 			})
 
+		// Start the controller for periodic removal of expired session
+		// affinity entries of services.
+		controller.NewManager().UpdateController("lb-affinity-gc",
+			controller.ControllerParams{
+				DoFunc:      lbmap.GCAffinity,
+				RunInterval: 30 * time.Second,
+			})
+
 		// Clean all lb entries
 		if !option.Config.RestoreState {
 			log.Debug("cleaning up all BPF LB maps")
@@ -620,6 +695,12 @@ func (d *Daemon) init() error {
 				if err := lbmap.RRSeq6Map.DeleteAll(); err != nil {
 					return err
 				}
+				if err := lbmap.Affinity6Map.DeleteAll(); err != nil {
+					return err
+				}
+				if err := lbmap.Maglev6Map.DeleteAll(); err != nil {
+					return err
+				}
 			}
 			if err := d.RevNATDeleteAll(); err != nil {
 				return err
@@ -632,6 +713,12 @@ func (d *Daemon) init() error {
 				if err := lbmap.RRSeq4Map.DeleteAll(); err != nil {
 					return err
 				}
+				if err := lbmap.Affinity4Map.DeleteAll(); err != nil {
+					return err
+				}
+				if err := lbmap.Maglev4Map.DeleteAll(); err != nil {
+					return err
+				}
 			}
 
 			// If we are not restoring state, all endpoints can be
@@ -692,11 +779,17 @@ func (d *Daemon) createNodeConfigHeaderfile() error {
 	fmt.Fprintf(fw, "#define UNMANAGED_ID %d\n", identity.GetReservedID(labels.IDNameUnmanaged))
 	fmt.Fprintf(fw, "#define INIT_ID %d\n", identity.GetReservedID(labels.IDNameInit))
 	fmt.Fprintf(fw, "#define LB_RR_MAX_SEQ %d\n", lbmap.MaxSeq)
+	fmt.Fprintf(fw, "#define LB_MAGLEV_LUT_SIZE %d\n", lbmap.MaglevTableSize)
+	fmt.Fprintf(fw, "#define LB_MAGLEV_MAP_MAX_ENTRIES %d\n", option.Config.LBMaglevMapMaxEntries)
 	fmt.Fprintf(fw, "#define CILIUM_LB_MAP_MAX_ENTRIES %d\n", lbmap.MaxEntries)
 	fmt.Fprintf(fw, "#define TUNNEL_ENDPOINT_MAP_SIZE %d\n", tunnel.MaxEntries)
 	fmt.Fprintf(fw, "#define PROXY_MAP_SIZE %d\n", proxymap.MaxEntries)
 	fmt.Fprintf(fw, "#define ENDPOINTS_MAP_SIZE %d\n", lxcmap.MaxEntries)
 	fmt.Fprintf(fw, "#define METRICS_MAP_SIZE %d\n", metricsmap.MaxEntries)
+	fmt.Fprintf(fw, "#define POLICY_AUDIT_MAP_SIZE %d\n", policyauditmap.MaxEntries)
+	fmt.Fprintf(fw, "#define THROTTLE_MAP_SIZE %d\n", throttlemap.MaxEntries)
+	fmt.Fprintf(fw, "#define EGRESS_MAP_SIZE %d\n", egressmap.MaxEntries)
+	fmt.Fprintf(fw, "#define NODEPORT_MAP_SIZE %d\n", lbmap.NodePortMaxEntries)
 	fmt.Fprintf(fw, "#define POLICY_MAP_SIZE %d\n", policymap.MaxEntries)
 	fmt.Fprintf(fw, "#define IPCACHE_MAP_SIZE %d\n", ipcachemap.MaxEntries)
 	fmt.Fprintf(fw, "#define POLICY_PROG_MAP_SIZE %d\n", policymap.ProgArrayMaxEntries)
@@ -717,6 +810,22 @@ func (d *Daemon) createNodeConfigHeaderfile() error {
 		fmt.Fprintf(fw, "#define ENABLE_IPV6\n")
 	}
 
+	if option.Config.EnableIPSec {
+		fmt.Fprintf(fw, "#define ENABLE_IPSEC\n")
+	}
+
+	if option.Config.EnableEgressGateway {
+		fmt.Fprintf(fw, "#define ENABLE_EGRESS_GATEWAY\n")
+	}
+
+	if option.Config.EnableBandwidthManager {
+		fmt.Fprintf(fw, "#define ENABLE_BANDWIDTH_MANAGER\n")
+	}
+
+	if option.Config.EnableNodePort {
+		fmt.Fprintf(fw, "#define ENABLE_NODEPORT\n")
+	}
+
 	fw.Flush()
 	f.Close()
 
@@ -850,6 +959,7 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 	}
 
 	ctmap.InitMapInfo(option.Config.CTMapEntriesGlobalTCP, option.Config.CTMapEntriesGlobalAny)
+	lbmap.InitMaglevMaps(option.Config.LBMaglevMapMaxEntries)
 
 	if err := workloads.Setup(option.Config.Workloads, map[string]string{}); err != nil {
 		return nil, nil, fmt.Errorf("unable to setup workload: %s", err)
@@ -860,14 +970,26 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 		k8sSvcCache:   k8s.NewServiceCache(),
 		policy:        policy.NewPolicyRepository(),
 		uniqueID:      map[uint64]context.CancelFunc{},
-		nodeMonitor:   monitorLaunch.NewNodeMonitor(option.Config.MonitorQueueSize),
+		nodeMonitor:   monitorLaunch.NewNodeMonitor(option.Config.MonitorQueueSize, option.Config.FlowBufferSize),
 		prefixLengths: createPrefixLengthCounter(),
 
+		k8sSvcFrontends:  k8s.NewFrontendRegistry(),
 		buildEndpointSem: semaphore.NewWeighted(int64(numWorkerThreads())),
 		compilationMutex: new(lock.RWMutex),
 		mtuConfig:        mtu.NewConfiguration(option.Config.Tunnel != option.TunnelDisabled, option.Config.MTU),
 	}
 
+	// The health check NodePort advertises the local backends of a service,
+	// which are only served to external clients when the datapath translates
+	// traffic on the native device.
+	if option.Config.EnableNodePort && option.Config.EnableHealthCheckNodePort {
+		d.svcHealthServer = healthserver.New()
+	}
+
+	if option.Config.EnableEgressGateway {
+		d.egressGatewayManager = egressgateway.NewManager()
+	}
+
 	t, err := trigger.NewTrigger(trigger.Parameters{
 		Name:              "policy_update",
 		PrometheusMetrics: true,
@@ -936,7 +1058,11 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 
 	// Inject BPF dependency, kvstore dependency into node package.
 	node.TunnelDatapath = tunnel.TunnelMap
-	node.NodeReg = &nodeStore.NodeRegistrar{}
+	if option.Config.KVStoreEnabled() {
+		node.NodeReg = &nodeStore.NodeRegistrar{}
+	} else {
+		node.NodeReg = node.NoOpNodeRegistrar{}
+	}
 
 	if err := node.AutoComplete(); err != nil {
 		log.WithError(err).Fatal("Cannot autocomplete node addresses")
@@ -985,6 +1111,10 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 	log.Info("Initializing IPAM")
 	ipam.Init()
 
+	if option.Config.IPAM == option.IPAMClusterPool {
+		d.initClusterPoolIPAM()
+	}
+
 	// restore endpoints before any IPs are allocated to avoid eventual IP
 	// conflicts later on, otherwise any IP conflict will result in the
 	// endpoint not being able to be restored.
@@ -1064,7 +1194,7 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 
 	// This needs to be done after the node addressing has been configured
 	// as the node address is required as suffix.
-	cache.InitIdentityAllocator(&d)
+	cache.InitIdentityAllocator(&d, k8s.CiliumClient())
 
 	if path := option.Config.ClusterMeshConfig; path != "" {
 		if option.Config.ClusterID == 0 {
@@ -1092,8 +1222,11 @@ func NewDaemon() (*Daemon, *endpointRestoreState, error) {
 
 	// Start watcher for endpoint IP --> identity mappings in key-value store.
 	// this needs to be done *after* init() for the daemon in that function,
-	// we populate the IPCache with the host's IP(s).
-	ipcache.InitIPIdentityWatcher()
+	// we populate the IPCache with the host's IP(s). Without a kvstore, the
+	// mappings are learned from CiliumEndpoints by the k8s watcher instead.
+	if option.Config.KVStoreEnabled() {
+		ipcache.InitIPIdentityWatcher()
+	}
 
 	// FIXME: Make the port range configurable.
 	d.l7Proxy = proxy.StartProxySupport(10000, 20000, option.Config.RunDir,
@@ -1420,7 +1553,9 @@ func (d *Daemon) bootstrapFQDN(restoredEndpoints *endpointRestoreState) (err err
 		// - Report the verdict in a monitor event and emit proxy metrics
 		// - Insert the DNS data into the cache when msg is a DNS response and we
 		//   can lookup the endpoint related to it
-		func(lookupTime time.Time, srcAddr, dstAddr string, msg *dns.Msg, protocol string, allowed bool, proxyErr error) error {
+		// msg has already been stripped of the rejectedIPs, these are only
+		// reported in the access log.
+		func(lookupTime time.Time, srcAddr, dstAddr string, msg *dns.Msg, protocol string, allowed bool, rejectedIPs []net.IP, proxyErr error) error {
 			var protoID = u8proto.ProtoIDs[strings.ToLower(protocol)]
 
 			var verdict accesslog.FlowVerdict
@@ -1491,6 +1626,11 @@ func (d *Daemon) bootstrapFQDN(restoredEndpoints *endpointRestoreState) (err err
 				log.WithError(err).Error("cannot extract DNS message details")
 			}
 
+			var qtype string
+			if len(msg.Question) > 0 {
+				qtype = dns.TypeToString[msg.Question[0].Qtype]
+			}
+
 			ep.UpdateProxyStatistics("dns", uint16(dstPort), ingress, !ingress, verdict)
 			record := logger.NewLogRecord(proxy.DefaultEndpointInfoRegistry, ep, flowType, ingress,
 				func(lr *logger.LogRecord) { lr.LogRecord.TransportProtocol = accesslog.TransportProtocol(protoID) },
@@ -1501,10 +1641,12 @@ func (d *Daemon) bootstrapFQDN(restoredEndpoints *endpointRestoreState) (err err
 					SrcIdentity: srcID,
 				}),
 				logger.LogTags.DNS(&accesslog.LogRecordDNS{
-					Query:  qname,
-					IPs:    responseIPs,
-					TTL:    TTL,
-					CNAMEs: CNAMEs,
+					Query:       qname,
+					IPs:         responseIPs,
+					TTL:         TTL,
+					CNAMEs:      CNAMEs,
+					QueryType:   qtype,
+					RejectedIPs: rejectedIPs,
 				}),
 			)
 			record.Log()
@@ -380,6 +380,43 @@ func init() {
 	flags.Bool(option.DisableK8sServices, false, "Disable east-west K8s load balancing by cilium")
 	option.BindEnv(option.DisableK8sServices)
 
+	flags.String(option.LBAlgorithm, option.LBAlgorithmRandom, "Default backend selection algorithm of services (random, maglev)")
+	option.BindEnv(option.LBAlgorithm)
+
+	flags.Int(option.LBMaglevMapMaxEntriesName, option.LBMaglevMapMaxEntriesDefault,
+		"Maximum number of services using Maglev per IP family, each taking 32KiB of memory")
+	option.BindEnv(option.LBMaglevMapMaxEntriesName)
+
+	flags.Bool(option.EnableHealthCheckNodePort, true, "Serve the health check NodePort of services with externalTrafficPolicy=Local (requires --enable-node-port)")
+	option.BindEnv(option.EnableHealthCheckNodePort)
+
+	flags.Bool(option.EnableIPSecName, false, "Enable IPsec encryption of traffic between nodes")
+	option.BindEnv(option.EnableIPSecName)
+
+	flags.String(option.IPSecKeyFileName, "", "Path to the file holding the IPsec keys")
+	option.BindEnv(option.IPSecKeyFileName)
+
+	flags.Bool(option.EnableHostFirewallName, false, "Enforce policies selecting the local node on traffic received on the native device")
+	option.BindEnv(option.EnableHostFirewallName)
+
+	flags.Bool(option.HostFirewallAuditName, false, "Only log policy verdicts of the host firewall without dropping traffic")
+	option.BindEnv(option.HostFirewallAuditName)
+
+	flags.Bool(option.EnableEgressGatewayName, false, "Steer traffic through gateway nodes according to CiliumEgressNATPolicies")
+	option.BindEnv(option.EnableEgressGatewayName)
+
+	flags.Bool(option.EnableBandwidthManagerName, false, "Shape the egress traffic of endpoints on the native device according to their bandwidth annotations")
+	option.BindEnv(option.EnableBandwidthManagerName)
+
+	flags.Bool(option.EnableNodePortName, false, "Translate traffic received on the native device for NodePort, externalIPs and LoadBalancer frontends to local backends")
+	option.BindEnv(option.EnableNodePortName)
+
+	flags.String(option.IPAMName, option.IPAMHostScope, "IP address management mode (host-scope, cluster-pool)")
+	option.BindEnv(option.IPAMName)
+
+	flags.String(option.IdentityAllocationModeName, option.IdentityAllocationModeKVstore, "Method to use for identity allocation (kvstore, crd)")
+	option.BindEnv(option.IdentityAllocationModeName)
+
 	flags.StringP(option.Docker, "e", workloads.GetRuntimeDefaultOpt(workloads.Docker, "endpoint"), "Path to docker runtime socket (DEPRECATED: use container-runtime-endpoint instead)")
 	option.BindEnv(option.Docker)
 
@@ -517,6 +554,10 @@ func init() {
 		"Size of the event queue when reading monitor events")
 	option.BindEnv(option.MonitorQueueSizeName)
 
+	flags.Int(option.FlowBufferSizeName, 0,
+		"Number of flows kept by the flow observer of the node monitor (0 to disable)")
+	option.BindEnv(option.FlowBufferSizeName)
+
 	flags.Int(option.MTUName, 0, "Overwrite auto-detected MTU of underlying network")
 	option.BindEnv(option.MTUName)
 
@@ -825,14 +866,20 @@ func initEnv(cmd *cobra.Command) {
 	if !option.Config.EnableIPv4 && !option.Config.EnableIPv6 {
 		log.Fatal("Either IPv4 or IPv6 addressing must be enabled")
 	}
-	if err := kvstore.Setup(option.Config.KVStore, option.Config.KVStoreOpt); err != nil {
-		addrkey := fmt.Sprintf("%s.address", option.Config.KVStore)
-		addr := option.Config.KVStoreOpt[addrkey]
-
-		log.WithError(err).WithFields(logrus.Fields{
-			"kvstore": option.Config.KVStore,
-			"address": addr,
-		}).Fatal("Unable to setup kvstore")
+	// The kvstore is optional if identities are allocated as
+	// CiliumIdentities, see DaemonConfig.Validate().
+	if option.Config.KVStoreEnabled() {
+		if err := kvstore.Setup(option.Config.KVStore, option.Config.KVStoreOpt); err != nil {
+			addrkey := fmt.Sprintf("%s.address", option.Config.KVStore)
+			addr := option.Config.KVStoreOpt[addrkey]
+
+			log.WithError(err).WithFields(logrus.Fields{
+				"kvstore": option.Config.KVStore,
+				"address": addr,
+			}).Fatal("Unable to setup kvstore")
+		}
+	} else {
+		log.Info("No kvstore configured, using Kubernetes resources to share state between nodes")
 	}
 
 	if err := labels.ParseLabelPrefixCfg(option.Config.Labels, option.Config.LabelPrefixFile); err != nil {
@@ -984,6 +1031,12 @@ func runDaemon() {
 
 	d.initHealth()
 
+	if option.Config.EnableHostFirewall {
+		if err := d.initHostEndpoint(); err != nil {
+			log.WithError(err).Fatal("Unable to create host endpoint")
+		}
+	}
+
 	d.startStatusCollector()
 
 	metricsErrs := initMetrics()
@@ -1072,6 +1125,7 @@ func (d *Daemon) instantiateAPI() *restapi.CiliumAPI {
 	// /policy/
 	api.PolicyGetPolicyHandler = newGetPolicyHandler(d)
 	api.PolicyPutPolicyHandler = newPutPolicyHandler(d)
+	api.PolicyPostPolicyPreviewHandler = newPostPolicyPreviewHandler(d)
 	api.PolicyDeletePolicyHandler = newDeletePolicyHandler(d)
 
 	// /policy/resolve/
@@ -0,0 +1,115 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/cilium/cilium/pkg/egressgateway"
+	"github.com/cilium/cilium/pkg/k8s"
+	cilium_v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/node"
+	"github.com/cilium/cilium/pkg/versioned"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+)
+
+func cenpPolicyID(cenp *cilium_v2.CiliumEgressNATPolicy) egressgateway.PolicyID {
+	return egressgateway.PolicyID{
+		Namespace: cenp.Namespace,
+		Name:      cenp.Name,
+	}
+}
+
+// addCiliumEgressNATPolicy adds or updates the egress gateway policy cenp.
+// An invalid policy replacing a valid one removes the latter.
+func (d *Daemon) addCiliumEgressNATPolicy(cenp *cilium_v2.CiliumEgressNATPolicy) error {
+	if d.egressGatewayManager == nil {
+		return nil
+	}
+
+	scopedLog := log.WithFields(logrus.Fields{
+		logfields.K8sNamespace:      cenp.Namespace,
+		"ciliumEgressNATPolicyName": cenp.Name,
+	})
+
+	p, err := egressgateway.ParsePolicy(cenp)
+	if err != nil {
+		scopedLog.WithError(err).Warning("Ignoring invalid CiliumEgressNATPolicy")
+		d.egressGatewayManager.OnDeleteEgressPolicy(cenpPolicyID(cenp))
+		return err
+	}
+
+	scopedLog.Debug("Adding CiliumEgressNATPolicy")
+	d.egressGatewayManager.OnAddEgressPolicy(p)
+	return nil
+}
+
+// deleteCiliumEgressNATPolicy deletes the egress gateway policy cenp.
+func (d *Daemon) deleteCiliumEgressNATPolicy(cenp *cilium_v2.CiliumEgressNATPolicy) error {
+	if d.egressGatewayManager == nil {
+		return nil
+	}
+
+	d.egressGatewayManager.OnDeleteEgressPolicy(cenpPolicyID(cenp))
+	return nil
+}
+
+// missingCENP returns all egress gateway policies from the given map which
+// have not been added.
+func (d *Daemon) missingCENP(m versioned.Map) versioned.Map {
+	missing := versioned.NewMap()
+	if d.egressGatewayManager == nil {
+		return missing
+	}
+	for k, v := range m {
+		cenp := v.Data.(*cilium_v2.CiliumEgressNATPolicy)
+		if !d.egressGatewayManager.HasPolicy(cenpPolicyID(cenp)) {
+			missing.Add(k, v)
+		}
+	}
+	return missing
+}
+
+// updateEgressGatewayPod updates the pod selected by egress gateway policies.
+func (d *Daemon) updateEgressGatewayPod(pod *v1.Pod) {
+	if d.egressGatewayManager != nil {
+		d.egressGatewayManager.OnUpdatePod(pod)
+	}
+}
+
+// deleteEgressGatewayPod deletes the pod selected by egress gateway policies.
+func (d *Daemon) deleteEgressGatewayPod(pod *v1.Pod) {
+	if d.egressGatewayManager != nil {
+		d.egressGatewayManager.OnDeletePod(pod)
+	}
+}
+
+// updateEgressGatewayNode updates the IP through which k8sNode is reached
+// when acting as egress gateway.
+func (d *Daemon) updateEgressGatewayNode(k8sNode *v1.Node) {
+	if d.egressGatewayManager == nil {
+		return
+	}
+	n := k8s.ParseNode(k8sNode, node.FromKubernetes)
+	d.egressGatewayManager.OnUpdateNode(n.Name, n.GetNodeIP(false))
+}
+
+// deleteEgressGatewayNode deletes the node k8sNode.
+func (d *Daemon) deleteEgressGatewayNode(k8sNode *v1.Node) {
+	if d.egressGatewayManager != nil {
+		d.egressGatewayManager.OnDeleteNode(k8sNode.GetName())
+	}
+}
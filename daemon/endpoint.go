@@ -23,7 +23,9 @@ import (
 
 	"github.com/cilium/cilium/api/v1/models"
 	. "github.com/cilium/cilium/api/v1/server/restapi/endpoint"
+	"github.com/cilium/cilium/pkg/annotation"
 	"github.com/cilium/cilium/pkg/api"
+	"github.com/cilium/cilium/pkg/bandwidth"
 	"github.com/cilium/cilium/pkg/completion"
 	"github.com/cilium/cilium/pkg/endpoint"
 	endpointid "github.com/cilium/cilium/pkg/endpoint/id"
@@ -34,6 +36,7 @@ import (
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/maps/lxcmap"
 	"github.com/cilium/cilium/pkg/option"
+	"github.com/cilium/cilium/pkg/policy"
 	"github.com/cilium/cilium/pkg/uuid"
 	"github.com/cilium/cilium/pkg/workloads"
 
@@ -133,15 +136,35 @@ func NewPutEndpointIDHandler(d *Daemon) PutEndpointIDHandler {
 	return &putEndpointID{d: d}
 }
 
-func fetchK8sLabels(ep *endpoint.Endpoint) (labels.Labels, labels.Labels, error) {
-	lbls, err := k8s.GetPodLabels(ep.GetK8sNamespace(), ep.GetK8sPodName())
+func fetchK8sLabels(ep *endpoint.Endpoint) (labels.Labels, labels.Labels, map[string]string, error) {
+	lbls, annotations, err := k8s.GetPodMetadata(ep.GetK8sNamespace(), ep.GetK8sPodName())
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	k8sLbls := labels.Map2Labels(lbls, labels.LabelSourceK8s)
 	identityLabels, infoLabels := labels.FilterLabels(k8sLbls)
-	return identityLabels, infoLabels, nil
+	return identityLabels, infoLabels, annotations, nil
+}
+
+// getEgressBandwidth returns the egress bandwidth limit in bits per second
+// requested by the given pod annotations, or 0 if no limit is requested.
+func getEgressBandwidth(annotations map[string]string) (uint64, error) {
+	value, ok := annotations[annotation.EgressBandwidth]
+	if !ok {
+		return 0, nil
+	}
+	return bandwidth.ParseRate(value)
+}
+
+// getVisibilityPolicy returns the L7 visibility policy requested by the given
+// pod annotations, or nil if none is requested.
+func getVisibilityPolicy(annotations map[string]string) (*policy.VisibilityPolicy, error) {
+	value, ok := annotations[annotation.ProxyVisibility]
+	if !ok {
+		return nil, nil
+	}
+	return policy.NewVisibilityPolicy(value)
 }
 
 // createEndpoint attempts to create the endpoint corresponding to the change
@@ -193,12 +216,24 @@ func (d *Daemon) createEndpoint(ctx context.Context, epTemplate *models.Endpoint
 	}
 
 	if ep.GetK8sNamespaceAndPodNameLocked() != "" && k8s.IsEnabled() {
-		identityLabels, info, err := fetchK8sLabels(ep)
+		identityLabels, info, annotations, err := fetchK8sLabels(ep)
 		if err != nil {
 			ep.Logger("api").WithError(err).Warning("Unable to fetch kubernetes labels")
 		} else {
 			addLabels.MergeLabels(identityLabels)
 			infoLabels.MergeLabels(info)
+
+			rate, err := getEgressBandwidth(annotations)
+			if err != nil {
+				ep.Logger("api").WithError(err).Warningf("Ignoring invalid %s annotation", annotation.EgressBandwidth)
+			}
+			ep.SetEgressBandwidth(rate)
+
+			vp, err := getVisibilityPolicy(annotations)
+			if err != nil {
+				ep.Logger("api").WithError(err).Warningf("Ignoring invalid %s annotation", annotation.ProxyVisibility)
+			}
+			ep.SetVisibilityPolicy(vp)
 		}
 	}
 
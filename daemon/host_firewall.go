@@ -0,0 +1,86 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/cilium/cilium/pkg/endpoint"
+	"github.com/cilium/cilium/pkg/endpointmanager"
+	"github.com/cilium/cilium/pkg/k8s"
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/node"
+	"github.com/cilium/cilium/pkg/option"
+
+	"k8s.io/api/core/v1"
+)
+
+// initHostEndpoint creates the host endpoint which enforces the policies
+// selecting the local node on the native device.
+func (d *Daemon) initHostEndpoint() error {
+	log.WithField(logfields.Interface, option.Config.Device).Info("Building host endpoint")
+
+	ep, err := endpoint.NewHostEndpoint(option.Config.Device)
+	if err != nil {
+		return err
+	}
+	ep.SetDefaultOpts(option.Config.Opts)
+	if option.Config.HostFirewallAudit {
+		ep.Options.SetValidated(option.PolicyAuditMode, option.OptionEnabled)
+	}
+
+	var nodeLabels labels.Labels
+	if k8s.IsEnabled() {
+		k8sNode, err := k8s.GetNode(k8s.Client(), node.GetName())
+		if err != nil {
+			return fmt.Errorf("unable to retrieve labels of node %s: %s", node.GetName(), err)
+		}
+		nodeLabels = labels.Map2Labels(k8sNode.GetLabels(), labels.LabelSourceK8s)
+	}
+
+	// Give the endpoint the reserved host identity
+	ep.UpdateLabels(d, labels.LabelHost, nodeLabels, true)
+
+	if err := endpointmanager.AddEndpoint(d, ep, "Create host endpoint"); err != nil {
+		return fmt.Errorf("unable to add host endpoint: %s", err)
+	}
+
+	d.hostEndpointMutex.Lock()
+	d.hostEndpoint = ep
+	d.hostEndpointMutex.Unlock()
+
+	return nil
+}
+
+// updateHostEndpointLabels updates the node labels of the host endpoint if
+// k8sNode is the local node.
+func (d *Daemon) updateHostEndpointLabels(k8sNode *v1.Node) {
+	if k8sNode.GetName() != node.GetName() {
+		return
+	}
+
+	d.hostEndpointMutex.RLock()
+	ep := d.hostEndpoint
+	d.hostEndpointMutex.RUnlock()
+	if ep == nil {
+		return
+	}
+
+	nodeLabels := labels.Map2Labels(k8sNode.GetLabels(), labels.LabelSourceK8s)
+	if err := ep.UpdateNodeLabels(d, nodeLabels); err != nil {
+		log.WithError(err).Warning("Unable to update labels of host endpoint")
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/models"
+	"github.com/cilium/cilium/pkg/controller"
+	"github.com/cilium/cilium/pkg/datapath/ipsec"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/maps/encrypt"
+	"github.com/cilium/cilium/pkg/node"
+	"github.com/cilium/cilium/pkg/option"
+)
+
+// ipSecKeyRotationDelay is the time the datapath keeps encrypting with the
+// previous key after a new key has been loaded. It leaves remote nodes time
+// to pick up the new key from the key file and install its xfrm states, which
+// they do within a minute of the key file being updated.
+const ipSecKeyRotationDelay = 5 * time.Minute
+
+// syncIPSecKeys loads the IPsec keys from the key file. If the keys have
+// changed, the xfrm states of all nodes are updated. The datapath switches to
+// a new key with a higher SPI after ipSecKeyRotationDelay, as remote nodes
+// can only decrypt the traffic once they have loaded the key as well.
+func syncIPSecKeys() error {
+	spi, changed, err := ipsec.LoadIPSecKeysFile(option.Config.IPSecKeyFile)
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+
+	node.ReplaceIPSecEndpoints()
+
+	current, _ := ipsec.GetKeyStatus()
+	if current == spi {
+		if err := encrypt.MapUpdateContext(spi); err != nil {
+			return fmt.Errorf("unable to update encryption state map: %s", err)
+		}
+		log.WithField(logfields.SPI, spi).Info("Loaded IPsec keys")
+		return nil
+	}
+
+	log.WithField(logfields.SPI, spi).Infof("Loaded IPsec keys, switching to new key in %s", ipSecKeyRotationDelay)
+	time.AfterFunc(ipSecKeyRotationDelay, func() {
+		err := ipsec.SwitchIPSecKey(spi, func(spi uint8) error {
+			return encrypt.MapUpdateContext(spi)
+		})
+		if err != nil {
+			log.WithError(err).WithField(logfields.SPI, spi).Warning("Unable to switch to new IPsec key")
+			return
+		}
+		log.WithField(logfields.SPI, spi).Info("Switched to new IPsec key")
+	})
+
+	return nil
+}
+
+// initIPSec loads the initial IPsec keys and starts the controller which
+// picks up key rotations from the key file.
+func initIPSec() error {
+	if err := encrypt.MapCreate(); err != nil {
+		return fmt.Errorf("unable to create encryption state map: %s", err)
+	}
+
+	if err := syncIPSecKeys(); err != nil {
+		return err
+	}
+
+	controller.NewManager().UpdateController("ipsec-key-sync",
+		controller.ControllerParams{
+			DoFunc:      syncIPSecKeys,
+			RunInterval: time.Minute,
+		})
+
+	return nil
+}
+
+func (d *Daemon) getEncryptionStatus() *models.EncryptionStatus {
+	if !option.Config.EnableIPSec {
+		return &models.EncryptionStatus{Mode: models.EncryptionStatusModeDisabled}
+	}
+
+	spi, keys := ipsec.GetKeyStatus()
+	return &models.EncryptionStatus{
+		Mode: models.EncryptionStatusModeIPsec,
+		Msg:  fmt.Sprintf("SPI %d, %d keys", spi, keys),
+	}
+}
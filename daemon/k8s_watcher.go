@@ -76,6 +76,10 @@ const (
 	cacheSyncTimeout            = time.Duration(3 * time.Minute)
 
 	metricCNP      = "CiliumNetworkPolicy"
+	metricCCNP     = "CiliumClusterwideNetworkPolicy"
+	metricCENP     = "CiliumEgressNATPolicy"
+	metricCN       = "CiliumNode"
+	metricCEP      = "CiliumEndpoint"
 	metricEndpoint = "Endpoint"
 	metricIngress  = "Ingress"
 	metricKNP      = "NetworkPolicy"
@@ -599,6 +603,146 @@ func (d *Daemon) EnableK8sWatcher(reSyncPeriod time.Duration) error {
 		blockWaitGroupToSyncResources(&d.k8sResourceSyncWaitGroup, ciliumV2Controller, "CiliumNetworkPolicy")
 
 		ciliumV2Controller.AddEventHandler(rehf)
+
+		ccnpController := si.Cilium().V2().CiliumClusterwideNetworkPolicies().Informer()
+		ccnpController.AddEventHandler(k8sUtils.ResourceEventHandlerFactory(
+			func(i interface{}) func() error {
+				return func() error {
+					err := d.addCiliumClusterwideNetworkPolicy(i.(*cilium_v2.CiliumClusterwideNetworkPolicy))
+					updateK8sEventMetric(metricCCNP, metricCreate, err == nil)
+					return nil
+				}
+			},
+			func(i interface{}) func() error {
+				return func() error {
+					err := d.deleteCiliumClusterwideNetworkPolicy(i.(*cilium_v2.CiliumClusterwideNetworkPolicy))
+					updateK8sEventMetric(metricCCNP, metricDelete, err == nil)
+					return nil
+				}
+			},
+			func(old, new interface{}) func() error {
+				return func() error {
+					oldCCNP := old.(*cilium_v2.CiliumClusterwideNetworkPolicy)
+					newCCNP := new.(*cilium_v2.CiliumClusterwideNetworkPolicy)
+					// Do not add rule into policy repository if the spec remains unchanged.
+					if oldCCNP.SpecEquals(newCCNP) {
+						return nil
+					}
+					err := d.addCiliumClusterwideNetworkPolicy(newCCNP)
+					updateK8sEventMetric(metricCCNP, metricUpdate, err == nil)
+					return nil
+				}
+			},
+			d.missingCCNP,
+			&cilium_v2.CiliumClusterwideNetworkPolicy{},
+			ciliumNPClient,
+			reSyncPeriod,
+			metrics.EventTSK8s,
+		))
+		blockWaitGroupToSyncResources(&d.k8sResourceSyncWaitGroup, ccnpController, "CiliumClusterwideNetworkPolicy")
+
+		if option.Config.EnableEgressGateway {
+			cenpController := si.Cilium().V2().CiliumEgressNATPolicies().Informer()
+			cenpController.AddEventHandler(k8sUtils.ResourceEventHandlerFactory(
+				func(i interface{}) func() error {
+					return func() error {
+						err := d.addCiliumEgressNATPolicy(i.(*cilium_v2.CiliumEgressNATPolicy))
+						updateK8sEventMetric(metricCENP, metricCreate, err == nil)
+						return nil
+					}
+				},
+				func(i interface{}) func() error {
+					return func() error {
+						err := d.deleteCiliumEgressNATPolicy(i.(*cilium_v2.CiliumEgressNATPolicy))
+						updateK8sEventMetric(metricCENP, metricDelete, err == nil)
+						return nil
+					}
+				},
+				func(old, new interface{}) func() error {
+					return func() error {
+						err := d.addCiliumEgressNATPolicy(new.(*cilium_v2.CiliumEgressNATPolicy))
+						updateK8sEventMetric(metricCENP, metricUpdate, err == nil)
+						return nil
+					}
+				},
+				d.missingCENP,
+				&cilium_v2.CiliumEgressNATPolicy{},
+				ciliumNPClient,
+				reSyncPeriod,
+				metrics.EventTSK8s,
+			))
+			blockWaitGroupToSyncResources(&d.k8sResourceSyncWaitGroup, cenpController, "CiliumEgressNATPolicy")
+		}
+
+		if option.Config.IPAM == option.IPAMClusterPool {
+			cnController := si.Cilium().V2().CiliumNodes().Informer()
+			cnController.AddEventHandler(k8sUtils.ResourceEventHandlerFactory(
+				func(i interface{}) func() error {
+					return func() error {
+						d.updateCiliumNode(i.(*cilium_v2.CiliumNode))
+						updateK8sEventMetric(metricCN, metricCreate, true)
+						return nil
+					}
+				},
+				func(i interface{}) func() error {
+					return func() error {
+						// Pod CIDRs of the local node remain in use
+						// until the agent restarts
+						d.deleteCiliumNode(i.(*cilium_v2.CiliumNode))
+						updateK8sEventMetric(metricCN, metricDelete, true)
+						return nil
+					}
+				},
+				func(old, new interface{}) func() error {
+					return func() error {
+						d.updateCiliumNode(new.(*cilium_v2.CiliumNode))
+						updateK8sEventMetric(metricCN, metricUpdate, true)
+						return nil
+					}
+				},
+				nil,
+				&cilium_v2.CiliumNode{},
+				ciliumNPClient,
+				reSyncPeriod,
+				metrics.EventTSK8s,
+			))
+			blockWaitGroupToSyncResources(&d.k8sResourceSyncWaitGroup, cnController, "CiliumNode")
+		}
+
+		// Without a kvstore, the IP to identity mappings of all
+		// endpoints are learned from their CiliumEndpoints
+		if !option.Config.KVStoreEnabled() {
+			cepController := si.Cilium().V2().CiliumEndpoints().Informer()
+			cepController.AddEventHandler(k8sUtils.ResourceEventHandlerFactory(
+				func(i interface{}) func() error {
+					return func() error {
+						d.updateCiliumEndpoint(nil, i.(*cilium_v2.CiliumEndpoint))
+						updateK8sEventMetric(metricCEP, metricCreate, true)
+						return nil
+					}
+				},
+				func(i interface{}) func() error {
+					return func() error {
+						d.deleteCiliumEndpoint(i.(*cilium_v2.CiliumEndpoint))
+						updateK8sEventMetric(metricCEP, metricDelete, true)
+						return nil
+					}
+				},
+				func(old, new interface{}) func() error {
+					return func() error {
+						d.updateCiliumEndpoint(old.(*cilium_v2.CiliumEndpoint), new.(*cilium_v2.CiliumEndpoint))
+						updateK8sEventMetric(metricCEP, metricUpdate, true)
+						return nil
+					}
+				},
+				nil,
+				&cilium_v2.CiliumEndpoint{},
+				ciliumNPClient,
+				reSyncPeriod,
+				metrics.EventTSK8s,
+			))
+			blockWaitGroupToSyncResources(&d.k8sResourceSyncWaitGroup, cepController, "CiliumEndpoint")
+		}
 	}
 
 	si.Start(wait.NeverStop)
@@ -895,6 +1039,10 @@ func (d *Daemon) delK8sSVCs(svc k8s.ServiceID, svcInfo *k8s.Service, se *k8s.End
 		return nil
 	}
 
+	if d.svcHealthServer != nil {
+		d.svcHealthServer.DeleteService(svc.Namespace, svc.Name)
+	}
+
 	// Headless services do not need any datapath implementation
 	if svcInfo.IsHeadless {
 		return nil
@@ -934,9 +1082,36 @@ func (d *Daemon) delK8sSVCs(svc k8s.ServiceID, svcInfo *k8s.Service, se *k8s.End
 			scopedLog.Debugf("# cilium lb delete-rev-nat %d", svcPort.ID)
 		}
 	}
+
+	for _, frontend := range d.k8sSvcFrontends.Delete(svc) {
+		d.delK8sSVCFrontend(scopedLog, frontend)
+	}
 	return nil
 }
 
+// delK8sSVCFrontend removes the given NodePort, ExternalIPs or LoadBalancer
+// frontend of a Kubernetes service from the datapath
+func (d *Daemon) delK8sSVCFrontend(scopedLog *logrus.Entry, frontend k8s.Frontend) {
+	fe := frontend.L3n4Addr
+	lbSvc := d.svcGetBySHA256Sum(fe.SHA256Sum())
+	if lbSvc == nil {
+		return
+	}
+
+	if err := service.DeleteID(uint32(lbSvc.FE.ID)); err != nil {
+		scopedLog.WithError(err).Warn("Error while cleaning service ID")
+	}
+
+	if err := d.svcDeleteByFrontend(&fe); err != nil {
+		scopedLog.WithError(err).WithField(logfields.Object, logfields.Repr(fe)).
+			Warn("Error deleting service by frontend")
+	}
+
+	if err := d.RevNATDelete(lbSvc.FE.ID); err != nil {
+		scopedLog.WithError(err).WithField(logfields.ServiceID, lbSvc.FE.ID).Warn("Error deleting reverse NAT")
+	}
+}
+
 func (d *Daemon) addK8sSVCs(svcID k8s.ServiceID, svc *k8s.Service, endpoints *k8s.Endpoints) error {
 	// If east-west load balancing is disabled, we should not sync(add or delete)
 	// K8s service to a cilium service.
@@ -944,16 +1119,19 @@ func (d *Daemon) addK8sSVCs(svcID k8s.ServiceID, svc *k8s.Service, endpoints *k8
 		return nil
 	}
 
-	// Headless services do not need any datapath implementation
-	if svc.IsHeadless {
-		return nil
-	}
-
 	scopedLog := log.WithFields(logrus.Fields{
 		logfields.K8sSvcName:   svcID.Name,
 		logfields.K8sNamespace: svcID.Namespace,
 	})
 
+	// Headless services do not need any datapath implementation
+	if svc.IsHeadless {
+		for _, frontend := range d.k8sSvcFrontends.Delete(svcID) {
+			d.delK8sSVCFrontend(scopedLog, frontend)
+		}
+		return nil
+	}
+
 	uniqPorts := svc.UniquePorts()
 
 	for fePortName, fePort := range svc.Ports {
@@ -983,24 +1161,107 @@ func (d *Daemon) addK8sSVCs(svcID k8s.ServiceID, svc *k8s.Service, endpoints *k8
 			fePort.ID = feAddrID.ID
 		}
 
-		besValues := []loadbalancer.LBBackEnd{}
-		for ip, portConfiguration := range endpoints.Backends {
-			if backendPort := portConfiguration[string(fePortName)]; backendPort != nil {
-				besValues = append(besValues, loadbalancer.LBBackEnd{
-					L3n4Addr: loadbalancer.L3n4Addr{IP: net.ParseIP(ip), L4Addr: *backendPort},
-					Weight:   0,
-				})
-			}
-		}
+		besValues := k8sBackends(endpoints, fePortName, false)
 
 		fe := loadbalancer.NewL3n4AddrID(fePort.Protocol, svc.FrontendIP, fePort.Port, fePort.ID)
-		if _, err := d.svcAdd(*fe, besValues, true); err != nil {
+		if _, err := d.svcAdd(*fe, besValues, loadbalancer.SVCTypeClusterIP, svc.SessionAffinityTimeoutSec, svc.LBAlgorithm, true); err != nil {
 			scopedLog.WithError(err).Error("Error while inserting service in LB map")
 		}
 	}
+
+	installed := []k8s.Frontend{}
+	for _, frontend := range svc.ExtraFrontends(nodePortAddrs()) {
+		feAddrID, err := service.AcquireID(frontend.L3n4Addr, 0)
+		if err != nil {
+			scopedLog.WithError(err).WithFields(logrus.Fields{
+				logfields.ServiceID: frontend.PortName,
+				logfields.IPAddr:    frontend.IP,
+				logfields.Port:      frontend.Port,
+				logfields.Protocol:  frontend.Protocol,
+			}).Errorf("Error while getting a new service ID for %s frontend. Ignoring frontend...", frontend.Type)
+			continue
+		}
+
+		// With externalTrafficPolicy=Local, traffic from outside the
+		// cluster is only forwarded to local backends so that the client
+		// source IP can be preserved without SNAT.
+		besValues := k8sBackends(endpoints, frontend.PortName, svc.ExternalTrafficPolicyLocal)
+		if _, err := d.svcAdd(*feAddrID, besValues, frontend.Type, svc.SessionAffinityTimeoutSec, svc.LBAlgorithm, true); err != nil {
+			scopedLog.WithError(err).WithField(logfields.Object, logfields.Repr(frontend)).
+				Error("Error while inserting service frontend in LB map")
+			continue
+		}
+		installed = append(installed, frontend)
+	}
+
+	// Remove the frontends which are no longer part of the service, e.g.
+	// after the removal of an external IP or a change of the node addresses
+	for _, frontend := range d.k8sSvcFrontends.Upsert(svcID, installed) {
+		d.delK8sSVCFrontend(scopedLog, frontend)
+	}
+
+	if d.svcHealthServer != nil {
+		if svc.ExternalTrafficPolicyLocal && svc.HealthCheckNodePort != 0 {
+			d.svcHealthServer.UpsertService(svcID.Namespace, svcID.Name,
+				countLocalBackends(endpoints), svc.HealthCheckNodePort)
+		} else {
+			d.svcHealthServer.DeleteService(svcID.Namespace, svcID.Name)
+		}
+	}
 	return nil
 }
 
+// k8sBackends returns the backends of the given endpoints for the service
+// port with the given name. If localOnly is set, only backends running on
+// the local node are returned.
+func k8sBackends(endpoints *k8s.Endpoints, portName loadbalancer.FEPortName, localOnly bool) []loadbalancer.LBBackEnd {
+	besValues := []loadbalancer.LBBackEnd{}
+	for ip, portConfiguration := range endpoints.Backends {
+		if localOnly && !endpoints.IsLocalBackend(ip, node.GetName()) {
+			continue
+		}
+		if backendPort := portConfiguration[string(portName)]; backendPort != nil {
+			besValues = append(besValues, loadbalancer.LBBackEnd{
+				L3n4Addr: loadbalancer.L3n4Addr{IP: net.ParseIP(ip), L4Addr: *backendPort},
+				Weight:   0,
+			})
+		}
+	}
+	return besValues
+}
+
+// countLocalBackends returns the number of backends of the given endpoints
+// running on the local node
+func countLocalBackends(endpoints *k8s.Endpoints) int {
+	count := 0
+	for ip := range endpoints.Backends {
+		if endpoints.IsLocalBackend(ip, node.GetName()) {
+			count++
+		}
+	}
+	return count
+}
+
+// nodePortAddrs returns the node addresses on which NodePort services are
+// exposed
+func nodePortAddrs() []net.IP {
+	addrs := []net.IP{}
+	if option.Config.EnableIPv4 {
+		if ip := node.GetExternalIPv4(); ip != nil {
+			addrs = append(addrs, ip)
+		}
+		if ip := node.GetInternalIPv4(); ip != nil && !ip.Equal(node.GetExternalIPv4()) {
+			addrs = append(addrs, ip)
+		}
+	}
+	if option.Config.EnableIPv6 {
+		if ip := node.GetIPv6(); ip != nil {
+			addrs = append(addrs, ip)
+		}
+	}
+	return addrs
+}
+
 func (d *Daemon) addIngressV1beta1(ingress *v1beta1.Ingress) error {
 	scopedLog := log.WithFields(logrus.Fields{
 		logfields.K8sIngressName: ingress.ObjectMeta.Name,
@@ -1460,6 +1721,66 @@ func (d *Daemon) missingCNPv2(m versioned.Map) versioned.Map {
 	return missing
 }
 
+func (d *Daemon) addCiliumClusterwideNetworkPolicy(ccnp *cilium_v2.CiliumClusterwideNetworkPolicy) error {
+	scopedLog := log.WithFields(logrus.Fields{
+		logfields.CiliumClusterwideNetworkPolicyName: ccnp.ObjectMeta.Name,
+		logfields.K8sAPIVersion:                      ccnp.TypeMeta.APIVersion,
+	})
+
+	scopedLog.Debug("Adding CiliumClusterwideNetworkPolicy")
+
+	rules, err := ccnp.Parse()
+	if err == nil {
+		err = k8s.PreprocessRules(rules, &d.k8sSvcCache)
+	}
+	if err == nil {
+		// Replace all rules with the same name and
+		// resourceTypeCiliumClusterwideNetworkPolicy
+		_, err = d.PolicyAdd(rules, &AddOptions{
+			ReplaceWithLabels: ccnp.GetIdentityLabels(),
+		})
+	}
+
+	if err != nil {
+		scopedLog.WithError(err).Warn("Unable to add CiliumClusterwideNetworkPolicy")
+	} else {
+		scopedLog.Info("Imported CiliumClusterwideNetworkPolicy")
+	}
+	return err
+}
+
+func (d *Daemon) deleteCiliumClusterwideNetworkPolicy(ccnp *cilium_v2.CiliumClusterwideNetworkPolicy) error {
+	scopedLog := log.WithFields(logrus.Fields{
+		logfields.CiliumClusterwideNetworkPolicyName: ccnp.ObjectMeta.Name,
+		logfields.K8sAPIVersion:                      ccnp.TypeMeta.APIVersion,
+	})
+
+	scopedLog.Debug("Deleting CiliumClusterwideNetworkPolicy")
+
+	_, err := d.PolicyDelete(ccnp.GetIdentityLabels())
+	if err == nil {
+		scopedLog.Info("Deleted CiliumClusterwideNetworkPolicy")
+	} else {
+		scopedLog.WithError(err).Warn("Unable to delete CiliumClusterwideNetworkPolicy")
+	}
+	return err
+}
+
+// missingCCNP returns all missing cluster-wide policies from the given map.
+func (d *Daemon) missingCCNP(m versioned.Map) versioned.Map {
+	missing := versioned.NewMap()
+	d.policy.Mutex.RLock()
+	for k, v := range m {
+		ccnp := v.Data.(*cilium_v2.CiliumClusterwideNetworkPolicy)
+		ruleLabels := ccnp.GetIdentityLabels()
+		if !d.policy.ContainsAllRLocked(labels.LabelArrayList{ruleLabels}) {
+			missing.Add(k, v)
+		}
+	}
+	d.policy.Mutex.RUnlock()
+	return missing
+}
+
 func (d *Daemon) updatePodHostIP(pod *v1.Pod) (bool, error) {
 	if pod.Spec.HostNetwork {
 		return true, fmt.Errorf("pod is using host networking")
@@ -1517,6 +1838,8 @@ func (d *Daemon) deletePodHostIP(pod *v1.Pod) (bool, error) {
 }
 
 func (d *Daemon) addK8sPodV1(pod *v1.Pod) error {
+	d.updateEgressGatewayPod(pod)
+
 	logger := log.WithFields(logrus.Fields{
 		logfields.K8sPodName:   pod.ObjectMeta.Name,
 		logfields.K8sNamespace: pod.ObjectMeta.Namespace,
@@ -1546,10 +1869,17 @@ func (d *Daemon) updateK8sPodV1(oldK8sPod, newK8sPod *v1.Pod) error {
 	// assigned
 	d.addK8sPodV1(newK8sPod)
 
-	// We only care about label updates
+	// We only care about label, egress bandwidth and visibility updates
 	oldPodLabels := oldK8sPod.GetLabels()
 	newPodLabels := newK8sPod.GetLabels()
-	if comparator.MapStringEquals(oldPodLabels, newPodLabels) {
+	labelsChanged := !comparator.MapStringEquals(oldPodLabels, newPodLabels)
+	oldBandwidth := oldK8sPod.GetAnnotations()[annotation.EgressBandwidth]
+	newBandwidth := newK8sPod.GetAnnotations()[annotation.EgressBandwidth]
+	bandwidthChanged := oldBandwidth != newBandwidth
+	oldVisibility := oldK8sPod.GetAnnotations()[annotation.ProxyVisibility]
+	newVisibility := newK8sPod.GetAnnotations()[annotation.ProxyVisibility]
+	visibilityChanged := oldVisibility != newVisibility
+	if !labelsChanged && !bandwidthChanged && !visibilityChanged {
 		return nil
 	}
 
@@ -1561,6 +1891,34 @@ func (d *Daemon) updateK8sPodV1(oldK8sPod, newK8sPod *v1.Pod) error {
 		return nil
 	}
 
+	if bandwidthChanged {
+		rate, err := getEgressBandwidth(newK8sPod.GetAnnotations())
+		if err != nil {
+			log.WithError(err).WithField("pod", podNSName).
+				Warningf("Ignoring invalid %s annotation", annotation.EgressBandwidth)
+		}
+		if err := podEP.UpdateEgressBandwidth(d, rate); err != nil {
+			log.WithError(err).Debugf("error while updating endpoint egress bandwidth")
+			return err
+		}
+	}
+
+	if visibilityChanged {
+		vp, err := getVisibilityPolicy(newK8sPod.GetAnnotations())
+		if err != nil {
+			log.WithError(err).WithField("pod", podNSName).
+				Warningf("Ignoring invalid %s annotation", annotation.ProxyVisibility)
+		}
+		if err := podEP.UpdateVisibilityPolicy(d, vp); err != nil {
+			log.WithError(err).Debugf("error while updating endpoint visibility policy")
+			return err
+		}
+	}
+
+	if !labelsChanged {
+		return nil
+	}
+
 	newLabels := labels.Map2Labels(newPodLabels, labels.LabelSourceK8s)
 	newIdtyLabels, _ := labels.FilterLabels(newLabels)
 	oldLabels := labels.Map2Labels(oldPodLabels, labels.LabelSourceK8s)
@@ -1580,6 +1938,8 @@ func (d *Daemon) updateK8sPodV1(oldK8sPod, newK8sPod *v1.Pod) error {
 }
 
 func (d *Daemon) deleteK8sPodV1(pod *v1.Pod) error {
+	d.deleteEgressGatewayPod(pod)
+
 	logger := log.WithFields(logrus.Fields{
 		logfields.K8sPodName:   pod.ObjectMeta.Name,
 		logfields.K8sNamespace: pod.ObjectMeta.Namespace,
@@ -1721,6 +2081,7 @@ func (d *Daemon) updateK8sNodeTunneling(k8sNodeOld, k8sNodeNew *v1.Node) error {
 	if nodeNew.Name == node.GetName() {
 		return nil
 	}
+	nodeNew.IPv4SecondaryAllocCIDRs = d.getRemoteIPv4PodCIDRs(nodeNew.Name)
 
 	getIDs := func(node *node.Node, k8sNode *v1.Node) (string, net.IP, error) {
 		if node == nil {
@@ -1754,6 +2115,7 @@ func (d *Daemon) updateK8sNodeTunneling(k8sNodeOld, k8sNodeNew *v1.Node) error {
 
 	if k8sNodeOld != nil {
 		nodeOld := k8s.ParseNode(k8sNodeOld, node.FromKubernetes)
+		nodeOld.IPv4SecondaryAllocCIDRs = nodeNew.IPv4SecondaryAllocCIDRs
 		var (
 			err            error
 			ciliumIPStrOld string
@@ -1804,6 +2166,8 @@ func (d *Daemon) addK8sNodeV1(k8sNode *v1.Node) error {
 		log.WithError(err).Warning("Unable to add ipcache entry of Kubernetes node")
 		return err
 	}
+	d.updateHostEndpointLabels(k8sNode)
+	d.updateEgressGatewayNode(k8sNode)
 	return nil
 }
 
@@ -1812,10 +2176,14 @@ func (d *Daemon) updateK8sNodeV1(k8sNodeOld, k8sNodeNew *v1.Node) error {
 		log.WithError(err).Warning("Unable to update ipcache entry of Kubernetes node")
 		return err
 	}
+	d.updateHostEndpointLabels(k8sNodeNew)
+	d.updateEgressGatewayNode(k8sNodeNew)
 	return nil
 }
 
 func (d *Daemon) deleteK8sNodeV1(k8sNode *v1.Node) error {
+	d.deleteEgressGatewayNode(k8sNode)
+
 	ip := k8sNode.GetAnnotations()[annotation.CiliumHostIP]
 
 	logger := log.WithFields(logrus.Fields{
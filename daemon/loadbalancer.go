@@ -30,13 +30,25 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// useMaglev returns true if the backends of a service with the given load
+// balancing algorithm must be selected with Maglev consistent hashing.
+func useMaglev(lbAlgorithm loadbalancer.SVCLBAlgorithm) bool {
+	if lbAlgorithm == loadbalancer.SVCLBAlgorithmDefault {
+		return option.Config.LBAlgorithm == option.LBAlgorithmMaglev
+	}
+	return lbAlgorithm == loadbalancer.SVCLBAlgorithmMaglev
+}
+
 // addSVC2BPFMap adds the given bpf service to the bpf maps. If addRevNAT is set, adds the
 // RevNAT value (feCilium.L3n4Addr) to the lb's RevNAT map for the given feCilium.ID.
+// A non-zero affinityTimeoutSec enables client IP based session affinity.
 func (d *Daemon) addSVC2BPFMap(feCilium loadbalancer.L3n4AddrID, feBPF lbmap.ServiceKey,
-	besBPF []lbmap.ServiceValue, addRevNAT bool) error {
+	besBPF []lbmap.ServiceValue, addRevNAT bool, affinityTimeoutSec uint32,
+	lbAlgorithm loadbalancer.SVCLBAlgorithm) error {
 	log.WithField(logfields.ServiceName, feCilium.String()).Debug("adding service to BPF maps")
 
-	if err := lbmap.UpdateService(feBPF, besBPF, addRevNAT, int(feCilium.ID)); err != nil {
+	if err := lbmap.UpdateService(feBPF, besBPF, addRevNAT, int(feCilium.ID), affinityTimeoutSec,
+		useMaglev(lbAlgorithm)); err != nil {
 		if addRevNAT {
 			delete(d.loadBalancer.RevNATMap, feCilium.ID)
 		}
@@ -83,33 +95,47 @@ func (d *Daemon) SVCAdd(feL3n4Addr loadbalancer.L3n4AddrID, be []loadbalancer.LB
 		return false, fmt.Errorf("service ID %d is already registered to L3n4Addr %s, please choose a different ID", feL3n4Addr.ID, feAddr.String())
 	}
 
-	return d.svcAdd(feL3n4Addr, be, addRevNAT)
+	return d.svcAdd(feL3n4Addr, be, loadbalancer.SVCTypeNone, 0, loadbalancer.SVCLBAlgorithmDefault, addRevNAT)
 }
 
-// svcAdd adds a service from the given feL3n4Addr (frontend) and LBBackEnd (backends).
-// If addRevNAT is set, the RevNAT entry is also created for this particular service.
+// svcAdd adds a service of type svcType from the given feL3n4Addr (frontend) and
+// LBBackEnd (backends). If addRevNAT is set, the RevNAT entry is also created for this particular service.
+// If affinityTimeoutSec is non-zero, clients stick to the backend selected for
+// them until no packet has been seen for the given number of seconds.
+// lbAlgorithm selects the algorithm used to pick the backend of new connections.
 // If any of the backend addresses set in bes have a different L3 address type than the
 // one set in fe, it returns an error without modifying the bpf LB map. If any backend
 // entry fails while updating the LB map, the frontend won't be inserted in the LB map
 // therefore there won't be any traffic going to the given backends.
 // All of the backends added will be DeepCopied to the internal load balancer map.
-func (d *Daemon) svcAdd(feL3n4Addr loadbalancer.L3n4AddrID, bes []loadbalancer.LBBackEnd, addRevNAT bool) (bool, error) {
+func (d *Daemon) svcAdd(feL3n4Addr loadbalancer.L3n4AddrID, bes []loadbalancer.LBBackEnd, svcType loadbalancer.SVCType,
+	affinityTimeoutSec uint32, lbAlgorithm loadbalancer.SVCLBAlgorithm, addRevNAT bool) (bool, error) {
 	log.WithFields(logrus.Fields{
 		logfields.ServiceID: feL3n4Addr.String(),
 		logfields.Object:    logfields.Repr(bes),
 	}).Debug("adding service")
 
-	// Move the slice to the loadbalancer map which has a mutex. If we don't
-	// copy the slice we might risk changing memory that should be locked.
-	beCpy := []loadbalancer.LBBackEnd{}
-	for _, v := range bes {
-		beCpy = append(beCpy, v)
+	d.loadBalancer.BPFMapMU.Lock()
+	defer d.loadBalancer.BPFMapMU.Unlock()
+
+	// Keep the backends in the slots they are installed in, the session
+	// affinity map refers to backends by slot. The returned slice is a copy
+	// which can be moved to the loadbalancer map.
+	var prevBackends []loadbalancer.LBBackEnd
+	if prevSvc, ok := d.loadBalancer.SVCMap[feL3n4Addr.L3n4Addr.SHA256Sum()]; ok {
+		prevBackends = prevSvc.BES
 	}
+	beCpy := loadbalancer.AssignBackendSlots(prevBackends, bes)
 
 	svc := loadbalancer.LBSVC{
 		FE:     feL3n4Addr,
 		BES:    beCpy,
 		Sha256: feL3n4Addr.L3n4Addr.SHA256Sum(),
+		Type:   svcType,
+
+		SessionAffinity:           affinityTimeoutSec != 0,
+		SessionAffinityTimeoutSec: affinityTimeoutSec,
+		LBAlgorithm:               lbAlgorithm,
 	}
 
 	fe, besValues, err := lbmap.LBSVC2ServiceKeynValue(svc)
@@ -118,10 +144,7 @@ func (d *Daemon) svcAdd(feL3n4Addr loadbalancer.L3n4AddrID, bes []loadbalancer.L
 		return false, err
 	}
 
-	d.loadBalancer.BPFMapMU.Lock()
-	defer d.loadBalancer.BPFMapMU.Unlock()
-
-	err = d.addSVC2BPFMap(feL3n4Addr, fe, besValues, addRevNAT)
+	err = d.addSVC2BPFMap(feL3n4Addr, fe, besValues, addRevNAT, affinityTimeoutSec, lbAlgorithm)
 	if err != nil {
 		return false, err
 	}
@@ -322,8 +345,13 @@ func (d *Daemon) svcGetBySHA256Sum(feL3n4SHA256Sum string) *loadbalancer.LBSVC {
 		beCpy = append(beCpy, v)
 	}
 	return &loadbalancer.LBSVC{
-		FE:  *v.FE.DeepCopy(),
-		BES: beCpy,
+		FE:   *v.FE.DeepCopy(),
+		BES:  beCpy,
+		Type: v.Type,
+
+		SessionAffinity:           v.SessionAffinity,
+		SessionAffinityTimeoutSec: v.SessionAffinityTimeoutSec,
+		LBAlgorithm:               v.LBAlgorithm,
 	}
 }
 
@@ -438,6 +466,12 @@ func openServiceMaps() error {
 		if _, err := lbmap.RRSeq6Map.OpenOrCreate(); err != nil {
 			return err
 		}
+		if _, err := lbmap.Affinity6Map.OpenOrCreate(); err != nil {
+			return err
+		}
+		if _, err := lbmap.Maglev6Map.OpenOrCreate(); err != nil {
+			return err
+		}
 		if _, err := proxymap.Proxy6Map.OpenOrCreate(); err != nil {
 			return err
 		}
@@ -453,6 +487,12 @@ func openServiceMaps() error {
 		if _, err := lbmap.RRSeq4Map.OpenOrCreate(); err != nil {
 			return err
 		}
+		if _, err := lbmap.Affinity4Map.OpenOrCreate(); err != nil {
+			return err
+		}
+		if _, err := lbmap.Maglev4Map.OpenOrCreate(); err != nil {
+			return err
+		}
 		if _, err := proxymap.Proxy4Map.OpenOrCreate(); err != nil {
 			return err
 		}
@@ -560,7 +600,7 @@ func (d *Daemon) SyncLBMap() error {
 				" This entry will be removed from the bpf's LB map.", svc.FE.String(), svc.BES, err)
 		}
 
-		err = d.addSVC2BPFMap(svc.FE, fe, besValues, false)
+		err = d.addSVC2BPFMap(svc.FE, fe, besValues, false, svc.SessionAffinityTimeoutSec, svc.LBAlgorithm)
 		if err != nil {
 			return fmt.Errorf("Unable to add service FE: %s: %s."+
 				" This entry will be removed from the bpf's LB map.", svc.FE.String(), err)
@@ -678,7 +718,7 @@ func (d *Daemon) syncLBMapsWithK8s() error {
 	k8sDeletedRevNATS := make(map[loadbalancer.ServiceID]bool)
 
 	// Set of L3n4Addrs in string form for storage as a key in map.
-	k8sServicesFrontendAddresses := d.k8sSvcCache.UniqueServiceFrontends()
+	k8sServicesFrontendAddresses := d.k8sSvcCache.UniqueServiceFrontends(nodePortAddrs())
 
 	d.loadBalancer.BPFMapMU.Lock()
 	defer d.loadBalancer.BPFMapMU.Unlock()
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 
 	"github.com/cilium/cilium/api/v1/models"
@@ -26,6 +27,7 @@ import (
 	"github.com/cilium/cilium/pkg/api"
 	"github.com/cilium/cilium/pkg/endpoint"
 	"github.com/cilium/cilium/pkg/endpointmanager"
+	"github.com/cilium/cilium/pkg/identity/cache"
 	"github.com/cilium/cilium/pkg/ipcache"
 	"github.com/cilium/cilium/pkg/labels"
 	"github.com/cilium/cilium/pkg/logging/logfields"
@@ -398,6 +400,49 @@ func (h *putPolicy) Handle(params PutPolicyParams) middleware.Responder {
 	return NewPutPolicyOK().WithPayload(policy)
 }
 
+type postPolicyPreview struct {
+	daemon *Daemon
+}
+
+func newPostPolicyPreviewHandler(d *Daemon) PostPolicyPreviewHandler {
+	return &postPolicyPreview{daemon: d}
+}
+
+func (h *postPolicyPreview) Handle(params PostPolicyPreviewParams) middleware.Responder {
+	d := h.daemon
+
+	var rules policyAPI.Rules
+	if err := json.Unmarshal([]byte(*params.Policy), &rules); err != nil {
+		return NewPostPolicyPreviewInvalidPolicy()
+	}
+
+	for _, r := range rules {
+		if err := r.Sanitize(); err != nil {
+			return api.Error(PostPolicyPreviewFailureCode, err)
+		}
+	}
+
+	// Resolve the policy against a copy of the repository so that the
+	// preview neither blocks policy updates nor modifies any endpoint.
+	d.policy.Mutex.RLock()
+	repo := d.policy.CopyWithRulesRLocked(rules)
+	d.policy.Mutex.RUnlock()
+
+	identityCache := cache.GetIdentityCache()
+	preview := &models.PolicyPreview{
+		Revision:  int64(repo.GetRevision()),
+		Endpoints: []*models.EndpointPolicyPreview{},
+	}
+	for _, ep := range endpointmanager.GetEndpoints() {
+		preview.Endpoints = append(preview.Endpoints, ep.GetPolicyPreview(repo, identityCache))
+	}
+	sort.Slice(preview.Endpoints, func(i, j int) bool {
+		return preview.Endpoints[i].ID < preview.Endpoints[j].ID
+	})
+
+	return NewPostPolicyPreviewOK().WithPayload(preview)
+}
+
 type getPolicy struct {
 	daemon *Daemon
 }
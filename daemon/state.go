@@ -92,9 +92,9 @@ func (d *Daemon) restoreOldEndpoints(dir string, clean bool) (*endpointRestoreSt
 		scopedLog := log.WithField(logfields.EndpointID, ep.ID)
 		skipRestore := false
 
-		// On each restart, the health endpoint is supposed to be recreated.
-		// Hence we need to clean health endpoint state unconditionally.
-		if ep.HasLabels(labels.LabelHealth) {
+		// On each restart, the health and host endpoints are supposed to be
+		// recreated. Hence we need to clean their state unconditionally.
+		if ep.HasLabels(labels.LabelHealth) || ep.HasLabels(labels.LabelHost) {
 			skipRestore = true
 		} else {
 			if ep.K8sPodName != "" && ep.K8sNamespace != "" && k8s.IsEnabled() {
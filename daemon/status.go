@@ -25,6 +25,7 @@ import (
 	"github.com/cilium/cilium/pkg/kvstore"
 	"github.com/cilium/cilium/pkg/node"
 	"github.com/cilium/cilium/pkg/option"
+	"github.com/cilium/cilium/pkg/sockops"
 	"github.com/cilium/cilium/pkg/status"
 	"github.com/cilium/cilium/pkg/workloads"
 
@@ -114,7 +115,8 @@ func (d *Daemon) getStatus() models.StatusResponse {
 			State: models.StatusStateWarning,
 			Msg:   "Stale status data",
 		}
-	case sr.Kvstore != nil && sr.Kvstore.State != models.StatusStateOk:
+	case sr.Kvstore != nil && sr.Kvstore.State != models.StatusStateOk &&
+		sr.Kvstore.State != models.StatusStateDisabled:
 		sr.Cilium = &models.Status{
 			State: sr.Kvstore.State,
 			Msg:   "Kvstore service is not ready",
@@ -156,6 +158,9 @@ func (d *Daemon) startStatusCollector() {
 		{
 			Name: "kvstore",
 			Probe: func(ctx context.Context) (interface{}, error) {
+				if !option.Config.KVStoreEnabled() {
+					return nil, nil
+				}
 				return kvstore.Client().Status()
 			},
 			OnStatusUpdate: func(status status.Status) {
@@ -164,6 +169,8 @@ func (d *Daemon) startStatusCollector() {
 				info, ok := status.Data.(string)
 
 				switch {
+				case !option.Config.KVStoreEnabled():
+					state = models.StatusStateDisabled
 				case ok && status.Err != nil:
 					state = models.StatusStateFailure
 					msg = fmt.Sprintf("Err: %s - %s", status.Err, info)
@@ -333,6 +340,39 @@ func (d *Daemon) startStatusCollector() {
 				}
 			},
 		},
+		{
+			Name: "encryption",
+			Probe: func(ctx context.Context) (interface{}, error) {
+				return d.getEncryptionStatus(), nil
+			},
+			OnStatusUpdate: func(status status.Status) {
+				d.statusCollectMutex.Lock()
+				defer d.statusCollectMutex.Unlock()
+
+				// EncryptionStatus has no way to report errors
+				if status.Err == nil {
+					if s, ok := status.Data.(*models.EncryptionStatus); ok {
+						d.statusResponse.Encryption = s
+					}
+				}
+			},
+		},
+		{
+			Name: "sockmap",
+			Probe: func(ctx context.Context) (interface{}, error) {
+				return sockops.Status(), nil
+			},
+			OnStatusUpdate: func(status status.Status) {
+				d.statusCollectMutex.Lock()
+				defer d.statusCollectMutex.Unlock()
+
+				if status.Err == nil {
+					if s, ok := status.Data.(*models.Status); ok {
+						d.statusResponse.Sockmap = s
+					}
+				}
+			},
+		},
 		{
 			Name: "controllers",
 			Probe: func(ctx context.Context) (interface{}, error) {
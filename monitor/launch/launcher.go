@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -53,12 +54,16 @@ type NodeMonitor struct {
 	lostLast uint64
 
 	queue chan []byte
+
+	// flowBufferSize is the number of flows kept by the flow observer
+	flowBufferSize int
 }
 
 // NewNodeMonitor returns a new node monitor
-func NewNodeMonitor(queueSize int) *NodeMonitor {
+func NewNodeMonitor(queueSize, flowBufferSize int) *NodeMonitor {
 	nm := &NodeMonitor{
-		queue: make(chan []byte, queueSize),
+		queue:          make(chan []byte, queueSize),
+		flowBufferSize: flowBufferSize,
 	}
 
 	go nm.eventDrainer()
@@ -95,7 +100,7 @@ func (nm *NodeMonitor) run(sockPath, bpfRoot string) error {
 	nm.pipe = pipe
 	nm.pipeLock.Unlock()
 
-	nm.Launcher.SetArgs([]string{"--bpf-root", bpfRoot})
+	nm.Launcher.SetArgs([]string{"--bpf-root", bpfRoot, "--flow-buffer-size", strconv.Itoa(nm.flowBufferSize)})
 	if err := nm.Launcher.Run(); err != nil {
 		return err
 	}
@@ -40,6 +40,10 @@ const (
 
 	// Version1_2 is the API 1.0 version of the protocol (see above).
 	Version1_2 = Version("1.2")
+
+	// VersionObserver is the version of the flow observer running inside the
+	// node monitor, which consumes payloads directly instead of over a socket.
+	VersionObserver = Version("observer")
 )
 
 // MonitorListener is a generic consumer of monitor events. Implementers are
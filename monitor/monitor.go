@@ -31,6 +31,7 @@ import (
 	"github.com/cilium/cilium/pkg/defaults"
 	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/monitor/observer"
 	"github.com/cilium/cilium/pkg/monitor/payload"
 	"github.com/sirupsen/logrus"
 )
@@ -102,8 +103,9 @@ func (m *Monitor) agentPipeReader(ctx context.Context, agentPipe io.Reader) {
 // NewMonitor creates a Monitor, and starts client connection handling and agent event
 // handling.
 // Note that the perf buffer reader is started only when listeners are
-// connected.
-func NewMonitor(ctx context.Context, nPages int, agentPipe io.Reader, server1_0, server1_2 net.Listener) (m *Monitor, err error) {
+// connected. If flowObserver is not nil, it is registered as a permanent
+// listener and the perf buffer reader is started right away.
+func NewMonitor(ctx context.Context, nPages int, agentPipe io.Reader, server1_0, server1_2 net.Listener, flowObserver *observer.Observer) (m *Monitor, err error) {
 	m = &Monitor{
 		ctx:              ctx,
 		listeners:        make(map[listener.MonitorListener]struct{}),
@@ -111,6 +113,10 @@ func NewMonitor(ctx context.Context, nPages int, agentPipe io.Reader, server1_0,
 		perfReaderCancel: func() {}, // no-op to avoid doing null checks everywhere
 	}
 
+	if flowObserver != nil {
+		m.registerObserver(ctx, flowObserver)
+	}
+
 	// start new MonitorListener handler
 	go m.connectionHandler1_0(ctx, server1_0)
 	go m.connectionHandler1_2(ctx, server1_2)
@@ -158,6 +164,22 @@ func (m *Monitor) registerNewListener(parentCtx context.Context, conn net.Conn,
 	}).Debug("New listener connected")
 }
 
+// registerObserver adds the flow observer as a listener. It is never removed,
+// so the perf reader started here keeps running until parentCtx is cancelled.
+func (m *Monitor) registerObserver(parentCtx context.Context, flowObserver *observer.Observer) {
+	m.Lock()
+	defer m.Unlock()
+
+	if len(m.listeners) == 0 {
+		m.perfReaderCancel()
+		perfEventReaderCtx, cancel := context.WithCancel(parentCtx)
+		m.perfReaderCancel = cancel
+		go m.perfEventReader(perfEventReaderCtx, m.nPages)
+	}
+
+	m.listeners[&listenerObserver{flowObserver}] = struct{}{}
+}
+
 // removeListener deletes the MonitorListener from the list, closes its queue, and
 // stops perfReader if this is the last MonitorListener
 func (m *Monitor) removeListener(ml listener.MonitorListener) {
@@ -22,15 +22,20 @@ import (
 	"path"
 	"syscall"
 
+	"github.com/cilium/cilium/api/v1/flow"
 	"github.com/cilium/cilium/common"
 	"github.com/cilium/cilium/pkg/api"
 	"github.com/cilium/cilium/pkg/bpf"
+	"github.com/cilium/cilium/pkg/client"
 	"github.com/cilium/cilium/pkg/defaults"
 	"github.com/cilium/cilium/pkg/logging"
 	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/monitor/observer"
+	"github.com/cilium/cilium/pkg/node"
 
 	gops "github.com/google/gops/agent"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
 )
 
 var (
@@ -54,11 +59,16 @@ var (
 	// bpfRoot is the path to the BPF mount. This can be non-default if
 	// cilium-agent mounts bpf at an alternate location.
 	bpfRoot string
+
+	// flowBufferSize is the number of flows kept by the flow observer, the
+	// observer is disabled if zero.
+	flowBufferSize int
 )
 
 func init() {
 	rootCmd.Flags().IntVar(&npages, "num-pages", 64, "Number of pages for ring buffer")
 	rootCmd.Flags().StringVar(&bpfRoot, "bpf-root", "/sys/fs/bpf", "Path to the root of the bpf mount")
+	rootCmd.Flags().IntVar(&flowBufferSize, "flow-buffer-size", 0, "Number of flows kept by the flow observer (0 to disable)")
 }
 
 // Execute is an entry point for node monitor
@@ -117,7 +127,23 @@ func runNodeMonitor() {
 
 	mainCtx, mainCtxCancel := context.WithCancel(context.Background())
 
-	monitorSingleton, err = NewMonitor(mainCtx, npages, pipe, server1_0, server1_2)
+	var flowObserver *observer.Observer
+	if flowBufferSize > 0 {
+		flowObserver = newFlowObserver(mainCtx)
+
+		observerServer := buildServerOrExit(defaults.ObserverSockPath)
+		grpcServer := grpc.NewServer()
+		flow.RegisterObserverServer(grpcServer, flowObserver)
+		go func() {
+			if err := grpcServer.Serve(observerServer); err != nil {
+				log.WithError(err).Error("Error while serving flow observer API")
+			}
+		}()
+		defer grpcServer.Stop() // Stop accepting new flow observer connections
+		log.Infof("Serving flow observer API at unix://%s", defaults.ObserverSockPath)
+	}
+
+	monitorSingleton, err = NewMonitor(mainCtx, npages, pipe, server1_0, server1_2, flowObserver)
 	if err != nil {
 		log.WithError(err).Fatal("Error initialising monitor handlers")
 	}
@@ -128,3 +154,20 @@ func runNodeMonitor() {
 	log.WithField(logfields.Signal, sig).Info("Exiting due to signal")
 	mainCtxCancel() // Signal a shutdown to spawned goroutines
 }
+
+// newFlowObserver creates the flow observer and starts decoding flows. Flows
+// are enriched with pod names and labels retrieved from the cilium-agent API
+// if it is reachable.
+func newFlowObserver(ctx context.Context) *observer.Observer {
+	var resolver observer.Resolver
+	if c, err := client.NewDefaultClient(); err != nil {
+		log.WithError(err).Warn("Unable to connect to cilium-agent, flows will not include pod names and labels")
+	} else {
+		resolver = observer.NewAgentResolver(c)
+	}
+
+	flowObserver := observer.NewObserver(flowBufferSize, queueSize, observer.NewParser(resolver, node.GetName()))
+	go flowObserver.Start(ctx)
+
+	return flowObserver
+}
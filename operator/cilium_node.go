@@ -0,0 +1,139 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cilium/cilium/pkg/backoff"
+	"github.com/cilium/cilium/pkg/defaults"
+	"github.com/cilium/cilium/pkg/ipam/clusterpool"
+	cilium_v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	informer "github.com/cilium/cilium/pkg/k8s/client/informers/externalversions"
+	k8sUtils "github.com/cilium/cilium/pkg/k8s/utils"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/metrics"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var nodeAllocator *clusterpool.NodeAllocator
+
+// startClusterPoolAllocator starts allocating pod CIDRs to CiliumNodes out of
+// the configured cluster pools
+func startClusterPoolAllocator() error {
+	var ipv4, ipv6 *clusterpool.Allocator
+
+	if len(clusterPoolIPv4CIDRs) == 0 && len(clusterPoolIPv6CIDRs) == 0 {
+		return fmt.Errorf("at least one of --cluster-pool-ipv4-cidr and --cluster-pool-ipv6-cidr must be specified")
+	}
+
+	if len(clusterPoolIPv4CIDRs) > 0 {
+		a, err := clusterpool.NewAllocator(clusterPoolIPv4CIDRs, clusterPoolIPv4MaskSize)
+		if err != nil {
+			return fmt.Errorf("unable to create IPv4 allocator: %s", err)
+		}
+		ipv4 = a
+	}
+
+	if len(clusterPoolIPv6CIDRs) > 0 {
+		a, err := clusterpool.NewAllocator(clusterPoolIPv6CIDRs, defaults.IPv6NodePrefixLen)
+		if err != nil {
+			return fmt.Errorf("unable to create IPv6 allocator: %s", err)
+		}
+		ipv6 = a
+	}
+
+	nodeAllocator = clusterpool.NewNodeAllocator(ipv4, ipv6)
+
+	// Occupy the CIDRs of all existing nodes before allocating any new
+	// CIDRs to avoid handing out CIDRs which are already in use
+	// CiliumNodes cannot be listed until the first agent has created the
+	// CiliumNode CRD
+	var nodes *cilium_v2.CiliumNodeList
+	listBackoff := backoff.Exponential{
+		Min:  time.Second,
+		Max:  time.Minute,
+		Name: "ciliumnode-list",
+	}
+	for {
+		var err error
+		nodes, err = ciliumK8sClient.CiliumV2().CiliumNodes().List(meta_v1.ListOptions{})
+		if err == nil {
+			break
+		}
+		log.WithError(err).Warning("Unable to list CiliumNodes, retrying")
+		listBackoff.Wait()
+	}
+	for i := range nodes.Items {
+		nodeAllocator.Restore(&nodes.Items[i])
+	}
+
+	watcher := k8sUtils.ResourceEventHandlerFactory(
+		func(i interface{}) func() error {
+			return func() error {
+				updateCiliumNode(i.(*cilium_v2.CiliumNode))
+				return nil
+			}
+		},
+		func(i interface{}) func() error {
+			return func() error {
+				nodeAllocator.Delete(i.(*cilium_v2.CiliumNode).Name)
+				return nil
+			}
+		},
+		func(old, new interface{}) func() error {
+			return func() error {
+				updateCiliumNode(new.(*cilium_v2.CiliumNode))
+				return nil
+			}
+		},
+		nil,
+		&cilium_v2.CiliumNode{},
+		ciliumK8sClient,
+		reSyncPeriod,
+		metrics.EventTSK8s,
+	)
+
+	si := informer.NewSharedInformerFactory(ciliumK8sClient, reSyncPeriod)
+	ciliumNodeInformer := si.Cilium().V2().CiliumNodes().Informer()
+	ciliumNodeInformer.AddEventHandler(watcher)
+	si.Start(wait.NeverStop)
+
+	return nil
+}
+
+// updateCiliumNode allocates pod CIDRs to the given node according to its
+// requirements and records them in the node spec
+func updateCiliumNode(node *cilium_v2.CiliumNode) {
+	scopedLog := log.WithField(logfields.NodeName, node.Name)
+
+	podCIDRs, changed, err := nodeAllocator.Update(node)
+	if err != nil {
+		scopedLog.WithError(err).Warning("Unable to allocate all requested pod CIDRs to node")
+	}
+
+	if !changed {
+		return
+	}
+
+	node = node.DeepCopy()
+	node.Spec.IPAM.PodCIDRs = podCIDRs
+	if _, err := ciliumK8sClient.CiliumV2().CiliumNodes().Update(node); err != nil {
+		scopedLog.WithError(err).Warning("Unable to update pod CIDRs of CiliumNode")
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/cilium/cilium/pkg/controller"
+	"github.com/cilium/cilium/pkg/k8s/identitybackend"
+)
+
+var (
+	// identityGCInterval is the interval in which unused CiliumIdentities
+	// are garbage collected
+	identityGCInterval time.Duration
+)
+
+// startCRDIdentityGC starts the garbage collector of CiliumIdentities. It
+// removes references which the owning node has not renewed in time and
+// deletes all identities which are no longer referenced by any node.
+func startCRDIdentityGC() {
+	log.WithField("interval", identityGCInterval).Info("Starting CiliumIdentity garbage collector")
+
+	// this dummy manager is needed only to add this controller to the global list
+	controller.NewManager().UpdateController("crd-identity-gc",
+		controller.ControllerParams{
+			RunInterval: identityGCInterval,
+			DoFunc: func() error {
+				return identitybackend.GarbageCollect(ciliumK8sClient, identitybackend.ReferenceTTL)
+			},
+		})
+}
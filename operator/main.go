@@ -55,6 +55,13 @@ var (
 	synchronizeServices bool
 	enableCepGC         bool
 
+	ipamMode                string
+	clusterPoolIPv4CIDRs    []string
+	clusterPoolIPv4MaskSize int
+	clusterPoolIPv6CIDRs    []string
+
+	identityAllocationMode string
+
 	ciliumK8sClient clientset.Interface
 )
 
@@ -98,6 +105,14 @@ func init() {
 	flags.BoolVar(&synchronizeServices, "synchronize-k8s-services", true, "Synchronize Kubernetes services to kvstore")
 	flags.BoolVar(&enableCepGC, "cilium-endpoint-gc", true, "Enable CiliumEndpoint garbage collector")
 
+	flags.StringVar(&ipamMode, option.IPAMName, option.IPAMHostScope, "IP address management mode (host-scope, cluster-pool)")
+	flags.StringSliceVar(&clusterPoolIPv4CIDRs, "cluster-pool-ipv4-cidr", []string{}, "IPv4 CIDR pools to allocate node pod CIDRs from in cluster-pool IPAM mode")
+	flags.IntVar(&clusterPoolIPv4MaskSize, "cluster-pool-ipv4-mask-size", 24, "Mask size of the IPv4 pod CIDRs allocated to nodes in cluster-pool IPAM mode")
+	flags.StringSliceVar(&clusterPoolIPv6CIDRs, "cluster-pool-ipv6-cidr", []string{}, fmt.Sprintf("IPv6 CIDR pools to allocate node /%d pod CIDRs from in cluster-pool IPAM mode", defaults.IPv6NodePrefixLen))
+
+	flags.StringVar(&identityAllocationMode, option.IdentityAllocationModeName, option.IdentityAllocationModeKVstore, "Method to use for identity allocation (kvstore, crd)")
+	flags.DurationVar(&identityGCInterval, "identity-gc-interval", 10*time.Minute, "GC interval for CiliumIdentities in CRD identity allocation mode")
+
 	viper.BindPFlags(flags)
 }
 
@@ -150,6 +165,24 @@ func runOperator(cmd *cobra.Command) {
 		enableCiliumEndpointSyncGC()
 	}
 
+	switch ipamMode {
+	case option.IPAMHostScope:
+	case option.IPAMClusterPool:
+		if err := startClusterPoolAllocator(); err != nil {
+			log.WithError(err).Fatal("Unable to start cluster-pool IPAM allocator")
+		}
+	default:
+		log.Fatalf("Invalid IPAM mode '%s'", ipamMode)
+	}
+
+	switch identityAllocationMode {
+	case option.IdentityAllocationModeKVstore:
+	case option.IdentityAllocationModeCRD:
+		startCRDIdentityGC()
+	default:
+		log.Fatalf("Invalid identity allocation mode '%s'", identityAllocationMode)
+	}
+
 	err = enableCNPWatcher()
 	if err != nil {
 		log.WithError(err).WithField("subsys", "CNPWatcher").Fatal(
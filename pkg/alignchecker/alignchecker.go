@@ -40,12 +40,16 @@ import (
 	"github.com/cilium/cilium/pkg/bpf"
 	"github.com/cilium/cilium/pkg/maps/configmap"
 	"github.com/cilium/cilium/pkg/maps/ctmap"
+	"github.com/cilium/cilium/pkg/maps/egressmap"
+	"github.com/cilium/cilium/pkg/maps/encrypt"
 	"github.com/cilium/cilium/pkg/maps/ipcache"
 	"github.com/cilium/cilium/pkg/maps/lbmap"
 	"github.com/cilium/cilium/pkg/maps/lxcmap"
 	"github.com/cilium/cilium/pkg/maps/metricsmap"
+	"github.com/cilium/cilium/pkg/maps/policyauditmap"
 	"github.com/cilium/cilium/pkg/maps/proxymap"
 	"github.com/cilium/cilium/pkg/maps/sockmap"
+	"github.com/cilium/cilium/pkg/maps/throttlemap"
 )
 
 func compareStructs(cStruct reflect.Type, vtc valueToCheck) error {
@@ -112,6 +116,22 @@ var cToGO = map[reflect.Type]valueToCheck{
 		sizeOfC:  C.sizeof_struct_lb6_service,
 		goStruct: reflect.TypeOf(lbmap.Service6Value{}),
 	},
+	reflect.TypeOf(C.struct_lb4_affinity_key{}): {
+		sizeOfC:  C.sizeof_struct_lb4_affinity_key,
+		goStruct: reflect.TypeOf(lbmap.Affinity4Key{}),
+	},
+	reflect.TypeOf(C.struct_lb6_affinity_key{}): {
+		sizeOfC:  C.sizeof_struct_lb6_affinity_key,
+		goStruct: reflect.TypeOf(lbmap.Affinity6Key{}),
+	},
+	reflect.TypeOf(C.struct_lb_affinity_val{}): {
+		sizeOfC:  C.sizeof_struct_lb_affinity_val,
+		goStruct: reflect.TypeOf(lbmap.AffinityValue{}),
+	},
+	reflect.TypeOf(C.struct_lb_maglev_lut{}): {
+		sizeOfC:  C.sizeof_struct_lb_maglev_lut,
+		goStruct: reflect.TypeOf(lbmap.MaglevValue{}),
+	},
 	reflect.TypeOf(C.struct_endpoint_key{}): {
 		sizeOfC:  C.sizeof_struct_endpoint_key,
 		goStruct: reflect.TypeOf(bpf.EndpointKey{}),
@@ -128,6 +148,30 @@ var cToGO = map[reflect.Type]valueToCheck{
 		sizeOfC:  C.sizeof_struct_metrics_value,
 		goStruct: reflect.TypeOf(metricsmap.Value{}),
 	},
+	reflect.TypeOf(C.struct_policy_audit_key{}): {
+		sizeOfC:  C.sizeof_struct_policy_audit_key,
+		goStruct: reflect.TypeOf(policyauditmap.Key{}),
+	},
+	reflect.TypeOf(C.struct_policy_audit_value{}): {
+		sizeOfC:  C.sizeof_struct_policy_audit_value,
+		goStruct: reflect.TypeOf(policyauditmap.Value{}),
+	},
+	reflect.TypeOf(C.struct_throttle_key{}): {
+		sizeOfC:  C.sizeof_struct_throttle_key,
+		goStruct: reflect.TypeOf(throttlemap.Key{}),
+	},
+	reflect.TypeOf(C.struct_throttle_value{}): {
+		sizeOfC:  C.sizeof_struct_throttle_value,
+		goStruct: reflect.TypeOf(throttlemap.Value{}),
+	},
+	reflect.TypeOf(C.struct_egress_key{}): {
+		sizeOfC:  C.sizeof_struct_egress_key,
+		goStruct: reflect.TypeOf(egressmap.Key{}),
+	},
+	reflect.TypeOf(C.struct_egress_info{}): {
+		sizeOfC:  C.sizeof_struct_egress_info,
+		goStruct: reflect.TypeOf(egressmap.Value{}),
+	},
 	reflect.TypeOf(C.struct_proxy4_tbl_key{}): {
 		sizeOfC:  C.sizeof_struct_proxy4_tbl_key,
 		goStruct: reflect.TypeOf(proxymap.Proxy4Key{}),
@@ -152,6 +196,10 @@ var cToGO = map[reflect.Type]valueToCheck{
 		sizeOfC:  C.sizeof_struct_ep_config,
 		goStruct: reflect.TypeOf(configmap.EndpointConfig{}),
 	},
+	reflect.TypeOf(C.struct_encrypt_config{}): {
+		sizeOfC:  C.sizeof_struct_encrypt_config,
+		goStruct: reflect.TypeOf(encrypt.EncryptValue{}),
+	},
 }
 
 func init() {
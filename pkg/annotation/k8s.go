@@ -51,4 +51,18 @@ const (
 	// GlobalService to true allows to expose remote endpoints without
 	// sharing local endpoints.
 	SharedService = Prefix + "shared-service"
+
+	// ServiceLBAlgorithm overrides the backend selection algorithm of a
+	// service. Valid values are "random" and "maglev".
+	ServiceLBAlgorithm = Prefix + "/lb-algorithm"
+
+	// EgressBandwidth is the annotation used to limit the egress bandwidth
+	// of a pod, e.g. "10M" for 10 Mbit/s. It is shared with the CNI
+	// bandwidth plugin and therefore does not use the Cilium prefix.
+	EgressBandwidth = "kubernetes.io/egress-bandwidth"
+
+	// ProxyVisibility is the annotation used to redirect the traffic of a
+	// pod to the proxy for L7 visibility without enforcing L7 policy, e.g.
+	// "<Ingress/80/TCP/HTTP>,<Egress/53/UDP/DNS>".
+	ProxyVisibility = "policy.cilium.io/proxy-visibility"
 )
@@ -0,0 +1,80 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bandwidth implements the parsing of the per-endpoint egress
+// bandwidth limit and the conversion into the parameters used to enforce it
+// in the datapath.
+package bandwidth
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// MinRate is the minimal egress bandwidth limit in bits per second
+	// which can be configured for an endpoint.
+	MinRate = 1000
+
+	// MaxRate is the maximal egress bandwidth limit in bits per second
+	// which can be configured for an endpoint.
+	MaxRate = 1000 * 1000 * 1000 * 1000 * 1000
+
+	// minDropHorizon is the minimal time for which packets are delayed to
+	// enforce the egress bandwidth limit before being dropped.
+	minDropHorizon = 2 * time.Second
+
+	// maxPacketSize is the size of the largest (GSO) packet which can be
+	// sent by an endpoint.
+	maxPacketSize = 64 * 1024
+)
+
+// ParseRate parses a bandwidth limit in the format of the
+// kubernetes.io/egress-bandwidth annotation, e.g. "10M", and returns it in
+// bits per second.
+func ParseRate(value string) (uint64, error) {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth %q: %s", value, err)
+	}
+
+	rate := q.Value()
+	if rate < MinRate || rate > MaxRate {
+		return 0, fmt.Errorf("bandwidth %q must be between %d and %d bits per second",
+			value, MinRate, MaxRate)
+	}
+
+	return uint64(rate), nil
+}
+
+// BytesPerSecond converts a rate in bits per second into bytes per second,
+// as used by the datapath.
+func BytesPerSecond(rate uint64) uint64 {
+	return rate / 8
+}
+
+// DropHorizon returns the maximum time a packet is delayed to enforce the
+// given rate in bits per second. Packets which would have to be delayed for
+// longer are dropped to bound the queue of the native device. At low rates,
+// the horizon is extended so that at least two packets of the largest size
+// can be queued.
+func DropHorizon(rate uint64) time.Duration {
+	horizon := time.Duration(2 * maxPacketSize * uint64(time.Second) / BytesPerSecond(rate))
+	if horizon < minDropHorizon {
+		return minDropHorizon
+	}
+	return horizon
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package bandwidth
+
+import (
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type BandwidthSuite struct{}
+
+var _ = Suite(&BandwidthSuite{})
+
+func (s *BandwidthSuite) TestParseRate(c *C) {
+	tests := []struct {
+		value string
+		rate  uint64
+		fails bool
+	}{
+		{value: "10M", rate: 10000000},
+		{value: "1G", rate: 1000000000},
+		{value: "100k", rate: 100000},
+		{value: "1Mi", rate: 1048576},
+		{value: "1k", rate: MinRate},
+		{value: "1P", rate: MaxRate},
+		{value: "999", fails: true},
+		{value: "2P", fails: true},
+		{value: "-10M", fails: true},
+		{value: "10 Mbit", fails: true},
+		{value: "", fails: true},
+	}
+
+	for _, tt := range tests {
+		rate, err := ParseRate(tt.value)
+		if tt.fails {
+			c.Assert(err, Not(IsNil), Commentf("value %q", tt.value))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("value %q", tt.value))
+		c.Assert(rate, Equals, tt.rate, Commentf("value %q", tt.value))
+	}
+}
+
+func (s *BandwidthSuite) TestDropHorizon(c *C) {
+	c.Assert(BytesPerSecond(10000000), Equals, uint64(1250000))
+
+	// Low rates require a longer horizon so that full GSO packets are
+	// delayed rather than dropped
+	c.Assert(DropHorizon(MinRate), Equals, 1048576*time.Millisecond)
+	c.Assert(DropHorizon(100000), Equals, 10485760*time.Microsecond)
+
+	c.Assert(DropHorizon(10000000), Equals, 2*time.Second)
+	c.Assert(DropHorizon(MaxRate), Equals, 2*time.Second)
+}
@@ -74,6 +74,16 @@ const (
 	BPF_BTF_GET_FD_BY_ID    = 19
 	BPF_TASK_FD_QUERY       = 20
 
+	// BPF attach type constants. Must match enum bpf_attach_type from linux/bpf.h
+	BPF_CGROUP_INET_INGRESS     = 0
+	BPF_CGROUP_INET_EGRESS      = 1
+	BPF_CGROUP_INET_SOCK_CREATE = 2
+	BPF_CGROUP_SOCK_OPS         = 3
+	BPF_SK_SKB_STREAM_PARSER    = 4
+	BPF_SK_SKB_STREAM_VERDICT   = 5
+	BPF_CGROUP_DEVICE           = 6
+	BPF_SK_MSG_VERDICT          = 7
+
 	// Flags for BPF_MAP_UPDATE_ELEM. Must match values from linux/bpf.h
 	BPF_ANY     = 0
 	BPF_NOEXIST = 1
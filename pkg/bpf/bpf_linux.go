@@ -312,23 +312,33 @@ func objCheck(fd int, path string, mapType int, keySize, valueSize, maxEntries,
 	return false
 }
 
-func OpenOrCreateMap(path string, mapType int, keySize, valueSize, maxEntries, flags uint32, innerID uint32) (int, bool, error) {
-	var fd int
-
-	redo := false
-	isNewMap := false
-
+// setMemlockRlimit lifts the RLIMIT_MEMLOCK limit which accounts for the memory
+// of BPF maps and programs.
+func setMemlockRlimit() error {
 	rl := unix.Rlimit{
 		Cur: math.MaxUint64,
 		Max: math.MaxUint64,
 	}
 
-	err := unix.Setrlimit(unix.RLIMIT_MEMLOCK, &rl)
-	if err != nil {
+	if err := unix.Setrlimit(unix.RLIMIT_MEMLOCK, &rl); err != nil {
 		if os.IsPermission(err) {
 			log.Error("Unable to set RLimits, insufficient permissions")
 		}
-		return 0, isNewMap, fmt.Errorf("Unable to increase rlimit: %s", err)
+		return fmt.Errorf("Unable to increase rlimit: %s", err)
+	}
+
+	return nil
+}
+
+func OpenOrCreateMap(path string, mapType int, keySize, valueSize, maxEntries, flags uint32, innerID uint32) (int, bool, error) {
+	var fd int
+
+	redo := false
+	isNewMap := false
+
+	err := setMemlockRlimit()
+	if err != nil {
+		return 0, isNewMap, err
 	}
 
 recreate:
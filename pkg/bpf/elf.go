@@ -0,0 +1,318 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpf
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/byteorder"
+)
+
+const (
+	// ELF section names understood by the loader. These follow the
+	// conventions of the iproute2 BPF loader, see bpf/include/iproute2/bpf_elf.h
+	elfSectionLicense = "license"
+	elfSectionMaps    = "maps"
+
+	// Object pinning settings of struct bpf_elf_map
+	PinNone     = 0
+	PinObjectNS = 1
+	PinGlobalNS = 2
+
+	// elfMapDefMinSize is the size of struct bpf_elf_map without the
+	// optional inner_id and inner_idx fields.
+	elfMapDefMinSize = 7 * 4
+
+	// bpfInsnSize is the size of struct bpf_insn
+	bpfInsnSize = 8
+
+	// bpfLdImm64 is the opcode of the BPF_LD | BPF_IMM | BPF_DW instruction
+	// used to load map file descriptors.
+	bpfLdImm64 = 0x18
+
+	// bpfPseudoMapFD marks the immediate of a bpfLdImm64 instruction as a
+	// map file descriptor.
+	bpfPseudoMapFD = 1
+)
+
+// ELFMap is a map definition found in the maps section of an ELF object. It
+// mirrors struct bpf_elf_map.
+type ELFMap struct {
+	Name       string
+	Type       uint32
+	KeySize    uint32
+	ValueSize  uint32
+	MaxEntries uint32
+	Flags      uint32
+	ID         uint32
+	Pinning    uint32
+	InnerID    uint32
+	InnerIdx   uint32
+}
+
+// ELFRelocation is a reference from a program instruction to a map.
+type ELFRelocation struct {
+	// Offset is the offset in bytes of the referencing instruction
+	Offset uint64
+	// Map is the name of the referenced map
+	Map string
+}
+
+// ELFProgram is a program section found in an ELF object.
+type ELFProgram struct {
+	Section      string
+	Instructions []byte
+	Relocations  []ELFRelocation
+}
+
+// tailCall returns the map ID and key of the program array slot this program
+// must be inserted into if the section follows the "<id>/<key>" naming of
+// __section_tail().
+func (p *ELFProgram) tailCall() (id, key uint32, ok bool) {
+	return parseTailCallSection(p.Section)
+}
+
+// ELF is a parsed BPF ELF object.
+type ELF struct {
+	Path     string
+	License  string
+	Maps     []*ELFMap
+	Programs map[string]*ELFProgram
+}
+
+// Map returns the map definition with the given name or nil.
+func (e *ELF) Map(name string) *ELFMap {
+	for _, m := range e.Maps {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// ParseELF parses the BPF ELF object at path.
+func ParseELF(path string) (*ELF, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	obj, err := parseELF(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse ELF object %s: %s", path, err)
+	}
+	obj.Path = path
+
+	return obj, nil
+}
+
+func parseELF(f *elf.File) (*ELF, error) {
+	if f.Type != elf.ET_REL || (f.Machine != elf.EM_NONE && f.Machine != elf.EM_BPF) {
+		return nil, fmt.Errorf("not an eBPF object")
+	}
+	if f.ByteOrder != byteorder.Native {
+		return nil, fmt.Errorf("object byte order %s does not match host", f.ByteOrder)
+	}
+
+	symbols, err := f.Symbols()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read symbols: %s", err)
+	}
+
+	obj := &ELF{
+		Programs: map[string]*ELFProgram{},
+	}
+
+	var mapsIndex elf.SectionIndex
+	progsByIndex := map[int]*ELFProgram{}
+
+	for i, sec := range f.Sections {
+		switch {
+		case sec.Name == elfSectionLicense:
+			data, err := sec.Data()
+			if err != nil {
+				return nil, fmt.Errorf("unable to read license: %s", err)
+			}
+			obj.License = string(bytes.TrimRight(data, "\x00"))
+
+		case sec.Name == elfSectionMaps:
+			data, err := sec.Data()
+			if err != nil {
+				return nil, fmt.Errorf("unable to read maps: %s", err)
+			}
+			mapsIndex = elf.SectionIndex(i)
+			if obj.Maps, err = parseMaps(data, symbols, mapsIndex); err != nil {
+				return nil, err
+			}
+
+		case sec.Type == elf.SHT_PROGBITS && sec.Flags&elf.SHF_EXECINSTR != 0 && sec.Size > 0:
+			data, err := sec.Data()
+			if err != nil {
+				return nil, fmt.Errorf("unable to read section %s: %s", sec.Name, err)
+			}
+			prog := &ELFProgram{
+				Section:      sec.Name,
+				Instructions: data,
+			}
+			obj.Programs[sec.Name] = prog
+			progsByIndex[i] = prog
+		}
+	}
+
+	for _, sec := range f.Sections {
+		if sec.Type != elf.SHT_REL {
+			continue
+		}
+		prog, ok := progsByIndex[int(sec.Info)]
+		if !ok {
+			continue
+		}
+		data, err := sec.Data()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read relocations %s: %s", sec.Name, err)
+		}
+		if prog.Relocations, err = parseRelocations(data, f.ByteOrder, symbols, mapsIndex); err != nil {
+			return nil, fmt.Errorf("section %s: %s", prog.Section, err)
+		}
+	}
+
+	return obj, nil
+}
+
+// parseMaps decodes all map definitions in the maps section. As the size of
+// struct bpf_elf_map depends on how the object was compiled, it is derived
+// from the number of map symbols like the iproute2 loader does.
+func parseMaps(data []byte, symbols []elf.Symbol, mapsIndex elf.SectionIndex) ([]*ELFMap, error) {
+	var mapSymbols []elf.Symbol
+	for _, sym := range symbols {
+		if sym.Section == mapsIndex && elf.ST_BIND(sym.Info) == elf.STB_GLOBAL {
+			mapSymbols = append(mapSymbols, sym)
+		}
+	}
+	if len(mapSymbols) == 0 {
+		return nil, nil
+	}
+
+	defSize := len(data) / len(mapSymbols)
+	if defSize < elfMapDefMinSize || defSize%4 != 0 || defSize*len(mapSymbols) != len(data) {
+		return nil, fmt.Errorf("invalid map definition size %d", defSize)
+	}
+
+	maps := make([]*ELFMap, 0, len(mapSymbols))
+	for _, sym := range mapSymbols {
+		if sym.Value%uint64(defSize) != 0 || sym.Value+uint64(defSize) > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid offset %d of map %s", sym.Value, sym.Name)
+		}
+		m := decodeMapDefinition(data[sym.Value : sym.Value+uint64(defSize)])
+		m.Name = sym.Name
+		maps = append(maps, m)
+	}
+
+	return maps, nil
+}
+
+func decodeMapDefinition(def []byte) *ELFMap {
+	field := func(i int) uint32 {
+		if (i+1)*4 > len(def) {
+			return 0
+		}
+		return byteorder.Native.Uint32(def[i*4:])
+	}
+
+	return &ELFMap{
+		Type:       field(0),
+		KeySize:    field(1),
+		ValueSize:  field(2),
+		MaxEntries: field(3),
+		Flags:      field(4),
+		ID:         field(5),
+		Pinning:    field(6),
+		InnerID:    field(7),
+		InnerIdx:   field(8),
+	}
+}
+
+// parseRelocations decodes the Elf64_Rel entries of a program section. Only
+// references to maps are supported as all helper functions are inlined.
+func parseRelocations(data []byte, bo binary.ByteOrder, symbols []elf.Symbol, mapsIndex elf.SectionIndex) ([]ELFRelocation, error) {
+	const relSize = 16
+
+	if len(data)%relSize != 0 {
+		return nil, fmt.Errorf("invalid relocation section size %d", len(data))
+	}
+
+	relocs := make([]ELFRelocation, 0, len(data)/relSize)
+	for off := 0; off < len(data); off += relSize {
+		offset := bo.Uint64(data[off:])
+		symIndex := int(elf.R_SYM64(bo.Uint64(data[off+8:])))
+		// The null symbol is not part of the list returned by Symbols()
+		if symIndex < 1 || symIndex > len(symbols) {
+			return nil, fmt.Errorf("invalid symbol index %d in relocation", symIndex)
+		}
+		sym := symbols[symIndex-1]
+		if mapsIndex == 0 || sym.Section != mapsIndex {
+			return nil, fmt.Errorf("unsupported relocation against symbol %s", sym.Name)
+		}
+		relocs = append(relocs, ELFRelocation{Offset: offset, Map: sym.Name})
+	}
+
+	return relocs, nil
+}
+
+// parseTailCallSection parses a section name of the form "<id>/<key>"
+func parseTailCallSection(section string) (id, key uint32, ok bool) {
+	parts := strings.Split(section, "/")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	i, err := strconv.ParseUint(parts[0], 0, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	k, err := strconv.ParseUint(parts[1], 0, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint32(i), uint32(k), true
+}
+
+// patchMapFD rewrites the instruction at offset in insns to load the map with
+// the given file descriptor.
+func patchMapFD(insns []byte, offset uint64, fd int) error {
+	if offset%bpfInsnSize != 0 || offset+2*bpfInsnSize > uint64(len(insns)) {
+		return fmt.Errorf("invalid instruction offset %d", offset)
+	}
+	insn := insns[offset : offset+bpfInsnSize]
+	if insn[0] != bpfLdImm64 {
+		return fmt.Errorf("instruction at offset %d is not a 64-bit immediate load", offset)
+	}
+
+	// The register byte holds dst_reg and src_reg as bitfields whose order
+	// depends on the byte order.
+	if byteorder.Native == binary.LittleEndian {
+		insn[1] = insn[1]&0x0f | bpfPseudoMapFD<<4
+	} else {
+		insn[1] = insn[1]&0xf0 | bpfPseudoMapFD
+	}
+	byteorder.Native.PutUint32(insn[4:], uint32(fd))
+
+	return nil
+}
@@ -0,0 +1,270 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package bpf
+
+import (
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/cilium/pkg/logging/logfields"
+)
+
+// pendingSuffix is appended to the path of a pinned map whose properties do
+// not match the map definition of the object being loaded. It uses the same
+// naming as cilium-map-migrate.
+const pendingSuffix = ":pending"
+
+// Collection is the set of maps and programs of an ELF object which have been
+// loaded into the kernel.
+type Collection struct {
+	// Programs maps section names to program file descriptors
+	Programs map[string]int
+
+	maps    map[string]int
+	pending []string
+}
+
+// LoadCollection loads the program in section entry and all tail call programs
+// of the ELF object into the kernel, together with the maps they reference. Tail call
+// programs are inserted into the program array with the matching map ID.
+//
+// Pinned maps are re-used if their properties match the map definition.
+// Otherwise the pinned map is moved aside and a new map is created, the old
+// map is deleted or restored by FinalizeMigration once it is known whether
+// attaching the program succeeded.
+func LoadCollection(obj *ELF, progType ProgType, entry string) (*Collection, error) {
+	if _, ok := obj.Programs[entry]; !ok {
+		return nil, fmt.Errorf("section %s not found in %s", entry, obj.Path)
+	}
+
+	if err := setMemlockRlimit(); err != nil {
+		return nil, err
+	}
+
+	coll := &Collection{
+		Programs: map[string]int{},
+		maps:     map[string]int{},
+	}
+
+	if err := coll.load(obj, progType, entry); err != nil {
+		coll.Close()
+		coll.FinalizeMigration(false)
+		return nil, err
+	}
+
+	return coll, nil
+}
+
+func (c *Collection) load(obj *ELF, progType ProgType, entry string) error {
+	var progs []*ELFProgram
+	for name, prog := range obj.Programs {
+		if _, _, isTailCall := prog.tailCall(); name == entry || isTailCall {
+			progs = append(progs, prog)
+		}
+	}
+
+	maps := referencedMaps(obj, progs)
+	for _, m := range maps {
+		if m.Pinning != PinGlobalNS {
+			continue
+		}
+		if err := c.migrateMap(m); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range maps {
+		fd, err := createELFMap(m)
+		if err != nil {
+			return err
+		}
+		c.maps[m.Name] = fd
+	}
+
+	for _, prog := range progs {
+		fd, err := c.loadProgram(obj, prog, progType)
+		if err != nil {
+			return err
+		}
+		c.Programs[prog.Section] = fd
+
+		if id, key, isTailCall := prog.tailCall(); isTailCall && prog.Section != entry {
+			if err := c.insertTailCall(obj, id, key, fd); err != nil {
+				return fmt.Errorf("unable to insert tail call %s: %s", prog.Section, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// referencedMaps returns the maps used by progs, including the program arrays
+// which tail calls are inserted into. Other maps defined by the object, e.g.
+// via shared headers, are left untouched.
+func referencedMaps(obj *ELF, progs []*ELFProgram) []*ELFMap {
+	used := map[string]bool{}
+	for _, prog := range progs {
+		for _, reloc := range prog.Relocations {
+			used[reloc.Map] = true
+		}
+		if id, _, isTailCall := prog.tailCall(); isTailCall {
+			for _, m := range obj.Maps {
+				if m.ID == id && m.Type == BPF_MAP_TYPE_PROG_ARRAY {
+					used[m.Name] = true
+				}
+			}
+		}
+	}
+
+	var maps []*ELFMap
+	for _, m := range obj.Maps {
+		if used[m.Name] {
+			maps = append(maps, m)
+		}
+	}
+	return maps
+}
+
+// migrateMap moves the pinned map for m aside if its properties do not match
+// the map definition.
+func (c *Collection) migrateMap(m *ELFMap) error {
+	path := MapPath(m.Name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	fd, err := ObjGet(path)
+	if err != nil {
+		return err
+	}
+	info, err := GetMapInfo(os.Getpid(), fd)
+	ObjClose(fd)
+	if err != nil {
+		return fmt.Errorf("unable to get info of pinned map %s: %s", path, err)
+	}
+
+	if uint32(info.MapType) == m.Type && info.KeySize == m.KeySize &&
+		info.ValueSize == m.ValueSize && info.MaxEntries == m.MaxEntries &&
+		info.Flags == m.Flags {
+		return nil
+	}
+
+	pending := path + pendingSuffix
+	log.WithField(logfields.BPFMapPath, path).Warning("Property mismatch in pinned map, migrating to new map")
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	if err := os.Rename(path, pending); err != nil {
+		return fmt.Errorf("unable to migrate pinned map %s: %s", path, err)
+	}
+	c.pending = append(c.pending, path)
+
+	return nil
+}
+
+func createELFMap(m *ELFMap) (int, error) {
+	isMapInMap := m.Type == BPF_MAP_TYPE_ARRAY_OF_MAPS || m.Type == BPF_MAP_TYPE_HASH_OF_MAPS
+
+	if m.Pinning == PinGlobalNS {
+		path := MapPath(m.Name)
+		// Map-in-map types require an inner map to be created, they
+		// can only be re-used if they have been created by the agent.
+		if _, err := os.Stat(path); isMapInMap && os.IsNotExist(err) {
+			return 0, fmt.Errorf("map %s: creating map-in-map types is not supported", m.Name)
+		}
+		fd, _, err := OpenOrCreateMap(path, int(m.Type), m.KeySize, m.ValueSize, m.MaxEntries, m.Flags, 0)
+		return fd, err
+	}
+
+	if isMapInMap {
+		return 0, fmt.Errorf("map %s: creating map-in-map types is not supported", m.Name)
+	}
+
+	// Maps pinned in the object namespace are private to the object and
+	// thus not shared across loads.
+	return CreateMap(int(m.Type), m.KeySize, m.ValueSize, m.MaxEntries, m.Flags, 0, m.Name)
+}
+
+func (c *Collection) loadProgram(obj *ELF, prog *ELFProgram, progType ProgType) (int, error) {
+	insns := make([]byte, len(prog.Instructions))
+	copy(insns, prog.Instructions)
+
+	for _, reloc := range prog.Relocations {
+		fd, ok := c.maps[reloc.Map]
+		if !ok {
+			return 0, fmt.Errorf("section %s references unknown map %s", prog.Section, reloc.Map)
+		}
+		if err := patchMapFD(insns, reloc.Offset, fd); err != nil {
+			return 0, fmt.Errorf("section %s: %s", prog.Section, err)
+		}
+	}
+
+	fd, err := LoadProgram(progType, insns, obj.License)
+	if err != nil {
+		return 0, fmt.Errorf("section %s: %s", prog.Section, err)
+	}
+
+	return fd, nil
+}
+
+func (c *Collection) insertTailCall(obj *ELF, id, key uint32, progFD int) error {
+	for _, m := range obj.Maps {
+		if m.ID != id || m.Type != BPF_MAP_TYPE_PROG_ARRAY {
+			continue
+		}
+		value := uint32(progFD)
+		return UpdateElement(c.maps[m.Name], unsafe.Pointer(&key), unsafe.Pointer(&value), BPF_ANY)
+	}
+
+	return fmt.Errorf("no program array with id %d", id)
+}
+
+// Close releases the file descriptors of all maps and programs. Pinned maps
+// and attached programs remain in use by the kernel.
+func (c *Collection) Close() {
+	for name, fd := range c.Programs {
+		ObjClose(fd)
+		delete(c.Programs, name)
+	}
+	for name, fd := range c.maps {
+		ObjClose(fd)
+		delete(c.maps, name)
+	}
+}
+
+// FinalizeMigration completes the migration of pinned maps whose properties
+// changed. If success is true, the old maps are removed. Otherwise they are
+// restored so that the previously attached program keeps operating on them.
+func (c *Collection) FinalizeMigration(success bool) {
+	for _, path := range c.pending {
+		pending := path + pendingSuffix
+		scopedLog := log.WithField(logfields.BPFMapPath, pending)
+		if success {
+			scopedLog.Info("Unlinking migrated map")
+			if err := os.Remove(pending); err != nil {
+				scopedLog.WithError(err).Warning("Unable to unlink migrated map")
+			}
+		} else {
+			scopedLog.Warning("Restoring migrated map due to failed load")
+			if err := os.Rename(pending, path); err != nil {
+				scopedLog.WithError(err).Warning("Unable to restore migrated map")
+			}
+		}
+	}
+	c.pending = nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package bpf
+
+import (
+	"debug/elf"
+	"encoding/binary"
+
+	"github.com/cilium/cilium/pkg/byteorder"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *BPFTestSuite) TestParseTailCallSection(c *C) {
+	id, key, ok := parseTailCallSection("2/7")
+	c.Assert(ok, Equals, true)
+	c.Assert(id, Equals, uint32(2))
+	c.Assert(key, Equals, uint32(7))
+
+	id, key, ok = parseTailCallSection("0x2/0x10")
+	c.Assert(ok, Equals, true)
+	c.Assert(id, Equals, uint32(2))
+	c.Assert(key, Equals, uint32(16))
+
+	for _, section := range []string{"from-container", "2/", "/7", "2/7/1", "a/b"} {
+		_, _, ok = parseTailCallSection(section)
+		c.Assert(ok, Equals, false, Commentf("section %s", section))
+	}
+}
+
+func (s *BPFTestSuite) TestParseMaps(c *C) {
+	def := func(fields ...uint32) []byte {
+		b := make([]byte, 4*len(fields))
+		for i, f := range fields {
+			byteorder.Native.PutUint32(b[i*4:], f)
+		}
+		return b
+	}
+
+	data := append(def(BPF_MAP_TYPE_HASH, 4, 8, 1024, 0, 0, PinGlobalNS),
+		def(BPF_MAP_TYPE_PROG_ARRAY, 4, 4, 32, 0, 2, PinNone)...)
+	symbols := []elf.Symbol{
+		{Name: "cilium_test", Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_OBJECT), Section: 3, Value: 0},
+		{Name: "cilium_calls", Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_NOTYPE), Section: 3, Value: 28},
+		{Name: "local", Info: elf.ST_INFO(elf.STB_LOCAL, elf.STT_NOTYPE), Section: 3, Value: 0},
+		{Name: "other", Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC), Section: 4, Value: 0},
+	}
+
+	maps, err := parseMaps(data, symbols, 3)
+	c.Assert(err, IsNil)
+	c.Assert(maps, DeepEquals, []*ELFMap{
+		{Name: "cilium_test", Type: BPF_MAP_TYPE_HASH, KeySize: 4, ValueSize: 8, MaxEntries: 1024, Pinning: PinGlobalNS},
+		{Name: "cilium_calls", Type: BPF_MAP_TYPE_PROG_ARRAY, KeySize: 4, ValueSize: 4, MaxEntries: 32, ID: 2},
+	})
+
+	// Map definitions including inner_id and inner_idx
+	data = def(BPF_MAP_TYPE_HASH, 4, 8, 1024, 0, 0, PinGlobalNS, 5, 6)
+	maps, err = parseMaps(data, symbols[:1], 3)
+	c.Assert(err, IsNil)
+	c.Assert(maps[0].InnerID, Equals, uint32(5))
+	c.Assert(maps[0].InnerIdx, Equals, uint32(6))
+
+	_, err = parseMaps(data[:20], symbols[:1], 3)
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *BPFTestSuite) TestParseRelocations(c *C) {
+	symbols := []elf.Symbol{
+		{Name: "cilium_test", Section: 3},
+		{Name: "helper", Section: 4},
+	}
+	rel := func(offset uint64, sym uint32) []byte {
+		b := make([]byte, 16)
+		binary.LittleEndian.PutUint64(b, offset)
+		binary.LittleEndian.PutUint64(b[8:], elf.R_INFO(sym, 1))
+		return b
+	}
+
+	relocs, err := parseRelocations(append(rel(8, 1), rel(32, 1)...), binary.LittleEndian, symbols, 3)
+	c.Assert(err, IsNil)
+	c.Assert(relocs, DeepEquals, []ELFRelocation{
+		{Offset: 8, Map: "cilium_test"},
+		{Offset: 32, Map: "cilium_test"},
+	})
+
+	_, err = parseRelocations(rel(8, 2), binary.LittleEndian, symbols, 3)
+	c.Assert(err, Not(IsNil))
+	_, err = parseRelocations(rel(8, 3), binary.LittleEndian, symbols, 3)
+	c.Assert(err, Not(IsNil))
+	_, err = parseRelocations(rel(8, 1)[:10], binary.LittleEndian, symbols, 3)
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *BPFTestSuite) TestPatchMapFD(c *C) {
+	// r1 = 0 ll; exit
+	insns := []byte{
+		bpfLdImm64, 0x01, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0x95, 0, 0, 0, 0, 0, 0, 0,
+	}
+
+	c.Assert(patchMapFD(insns, 0, 42), IsNil)
+	c.Assert(byteorder.Native.Uint32(insns[4:]), Equals, uint32(42))
+	if byteorder.Native == binary.LittleEndian {
+		c.Assert(insns[1], Equals, byte(bpfPseudoMapFD<<4|0x01))
+	}
+
+	c.Assert(patchMapFD(insns, 4, 42), Not(IsNil))
+	c.Assert(patchMapFD(insns, 8, 42), Not(IsNil))
+	c.Assert(patchMapFD(insns, 16, 42), Not(IsNil))
+}
@@ -17,7 +17,9 @@
 package bpf
 
 import (
+	"bytes"
 	"fmt"
+	"runtime"
 	"unsafe"
 
 	"golang.org/x/sys/unix"
@@ -113,3 +115,123 @@ func GetProgInfoByFD(fd int) (ProgInfo, error) {
 
 	return info, nil
 }
+
+const (
+	// progLogSize is the size of the buffer used to retrieve the verifier
+	// log of a program which failed to load.
+	progLogSize = 4 * 1024 * 1024
+
+	// progLogTail is the maximum number of bytes of the verifier log
+	// included in the error returned by LoadProgram.
+	progLogTail = 4096
+)
+
+// This struct must be in sync with union bpf_attr's anonymous struct used by
+// the BPF_PROG_LOAD command
+type bpfAttrProgLoad struct {
+	progType    uint32
+	insnCnt     uint32
+	insns       uint64
+	license     uint64
+	logLevel    uint32
+	logSize     uint32
+	logBuf      uint64
+	kernVersion uint32
+}
+
+func progLoad(progType ProgType, insns []byte, license []byte, logBuf []byte) (int, error) {
+	uba := bpfAttrProgLoad{
+		progType: uint32(progType),
+		insnCnt:  uint32(len(insns) / bpfInsnSize),
+		insns:    uint64(uintptr(unsafe.Pointer(&insns[0]))),
+		license:  uint64(uintptr(unsafe.Pointer(&license[0]))),
+	}
+	if len(logBuf) > 0 {
+		uba.logLevel = 1
+		uba.logSize = uint32(len(logBuf))
+		uba.logBuf = uint64(uintptr(unsafe.Pointer(&logBuf[0])))
+	}
+
+	fd, _, err := unix.Syscall(unix.SYS_BPF, BPF_PROG_LOAD, uintptr(unsafe.Pointer(&uba)), unsafe.Sizeof(uba))
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(license)
+	runtime.KeepAlive(logBuf)
+	if err != 0 {
+		return 0, err
+	}
+
+	return int(fd), nil
+}
+
+// LoadProgram loads the instructions insns as a program of type progType into
+// the kernel and returns its file descriptor. If the verifier rejects the
+// program, the end of the verifier log is included in the returned error.
+func LoadProgram(progType ProgType, insns []byte, license string) (int, error) {
+	if len(insns) == 0 || len(insns)%bpfInsnSize != 0 {
+		return 0, fmt.Errorf("Invalid program size %d", len(insns))
+	}
+	licenseStr := append([]byte(license), 0)
+
+	fd, err := progLoad(progType, insns, licenseStr, nil)
+	if err == nil {
+		return fd, nil
+	}
+
+	// Load the program again to retrieve the verifier log
+	logBuf := make([]byte, progLogSize)
+	if fd, err2 := progLoad(progType, insns, licenseStr, logBuf); err2 == nil {
+		return fd, nil
+	}
+	if n := bytes.IndexByte(logBuf, 0); n >= 0 {
+		logBuf = logBuf[:n]
+	}
+	verifierLog := string(logBuf)
+	if len(verifierLog) > progLogTail {
+		verifierLog = "..." + verifierLog[len(verifierLog)-progLogTail:]
+	}
+
+	return 0, fmt.Errorf("Unable to load %s program: %s\n%s", progType, err, verifierLog)
+}
+
+// This struct must be in sync with union bpf_attr's anonymous struct used by
+// the BPF_PROG_ATTACH and BPF_PROG_DETACH commands
+type bpfAttrProgAttach struct {
+	targetFD    uint32
+	attachBpfFD uint32
+	attachType  uint32
+	attachFlags uint32
+}
+
+func progAttachCmd(cmd uintptr, progFD, targetFD int, attachType uint32) error {
+	uba := bpfAttrProgAttach{
+		targetFD:    uint32(targetFD),
+		attachBpfFD: uint32(progFD),
+		attachType:  attachType,
+	}
+
+	ret, _, err := unix.Syscall(unix.SYS_BPF, cmd, uintptr(unsafe.Pointer(&uba)), unsafe.Sizeof(uba))
+	if ret != 0 || err != 0 {
+		return err
+	}
+
+	return nil
+}
+
+// ProgAttach attaches the program progFD to the target targetFD, e.g. a cgroup
+// directory or a sockmap, at the hook selected by attachType. A program
+// previously attached to the same hook is replaced.
+func ProgAttach(progFD, targetFD int, attachType uint32) error {
+	if err := progAttachCmd(BPF_PROG_ATTACH, progFD, targetFD, attachType); err != nil {
+		return fmt.Errorf("Unable to attach program with file descriptor %d: %s", progFD, err)
+	}
+	return nil
+}
+
+// ProgDetach detaches the program progFD from the hook selected by attachType
+// of the target targetFD.
+func ProgDetach(progFD, targetFD int, attachType uint32) error {
+	if err := progAttachCmd(BPF_PROG_DETACH, progFD, targetFD, attachType); err != nil {
+		return fmt.Errorf("Unable to detach program with file descriptor %d: %s", progFD, err)
+	}
+	return nil
+}
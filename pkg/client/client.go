@@ -292,6 +292,14 @@ func FormatStatusResponse(w io.Writer, sr *models.StatusResponse, allAddresses,
 		}
 	}
 
+	if sr.Encryption != nil {
+		fmt.Fprintf(w, "Encryption:\t%s\t%s\n", sr.Encryption.Mode, sr.Encryption.Msg)
+	}
+
+	if sr.Sockmap != nil {
+		fmt.Fprintf(w, "Sockmap:\t%s\t%s\n", sr.Sockmap.State, sr.Sockmap.Msg)
+	}
+
 	if sr.Controllers != nil {
 		nFailing, out := 0, []string{"  Name\tLast success\tLast error\tCount\tMessage\n"}
 		for _, ctrl := range sr.Controllers {
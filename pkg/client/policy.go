@@ -30,6 +30,17 @@ func (c *Client) PolicyPut(policyJSON string) (*models.Policy, error) {
 	return resp.Payload, nil
 }
 
+// PolicyPreview returns the policy changes of local endpoints which would
+// result from inserting `policyJSON`, without inserting it
+func (c *Client) PolicyPreview(policyJSON string) (*models.PolicyPreview, error) {
+	params := policy.NewPostPolicyPreviewParams().WithPolicy(&policyJSON).WithTimeout(api.ClientTimeout)
+	resp, err := c.Policy.PostPolicyPreview(params)
+	if err != nil {
+		return nil, Hint(err)
+	}
+	return resp.Payload, nil
+}
+
 // PolicyGet returns policy rules
 func (c *Client) PolicyGet(labels []string) (*models.Policy, error) {
 	params := policy.NewGetPolicyParams().WithLabels(labels).WithTimeout(api.ClientTimeout)
@@ -105,7 +105,7 @@ func (s *ClusterMeshTestSuite) TestClusterMesh(c *C) {
 
 	logging.DefaultLogger.SetLevel(logrus.DebugLevel)
 
-	cache.InitIdentityAllocator(&identityAllocatorOwnerMock{})
+	cache.InitIdentityAllocator(&identityAllocatorOwnerMock{}, nil)
 	defer cache.Close()
 
 	dir, err := ioutil.TempDir("", "multicluster")
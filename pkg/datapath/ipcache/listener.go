@@ -139,7 +139,7 @@ func updateStaleEntriesFunction(keysToRemove map[string]*ipcacheMap.Key) bpf.Dum
 		// Don't RLock as part of the same goroutine.
 		if i, exists := ipcache.IPIdentityCache.LookupByPrefixRLocked(keyToIP); !exists {
 			switch i.Source {
-			case ipcache.FromKVStore, ipcache.FromAgentLocal:
+			case ipcache.FromKVStore, ipcache.FromCustomResource, ipcache.FromAgentLocal:
 				// Cannot delete from map during callback because DumpWithCallback
 				// RLocks the map.
 				keysToRemove[keyToIP] = k
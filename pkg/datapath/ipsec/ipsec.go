@@ -0,0 +1,434 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipsec
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	// MaxSPI is the largest SPI which can be encoded into the key bits of
+	// the encryption mark
+	MaxSPI = 15
+
+	// RouteMarkEncrypt is the skb mark set by the datapath on packets which
+	// must be encrypted. Must be in sync with MARK_MAGIC_ENCRYPT in
+	// <bpf/lib/common.h>
+	RouteMarkEncrypt = 0x0E00
+
+	// RouteMarkMask is the mask matching the encryption mark and the SPI of
+	// the key to use, which the datapath stores in the upper four bits
+	RouteMarkMask = 0xFF00
+
+	// ipSecReqID identifies all xfrm states and policies managed by Cilium
+	ipSecReqID = 1
+)
+
+type ipSecKey struct {
+	Spi   uint8
+	Auth  *netlink.XfrmStateAlgo
+	Crypt *netlink.XfrmStateAlgo
+}
+
+var (
+	ipSecLock lock.RWMutex
+
+	// ipSecKeys contains all keys currently listed in the key file, indexed
+	// by their SPI
+	ipSecKeys = map[uint8]*ipSecKey{}
+
+	// ipSecCurrentKey is the key used to encrypt traffic leaving the local
+	// node. It is retained when removed from the key file until the
+	// datapath has switched to another key.
+	ipSecCurrentKey *ipSecKey
+)
+
+// installedKeys returns all keys for which xfrm states must be installed:
+// the keys listed in the key file and the key currently in use. Must be
+// called with ipSecLock held.
+func installedKeys() []*ipSecKey {
+	keys := make([]*ipSecKey, 0, len(ipSecKeys)+1)
+	for _, key := range ipSecKeys {
+		keys = append(keys, key)
+	}
+	if ipSecCurrentKey != nil && ipSecKeys[ipSecCurrentKey.Spi] == nil {
+		keys = append(keys, ipSecCurrentKey)
+	}
+	return keys
+}
+
+// getEncryptMark returns the mark identifying packets to be encrypted with the
+// key of the given SPI
+func getEncryptMark(spi uint8) *netlink.XfrmMark {
+	return &netlink.XfrmMark{
+		Value: uint32(spi)<<12 | RouteMarkEncrypt,
+		Mask:  RouteMarkMask,
+	}
+}
+
+func decodeKey(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+// parseIPSecKey parses a line of the key file. The expected format is:
+//
+//	<spi> <auth-algorithm> <auth-key> <encryption-algorithm> <encryption-key>
+//
+// e.g. "3 hmac(sha256) 0123...cdef cbc(aes) 0123...cdef"
+func parseIPSecKey(line string) (*ipSecKey, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	spi, err := strconv.ParseUint(fields[0], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SPI %q: %s", fields[0], err)
+	}
+	if spi == 0 || spi > MaxSPI {
+		return nil, fmt.Errorf("SPI %d out of range [1, %d]", spi, MaxSPI)
+	}
+
+	authKey, err := decodeKey(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid authentication key: %s", err)
+	}
+
+	cryptKey, err := decodeKey(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %s", err)
+	}
+
+	return &ipSecKey{
+		Spi:   uint8(spi),
+		Auth:  &netlink.XfrmStateAlgo{Name: fields[1], Key: authKey},
+		Crypt: &netlink.XfrmStateAlgo{Name: fields[3], Key: cryptKey},
+	}, nil
+}
+
+// loadIPSecKeys replaces the set of known keys with the keys read from r.
+// Lines which are empty or start with '#' are ignored. It returns the SPI of
+// the key to use for outgoing traffic, which is the highest SPI listed, the
+// SPIs of all keys which were not known before and the SPIs of all keys which
+// are no longer listed, except for the key currently in use. The first keys
+// loaded are used right away, later keys only after SwitchIPSecKey.
+func loadIPSecKeys(r io.Reader) (uint8, []uint8, []uint8, error) {
+	keys := map[uint8]*ipSecKey{}
+	spi := uint8(0)
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := parseIPSecKey(line)
+		if err != nil {
+			return 0, nil, nil, fmt.Errorf("line %d: %s", lineNum, err)
+		}
+		if _, ok := keys[key.Spi]; ok {
+			return 0, nil, nil, fmt.Errorf("line %d: duplicate SPI %d", lineNum, key.Spi)
+		}
+		keys[key.Spi] = key
+		if key.Spi > spi {
+			spi = key.Spi
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, nil, nil, err
+	}
+	if len(keys) == 0 {
+		return 0, nil, nil, fmt.Errorf("no keys found")
+	}
+
+	ipSecLock.Lock()
+	defer ipSecLock.Unlock()
+
+	added, removed := []uint8{}, []uint8{}
+	for newSPI := range keys {
+		if _, ok := ipSecKeys[newSPI]; !ok {
+			added = append(added, newSPI)
+		}
+	}
+	for _, oldKey := range installedKeys() {
+		if _, ok := keys[oldKey.Spi]; !ok && oldKey != ipSecCurrentKey {
+			removed = append(removed, oldKey.Spi)
+		}
+	}
+	ipSecKeys = keys
+	if ipSecCurrentKey == nil {
+		ipSecCurrentKey = keys[spi]
+	}
+
+	return spi, added, removed, nil
+}
+
+// LoadIPSecKeysFile loads the keys from the key file at path and removes all
+// xfrm states and policies of keys which are no longer listed in the file. It
+// returns the SPI of the key to use for outgoing traffic and whether the set
+// of keys has changed since the last call.
+//
+// Keys are rotated by adding a new key with a higher SPI to the file, the
+// caller then switches to it with SwitchIPSecKey. Traffic using the previous
+// key continues to be decrypted until its line is removed from the file,
+// which should only be done once all nodes have switched to the new key.
+func LoadIPSecKeysFile(path string) (uint8, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer file.Close()
+
+	spi, added, removed, err := loadIPSecKeys(file)
+	if err != nil {
+		return 0, false, fmt.Errorf("unable to parse IPsec key file %s: %s", path, err)
+	}
+
+	for _, oldSPI := range removed {
+		if err := deleteIPSecKey(oldSPI); err != nil {
+			log.WithError(err).WithField(logfields.SPI, oldSPI).Warning("Unable to remove xfrm state of old IPsec key")
+		}
+	}
+
+	return spi, len(added) > 0 || len(removed) > 0, nil
+}
+
+// SwitchIPSecKey switches the key used to encrypt traffic leaving the local
+// node to the listed key with the given SPI. update is called to configure
+// the datapath with the new key. Afterwards, the xfrm states and policies of
+// the previous key are removed if it is no longer listed in the key file.
+func SwitchIPSecKey(spi uint8, update func(spi uint8) error) error {
+	ipSecLock.Lock()
+	defer ipSecLock.Unlock()
+
+	key, ok := ipSecKeys[spi]
+	if !ok {
+		return fmt.Errorf("IPsec key with SPI %d is no longer listed", spi)
+	}
+	if err := update(spi); err != nil {
+		return err
+	}
+
+	oldKey := ipSecCurrentKey
+	ipSecCurrentKey = key
+	if oldKey != nil && oldKey != key && ipSecKeys[oldKey.Spi] == nil {
+		return deleteIPSecKey(oldKey.Spi)
+	}
+	return nil
+}
+
+// GetKeyStatus returns the SPI of the key used for outgoing traffic and the
+// number of keys accepted for incoming traffic
+func GetKeyStatus() (uint8, int) {
+	ipSecLock.RLock()
+	defer ipSecLock.RUnlock()
+
+	if ipSecCurrentKey == nil {
+		return 0, 0
+	}
+	return ipSecCurrentKey.Spi, len(installedKeys())
+}
+
+func ipSecReplaceState(src, dst net.IP, key *ipSecKey, mark *netlink.XfrmMark) error {
+	state := &netlink.XfrmState{
+		Src:          src,
+		Dst:          dst,
+		Proto:        netlink.XFRM_PROTO_ESP,
+		Mode:         netlink.XFRM_MODE_TUNNEL,
+		Spi:          int(key.Spi),
+		Reqid:        ipSecReqID,
+		ReplayWindow: 32,
+		Auth:         key.Auth,
+		Crypt:        key.Crypt,
+		Mark:         mark,
+	}
+
+	// The key material of a state can't be changed, a new key must use a
+	// new SPI. An existing state is therefore already up to date.
+	if err := netlink.XfrmStateAdd(state); err != nil && !os.IsExist(err) {
+		return err
+	}
+	return nil
+}
+
+func ipSecReplacePolicy(src, dst *net.IPNet, tmplSrc, tmplDst net.IP, dir netlink.Dir, spi uint8, mark *netlink.XfrmMark) error {
+	policy := &netlink.XfrmPolicy{
+		Src:  src,
+		Dst:  dst,
+		Dir:  dir,
+		Mark: mark,
+		Tmpls: []netlink.XfrmPolicyTmpl{
+			{
+				Src:   tmplSrc,
+				Dst:   tmplDst,
+				Proto: netlink.XFRM_PROTO_ESP,
+				Mode:  netlink.XFRM_MODE_TUNNEL,
+				Spi:   int(spi),
+				Reqid: ipSecReqID,
+			},
+		},
+	}
+
+	return netlink.XfrmPolicyUpdate(policy)
+}
+
+// UpsertIPSecEndpoint installs the xfrm states and policies to encrypt all
+// traffic from the local allocation range towards the allocation range of a
+// remote node, and to decrypt the traffic in the opposite direction. A state
+// is installed for each known key, so that traffic from nodes which have not
+// switched to the latest key yet can still be decrypted.
+func UpsertIPSecEndpoint(local, remote *net.IPNet, localNodeIP, remoteNodeIP net.IP) error {
+	ipSecLock.RLock()
+	defer ipSecLock.RUnlock()
+
+	if len(ipSecKeys) == 0 {
+		return fmt.Errorf("no IPsec keys loaded")
+	}
+
+	for _, key := range installedKeys() {
+		if err := ipSecReplaceState(localNodeIP, remoteNodeIP, key, getEncryptMark(key.Spi)); err != nil {
+			return fmt.Errorf("unable to replace egress xfrm state: %s", err)
+		}
+		if err := ipSecReplaceState(remoteNodeIP, localNodeIP, key, nil); err != nil {
+			return fmt.Errorf("unable to replace ingress xfrm state: %s", err)
+		}
+		if err := ipSecReplacePolicy(local, remote, localNodeIP, remoteNodeIP, netlink.XFRM_DIR_OUT, key.Spi, getEncryptMark(key.Spi)); err != nil {
+			return fmt.Errorf("unable to replace egress xfrm policy: %s", err)
+		}
+	}
+
+	// The ingress policies accept traffic encrypted with any known key. The
+	// decrypted traffic is forwarded to the endpoints, which requires a
+	// forward policy as well.
+	if err := ipSecReplacePolicy(remote, local, remoteNodeIP, localNodeIP, netlink.XFRM_DIR_IN, 0, nil); err != nil {
+		return fmt.Errorf("unable to replace ingress xfrm policy: %s", err)
+	}
+	if err := ipSecReplacePolicy(remote, local, remoteNodeIP, localNodeIP, netlink.XFRM_DIR_FWD, 0, nil); err != nil {
+		return fmt.Errorf("unable to replace forward xfrm policy: %s", err)
+	}
+
+	return nil
+}
+
+func ipFamily(ip net.IP) int {
+	if ip.To4() != nil {
+		return netlink.FAMILY_V4
+	}
+	return netlink.FAMILY_V6
+}
+
+// deleteIPSecStates removes all xfrm states of the given family managed by
+// Cilium for which match returns true
+func deleteIPSecStates(family int, match func(s *netlink.XfrmState) bool) error {
+	states, err := netlink.XfrmStateList(family)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for i := range states {
+		s := &states[i]
+		if s.Reqid != ipSecReqID || !match(s) {
+			continue
+		}
+		if err := netlink.XfrmStateDel(s); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to delete xfrm states: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// deleteIPSecPolicies removes all xfrm policies of the given family managed
+// by Cilium for which match returns true
+func deleteIPSecPolicies(family int, match func(p *netlink.XfrmPolicy) bool) error {
+	policies, err := netlink.XfrmPolicyList(family)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for i := range policies {
+		p := &policies[i]
+		if len(p.Tmpls) == 0 || p.Tmpls[0].Reqid != ipSecReqID || !match(p) {
+			continue
+		}
+		if err := netlink.XfrmPolicyDel(p); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to delete xfrm policies: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// DeleteIPSecEndpoint removes all xfrm states and policies used to encrypt
+// and decrypt traffic exchanged with the remote node reachable at
+// remoteNodeIP, i.e. the egress, ingress and forward policies.
+func DeleteIPSecEndpoint(remoteNodeIP net.IP) error {
+	family := ipFamily(remoteNodeIP)
+
+	errState := deleteIPSecStates(family, func(s *netlink.XfrmState) bool {
+		return s.Src.Equal(remoteNodeIP) || s.Dst.Equal(remoteNodeIP)
+	})
+	errPolicy := deleteIPSecPolicies(family, func(p *netlink.XfrmPolicy) bool {
+		return p.Tmpls[0].Src.Equal(remoteNodeIP) || p.Tmpls[0].Dst.Equal(remoteNodeIP)
+	})
+
+	if errState != nil {
+		return errState
+	}
+	return errPolicy
+}
+
+// deleteIPSecKey removes all xfrm states and policies using the key with the
+// given SPI
+func deleteIPSecKey(spi uint8) error {
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		if err := deleteIPSecStates(family, func(s *netlink.XfrmState) bool {
+			return s.Spi == int(spi)
+		}); err != nil {
+			return err
+		}
+		if err := deleteIPSecPolicies(family, func(p *netlink.XfrmPolicy) bool {
+			return p.Tmpls[0].Spi == int(spi)
+		}); err != nil {
+			return err
+		}
+	}
+
+	log.WithFields(logrus.Fields{
+		logfields.SPI: spi,
+	}).Info("Removed xfrm states and policies of old IPsec key")
+
+	return nil
+}
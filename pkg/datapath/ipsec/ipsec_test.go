@@ -0,0 +1,130 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package ipsec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/checker"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) { TestingT(t) }
+
+type IPSecSuite struct{}
+
+var _ = Suite(&IPSecSuite{})
+
+const (
+	authKey  = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	cryptKey = "0x0123456789abcdef0123456789abcdef"
+)
+
+func (s *IPSecSuite) TearDownTest(c *C) {
+	ipSecKeys = map[uint8]*ipSecKey{}
+	ipSecCurrentKey = nil
+}
+
+func (s *IPSecSuite) TestParseIPSecKey(c *C) {
+	key, err := parseIPSecKey("3 hmac(sha256) " + authKey + " cbc(aes) " + cryptKey)
+	c.Assert(err, IsNil)
+	c.Assert(key.Spi, Equals, uint8(3))
+	c.Assert(key.Auth.Name, Equals, "hmac(sha256)")
+	c.Assert(len(key.Auth.Key), Equals, 32)
+	c.Assert(key.Crypt.Name, Equals, "cbc(aes)")
+	c.Assert(len(key.Crypt.Key), Equals, 16)
+
+	for _, line := range []string{
+		"",
+		"3 hmac(sha256) " + authKey + " cbc(aes)",
+		"0 hmac(sha256) " + authKey + " cbc(aes) " + cryptKey,
+		"16 hmac(sha256) " + authKey + " cbc(aes) " + cryptKey,
+		"x hmac(sha256) " + authKey + " cbc(aes) " + cryptKey,
+		"3 hmac(sha256) nothex cbc(aes) " + cryptKey,
+		"3 hmac(sha256) " + authKey + " cbc(aes) nothex",
+	} {
+		_, err := parseIPSecKey(line)
+		c.Assert(err, Not(IsNil), Commentf("line %q", line))
+	}
+}
+
+func (s *IPSecSuite) TestLoadIPSecKeys(c *C) {
+	keyLine := func(spi string) string {
+		return spi + " hmac(sha256) " + authKey + " cbc(aes) " + cryptKey + "\n"
+	}
+
+	spi, added, removed, err := loadIPSecKeys(strings.NewReader("# comment\n\n" + keyLine("1")))
+	c.Assert(err, IsNil)
+	c.Assert(spi, Equals, uint8(1))
+	c.Assert(added, checker.DeepEquals, []uint8{1})
+	c.Assert(removed, checker.DeepEquals, []uint8{})
+
+	current, keys := GetKeyStatus()
+	c.Assert(current, Equals, uint8(1))
+	c.Assert(keys, Equals, 1)
+
+	// Rotation: the key with the highest SPI is installed, but outgoing
+	// traffic keeps using the previous key until switching to it
+	spi, added, removed, err = loadIPSecKeys(strings.NewReader(keyLine("1") + keyLine("2")))
+	c.Assert(err, IsNil)
+	c.Assert(spi, Equals, uint8(2))
+	c.Assert(added, checker.DeepEquals, []uint8{2})
+	c.Assert(removed, checker.DeepEquals, []uint8{})
+
+	current, keys = GetKeyStatus()
+	c.Assert(current, Equals, uint8(1))
+	c.Assert(keys, Equals, 2)
+
+	updated := []uint8{}
+	update := func(spi uint8) error {
+		updated = append(updated, spi)
+		return nil
+	}
+	c.Assert(SwitchIPSecKey(2, update), IsNil)
+	c.Assert(SwitchIPSecKey(5, update), Not(IsNil))
+	c.Assert(updated, checker.DeepEquals, []uint8{2})
+
+	current, keys = GetKeyStatus()
+	c.Assert(current, Equals, uint8(2))
+	c.Assert(keys, Equals, 2)
+
+	// The key in use is retained until switching to another key
+	spi, added, removed, err = loadIPSecKeys(strings.NewReader(keyLine("3")))
+	c.Assert(err, IsNil)
+	c.Assert(spi, Equals, uint8(3))
+	c.Assert(added, checker.DeepEquals, []uint8{3})
+	c.Assert(removed, checker.DeepEquals, []uint8{1})
+
+	// Invalid files leave the loaded keys untouched
+	_, _, _, err = loadIPSecKeys(strings.NewReader(keyLine("4") + keyLine("4")))
+	c.Assert(err, Not(IsNil))
+	_, _, _, err = loadIPSecKeys(strings.NewReader("# no keys\n"))
+	c.Assert(err, Not(IsNil))
+
+	current, keys = GetKeyStatus()
+	c.Assert(current, Equals, uint8(2))
+	c.Assert(keys, Equals, 2)
+}
+
+func (s *IPSecSuite) TestGetEncryptMark(c *C) {
+	mark := getEncryptMark(3)
+	c.Assert(mark.Value, Equals, uint32(0x3E00))
+	c.Assert(mark.Mask, Equals, uint32(RouteMarkMask))
+}
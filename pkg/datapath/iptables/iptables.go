@@ -345,3 +345,87 @@ func InstallRules() error {
 
 	return nil
 }
+
+// InstallAuxAllocRangeRules installs the rules accepting the forwarding of
+// and masquerading the traffic from the additional allocation range cidr in
+// the same way as for the local node allocation range. The rules are removed
+// by RemoveRules.
+func InstallAuxAllocRangeRules(cidr string) error {
+	if err := runProg("iptables", []string{
+		"-A", ciliumForwardChain,
+		"-s", cidr,
+		"-m", "comment", "--comment", "cilium: cluster->any forward accept",
+		"-j", "ACCEPT"}, false); err != nil {
+		return err
+	}
+
+	if option.Config.Masquerade {
+		egressSnatDstAddrExclusion := cidr
+		if option.Config.Tunnel == option.TunnelDisabled {
+			egressSnatDstAddrExclusion = node.GetIPv4ClusterRange().String()
+		}
+
+		if err := runProg("iptables", []string{
+			"-t", "nat",
+			"-A", "CILIUM_POST",
+			"-s", cidr,
+			"!", "-d", egressSnatDstAddrExclusion,
+			"!", "-o", "cilium_+",
+			"-m", "comment", "--comment", "cilium masquerade non-cluster",
+			"-j", "MASQUERADE"}, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// egressNATRuleArgs returns the arguments of the iptables command op applied to
+// the rule masquerading the traffic from srcIP to dstCIDR with egressIP.
+func egressNATRuleArgs(op, srcIP, dstCIDR, egressIP string) []string {
+	return []string{
+		"-t", "nat",
+		op, ciliumPostNatChain,
+		"-s", srcIP,
+		"-d", dstCIDR,
+		"-m", "comment", "--comment", "cilium egress nat",
+		"-j", "SNAT", "--to-source", egressIP}
+}
+
+// egressForwardRuleArgs returns the arguments of the iptables command op
+// applied to the rule accepting the forwarding of the traffic from srcIP to
+// dstCIDR.
+func egressForwardRuleArgs(op, srcIP, dstCIDR string) []string {
+	return []string{
+		"-t", "filter",
+		op, ciliumForwardChain,
+		"-s", srcIP,
+		"-d", dstCIDR,
+		"-m", "comment", "--comment", "cilium egress gateway forward accept",
+		"-j", "ACCEPT"}
+}
+
+// InstallEgressNATRules installs the rules forwarding the traffic from srcIP
+// to dstCIDR and masquerading it with egressIP. The rules are inserted at the
+// top of the chains so that they take precedence over the masquerading of
+// the traffic of local endpoints.
+func InstallEgressNATRules(srcIP, dstCIDR, egressIP string) error {
+	if err := runProg("iptables", egressForwardRuleArgs("-I", srcIP, dstCIDR), false); err != nil {
+		return err
+	}
+	if err := runProg("iptables", egressNATRuleArgs("-I", srcIP, dstCIDR, egressIP), false); err != nil {
+		runProg("iptables", egressForwardRuleArgs("-D", srcIP, dstCIDR), true)
+		return err
+	}
+	return nil
+}
+
+// RemoveEgressNATRules removes the rules installed by InstallEgressNATRules.
+func RemoveEgressNATRules(srcIP, dstCIDR, egressIP string) error {
+	errNAT := runProg("iptables", egressNATRuleArgs("-D", srcIP, dstCIDR, egressIP), false)
+	errForward := runProg("iptables", egressForwardRuleArgs("-D", srcIP, dstCIDR), false)
+	if errNAT != nil {
+		return errNAT
+	}
+	return errForward
+}
@@ -46,6 +46,8 @@ type progInfo struct {
 	Output string
 	// OutputType to be created by LLVM
 	OutputType OutputType
+	// Options are additional compiler flags, e.g. preprocessor defines
+	Options []string
 }
 
 // directoryInfo includes relevant directories for compilation and linking
@@ -74,6 +76,13 @@ var (
 	endpointObjDebug = fmt.Sprintf("%s.dbg.o", endpointPrefix)
 	endpointAsm      = fmt.Sprintf("%s.%s", endpointPrefix, outputAssembly)
 
+	hostEndpointPrefix   = "bpf_netdev"
+	hostEndpointProg     = fmt.Sprintf("%s.%s", hostEndpointPrefix, outputSource)
+	hostEndpointObj      = fmt.Sprintf("%s.o", hostEndpointPrefix)
+	hostEndpointObjDebug = fmt.Sprintf("%s.dbg.o", hostEndpointPrefix)
+	hostEndpointAsm      = fmt.Sprintf("%s.%s", hostEndpointPrefix, outputAssembly)
+	hostEndpointOptions  = []string{"-DHOST_ENDPOINT"}
+
 	// testIncludes allows the unit tests to inject additional include
 	// paths into the compile command at test time. It is usually nil.
 	testIncludes string
@@ -100,6 +109,33 @@ var (
 		Output:     endpointObj,
 		OutputType: outputObject,
 	}
+
+	hostDebugProgs = []*progInfo{
+		{
+			Source:     hostEndpointProg,
+			Output:     hostEndpointObjDebug,
+			OutputType: outputObject,
+			Options:    hostEndpointOptions,
+		},
+		{
+			Source:     hostEndpointProg,
+			Output:     hostEndpointAsm,
+			OutputType: outputAssembly,
+			Options:    hostEndpointOptions,
+		},
+		{
+			Source:     hostEndpointProg,
+			Output:     hostEndpointProg,
+			OutputType: outputSource,
+			Options:    hostEndpointOptions,
+		},
+	}
+	hostDatapathProg = &progInfo{
+		Source:     hostEndpointProg,
+		Output:     hostEndpointObj,
+		OutputType: outputObject,
+		Options:    hostEndpointOptions,
+	}
 )
 
 // progLDFlags determines the loader flags for the specified prog and paths.
@@ -191,14 +227,17 @@ func progCFlags(prog *progInfo, dir *directoryInfo) []string {
 		output = "-" // stdout
 	}
 
-	return []string{
+	flags := []string{
 		testIncludes,
 		fmt.Sprintf("-I%s", path.Join(dir.Runtime, "globals")),
 		fmt.Sprintf("-I%s", dir.State),
 		fmt.Sprintf("-I%s", path.Join(dir.Library, "include")),
+	}
+	flags = append(flags, prog.Options...)
+	return append(flags,
 		"-c", path.Join(dir.Library, prog.Source),
 		"-o", output,
-	}
+	)
 }
 
 // compile and link a program.
@@ -32,6 +32,11 @@ var (
 
 const (
 	symbolFromEndpoint = "from-container"
+	symbolFromNetdev   = "from-netdev"
+	symbolToNetdev     = "to-netdev"
+
+	dirIngress = "ingress"
+	dirEgress  = "egress"
 )
 
 // endpoint provides access to endpoint information that is necessary to
@@ -40,6 +45,18 @@ type endpoint interface {
 	InterfaceName() string
 	Logger(subsystem string) *logrus.Entry
 	StateDir() string
+	// IsHost returns true if the endpoint is the host endpoint, whose
+	// datapath is attached to the native device of the node.
+	IsHost() bool
+}
+
+// datapathProgs returns the program to compile for the endpoint as well as
+// the additional programs to compile if debugging is enabled.
+func datapathProgs(ep endpoint) (*progInfo, []*progInfo) {
+	if ep.IsHost() {
+		return hostDatapathProg, hostDebugProgs
+	}
+	return datapathProg, debugProgs
 }
 
 // compileDatapath invokes the compiler and linker to create all state files for
@@ -52,10 +69,11 @@ type endpoint interface {
 func compileDatapath(ctx context.Context, ep endpoint, dirs *directoryInfo, debug bool) error {
 	// TODO: Consider logging kernel/clang versions here too
 	epLog := ep.Logger(Subsystem)
+	prog, progsDebug := datapathProgs(ep)
 
 	// Write out assembly and preprocessing files for debugging purposes
 	if debug {
-		for _, p := range debugProgs {
+		for _, p := range progsDebug {
 			if err := compile(ctx, p, dirs, debug); err != nil {
 				scopedLog := epLog.WithFields(logrus.Fields{
 					logfields.Params: logfields.Repr(p),
@@ -68,9 +86,9 @@ func compileDatapath(ctx context.Context, ep endpoint, dirs *directoryInfo, debu
 	}
 
 	// Compile the new program
-	if err := compile(ctx, datapathProg, dirs, debug); err != nil {
+	if err := compile(ctx, prog, dirs, debug); err != nil {
 		scopedLog := epLog.WithFields(logrus.Fields{
-			logfields.Params: logfields.Repr(datapathProg),
+			logfields.Params: logfields.Repr(prog),
 			logfields.Debug:  false,
 		})
 		scopedLog.WithError(err).Warn("JoinEP: Failed to compile")
@@ -81,9 +99,23 @@ func compileDatapath(ctx context.Context, ep endpoint, dirs *directoryInfo, debu
 }
 
 func reloadDatapath(ctx context.Context, ep endpoint, dirs *directoryInfo) error {
+	var (
+		objPath string
+		err     error
+	)
+
 	// Replace the current program
-	objPath := path.Join(dirs.Output, endpointObj)
-	if err := replaceDatapath(ctx, ep.InterfaceName(), objPath, symbolFromEndpoint); err != nil {
+	if ep.IsHost() {
+		objPath = path.Join(dirs.Output, hostEndpointObj)
+		err = replaceDatapath(ctx, ep.InterfaceName(), objPath, symbolFromNetdev, dirIngress)
+		if err == nil {
+			err = replaceDatapath(ctx, ep.InterfaceName(), objPath, symbolToNetdev, dirEgress)
+		}
+	} else {
+		objPath = path.Join(dirs.Output, endpointObj)
+		err = replaceDatapath(ctx, ep.InterfaceName(), objPath, symbolFromEndpoint, dirIngress)
+	}
+	if err != nil {
 		scopedLog := ep.Logger(Subsystem).WithFields(logrus.Fields{
 			logfields.Path: objPath,
 			logfields.Veth: ep.InterfaceName(),
@@ -97,6 +97,10 @@ func (ep *testEP) StateDir() string {
 	return "test_loader"
 }
 
+func (ep *testEP) IsHost() bool {
+	return false
+}
+
 func prepareEnv(ep *testEP) (func() error, error) {
 	link := netlink.Dummy{
 		LinkAttrs: netlink.LinkAttrs{
@@ -151,10 +155,10 @@ func (s *LoaderTestSuite) TestReload(c *C) {
 	c.Assert(err, IsNil)
 
 	objPath := fmt.Sprintf("%s/%s", dirInfo.Output, endpointObj)
-	err = replaceDatapath(ctx, ep.InterfaceName(), objPath, symbolFromEndpoint)
+	err = replaceDatapath(ctx, ep.InterfaceName(), objPath, symbolFromEndpoint, dirIngress)
 	c.Assert(err, IsNil)
 
-	err = replaceDatapath(ctx, ep.InterfaceName(), objPath, symbolFromEndpoint)
+	err = replaceDatapath(ctx, ep.InterfaceName(), objPath, symbolFromEndpoint, dirIngress)
 	c.Assert(err, IsNil)
 }
 
@@ -208,7 +212,7 @@ func BenchmarkReplaceDatapath(b *testing.B) {
 	objPath := fmt.Sprintf("%s/%s", dirInfo.Output, endpointObj)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if err := replaceDatapath(ctx, ep.InterfaceName(), objPath, symbolFromEndpoint); err != nil {
+		if err := replaceDatapath(ctx, ep.InterfaceName(), objPath, symbolFromEndpoint, dirIngress); err != nil {
 			b.Fatal(err)
 		}
 	}
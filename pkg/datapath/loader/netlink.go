@@ -1,4 +1,4 @@
-// Copyright 2017-2018 Authors of Cilium
+// Copyright 2017-2019 Authors of Cilium
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -17,15 +17,13 @@ package loader
 import (
 	"context"
 	"fmt"
+	"path"
 
 	"github.com/cilium/cilium/pkg/bpf"
-	"github.com/cilium/cilium/pkg/command/exec"
 
 	"github.com/vishvananda/netlink"
-)
-
-const (
-	libbpfFixupMsg = "struct bpf_elf_map fixup performed due to size mismatch!"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 )
 
 func replaceQdisc(ifName string) error {
@@ -53,41 +51,95 @@ func replaceQdisc(ifName string) error {
 	return nil
 }
 
-// replaceDatapath the qdisc and BPF program for a endpoint
-func replaceDatapath(ctx context.Context, ifName string, objPath string, progSec string) error {
-	err := replaceQdisc(ifName)
+// replaceBPFFilter attaches the BPF program of filter, replacing any filter
+// with the same handle and priority. It is equivalent to `tc filter replace`,
+// which the vendored netlink library only supports in its add variant that
+// fails if the filter already exists.
+func replaceBPFFilter(filter *netlink.BpfFilter) error {
+	attrs := filter.Attrs()
+	req := nl.NewNetlinkRequest(unix.RTM_NEWTFILTER, unix.NLM_F_CREATE|unix.NLM_F_ACK)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(attrs.LinkIndex),
+		Handle:  attrs.Handle,
+		Parent:  attrs.Parent,
+		Info:    netlink.MakeHandle(attrs.Priority, nl.Swap16(attrs.Protocol)),
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated(filter.Type())))
+
+	var flags uint32
+	if filter.DirectAction {
+		flags |= nl.TCA_BPF_FLAG_ACT_DIRECT
+	}
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	nl.NewRtAttrChild(options, nl.TCA_BPF_FD, nl.Uint32Attr(uint32(filter.Fd)))
+	nl.NewRtAttrChild(options, nl.TCA_BPF_NAME, nl.ZeroTerminated(filter.Name))
+	nl.NewRtAttrChild(options, nl.TCA_BPF_FLAGS, nl.Uint32Attr(flags))
+	req.AddData(options)
+
+	_, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	return err
+}
+
+// replaceDatapath the qdisc and BPF program for a endpoint. progDirection
+// selects whether the program is attached to the ingress or egress hook.
+//
+// The object is loaded natively: maps are created or re-used from bpffs,
+// with pinned maps whose properties changed being migrated, the program in
+// section progSec and its tail calls are loaded and the program is attached
+// as a direct-action tc filter.
+func replaceDatapath(ctx context.Context, ifName string, objPath string, progSec string, progDirection string) (err error) {
+	link, err := netlink.LinkByName(ifName)
 	if err != nil {
+		return err
+	}
+
+	var parent uint32
+	switch progDirection {
+	case dirIngress:
+		parent = netlink.HANDLE_MIN_INGRESS
+	case dirEgress:
+		parent = netlink.HANDLE_MIN_EGRESS
+	default:
+		return fmt.Errorf("Invalid program direction %s", progDirection)
+	}
+
+	if err = replaceQdisc(ifName); err != nil {
 		return fmt.Errorf("Failed to replace Qdisc for %s: %s", ifName, err)
 	}
 
-	// FIXME: Replace cilium-map-migrate with Golang map migration
-	cmd := exec.CommandContext(ctx, "cilium-map-migrate", "-s", objPath)
-	cmd.Env = bpf.Environment()
-	if _, err = cmd.CombinedOutput(log, true); err != nil {
+	obj, err := bpf.ParseELF(objPath)
+	if err != nil {
 		return err
 	}
+
+	coll, err := bpf.LoadCollection(obj, bpf.ProgTypeSchedCls, progSec)
+	if err != nil {
+		return fmt.Errorf("Failed to load %s: %s", objPath, err)
+	}
+	defer coll.Close()
 	defer func() {
-		var retCode string
-		if err == nil {
-			retCode = "0"
-		} else {
-			retCode = "1"
-		}
-		args := []string{"-e", objPath, "-r", retCode}
-		cmd := exec.CommandContext(ctx, "cilium-map-migrate", args...)
-		cmd.Env = bpf.Environment()
-		_, _ = cmd.CombinedOutput(log, true) // ignore errors
+		coll.FinalizeMigration(err == nil)
 	}()
 
-	// FIXME: replace exec with native call
-	args := []string{"filter", "replace", "dev", ifName, "ingress",
-		"prio", "1", "handle", "1", "bpf", "da", "obj", objPath,
-		"sec", progSec,
+	if err = ctx.Err(); err != nil {
+		return err
 	}
-	cmd = exec.CommandContext(ctx, "tc", args...).WithFilters(libbpfFixupMsg)
-	_, err = cmd.CombinedOutput(log, true)
-	if err != nil {
-		return fmt.Errorf("Failed to load tc filter: %s", err)
+
+	filter := &netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: link.Attrs().Index,
+			Parent:    parent,
+			Handle:    1,
+			Protocol:  unix.ETH_P_ALL,
+			Priority:  1,
+		},
+		Fd:           coll.Programs[progSec],
+		Name:         fmt.Sprintf("%s:[%s]", path.Base(objPath), progSec),
+		DirectAction: true,
+	}
+	if err = replaceBPFFilter(filter); err != nil {
+		return fmt.Errorf("Failed to attach tc filter: %s", err)
 	}
 
 	return nil
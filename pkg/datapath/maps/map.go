@@ -134,6 +134,10 @@ func RemoveDisabledMaps() {
 			"cilium_proxy4"}...)
 	}
 
+	if !option.Config.EnableEgressGateway {
+		maps = append(maps, "cilium_egress_v4")
+	}
+
 	for _, m := range maps {
 		p := path.Join(bpf.MapPrefixPath(), m)
 		if _, err := os.Stat(p); !os.IsNotExist(err) {
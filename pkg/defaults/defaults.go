@@ -59,6 +59,10 @@ const (
 	// This is the 1.2 protocol version.
 	MonitorSockPath1_2 = RuntimePath + "/monitor1_2.sock"
 
+	// ObserverSockPath is the path to the UNIX domain socket serving the
+	// gRPC flow observer API of the node monitor.
+	ObserverSockPath = RuntimePath + "/observer.sock"
+
 	// PidFilePath is the path to the pid file for the agent.
 	PidFilePath = RuntimePath + "/cilium.pid"
 
@@ -0,0 +1,91 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package egressgateway
+
+import (
+	"net"
+
+	"github.com/cilium/cilium/pkg/datapath/iptables"
+	"github.com/cilium/cilium/pkg/maps/egressmap"
+)
+
+// Entry steers the traffic from a local pod to a destination prefix through
+// a gateway node
+type Entry struct {
+	SourceIP        string
+	DestinationCIDR string
+	EgressIP        string
+	TunnelEndpoint  string
+}
+
+// NATRule masquerades the traffic from a pod to a destination prefix on the
+// gateway node
+type NATRule struct {
+	SourceIP        string
+	DestinationCIDR string
+	EgressIP        string
+}
+
+// Datapath is the datapath state programmed by the Manager
+type Datapath interface {
+	// UpdateEgressEntry inserts or updates the egress map entry of e
+	UpdateEgressEntry(e Entry) error
+
+	// DeleteEgressEntry deletes the egress map entry of e
+	DeleteEgressEntry(e Entry) error
+
+	// InstallNATRule installs the iptables rules of r
+	InstallNATRule(r NATRule) error
+
+	// RemoveNATRule removes the iptables rules of r
+	RemoveNATRule(r NATRule) error
+}
+
+// linuxDatapath programs the egress map and iptables
+type linuxDatapath struct{}
+
+func egressMapKeyValue(e Entry) (egressmap.Key, egressmap.Value, error) {
+	_, cidr, err := net.ParseCIDR(e.DestinationCIDR)
+	if err != nil {
+		return egressmap.Key{}, egressmap.Value{}, err
+	}
+	key := egressmap.NewKey(net.ParseIP(e.SourceIP), cidr)
+	value := egressmap.NewValue(net.ParseIP(e.EgressIP), net.ParseIP(e.TunnelEndpoint))
+	return key, value, nil
+}
+
+func (linuxDatapath) UpdateEgressEntry(e Entry) error {
+	key, value, err := egressMapKeyValue(e)
+	if err != nil {
+		return err
+	}
+	return egressmap.Egress.Update(&key, &value)
+}
+
+func (linuxDatapath) DeleteEgressEntry(e Entry) error {
+	key, _, err := egressMapKeyValue(e)
+	if err != nil {
+		return err
+	}
+	return egressmap.Egress.Delete(&key)
+}
+
+func (linuxDatapath) InstallNATRule(r NATRule) error {
+	return iptables.InstallEgressNATRules(r.SourceIP, r.DestinationCIDR, r.EgressIP)
+}
+
+func (linuxDatapath) RemoveNATRule(r NATRule) error {
+	return iptables.RemoveEgressNATRules(r.SourceIP, r.DestinationCIDR, r.EgressIP)
+}
@@ -0,0 +1,331 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package egressgateway
+
+import (
+	"net"
+	"sort"
+
+	k8sConst "github.com/cilium/cilium/pkg/k8s/apis/cilium.io"
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/node"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+)
+
+// podInfo is the state of a pod relevant to egress policies
+type podInfo struct {
+	labels   labels.LabelArray
+	ip       string
+	nodeName string
+}
+
+// entryKey identifies the traffic steered by an Entry or NATRule
+type entryKey struct {
+	sourceIP        string
+	destinationCIDR string
+}
+
+// Manager keeps the egress map and the NAT rules of the gateway node in sync
+// with the CiliumEgressNATPolicies, the pods and the nodes of the cluster.
+type Manager struct {
+	mutex lock.Mutex
+
+	// nodeName is the name of the local node
+	nodeName string
+
+	datapath Datapath
+
+	policies map[PolicyID]*Policy
+	pods     map[string]*podInfo
+	nodeIPs  map[string]net.IP
+
+	// entries and natRules are the entries and rules installed in the
+	// datapath
+	entries  map[entryKey]Entry
+	natRules map[NATRule]struct{}
+}
+
+// NewManager returns a Manager programming the egress map and iptables of the
+// local node.
+func NewManager() *Manager {
+	return newManager(node.GetName(), linuxDatapath{})
+}
+
+func newManager(nodeName string, dp Datapath) *Manager {
+	return &Manager{
+		nodeName: nodeName,
+		datapath: dp,
+		policies: map[PolicyID]*Policy{},
+		pods:     map[string]*podInfo{},
+		nodeIPs:  map[string]net.IP{},
+		entries:  map[entryKey]Entry{},
+		natRules: map[NATRule]struct{}{},
+	}
+}
+
+// OnAddEgressPolicy adds or updates the policy p.
+func (m *Manager) OnAddEgressPolicy(p *Policy) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.policies[p.ID] = p
+	m.reconcile()
+}
+
+// OnDeleteEgressPolicy deletes the policy identified by id.
+func (m *Manager) OnDeleteEgressPolicy(id PolicyID) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.policies[id]; !ok {
+		return
+	}
+	delete(m.policies, id)
+	m.reconcile()
+}
+
+// HasPolicy returns true if the policy identified by id has been added.
+func (m *Manager) HasPolicy(id PolicyID) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, ok := m.policies[id]
+	return ok
+}
+
+func podKey(pod *v1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+// getPodInfo returns the state of pod relevant to egress policies, or nil if
+// egress policies do not apply to pod.
+func getPodInfo(pod *v1.Pod) *podInfo {
+	if pod.Spec.HostNetwork || pod.Spec.NodeName == "" {
+		return nil
+	}
+	ip := net.ParseIP(pod.Status.PodIP)
+	if ip == nil || ip.To4() == nil {
+		return nil
+	}
+
+	lbls := make(map[string]string, len(pod.Labels)+2)
+	for k, v := range pod.Labels {
+		lbls[k] = v
+	}
+	lbls[k8sConst.PodNamespaceLabel] = pod.Namespace
+	if pod.Spec.ServiceAccountName != "" {
+		lbls[k8sConst.PolicyLabelServiceAccount] = pod.Spec.ServiceAccountName
+	}
+
+	return &podInfo{
+		labels:   labels.Map2Labels(lbls, labels.LabelSourceK8s).LabelArray(),
+		ip:       ip.String(),
+		nodeName: pod.Spec.NodeName,
+	}
+}
+
+// OnUpdatePod adds or updates the pod.
+func (m *Manager) OnUpdatePod(pod *v1.Pod) {
+	info := getPodInfo(pod)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := podKey(pod)
+	if info == nil {
+		if _, ok := m.pods[key]; !ok {
+			return
+		}
+		delete(m.pods, key)
+	} else {
+		m.pods[key] = info
+	}
+	m.reconcile()
+}
+
+// OnDeletePod deletes the pod.
+func (m *Manager) OnDeletePod(pod *v1.Pod) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := podKey(pod)
+	if _, ok := m.pods[key]; !ok {
+		return
+	}
+	delete(m.pods, key)
+	m.reconcile()
+}
+
+// OnUpdateNode adds or updates the IP of the node called name.
+func (m *Manager) OnUpdateNode(name string, ip net.IP) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if ip == nil {
+		delete(m.nodeIPs, name)
+	} else {
+		if oldIP, ok := m.nodeIPs[name]; ok && oldIP.Equal(ip) {
+			return
+		}
+		m.nodeIPs[name] = ip
+	}
+	m.reconcile()
+}
+
+// OnDeleteNode deletes the node called name.
+func (m *Manager) OnDeleteNode(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.nodeIPs[name]; !ok {
+		return
+	}
+	delete(m.nodeIPs, name)
+	m.reconcile()
+}
+
+// ReinstallRules installs all NAT rules again. It must be called after the
+// Cilium iptables chains have been recreated.
+func (m *Manager) ReinstallRules() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for r := range m.natRules {
+		if err := m.datapath.InstallNATRule(r); err != nil {
+			log.WithError(err).WithFields(natRuleFields(r)).Warning("Unable to reinstall egress NAT rule")
+		}
+	}
+}
+
+func entryFields(e Entry) logrus.Fields {
+	return logrus.Fields{
+		logfields.IPAddr:   e.SourceIP,
+		logfields.V4Prefix: e.DestinationCIDR,
+		"egressIP":         e.EgressIP,
+		"tunnelEndpoint":   e.TunnelEndpoint,
+	}
+}
+
+func natRuleFields(r NATRule) logrus.Fields {
+	return logrus.Fields{
+		logfields.IPAddr:   r.SourceIP,
+		logfields.V4Prefix: r.DestinationCIDR,
+		"egressIP":         r.EgressIP,
+	}
+}
+
+// desiredState returns the entries and NAT rules required by the current
+// policies. When several policies select the same pod and destination prefix,
+// the policy whose ID sorts first wins.
+func (m *Manager) desiredState() (map[entryKey]Entry, map[NATRule]struct{}) {
+	entries := map[entryKey]Entry{}
+	natRules := map[NATRule]struct{}{}
+	claimed := map[entryKey]struct{}{}
+
+	ids := make([]PolicyID, 0, len(m.policies))
+	for id := range m.policies {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+
+	for _, id := range ids {
+		p := m.policies[id]
+		isGateway := p.GatewayNode == m.nodeName
+		gatewayIP := m.nodeIPs[p.GatewayNode]
+
+		for _, pod := range m.pods {
+			if !p.EndpointSelector.Matches(pod.labels) {
+				continue
+			}
+			for _, cidr := range p.DestinationCIDRs {
+				key := entryKey{sourceIP: pod.ip, destinationCIDR: cidr.String()}
+				if _, ok := claimed[key]; ok {
+					continue
+				}
+				claimed[key] = struct{}{}
+
+				switch {
+				case isGateway:
+					natRules[NATRule{
+						SourceIP:        key.sourceIP,
+						DestinationCIDR: key.destinationCIDR,
+						EgressIP:        p.EgressIP.String(),
+					}] = struct{}{}
+				case pod.nodeName == m.nodeName && gatewayIP != nil:
+					entries[key] = Entry{
+						SourceIP:        key.sourceIP,
+						DestinationCIDR: key.destinationCIDR,
+						EgressIP:        p.EgressIP.String(),
+						TunnelEndpoint:  gatewayIP.String(),
+					}
+				}
+			}
+		}
+	}
+
+	return entries, natRules
+}
+
+// reconcile brings the datapath in sync with the desired state. Must be
+// called with m.mutex held.
+func (m *Manager) reconcile() {
+	entries, natRules := m.desiredState()
+
+	for key, e := range m.entries {
+		if newEntry, ok := entries[key]; ok && newEntry == e {
+			continue
+		}
+		if err := m.datapath.DeleteEgressEntry(e); err != nil {
+			log.WithError(err).WithFields(entryFields(e)).Warning("Unable to delete egress map entry")
+		}
+		delete(m.entries, key)
+	}
+	for key, e := range entries {
+		if _, ok := m.entries[key]; ok {
+			continue
+		}
+		if err := m.datapath.UpdateEgressEntry(e); err != nil {
+			log.WithError(err).WithFields(entryFields(e)).Warning("Unable to insert egress map entry")
+			continue
+		}
+		m.entries[key] = e
+	}
+
+	for r := range m.natRules {
+		if _, ok := natRules[r]; ok {
+			continue
+		}
+		if err := m.datapath.RemoveNATRule(r); err != nil {
+			log.WithError(err).WithFields(natRuleFields(r)).Warning("Unable to remove egress NAT rule")
+		}
+		delete(m.natRules, r)
+	}
+	for r := range natRules {
+		if _, ok := m.natRules[r]; ok {
+			continue
+		}
+		if err := m.datapath.InstallNATRule(r); err != nil {
+			log.WithError(err).WithFields(natRuleFields(r)).Warning("Unable to install egress NAT rule")
+			continue
+		}
+		m.natRules[r] = struct{}{}
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package egressgateway
+
+import (
+	"net"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	. "gopkg.in/check.v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type EgressGatewaySuite struct{}
+
+var _ = Suite(&EgressGatewaySuite{})
+
+type fakeDatapath struct {
+	entries  map[Entry]struct{}
+	natRules map[NATRule]struct{}
+}
+
+func newFakeDatapath() *fakeDatapath {
+	return &fakeDatapath{
+		entries:  map[Entry]struct{}{},
+		natRules: map[NATRule]struct{}{},
+	}
+}
+
+func (f *fakeDatapath) UpdateEgressEntry(e Entry) error {
+	f.entries[e] = struct{}{}
+	return nil
+}
+
+func (f *fakeDatapath) DeleteEgressEntry(e Entry) error {
+	delete(f.entries, e)
+	return nil
+}
+
+func (f *fakeDatapath) InstallNATRule(r NATRule) error {
+	f.natRules[r] = struct{}{}
+	return nil
+}
+
+func (f *fakeDatapath) RemoveNATRule(r NATRule) error {
+	delete(f.natRules, r)
+	return nil
+}
+
+func newPolicy(c *C, name, app, cidr, gatewayNode, egressIP string) *Policy {
+	es := api.NewESFromLabels()
+	if app != "" {
+		es = api.NewESFromLabels(labels.ParseSelectLabel("k8s:app=" + app))
+	}
+	p, err := ParsePolicy(&v2.CiliumEgressNATPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+		},
+		Spec: v2.CiliumEgressNATPolicySpec{
+			EndpointSelector: es,
+			DestinationCIDRs: []api.CIDR{api.CIDR(cidr)},
+			GatewayNode:      gatewayNode,
+			EgressIP:         egressIP,
+		},
+	})
+	c.Assert(err, IsNil)
+	return p
+}
+
+func newPod(namespace, name, app, nodeName, ip string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{"app": app},
+		},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+		},
+		Status: v1.PodStatus{
+			PodIP: ip,
+		},
+	}
+}
+
+func (s *EgressGatewaySuite) TestParsePolicy(c *C) {
+	valid := &v2.CiliumEgressNATPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "egress",
+		},
+		Spec: v2.CiliumEgressNATPolicySpec{
+			EndpointSelector: api.NewESFromLabels(),
+			DestinationCIDRs: []api.CIDR{"192.168.10.1/24"},
+			GatewayNode:      "node2",
+			EgressIP:         "10.1.0.100",
+		},
+	}
+
+	p, err := ParsePolicy(valid)
+	c.Assert(err, IsNil)
+	c.Assert(p.ID, Equals, PolicyID{Namespace: "default", Name: "egress"})
+	c.Assert(p.DestinationCIDRs[0].String(), Equals, "192.168.10.0/24")
+	c.Assert(p.EgressIP.Equal(net.ParseIP("10.1.0.100")), Equals, true)
+
+	invalid := []func(spec *v2.CiliumEgressNATPolicySpec){
+		func(spec *v2.CiliumEgressNATPolicySpec) { spec.DestinationCIDRs = nil },
+		func(spec *v2.CiliumEgressNATPolicySpec) { spec.DestinationCIDRs = []api.CIDR{"f00d::/64"} },
+		func(spec *v2.CiliumEgressNATPolicySpec) { spec.DestinationCIDRs = []api.CIDR{"foo"} },
+		func(spec *v2.CiliumEgressNATPolicySpec) { spec.GatewayNode = "" },
+		func(spec *v2.CiliumEgressNATPolicySpec) { spec.EgressIP = "f00d::1" },
+		func(spec *v2.CiliumEgressNATPolicySpec) { spec.EgressIP = "" },
+	}
+	for _, mutate := range invalid {
+		cenp := valid.DeepCopy()
+		mutate(&cenp.Spec)
+		_, err := ParsePolicy(cenp)
+		c.Assert(err, Not(IsNil))
+	}
+}
+
+func (s *EgressGatewaySuite) TestManagerSourceNode(c *C) {
+	dp := newFakeDatapath()
+	m := newManager("node1", dp)
+
+	m.OnUpdateNode("node2", net.ParseIP("192.168.1.2"))
+	m.OnAddEgressPolicy(newPolicy(c, "egress", "client", "1.1.1.0/24", "node2", "10.1.0.100"))
+	m.OnUpdatePod(newPod("default", "client", "client", "node1", "10.0.1.5"))
+	m.OnUpdatePod(newPod("default", "remote", "client", "node3", "10.0.3.5"))
+	m.OnUpdatePod(newPod("default", "other", "other", "node1", "10.0.1.6"))
+	m.OnUpdatePod(newPod("foo", "client", "client", "node1", "10.0.1.7"))
+
+	entry := Entry{
+		SourceIP:        "10.0.1.5",
+		DestinationCIDR: "1.1.1.0/24",
+		EgressIP:        "10.1.0.100",
+		TunnelEndpoint:  "192.168.1.2",
+	}
+	c.Assert(dp.entries, DeepEquals, map[Entry]struct{}{entry: {}})
+	c.Assert(dp.natRules, HasLen, 0)
+
+	// The gateway node changes its IP
+	m.OnUpdateNode("node2", net.ParseIP("192.168.1.20"))
+	entry.TunnelEndpoint = "192.168.1.20"
+	c.Assert(dp.entries, DeepEquals, map[Entry]struct{}{entry: {}})
+
+	// The entries are removed when the gateway node is unknown
+	m.OnDeleteNode("node2")
+	c.Assert(dp.entries, HasLen, 0)
+	m.OnUpdateNode("node2", net.ParseIP("192.168.1.20"))
+	c.Assert(dp.entries, HasLen, 1)
+
+	m.OnDeletePod(newPod("default", "client", "", "", ""))
+	c.Assert(dp.entries, HasLen, 0)
+	m.OnUpdatePod(newPod("default", "client", "client", "node1", "10.0.1.5"))
+	c.Assert(dp.entries, HasLen, 1)
+
+	m.OnDeleteEgressPolicy(PolicyID{Namespace: "default", Name: "egress"})
+	c.Assert(dp.entries, HasLen, 0)
+}
+
+func (s *EgressGatewaySuite) TestManagerGatewayNode(c *C) {
+	dp := newFakeDatapath()
+	m := newManager("node2", dp)
+
+	m.OnUpdateNode("node2", net.ParseIP("192.168.1.2"))
+	m.OnAddEgressPolicy(newPolicy(c, "egress", "client", "1.1.1.0/24", "node2", "10.1.0.100"))
+	m.OnUpdatePod(newPod("default", "client", "client", "node1", "10.0.1.5"))
+	m.OnUpdatePod(newPod("default", "local", "client", "node2", "10.0.2.5"))
+	m.OnUpdatePod(newPod("default", "other", "other", "node1", "10.0.1.6"))
+
+	c.Assert(dp.entries, HasLen, 0)
+	c.Assert(dp.natRules, DeepEquals, map[NATRule]struct{}{
+		{SourceIP: "10.0.1.5", DestinationCIDR: "1.1.1.0/24", EgressIP: "10.1.0.100"}: {},
+		{SourceIP: "10.0.2.5", DestinationCIDR: "1.1.1.0/24", EgressIP: "10.1.0.100"}: {},
+	})
+
+	// Rules are reinstalled after the iptables chains have been flushed
+	dp.natRules = map[NATRule]struct{}{}
+	m.ReinstallRules()
+	c.Assert(dp.natRules, HasLen, 2)
+
+	// The pod loses its selected label
+	m.OnUpdatePod(newPod("default", "client", "other", "node1", "10.0.1.5"))
+	c.Assert(dp.natRules, DeepEquals, map[NATRule]struct{}{
+		{SourceIP: "10.0.2.5", DestinationCIDR: "1.1.1.0/24", EgressIP: "10.1.0.100"}: {},
+	})
+}
+
+func (s *EgressGatewaySuite) TestManagerConflict(c *C) {
+	dp := newFakeDatapath()
+	m := newManager("node1", dp)
+
+	m.OnUpdateNode("node2", net.ParseIP("192.168.1.2"))
+	m.OnUpdateNode("node3", net.ParseIP("192.168.1.3"))
+	m.OnUpdatePod(newPod("default", "client", "client", "node1", "10.0.1.5"))
+	m.OnAddEgressPolicy(newPolicy(c, "b", "client", "1.1.1.0/24", "node3", "10.1.0.103"))
+	m.OnAddEgressPolicy(newPolicy(c, "a", "", "1.1.1.0/24", "node2", "10.1.0.102"))
+
+	c.Assert(dp.entries, DeepEquals, map[Entry]struct{}{
+		{SourceIP: "10.0.1.5", DestinationCIDR: "1.1.1.0/24", EgressIP: "10.1.0.102", TunnelEndpoint: "192.168.1.2"}: {},
+	})
+
+	m.OnDeleteEgressPolicy(PolicyID{Namespace: "default", Name: "a"})
+	c.Assert(dp.entries, DeepEquals, map[Entry]struct{}{
+		{SourceIP: "10.0.1.5", DestinationCIDR: "1.1.1.0/24", EgressIP: "10.1.0.103", TunnelEndpoint: "192.168.1.3"}: {},
+	})
+}
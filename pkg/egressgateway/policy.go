@@ -0,0 +1,111 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package egressgateway steers the traffic of the pods selected by
+// CiliumEgressNATPolicies through gateway nodes which masquerade it with a
+// fixed egress IP.
+package egressgateway
+
+import (
+	"fmt"
+	"net"
+
+	k8sConst "github.com/cilium/cilium/pkg/k8s/apis/cilium.io"
+	"github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/policy/api"
+)
+
+var log = logging.DefaultLogger.WithField(logfields.LogSubsys, "egressgateway")
+
+// podNamespaceLabel is the key of the label holding the namespace of a pod
+// in an endpoint selector
+var podNamespaceLabel = labels.LabelSourceK8sKeyPrefix + k8sConst.PodNamespaceLabel
+
+// PolicyID identifies a CiliumEgressNATPolicy
+type PolicyID struct {
+	Namespace string
+	Name      string
+}
+
+// String returns the namespaced name of the policy
+func (id PolicyID) String() string {
+	return id.Namespace + "/" + id.Name
+}
+
+// Policy is the parsed form of a CiliumEgressNATPolicy
+type Policy struct {
+	ID PolicyID
+
+	// EndpointSelector selects the pods subject to the policy. It only
+	// matches pods in the namespace of the policy.
+	EndpointSelector api.EndpointSelector
+
+	// DestinationCIDRs are the destination prefixes of the policy
+	DestinationCIDRs []*net.IPNet
+
+	// GatewayNode is the name of the node masquerading the traffic
+	GatewayNode string
+
+	// EgressIP is the IPv4 address the traffic is masqueraded with
+	EgressIP net.IP
+}
+
+// ParsePolicy validates cenp and returns its parsed form.
+func ParsePolicy(cenp *v2.CiliumEgressNATPolicy) (*Policy, error) {
+	spec := &cenp.Spec
+
+	if spec.EndpointSelector.LabelSelector == nil {
+		return nil, fmt.Errorf("endpointSelector must be set")
+	}
+	es := api.NewESFromK8sLabelSelector("", spec.EndpointSelector.LabelSelector)
+	es.AddMatch(podNamespaceLabel, cenp.Namespace)
+
+	if len(spec.DestinationCIDRs) == 0 {
+		return nil, fmt.Errorf("destinationCIDRs must not be empty")
+	}
+	cidrs := make([]*net.IPNet, 0, len(spec.DestinationCIDRs))
+	for _, c := range spec.DestinationCIDRs {
+		_, cidr, err := net.ParseCIDR(string(c))
+		if err != nil {
+			return nil, fmt.Errorf("invalid destination CIDR %q: %s", c, err)
+		}
+		if cidr.IP.To4() == nil {
+			return nil, fmt.Errorf("destination CIDR %q is not an IPv4 prefix", c)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+
+	if spec.GatewayNode == "" {
+		return nil, fmt.Errorf("gatewayNode must be set")
+	}
+
+	egressIP := net.ParseIP(spec.EgressIP)
+	if egressIP == nil || egressIP.To4() == nil {
+		return nil, fmt.Errorf("invalid egress IP %q: must be an IPv4 address", spec.EgressIP)
+	}
+
+	return &Policy{
+		ID: PolicyID{
+			Namespace: cenp.Namespace,
+			Name:      cenp.Name,
+		},
+		EndpointSelector: es,
+		DestinationCIDRs: cidrs,
+		GatewayNode:      spec.GatewayNode,
+		EgressIP:         egressIP.To4(),
+	}, nil
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/common"
+	"github.com/cilium/cilium/pkg/bandwidth"
 	"github.com/cilium/cilium/pkg/bpf"
 	"github.com/cilium/cilium/pkg/byteorder"
 	"github.com/cilium/cilium/pkg/completion"
@@ -43,7 +44,9 @@ import (
 	"github.com/cilium/cilium/pkg/maps/eppolicymap"
 	"github.com/cilium/cilium/pkg/maps/ipcache"
 	"github.com/cilium/cilium/pkg/maps/lxcmap"
+	"github.com/cilium/cilium/pkg/maps/policyauditmap"
 	"github.com/cilium/cilium/pkg/maps/policymap"
+	"github.com/cilium/cilium/pkg/maps/throttlemap"
 	"github.com/cilium/cilium/pkg/option"
 	"github.com/cilium/cilium/pkg/policy"
 	"github.com/cilium/cilium/pkg/policy/trafficdirection"
@@ -341,6 +344,10 @@ func (e *Endpoint) addNewRedirectsFromMap(owner Owner, m policy.L4PolicyMap, des
 			keysFromFilter := l4.ToKeys(direction, *e.prevIdentityCache, e.desiredPolicy.DeniedIngressIdentities)
 
 			for _, keyFromFilter := range keysFromFilter {
+				// Deny entries take precedence over redirects.
+				if e.desiredPolicy.PolicyMapState.Denies(keyFromFilter) {
+					continue
+				}
 				if oldEntry, ok := e.desiredPolicy.PolicyMapState[keyFromFilter]; ok {
 					updatedDesiredMapState[keyFromFilter] = oldEntry
 				} else {
@@ -493,6 +500,19 @@ func (e *Endpoint) removeOldRedirects(owner Owner, desiredRedirects map[string]b
 // Must be called with endpoint.Mutex not held and endpoint.BuildMutex held.
 // Returns the policy revision number when the regeneration has called, a
 // boolean if the BPF compilation was executed and an error in case of an error.
+// syncEgressBandwidth configures the egress bandwidth limit of the endpoint
+// described by epInfo in the throttle map
+func syncEgressBandwidth(epInfo *epInfoCache) error {
+	if !option.Config.EnableBandwidthManager {
+		return nil
+	}
+	if epInfo.egressBandwidth == 0 {
+		return throttlemap.DeleteEndpoint(epInfo.endpointID)
+	}
+	return throttlemap.Update(epInfo.endpointID, bandwidth.BytesPerSecond(epInfo.egressBandwidth),
+		bandwidth.DropHorizon(epInfo.egressBandwidth))
+}
+
 func (e *Endpoint) regenerateBPF(owner Owner, regenContext *regenerationContext) (revnum uint64, compiled bool, reterr error) {
 	var (
 		err                 error
@@ -551,6 +571,9 @@ func (e *Endpoint) regenerateBPF(owner Owner, regenContext *regenerationContext)
 	stats.mapSync.Start()
 	epErr := eppolicymap.WriteEndpoint(datapathRegenCtxt.epInfoCache.keys, e.PolicyMap.Fd)
 	err = lxcmap.WriteEndpoint(datapathRegenCtxt.epInfoCache)
+	if err == nil {
+		err = syncEgressBandwidth(datapathRegenCtxt.epInfoCache)
+	}
 	stats.mapSync.End(err == nil)
 	if epErr != nil {
 		e.logStatusLocked(BPF, Warning, fmt.Sprintf("Unable to sync EpToPolicy Map continue with Sockmap support: %s", err))
@@ -912,6 +935,15 @@ func (e *Endpoint) DeleteMapsLocked() []error {
 		errors = append(errors, fmt.Errorf("unable to remove endpoint from global policy map: %s", err))
 	}
 
+	// Remove policy audit counters of EP
+	errors = append(errors, policyauditmap.DeleteEndpoint(e.ID)...)
+
+	if option.Config.EnableBandwidthManager {
+		if err := throttlemap.DeleteEndpoint(e.ID); err != nil {
+			errors = append(errors, fmt.Errorf("unable to remove endpoint from throttle map: %s", err))
+		}
+	}
+
 	return errors
 }
 
@@ -1073,8 +1105,9 @@ func (e *Endpoint) syncPolicyMap() error {
 		policyMapKeyToPolicyKey := policy.Key{
 			Identity:         keyHostOrder.Identity,
 			DestPort:         keyHostOrder.DestPort,
+			DestPortWildcard: keyHostOrder.GetDestPortWildcard(),
 			Nexthdr:          keyHostOrder.Nexthdr,
-			TrafficDirection: keyHostOrder.TrafficDirection,
+			TrafficDirection: keyHostOrder.GetDirection(),
 		}
 
 		// If key that is in policy map is not in desired state, just remove it.
@@ -1096,14 +1129,15 @@ func (e *Endpoint) syncPolicyMap() error {
 		if oldEntry, ok := e.realizedPolicy.PolicyMapState[keyToAdd]; !ok || oldEntry != entry {
 
 			// Convert from policy.Key to policymap.Key
-			policyKeyToPolicyMapKey := policymap.PolicyKey{
-				Identity:         keyToAdd.Identity,
-				DestPort:         keyToAdd.DestPort,
-				Nexthdr:          keyToAdd.Nexthdr,
-				TrafficDirection: keyToAdd.TrafficDirection,
-			}
+			policyKeyToPolicyMapKey := policymap.NewKey(keyToAdd.Identity, keyToAdd.DestPort,
+				keyToAdd.DestPortWildcard, keyToAdd.Nexthdr, keyToAdd.TrafficDirection)
 
-			err := e.PolicyMap.AllowKey(policyKeyToPolicyMapKey, entry.ProxyPort)
+			var err error
+			if entry.IsDeny {
+				err = e.PolicyMap.DenyKey(policyKeyToPolicyMapKey)
+			} else {
+				err = e.PolicyMap.AllowKey(policyKeyToPolicyMapKey, entry.ProxyPort)
+			}
 			if err != nil {
 				e.getLogger().WithError(err).Errorf("Failed to add PolicyMap key %s %d", policyKeyToPolicyMapKey.String(), entry.ProxyPort)
 				errors = append(errors, err)
@@ -34,10 +34,15 @@ type epInfoCache struct {
 	keys  []*lxcmap.EndpointKey
 	value *lxcmap.EndpointInfo
 
+	// For throttlemap
+	endpointID      uint16
+	egressBandwidth uint64
+
 	// For datapath.loader.endpoint
 	epdir    string
 	id       string
 	ifName   string
+	isHost   bool
 	endpoint *Endpoint // Used to get the endpoint's logger.
 }
 
@@ -49,7 +54,11 @@ func (e *Endpoint) createEpInfoCache(epdir string) *epInfoCache {
 		epdir:    epdir,
 		id:       e.StringID(),
 		ifName:   e.IfName,
+		isHost:   e.IsHost(),
 		keys:     e.GetBPFKeys(),
+
+		endpointID:      e.ID,
+		egressBandwidth: e.EgressBandwidth,
 	}
 
 	var err error
@@ -67,6 +76,11 @@ func (ep *epInfoCache) InterfaceName() string {
 	return ep.ifName
 }
 
+// IsHost returns true if the endpoint is the host endpoint.
+func (ep *epInfoCache) IsHost() bool {
+	return ep.isHost
+}
+
 // StringID returns the endpoint's ID in a string.
 func (ep *epInfoCache) StringID() string {
 	return ep.id
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"reflect"
 	"runtime"
 	"sort"
 	"strconv"
@@ -43,9 +44,11 @@ import (
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/mac"
 	bpfconfig "github.com/cilium/cilium/pkg/maps/configmap"
+	"github.com/cilium/cilium/pkg/maps/policyauditmap"
 	"github.com/cilium/cilium/pkg/maps/policymap"
 	"github.com/cilium/cilium/pkg/metrics"
 	"github.com/cilium/cilium/pkg/monitor/notifications"
+	"github.com/cilium/cilium/pkg/node"
 	"github.com/cilium/cilium/pkg/option"
 	"github.com/cilium/cilium/pkg/policy"
 	"github.com/cilium/cilium/pkg/policy/trafficdirection"
@@ -208,6 +211,15 @@ type Endpoint struct {
 	// K8sNamespace is the Kubernetes namespace of the endpoint
 	K8sNamespace string
 
+	// EgressBandwidth is the egress bandwidth limit of the endpoint in bits
+	// per second. If 0, the egress bandwidth is not limited.
+	EgressBandwidth uint64
+
+	// visibilityPolicy is the set of ports for which the traffic of the
+	// endpoint is redirected to the proxy for L7 visibility only, nil if
+	// none.
+	visibilityPolicy *policy.VisibilityPolicy
+
 	// policyRevision is the policy revision this endpoint is currently on
 	// to modify this field please use endpoint.setPolicyRevision instead
 	policyRevision uint64
@@ -279,6 +291,10 @@ type Endpoint struct {
 
 	realizedPolicy *policy.EndpointPolicy
 
+	// isHost is true if the endpoint represents the local host, see
+	// NewHostEndpoint()
+	isHost bool
+
 	///////////////////////
 	// DEPRECATED FIELDS //
 	///////////////////////
@@ -513,10 +529,11 @@ func (e *Endpoint) GetModelRLocked() *models.Endpoint {
 					IPV4: e.IPv4.String(),
 					IPV6: e.IPv6.String(),
 				}},
-				InterfaceIndex: int64(e.IfIndex),
-				InterfaceName:  e.IfName,
-				Mac:            e.LXCMAC.String(),
-				HostMac:        e.NodeMAC.String(),
+				InterfaceIndex:  int64(e.IfIndex),
+				InterfaceName:   e.IfName,
+				Mac:             e.LXCMAC.String(),
+				HostMac:         e.NodeMAC.String(),
+				EgressBandwidth: int64(e.EgressBandwidth),
 			},
 			ExternalIdentifiers: &models.EndpointIdentifiers{
 				ContainerID:      e.ContainerID,
@@ -535,6 +552,17 @@ func (e *Endpoint) GetModelRLocked() *models.Endpoint {
 		},
 	}
 
+	// The node IP allows other nodes to locate the endpoint based on its
+	// CiliumEndpoint resource.
+	if hostIP := node.GetExternalIPv4(); hostIP != nil {
+		mdl.Status.Networking.HostAddressing = &models.NodeAddressing{
+			IPV4: &models.NodeAddressingElement{
+				Enabled: option.Config.EnableIPv4,
+				IP:      hostIP.String(),
+			},
+		}
+	}
+
 	return mdl
 }
 
@@ -622,6 +650,83 @@ func (e *Endpoint) GetModel() *models.Endpoint {
 	return e.GetModelRLocked()
 }
 
+// policyPreviewEntries converts the given policy keys into a sorted list of
+// API policy preview entries.
+func policyPreviewEntries(keys []policy.Key) []*models.PolicyPreviewEntry {
+	entries := make([]*models.PolicyPreviewEntry, 0, len(keys))
+	for _, key := range keys {
+		entry := &models.PolicyPreviewEntry{
+			Identity: int64(key.Identity),
+			Port:     int64(key.DestPort),
+		}
+		if key.DestPortWildcard != 0 {
+			entry.EndPort = int64(key.DestPort) + 1<<key.DestPortWildcard - 1
+		}
+		switch trafficdirection.TrafficDirection(key.TrafficDirection) {
+		case trafficdirection.Ingress:
+			entry.Direction = models.PolicyPreviewEntryDirectionIngress
+		case trafficdirection.Egress:
+			entry.Direction = models.PolicyPreviewEntryDirectionEgress
+		}
+		if key.Nexthdr != 0 {
+			entry.Protocol = u8proto.U8proto(key.Nexthdr).String()
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		switch {
+		case entries[i].Direction != entries[j].Direction:
+			return entries[i].Direction > entries[j].Direction
+		case entries[i].Identity != entries[j].Identity:
+			return entries[i].Identity < entries[j].Identity
+		case entries[i].Port != entries[j].Port:
+			return entries[i].Port < entries[j].Port
+		case entries[i].EndPort != entries[j].EndPort:
+			return entries[i].EndPort < entries[j].EndPort
+		}
+		return entries[i].Protocol < entries[j].Protocol
+	})
+
+	return entries
+}
+
+// GetPolicyPreview resolves the policy of the endpoint against the rules in
+// repo and returns which policy entries would be allowed or denied compared
+// to the policy currently realized for the endpoint. The endpoint and its
+// datapath state are not modified. Errors resolving the policy are reported
+// in the returned model.
+//
+// Must be called with repo.Mutex held for reading if repo is shared.
+func (e *Endpoint) GetPolicyPreview(repo *policy.Repository, identityCache cache.IdentityCache) *models.EndpointPolicyPreview {
+	preview := &models.EndpointPolicyPreview{
+		ID: int64(e.ID),
+	}
+
+	if err := e.RLockAlive(); err != nil {
+		preview.Error = err.Error()
+		return preview
+	}
+	defer e.RUnlock()
+
+	if e.SecurityIdentity == nil {
+		preview.Error = "endpoint has no security identity"
+		return preview
+	}
+
+	desired, err := repo.ResolvePolicy(e.ID, e.policySubjectLabels(), e, identityCache)
+	if err != nil {
+		preview.Error = err.Error()
+		return preview
+	}
+
+	allowed, denied := e.realizedPolicy.PolicyMapState.DiffAllowed(desired.PolicyMapState)
+	preview.NewlyAllowed = policyPreviewEntries(allowed)
+	preview.NewlyDenied = policyPreviewEntries(denied)
+
+	return preview
+}
+
 // GetPolicyModel returns the endpoint's policy as an API model.
 //
 // Must be called with e.Mutex locked.
@@ -637,13 +742,14 @@ func (e *Endpoint) GetPolicyModel() *models.EndpointPolicyStatus {
 	realizedIngressIdentities := make([]int64, 0)
 	realizedEgressIdentities := make([]int64, 0)
 
-	for policyMapKey := range e.realizedPolicy.PolicyMapState {
-		if policyMapKey.DestPort != 0 {
+	for policyMapKey, entry := range e.realizedPolicy.PolicyMapState {
+		if policyMapKey.DestPort != 0 || entry.IsDeny {
 			// If the port is non-zero, then the Key no longer only applies
 			// at L3. AllowedIngressIdentities and AllowedEgressIdentities
 			// contain sets of which identities (i.e., label-based L3 only)
 			// are allowed, so anything which contains L4-related policy should
-			// not be added to these sets.
+			// not be added to these sets. Denied identities are not allowed
+			// either.
 			continue
 		}
 		switch trafficdirection.TrafficDirection(policyMapKey.TrafficDirection) {
@@ -659,13 +765,14 @@ func (e *Endpoint) GetPolicyModel() *models.EndpointPolicyStatus {
 	desiredIngressIdentities := make([]int64, 0)
 	desiredEgressIdentities := make([]int64, 0)
 
-	for policyMapKey := range e.desiredPolicy.PolicyMapState {
-		if policyMapKey.DestPort != 0 {
+	for policyMapKey, entry := range e.desiredPolicy.PolicyMapState {
+		if policyMapKey.DestPort != 0 || entry.IsDeny {
 			// If the port is non-zero, then the Key no longer only applies
 			// at L3. AllowedIngressIdentities and AllowedEgressIdentities
 			// contain sets of which identities (i.e., label-based L3 only)
 			// are allowed, so anything which contains L4-related policy should
-			// not be added to these sets.
+			// not be added to these sets. Denied identities are not allowed
+			// either.
 			continue
 		}
 		switch trafficdirection.TrafficDirection(policyMapKey.TrafficDirection) {
@@ -732,6 +839,15 @@ func (e *Endpoint) GetPolicyModel() *models.EndpointPolicyStatus {
 		L4:                       desiredL4Policy.GetModel(),
 		PolicyEnabled:            policyEnabled,
 	}
+	var auditStats *models.PolicyAuditStatistics
+	if e.Options.IsEnabled(option.PolicyAuditMode) {
+		ingressDrops, egressDrops := policyauditmap.GetAuditedDrops(e.ID)
+		auditStats = &models.PolicyAuditStatistics{
+			IngressDrops: int64(ingressDrops),
+			EgressDrops:  int64(egressDrops),
+		}
+	}
+
 	// FIXME GH-3280 Once we start returning revisions Realized should be the
 	// policy implemented in the data path
 	return &models.EndpointPolicyStatus{
@@ -739,6 +855,7 @@ func (e *Endpoint) GetPolicyModel() *models.EndpointPolicyStatus {
 		Realized:            mdl,
 		ProxyPolicyRevision: int64(e.proxyPolicyRevision),
 		ProxyStatistics:     proxyStats,
+		AuditStatistics:     auditStats,
 	}
 }
 
@@ -1005,8 +1122,8 @@ func (e *Endpoint) Allows(id identityPkg.NumericIdentity) bool {
 		TrafficDirection: trafficdirection.Ingress.Uint8(),
 	}
 
-	_, ok := e.desiredPolicy.PolicyMapState[keyToLookup]
-	return ok
+	entry, ok := e.desiredPolicy.PolicyMapState[keyToLookup]
+	return ok && !entry.IsDeny
 }
 
 // String returns endpoint on a JSON format.
@@ -1506,6 +1623,70 @@ func (e *Endpoint) SetK8sPodName(name string) {
 	e.Unlock()
 }
 
+// SetEgressBandwidth modifies the endpoint's egress bandwidth limit in bits
+// per second. A rate of 0 removes the limit. The new limit takes effect with
+// the next regeneration of the endpoint, if the bandwidth manager is enabled.
+func (e *Endpoint) SetEgressBandwidth(rate uint64) {
+	e.UnconditionalLock()
+	e.EgressBandwidth = rate
+	e.Unlock()
+}
+
+// UpdateEgressBandwidth modifies the endpoint's egress bandwidth limit in bits
+// per second and regenerates the endpoint if the limit has changed.
+func (e *Endpoint) UpdateEgressBandwidth(owner Owner, rate uint64) error {
+	if err := e.LockAlive(); err != nil {
+		return err
+	}
+
+	if e.EgressBandwidth == rate {
+		e.Unlock()
+		return nil
+	}
+	e.EgressBandwidth = rate
+
+	reason := "egress bandwidth limit updated"
+	stateTransitionSucceeded := e.SetStateLocked(StateWaitingToRegenerate, reason)
+	e.Unlock()
+
+	if stateTransitionSucceeded {
+		e.Regenerate(owner, &ExternalRegenerationMetadata{Reason: reason})
+	}
+	return nil
+}
+
+// SetVisibilityPolicy modifies the ports for which L7 visibility is requested.
+// The new policy takes effect with the next regeneration of the endpoint.
+func (e *Endpoint) SetVisibilityPolicy(vp *policy.VisibilityPolicy) {
+	e.UnconditionalLock()
+	e.visibilityPolicy = vp
+	e.Unlock()
+}
+
+// UpdateVisibilityPolicy modifies the ports for which L7 visibility is
+// requested and regenerates the endpoint if they changed.
+func (e *Endpoint) UpdateVisibilityPolicy(owner Owner, vp *policy.VisibilityPolicy) error {
+	if err := e.LockAlive(); err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(e.visibilityPolicy, vp) {
+		e.Unlock()
+		return nil
+	}
+	e.visibilityPolicy = vp
+	e.ForcePolicyCompute()
+
+	reason := "visibility policy updated"
+	stateTransitionSucceeded := e.SetStateLocked(StateWaitingToRegenerate, reason)
+	e.Unlock()
+
+	if stateTransitionSucceeded {
+		e.Regenerate(owner, &ExternalRegenerationMetadata{Reason: reason})
+	}
+	return nil
+}
+
 // SetContainerID modifies the endpoint's container ID
 func (e *Endpoint) SetContainerID(id string) {
 	e.UnconditionalLock()
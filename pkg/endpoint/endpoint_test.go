@@ -30,6 +30,7 @@ import (
 	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/policy"
 	"github.com/cilium/cilium/pkg/policy/api"
+	"github.com/cilium/cilium/pkg/policy/trafficdirection"
 	. "gopkg.in/check.v1"
 )
 
@@ -406,6 +407,21 @@ func (s *EndpointSuite) TestProxyID(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *EndpointSuite) TestPolicyPreviewEntries(c *C) {
+	entries := policyPreviewEntries([]policy.Key{
+		{Identity: 300, DestPort: 80, Nexthdr: 6, TrafficDirection: trafficdirection.Egress.Uint8()},
+		{Identity: 200, TrafficDirection: trafficdirection.Ingress.Uint8()},
+		{Identity: 100, DestPort: 53, Nexthdr: 17, TrafficDirection: trafficdirection.Ingress.Uint8()},
+		{Identity: 100, DestPort: 32000, DestPortWildcard: 8, Nexthdr: 6, TrafficDirection: trafficdirection.Ingress.Uint8()},
+	})
+	c.Assert(entries, checker.DeepEquals, []*models.PolicyPreviewEntry{
+		{Direction: models.PolicyPreviewEntryDirectionIngress, Identity: 100, Port: 53, Protocol: "UDP"},
+		{Direction: models.PolicyPreviewEntryDirectionIngress, Identity: 100, Port: 32000, EndPort: 32255, Protocol: "TCP"},
+		{Direction: models.PolicyPreviewEntryDirectionIngress, Identity: 200},
+		{Direction: models.PolicyPreviewEntryDirectionEgress, Identity: 300, Port: 80, Protocol: "TCP"},
+	})
+}
+
 func TestEndpoint_GetK8sPodLabels(t *testing.T) {
 	type fields struct {
 		OpLabels pkgLabels.OpLabels
@@ -0,0 +1,101 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"fmt"
+
+	"github.com/cilium/cilium/api/v1/models"
+	pkgLabels "github.com/cilium/cilium/pkg/labels"
+
+	"github.com/vishvananda/netlink"
+)
+
+// NewHostEndpoint creates the endpoint representing the local host. Its
+// datapath is attached to the native device ifName, where it enforces the
+// rules selecting the node via a NodeSelector on traffic destined to the
+// host. The endpoint has no addresses of its own and the reserved host
+// identity.
+func NewHostEndpoint(ifName string) (*Endpoint, error) {
+	link, err := netlink.LinkByName(ifName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find device %s: %s", ifName, err)
+	}
+
+	mac := link.Attrs().HardwareAddr.String()
+	ep, err := NewEndpointFromChangeModel(&models.EndpointChangeRequest{
+		ContainerName:  "cilium-host",
+		InterfaceName:  ifName,
+		InterfaceIndex: int64(link.Attrs().Index),
+		Mac:            mac,
+		HostMac:        mac,
+		State:          models.EndpointStateWaitingForIdentity,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ep.isHost = true
+
+	return ep, nil
+}
+
+// IsHost returns true if the endpoint is the host endpoint.
+func (e *Endpoint) IsHost() bool {
+	return e.isHost
+}
+
+// UpdateNodeLabels replaces the node labels of the host endpoint and
+// regenerates it if they changed, as the node labels determine which rules
+// select the host endpoint.
+func (e *Endpoint) UpdateNodeLabels(owner Owner, nodeLabels pkgLabels.Labels) error {
+	if nodeLabels == nil {
+		nodeLabels = pkgLabels.Labels{}
+	}
+
+	if err := e.LockAlive(); err != nil {
+		return err
+	}
+
+	if !e.OpLabels.ReplaceInformationLabels(nodeLabels, e.getLogger()) {
+		e.Unlock()
+		return nil
+	}
+
+	reason := "node labels updated"
+	e.ForcePolicyCompute()
+	stateTransitionSucceeded := e.SetStateLocked(StateWaitingToRegenerate, reason)
+	e.Unlock()
+
+	if stateTransitionSucceeded {
+		e.Regenerate(owner, &ExternalRegenerationMetadata{Reason: reason})
+	}
+	return nil
+}
+
+// policySubjectLabels returns the labels which select the rules applying to
+// the endpoint. These are the labels of the security identity, which for the
+// host endpoint are extended with the labels of the node.
+//
+// Must be called with e.Mutex held.
+func (e *Endpoint) policySubjectLabels() pkgLabels.LabelArray {
+	if !e.isHost {
+		return e.SecurityIdentity.LabelArray
+	}
+
+	lbls := pkgLabels.Labels{}
+	lbls.MergeLabels(e.SecurityIdentity.Labels)
+	lbls.MergeLabels(e.OpLabels.OrchestrationInfo)
+	return lbls.LabelArray()
+}
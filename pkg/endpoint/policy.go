@@ -63,6 +63,13 @@ func (e *Endpoint) LookupRedirectPort(l4Filter *policy.L4Filter) uint16 {
 	return e.realizedRedirects[proxyID]
 }
 
+// GetVisibilityPolicy returns the ports of the endpoint for which L7
+// visibility is requested, or nil if none.
+// Must be called with Endpoint.Mutex held.
+func (e *Endpoint) GetVisibilityPolicy() *policy.VisibilityPolicy {
+	return e.visibilityPolicy
+}
+
 // Note that this function assumes that endpoint policy has already been generated!
 // must be called with endpoint.Mutex held for reading
 func (e *Endpoint) updateNetworkPolicy(owner Owner, proxyWaitGroup *completion.WaitGroup) (reterr error, revertFunc revert.RevertFunc) {
@@ -156,7 +163,7 @@ func (e *Endpoint) regeneratePolicy(owner Owner) error {
 	// policy needs to be enforced for either ingress or egress.
 	e.prevIdentityCache = labelsMap
 
-	calculatedPolicy, err := repo.ResolvePolicy(e.ID, e.SecurityIdentity.LabelArray, e, *labelsMap)
+	calculatedPolicy, err := repo.ResolvePolicy(e.ID, e.policySubjectLabels(), e, *labelsMap)
 	if err != nil {
 		return err
 	}
@@ -515,6 +522,12 @@ func (e *Endpoint) runIPIdentitySync(endpointIP addressing.CiliumIP) {
 		return
 	}
 
+	// Without a kvstore, the mapping is propagated via the CiliumEndpoint
+	// resource of the endpoint instead.
+	if !option.Config.KVStoreEnabled() {
+		return
+	}
+
 	addressFamily := endpointIP.GetFamilyString()
 
 	e.controllers.UpdateController(fmt.Sprintf("sync-%s-identity-mapping (%d)", addressFamily, e.ID),
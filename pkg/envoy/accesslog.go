@@ -38,6 +38,20 @@ func ParseURL(scheme, host, path string) *url.URL {
 	return u
 }
 
+// ParseGRPCPath returns the gRPC service and method names of a request with
+// the given headers and path. Empty strings are returned if the request is not
+// a gRPC call.
+func ParseGRPCPath(headers http.Header, path string) (service, method string) {
+	if !strings.HasPrefix(headers.Get("content-type"), "application/grpc") {
+		return "", ""
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
 // getNetHttpHeaders returns the Headers as net.http.Header
 func GetNetHttpHeaders(httpHeaders []*cilium.KeyValue) http.Header {
 	headers := make(http.Header)
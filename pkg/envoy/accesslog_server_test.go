@@ -40,3 +40,23 @@ func (k *AccessLogServerSuite) TestParseURL(c *C) {
 		c.Assert(u.Path, Equals, "/foo")
 	}
 }
+
+func (k *AccessLogServerSuite) TestParseGRPCPath(c *C) {
+	grpcHeaders := GetNetHttpHeaders([]*cilium.KeyValue{
+		{Key: "content-type", Value: "application/grpc+proto"},
+	})
+	service, method := ParseGRPCPath(grpcHeaders, "/helloworld.Greeter/SayHello")
+	c.Assert(service, Equals, "helloworld.Greeter")
+	c.Assert(method, Equals, "SayHello")
+
+	service, method = ParseGRPCPath(grpcHeaders, "/helloworld.Greeter")
+	c.Assert(service, Equals, "")
+	c.Assert(method, Equals, "")
+
+	httpHeaders := GetNetHttpHeaders([]*cilium.KeyValue{
+		{Key: "content-type", Value: "application/json"},
+	})
+	service, method = ParseGRPCPath(httpHeaders, "/helloworld.Greeter/SayHello")
+	c.Assert(service, Equals, "")
+	c.Assert(method, Equals, "")
+}
@@ -384,6 +384,38 @@ func getL7Rule(l7 *api.PortRuleL7) *cilium.L7NetworkPolicyRule {
 	return rule // No ruleRef
 }
 
+// getCassandraRule returns the key-value pair rule consumed by the Cassandra
+// parser of proxylib for the given Cassandra rule.
+func getCassandraRule(c *api.PortRuleCassandra) *cilium.L7NetworkPolicyRule {
+	rule := &cilium.L7NetworkPolicyRule{Rule: make(map[string]string, 2)}
+	if c.QueryAction != "" {
+		rule.Rule["query_action"] = c.QueryAction
+	}
+	if c.QueryTable != "" {
+		rule.Rule["query_table"] = c.QueryTable
+	}
+	return rule
+}
+
+// getMemcachedRule returns the key-value pair rule consumed by the memcached
+// parser of proxylib for the given memcached rule.
+func getMemcachedRule(m *api.PortRuleMemcached) *cilium.L7NetworkPolicyRule {
+	rule := &cilium.L7NetworkPolicyRule{Rule: make(map[string]string, 4)}
+	if m.Command != "" {
+		rule.Rule["command"] = m.Command
+	}
+	if m.KeyExact != "" {
+		rule.Rule["keyExact"] = m.KeyExact
+	}
+	if m.KeyPrefix != "" {
+		rule.Rule["keyPrefix"] = m.KeyPrefix
+	}
+	if m.KeyRegex != "" {
+		rule.Rule["keyRegex"] = m.KeyRegex
+	}
+	return rule
+}
+
 func getHTTPRule(h *api.PortRuleHTTP) (headers []*envoy_api_v2_route.HeaderMatcher, ruleRef string) {
 	// Count the number of header matches we need
 	cnt := len(h.Headers)
@@ -449,6 +481,31 @@ func getHTTPRule(h *api.PortRuleHTTP) (headers []*envoy_api_v2_route.HeaderMatch
 	return
 }
 
+// getGRPCRule returns the HTTP header matchers for the gRPC calls allowed by
+// the given gRPC rule. gRPC calls are HTTP/2 POST requests to the path
+// "/<service>/<method>" with a content type of "application/grpc".
+func getGRPCRule(g *api.PortRuleGRPC) (headers []*envoy_api_v2_route.HeaderMatcher, ruleRef string) {
+	headers = make([]*envoy_api_v2_route.HeaderMatcher, 0, 3)
+	if g.IsPathPrefix() {
+		headers = append(headers, &envoy_api_v2_route.HeaderMatcher{Name: ":path",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: g.Path()}})
+	} else {
+		headers = append(headers, &envoy_api_v2_route.HeaderMatcher{Name: ":path",
+			HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: g.Path()}})
+	}
+	headers = append(headers, &envoy_api_v2_route.HeaderMatcher{Name: ":method",
+		HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: "POST"}})
+	headers = append(headers, &envoy_api_v2_route.HeaderMatcher{Name: "content-type",
+		HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "application/grpc"}})
+	SortHeaderMatchers(headers)
+
+	ruleRef = `GRPCService("` + g.Service + `")`
+	if g.Method != "" {
+		ruleRef += ` && GRPCMethod("` + g.Method + `")`
+	}
+	return
+}
+
 func createBootstrap(filePath string, name, cluster, version string, xdsSock, egressClusterName, ingressClusterName string, adminPath string) {
 	connectTimeout := int64(option.Config.ProxyConnectTimeout) // in seconds
 
@@ -556,12 +613,16 @@ func getPortNetworkPolicyRule(sel api.EndpointSelector, l7Parser policy.L7Parser
 
 	switch l7Parser {
 	case policy.ParserTypeHTTP:
-		if len(l7Rules.HTTP) > 0 { // Just cautious. This should never be false.
-			httpRules := make([]*cilium.HttpNetworkPolicyRule, 0, len(l7Rules.HTTP))
+		if len(l7Rules.HTTP) > 0 || len(l7Rules.GRPC) > 0 { // Just cautious. This should never be false.
+			httpRules := make([]*cilium.HttpNetworkPolicyRule, 0, len(l7Rules.HTTP)+len(l7Rules.GRPC))
 			for _, l7 := range l7Rules.HTTP {
 				headers, _ := getHTTPRule(&l7)
 				httpRules = append(httpRules, &cilium.HttpNetworkPolicyRule{Headers: headers})
 			}
+			for _, l7 := range l7Rules.GRPC {
+				headers, _ := getGRPCRule(&l7)
+				httpRules = append(httpRules, &cilium.HttpNetworkPolicyRule{Headers: headers})
+			}
 			SortHTTPNetworkPolicyRules(httpRules)
 			r.L7 = &cilium.PortNetworkPolicyRule_HttpRules{
 				HttpRules: &cilium.HttpNetworkPolicyRules{
@@ -576,9 +637,17 @@ func getPortNetworkPolicyRule(sel api.EndpointSelector, l7Parser policy.L7Parser
 		// TODO: Support DNS. For now, just ignore any DNS L7 rule.
 
 	default:
-		// Assume unknown parser types use a Key-Value Pair policy
-		if len(l7Rules.L7) > 0 {
-			kvpRules := make([]*cilium.L7NetworkPolicyRule, 0, len(l7Rules.L7))
+		// Assume unknown parser types use a Key-Value Pair policy. Typed
+		// Cassandra and memcached rules are translated to the Key-Value
+		// Pairs understood by the respective proxylib parser.
+		if len(l7Rules.L7) > 0 || len(l7Rules.Cassandra) > 0 || len(l7Rules.Memcached) > 0 {
+			kvpRules := make([]*cilium.L7NetworkPolicyRule, 0, len(l7Rules.L7)+len(l7Rules.Cassandra)+len(l7Rules.Memcached))
+			for _, l7 := range l7Rules.Cassandra {
+				kvpRules = append(kvpRules, getCassandraRule(&l7))
+			}
+			for _, l7 := range l7Rules.Memcached {
+				kvpRules = append(kvpRules, getMemcachedRule(&l7))
+			}
 			for _, l7 := range l7Rules.L7 {
 				kvpRules = append(kvpRules, getL7Rule(&l7))
 			}
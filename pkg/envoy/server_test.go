@@ -331,6 +331,61 @@ func (s *ServerSuite) TestGetHTTPRule(c *C) {
 	c.Assert(obtained, checker.DeepEquals, ExpectedHeaders1)
 }
 
+func (s *ServerSuite) TestGetGRPCRule(c *C) {
+	obtained, _ := getGRPCRule(&api.PortRuleGRPC{Service: "helloworld.Greeter", Method: "SayHello"})
+	c.Assert(obtained, checker.DeepEquals, []*envoy_api_v2_route.HeaderMatcher{
+		{Name: ":method", HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: "POST"}},
+		{Name: ":path", HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: "/helloworld.Greeter/SayHello"}},
+		{Name: "content-type", HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "application/grpc"}},
+	})
+
+	// Without a method all methods of the service are allowed.
+	obtained, _ = getGRPCRule(&api.PortRuleGRPC{Service: "helloworld.Greeter"})
+	c.Assert(obtained, checker.DeepEquals, []*envoy_api_v2_route.HeaderMatcher{
+		{Name: ":method", HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_ExactMatch{ExactMatch: "POST"}},
+		{Name: ":path", HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "/helloworld.Greeter/"}},
+		{Name: "content-type", HeaderMatchSpecifier: &envoy_api_v2_route.HeaderMatcher_PrefixMatch{PrefixMatch: "application/grpc"}},
+	})
+}
+
+func (s *ServerSuite) TestGetPortNetworkPolicyRuleKeyValuePairs(c *C) {
+	obtained := getPortNetworkPolicyRule(EndpointSelector1, policy.ParserTypeCassandra, api.L7Rules{
+		Cassandra: []api.PortRuleCassandra{
+			{QueryAction: "select", QueryTable: "deathstar.*"},
+			{QueryAction: "insert"},
+		},
+	}, IdentityCache, DeniedIdentitiesNone)
+	c.Assert(obtained, checker.DeepEquals, &cilium.PortNetworkPolicyRule{
+		RemotePolicies: []uint64{1001, 1002},
+		L7Proto:        "cassandra",
+		L7: &cilium.PortNetworkPolicyRule_L7Rules{
+			L7Rules: &cilium.L7NetworkPolicyRules{
+				L7Rules: []*cilium.L7NetworkPolicyRule{
+					{Rule: map[string]string{"query_action": "select", "query_table": "deathstar.*"}},
+					{Rule: map[string]string{"query_action": "insert"}},
+				},
+			},
+		},
+	})
+
+	obtained = getPortNetworkPolicyRule(EndpointSelector1, policy.ParserTypeMemcached, api.L7Rules{
+		Memcached: []api.PortRuleMemcached{
+			{Command: "get", KeyPrefix: "alliance/"},
+		},
+	}, IdentityCache, DeniedIdentitiesNone)
+	c.Assert(obtained, checker.DeepEquals, &cilium.PortNetworkPolicyRule{
+		RemotePolicies: []uint64{1001, 1002},
+		L7Proto:        "memcache",
+		L7: &cilium.PortNetworkPolicyRule_L7Rules{
+			L7Rules: &cilium.L7NetworkPolicyRules{
+				L7Rules: []*cilium.L7NetworkPolicyRule{
+					{Rule: map[string]string{"command": "get", "keyPrefix": "alliance/"}},
+				},
+			},
+		},
+	})
+}
+
 func (s *ServerSuite) TestGetPortNetworkPolicyRule(c *C) {
 	obtained := getPortNetworkPolicyRule(EndpointSelector1, policy.ParserTypeHTTP, L7Rules1,
 		IdentityCache, DeniedIdentitiesNone)
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -108,6 +109,10 @@ type DNSProxy struct {
 	// To insert a wildcard ".", use .{1} to indicate a single wildcard character.
 	allowed *regexpmap.RegexpMap
 
+	// restrictions holds the parsed Restriction of allowed patterns with a
+	// restriction, keyed by Restriction.key(), and counts their references.
+	restrictions map[string]*restrictionEntry
+
 	// rejectReply is the OPCode send from the DNS-proxy to the endpoint if the
 	// DNS request is invalid
 	rejectReply int
@@ -119,7 +124,67 @@ type LookupEndpointIDByIPFunc func(ip net.IP) (endpointID string, err error)
 
 // NotifyOnDNSMsgFunc handles propagating DNS response data
 // See DNSProxy.LookupEndpointIDByIP for usage.
-type NotifyOnDNSMsgFunc func(lookupTime time.Time, srcAddr, dstAddr string, msg *dns.Msg, protocol string, allowed bool, proxyErr error) error
+// rejectedIPs are the IPs which were removed from a response because they are
+// not allowed by the ResponseCIDRs of the matching rules.
+type NotifyOnDNSMsgFunc func(lookupTime time.Time, srcAddr, dstAddr string, msg *dns.Msg, protocol string, allowed bool, rejectedIPs []net.IP, proxyErr error) error
+
+// Restriction limits the lookups allowed by a pattern.
+type Restriction struct {
+	// QueryTypes are the allowed query types. All query types are allowed
+	// when empty.
+	QueryTypes []uint16
+
+	// ResponseCIDRs are the prefixes IPs in A and AAAA records of responses
+	// must be contained in. Other records are removed from the response. All
+	// IPs are allowed when empty.
+	ResponseCIDRs []*net.IPNet
+}
+
+// key returns a canonical representation of r, or "" if r does not restrict
+// anything.
+func (r *Restriction) key() string {
+	if len(r.QueryTypes) == 0 && len(r.ResponseCIDRs) == 0 {
+		return ""
+	}
+
+	qtypes := make([]string, 0, len(r.QueryTypes))
+	for _, qtype := range r.QueryTypes {
+		qtypes = append(qtypes, strconv.Itoa(int(qtype)))
+	}
+	cidrs := make([]string, 0, len(r.ResponseCIDRs))
+	for _, cidr := range r.ResponseCIDRs {
+		cidrs = append(cidrs, cidr.String())
+	}
+	sort.Strings(qtypes)
+	sort.Strings(cidrs)
+
+	return strings.Join(qtypes, ",") + ";" + strings.Join(cidrs, ",")
+}
+
+// allowsQueryType returns true if qtype is allowed by r
+func (r *Restriction) allowsQueryType(qtype uint16) bool {
+	if len(r.QueryTypes) == 0 {
+		return true
+	}
+	for _, t := range r.QueryTypes {
+		if t == qtype {
+			return true
+		}
+	}
+	return false
+}
+
+type restrictionEntry struct {
+	Restriction
+	refcount int
+}
+
+// AllowedPattern is a regexp of DNS names allowed to be looked up, optionally
+// restricted in the query types and response IPs.
+type AllowedPattern struct {
+	Pattern string
+	Restriction
+}
 
 // StartDNSProxy starts a proxy used for DNS L7 redirects that listens on
 // address and port.
@@ -146,6 +211,7 @@ func StartDNSProxy(address string, port uint16, lookupEPFunc LookupEndpointIDByI
 		NotifyOnDNSMsg:        notifyFunc,
 		lookupTargetDNSServer: lookupTargetDNSServer,
 		allowed:               regexpmap.NewRegexpMap(),
+		restrictions:          map[string]*restrictionEntry{},
 		rejectReply:           dns.RcodeRefused,
 	}
 
@@ -214,30 +280,139 @@ func (p *DNSProxy) RemoveAllowed(reStr, endpointID string) {
 // of a hack to ensure atomic updates of rules until we replace the tracking
 // with something better.
 func (p *DNSProxy) UpdateAllowed(reStrToAdd, reStrToRemove []string, endpointID string) {
-	for i := range reStrToAdd {
-		reStrToAdd[i] = prepareNameMatch(reStrToAdd[i])
+	toAdd := make([]AllowedPattern, 0, len(reStrToAdd))
+	for _, reStr := range reStrToAdd {
+		toAdd = append(toAdd, AllowedPattern{Pattern: reStr})
 	}
-	for i := range reStrToRemove {
-		reStrToRemove[i] = prepareNameMatch(reStrToRemove[i])
+	toRemove := make([]AllowedPattern, 0, len(reStrToRemove))
+	for _, reStr := range reStrToRemove {
+		toRemove = append(toRemove, AllowedPattern{Pattern: reStr})
 	}
+	p.UpdateAllowedPatterns(toAdd, toRemove, endpointID)
+}
 
+// UpdateAllowedPatterns is like UpdateAllowed but also takes the restriction
+// of each pattern into account. A pattern must be removed with the same
+// restriction it was added with.
+func (p *DNSProxy) UpdateAllowedPatterns(toAdd, toRemove []AllowedPattern, endpointID string) {
 	p.Lock()
 	defer p.Unlock()
-	for _, reStr := range reStrToRemove {
-		p.allowed.Remove(reStr, endpointID)
+	for _, pattern := range toRemove {
+		key := pattern.key()
+		p.allowed.Remove(prepareNameMatch(pattern.Pattern), allowedValue(endpointID, key))
+		if entry, ok := p.restrictions[key]; ok {
+			entry.refcount--
+			if entry.refcount <= 0 {
+				delete(p.restrictions, key)
+			}
+		}
 	}
-	for _, reStr := range reStrToAdd {
-		p.allowed.Add(reStr, endpointID)
+	for _, pattern := range toAdd {
+		key := pattern.key()
+		p.allowed.Add(prepareNameMatch(pattern.Pattern), allowedValue(endpointID, key))
+		if key == "" {
+			continue
+		}
+		entry, ok := p.restrictions[key]
+		if !ok {
+			entry = &restrictionEntry{Restriction: pattern.Restriction}
+			p.restrictions[key] = entry
+		}
+		entry.refcount++
+	}
+}
+
+// allowedValue returns the value stored in DNSProxy.allowed for a pattern
+// allowed for endpointID with the restriction key.
+func allowedValue(endpointID, key string) string {
+	if key == "" {
+		return endpointID
 	}
+	return endpointID + "|" + key
 }
 
 // CheckAllowed checks name against the rules added to the proxy, and only
 // returns true if this endpointID was added (via AddAllowed) previously.
+// Query type restrictions are not taken into account.
 func (p *DNSProxy) CheckAllowed(name, endpointID string) bool {
+	allowed, _ := p.checkAllowedQuery(name, dns.TypeNone, endpointID)
+	return allowed
+}
+
+// checkAllowedQuery checks name and qtype against the rules added to the
+// proxy for endpointID. dns.TypeNone skips the query type check. If the query
+// is allowed, the returned CIDRs are the prefixes which IPs in the response
+// must be contained in, nil if all IPs are allowed.
+func (p *DNSProxy) checkAllowedQuery(name string, qtype uint16, endpointID string) (allowed bool, responseCIDRs []*net.IPNet) {
 	name = strings.ToLower(name)
 	p.Lock()
 	defer p.Unlock()
-	return p.allowed.LookupContainsValue(name, endpointID)
+
+	// All rules allowing the query contribute their response CIDRs. A rule
+	// without response CIDRs lifts the response filtering altogether.
+	restricted := true
+	for _, value := range p.allowed.LookupValues(name) {
+		var r *Restriction
+		switch {
+		case value == endpointID:
+			r = &Restriction{}
+		case strings.HasPrefix(value, endpointID+"|"):
+			entry, ok := p.restrictions[strings.TrimPrefix(value, endpointID+"|")]
+			if !ok {
+				continue
+			}
+			r = &entry.Restriction
+		default:
+			continue
+		}
+
+		if qtype != dns.TypeNone && !r.allowsQueryType(qtype) {
+			continue
+		}
+		allowed = true
+		if len(r.ResponseCIDRs) == 0 {
+			restricted = false
+		} else {
+			responseCIDRs = append(responseCIDRs, r.ResponseCIDRs...)
+		}
+	}
+
+	if !allowed || !restricted {
+		return allowed, nil
+	}
+	return true, responseCIDRs
+}
+
+// filterResponseIPs removes all A and AAAA records from msg whose IP is not
+// contained in any of cidrs and returns the removed IPs.
+func filterResponseIPs(msg *dns.Msg, cidrs []*net.IPNet) (rejectedIPs []net.IP) {
+	allowed := func(ip net.IP) bool {
+		for _, cidr := range cidrs {
+			if cidr.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	answers := msg.Answer[:0]
+	for _, rr := range msg.Answer {
+		var ip net.IP
+		switch rr := rr.(type) {
+		case *dns.A:
+			ip = rr.A
+		case *dns.AAAA:
+			ip = rr.AAAA
+		}
+		if ip != nil && !allowed(ip) {
+			rejectedIPs = append(rejectedIPs, ip)
+			continue
+		}
+		answers = append(answers, rr)
+	}
+	msg.Answer = answers
+
+	return rejectedIPs
 }
 
 // ServeDNS handles individual DNS requests forwarded to the proxy, and meets
@@ -245,26 +420,30 @@ func (p *DNSProxy) CheckAllowed(name, endpointID string) bool {
 // It will:
 //  - Look up the endpoint that sent the request by IP, via LookupEndpointIDByIP.
 //  - Check that the endpoint ID is in the set of values associated with the
-//  DNS query (lowercased). If not, the request is dropped.
+//  DNS query (lowercased) and query type. If not, the request is dropped.
 //  - The allowed request is forwarded to the originally intended DNS server IP
+//  - A and AAAA records outside of the allowed response CIDRs are removed from
+//  the response.
 //  - The response is shared via NotifyOnDNSMsg (this will go to a
 //  fqdn/RuleGen instance).
 //  - Write the response to the endpoint.
 func (p *DNSProxy) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
 	requestID := request.Id // keep the original request ID
 	qname := string(request.Question[0].Name)
+	qtype := request.Question[0].Qtype
 	protocol := w.LocalAddr().Network()
 	endpointAddr := w.RemoteAddr().String()
 	scopedLog := log.WithFields(logrus.Fields{
-		logfields.DNSName: qname,
-		logfields.IPAddr:  w.RemoteAddr()})
+		logfields.DNSName:      qname,
+		logfields.DNSQueryType: dns.TypeToString[qtype],
+		logfields.IPAddr:       w.RemoteAddr()})
 
 	scopedLog.Debug("Handling DNS query from endpoint")
 
 	endpointIPStr, _, err := net.SplitHostPort(w.RemoteAddr().String())
 	if err != nil {
 		scopedLog.WithError(err).Error("cannot extract endpoint IP from DNS request")
-		p.NotifyOnDNSMsg(time.Now(), endpointAddr, "", request, protocol, false,
+		p.NotifyOnDNSMsg(time.Now(), endpointAddr, "", request, protocol, false, nil,
 			fmt.Errorf("Cannot extract endpoint IP from DNS request: %s", err))
 		p.sendRefused(scopedLog, w, request)
 		return
@@ -272,7 +451,7 @@ func (p *DNSProxy) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
 	endpointID, err := p.LookupEndpointIDByIP(net.ParseIP(endpointIPStr))
 	if err != nil {
 		scopedLog.WithError(err).Error("cannot extract endpoint ID from DNS request")
-		p.NotifyOnDNSMsg(time.Now(), endpointAddr, "", request, protocol, false,
+		p.NotifyOnDNSMsg(time.Now(), endpointAddr, "", request, protocol, false, nil,
 			fmt.Errorf("Cannot extract endpoint ID from DNS request: %s", err))
 		p.sendRefused(scopedLog, w, request)
 		return
@@ -282,7 +461,7 @@ func (p *DNSProxy) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
 	targetServerAddr, err := p.lookupTargetDNSServer(w)
 	if err != nil {
 		scopedLog.WithError(err).Error("Cannot extract target server address to forward DNS request to")
-		p.NotifyOnDNSMsg(time.Now(), endpointAddr, targetServerAddr, request, protocol, false,
+		p.NotifyOnDNSMsg(time.Now(), endpointAddr, targetServerAddr, request, protocol, false, nil,
 			fmt.Errorf("Cannot extract target server address to forward DNS request to: %s", err))
 		p.sendRefused(scopedLog, w, request)
 		return
@@ -294,15 +473,16 @@ func (p *DNSProxy) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
 	// Note: The cache doesn't know about the source of the DNS data (yet) and so
 	// it won't enforce any separation between results from different endpoints.
 	// This isn't ideal but we are trusting the DNS responses anyway.
-	if !p.CheckAllowed(qname, endpointID) {
+	allowed, responseCIDRs := p.checkAllowedQuery(qname, qtype, endpointID)
+	if !allowed {
 		scopedLog.Debug("Rejecting DNS query from endpoint due to policy")
 		err = p.sendRefused(scopedLog, w, request)
-		p.NotifyOnDNSMsg(time.Now(), endpointAddr, targetServerAddr, request, protocol, false, err)
+		p.NotifyOnDNSMsg(time.Now(), endpointAddr, targetServerAddr, request, protocol, false, nil, err)
 		return
 	}
 
 	scopedLog.Debug("Forwarding DNS request for a name that is allowed")
-	p.NotifyOnDNSMsg(time.Now(), endpointAddr, targetServerAddr, request, protocol, true, nil)
+	p.NotifyOnDNSMsg(time.Now(), endpointAddr, targetServerAddr, request, protocol, true, nil, nil)
 
 	// Keep the same L4 protocol. This handles DNS re-requests over TCP, for
 	// requests that were too large for UDP.
@@ -314,7 +494,7 @@ func (p *DNSProxy) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
 		client = p.TCPClient
 	default:
 		scopedLog.Error("Cannot parse DNS proxy client network to select forward client")
-		p.NotifyOnDNSMsg(time.Now(), endpointAddr, targetServerAddr, request, protocol, false,
+		p.NotifyOnDNSMsg(time.Now(), endpointAddr, targetServerAddr, request, protocol, false, nil,
 			fmt.Errorf("Cannot parse DNS proxy client network to select forward client: %s", err))
 		p.sendRefused(scopedLog, w, request)
 		return
@@ -324,14 +504,25 @@ func (p *DNSProxy) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
 	response, _, err := client.Exchange(request, targetServerAddr)
 	if err != nil {
 		scopedLog.WithError(err).Error("Cannot forward proxied DNS lookup")
-		p.NotifyOnDNSMsg(time.Now(), endpointAddr, targetServerAddr, request, protocol, false,
+		p.NotifyOnDNSMsg(time.Now(), endpointAddr, targetServerAddr, request, protocol, false, nil,
 			fmt.Errorf("Cannot forward proxied DNS lookup: %s", err))
 		p.sendRefused(scopedLog, w, request)
 		return
 	}
 
 	scopedLog.WithField(logfields.Response, response).Debug("Received DNS response to proxied lookup")
-	p.NotifyOnDNSMsg(time.Now(), targetServerAddr, endpointAddr, response, protocol, true, nil)
+
+	// Remove records pointing to IPs not allowed by policy before the
+	// response is shared, so that they neither reach the endpoint nor the
+	// DNS cache used for toFQDNs rules.
+	var rejectedIPs []net.IP
+	if responseCIDRs != nil {
+		rejectedIPs = filterResponseIPs(response, responseCIDRs)
+		if len(rejectedIPs) > 0 {
+			scopedLog.WithField("rejectedIPs", rejectedIPs).Debug("Removed DNS response records due to policy")
+		}
+	}
+	p.NotifyOnDNSMsg(time.Now(), targetServerAddr, endpointAddr, response, protocol, true, rejectedIPs, nil)
 
 	scopedLog.Debug("Responding to original DNS query")
 	// restore the ID to the one in the inital request so it matches what the requester expects.
@@ -339,7 +530,7 @@ func (p *DNSProxy) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
 	err = w.WriteMsg(response)
 	if err != nil {
 		scopedLog.WithError(err).Error("Cannot forward proxied DNS response")
-		p.NotifyOnDNSMsg(time.Now(), targetServerAddr, endpointAddr, response, protocol, true,
+		p.NotifyOnDNSMsg(time.Now(), targetServerAddr, endpointAddr, response, protocol, true, rejectedIPs,
 			fmt.Errorf("Cannot forward proxied DNS response: %s", err))
 	}
 }
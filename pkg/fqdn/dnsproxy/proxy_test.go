@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cilium/cilium/pkg/checker"
 	"github.com/cilium/cilium/pkg/fqdn/regexpmap"
 	"github.com/cilium/cilium/pkg/option"
 
@@ -84,7 +85,7 @@ func (s *DNSProxyTestSuite) SetUpSuite(c *C) {
 		func(ip net.IP) (endpointID string, err error) {
 			return "endpoint1", nil
 		},
-		func(lookupTime time.Time, srcAddr, dstAddr string, msg *dns.Msg, protocol string, allowed bool, proxyErr error) error {
+		func(lookupTime time.Time, srcAddr, dstAddr string, msg *dns.Msg, protocol string, allowed bool, rejectedIPs []net.IP, proxyErr error) error {
 			return nil
 		})
 	c.Assert(err, IsNil, Commentf("error starting DNS Proxy"))
@@ -103,6 +104,7 @@ func (s *DNSProxyTestSuite) SetUpSuite(c *C) {
 
 func (s *DNSProxyTestSuite) TearDownTest(c *C) {
 	s.proxy.allowed = regexpmap.NewRegexpMap()
+	s.proxy.restrictions = map[string]*restrictionEntry{}
 }
 
 func (s *DNSProxyTestSuite) TearDownSuite(c *C) {
@@ -198,6 +200,92 @@ func (s *DNSProxyTestSuite) TestRespondMixedCaseInResponse(c *C) {
 	c.Assert(response.Answer[0].String(), Equals, "ciliuM.io.\t60\tIN\tA\t1.1.1.1", Commentf("Proxy returned incorrect RRs"))
 }
 
+func (s *DNSProxyTestSuite) TestRejectQueryType(c *C) {
+	s.proxy.UpdateAllowedPatterns([]AllowedPattern{{
+		Pattern:     "c[il]{3,3}um[.]io[.]",
+		Restriction: Restriction{QueryTypes: []uint16{dns.TypeAAAA}},
+	}}, nil, "endpoint1")
+	request := new(dns.Msg)
+	request.SetQuestion("cilium.io.", dns.TypeA)
+	response, _, err := s.dnsTCPClient.Exchange(request, s.proxy.TCPServer.Listener.Addr().String())
+	c.Assert(err, IsNil, Commentf("DNS request from test client returned error when it should be rejected"))
+	c.Assert(response.Rcode, Not(Equals), dns.RcodeSuccess, Commentf("DNS request with a query type not allowed was not rejected"))
+}
+
+func (s *DNSProxyTestSuite) TestAcceptQueryType(c *C) {
+	s.proxy.UpdateAllowedPatterns([]AllowedPattern{{
+		Pattern:     "c[il]{3,3}um[.]io[.]",
+		Restriction: Restriction{QueryTypes: []uint16{dns.TypeA}},
+	}}, nil, "endpoint1")
+	request := new(dns.Msg)
+	request.SetQuestion("cilium.io.", dns.TypeA)
+	response, _, err := s.dnsTCPClient.Exchange(request, s.proxy.TCPServer.Listener.Addr().String())
+	c.Assert(err, IsNil, Commentf("DNS request from test client failed when it should succeed"))
+	c.Assert(response.Rcode, Equals, dns.RcodeSuccess)
+	c.Assert(len(response.Answer), Equals, 1, Commentf("Proxy returned incorrect number of answer RRs", response.Answer))
+}
+
+func (s *DNSProxyTestSuite) TestFilterResponseCIDRs(c *C) {
+	_, allowed, _ := net.ParseCIDR("1.1.0.0/16")
+	_, other, _ := net.ParseCIDR("10.0.0.0/8")
+
+	// The only record of the response is outside of the allowed CIDR
+	s.proxy.UpdateAllowedPatterns([]AllowedPattern{{
+		Pattern:     "c[il]{3,3}um[.]io[.]",
+		Restriction: Restriction{ResponseCIDRs: []*net.IPNet{other}},
+	}}, nil, "endpoint1")
+	request := new(dns.Msg)
+	request.SetQuestion("cilium.io.", dns.TypeA)
+	response, _, err := s.dnsTCPClient.Exchange(request, s.proxy.TCPServer.Listener.Addr().String())
+	c.Assert(err, IsNil, Commentf("DNS request from test client failed when it should succeed"))
+	c.Assert(len(response.Answer), Equals, 0, Commentf("Proxy did not remove RRs outside of the allowed CIDRs", response.Answer))
+
+	// A second rule allows the IP
+	s.proxy.UpdateAllowedPatterns([]AllowedPattern{{
+		Pattern:     "c[il]{3,3}um[.]io[.]",
+		Restriction: Restriction{ResponseCIDRs: []*net.IPNet{allowed}},
+	}}, nil, "endpoint1")
+	response, _, err = s.dnsTCPClient.Exchange(request, s.proxy.TCPServer.Listener.Addr().String())
+	c.Assert(err, IsNil, Commentf("DNS request from test client failed when it should succeed"))
+	c.Assert(len(response.Answer), Equals, 1, Commentf("Proxy returned incorrect number of answer RRs", response.Answer))
+}
+
+func (s *DNSProxyTestSuite) TestCheckAllowedQueryRestrictions(c *C) {
+	_, cidr, _ := net.ParseCIDR("1.1.0.0/16")
+	restricted := AllowedPattern{
+		Pattern: "c[il]{3,3}um[.]io[.]",
+		Restriction: Restriction{
+			QueryTypes:    []uint16{dns.TypeA, dns.TypeAAAA},
+			ResponseCIDRs: []*net.IPNet{cidr},
+		},
+	}
+	s.proxy.UpdateAllowedPatterns([]AllowedPattern{restricted}, nil, "endpoint1")
+
+	allowed, cidrs := s.proxy.checkAllowedQuery("cilium.io.", dns.TypeA, "endpoint1")
+	c.Assert(allowed, Equals, true)
+	c.Assert(cidrs, checker.DeepEquals, []*net.IPNet{cidr})
+
+	allowed, _ = s.proxy.checkAllowedQuery("cilium.io.", dns.TypeTXT, "endpoint1")
+	c.Assert(allowed, Equals, false)
+	allowed, _ = s.proxy.checkAllowedQuery("cilium.io.", dns.TypeA, "endpoint2")
+	c.Assert(allowed, Equals, false)
+	c.Assert(s.proxy.CheckAllowed("cilium.io.", "endpoint1"), Equals, true)
+
+	// An unrestricted pattern lifts the response filtering
+	s.proxy.AddAllowed("c[il]{3,3}um[.]io[.]", "endpoint1")
+	allowed, cidrs = s.proxy.checkAllowedQuery("cilium.io.", dns.TypeTXT, "endpoint1")
+	c.Assert(allowed, Equals, true)
+	c.Assert(cidrs, IsNil)
+	allowed, cidrs = s.proxy.checkAllowedQuery("cilium.io.", dns.TypeA, "endpoint1")
+	c.Assert(allowed, Equals, true)
+	c.Assert(cidrs, IsNil)
+
+	s.proxy.UpdateAllowedPatterns(nil, []AllowedPattern{restricted}, "endpoint1")
+	c.Assert(s.proxy.restrictions, HasLen, 0)
+	allowed, _ = s.proxy.checkAllowedQuery("cilium.io.", dns.TypeTXT, "endpoint1")
+	c.Assert(allowed, Equals, true)
+}
+
 func (s *DNSProxyTestSuite) TestCheckAllowedMixedCaseChecked(c *C) {
 	s.proxy.AddAllowed("c[il]{3,3}um[.]io[.]", "endpoint1")
 
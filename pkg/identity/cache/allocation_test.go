@@ -108,7 +108,7 @@ func (d dummyOwner) GetNodeSuffix() string {
 }
 
 func (ias *IdentityAllocatorSuite) TestGetIdentityCache(c *C) {
-	InitIdentityAllocator(dummyOwner{})
+	InitIdentityAllocator(dummyOwner{}, nil)
 	defer Close()
 	defer IdentityAllocator.DeleteAllKeys()
 
@@ -122,7 +122,7 @@ func (ias *IdentityAllocatorSuite) TestAllocator(c *C) {
 	lbls2 := labels.NewLabelsFromSortedList("id=bar;user=anna")
 	lbls3 := labels.NewLabelsFromSortedList("id=bar;user=susan")
 
-	InitIdentityAllocator(dummyOwner{})
+	InitIdentityAllocator(dummyOwner{}, nil)
 	defer Close()
 	defer IdentityAllocator.DeleteAllKeys()
 
@@ -184,7 +184,7 @@ func (ias *IdentityAllocatorSuite) TestAllocator(c *C) {
 func (ias *IdentityAllocatorSuite) TestLocalAllocationr(c *C) {
 	lbls1 := labels.NewLabelsFromSortedList("cidr:192.0.2.3/32")
 
-	InitIdentityAllocator(dummyOwner{})
+	InitIdentityAllocator(dummyOwner{}, nil)
 	defer Close()
 	defer IdentityAllocator.DeleteAllKeys()
 
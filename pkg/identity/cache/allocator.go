@@ -20,11 +20,14 @@ import (
 
 	"github.com/cilium/cilium/pkg/identity"
 	"github.com/cilium/cilium/pkg/idpool"
+	clientset "github.com/cilium/cilium/pkg/k8s/client/clientset/versioned"
+	"github.com/cilium/cilium/pkg/k8s/identitybackend"
 	"github.com/cilium/cilium/pkg/kvstore"
 	"github.com/cilium/cilium/pkg/kvstore/allocator"
 	"github.com/cilium/cilium/pkg/labels"
 	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/node"
 	"github.com/cilium/cilium/pkg/option"
 
 	"github.com/sirupsen/logrus"
@@ -50,6 +53,16 @@ func (gi globalIdentity) PutKey(v string) (allocator.AllocatorKey, error) {
 	return globalIdentity{labels.NewLabelsFromSortedList(string(b))}, nil
 }
 
+// GetAsMap() encodes a globalIdentity as a map of "source:key" to value
+func (gi globalIdentity) GetAsMap() map[string]string {
+	return gi.StringMap()
+}
+
+// PutKeyFromMap() decodes a globalIdentity from its map representation
+func (gi globalIdentity) PutKeyFromMap(m map[string]string) allocator.AllocatorKey {
+	return globalIdentity{labels.Map2Labels(m, "")}
+}
+
 var (
 	// IdentityAllocator is an allocator for security identities from the
 	// kvstore.
@@ -79,8 +92,10 @@ type IdentityAllocatorOwner interface {
 }
 
 // InitIdentityAllocator creates the the identity allocator. Only the first
-// invocation of this function will have an effect.
-func InitIdentityAllocator(owner IdentityAllocatorOwner) {
+// invocation of this function will have an effect. The identities are
+// allocated in the kvstore or, in CRD identity allocation mode, as
+// CiliumIdentity resources via client.
+func InitIdentityAllocator(owner IdentityAllocatorOwner, client clientset.Interface) {
 	setupMutex.Lock()
 	defer setupMutex.Unlock()
 
@@ -101,12 +116,24 @@ func InitIdentityAllocator(owner IdentityAllocatorOwner) {
 	// initial cache
 	watcher.watch(owner, events)
 
-	a, err := allocator.NewAllocator(IdentitiesPath, globalIdentity{},
+	opts := []allocator.AllocatorOption{
 		allocator.WithMax(maxID), allocator.WithMin(minID),
 		allocator.WithSuffix(owner.GetNodeSuffix()),
 		allocator.WithEvents(events),
 		allocator.WithMasterKeyProtection(),
-		allocator.WithPrefixMask(idpool.ID(option.Config.ClusterID<<identity.ClusterIDShift)))
+		allocator.WithPrefixMask(idpool.ID(option.Config.ClusterID << identity.ClusterIDShift)),
+	}
+
+	if option.Config.IdentityAllocationMode == option.IdentityAllocationModeCRD {
+		log.Info("Allocating identities as CiliumIdentity resources")
+		backend, err := identitybackend.NewCRDBackend(node.GetName(), client, globalIdentity{})
+		if err != nil {
+			log.WithError(err).Fatal("Unable to initialize CiliumIdentity backend")
+		}
+		opts = append(opts, allocator.WithBackend(backend))
+	}
+
+	a, err := allocator.NewAllocator(IdentitiesPath, globalIdentity{}, opts...)
 	if err != nil {
 		log.WithError(err).Fatal("Unable to initialize identity allocator")
 	}
@@ -24,6 +24,7 @@ import (
 	"github.com/cilium/cilium/pkg/node"
 
 	k8sAPI "k8s.io/kubernetes/pkg/apis/core"
+	"k8s.io/kubernetes/pkg/registry/core/service/ipallocator"
 )
 
 const (
@@ -31,6 +32,10 @@ const (
 	metricRelease  = "release"
 	familyIPv4     = "ipv4"
 	familyIPv6     = "ipv6"
+
+	// ipv4LowWatermark is the number of available IPv4 addresses below
+	// which additional IPv4 allocation ranges are requested
+	ipv4LowWatermark = 16
 )
 
 // Error definitions
@@ -42,6 +47,84 @@ var (
 	ErrIPv6Disabled = errors.New("IPv6 allocation disabled")
 )
 
+// ipv4AllocatorFor returns the IPv4 allocator of the range containing ip.
+// The primary allocator is returned if no range contains ip.
+func ipv4AllocatorFor(ip net.IP) *ipallocator.Range {
+	for _, r := range ipamConf.IPv4AuxAllocators {
+		if cidr := r.CIDR(); cidr.Contains(ip) {
+			return r
+		}
+	}
+	return ipamConf.IPv4Allocator
+}
+
+// checkIPv4LowWatermark requests an additional IPv4 allocation range if the
+// number of available IPv4 addresses is below the low watermark
+func checkIPv4LowWatermark() {
+	if ipamConf.ipv4RangeRequester == nil || ipamConf.IPv4Allocator == nil {
+		return
+	}
+
+	free := ipamConf.IPv4Allocator.Free()
+	for _, r := range ipamConf.IPv4AuxAllocators {
+		free += r.Free()
+	}
+
+	if free < ipv4LowWatermark {
+		ipamConf.ipv4RangeRequester(len(ipamConf.IPv4AuxAllocators) + 2)
+	}
+}
+
+// allocateNextIPv4 allocates the next available IPv4 address out of the
+// primary allocation range, falling back to the additional ranges once it is
+// exhausted
+func allocateNextIPv4() (net.IP, error) {
+	defer checkIPv4LowWatermark()
+
+	ip, err := ipamConf.IPv4Allocator.AllocateNext()
+	if err != ipallocator.ErrFull {
+		return ip, err
+	}
+
+	for _, r := range ipamConf.IPv4AuxAllocators {
+		ip, err = r.AllocateNext()
+		if err != ipallocator.ErrFull {
+			return ip, err
+		}
+	}
+
+	return nil, err
+}
+
+// AddIPv4AllocRange adds an additional IPv4 allocation range which is used
+// once the primary IPv4 allocation range is exhausted. It returns false if
+// the range is already in use.
+func AddIPv4AllocRange(cidr *net.IPNet) bool {
+	ipamConf.allocatorMutex.Lock()
+	defer ipamConf.allocatorMutex.Unlock()
+
+	for _, r := range append([]*ipallocator.Range{ipamConf.IPv4Allocator}, ipamConf.IPv4AuxAllocators...) {
+		if r == nil {
+			continue
+		}
+		if existing := r.CIDR(); existing.String() == cidr.String() {
+			return false
+		}
+	}
+
+	ipamConf.IPv4AuxAllocators = append(ipamConf.IPv4AuxAllocators, ipallocator.NewCIDRRange(cidr))
+	return true
+}
+
+// SetIPv4RangeRequester sets the function called to request additional IPv4
+// allocation ranges when the available IPv4 addresses run low
+func SetIPv4RangeRequester(requester IPv4RangeRequester) {
+	ipamConf.allocatorMutex.Lock()
+	ipamConf.ipv4RangeRequester = requester
+	checkIPv4LowWatermark()
+	ipamConf.allocatorMutex.Unlock()
+}
+
 // AllocateIP allocates a IP address.
 func AllocateIP(ip net.IP) error {
 	ipamConf.allocatorMutex.Lock()
@@ -52,9 +135,10 @@ func AllocateIP(ip net.IP) error {
 			return ErrIPv4Disabled
 		}
 
-		if err := ipamConf.IPv4Allocator.Allocate(ip); err != nil {
+		if err := ipv4AllocatorFor(ip).Allocate(ip); err != nil {
 			return err
 		}
+		checkIPv4LowWatermark()
 	} else {
 		family = familyIPv6
 		if ipamConf.IPv6Allocator == nil {
@@ -85,6 +169,9 @@ func AllocateIPString(ipAddr string) error {
 // allocation is limited to the specified address family. If the pool has been
 // drained of addresses, an error will be returned.
 func AllocateNext(family string) (net.IP, net.IP, error) {
+	ipamConf.allocatorMutex.Lock()
+	defer ipamConf.allocatorMutex.Unlock()
+
 	var ipv4, ipv6 net.IP
 
 	if (family == "ipv6" || family == "") && ipamConf.IPv6Allocator != nil {
@@ -98,7 +185,7 @@ func AllocateNext(family string) (net.IP, net.IP, error) {
 	}
 
 	if (family == "ipv4" || family == "") && ipamConf.IPv4Allocator != nil {
-		ipConf, err := ipamConf.IPv4Allocator.AllocateNext()
+		ipConf, err := allocateNextIPv4()
 		if err != nil {
 			return nil, nil, err
 		}
@@ -120,7 +207,7 @@ func ReleaseIP(ip net.IP) error {
 			return ErrIPv4Disabled
 		}
 
-		if err := ipamConf.IPv4Allocator.Release(ip); err != nil {
+		if err := ipv4AllocatorFor(ip).Release(ip); err != nil {
 			return err
 		}
 	} else {
@@ -164,6 +251,11 @@ func Dump() ([]string, []string) {
 			}
 		}
 	}
+	for _, r := range ipamConf.IPv4AuxAllocators {
+		r.ForEach(func(ip net.IP) {
+			allocv4 = append(allocv4, ip.String())
+		})
+	}
 
 	allocv6 := []string{}
 	ralv6 := k8sAPI.RangeAllocation{}
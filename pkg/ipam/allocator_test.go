@@ -43,3 +43,45 @@ func (s *AllocatorSuite) TestAllocatedIPDump(c *C) {
 		c.Assert(net.ParseIP(allocv6[i]), NotNil)
 	}
 }
+
+func (s *AllocatorSuite) TestIPv4AuxAllocRange(c *C) {
+	node.InitDefaultPrefix("")
+	oldRange := node.GetIPv4AllocRange()
+	defer node.SetIPv4AllocRange(oldRange)
+
+	_, primary, _ := net.ParseCIDR("10.200.0.0/29")
+	_, aux, _ := net.ParseCIDR("10.201.0.0/29")
+	node.SetIPv4AllocRange(primary)
+	Init()
+
+	requested := 0
+	SetIPv4RangeRequester(func(ranges int) {
+		requested = ranges
+	})
+	c.Assert(requested, Equals, 2)
+
+	// Drain the primary range
+	for i := 0; i < 6; i++ {
+		ipv4, _, err := AllocateNext("ipv4")
+		c.Assert(err, IsNil)
+		c.Assert(primary.Contains(ipv4), Equals, true)
+	}
+	_, _, err := AllocateNext("ipv4")
+	c.Assert(err, Not(IsNil))
+
+	c.Assert(AddIPv4AllocRange(aux), Equals, true)
+	c.Assert(AddIPv4AllocRange(aux), Equals, false)
+	c.Assert(AddIPv4AllocRange(primary), Equals, false)
+
+	ipv4, _, err := AllocateNext("ipv4")
+	c.Assert(err, IsNil)
+	c.Assert(aux.Contains(ipv4), Equals, true)
+	c.Assert(requested, Equals, 3)
+
+	allocv4, _ := Dump()
+	c.Assert(allocv4, HasLen, 7)
+	c.Assert(allocv4[6], Equals, ipv4.String())
+
+	c.Assert(ReleaseIP(ipv4), IsNil)
+	c.Assert(AllocateIP(ipv4), IsNil)
+}
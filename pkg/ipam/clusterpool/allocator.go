@@ -0,0 +1,234 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clusterpool implements the allocation of per-node pod CIDRs out of
+// cluster-wide CIDR pools as performed by cilium-operator in cluster-pool
+// IPAM mode.
+package clusterpool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+
+	"github.com/cilium/cilium/pkg/lock"
+)
+
+// Error definitions
+var (
+	// ErrPoolExhausted is returned when all pools have been drained of
+	// CIDRs
+	ErrPoolExhausted = errors.New("all CIDR pools have been exhausted")
+
+	// ErrNotInPool is returned when a CIDR is not part of any pool
+	ErrNotInPool = errors.New("CIDR is not part of any pool")
+
+	// ErrAllocated is returned when a CIDR has already been allocated
+	ErrAllocated = errors.New("CIDR has already been allocated")
+)
+
+// maxSubnetBits is the maximum number of bits used to index the subnets of
+// a pool
+const maxSubnetBits = 32
+
+// cidrPool allocates fixed size subnets out of a single CIDR
+type cidrPool struct {
+	cidr     *net.IPNet
+	maskSize int
+	bits     int
+
+	// size is the number of subnets in the pool
+	size uint64
+
+	// next is the index of the next subnet to consider for allocation
+	next uint64
+
+	// allocated is the set of allocated subnet indices
+	allocated map[uint64]struct{}
+}
+
+func newCIDRPool(cidr *net.IPNet, maskSize int) (*cidrPool, error) {
+	ones, bits := cidr.Mask.Size()
+	if maskSize < ones || maskSize > bits {
+		return nil, fmt.Errorf("mask size /%d must be between /%d and /%d for CIDR %s",
+			maskSize, ones, bits, cidr)
+	}
+	if maskSize-ones > maxSubnetBits {
+		return nil, fmt.Errorf("CIDR %s contains more than 2^%d subnets of size /%d",
+			cidr, maxSubnetBits, maskSize)
+	}
+
+	return &cidrPool{
+		cidr:      cidr,
+		maskSize:  maskSize,
+		bits:      bits,
+		size:      uint64(1) << uint(maskSize-ones),
+		allocated: map[uint64]struct{}{},
+	}, nil
+}
+
+// subnet returns the subnet with the given index
+func (p *cidrPool) subnet(index uint64) *net.IPNet {
+	base := big.NewInt(0).SetBytes(p.cidr.IP)
+	offset := big.NewInt(0).SetUint64(index)
+	offset.Lsh(offset, uint(p.bits-p.maskSize))
+	base.Add(base, offset)
+
+	ip := make(net.IP, len(p.cidr.IP))
+	b := base.Bytes()
+	copy(ip[len(ip)-len(b):], b)
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(p.maskSize, p.bits)}
+}
+
+// index returns the index of the given subnet or false if the subnet is not
+// a subnet of the pool
+func (p *cidrPool) index(subnet *net.IPNet) (uint64, bool) {
+	ones, bits := subnet.Mask.Size()
+	if ones != p.maskSize || bits != p.bits || !p.cidr.Contains(subnet.IP) {
+		return 0, false
+	}
+
+	ip := subnet.IP.To16()
+	if p.bits == 8*net.IPv4len {
+		ip = subnet.IP.To4()
+	}
+	offset := big.NewInt(0).Sub(big.NewInt(0).SetBytes(ip), big.NewInt(0).SetBytes(p.cidr.IP))
+	offset.Rsh(offset, uint(p.bits-p.maskSize))
+
+	return offset.Uint64(), true
+}
+
+func (p *cidrPool) allocate() (*net.IPNet, bool) {
+	if uint64(len(p.allocated)) >= p.size {
+		return nil, false
+	}
+
+	for i := uint64(0); i < p.size; i++ {
+		index := (p.next + i) % p.size
+		if _, ok := p.allocated[index]; !ok {
+			p.allocated[index] = struct{}{}
+			p.next = (index + 1) % p.size
+			return p.subnet(index), true
+		}
+	}
+
+	return nil, false
+}
+
+// Allocator allocates fixed size CIDRs out of a list of pools. All pools of
+// an allocator must be of the same address family.
+type Allocator struct {
+	mutex lock.Mutex
+	pools []*cidrPool
+}
+
+// NewAllocator returns an allocator handing out CIDRs with the given mask
+// size out of the given pools. Pools are drained in the order given.
+func NewAllocator(pools []string, maskSize int) (*Allocator, error) {
+	if len(pools) == 0 {
+		return nil, fmt.Errorf("at least one CIDR pool must be specified")
+	}
+
+	a := &Allocator{}
+	var ipv4 bool
+	for i, pool := range pools {
+		_, cidr, err := net.ParseCIDR(pool)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR pool %q: %s", pool, err)
+		}
+
+		isIPv4 := cidr.IP.To4() != nil
+		if i == 0 {
+			ipv4 = isIPv4
+		} else if isIPv4 != ipv4 {
+			return nil, fmt.Errorf("CIDR pool %s does not match the address family of %s", pool, pools[0])
+		}
+
+		for _, p := range a.pools {
+			if p.cidr.Contains(cidr.IP) || cidr.Contains(p.cidr.IP) {
+				return nil, fmt.Errorf("CIDR pool %s overlaps with %s", cidr, p.cidr)
+			}
+		}
+
+		p, err := newCIDRPool(cidr, maskSize)
+		if err != nil {
+			return nil, err
+		}
+		a.pools = append(a.pools, p)
+	}
+
+	return a, nil
+}
+
+// Allocate allocates a CIDR out of the first pool with CIDRs left
+func (a *Allocator) Allocate() (*net.IPNet, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, p := range a.pools {
+		if cidr, ok := p.allocate(); ok {
+			return cidr, nil
+		}
+	}
+
+	return nil, ErrPoolExhausted
+}
+
+// Occupy marks the given CIDR as allocated
+func (a *Allocator) Occupy(cidr *net.IPNet) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, p := range a.pools {
+		if index, ok := p.index(cidr); ok {
+			if _, ok := p.allocated[index]; ok {
+				return ErrAllocated
+			}
+			p.allocated[index] = struct{}{}
+			return nil
+		}
+	}
+
+	return ErrNotInPool
+}
+
+// Release returns the given CIDR to its pool
+func (a *Allocator) Release(cidr *net.IPNet) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, p := range a.pools {
+		if index, ok := p.index(cidr); ok {
+			delete(p.allocated, index)
+			return
+		}
+	}
+}
+
+// Contains returns true if the given CIDR is a subnet of any of the pools
+// with the mask size of the allocator
+func (a *Allocator) Contains(cidr *net.IPNet) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, p := range a.pools {
+		if _, ok := p.index(cidr); ok {
+			return true
+		}
+	}
+
+	return false
+}
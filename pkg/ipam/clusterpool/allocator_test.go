@@ -0,0 +1,185 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package clusterpool
+
+import (
+	"net"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type ClusterPoolSuite struct{}
+
+var _ = Suite(&ClusterPoolSuite{})
+
+func mustParseCIDR(c *C, s string) *net.IPNet {
+	_, cidr, err := net.ParseCIDR(s)
+	c.Assert(err, IsNil)
+	return cidr
+}
+
+func (s *ClusterPoolSuite) TestNewAllocator(c *C) {
+	_, err := NewAllocator(nil, 24)
+	c.Assert(err, Not(IsNil))
+
+	_, err = NewAllocator([]string{"10.0.0.0/16"}, 8)
+	c.Assert(err, Not(IsNil))
+
+	_, err = NewAllocator([]string{"10.0.0.0/16", "f00d::/64"}, 24)
+	c.Assert(err, Not(IsNil))
+
+	_, err = NewAllocator([]string{"10.0.0.0/16", "10.0.1.0/24"}, 24)
+	c.Assert(err, Not(IsNil))
+
+	_, err = NewAllocator([]string{"f00d::/48"}, 96)
+	c.Assert(err, Not(IsNil))
+
+	_, err = NewAllocator([]string{"f00d::/64"}, 96)
+	c.Assert(err, IsNil)
+}
+
+func (s *ClusterPoolSuite) TestAllocate(c *C) {
+	a, err := NewAllocator([]string{"10.0.0.0/23", "10.1.0.0/24"}, 24)
+	c.Assert(err, IsNil)
+
+	cidr, err := a.Allocate()
+	c.Assert(err, IsNil)
+	c.Assert(cidr.String(), Equals, "10.0.0.0/24")
+
+	cidr, err = a.Allocate()
+	c.Assert(err, IsNil)
+	c.Assert(cidr.String(), Equals, "10.0.1.0/24")
+
+	cidr, err = a.Allocate()
+	c.Assert(err, IsNil)
+	c.Assert(cidr.String(), Equals, "10.1.0.0/24")
+
+	_, err = a.Allocate()
+	c.Assert(err, Equals, ErrPoolExhausted)
+
+	a.Release(mustParseCIDR(c, "10.0.1.0/24"))
+	cidr, err = a.Allocate()
+	c.Assert(err, IsNil)
+	c.Assert(cidr.String(), Equals, "10.0.1.0/24")
+}
+
+func (s *ClusterPoolSuite) TestAllocateIPv6(c *C) {
+	a, err := NewAllocator([]string{"f00d::/64"}, 96)
+	c.Assert(err, IsNil)
+
+	cidr, err := a.Allocate()
+	c.Assert(err, IsNil)
+	c.Assert(cidr.String(), Equals, "f00d::/96")
+
+	cidr, err = a.Allocate()
+	c.Assert(err, IsNil)
+	c.Assert(cidr.String(), Equals, "f00d::1:0:0/96")
+}
+
+func (s *ClusterPoolSuite) TestOccupy(c *C) {
+	a, err := NewAllocator([]string{"10.0.0.0/22"}, 24)
+	c.Assert(err, IsNil)
+
+	c.Assert(a.Occupy(mustParseCIDR(c, "10.0.0.0/24")), IsNil)
+	c.Assert(a.Occupy(mustParseCIDR(c, "10.0.0.0/24")), Equals, ErrAllocated)
+	c.Assert(a.Occupy(mustParseCIDR(c, "10.0.0.0/25")), Equals, ErrNotInPool)
+	c.Assert(a.Occupy(mustParseCIDR(c, "10.1.0.0/24")), Equals, ErrNotInPool)
+	c.Assert(a.Occupy(mustParseCIDR(c, "10.0.2.0/24")), IsNil)
+
+	cidr, err := a.Allocate()
+	c.Assert(err, IsNil)
+	c.Assert(cidr.String(), Equals, "10.0.1.0/24")
+
+	cidr, err = a.Allocate()
+	c.Assert(err, IsNil)
+	c.Assert(cidr.String(), Equals, "10.0.3.0/24")
+
+	_, err = a.Allocate()
+	c.Assert(err, Equals, ErrPoolExhausted)
+}
+
+func newCiliumNode(name string, requested int, podCIDRs ...string) *v2.CiliumNode {
+	node := &v2.CiliumNode{}
+	node.Name = name
+	node.Spec.IPAM.PodCIDRs = podCIDRs
+	node.Status.IPAM.RequestedIPv4PodCIDRs = requested
+	return node
+}
+
+func (s *ClusterPoolSuite) TestNodeAllocator(c *C) {
+	v4, err := NewAllocator([]string{"10.0.0.0/22"}, 24)
+	c.Assert(err, IsNil)
+	v6, err := NewAllocator([]string{"f00d::/64"}, 96)
+	c.Assert(err, IsNil)
+	n := NewNodeAllocator(v4, v6)
+
+	// Existing allocations are retained and occupied
+	podCIDRs, changed, err := n.Update(newCiliumNode("node1", 0, "10.0.0.0/24", "f00d::/96"))
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, false)
+	c.Assert(podCIDRs, DeepEquals, []string{"10.0.0.0/24", "f00d::/96"})
+
+	// A new node is allocated one CIDR per address family
+	podCIDRs, changed, err = n.Update(newCiliumNode("node2", 0))
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, true)
+	c.Assert(podCIDRs, DeepEquals, []string{"f00d::1:0:0/96", "10.0.1.0/24"})
+
+	// A CIDR owned by another node is removed
+	podCIDRs, changed, err = n.Update(newCiliumNode("node3", 0, "10.0.0.0/24"))
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, true)
+	c.Assert(podCIDRs, DeepEquals, []string{"f00d::2:0:0/96", "10.0.2.0/24"})
+
+	// The allocation grows on request until the pool is exhausted
+	podCIDRs, changed, err = n.Update(newCiliumNode("node1", 3, "10.0.0.0/24", "f00d::/96"))
+	c.Assert(err, Equals, ErrPoolExhausted)
+	c.Assert(changed, Equals, true)
+	c.Assert(podCIDRs, DeepEquals, []string{"10.0.0.0/24", "f00d::/96", "10.0.3.0/24"})
+
+	// Deleting a node releases its CIDRs
+	n.Delete("node2")
+	podCIDRs, changed, err = n.Update(newCiliumNode("node1", 3, "10.0.0.0/24", "f00d::/96", "10.0.3.0/24"))
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, true)
+	c.Assert(podCIDRs, DeepEquals, []string{"10.0.0.0/24", "f00d::/96", "10.0.3.0/24", "10.0.1.0/24"})
+}
+
+func (s *ClusterPoolSuite) TestNodeAllocatorRestore(c *C) {
+	v4, err := NewAllocator([]string{"10.0.0.0/23"}, 24)
+	c.Assert(err, IsNil)
+	n := NewNodeAllocator(v4, nil)
+
+	n.Restore(newCiliumNode("node1", 0, "10.0.0.0/24"))
+
+	podCIDRs, changed, err := n.Update(newCiliumNode("node2", 0))
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, true)
+	c.Assert(podCIDRs, DeepEquals, []string{"10.0.1.0/24"})
+
+	podCIDRs, changed, err = n.Update(newCiliumNode("node1", 0, "10.0.0.0/24"))
+	c.Assert(err, IsNil)
+	c.Assert(changed, Equals, false)
+	c.Assert(podCIDRs, DeepEquals, []string{"10.0.0.0/24"})
+}
@@ -0,0 +1,188 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterpool
+
+import (
+	"net"
+
+	"github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/sirupsen/logrus"
+)
+
+var log = logging.DefaultLogger.WithField(logfields.LogSubsys, "ipam-cluster-pool")
+
+// NodeAllocator allocates the pod CIDRs of CiliumNodes out of the cluster
+// pools
+type NodeAllocator struct {
+	mutex lock.Mutex
+	ipv4  *Allocator
+	ipv6  *Allocator
+
+	// owners maps allocated CIDRs to the name of the node owning them
+	owners map[string]string
+}
+
+// NewNodeAllocator returns a new node allocator using the given IPv4 and
+// IPv6 allocators. Either allocator may be nil to disable the address family.
+func NewNodeAllocator(ipv4, ipv6 *Allocator) *NodeAllocator {
+	return &NodeAllocator{
+		ipv4:   ipv4,
+		ipv6:   ipv6,
+		owners: map[string]string{},
+	}
+}
+
+func (n *NodeAllocator) allocatorFor(cidr *net.IPNet) *Allocator {
+	if cidr.IP.To4() != nil {
+		return n.ipv4
+	}
+	return n.ipv6
+}
+
+// occupy records the pod CIDRs of the given node as allocated to the node.
+// CIDRs which are invalid or owned by another node are skipped. It returns
+// the retained pod CIDRs, the number of retained CIDRs per address family
+// and whether any CIDR was skipped.
+func (n *NodeAllocator) occupy(node *v2.CiliumNode) (podCIDRs []string, ipv4CIDRs, ipv6CIDRs int, changed bool) {
+	scopedLog := log.WithField(logfields.NodeName, node.Name)
+	owned := map[string]struct{}{}
+
+	for _, s := range node.Spec.IPAM.PodCIDRs {
+		_, cidr, err := net.ParseCIDR(s)
+		if err != nil {
+			scopedLog.WithError(err).WithField(logfields.CIDR, s).Warning("Removing invalid pod CIDR from node")
+			changed = true
+			continue
+		}
+
+		key := cidr.String()
+		if owner, ok := n.owners[key]; ok && owner != node.Name {
+			scopedLog.WithFields(logrus.Fields{
+				logfields.CIDR: key,
+				"owner":        owner,
+			}).Warning("Removing pod CIDR allocated to another node")
+			changed = true
+			continue
+		} else if !ok {
+			allocator := n.allocatorFor(cidr)
+			if allocator != nil && allocator.Contains(cidr) {
+				if err := allocator.Occupy(cidr); err != nil {
+					scopedLog.WithError(err).WithField(logfields.CIDR, key).Warning("Unable to occupy pod CIDR of node")
+				} else {
+					n.owners[key] = node.Name
+				}
+			} else {
+				scopedLog.WithField(logfields.CIDR, key).Warning("Pod CIDR of node is not part of any pool")
+			}
+		}
+
+		owned[key] = struct{}{}
+		podCIDRs = append(podCIDRs, key)
+		if cidr.IP.To4() != nil {
+			ipv4CIDRs++
+		} else {
+			ipv6CIDRs++
+		}
+	}
+
+	// Release CIDRs which have been removed from the node
+	for key, owner := range n.owners {
+		if _, ok := owned[key]; !ok && owner == node.Name {
+			_, cidr, _ := net.ParseCIDR(key)
+			n.allocatorFor(cidr).Release(cidr)
+			delete(n.owners, key)
+		}
+	}
+
+	return
+}
+
+// Restore records the pod CIDRs of the given node as allocated without
+// allocating any new CIDRs. It must be called for all existing nodes before
+// the first call to Update to avoid handing out CIDRs already in use.
+func (n *NodeAllocator) Restore(node *v2.CiliumNode) {
+	n.mutex.Lock()
+	n.occupy(node)
+	n.mutex.Unlock()
+}
+
+// Update reconciles the pod CIDRs of the given node with the allocations of
+// the pools. CIDRs already recorded in the node are retained unless they are
+// owned by another node. The node is guaranteed an IPv6 CIDR if IPv6 is
+// enabled and at least max(1, Status.IPAM.RequestedIPv4PodCIDRs) IPv4 CIDRs
+// if IPv4 is enabled, as long as the pools have CIDRs left. It returns the
+// resulting list of pod CIDRs and whether it differs from the list in the
+// node spec.
+func (n *NodeAllocator) Update(node *v2.CiliumNode) ([]string, bool, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	scopedLog := log.WithField(logfields.NodeName, node.Name)
+	podCIDRs, ipv4CIDRs, ipv6CIDRs, changed := n.occupy(node)
+
+	var err error
+	allocate := func(allocator *Allocator) bool {
+		cidr, allocErr := allocator.Allocate()
+		if allocErr != nil {
+			err = allocErr
+			return false
+		}
+		n.owners[cidr.String()] = node.Name
+		podCIDRs = append(podCIDRs, cidr.String())
+		changed = true
+		scopedLog.WithField(logfields.CIDR, cidr).Info("Allocated pod CIDR to node")
+		return true
+	}
+
+	if n.ipv6 != nil && ipv6CIDRs == 0 {
+		allocate(n.ipv6)
+	}
+
+	if n.ipv4 != nil {
+		requested := node.Status.IPAM.RequestedIPv4PodCIDRs
+		if requested < 1 {
+			requested = 1
+		}
+		for ; ipv4CIDRs < requested; ipv4CIDRs++ {
+			if !allocate(n.ipv4) {
+				break
+			}
+		}
+	}
+
+	return podCIDRs, changed, err
+}
+
+// Delete releases all CIDRs allocated to the node with the given name
+func (n *NodeAllocator) Delete(nodeName string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	for key, owner := range n.owners {
+		if owner == nodeName {
+			_, cidr, _ := net.ParseCIDR(key)
+			n.allocatorFor(cidr).Release(cidr)
+			delete(n.owners, key)
+			log.WithFields(logrus.Fields{
+				logfields.NodeName: nodeName,
+				logfields.CIDR:     key,
+			}).Info("Released pod CIDR of deleted node")
+		}
+	}
+}
@@ -27,6 +27,20 @@ type Config struct {
 	IPv6Allocator *ipallocator.Range
 	IPv4Allocator *ipallocator.Range
 
+	// IPv4AuxAllocators are the allocators of additional IPv4 allocation
+	// ranges which are used once IPv4Allocator is exhausted
+	IPv4AuxAllocators []*ipallocator.Range
+
+	// ipv4RangeRequester is called when the available IPv4 addresses
+	// run low
+	ipv4RangeRequester IPv4RangeRequester
+
 	// mutex covers access to all members of this struct
 	allocatorMutex lock.RWMutex
 }
+
+// IPv4RangeRequester is called with the number of IPv4 allocation ranges
+// required when the number of available IPv4 addresses across all ranges
+// drops below the low watermark. It is called with the allocator mutex held
+// and must not block.
+type IPv4RangeRequester func(ranges int)
@@ -45,6 +45,10 @@ const (
 	// kvstore
 	FromKVStore Source = "kvstore"
 
+	// FromCustomResource is the source used for identities derived from
+	// CiliumEndpoint resources when no kvstore is in use
+	FromCustomResource Source = "custom-resource"
+
 	// FromAgentLocal is the source used for identities derived during the
 	// agent bootup process. This includes identities for endpoint IPs.
 	FromAgentLocal Source = "agent-local"
@@ -190,6 +194,8 @@ func allowOverwrite(existing, new Source) bool {
 		return new != FromCIDR
 	case FromKVStore:
 		return new == FromKVStore || new == FromAgentLocal
+	case FromCustomResource:
+		return new == FromCustomResource || new == FromAgentLocal
 	case FromAgentLocal:
 		return new == FromAgentLocal
 
@@ -254,4 +254,9 @@ func (s *IPCacheTestSuite) TestAllowOverwrite(c *C) {
 	c.Assert(allowOverwrite(FromAgentLocal, FromKubernetes), Equals, false)
 	c.Assert(allowOverwrite(FromAgentLocal, FromKVStore), Equals, false)
 	c.Assert(allowOverwrite(FromAgentLocal, FromAgentLocal), Equals, true)
+	c.Assert(allowOverwrite(FromKubernetes, FromCustomResource), Equals, true)
+	c.Assert(allowOverwrite(FromCustomResource, FromKubernetes), Equals, false)
+	c.Assert(allowOverwrite(FromCustomResource, FromCustomResource), Equals, true)
+	c.Assert(allowOverwrite(FromCustomResource, FromAgentLocal), Equals, true)
+	c.Assert(allowOverwrite(FromAgentLocal, FromCustomResource), Equals, false)
 }
@@ -44,6 +44,10 @@ const (
 	// ResourceTypeCiliumNetworkPolicy is the resource type used for the
 	// PolicyLabelDerivedFrom label
 	ResourceTypeCiliumNetworkPolicy = "CiliumNetworkPolicy"
+
+	// ResourceTypeCiliumClusterwideNetworkPolicy is the resource type used
+	// for the PolicyLabelDerivedFrom label
+	ResourceTypeCiliumClusterwideNetworkPolicy = "CiliumClusterwideNetworkPolicy"
 )
 
 var (
@@ -73,13 +77,14 @@ func getEndpointSelector(namespace string, labelSelector *metav1.LabelSelector,
 
 	// The user can explicitly specify the namespace in the
 	// FromEndpoints selector. If omitted, we limit the
-	// scope to the namespace the policy lives in.
+	// scope to the namespace the policy lives in. Cluster-wide
+	// policies have no namespace and are not limited.
 	//
 	// Policies applying on initializing pods are a special case.
 	// Those pods don't have any labels, so they don't have a namespace label either.
 	// Don't add a namespace label to those endpoint selectors, or we wouldn't be
 	// able to match on those pods.
-	if !matchesInit && !es.HasKey(podPrefixLbl) && !es.HasKey(podAnyPrefixLbl) {
+	if namespace != "" && !matchesInit && !es.HasKey(podPrefixLbl) && !es.HasKey(podAnyPrefixLbl) {
 		es.AddMatch(podPrefixLbl, namespace)
 	}
 
@@ -103,6 +108,12 @@ func parseToCiliumIngressRule(namespace string, inRule, retRule *api.Rule) {
 				retRule.Ingress[i].ToPorts = make([]api.PortRule, len(ing.ToPorts))
 				copy(retRule.Ingress[i].ToPorts, ing.ToPorts)
 			}
+
+			if ing.ICMPs != nil {
+				retRule.Ingress[i].ICMPs = make([]api.ICMPRule, len(ing.ICMPs))
+				copy(retRule.Ingress[i].ICMPs, ing.ICMPs)
+			}
+
 			if ing.FromCIDR != nil {
 				retRule.Ingress[i].FromCIDR = make([]api.CIDR, len(ing.FromCIDR))
 				copy(retRule.Ingress[i].FromCIDR, ing.FromCIDR)
@@ -128,6 +139,41 @@ func parseToCiliumIngressRule(namespace string, inRule, retRule *api.Rule) {
 	}
 }
 
+func parseToCiliumIngressDenyRule(namespace string, inRule, retRule *api.Rule) {
+	matchesInit := retRule.EndpointSelector.HasKey(podInitLbl)
+
+	if inRule.IngressDeny != nil {
+		retRule.IngressDeny = make([]api.IngressDenyRule, len(inRule.IngressDeny))
+		for i, ing := range inRule.IngressDeny {
+			if ing.FromEndpoints != nil {
+				retRule.IngressDeny[i].FromEndpoints = make([]api.EndpointSelector, len(ing.FromEndpoints))
+				for j, ep := range ing.FromEndpoints {
+					retRule.IngressDeny[i].FromEndpoints[j] = getEndpointSelector(namespace, ep.LabelSelector, true, matchesInit)
+				}
+			}
+
+			if ing.ToPorts != nil {
+				retRule.IngressDeny[i].ToPorts = make([]api.PortDenyRule, len(ing.ToPorts))
+				copy(retRule.IngressDeny[i].ToPorts, ing.ToPorts)
+			}
+			if ing.FromCIDR != nil {
+				retRule.IngressDeny[i].FromCIDR = make([]api.CIDR, len(ing.FromCIDR))
+				copy(retRule.IngressDeny[i].FromCIDR, ing.FromCIDR)
+			}
+
+			if ing.FromCIDRSet != nil {
+				retRule.IngressDeny[i].FromCIDRSet = make([]api.CIDRRule, len(ing.FromCIDRSet))
+				copy(retRule.IngressDeny[i].FromCIDRSet, ing.FromCIDRSet)
+			}
+
+			if ing.FromEntities != nil {
+				retRule.IngressDeny[i].FromEntities = make([]api.Entity, len(ing.FromEntities))
+				copy(retRule.IngressDeny[i].FromEntities, ing.FromEntities)
+			}
+		}
+	}
+}
+
 func parseToCiliumEgressRule(namespace string, inRule, retRule *api.Rule) {
 	matchesInit := retRule.EndpointSelector.HasKey(podInitLbl)
 
@@ -146,6 +192,12 @@ func parseToCiliumEgressRule(namespace string, inRule, retRule *api.Rule) {
 				retRule.Egress[i].ToPorts = make([]api.PortRule, len(egr.ToPorts))
 				copy(retRule.Egress[i].ToPorts, egr.ToPorts)
 			}
+
+			if egr.ICMPs != nil {
+				retRule.Egress[i].ICMPs = make([]api.ICMPRule, len(egr.ICMPs))
+				copy(retRule.Egress[i].ICMPs, egr.ICMPs)
+			}
+
 			if egr.ToCIDR != nil {
 				retRule.Egress[i].ToCIDR = make([]api.CIDR, len(egr.ToCIDR))
 				copy(retRule.Egress[i].ToCIDR, egr.ToCIDR)
@@ -186,6 +238,42 @@ func parseToCiliumEgressRule(namespace string, inRule, retRule *api.Rule) {
 	}
 }
 
+func parseToCiliumEgressDenyRule(namespace string, inRule, retRule *api.Rule) {
+	matchesInit := retRule.EndpointSelector.HasKey(podInitLbl)
+
+	if inRule.EgressDeny != nil {
+		retRule.EgressDeny = make([]api.EgressDenyRule, len(inRule.EgressDeny))
+
+		for i, egr := range inRule.EgressDeny {
+			if egr.ToEndpoints != nil {
+				retRule.EgressDeny[i].ToEndpoints = make([]api.EndpointSelector, len(egr.ToEndpoints))
+				for j, ep := range egr.ToEndpoints {
+					retRule.EgressDeny[i].ToEndpoints[j] = getEndpointSelector(namespace, ep.LabelSelector, true, matchesInit)
+				}
+			}
+
+			if egr.ToPorts != nil {
+				retRule.EgressDeny[i].ToPorts = make([]api.PortDenyRule, len(egr.ToPorts))
+				copy(retRule.EgressDeny[i].ToPorts, egr.ToPorts)
+			}
+			if egr.ToCIDR != nil {
+				retRule.EgressDeny[i].ToCIDR = make([]api.CIDR, len(egr.ToCIDR))
+				copy(retRule.EgressDeny[i].ToCIDR, egr.ToCIDR)
+			}
+
+			if egr.ToCIDRSet != nil {
+				retRule.EgressDeny[i].ToCIDRSet = make(api.CIDRRuleSlice, len(egr.ToCIDRSet))
+				copy(retRule.EgressDeny[i].ToCIDRSet, egr.ToCIDRSet)
+			}
+
+			if egr.ToEntities != nil {
+				retRule.EgressDeny[i].ToEntities = make([]api.Entity, len(egr.ToEntities))
+				copy(retRule.EgressDeny[i].ToEntities, egr.ToEntities)
+			}
+		}
+	}
+}
+
 // namespacesAreValid checks the set of namespaces from a rule returns true if
 // they are not specified, or if they are specified and match the namespace
 // where the rule is being inserted.
@@ -220,10 +308,21 @@ func ParseToCiliumRule(namespace, name string, uid types.UID, r *api.Rule) *api.
 			}
 			retRule.EndpointSelector.AddMatch(podPrefixLbl, namespace)
 		}
+	} else if r.NodeSelector.LabelSelector != nil {
+		// Nodes are not namespaced, a namespaced policy must thus not be
+		// able to select them. The rule is left without selector so that
+		// it selects nothing.
+		log.WithFields(logrus.Fields{
+			logfields.K8sNamespace:            namespace,
+			logfields.CiliumNetworkPolicyName: name,
+		}).Warn("CiliumNetworkPolicy contains illegal NodeSelector." +
+			" Nodes can only be selected by CiliumClusterwideNetworkPolicies, ignoring rule.")
 	}
 
 	parseToCiliumIngressRule(namespace, r, retRule)
+	parseToCiliumIngressDenyRule(namespace, r, retRule)
 	parseToCiliumEgressRule(namespace, r, retRule)
+	parseToCiliumEgressDenyRule(namespace, r, retRule)
 
 	retRule.Labels = ParseToCiliumLabels(namespace, name, uid, r.Labels)
 
@@ -232,6 +331,31 @@ func ParseToCiliumRule(namespace, name string, uid types.UID, r *api.Rule) *api.
 	return retRule
 }
 
+// ParseToCiliumClusterwideRule returns an api.Rule of a
+// CiliumClusterwideNetworkPolicy with all the labels parsed into cilium
+// labels. Unlike ParseToCiliumRule, the endpoint selectors are not restricted
+// to a namespace, and the rule may select nodes.
+func ParseToCiliumClusterwideRule(name string, uid types.UID, r *api.Rule) *api.Rule {
+	retRule := &api.Rule{}
+	if r.EndpointSelector.LabelSelector != nil {
+		retRule.EndpointSelector = api.NewESFromK8sLabelSelector("", r.EndpointSelector.LabelSelector)
+	} else if r.NodeSelector.LabelSelector != nil {
+		retRule.NodeSelector = api.NewESFromK8sLabelSelector("", r.NodeSelector.LabelSelector)
+	}
+
+	parseToCiliumIngressRule("", r, retRule)
+	parseToCiliumIngressDenyRule("", r, retRule)
+	parseToCiliumEgressRule("", r, retRule)
+	parseToCiliumEgressDenyRule("", r, retRule)
+
+	policyLbls := GetPolicyLabels("", name, uid, ResourceTypeCiliumClusterwideNetworkPolicy)
+	retRule.Labels = append(policyLbls, r.Labels...)
+
+	retRule.Description = r.Description
+
+	return retRule
+}
+
 // ParseToCiliumLabels returns all ruleLbls appended with a specific label that
 // represents the given namespace and name along with a label that specifies
 // these labels were derived from a CiliumNetworkPolicy.
@@ -356,3 +356,56 @@ func (s *CiliumUtilsSuite) TestParseToCiliumLabels(c *C) {
 		c.Assert(got, checker.DeepEquals, tt.want, Commentf("Test Name: %s", tt.name))
 	}
 }
+
+func (s *CiliumUtilsSuite) TestParseToCiliumRuleNodeSelector(c *C) {
+	nodeLabels := labels.LabelArray{
+		labels.NewLabel(labels.IDNameHost, "", labels.LabelSourceReserved),
+		labels.NewLabel("node-role.kubernetes.io/worker", "", labels.LabelSourceK8s),
+	}
+	rule := &api.Rule{
+		NodeSelector: api.NewESFromLabels(labels.ParseSelectLabel("node-role.kubernetes.io/worker")),
+	}
+
+	// A namespaced policy must not be able to select nodes.
+	parsed := ParseToCiliumRule("default", "host-policy", "", rule)
+	c.Assert(parsed.NodeSelector.LabelSelector, IsNil)
+	c.Assert(parsed.NodeSelector.Matches(nodeLabels), Equals, false)
+	c.Assert(parsed.EndpointSelector.Matches(nodeLabels), Equals, false)
+}
+
+func (s *CiliumUtilsSuite) TestParseToCiliumClusterwideRule(c *C) {
+	nodeLabels := labels.LabelArray{
+		labels.NewLabel(labels.IDNameHost, "", labels.LabelSourceReserved),
+		labels.NewLabel("node-role.kubernetes.io/worker", "", labels.LabelSourceK8s),
+	}
+	rule := &api.Rule{
+		NodeSelector: api.NewESFromLabels(labels.ParseSelectLabel("node-role.kubernetes.io/worker")),
+	}
+
+	parsed := ParseToCiliumClusterwideRule("host-policy", "", rule)
+	c.Assert(parsed.NodeSelector.Matches(nodeLabels), Equals, true)
+	c.Assert(parsed.Labels, checker.DeepEquals,
+		GetPolicyLabels("", "host-policy", "", ResourceTypeCiliumClusterwideNetworkPolicy))
+
+	// Endpoint selectors of a cluster-wide policy are not restricted to a
+	// namespace.
+	rule = &api.Rule{
+		EndpointSelector: api.NewESFromLabels(labels.ParseSelectLabel("role=frontend")),
+		Ingress: []api.IngressRule{
+			{
+				FromEndpoints: []api.EndpointSelector{
+					api.NewESFromLabels(labels.ParseSelectLabel("role=backend")),
+				},
+			},
+		},
+	}
+	parsed = ParseToCiliumClusterwideRule("cluster-policy", "", rule)
+	backend := labels.LabelArray{
+		labels.NewLabel("role", "backend", labels.LabelSourceK8s),
+		labels.NewLabel(k8sConst.PodNamespaceLabel, "production", labels.LabelSourceK8s),
+	}
+	c.Assert(parsed.Ingress, HasLen, 1)
+	c.Assert(parsed.Ingress[0].FromEndpoints, HasLen, 1)
+	c.Assert(parsed.Ingress[0].FromEndpoints[0].Matches(backend), Equals, true)
+	c.Assert(parsed.Ingress[0].FromEndpoints[0].HasKey(podPrefixLbl), Equals, false)
+}
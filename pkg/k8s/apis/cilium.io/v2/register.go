@@ -41,7 +41,7 @@ const (
 
 	// CustomResourceDefinitionSchemaVersion is semver-conformant version of CRD schema
 	// Used to determine if CRD needs to be updated in cluster
-	CustomResourceDefinitionSchemaVersion = "1.12"
+	CustomResourceDefinitionSchemaVersion = "1.26"
 
 	// CustomResourceDefinitionSchemaVersionKey is key to label which holds the CRD schema version
 	CustomResourceDefinitionSchemaVersionKey = "io.cilium.k8s.crd.schema.version"
@@ -49,6 +49,18 @@ const (
 	// CNPKindDefinition is the kind name for Cilium Network Policy
 	CNPKindDefinition = "CiliumNetworkPolicy"
 
+	// CCNPKindDefinition is the kind name for Cilium Clusterwide Network Policy
+	CCNPKindDefinition = "CiliumClusterwideNetworkPolicy"
+
+	// CENPKindDefinition is the kind name for Cilium Egress NAT Policy
+	CENPKindDefinition = "CiliumEgressNATPolicy"
+
+	// CNKindDefinition is the kind name for Cilium Node
+	CNKindDefinition = "CiliumNode"
+
+	// CIDKindDefinition is the kind name for Cilium Identity
+	CIDKindDefinition = "CiliumIdentity"
+
 	fqdnNameRegex = `^(([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9\-]*[a-zA-Z0-9])\.)*([A-Za-z0-9]|[A-Za-z0-9][A-Za-z0-9\-]*[A-Za-z0-9])\.?$`
 
 	fqdnPatternRegex = `^(([a-zA-Z0-9\*]|[a-zA-Z0-9\*][a-zA-Z0-9\-\*]*[a-zA-Z0-9\*])\.)*([A-Za-z0-9\*]|[A-Za-z0-9\*][A-Za-z0-9\-\*]*[A-Za-z0-9\*])\.?$`
@@ -102,7 +114,15 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&CiliumNetworkPolicy{},
 		&CiliumNetworkPolicyList{},
+		&CiliumClusterwideNetworkPolicy{},
+		&CiliumClusterwideNetworkPolicyList{},
 		&CiliumEndpoint{},
+		&CiliumEgressNATPolicy{},
+		&CiliumEgressNATPolicyList{},
+		&CiliumNode{},
+		&CiliumNodeList{},
+		&CiliumIdentity{},
+		&CiliumIdentityList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -115,10 +135,26 @@ func CreateCustomResourceDefinitions(clientset apiextensionsclient.Interface) er
 		return err
 	}
 
+	if err := createCCNPCRD(clientset); err != nil {
+		return err
+	}
+
 	if err := createCEPCRD(clientset); err != nil {
 		return err
 	}
 
+	if err := createCENPCRD(clientset); err != nil {
+		return err
+	}
+
+	if err := createCNCRD(clientset); err != nil {
+		return err
+	}
+
+	if err := createCIDCRD(clientset); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -168,6 +204,50 @@ func createCNPCRD(clientset apiextensionsclient.Interface) error {
 	return createUpdateCRD(clientset, "CiliumNetworkPolicy/v2", res)
 }
 
+// createCCNPCRD creates and updates the CiliumClusterwideNetworkPolicies CRD.
+// It should be called on agent startup but is idempotent and safe to call
+// again.
+func createCCNPCRD(clientset apiextensionsclient.Interface) error {
+	var (
+		// CustomResourceDefinitionSingularName is the singular name of custom resource definition
+		CustomResourceDefinitionSingularName = "ciliumclusterwidenetworkpolicy"
+
+		// CustomResourceDefinitionPluralName is the plural name of custom resource definition
+		CustomResourceDefinitionPluralName = "ciliumclusterwidenetworkpolicies"
+
+		// CustomResourceDefinitionShortNames are the abbreviated names to refer to this CRD's instances
+		CustomResourceDefinitionShortNames = []string{"ccnp"}
+
+		// CustomResourceDefinitionKind is the Kind name of custom resource definition
+		CustomResourceDefinitionKind = CCNPKindDefinition
+
+		CRDName = CustomResourceDefinitionPluralName + "." + SchemeGroupVersion.Group
+	)
+
+	res := &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CRDName,
+			Labels: map[string]string{
+				CustomResourceDefinitionSchemaVersionKey: CustomResourceDefinitionSchemaVersion,
+			},
+		},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   SchemeGroupVersion.Group,
+			Version: SchemeGroupVersion.Version,
+			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+				Plural:     CustomResourceDefinitionPluralName,
+				Singular:   CustomResourceDefinitionSingularName,
+				ShortNames: CustomResourceDefinitionShortNames,
+				Kind:       CustomResourceDefinitionKind,
+			},
+			Scope:      apiextensionsv1beta1.ClusterScoped,
+			Validation: &cnpCRV,
+		},
+	}
+
+	return createUpdateCRD(clientset, "CiliumClusterwideNetworkPolicy/v2", res)
+}
+
 // createCEPCRD creates and updates the CiliumEndpoint CRD. It should be called
 // on agent startup but is idempotent and safe to call again.
 func createCEPCRD(clientset apiextensionsclient.Interface) error {
@@ -249,6 +329,161 @@ func createCEPCRD(clientset apiextensionsclient.Interface) error {
 	return createUpdateCRD(clientset, "v2.CiliumEndpoint", res)
 }
 
+// createCENPCRD creates and updates the CiliumEgressNATPolicy CRD. It should
+// be called on agent startup but is idempotent and safe to call again.
+func createCENPCRD(clientset apiextensionsclient.Interface) error {
+	var (
+		// CustomResourceDefinitionSingularName is the singular name of custom resource definition
+		CustomResourceDefinitionSingularName = "ciliumegressnatpolicy"
+
+		// CustomResourceDefinitionPluralName is the plural name of custom resource definition
+		CustomResourceDefinitionPluralName = "ciliumegressnatpolicies"
+
+		// CustomResourceDefinitionShortNames are the abbreviated names to refer to this CRD's instances
+		CustomResourceDefinitionShortNames = []string{"cenp", "ciliumenp"}
+
+		// CustomResourceDefinitionKind is the Kind name of custom resource definition
+		CustomResourceDefinitionKind = CENPKindDefinition
+
+		CRDName = CustomResourceDefinitionPluralName + "." + SchemeGroupVersion.Group
+	)
+
+	res := &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CRDName,
+			Labels: map[string]string{
+				CustomResourceDefinitionSchemaVersionKey: CustomResourceDefinitionSchemaVersion,
+			},
+		},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   SchemeGroupVersion.Group,
+			Version: SchemeGroupVersion.Version,
+			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+				Plural:     CustomResourceDefinitionPluralName,
+				Singular:   CustomResourceDefinitionSingularName,
+				ShortNames: CustomResourceDefinitionShortNames,
+				Kind:       CustomResourceDefinitionKind,
+			},
+			AdditionalPrinterColumns: []apiextensionsv1beta1.CustomResourceColumnDefinition{
+				{
+					Name:        "Gateway Node",
+					Type:        "string",
+					Description: "Node masquerading the traffic",
+					JSONPath:    ".spec.gatewayNode",
+				},
+				{
+					Name:        "Egress IP",
+					Type:        "string",
+					Description: "Source IP the traffic is masqueraded with",
+					JSONPath:    ".spec.egressIP",
+				},
+			},
+			Scope:      apiextensionsv1beta1.NamespaceScoped,
+			Validation: &cenpCRV,
+		},
+	}
+
+	return createUpdateCRD(clientset, "CiliumEgressNATPolicy/v2", res)
+}
+
+// createCNCRD creates and updates the CiliumNode CRD. It should be called on
+// agent startup but is idempotent and safe to call again.
+func createCNCRD(clientset apiextensionsclient.Interface) error {
+	var (
+		// CustomResourceDefinitionSingularName is the singular name of custom resource definition
+		CustomResourceDefinitionSingularName = "ciliumnode"
+
+		// CustomResourceDefinitionPluralName is the plural name of custom resource definition
+		CustomResourceDefinitionPluralName = "ciliumnodes"
+
+		// CustomResourceDefinitionShortNames are the abbreviated names to refer to this CRD's instances
+		CustomResourceDefinitionShortNames = []string{"cn", "ciliumn"}
+
+		// CustomResourceDefinitionKind is the Kind name of custom resource definition
+		CustomResourceDefinitionKind = CNKindDefinition
+
+		CRDName = CustomResourceDefinitionPluralName + "." + SchemeGroupVersion.Group
+	)
+
+	res := &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CRDName,
+			Labels: map[string]string{
+				CustomResourceDefinitionSchemaVersionKey: CustomResourceDefinitionSchemaVersion,
+			},
+		},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   SchemeGroupVersion.Group,
+			Version: SchemeGroupVersion.Version,
+			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+				Plural:     CustomResourceDefinitionPluralName,
+				Singular:   CustomResourceDefinitionSingularName,
+				ShortNames: CustomResourceDefinitionShortNames,
+				Kind:       CustomResourceDefinitionKind,
+			},
+			AdditionalPrinterColumns: []apiextensionsv1beta1.CustomResourceColumnDefinition{
+				{
+					Name:        "Pod CIDRs",
+					Type:        "string",
+					Description: "Pod CIDRs allocated to the node",
+					JSONPath:    ".spec.ipam.podCIDRs",
+				},
+			},
+			Subresources: &apiextensionsv1beta1.CustomResourceSubresources{
+				Status: &apiextensionsv1beta1.CustomResourceSubresourceStatus{},
+			},
+			Scope: apiextensionsv1beta1.ClusterScoped,
+		},
+	}
+
+	return createUpdateCRD(clientset, "CiliumNode/v2", res)
+}
+
+// createCIDCRD creates and updates the CiliumIdentity CRD. It should be
+// called on agent startup but is idempotent and safe to call again.
+func createCIDCRD(clientset apiextensionsclient.Interface) error {
+	var (
+		// CustomResourceDefinitionSingularName is the singular name of custom resource definition
+		CustomResourceDefinitionSingularName = "ciliumidentity"
+
+		// CustomResourceDefinitionPluralName is the plural name of custom resource definition
+		CustomResourceDefinitionPluralName = "ciliumidentities"
+
+		// CustomResourceDefinitionShortNames are the abbreviated names to refer to this CRD's instances
+		CustomResourceDefinitionShortNames = []string{"ciliumid"}
+
+		// CustomResourceDefinitionKind is the Kind name of custom resource definition
+		CustomResourceDefinitionKind = CIDKindDefinition
+
+		CRDName = CustomResourceDefinitionPluralName + "." + SchemeGroupVersion.Group
+	)
+
+	res := &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: CRDName,
+			Labels: map[string]string{
+				CustomResourceDefinitionSchemaVersionKey: CustomResourceDefinitionSchemaVersion,
+			},
+		},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   SchemeGroupVersion.Group,
+			Version: SchemeGroupVersion.Version,
+			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+				Plural:     CustomResourceDefinitionPluralName,
+				Singular:   CustomResourceDefinitionSingularName,
+				ShortNames: CustomResourceDefinitionShortNames,
+				Kind:       CustomResourceDefinitionKind,
+			},
+			Subresources: &apiextensionsv1beta1.CustomResourceSubresources{
+				Status: &apiextensionsv1beta1.CustomResourceSubresourceStatus{},
+			},
+			Scope: apiextensionsv1beta1.ClusterScoped,
+		},
+	}
+
+	return createUpdateCRD(clientset, "CiliumIdentity/v2", res)
+}
+
 // createUpdateCRD ensures the CRD object is installed into the k8s cluster. It
 // will create or update the CRD and it's validation when needed
 func createUpdateCRD(clientset apiextensionsclient.Interface, CRDName string, crd *apiextensionsv1beta1.CustomResourceDefinition) error {
@@ -364,6 +599,14 @@ func getInt64(i int64) *int64 {
 	return &i
 }
 
+func getEnum(values ...string) []apiextensionsv1beta1.JSON {
+	enum := make([]apiextensionsv1beta1.JSON, 0, len(values))
+	for _, v := range values {
+		enum = append(enum, apiextensionsv1beta1.JSON{Raw: []byte(`"` + v + `"`)})
+	}
+	return enum
+}
+
 var (
 	// cepCRV is a minimal validation for CEP objects. Since only the agent is
 	// creating them, it is better to be permissive and have some data, if buggy,
@@ -378,22 +621,73 @@ var (
 		},
 	}
 
+	cenpCRV = apiextensionsv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextensionsv1beta1.JSONSchemaProps{
+			Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+				"spec": CENPSpec,
+			},
+			Required: []string{
+				"spec",
+			},
+		},
+	}
+
+	CENPSpec = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "CiliumEgressNATPolicySpec is the specification of a CiliumEgressNATPolicy.",
+		Type:        "object",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"endpointSelector": EndpointSelector,
+			"destinationCIDRs": {
+				Description: "DestinationCIDRs is the list of destination prefixes the " +
+					"traffic of the selected pods is steered through the gateway node for.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDR,
+				},
+			},
+			"gatewayNode": {
+				Description: "GatewayNode is the name of the node masquerading the traffic.",
+				Type:        "string",
+			},
+			"egressIP": {
+				Description: "EgressIP is the source IP the traffic is masqueraded with on " +
+					"the gateway node. It must be assigned to an interface of the gateway node.",
+				Type:   "string",
+				Format: "ipv4",
+			},
+		},
+		Required: []string{
+			"endpointSelector",
+			"destinationCIDRs",
+			"gatewayNode",
+			"egressIP",
+		},
+	}
+
 	properties = map[string]apiextensionsv1beta1.JSONSchemaProps{
 		"CIDR":                     CIDR,
 		"CIDRRule":                 CIDRRule,
+		"EgressDenyRule":           EgressDenyRule,
 		"EgressRule":               EgressRule,
 		"EndpointSelector":         EndpointSelector,
+		"ICMPField":                ICMPField,
+		"ICMPRule":                 ICMPRule,
+		"IngressDenyRule":          IngressDenyRule,
 		"IngressRule":              IngressRule,
 		"K8sServiceNamespace":      K8sServiceNamespace,
 		"L7Rules":                  L7Rules,
 		"Label":                    Label,
 		"LabelSelector":            LabelSelector,
 		"LabelSelectorRequirement": LabelSelectorRequirement,
+		"PortDenyRule":             PortDenyRule,
 		"PortProtocol":             PortProtocol,
 		"PortRule":                 PortRule,
+		"PortRuleCassandra":        PortRuleCassandra,
+		"PortRuleGRPC":             PortRuleGRPC,
 		"PortRuleHTTP":             PortRuleHTTP,
 		"PortRuleKafka":            PortRuleKafka,
 		"PortRuleL7":               PortRuleL7,
+		"PortRuleMemcached":        PortRuleMemcached,
 		"Rule":                     Rule,
 		"Service":                  Service,
 		"ServiceSelector":          ServiceSelector,
@@ -510,6 +804,17 @@ var (
 					Schema: &PortRule,
 				},
 			},
+			"icmps": {
+				Description: "ICMPs is a list of ICMP rule identified by type number and code " +
+					"which the endpoint subject to the rule is allowed to send. Combining " +
+					"ICMPs and ToPorts in the same rule is not supported.\n\nExample: Any " +
+					"endpoint with the label \"app=httpd\" is only allowed to send ICMPv6 " +
+					"neighbor solicitations (type 135).",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &ICMPRule,
+				},
+			},
 			"toServices": {
 				Description: "ToServices is a list of services to which the endpoint subject " +
 					"to the rule is allowed to initiate connections.\n\nExample: Any endpoint " +
@@ -563,6 +868,73 @@ var (
 		},
 	}
 
+	EgressDenyRule = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "EgressDenyRule contains all rule types which can be applied at egress, " +
+			"i.e. network traffic that originates inside the endpoint and exits the endpoint " +
+			"selected by the endpointSelector, and which must be denied.\n\n- All members of " +
+			"this structure are optional. If omitted or empty, the\n  member will have no " +
+			"effect on the rule.\n\n- Deny rules take precedence over allow rules. Traffic " +
+			"matching an\n  EgressDenyRule is dropped even if it is allowed by an EgressRule." +
+			"\n\n- If ToPorts is omitted, all traffic to the selected peers is denied.",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"toCIDR": {
+				Description: "ToCIDR is a list of IP blocks which the endpoint subject to the " +
+					"rule is not allowed to initiate connections to. This will match on the " +
+					"destination IP address of outgoing connections.\n\nExample: Any endpoint " +
+					"with the label \"app=database-proxy\" is not allowed to initiate " +
+					"connections to 10.2.3.0/24",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDR,
+				},
+			},
+			"toCIDRSet": {
+				Description: "ToCIDRSet is a list of IP blocks which the endpoint subject to " +
+					"the rule is not allowed to initiate connections to, along with a list of " +
+					"subnets contained within their corresponding IP block to which traffic " +
+					"should not be denied.\n\nExample: Any endpoint with the label " +
+					"\"app=database-proxy\" is not allowed to initiate connections to " +
+					"10.2.3.0/24 except to IPs in subnet 10.2.3.0/28.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDRRule,
+				},
+			},
+			"toEntities": {
+				Description: "ToEntities is a list of special entities to which the endpoint " +
+					"subject to the rule is not allowed to initiate connections. Supported " +
+					"entities are `world`, `cluster` and `host`",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1beta1.JSONSchemaProps{
+						Type: "string",
+					},
+				},
+			},
+			"toPorts": {
+				Description: "ToPorts is a list of destination ports identified by port number " +
+					"and protocol which the endpoint subject to the rule is not allowed to " +
+					"connect to.\n\nExample: Any endpoint with the label \"role=frontend\" is " +
+					"not allowed to initiate connections to destination port 8080/tcp",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &PortDenyRule,
+				},
+			},
+			"toEndpoints": {
+				Description: "ToEndpoints is a list of endpoints identified by an " +
+					"EndpointSelector to which the endpoint subject to the rule " +
+					"is not allowed to communicate.\n\nExample: Any endpoint with the label " +
+					"\"role=frontend\" can not communicate with any endpoint carrying the " +
+					"label \"role=backend\".",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &EndpointSelector,
+				},
+			},
+		},
+	}
+
 	FQDNRule = apiextensionsv1beta1.JSONSchemaProps{
 		Description: `FQDNRule is a rule that specifies an fully qualified domain name to which outside communication is allowed`,
 		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
@@ -693,6 +1065,85 @@ var (
 					Schema: &PortRule,
 				},
 			},
+			"icmps": {
+				Description: "ICMPs is a list of ICMP rule identified by type number and code " +
+					"which the endpoint subject to the rule is allowed to receive. Combining " +
+					"ICMPs and ToPorts in the same rule is not supported.\n\nExample: Any " +
+					"endpoint with the label \"app=httpd\" can only accept incoming ICMP " +
+					"echo requests (type 8).",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &ICMPRule,
+				},
+			},
+		},
+	}
+
+	IngressDenyRule = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "IngressDenyRule contains all rule types which can be applied at " +
+			"ingress, i.e. network traffic that originates outside of the endpoint and is " +
+			"entering the endpoint selected by the endpointSelector, and which must be " +
+			"denied.\n\n- All members of this structure are optional. If omitted or empty, " +
+			"the\n  member will have no effect on the rule.\n\n- Deny rules take precedence " +
+			"over allow rules. Traffic matching an\n  IngressDenyRule is dropped even if it " +
+			"is allowed by an IngressRule.\n\n- If ToPorts is omitted, all traffic from the " +
+			"selected peers is denied.",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"fromCIDR": {
+				Description: "FromCIDR is a list of IP blocks which the endpoint subject to " +
+					"the rule is not allowed to receive connections from. This will match on " +
+					"the source IP address of incoming connections.\n\nExample: Any endpoint " +
+					"with the label \"app=my-legacy-pet\" is not allowed to receive " +
+					"connections from 10.3.9.1",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDR,
+				},
+			},
+			"fromCIDRSet": {
+				Description: "FromCIDRSet is a list of IP blocks which the endpoint subject to " +
+					"the rule is not allowed to receive connections from, along with a list of " +
+					"subnets contained within their corresponding IP block from which traffic " +
+					"should not be denied.\n\nExample: Any endpoint with the label " +
+					"\"app=my-legacy-pet\" is not allowed to receive connections from " +
+					"10.0.0.0/8 except from IPs in subnet 10.96.0.0/12.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDRRule,
+				},
+			},
+			"fromEndpoints": {
+				Description: "FromEndpoints is a list of endpoints identified by an " +
+					"EndpointSelector which are not allowed to communicate with the endpoint " +
+					"subject to the rule.\n\nExample: Any endpoint with the label " +
+					"\"role=backend\" cannot be consumed by any endpoint carrying the label " +
+					"\"role=frontend\".",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &EndpointSelector,
+				},
+			},
+			"fromEntities": {
+				Description: "FromEntities is a list of special entities which the endpoint " +
+					"subject to the rule is not allowed to receive connections from. " +
+					"Supported entities are `world`, `cluster` and `host`",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1beta1.JSONSchemaProps{
+						Type: "string",
+					},
+				},
+			},
+			"toPorts": {
+				Description: "ToPorts is a list of destination ports identified by port number " +
+					"and protocol which the endpoint subject to the rule is not allowed to " +
+					"receive connections on.\n\nExample: Any endpoint with the label " +
+					"\"app=httpd\" can not accept incoming connections on port 80/tcp.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &PortDenyRule,
+				},
+			},
 		},
 	}
 
@@ -746,6 +1197,116 @@ var (
 					Schema: &PortRuleDNS,
 				},
 			},
+			"grpc": {
+				Description: "gRPC specific rules.",
+				Type:        "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &PortRuleGRPC,
+				},
+			},
+			"cassandra": {
+				Description: "Cassandra specific rules.",
+				Type:        "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &PortRuleCassandra,
+				},
+			},
+			"memcached": {
+				Description: "Memcached specific rules.",
+				Type:        "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &PortRuleMemcached,
+				},
+			},
+		},
+	}
+
+	PortRuleCassandra = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "PortRuleCassandra is a list of Cassandra protocol constraints. All fields " +
+			"are optional, if all fields are empty or missing, the rule will match all " +
+			"Cassandra messages.",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"queryAction": {
+				Description: "QueryAction is the action of the query, e.g. \"select\", \"insert\" " +
+					"or \"create-table\". If omitted or empty, all actions are allowed.",
+				Type: "string",
+				Enum: getEnum(
+					"select", "delete", "insert", "update",
+					"create-table", "drop-table", "alter-table", "truncate-table",
+					"use", "create-keyspace", "alter-keyspace", "drop-keyspace",
+					"drop-index", "create-index",
+					"create-materialized-view", "drop-materialized-view",
+					"create-role", "alter-role", "drop-role", "grant-role", "revoke-role", "list-roles",
+					"grant-permission", "revoke-permission", "list-permissions",
+					"create-user", "alter-user", "drop-user", "list-users",
+					"create-function", "drop-function", "create-aggregate", "drop-aggregate",
+					"create-type", "alter-type", "drop-type", "create-trigger", "drop-trigger",
+				),
+			},
+			"queryTable": {
+				Description: "QueryTable is an extended POSIX regex matched against the table or " +
+					"keyspace the query applies to, in the format \"keyspace.table\". If " +
+					"omitted or empty, all tables are allowed.",
+				Type: "string",
+			},
+		},
+	}
+
+	PortRuleMemcached = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "PortRuleMemcached is a list of memcached protocol constraints. All fields " +
+			"are optional, if all fields are empty or missing, the rule will match all " +
+			"memcached requests.",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"command": {
+				Description: "Command is the name of a memcached command, e.g. \"get\" or \"set\", " +
+					"or of a group of commands, e.g. \"storage\" or \"writeGroup\". If omitted " +
+					"or empty, all commands are allowed.",
+				Type: "string",
+				Enum: getEnum(
+					"add", "set", "replace", "append", "prepend", "cas", "incr", "decr",
+					"storage", "get", "delete", "touch", "gat", "writeGroup", "slabs", "lru",
+					"lru_crawler", "watch", "stats", "flush_all", "cache_memlimit",
+					"version", "misbehave", "quit", "noop", "verbosity",
+					"sasl-list-mechs", "sasl-auth", "sasl-step",
+					"rget", "rset", "rsetq", "rappend", "rappendq", "rprepend", "rprependq",
+					"rdelete", "rdeleteq", "rincr", "rincrq", "rdecr", "rdecrq",
+					"set-vbucket", "get-vbucket", "del-vbucket",
+					"tap-connect", "tap-mutation", "tap-delete", "tap-flush", "tap-opaque",
+					"tap-vbucket-set", "tap-checkpoint-start", "tap-checkpoint-end",
+				),
+			},
+			"keyExact": {
+				Description: "KeyExact is the key which must be accessed by the command.",
+				Type:        "string",
+			},
+			"keyPrefix": {
+				Description: "KeyPrefix is a prefix of the keys which may be accessed by the command.",
+				Type:        "string",
+			},
+			"keyRegex": {
+				Description: "KeyRegex is an extended POSIX regex matched against the keys which " +
+					"may be accessed by the command.",
+				Type: "string",
+			},
+		},
+	}
+
+	PortRuleGRPC = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "PortRuleGRPC is a gRPC service and optional method which is allowed to be called.",
+		Required: []string{
+			"service",
+		},
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"service": {
+				Description: "Service is the fully qualified name of a gRPC service, including " +
+					"the protobuf package name, e.g. \"helloworld.Greeter\".",
+				Type: "string",
+			},
+			"method": {
+				Description: "Method is the name of a method of the gRPC service, e.g. " +
+					"\"SayHello\". If omitted or empty, all methods of the service are allowed.",
+				Type: "string",
+			},
 		},
 	}
 
@@ -754,6 +1315,24 @@ var (
 		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
 			"matchName":    MatchFQDNName,
 			"matchPattern": MatchFQDNPattern,
+			"matchQueryTypes": {
+				Description: "MatchQueryTypes restricts the allowed DNS query types, " +
+					"e.g. A, AAAA or CNAME. All query types are allowed when empty.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &apiextensionsv1beta1.JSONSchemaProps{
+						Type: "string",
+					},
+				},
+			},
+			"responseCIDRs": {
+				Description: "ResponseCIDRs restricts the IPs which may be returned " +
+					"in A and AAAA records of DNS responses. All IPs are allowed when empty.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &CIDR,
+				},
+			},
 		},
 	}
 
@@ -849,10 +1428,17 @@ var (
 			"port",
 		},
 		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"endPort": {
+				Description: "EndPort is the last L4 port number of a port range starting at " +
+					"Port. If set, the port/protocol applies to all ports in the inclusive " +
+					"range Port-EndPort. EndPort must not be smaller than Port. Port ranges " +
+					"cannot be combined with L7 rules.",
+				Type:   "integer",
+				Format: "uint16",
+			},
 			"port": {
-				Description: "Port is an L4 port number. For now the string will be strictly " +
-					"parsed as a single uint16. In the future, this field may support ranges " +
-					"in the form \"1024-2048",
+				Description: "Port is an L4 port number. The string will be strictly parsed " +
+					"as a single uint16. If EndPort is set, Port is the first port of the range.",
 				Type: "string",
 				// uint16 string regex
 				Pattern: `^(6553[0-5]|655[0-2][0-9]|65[0-4][0-9]{2}|6[0-4][0-9]{3}|` +
@@ -878,6 +1464,67 @@ var (
 		},
 	}
 
+	ICMPRule = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "ICMPRule is a list of ICMP fields.",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"fields": {
+				Description: "Fields is a list of ICMP fields.",
+				Type:        "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &ICMPField,
+				},
+			},
+		},
+	}
+
+	ICMPField = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "ICMPField is a ICMP field.",
+		Required: []string{
+			"type",
+		},
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"family": {
+				Description: "Family is a IP address version. Currently, we support `IPv4` " +
+					"and `IPv6`. `IPv4` is set as default.",
+				Type: "string",
+				Enum: []apiextensionsv1beta1.JSON{
+					{
+						Raw: []byte(`"IPv4"`),
+					},
+					{
+						Raw: []byte(`"IPv6"`),
+					},
+				},
+			},
+			"type": {
+				Description: "Type is a ICMP-type. It should be 0-255 (8bit).",
+				Type:        "integer",
+				Format:      "uint8",
+			},
+			"code": {
+				Description: "Code is a ICMP-code of the given Type. If omitted, all codes " +
+					"of the ICMP-type match. It should be 0-254 (8bit).",
+				Type:   "integer",
+				Format: "uint8",
+			},
+		},
+	}
+
+	PortDenyRule = apiextensionsv1beta1.JSONSchemaProps{
+		Description: "PortDenyRule is a list of ports/protocol that should be used for deny " +
+			"policies. This structure lacks the L7Rules since it's not supported in deny " +
+			"policies.",
+		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+			"ports": {
+				Description: "Ports is a list of L4 port/protocol",
+				Type:        "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &PortProtocol,
+				},
+			},
+		},
+	}
+
 	PortRule = apiextensionsv1beta1.JSONSchemaProps{
 		Description: "PortRule is a list of ports/protocol combinations with optional Layer 7 " +
 			"rules which must be met.",
@@ -1035,16 +1682,14 @@ var (
 
 	Rule = apiextensionsv1beta1.JSONSchemaProps{
 		Description: "Rule is a policy rule which must be applied to all endpoints which match " +
-			"the labels contained in the endpointSelector\n\nEach rule is split into an " +
+			"the labels contained in the endpointSelector, or to the host endpoints of all " +
+			"nodes which match the labels contained in the nodeSelector\n\nEach rule is split into an " +
 			"ingress section which contains all rules applicable at ingress, and an egress " +
 			"section applicable at egress. For rule types such as `L4Rule` and `CIDR` which " +
 			"can be applied at both ingress and egress, both ingress and egress side have to " +
 			"either specifically allow the connection or one side has to be omitted.\n\n" +
 			"Either ingress, egress, or both can be provided. If both ingress and egress are " +
 			"omitted, the rule has no effect.",
-		Required: []string{
-			"endpointSelector",
-		},
 		Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
 			"Description": {
 				Description: "Description is a free form string, it can be used by the creator " +
@@ -1060,6 +1705,16 @@ var (
 					Schema: &EgressRule,
 				},
 			},
+			"egressDeny": {
+				Description: "EgressDeny is a list of EgressDenyRule which are enforced at " +
+					"egress. Any rule inserted here will be denied regardless of the allowed " +
+					"egress rules in the 'egress' field. If omitted or empty, this rule does " +
+					"not apply at egress.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &EgressDenyRule,
+				},
+			},
 			"endpointSelector": EndpointSelector,
 			"ingress": {
 				Description: "Ingress is a list of IngressRule which are enforced at ingress. " +
@@ -1069,6 +1724,16 @@ var (
 					Schema: &IngressRule,
 				},
 			},
+			"ingressDeny": {
+				Description: "IngressDeny is a list of IngressDenyRule which are enforced at " +
+					"ingress. Any rule inserted here will be denied regardless of the allowed " +
+					"ingress rules in the 'ingress' field. If omitted or empty, this rule " +
+					"does not apply at ingress.",
+				Type: "array",
+				Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{
+					Schema: &IngressDenyRule,
+				},
+			},
 			"labels": {
 				Description: "Labels is a list of optional strings which can be used to " +
 					"re-identify the rule or to store metadata. It is possible to lookup or " +
@@ -1079,6 +1744,7 @@ var (
 					Schema: &Label,
 				},
 			},
+			"nodeSelector": EndpointSelector,
 		},
 	}
 
@@ -1125,14 +1791,27 @@ func init() {
 
 	ruleProps := Rule.Properties["endpointSelector"]
 	ruleProps.Description = "EndpointSelector selects all endpoints which should be subject " +
-		"to this rule. Cannot be empty."
+		"to this rule. EndpointSelector and NodeSelector cannot be both empty and are " +
+		"mutually exclusive."
 	Rule.Properties["endpointSelector"] = ruleProps
 
+	nodeProps := Rule.Properties["nodeSelector"]
+	nodeProps.Description = "NodeSelector selects all nodes which should be subject to this " +
+		"rule. The rule is then enforced on the host endpoint of every selected node. " +
+		"EndpointSelector and NodeSelector cannot be both empty and are mutually exclusive. " +
+		"Only allowed in a CiliumClusterwideNetworkPolicy as nodes are not namespaced."
+	Rule.Properties["nodeSelector"] = nodeProps
+
 	serviceProps := Service.Properties["k8sServiceSelector"]
 	serviceProps.Description = "K8sServiceSelector selects services by k8s labels. " +
 		"Not supported yet"
 	Service.Properties["k8sServiceSelector"] = serviceProps
 
+	cenpSelectorProps := CENPSpec.Properties["endpointSelector"]
+	cenpSelectorProps.Description = "EndpointSelector selects the pods whose traffic is " +
+		"steered through the gateway node. Only pods in the namespace of the policy are selected."
+	CENPSpec.Properties["endpointSelector"] = cenpSelectorProps
+
 	spec.Description = "Spec is the desired Cilium specific rule specification."
 	spec.Type = "object"
 
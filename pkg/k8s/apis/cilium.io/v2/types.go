@@ -15,6 +15,7 @@
 package v2
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"time"
@@ -183,6 +184,11 @@ func (r *CiliumNetworkPolicy) AnnotationsEquals(o *CiliumNetworkPolicy) bool {
 	return reflect.DeepEqual(r.ObjectMeta.Annotations, o.ObjectMeta.Annotations)
 }
 
+// errNodeSelector is returned when parsing a CiliumNetworkPolicy which selects
+// nodes. Nodes are not namespaced and can only be selected by
+// CiliumClusterwideNetworkPolicies or policies imported through the agent API.
+var errNodeSelector = errors.New("nodeSelector is not allowed in a namespaced policy, use a CiliumClusterwideNetworkPolicy instead")
+
 // Parse parses a CiliumNetworkPolicy and returns a list of cilium policy
 // rules.
 func (r *CiliumNetworkPolicy) Parse() (api.Rules, error) {
@@ -201,6 +207,9 @@ func (r *CiliumNetworkPolicy) Parse() (api.Rules, error) {
 			return nil, fmt.Errorf("Invalid CiliumNetworkPolicy spec: %s", err)
 
 		}
+		if r.Spec.NodeSelector.LabelSelector != nil {
+			return nil, fmt.Errorf("Invalid CiliumNetworkPolicy spec: %s", errNodeSelector)
+		}
 		cr := k8sCiliumUtils.ParseToCiliumRule(namespace, name, uid, r.Spec)
 		retRules = append(retRules, cr)
 	}
@@ -210,6 +219,9 @@ func (r *CiliumNetworkPolicy) Parse() (api.Rules, error) {
 				return nil, fmt.Errorf("Invalid CiliumNetworkPolicy specs: %s", err)
 
 			}
+			if rule.NodeSelector.LabelSelector != nil {
+				return nil, fmt.Errorf("Invalid CiliumNetworkPolicy specs: %s", errNodeSelector)
+			}
 			cr := k8sCiliumUtils.ParseToCiliumRule(namespace, name, uid, rule)
 			retRules = append(retRules, cr)
 		}
@@ -263,6 +275,91 @@ type CiliumNetworkPolicyList struct {
 	Items []CiliumNetworkPolicy `json:"items"`
 }
 
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CiliumClusterwideNetworkPolicy is a cluster-scoped version of
+// CiliumNetworkPolicy. Its endpoint selectors are not restricted to a
+// namespace, and its rules may select nodes with a NodeSelector.
+// +k8s:openapi-gen=false
+type CiliumClusterwideNetworkPolicy struct {
+	// +k8s:openapi-gen=false
+	metav1.TypeMeta `json:",inline"`
+	// +k8s:openapi-gen=false
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec is the desired Cilium specific rule specification.
+	Spec *api.Rule `json:"spec,omitempty"`
+
+	// Specs is a list of desired Cilium specific rule specification.
+	Specs api.Rules `json:"specs,omitempty"`
+}
+
+// errClusterwideDerivative is returned when parsing a
+// CiliumClusterwideNetworkPolicy with a rule which requires a derivative
+// policy. Derivative policies are only created for CiliumNetworkPolicies.
+var errClusterwideDerivative = errors.New("toGroups is not supported in a CiliumClusterwideNetworkPolicy")
+
+// SpecEquals returns true if the spec and specs of r and o are the same.
+func (r *CiliumClusterwideNetworkPolicy) SpecEquals(o *CiliumClusterwideNetworkPolicy) bool {
+	if o == nil {
+		return r == nil
+	}
+	return reflect.DeepEqual(r.Spec, o.Spec) &&
+		reflect.DeepEqual(r.Specs, o.Specs)
+}
+
+// Parse parses a CiliumClusterwideNetworkPolicy and returns a list of cilium
+// policy rules.
+func (r *CiliumClusterwideNetworkPolicy) Parse() (api.Rules, error) {
+	if r.ObjectMeta.Name == "" {
+		return nil, fmt.Errorf("CiliumClusterwideNetworkPolicy must have name")
+	}
+
+	name := r.ObjectMeta.Name
+	uid := r.ObjectMeta.UID
+
+	rules := r.Specs
+	if r.Spec != nil {
+		rules = append(api.Rules{r.Spec}, rules...)
+	}
+
+	retRules := make(api.Rules, 0, len(rules))
+	for _, rule := range rules {
+		if err := rule.Sanitize(); err != nil {
+			return nil, fmt.Errorf("Invalid CiliumClusterwideNetworkPolicy spec: %s", err)
+		}
+		if rule.RequiresDerivative() {
+			return nil, fmt.Errorf("Invalid CiliumClusterwideNetworkPolicy spec: %s", errClusterwideDerivative)
+		}
+		retRules = append(retRules, k8sCiliumUtils.ParseToCiliumClusterwideRule(name, uid, rule))
+	}
+
+	return retRules, nil
+}
+
+// GetIdentityLabels returns all rule labels in the
+// CiliumClusterwideNetworkPolicy.
+func (r *CiliumClusterwideNetworkPolicy) GetIdentityLabels() labels.LabelArray {
+	return k8sCiliumUtils.GetPolicyLabels("", r.ObjectMeta.Name, r.ObjectMeta.UID,
+		k8sCiliumUtils.ResourceTypeCiliumClusterwideNetworkPolicy)
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CiliumClusterwideNetworkPolicyList is a list of
+// CiliumClusterwideNetworkPolicy objects
+// +k8s:openapi-gen=false
+type CiliumClusterwideNetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	// Items is a list of CiliumClusterwideNetworkPolicy
+	Items []CiliumClusterwideNetworkPolicy `json:"items"`
+}
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -312,3 +409,157 @@ type CiliumEndpointList struct {
 	// Items is a list of CiliumEndpoint
 	Items []CiliumEndpoint `json:"items"`
 }
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CiliumEgressNATPolicy steers the traffic of the selected pods towards the
+// selected destinations through a gateway node, which masquerades it with a
+// fixed egress IP.
+// +k8s:openapi-gen=false
+type CiliumEgressNATPolicy struct {
+	// +k8s:openapi-gen=false
+	metav1.TypeMeta `json:",inline"`
+	// +k8s:openapi-gen=false
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec is the desired egress NAT specification.
+	Spec CiliumEgressNATPolicySpec `json:"spec"`
+}
+
+// CiliumEgressNATPolicySpec is the specification of a CiliumEgressNATPolicy
+type CiliumEgressNATPolicySpec struct {
+	// EndpointSelector selects the pods whose traffic is steered through the
+	// gateway node. Only pods in the namespace of the policy are selected.
+	EndpointSelector api.EndpointSelector `json:"endpointSelector"`
+
+	// DestinationCIDRs is the list of destination prefixes the traffic of
+	// the selected pods is steered through the gateway node for.
+	DestinationCIDRs []api.CIDR `json:"destinationCIDRs"`
+
+	// GatewayNode is the name of the node masquerading the traffic.
+	GatewayNode string `json:"gatewayNode"`
+
+	// EgressIP is the source IP the traffic is masqueraded with on the
+	// gateway node. It must be assigned to an interface of the gateway node.
+	EgressIP string `json:"egressIP"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CiliumEgressNATPolicyList is a list of CiliumEgressNATPolicy objects
+// +k8s:openapi-gen=false
+type CiliumEgressNATPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	// Items is a list of CiliumEgressNATPolicy
+	Items []CiliumEgressNATPolicy `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CiliumNode represents a node managed by Cilium. In cluster-pool IPAM mode,
+// it records the pod CIDRs allocated to the node by cilium-operator.
+// +k8s:openapi-gen=false
+type CiliumNode struct {
+	// +k8s:openapi-gen=false
+	metav1.TypeMeta `json:",inline"`
+	// +k8s:openapi-gen=false
+	metav1.ObjectMeta `json:"metadata"`
+
+	// Spec is the specification of the node, written by cilium-operator.
+	Spec NodeSpec `json:"spec"`
+
+	// Status is the status of the node, written by the agent running on
+	// the node.
+	Status NodeStatus `json:"status,omitempty"`
+}
+
+// NodeSpec is the specification of a CiliumNode
+type NodeSpec struct {
+	// IPAM is the address management specification of the node.
+	IPAM IPAMSpec `json:"ipam,omitempty"`
+}
+
+// IPAMSpec is the address management specification of a node
+type IPAMSpec struct {
+	// PodCIDRs is the list of CIDRs allocated to the node, out of which the
+	// agent allocates pod IPs. The first IPv4 and the first IPv6 CIDR are
+	// the primary allocation ranges of the node, further IPv4 CIDRs are
+	// allocated when the node runs out of IPs.
+	PodCIDRs []string `json:"podCIDRs,omitempty"`
+}
+
+// NodeStatus is the status of a CiliumNode
+type NodeStatus struct {
+	// IPAM is the address management status of the node.
+	IPAM IPAMStatus `json:"ipam,omitempty"`
+}
+
+// IPAMStatus is the address management status of a node
+type IPAMStatus struct {
+	// RequestedIPv4PodCIDRs is the number of IPv4 pod CIDRs the agent
+	// requires. The operator allocates additional IPv4 CIDRs to the node
+	// until the node has at least this many.
+	RequestedIPv4PodCIDRs int `json:"requestedIPv4PodCIDRs,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CiliumNodeList is a list of CiliumNode objects
+// +k8s:openapi-gen=false
+type CiliumNodeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	// Items is a list of CiliumNode
+	Items []CiliumNode `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CiliumIdentity is a security identity allocated by Cilium. In CRD identity
+// allocation mode, CiliumIdentity objects replace the kvstore as the backing
+// store of the identity allocator. The name of the object is the numeric
+// identity.
+// +k8s:openapi-gen=false
+type CiliumIdentity struct {
+	// +k8s:openapi-gen=false
+	metav1.TypeMeta `json:",inline"`
+	// +k8s:openapi-gen=false
+	metav1.ObjectMeta `json:"metadata"`
+
+	// SecurityLabels is the set of labels the identity represents, in the
+	// form "source:key" => value.
+	SecurityLabels map[string]string `json:"security-labels"`
+
+	// Status is the status of the identity, written by the agents using
+	// the identity.
+	Status IdentityStatus `json:"status,omitempty"`
+}
+
+// IdentityStatus is the status of a CiliumIdentity
+type IdentityStatus struct {
+	// Nodes is the list of nodes using the identity, along with the time
+	// each node last acquired a reference. An identity which is no longer
+	// used by any node is deleted by cilium-operator.
+	Nodes map[string]metav1.Time `json:"nodes,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CiliumIdentityList is a list of CiliumIdentity objects
+// +k8s:openapi-gen=false
+type CiliumIdentityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	// Items is a list of CiliumIdentity
+	Items []CiliumIdentity `json:"items"`
+}
@@ -387,3 +387,78 @@ func (s *CiliumV2Suite) TestParseRules(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(cnpl, checker.DeepEquals, *expectedPolicyRuleListWithLabel)
 }
+
+func (s *CiliumV2Suite) TestParseNodeSelector(c *C) {
+	nodeRule := &api.Rule{
+		NodeSelector: api.NewESFromLabels(labels.ParseSelectLabel("node-role.kubernetes.io/worker")),
+	}
+
+	cnp := &CiliumNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "host-policy",
+			Namespace: "default",
+		},
+		Spec: nodeRule,
+	}
+	rules, err := cnp.Parse()
+	c.Assert(err, Not(IsNil))
+	c.Assert(rules, IsNil)
+
+	cnp.Spec = nil
+	cnp.Specs = api.Rules{nodeRule}
+	rules, err = cnp.Parse()
+	c.Assert(err, Not(IsNil))
+	c.Assert(rules, IsNil)
+}
+
+func (s *CiliumV2Suite) TestParseClusterwideNodeSelector(c *C) {
+	nodeRule := &api.Rule{
+		NodeSelector: api.NewESFromLabels(labels.ParseSelectLabel("node-role.kubernetes.io/worker")),
+	}
+
+	ccnp := &CiliumClusterwideNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "host-policy",
+			UID:  uuidRule,
+		},
+		Spec: nodeRule,
+	}
+	rules, err := ccnp.Parse()
+	c.Assert(err, IsNil)
+	c.Assert(rules, HasLen, 1)
+	c.Assert(rules[0].NodeSelector.LabelSelector, Not(IsNil))
+	c.Assert(rules[0].Labels, checker.DeepEquals, k8sUtils.GetPolicyLabels(
+		"", "host-policy", uuidRule, k8sUtils.ResourceTypeCiliumClusterwideNetworkPolicy))
+	c.Assert(ccnp.GetIdentityLabels(), checker.DeepEquals, rules[0].Labels)
+
+	ccnp.Spec = nil
+	ccnp.Specs = api.Rules{nodeRule}
+	rules, err = ccnp.Parse()
+	c.Assert(err, IsNil)
+	c.Assert(rules, HasLen, 1)
+}
+
+func (s *CiliumV2Suite) TestParseClusterwideDerivative(c *C) {
+	ccnp := &CiliumClusterwideNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "to-groups",
+		},
+		Spec: &api.Rule{
+			EndpointSelector: api.NewESFromLabels(labels.ParseSelectLabel("role=frontend")),
+			Egress: []api.EgressRule{
+				{
+					ToGroups: []api.ToGroups{
+						{
+							AWS: &api.AWSGroup{
+								Labels: map[string]string{"test": "a"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	rules, err := ccnp.Parse()
+	c.Assert(err, Not(IsNil))
+	c.Assert(rules, IsNil)
+}
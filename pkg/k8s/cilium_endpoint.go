@@ -0,0 +1,63 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"net"
+
+	"github.com/cilium/cilium/pkg/identity"
+	cilium_v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+)
+
+// CiliumEndpointIPIdentity is the security identity of the IPs of a
+// CiliumEndpoint together with the IP of the node hosting the endpoint
+type CiliumEndpointIPIdentity struct {
+	IPs    []string
+	ID     identity.NumericIdentity
+	HostIP net.IP
+}
+
+// ParseCiliumEndpointIPIdentity returns the IPs, the security identity and the
+// node IP of the given CiliumEndpoint. It returns nil if the CiliumEndpoint
+// does not carry an identity or IP yet.
+func ParseCiliumEndpointIPIdentity(cep *cilium_v2.CiliumEndpoint) *CiliumEndpointIPIdentity {
+	status := cep.Status.Status
+	if status == nil || status.Identity == nil || status.Networking == nil {
+		return nil
+	}
+
+	ipID := &CiliumEndpointIPIdentity{
+		ID: identity.NumericIdentity(status.Identity.ID),
+	}
+	for _, pair := range status.Networking.Addressing {
+		if pair == nil {
+			continue
+		}
+		for _, s := range []string{pair.IPV4, pair.IPV6} {
+			if ip := net.ParseIP(s); ip != nil {
+				ipID.IPs = append(ipID.IPs, ip.String())
+			}
+		}
+	}
+	if len(ipID.IPs) == 0 {
+		return nil
+	}
+
+	if addr := status.Networking.HostAddressing; addr != nil && addr.IPV4 != nil {
+		ipID.HostIP = net.ParseIP(addr.IPV4.IP)
+	}
+
+	return ipID
+}
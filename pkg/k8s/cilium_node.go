@@ -0,0 +1,149 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/cilium/cilium/pkg/backoff"
+	cilium_v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/node"
+	"github.com/cilium/cilium/pkg/option"
+
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ParseCiliumNodePodCIDRs returns the IPv4 and IPv6 pod CIDRs allocated to
+// the given CiliumNode in the order of allocation. Invalid CIDRs are skipped.
+func ParseCiliumNodePodCIDRs(cn *cilium_v2.CiliumNode) (ipv4, ipv6 []*net.IPNet) {
+	for _, s := range cn.Spec.IPAM.PodCIDRs {
+		_, cidr, err := net.ParseCIDR(s)
+		if err != nil {
+			log.WithError(err).WithField(logfields.CIDR, s).Warning("Ignoring invalid pod CIDR of CiliumNode")
+			continue
+		}
+		if cidr.IP.To4() != nil {
+			ipv4 = append(ipv4, cidr)
+		} else {
+			ipv6 = append(ipv6, cidr)
+		}
+	}
+	return
+}
+
+// getOrCreateCiliumNode returns the CiliumNode with the given name, creating
+// it if it does not exist yet. The CiliumNode is owned by the Kubernetes
+// node of the same name so it is removed along with the node.
+func getOrCreateCiliumNode(nodeName string) (*cilium_v2.CiliumNode, error) {
+	cn, err := CiliumClient().CiliumV2().CiliumNodes().Get(nodeName, metav1.GetOptions{})
+	if err == nil || !errors.IsNotFound(err) {
+		return cn, err
+	}
+
+	cn = &cilium_v2.CiliumNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: nodeName,
+		},
+	}
+
+	if k8sNode, err := GetNode(Client(), nodeName); err == nil {
+		cn.ObjectMeta.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "v1",
+			Kind:       "Node",
+			Name:       k8sNode.Name,
+			UID:        k8sNode.UID,
+		}}
+	}
+
+	log.WithField(logfields.NodeName, nodeName).Info("Creating CiliumNode resource")
+	return CiliumClient().CiliumV2().CiliumNodes().Create(cn)
+}
+
+// waitForCiliumNodeAllocation creates the CiliumNode resource of the local
+// node if needed and waits for cilium-operator to allocate pod CIDRs of all
+// enabled address families to it
+func waitForCiliumNodeAllocation(nodeName string) (*cilium_v2.CiliumNode, error) {
+	backoff := backoff.Exponential{
+		Min:    time.Duration(200) * time.Millisecond,
+		Factor: 2.0,
+		Name:   "k8s-ciliumnode-allocation",
+	}
+
+	for retry := 0; retry < nodeRetrievalMaxRetries; retry++ {
+		cn, err := getOrCreateCiliumNode(nodeName)
+		if err == nil {
+			ipv4, ipv6 := ParseCiliumNodePodCIDRs(cn)
+			switch {
+			case option.Config.EnableIPv4 && len(ipv4) == 0:
+				err = fmt.Errorf("IPv4 pod CIDR not allocated yet")
+			case option.Config.EnableIPv6 && len(ipv6) == 0:
+				err = fmt.Errorf("IPv6 pod CIDR not allocated yet")
+			default:
+				return cn, nil
+			}
+		}
+
+		log.WithError(err).Warning("Waiting for pod CIDR allocation by cilium-operator")
+		backoff.Wait()
+	}
+
+	return nil, fmt.Errorf("no pod CIDRs allocated to CiliumNode %s", nodeName)
+}
+
+// useCiliumNodeAllocation retrieves the pod CIDRs allocated to the local node
+// by cilium-operator and uses the first CIDR of each address family as the
+// allocation range of the node. Additional IPv4 CIDRs are consumed once IPAM
+// has been initialized.
+func useCiliumNodeAllocation(nodeName string) error {
+	restConfig, err := CreateConfig()
+	if err != nil {
+		return fmt.Errorf("unable to create rest configuration: %s", err)
+	}
+
+	apiextensionsclientset, err := apiextensionsclient.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("unable to create rest configuration for k8s CRD: %s", err)
+	}
+
+	// The CiliumNode CRD must exist before the CiliumNode of the local node
+	// can be created
+	if err := cilium_v2.CreateCustomResourceDefinitions(apiextensionsclientset); err != nil {
+		return fmt.Errorf("unable to create custom resource definitions: %s", err)
+	}
+
+	cn, err := waitForCiliumNodeAllocation(nodeName)
+	if err != nil {
+		return err
+	}
+
+	ipv4, ipv6 := ParseCiliumNodePodCIDRs(cn)
+	if len(ipv4) > 0 {
+		log.WithField(logfields.V4Prefix, ipv4[0]).Info("Using IPv4 pod CIDR allocated by cilium-operator for ipv4-range")
+		node.SetIPv4AllocRange(ipv4[0])
+	}
+	if len(ipv6) > 0 {
+		log.WithField(logfields.V6Prefix, ipv6[0]).Info("Using IPv6 pod CIDR allocated by cilium-operator for ipv6-range")
+		if err := node.SetIPv6NodeRange(ipv6[0]); err != nil {
+			return fmt.Errorf("unable to use IPv6 pod CIDR %s: %s", ipv6[0], err)
+		}
+	}
+
+	return nil
+}
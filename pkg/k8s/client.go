@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/cilium/cilium/api/v1/models"
+	clientset "github.com/cilium/cilium/pkg/k8s/client/clientset/versioned"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 
 	go_version "github.com/hashicorp/go-version"
@@ -37,6 +38,9 @@ var (
 
 	// k8sCli is the default client.
 	k8sCli = &K8sClient{}
+
+	// ciliumCli is the default client for Cilium resources.
+	ciliumCli clientset.Interface
 )
 
 // CreateConfig creates a rest.Config for a given endpoint using a kubeconfig file.
@@ -138,6 +142,11 @@ func Client() *K8sClient {
 	return k8sCli
 }
 
+// CiliumClient returns the default client for Cilium resources.
+func CiliumClient() clientset.Interface {
+	return ciliumCli
+}
+
 func createDefaultClient() error {
 	restConfig, err := CreateConfig()
 	if err != nil {
@@ -151,5 +160,12 @@ func createDefaultClient() error {
 
 	k8sCli.Interface = createdK8sClient
 
+	createdCiliumClient, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("unable to create cilium k8s client: %s", err)
+	}
+
+	ciliumCli = createdCiliumClient
+
 	return nil
 }
@@ -25,8 +25,12 @@ import (
 
 type CiliumV2Interface interface {
 	RESTClient() rest.Interface
+	CiliumClusterwideNetworkPoliciesGetter
+	CiliumEgressNATPoliciesGetter
 	CiliumEndpointsGetter
+	CiliumIdentitiesGetter
 	CiliumNetworkPoliciesGetter
+	CiliumNodesGetter
 }
 
 // CiliumV2Client is used to interact with features provided by the cilium.io group.
@@ -34,14 +38,30 @@ type CiliumV2Client struct {
 	restClient rest.Interface
 }
 
+func (c *CiliumV2Client) CiliumClusterwideNetworkPolicies() CiliumClusterwideNetworkPolicyInterface {
+	return newCiliumClusterwideNetworkPolicies(c)
+}
+
+func (c *CiliumV2Client) CiliumEgressNATPolicies(namespace string) CiliumEgressNATPolicyInterface {
+	return newCiliumEgressNATPolicies(c, namespace)
+}
+
 func (c *CiliumV2Client) CiliumEndpoints(namespace string) CiliumEndpointInterface {
 	return newCiliumEndpoints(c, namespace)
 }
 
+func (c *CiliumV2Client) CiliumIdentities() CiliumIdentityInterface {
+	return newCiliumIdentities(c)
+}
+
 func (c *CiliumV2Client) CiliumNetworkPolicies(namespace string) CiliumNetworkPolicyInterface {
 	return newCiliumNetworkPolicies(c, namespace)
 }
 
+func (c *CiliumV2Client) CiliumNodes() CiliumNodeInterface {
+	return newCiliumNodes(c)
+}
+
 // NewForConfig creates a new CiliumV2Client for the given config.
 func NewForConfig(c *rest.Config) (*CiliumV2Client, error) {
 	config := *c
@@ -26,14 +26,30 @@ type FakeCiliumV2 struct {
 	*testing.Fake
 }
 
+func (c *FakeCiliumV2) CiliumClusterwideNetworkPolicies() v2.CiliumClusterwideNetworkPolicyInterface {
+	return &FakeCiliumClusterwideNetworkPolicies{c}
+}
+
+func (c *FakeCiliumV2) CiliumEgressNATPolicies(namespace string) v2.CiliumEgressNATPolicyInterface {
+	return &FakeCiliumEgressNATPolicies{c, namespace}
+}
+
 func (c *FakeCiliumV2) CiliumEndpoints(namespace string) v2.CiliumEndpointInterface {
 	return &FakeCiliumEndpoints{c, namespace}
 }
 
+func (c *FakeCiliumV2) CiliumIdentities() v2.CiliumIdentityInterface {
+	return &FakeCiliumIdentities{c}
+}
+
 func (c *FakeCiliumV2) CiliumNetworkPolicies(namespace string) v2.CiliumNetworkPolicyInterface {
 	return &FakeCiliumNetworkPolicies{c, namespace}
 }
 
+func (c *FakeCiliumV2) CiliumNodes() v2.CiliumNodeInterface {
+	return &FakeCiliumNodes{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeCiliumV2) RESTClient() rest.Interface {
@@ -16,6 +16,14 @@
 
 package v2
 
+type CiliumClusterwideNetworkPolicyExpansion interface{}
+
+type CiliumEgressNATPolicyExpansion interface{}
+
 type CiliumEndpointExpansion interface{}
 
+type CiliumIdentityExpansion interface{}
+
 type CiliumNetworkPolicyExpansion interface{}
+
+type CiliumNodeExpansion interface{}
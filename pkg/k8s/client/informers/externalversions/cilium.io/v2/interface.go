@@ -22,10 +22,18 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// CiliumClusterwideNetworkPolicies returns a CiliumClusterwideNetworkPolicyInformer.
+	CiliumClusterwideNetworkPolicies() CiliumClusterwideNetworkPolicyInformer
+	// CiliumEgressNATPolicies returns a CiliumEgressNATPolicyInformer.
+	CiliumEgressNATPolicies() CiliumEgressNATPolicyInformer
 	// CiliumEndpoints returns a CiliumEndpointInformer.
 	CiliumEndpoints() CiliumEndpointInformer
+	// CiliumIdentities returns a CiliumIdentityInformer.
+	CiliumIdentities() CiliumIdentityInformer
 	// CiliumNetworkPolicies returns a CiliumNetworkPolicyInformer.
 	CiliumNetworkPolicies() CiliumNetworkPolicyInformer
+	// CiliumNodes returns a CiliumNodeInformer.
+	CiliumNodes() CiliumNodeInformer
 }
 
 type version struct {
@@ -39,12 +47,32 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// CiliumClusterwideNetworkPolicies returns a CiliumClusterwideNetworkPolicyInformer.
+func (v *version) CiliumClusterwideNetworkPolicies() CiliumClusterwideNetworkPolicyInformer {
+	return &ciliumClusterwideNetworkPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// CiliumEgressNATPolicies returns a CiliumEgressNATPolicyInformer.
+func (v *version) CiliumEgressNATPolicies() CiliumEgressNATPolicyInformer {
+	return &ciliumEgressNATPolicyInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // CiliumEndpoints returns a CiliumEndpointInformer.
 func (v *version) CiliumEndpoints() CiliumEndpointInformer {
 	return &ciliumEndpointInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// CiliumIdentities returns a CiliumIdentityInformer.
+func (v *version) CiliumIdentities() CiliumIdentityInformer {
+	return &ciliumIdentityInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // CiliumNetworkPolicies returns a CiliumNetworkPolicyInformer.
 func (v *version) CiliumNetworkPolicies() CiliumNetworkPolicyInformer {
 	return &ciliumNetworkPolicyInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// CiliumNodes returns a CiliumNodeInformer.
+func (v *version) CiliumNodes() CiliumNodeInformer {
+	return &ciliumNodeInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -16,6 +16,18 @@
 
 package v2
 
+// CiliumClusterwideNetworkPolicyListerExpansion allows custom methods to be added to
+// CiliumClusterwideNetworkPolicyLister.
+type CiliumClusterwideNetworkPolicyListerExpansion interface{}
+
+// CiliumEgressNATPolicyListerExpansion allows custom methods to be added to
+// CiliumEgressNATPolicyLister.
+type CiliumEgressNATPolicyListerExpansion interface{}
+
+// CiliumEgressNATPolicyNamespaceListerExpansion allows custom methods to be added to
+// CiliumEgressNATPolicyNamespaceLister.
+type CiliumEgressNATPolicyNamespaceListerExpansion interface{}
+
 // CiliumEndpointListerExpansion allows custom methods to be added to
 // CiliumEndpointLister.
 type CiliumEndpointListerExpansion interface{}
@@ -24,6 +36,10 @@ type CiliumEndpointListerExpansion interface{}
 // CiliumEndpointNamespaceLister.
 type CiliumEndpointNamespaceListerExpansion interface{}
 
+// CiliumIdentityListerExpansion allows custom methods to be added to
+// CiliumIdentityLister.
+type CiliumIdentityListerExpansion interface{}
+
 // CiliumNetworkPolicyListerExpansion allows custom methods to be added to
 // CiliumNetworkPolicyLister.
 type CiliumNetworkPolicyListerExpansion interface{}
@@ -31,3 +47,7 @@ type CiliumNetworkPolicyListerExpansion interface{}
 // CiliumNetworkPolicyNamespaceListerExpansion allows custom methods to be added to
 // CiliumNetworkPolicyNamespaceLister.
 type CiliumNetworkPolicyNamespaceListerExpansion interface{}
+
+// CiliumNodeListerExpansion allows custom methods to be added to
+// CiliumNodeLister.
+type CiliumNodeListerExpansion interface{}
@@ -37,6 +37,10 @@ type Endpoints struct {
 	// the map is the backend IP in string form. The value defines the list
 	// of ports for that backend IP in the form of a PortConfiguration.
 	Backends map[string]service.PortConfiguration
+
+	// NodeNames maps backend IPs to the name of the node the backend is
+	// running on. Backends with an unknown node are not included.
+	NodeNames map[string]string
 }
 
 // String returns the string representation of an endpoints resource, with
@@ -89,9 +93,25 @@ func (e *Endpoints) DeepEquals(o *Endpoints) bool {
 		}
 	}
 
+	if len(e.NodeNames) != len(o.NodeNames) {
+		return false
+	}
+	for ip, nodeName := range e.NodeNames {
+		if oNodeName, ok := o.NodeNames[ip]; !ok || nodeName != oNodeName {
+			return false
+		}
+	}
+
 	return true
 }
 
+// IsLocalBackend returns true if the backend with the given IP is running on
+// the node with the given name
+func (e *Endpoints) IsLocalBackend(ip, nodeName string) bool {
+	backendNode, ok := e.NodeNames[ip]
+	return ok && backendNode == nodeName
+}
+
 // CIDRPrefixes returns the endpoint's backends as a slice of IPNets.
 func (e *Endpoints) CIDRPrefixes() ([]*net.IPNet, error) {
 	prefixes := make([]string, len(e.Backends))
@@ -129,6 +149,13 @@ func ParseEndpoints(ep *v1.Endpoints) (ServiceID, *Endpoints) {
 				endpoints.Backends[addr.IP] = backend
 			}
 
+			if addr.NodeName != nil && *addr.NodeName != "" {
+				if endpoints.NodeNames == nil {
+					endpoints.NodeNames = map[string]string{}
+				}
+				endpoints.NodeNames[addr.IP] = *addr.NodeName
+			}
+
 			for _, port := range sub.Ports {
 				lbPort := loadbalancer.NewL4Addr(loadbalancer.L4Type(port.Protocol), uint16(port.Port))
 				backend[port.Name] = lbPort
@@ -424,6 +424,51 @@ func (s *K8sSuite) Test_parseK8sEPv1(c *check.C) {
 				return svcEP
 			},
 		},
+		{
+			name: "endpoint with addresses on known nodes",
+			setupArgs: func() args {
+				nodeName := "node1"
+				return args{
+					eps: &v1.Endpoints{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "foo",
+							Namespace: "bar",
+						},
+						Subsets: []v1.EndpointSubset{
+							{
+								Addresses: []v1.EndpointAddress{
+									{
+										IP:       "172.0.0.1",
+										NodeName: &nodeName,
+									},
+									{
+										IP: "172.0.0.2",
+									},
+								},
+								Ports: []v1.EndpointPort{
+									{
+										Name:     "http-test-svc",
+										Port:     8080,
+										Protocol: v1.ProtocolTCP,
+									},
+								},
+							},
+						},
+					},
+				}
+			},
+			setupWanted: func() *Endpoints {
+				svcEP := newEndpoints()
+				svcEP.Backends["172.0.0.1"] = service.PortConfiguration{
+					"http-test-svc": loadbalancer.NewL4Addr(loadbalancer.TCP, 8080),
+				}
+				svcEP.Backends["172.0.0.2"] = service.PortConfiguration{
+					"http-test-svc": loadbalancer.NewL4Addr(loadbalancer.TCP, 8080),
+				}
+				svcEP.NodeNames = map[string]string{"172.0.0.1": "node1"}
+				return svcEP
+			},
+		},
 	}
 	for _, tt := range tests {
 		args := tt.setupArgs()
@@ -468,3 +513,12 @@ func (s *K8sSuite) TestEndpointsString(c *check.C) {
 	_, ep := ParseEndpoints(endpoints)
 	c.Assert(ep.String(), check.Equals, "172.0.0.1:8080/TCP,172.0.0.1:8081/TCP,172.0.0.2:8080/TCP,172.0.0.2:8081/TCP")
 }
+
+func (s *K8sSuite) TestEndpointsIsLocalBackend(c *check.C) {
+	ep := newEndpoints()
+	ep.NodeNames = map[string]string{"172.0.0.1": "node1"}
+	c.Assert(ep.IsLocalBackend("172.0.0.1", "node1"), check.Equals, true)
+	c.Assert(ep.IsLocalBackend("172.0.0.1", "node2"), check.Equals, false)
+	c.Assert(ep.IsLocalBackend("172.0.0.2", "node1"), check.Equals, false)
+	c.Assert(ep.DeepEquals(newEndpoints()), check.Equals, false)
+}
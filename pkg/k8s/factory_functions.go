@@ -75,6 +75,38 @@ func init() {
 		equalV2CNP,
 	)
 
+	utils.RegisterObject(
+		&cilium_v2.CiliumClusterwideNetworkPolicy{},
+		"ciliumclusterwidenetworkpolicies",
+		copyObjToV2CCNP,
+		listV2CCNP,
+		equalV2CCNP,
+	)
+
+	utils.RegisterObject(
+		&cilium_v2.CiliumEgressNATPolicy{},
+		"ciliumegressnatpolicies",
+		copyObjToV2CENP,
+		listV2CENP,
+		equalV2CENP,
+	)
+
+	utils.RegisterObject(
+		&cilium_v2.CiliumNode{},
+		"ciliumnodes",
+		copyObjToV2CN,
+		listV2CN,
+		equalV2CN,
+	)
+
+	utils.RegisterObject(
+		&cilium_v2.CiliumEndpoint{},
+		"ciliumendpoints",
+		copyObjToV2CEP,
+		listV2CEP,
+		equalV2CEP,
+	)
+
 	utils.RegisterObject(
 		&v1.Pod{},
 		"pods",
@@ -150,6 +182,46 @@ func copyObjToV2CNP(obj interface{}) meta_v1.Object {
 	return cnp.DeepCopy()
 }
 
+func copyObjToV2CCNP(obj interface{}) meta_v1.Object {
+	ccnp, ok := obj.(*cilium_v2.CiliumClusterwideNetworkPolicy)
+	if !ok {
+		log.WithField(logfields.Object, logfields.Repr(obj)).
+			Warn("Ignoring invalid k8s v2 CiliumClusterwideNetworkPolicy")
+		return nil
+	}
+	return ccnp.DeepCopy()
+}
+
+func copyObjToV2CENP(obj interface{}) meta_v1.Object {
+	cenp, ok := obj.(*cilium_v2.CiliumEgressNATPolicy)
+	if !ok {
+		log.WithField(logfields.Object, logfields.Repr(obj)).
+			Warn("Ignoring invalid k8s v2 CiliumEgressNATPolicy")
+		return nil
+	}
+	return cenp.DeepCopy()
+}
+
+func copyObjToV2CN(obj interface{}) meta_v1.Object {
+	cn, ok := obj.(*cilium_v2.CiliumNode)
+	if !ok {
+		log.WithField(logfields.Object, logfields.Repr(obj)).
+			Warn("Ignoring invalid k8s v2 CiliumNode")
+		return nil
+	}
+	return cn.DeepCopy()
+}
+
+func copyObjToV2CEP(obj interface{}) meta_v1.Object {
+	cep, ok := obj.(*cilium_v2.CiliumEndpoint)
+	if !ok {
+		log.WithField(logfields.Object, logfields.Repr(obj)).
+			Warn("Ignoring invalid k8s v2 CiliumEndpoint")
+		return nil
+	}
+	return cep.DeepCopy()
+}
+
 func copyObjToV1Pod(obj interface{}) meta_v1.Object {
 	pod, ok := obj.(*v1.Pod)
 	if !ok {
@@ -310,6 +382,110 @@ func listV2CNP(client interface{}) func() (versioned.Map, error) {
 	}
 }
 
+func listV2CCNP(client interface{}) func() (versioned.Map, error) {
+	k8sClient, ok := client.(versionedClient.Interface)
+	if !ok {
+		log.Panicf("Invalid resource type %s: expecting 'versionedClient.Interface'", reflect.TypeOf(client))
+	}
+	return func() (versioned.Map, error) {
+		m := versioned.NewMap()
+		// Limit the number of elements to avoid network congestion every N minutes
+		lo := meta_v1.ListOptions{Limit: 50}
+		for {
+			list, err := k8sClient.CiliumV2().CiliumClusterwideNetworkPolicies().List(lo)
+			if err != nil {
+				return nil, err
+			}
+			lo.Continue = list.Continue
+			for i := range list.Items {
+				m.Add(utils.GetVerStructFrom(&list.Items[i]))
+			}
+			if lo.Continue == "" {
+				break
+			}
+		}
+		return m, nil
+	}
+}
+
+func listV2CENP(client interface{}) func() (versioned.Map, error) {
+	k8sClient, ok := client.(versionedClient.Interface)
+	if !ok {
+		log.Panicf("Invalid resource type %s: expecting 'versionedClient.Interface'", reflect.TypeOf(client))
+	}
+	return func() (versioned.Map, error) {
+		m := versioned.NewMap()
+		// Limit the number of elements to avoid network congestion every N minutes
+		lo := meta_v1.ListOptions{Limit: 50}
+		for {
+			list, err := k8sClient.CiliumV2().CiliumEgressNATPolicies("").List(lo)
+			if err != nil {
+				return nil, err
+			}
+			lo.Continue = list.Continue
+			for i := range list.Items {
+				m.Add(utils.GetVerStructFrom(&list.Items[i]))
+			}
+			if lo.Continue == "" {
+				break
+			}
+		}
+		return m, nil
+	}
+}
+
+func listV2CN(client interface{}) func() (versioned.Map, error) {
+	k8sClient, ok := client.(versionedClient.Interface)
+	if !ok {
+		log.Panicf("Invalid resource type %s: expecting 'versionedClient.Interface'", reflect.TypeOf(client))
+	}
+	return func() (versioned.Map, error) {
+		m := versioned.NewMap()
+		// Limit the number of elements to avoid network congestion every N minutes
+		lo := meta_v1.ListOptions{Limit: 50}
+		for {
+			list, err := k8sClient.CiliumV2().CiliumNodes().List(lo)
+			if err != nil {
+				return nil, err
+			}
+			lo.Continue = list.Continue
+			for i := range list.Items {
+				m.Add(utils.GetVerStructFrom(&list.Items[i]))
+			}
+			if lo.Continue == "" {
+				break
+			}
+		}
+		return m, nil
+	}
+}
+
+func listV2CEP(client interface{}) func() (versioned.Map, error) {
+	k8sClient, ok := client.(versionedClient.Interface)
+	if !ok {
+		log.Panicf("Invalid resource type %s: expecting 'versionedClient.Interface'", reflect.TypeOf(client))
+	}
+	return func() (versioned.Map, error) {
+		m := versioned.NewMap()
+		// Limit the number of elements to avoid network congestion every N minutes
+		lo := meta_v1.ListOptions{Limit: 50}
+		for {
+			list, err := k8sClient.CiliumV2().CiliumEndpoints("").List(lo)
+			if err != nil {
+				return nil, err
+			}
+			lo.Continue = list.Continue
+			for i := range list.Items {
+				m.Add(utils.GetVerStructFrom(&list.Items[i]))
+			}
+			if lo.Continue == "" {
+				break
+			}
+		}
+		return m, nil
+	}
+}
+
 func listV1Pod(client interface{}) func() (versioned.Map, error) {
 	k8sClient, ok := client.(kubernetes.Interface)
 	if !ok {
@@ -424,6 +600,24 @@ func equalV1Services(o1, o2 interface{}) bool {
 		return false
 	}
 
+	// Node ports, external IPs and load balancer ingress IPs are
+	// translated into additional frontends.
+	if !reflect.DeepEqual(svc1.Spec.Ports, svc2.Spec.Ports) ||
+		!reflect.DeepEqual(svc1.Spec.ExternalIPs, svc2.Spec.ExternalIPs) ||
+		!reflect.DeepEqual(svc1.Status.LoadBalancer, svc2.Status.LoadBalancer) {
+		return false
+	}
+
+	if svc1.Spec.ExternalTrafficPolicy != svc2.Spec.ExternalTrafficPolicy ||
+		svc1.Spec.HealthCheckNodePort != svc2.Spec.HealthCheckNodePort {
+		return false
+	}
+
+	if svc1.Spec.SessionAffinity != svc2.Spec.SessionAffinity ||
+		!reflect.DeepEqual(svc1.Spec.SessionAffinityConfig, svc2.Spec.SessionAffinityConfig) {
+		return false
+	}
+
 	clusterIP := net.ParseIP(svc1.Spec.ClusterIP)
 	headless := false
 	if strings.ToLower(svc1.Spec.ClusterIP) == "none" {
@@ -506,6 +700,72 @@ func equalV2CNP(o1, o2 interface{}) bool {
 		reflect.DeepEqual(cnp1.Specs, cnp2.Specs)
 }
 
+func equalV2CCNP(o1, o2 interface{}) bool {
+	ccnp1, ok := o1.(*cilium_v2.CiliumClusterwideNetworkPolicy)
+	if !ok {
+		log.Panicf("Invalid resource type %q, expecting *cilium_v2.CiliumClusterwideNetworkPolicy", reflect.TypeOf(o1))
+		return false
+	}
+	ccnp2, ok := o2.(*cilium_v2.CiliumClusterwideNetworkPolicy)
+	if !ok {
+		log.Panicf("Invalid resource type %q, expecting *cilium_v2.CiliumClusterwideNetworkPolicy", reflect.TypeOf(o2))
+		return false
+	}
+	return ccnp1.Name == ccnp2.Name &&
+		reflect.DeepEqual(ccnp1.Spec, ccnp2.Spec) &&
+		reflect.DeepEqual(ccnp1.Specs, ccnp2.Specs)
+}
+
+func equalV2CENP(o1, o2 interface{}) bool {
+	cenp1, ok := o1.(*cilium_v2.CiliumEgressNATPolicy)
+	if !ok {
+		log.Panicf("Invalid resource type %q, expecting *cilium_v2.CiliumEgressNATPolicy", reflect.TypeOf(o1))
+		return false
+	}
+	cenp2, ok := o2.(*cilium_v2.CiliumEgressNATPolicy)
+	if !ok {
+		log.Panicf("Invalid resource type %q, expecting *cilium_v2.CiliumEgressNATPolicy", reflect.TypeOf(o2))
+		return false
+	}
+	return cenp1.Name == cenp2.Name &&
+		cenp1.Namespace == cenp2.Namespace &&
+		reflect.DeepEqual(cenp1.Spec, cenp2.Spec)
+}
+
+func equalV2CN(o1, o2 interface{}) bool {
+	cn1, ok := o1.(*cilium_v2.CiliumNode)
+	if !ok {
+		log.Panicf("Invalid resource type %q, expecting *cilium_v2.CiliumNode", reflect.TypeOf(o1))
+		return false
+	}
+	cn2, ok := o2.(*cilium_v2.CiliumNode)
+	if !ok {
+		log.Panicf("Invalid resource type %q, expecting *cilium_v2.CiliumNode", reflect.TypeOf(o2))
+		return false
+	}
+	return cn1.Name == cn2.Name &&
+		reflect.DeepEqual(cn1.Spec, cn2.Spec) &&
+		reflect.DeepEqual(cn1.Status, cn2.Status)
+}
+
+func equalV2CEP(o1, o2 interface{}) bool {
+	cep1, ok := o1.(*cilium_v2.CiliumEndpoint)
+	if !ok {
+		log.Panicf("Invalid resource type %q, expecting *cilium_v2.CiliumEndpoint", reflect.TypeOf(o1))
+		return false
+	}
+	cep2, ok := o2.(*cilium_v2.CiliumEndpoint)
+	if !ok {
+		log.Panicf("Invalid resource type %q, expecting *cilium_v2.CiliumEndpoint", reflect.TypeOf(o2))
+		return false
+	}
+	// We only care about the IPs, the identity and the node of the
+	// endpoint, the rest of the status changes frequently.
+	return cep1.Name == cep2.Name &&
+		cep1.Namespace == cep2.Namespace &&
+		reflect.DeepEqual(ParseCiliumEndpointIPIdentity(cep1), ParseCiliumEndpointIPIdentity(cep2))
+}
+
 func equalV1Pod(o1, o2 interface{}) bool {
 	pod1, ok := o1.(*v1.Pod)
 	if !ok {
@@ -17,7 +17,10 @@
 package k8s
 
 import (
+	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/pkg/annotation"
+	"github.com/cilium/cilium/pkg/checker"
+	"github.com/cilium/cilium/pkg/identity"
 	"github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
 	"github.com/cilium/cilium/pkg/labels"
 	"github.com/cilium/cilium/pkg/policy/api"
@@ -175,6 +178,148 @@ func (s *K8sSuite) Test_equalV2CNP(c *C) {
 	}
 }
 
+func (s *K8sSuite) Test_equalV2CCNP(c *C) {
+	type args struct {
+		o1 *v2.CiliumClusterwideNetworkPolicy
+		o2 *v2.CiliumClusterwideNetworkPolicy
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "CCNP with the same name",
+			args: args{
+				o1: &v2.CiliumClusterwideNetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "rule1",
+					},
+				},
+				o2: &v2.CiliumClusterwideNetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "rule1",
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "CCNP with the different spec",
+			args: args{
+				o1: &v2.CiliumClusterwideNetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "rule1",
+					},
+					Spec: &api.Rule{
+						NodeSelector: api.NewESFromLabels(labels.NewLabel("foo", "bar", "k8s")),
+					},
+				},
+				o2: &v2.CiliumClusterwideNetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "rule1",
+					},
+					Spec: nil,
+				},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		got := equalV2CCNP(tt.args.o1, tt.args.o2)
+		c.Assert(got, Equals, tt.want, Commentf("Test Name: %s", tt.name))
+	}
+}
+
+func (s *K8sSuite) Test_equalV2CEP(c *C) {
+	newCEP := func(id int64, ipv4, hostIP string, state string) *v2.CiliumEndpoint {
+		return &v2.CiliumEndpoint{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pod1",
+				Namespace: "default",
+			},
+			Status: v2.CiliumEndpointDetail{
+				Status: &models.EndpointStatus{
+					Identity: &models.Identity{ID: id},
+					Networking: &models.EndpointNetworking{
+						Addressing: []*models.AddressPair{{IPV4: ipv4}},
+						HostAddressing: &models.NodeAddressing{
+							IPV4: &models.NodeAddressingElement{IP: hostIP},
+						},
+					},
+					State: models.EndpointState(state),
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		o1   *v2.CiliumEndpoint
+		o2   *v2.CiliumEndpoint
+		want bool
+	}{
+		{
+			name: "CEP with the same identity and IPs",
+			o1:   newCEP(1000, "10.0.0.1", "192.168.0.1", "ready"),
+			o2:   newCEP(1000, "10.0.0.1", "192.168.0.1", "regenerating"),
+			want: true,
+		},
+		{
+			name: "CEP with a different identity",
+			o1:   newCEP(1000, "10.0.0.1", "192.168.0.1", "ready"),
+			o2:   newCEP(1001, "10.0.0.1", "192.168.0.1", "ready"),
+			want: false,
+		},
+		{
+			name: "CEP with a different IP",
+			o1:   newCEP(1000, "10.0.0.1", "192.168.0.1", "ready"),
+			o2:   newCEP(1000, "10.0.0.2", "192.168.0.1", "ready"),
+			want: false,
+		},
+		{
+			name: "CEP with a different node IP",
+			o1:   newCEP(1000, "10.0.0.1", "192.168.0.1", "ready"),
+			o2:   newCEP(1000, "10.0.0.1", "192.168.0.2", "ready"),
+			want: false,
+		},
+		{
+			name: "CEP without an IP",
+			o1:   newCEP(1000, "", "192.168.0.1", "ready"),
+			o2:   newCEP(1001, "<nil>", "192.168.0.1", "ready"),
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		got := equalV2CEP(tt.o1, tt.o2)
+		c.Assert(got, Equals, tt.want, Commentf("Test Name: %s", tt.name))
+	}
+}
+
+func (s *K8sSuite) TestParseCiliumEndpointIPIdentity(c *C) {
+	cep := &v2.CiliumEndpoint{
+		Status: v2.CiliumEndpointDetail{
+			Status: &models.EndpointStatus{
+				Identity: &models.Identity{ID: 1000},
+				Networking: &models.EndpointNetworking{
+					Addressing: []*models.AddressPair{{IPV4: "10.0.0.1", IPV6: "f00d::1"}},
+					HostAddressing: &models.NodeAddressing{
+						IPV4: &models.NodeAddressingElement{IP: "192.168.0.1"},
+					},
+				},
+			},
+		},
+	}
+	ipID := ParseCiliumEndpointIPIdentity(cep)
+	c.Assert(ipID, Not(IsNil))
+	c.Assert(ipID.IPs, checker.DeepEquals, []string{"10.0.0.1", "f00d::1"})
+	c.Assert(ipID.ID, Equals, identity.NumericIdentity(1000))
+	c.Assert(ipID.HostIP.String(), Equals, "192.168.0.1")
+
+	cep.Status.Status.Identity = nil
+	c.Assert(ParseCiliumEndpointIPIdentity(cep), IsNil)
+	c.Assert(ParseCiliumEndpointIPIdentity(&v2.CiliumEndpoint{}), IsNil)
+}
+
 func (s *K8sSuite) Test_equalV1Endpoints(c *C) {
 	type args struct {
 		o1 *core_v1.Endpoints
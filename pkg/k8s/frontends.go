@@ -0,0 +1,79 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"github.com/cilium/cilium/pkg/lock"
+)
+
+// FrontendRegistry records the extra frontends (NodePort, ExternalIPs and
+// LoadBalancer) which have been installed in the datapath for each service.
+// The frontends of a service depend on the node addresses at the time they
+// were installed, so they cannot be recomputed reliably when the service is
+// updated or deleted.
+type FrontendRegistry struct {
+	mutex     lock.Mutex
+	frontends map[ServiceID]map[string]Frontend
+}
+
+// NewFrontendRegistry returns a new, empty FrontendRegistry
+func NewFrontendRegistry() *FrontendRegistry {
+	return &FrontendRegistry{
+		frontends: map[ServiceID]map[string]Frontend{},
+	}
+}
+
+// Upsert records the given frontends as the frontends installed for the
+// service and returns the previously installed frontends of the service
+// which are no longer part of it. Frontends are identified by IP and port
+// number, as in Service.ExtraFrontends.
+func (r *FrontendRegistry) Upsert(id ServiceID, frontends []Frontend) []Frontend {
+	installed := make(map[string]Frontend, len(frontends))
+	for _, fe := range frontends {
+		installed[fe.String()] = fe
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stale := []Frontend{}
+	for key, fe := range r.frontends[id] {
+		if _, ok := installed[key]; !ok {
+			stale = append(stale, fe)
+		}
+	}
+
+	if len(installed) == 0 {
+		delete(r.frontends, id)
+	} else {
+		r.frontends[id] = installed
+	}
+
+	return stale
+}
+
+// Delete forgets the service and returns all frontends installed for it
+func (r *FrontendRegistry) Delete(id ServiceID) []Frontend {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	frontends := make([]Frontend, 0, len(r.frontends[id]))
+	for _, fe := range r.frontends[id] {
+		frontends = append(frontends, fe)
+	}
+	delete(r.frontends, id)
+
+	return frontends
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package k8s
+
+import (
+	"net"
+	"sort"
+
+	"github.com/cilium/cilium/pkg/checker"
+	"github.com/cilium/cilium/pkg/loadbalancer"
+
+	"gopkg.in/check.v1"
+)
+
+func sortedFrontends(frontends []Frontend) []Frontend {
+	sort.Slice(frontends, func(i, j int) bool {
+		return frontends[i].String() < frontends[j].String()
+	})
+	return frontends
+}
+
+func newFrontendTestService() *Service {
+	svc := NewService(net.ParseIP("10.0.0.1"), false, nil, nil)
+	svc.Ports[loadbalancer.FEPortName("http")] = loadbalancer.NewFEPort(loadbalancer.TCP, 80)
+	svc.NodePorts = map[loadbalancer.FEPortName]uint16{
+		loadbalancer.FEPortName("http"): 31000,
+	}
+	svc.ExternalIPs = []net.IP{net.ParseIP("192.168.0.1")}
+	svc.LoadBalancerIPs = []net.IP{net.ParseIP("192.168.0.2")}
+	return svc
+}
+
+func (s *K8sSuite) TestFrontendRegistryRemovedFrontends(c *check.C) {
+	id := ServiceID{Name: "foo", Namespace: "bar"}
+	nodeAddrs := []net.IP{net.ParseIP("172.16.0.1")}
+
+	externalIP := Frontend{
+		L3n4Addr: *loadbalancer.NewL3n4Addr(loadbalancer.TCP, net.ParseIP("192.168.0.1"), 80),
+		PortName: loadbalancer.FEPortName("http"),
+		Type:     loadbalancer.SVCTypeExternalIPs,
+	}
+	lbIP := Frontend{
+		L3n4Addr: *loadbalancer.NewL3n4Addr(loadbalancer.TCP, net.ParseIP("192.168.0.2"), 80),
+		PortName: loadbalancer.FEPortName("http"),
+		Type:     loadbalancer.SVCTypeLoadBalancer,
+	}
+	nodePort := Frontend{
+		L3n4Addr: *loadbalancer.NewL3n4Addr(loadbalancer.TCP, net.ParseIP("172.16.0.1"), 31000),
+		PortName: loadbalancer.FEPortName("http"),
+		Type:     loadbalancer.SVCTypeNodePort,
+	}
+
+	r := NewFrontendRegistry()
+	svc := newFrontendTestService()
+	c.Assert(r.Upsert(id, svc.ExtraFrontends(nodeAddrs)), check.HasLen, 0)
+	// An unchanged service has no stale frontends
+	c.Assert(r.Upsert(id, svc.ExtraFrontends(nodeAddrs)), check.HasLen, 0)
+
+	svc.ExternalIPs = nil
+	c.Assert(r.Upsert(id, svc.ExtraFrontends(nodeAddrs)), checker.DeepEquals, []Frontend{externalIP})
+
+	svc.LoadBalancerIPs = nil
+	c.Assert(r.Upsert(id, svc.ExtraFrontends(nodeAddrs)), checker.DeepEquals, []Frontend{lbIP})
+
+	svc.NodePorts = nil
+	c.Assert(r.Upsert(id, svc.ExtraFrontends(nodeAddrs)), checker.DeepEquals, []Frontend{nodePort})
+	c.Assert(r.Delete(id), check.HasLen, 0)
+}
+
+func (s *K8sSuite) TestFrontendRegistryNodeAddressChange(c *check.C) {
+	id := ServiceID{Name: "foo", Namespace: "bar"}
+	svc := newFrontendTestService()
+	svc.ExternalIPs = nil
+	svc.LoadBalancerIPs = nil
+
+	oldNodePort := Frontend{
+		L3n4Addr: *loadbalancer.NewL3n4Addr(loadbalancer.TCP, net.ParseIP("172.16.0.1"), 31000),
+		PortName: loadbalancer.FEPortName("http"),
+		Type:     loadbalancer.SVCTypeNodePort,
+	}
+	newNodePort := Frontend{
+		L3n4Addr: *loadbalancer.NewL3n4Addr(loadbalancer.TCP, net.ParseIP("172.16.0.2"), 31000),
+		PortName: loadbalancer.FEPortName("http"),
+		Type:     loadbalancer.SVCTypeNodePort,
+	}
+
+	r := NewFrontendRegistry()
+	c.Assert(r.Upsert(id, svc.ExtraFrontends([]net.IP{oldNodePort.IP})), check.HasLen, 0)
+
+	// Updating the service after a node address change releases the
+	// frontend of the old address
+	c.Assert(r.Upsert(id, svc.ExtraFrontends([]net.IP{newNodePort.IP})), checker.DeepEquals, []Frontend{oldNodePort})
+
+	// Deleting the service after a node address change returns the
+	// installed frontend, not one of the current node address
+	r = NewFrontendRegistry()
+	r.Upsert(id, svc.ExtraFrontends([]net.IP{oldNodePort.IP}))
+	c.Assert(r.Delete(id), checker.DeepEquals, []Frontend{oldNodePort})
+	c.Assert(r.Delete(id), check.HasLen, 0)
+}
+
+func (s *K8sSuite) TestFrontendRegistryDelete(c *check.C) {
+	id := ServiceID{Name: "foo", Namespace: "bar"}
+	other := ServiceID{Name: "other", Namespace: "bar"}
+	nodeAddrs := []net.IP{net.ParseIP("172.16.0.1")}
+	svc := newFrontendTestService()
+
+	r := NewFrontendRegistry()
+	r.Upsert(id, svc.ExtraFrontends(nodeAddrs))
+	r.Upsert(other, svc.ExtraFrontends(nodeAddrs))
+
+	c.Assert(sortedFrontends(r.Delete(id)), checker.DeepEquals, sortedFrontends(svc.ExtraFrontends(nodeAddrs)))
+	c.Assert(r.Delete(id), check.HasLen, 0)
+	c.Assert(r.Delete(other), check.HasLen, 3)
+}
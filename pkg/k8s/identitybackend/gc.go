@@ -0,0 +1,87 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identitybackend
+
+import (
+	"time"
+
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	clientset "github.com/cilium/cilium/pkg/k8s/client/clientset/versioned"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GarbageCollect removes all references which have not been renewed within
+// ttl from the CiliumIdentities and deletes all CiliumIdentities which are no
+// longer referenced by any node. CiliumIdentities created less than ttl ago
+// are never deleted to give the allocating node time to record its
+// reference.
+//
+// A node which acquires a reference to a CiliumIdentity while it is being
+// deleted re-creates it, see allocator.WithMasterKeyProtection().
+func GarbageCollect(client clientset.Interface, ttl time.Duration) error {
+	identities, err := client.CiliumV2().CiliumIdentities().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for i := range identities.Items {
+		identity := &identities.Items[i]
+		scopedLog := log.WithFields(logrus.Fields{
+			logfields.Identity: identity.Name,
+			"labels":           identity.SecurityLabels,
+		})
+
+		nodes := map[string]metav1.Time{}
+		for node, lastRenewed := range identity.Status.Nodes {
+			if time.Since(lastRenewed.Time) < ttl {
+				nodes[node] = lastRenewed
+			}
+		}
+
+		switch {
+		case len(nodes) == len(identity.Status.Nodes):
+			if len(nodes) > 0 || time.Since(identity.CreationTimestamp.Time) < ttl {
+				continue
+			}
+		case len(nodes) > 0:
+			identity = identity.DeepCopy()
+			identity.Status.Nodes = nodes
+			if _, err := client.CiliumV2().CiliumIdentities().UpdateStatus(identity); err != nil {
+				scopedLog.WithError(err).Warning("Unable to remove stale references from CiliumIdentity")
+			}
+			continue
+		}
+
+		err := deleteIdentity(client, identity)
+		switch {
+		case err == nil:
+			scopedLog.Info("Deleted unused CiliumIdentity")
+		case !k8sErrors.IsNotFound(err):
+			scopedLog.WithError(err).Warning("Unable to delete unused CiliumIdentity")
+		}
+	}
+
+	return nil
+}
+
+func deleteIdentity(client clientset.Interface, identity *v2.CiliumIdentity) error {
+	return client.CiliumV2().CiliumIdentities().Delete(identity.Name, &metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &identity.UID},
+	})
+}
@@ -0,0 +1,401 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package identitybackend implements an allocator backend storing the
+// identities as CiliumIdentity custom resources.
+package identitybackend
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/cilium/cilium/pkg/idpool"
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	clientset "github.com/cilium/cilium/pkg/k8s/client/clientset/versioned"
+	"github.com/cilium/cilium/pkg/kvstore/allocator"
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// byKeyIndex is the name of the index of the identities by key
+	byKeyIndex = "by-key-index"
+
+	// ReferenceRefreshInterval is the interval in which a node renews its
+	// references to the identities it is using
+	ReferenceRefreshInterval = 5 * time.Minute
+
+	// ReferenceTTL is the time after which a reference which has not been
+	// renewed is considered stale and removed by the garbage collector
+	ReferenceTTL = 3 * ReferenceRefreshInterval
+)
+
+var (
+	log = logging.DefaultLogger.WithField(logfields.LogSubsys, "crd-allocator")
+)
+
+// crdBackend is an allocator backend storing each master key as a
+// CiliumIdentity. The name of the CiliumIdentity is the ID, the key is stored
+// in the security labels and the nodes referencing the ID (slave keys) are
+// recorded in the status of the CiliumIdentity.
+//
+// Unlike the kvstore backend, allocations are not serialized by a lock. The
+// creation of a CiliumIdentity fails if the ID is already taken, so two nodes
+// can never allocate the same ID for different keys. Two nodes allocating an
+// ID for the same key at the same time may however end up with different IDs
+// for the key. Both IDs remain valid and the unused ID is deleted by the
+// garbage collector of cilium-operator once no node references it anymore.
+type crdBackend struct {
+	// nodeName is the name of the local node, used to record the
+	// references of the node
+	nodeName string
+
+	client  clientset.Interface
+	keyType allocator.AllocatorKey
+
+	// storeMutex protects store
+	storeMutex lock.RWMutex
+
+	// store is the informer store of all CiliumIdentities, indexed by key.
+	// It is nil until ListAndWatch() has been called.
+	store cache.Indexer
+}
+
+// NewCRDBackend returns an allocator backend storing identities as
+// CiliumIdentity objects via client. References of the local node are
+// recorded under nodeName.
+func NewCRDBackend(nodeName string, client clientset.Interface, keyType allocator.AllocatorKey) (allocator.Backend, error) {
+	if client == nil {
+		return nil, fmt.Errorf("kubernetes client not configured")
+	}
+
+	if nodeName == "" {
+		return nil, fmt.Errorf("node name not configured")
+	}
+
+	return &crdBackend{
+		nodeName: nodeName,
+		client:   client,
+		keyType:  keyType,
+	}, nil
+}
+
+func (c *crdBackend) getStore() cache.Indexer {
+	c.storeMutex.RLock()
+	defer c.storeMutex.RUnlock()
+	return c.store
+}
+
+// DeleteAllKeys deletes all CiliumIdentities
+func (c *crdBackend) DeleteAllKeys() {
+	err := c.client.CiliumV2().CiliumIdentities().DeleteCollection(&metav1.DeleteOptions{}, metav1.ListOptions{})
+	if err != nil {
+		log.WithError(err).Warning("Unable to delete CiliumIdentities")
+	}
+}
+
+// AllocateID creates the CiliumIdentity for id. The creation fails if a
+// CiliumIdentity with the same ID already exists.
+func (c *crdBackend) AllocateID(id idpool.ID, key allocator.AllocatorKey) error {
+	identity := &v2.CiliumIdentity{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: id.String(),
+		},
+		SecurityLabels: key.GetAsMap(),
+	}
+
+	if _, err := c.client.CiliumV2().CiliumIdentities().Create(identity); err != nil {
+		return fmt.Errorf("unable to create CiliumIdentity %s: %s", id, err)
+	}
+
+	return nil
+}
+
+// AcquireReference records the local node in the status of the
+// CiliumIdentity of id
+func (c *crdBackend) AcquireReference(id idpool.ID, key allocator.AllocatorKey) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		identity, err := c.client.CiliumV2().CiliumIdentities().Get(id.String(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(identity.SecurityLabels, key.GetAsMap()) {
+			return fmt.Errorf("CiliumIdentity %s is allocated to a different key", id)
+		}
+
+		identity = identity.DeepCopy()
+		if identity.Status.Nodes == nil {
+			identity.Status.Nodes = map[string]metav1.Time{}
+		}
+		identity.Status.Nodes[c.nodeName] = metav1.Now()
+
+		_, err = c.client.CiliumV2().CiliumIdentities().UpdateStatus(identity)
+		return err
+	})
+}
+
+// Release removes the local node from the status of all CiliumIdentities of
+// key
+func (c *crdBackend) Release(key allocator.AllocatorKey) error {
+	store := c.getStore()
+	if store == nil {
+		return fmt.Errorf("CiliumIdentity store not initialized")
+	}
+
+	objs, err := store.ByIndex(byKeyIndex, key.GetKey())
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		identity, ok := obj.(*v2.CiliumIdentity)
+		if !ok {
+			continue
+		}
+
+		if _, ok := identity.Status.Nodes[c.nodeName]; !ok {
+			continue
+		}
+
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			identity, err := c.client.CiliumV2().CiliumIdentities().Get(identity.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			if _, ok := identity.Status.Nodes[c.nodeName]; !ok {
+				return nil
+			}
+
+			identity = identity.DeepCopy()
+			delete(identity.Status.Nodes, c.nodeName)
+
+			_, err = c.client.CiliumV2().CiliumIdentities().UpdateStatus(identity)
+			return err
+		})
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateKey re-creates the CiliumIdentity of id if it is missing and renews
+// the reference of the local node in its status. As UpdateKey is called
+// periodically for all identities in local use, it acts as the keepalive of
+// the references, which would otherwise be removed as stale by the garbage
+// collector.
+func (c *crdBackend) UpdateKey(id idpool.ID, key allocator.AllocatorKey, reliablyMissing bool) error {
+	missing := reliablyMissing
+	if store := c.getStore(); store != nil && !missing {
+		obj, exists, err := store.GetByKey(id.String())
+		switch {
+		case err != nil:
+		case !exists:
+			missing = true
+		default:
+			if identity, ok := obj.(*v2.CiliumIdentity); ok {
+				lastRenewed, ok := identity.Status.Nodes[c.nodeName]
+				if ok && time.Since(lastRenewed.Time) < ReferenceRefreshInterval &&
+					reflect.DeepEqual(identity.SecurityLabels, key.GetAsMap()) {
+					return nil
+				}
+			}
+		}
+	}
+
+	// Avoid a create request for every keepalive, the CiliumIdentity is
+	// only re-created if it is known to be missing
+	if !missing {
+		err := c.AcquireReference(id, key)
+		if !k8sErrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if err := c.AllocateID(id, key); err == nil {
+		log.WithField(logfields.Identity, id).Warning("Re-created potentially missing CiliumIdentity")
+	}
+
+	return c.AcquireReference(id, key)
+}
+
+// Get returns the ID of the first CiliumIdentity of key found in the store
+func (c *crdBackend) Get(key allocator.AllocatorKey) (idpool.ID, error) {
+	store := c.getStore()
+	if store == nil {
+		return idpool.NoID, nil
+	}
+
+	objs, err := store.ByIndex(byKeyIndex, key.GetKey())
+	if err != nil {
+		return idpool.NoID, err
+	}
+
+	for _, obj := range objs {
+		identity, ok := obj.(*v2.CiliumIdentity)
+		if !ok {
+			continue
+		}
+
+		id, err := strconv.ParseUint(identity.Name, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		return idpool.ID(id), nil
+	}
+
+	return idpool.NoID, nil
+}
+
+// GetByID returns the key of the CiliumIdentity of id
+func (c *crdBackend) GetByID(id idpool.ID) (allocator.AllocatorKey, error) {
+	store := c.getStore()
+	if store == nil {
+		return nil, fmt.Errorf("CiliumIdentity store not initialized")
+	}
+
+	obj, exists, err := store.GetByKey(id.String())
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	identity, ok := obj.(*v2.CiliumIdentity)
+	if !ok {
+		return nil, fmt.Errorf("invalid object %T in CiliumIdentity store", obj)
+	}
+
+	return c.keyType.PutKeyFromMap(identity.SecurityLabels), nil
+}
+
+type noopLock struct{}
+
+func (noopLock) Unlock() error { return nil }
+
+// Lock does not lock anything. The creation of a CiliumIdentity is atomic
+// and fails if the ID is already in use.
+func (c *crdBackend) Lock(key allocator.AllocatorKey) (allocator.Unlocker, error) {
+	return noopLock{}, nil
+}
+
+func (c *crdBackend) parseIdentity(obj interface{}) (idpool.ID, allocator.AllocatorKey, bool) {
+	identity, ok := obj.(*v2.CiliumIdentity)
+	if !ok {
+		return idpool.NoID, nil, false
+	}
+
+	id, err := strconv.ParseUint(identity.Name, 10, 64)
+	if err != nil {
+		log.WithError(err).WithField(logfields.Identity, identity.Name).Warning("Ignoring CiliumIdentity with invalid name")
+		return idpool.NoID, nil, false
+	}
+
+	return idpool.ID(id), c.keyType.PutKeyFromMap(identity.SecurityLabels), true
+}
+
+// ListAndWatch starts an informer on all CiliumIdentities and reports them to
+// handler until a value is received on stopChan
+func (c *crdBackend) ListAndWatch(handler allocator.CacheMutations, stopChan chan bool) {
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return c.client.CiliumV2().CiliumIdentities().List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return c.client.CiliumV2().CiliumIdentities().Watch(options)
+		},
+	}
+
+	store, controller := cache.NewIndexerInformer(lw, &v2.CiliumIdentity{}, 0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				if id, key, ok := c.parseIdentity(obj); ok {
+					handler.OnAdd(id, key)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldIdentity, ok1 := oldObj.(*v2.CiliumIdentity)
+				newIdentity, ok2 := newObj.(*v2.CiliumIdentity)
+				// Only changes of the key are relevant to the
+				// allocator, status updates are frequent
+				if ok1 && ok2 && reflect.DeepEqual(oldIdentity.SecurityLabels, newIdentity.SecurityLabels) {
+					return
+				}
+				if id, key, ok := c.parseIdentity(newObj); ok {
+					handler.OnModify(id, key)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				if deletedObj, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = deletedObj.Obj
+				}
+				if id, key, ok := c.parseIdentity(obj); ok {
+					handler.OnDelete(id, key)
+				}
+			},
+		},
+		cache.Indexers{byKeyIndex: c.keyIndexFunc},
+	)
+
+	c.storeMutex.Lock()
+	c.store = store
+	c.storeMutex.Unlock()
+
+	stop := make(chan struct{})
+	go func() {
+		if cache.WaitForCacheSync(stop, controller.HasSynced) {
+			handler.OnListDone()
+		}
+	}()
+	go controller.Run(stop)
+
+	<-stopChan
+	close(stop)
+}
+
+func (c *crdBackend) keyIndexFunc(obj interface{}) ([]string, error) {
+	identity, ok := obj.(*v2.CiliumIdentity)
+	if !ok {
+		return nil, fmt.Errorf("object is not a CiliumIdentity")
+	}
+
+	return []string{c.keyType.PutKeyFromMap(identity.SecurityLabels).GetKey()}, nil
+}
+
+// RunGC does not perform any garbage collection. Unused CiliumIdentities are
+// deleted by cilium-operator, see GarbageCollect().
+func (c *crdBackend) RunGC() error {
+	return nil
+}
+
+// Status returns the status of the backend
+func (c *crdBackend) Status() (string, error) {
+	if c.getStore() == nil {
+		return "CiliumIdentity store not initialized", nil
+	}
+
+	return "OK", nil
+}
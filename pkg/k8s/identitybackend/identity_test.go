@@ -0,0 +1,221 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package identitybackend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/pkg/idpool"
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	"github.com/cilium/cilium/pkg/k8s/client/clientset/versioned/fake"
+	"github.com/cilium/cilium/pkg/kvstore/allocator"
+	"github.com/cilium/cilium/pkg/testutils"
+
+	. "gopkg.in/check.v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type IdentityBackendSuite struct{}
+
+var _ = Suite(&IdentityBackendSuite{})
+
+type testKey string
+
+func (t testKey) GetKey() string { return string(t) }
+func (t testKey) String() string { return string(t) }
+func (t testKey) PutKey(v string) (allocator.AllocatorKey, error) {
+	return testKey(v), nil
+}
+func (t testKey) GetAsMap() map[string]string {
+	return map[string]string{"key": string(t)}
+}
+func (t testKey) PutKeyFromMap(m map[string]string) allocator.AllocatorKey {
+	return testKey(m["key"])
+}
+
+func newTestAllocator(c *C, client *fake.Clientset, nodeName string) *allocator.Allocator {
+	backend, err := NewCRDBackend(nodeName, client, testKey(""))
+	c.Assert(err, IsNil)
+
+	a, err := allocator.NewAllocator("", testKey(""), allocator.WithBackend(backend),
+		allocator.WithMax(idpool.ID(16)), allocator.WithSuffix(nodeName), allocator.WithoutGC())
+	c.Assert(err, IsNil)
+	a.WaitForInitialSync()
+
+	return a
+}
+
+func (s *IdentityBackendSuite) TestAllocate(c *C) {
+	client := fake.NewSimpleClientset()
+
+	a1 := newTestAllocator(c, client, "node1")
+	defer a1.Delete()
+
+	id, isNew, err := a1.Allocate(testKey("foo"))
+	c.Assert(err, IsNil)
+	c.Assert(isNew, Equals, true)
+	c.Assert(id, Not(Equals), idpool.NoID)
+
+	identity, err := client.CiliumV2().CiliumIdentities().Get(id.String(), metav1.GetOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(identity.SecurityLabels, DeepEquals, map[string]string{"key": "foo"})
+	c.Assert(identity.Status.Nodes, HasLen, 1)
+	_, ok := identity.Status.Nodes["node1"]
+	c.Assert(ok, Equals, true)
+
+	// wait for the identity to show up in the cache of the allocator
+	c.Assert(testutils.WaitUntil(func() bool {
+		key, err := a1.GetByID(id)
+		return err == nil && key != nil && key.GetKey() == "foo"
+	}, 5*time.Second), IsNil)
+
+	// a second node must be handed out the same ID
+	a2 := newTestAllocator(c, client, "node2")
+	defer a2.Delete()
+
+	id2, isNew, err := a2.Allocate(testKey("foo"))
+	c.Assert(err, IsNil)
+	c.Assert(isNew, Equals, false)
+	c.Assert(id2, Equals, id)
+
+	identity, err = client.CiliumV2().CiliumIdentities().Get(id.String(), metav1.GetOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(identity.Status.Nodes, HasLen, 2)
+
+	// releasing the last local use removes the reference of the node
+	lastUse, err := a1.Release(testKey("foo"))
+	c.Assert(err, IsNil)
+	c.Assert(lastUse, Equals, true)
+
+	identity, err = client.CiliumV2().CiliumIdentities().Get(id.String(), metav1.GetOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(identity.Status.Nodes, HasLen, 1)
+	_, ok = identity.Status.Nodes["node2"]
+	c.Assert(ok, Equals, true)
+
+	// a different key is allocated a different ID
+	id3, isNew, err := a2.Allocate(testKey("bar"))
+	c.Assert(err, IsNil)
+	c.Assert(isNew, Equals, true)
+	c.Assert(id3, Not(Equals), id)
+}
+
+func (s *IdentityBackendSuite) TestGarbageCollect(c *C) {
+	var (
+		now   = metav1.Now()
+		stale = metav1.NewTime(now.Add(-2 * ReferenceTTL))
+	)
+
+	client := fake.NewSimpleClientset(
+		// in use by node1
+		&v2.CiliumIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "1"},
+			Status: v2.IdentityStatus{Nodes: map[string]metav1.Time{
+				"node1": now,
+			}},
+		},
+		// in use by node1, stale reference of node2
+		&v2.CiliumIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "2"},
+			Status: v2.IdentityStatus{Nodes: map[string]metav1.Time{
+				"node1": now,
+				"node2": stale,
+			}},
+		},
+		// only stale references
+		&v2.CiliumIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "3"},
+			Status: v2.IdentityStatus{Nodes: map[string]metav1.Time{
+				"node2": stale,
+			}},
+		},
+		// no references
+		&v2.CiliumIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "4"},
+		},
+		// no references yet, but just created
+		&v2.CiliumIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "5", CreationTimestamp: now},
+		},
+	)
+
+	c.Assert(GarbageCollect(client, ReferenceTTL), IsNil)
+
+	identities, err := client.CiliumV2().CiliumIdentities().List(metav1.ListOptions{})
+	c.Assert(err, IsNil)
+
+	remaining := map[string]*v2.CiliumIdentity{}
+	for i := range identities.Items {
+		remaining[identities.Items[i].Name] = &identities.Items[i]
+	}
+
+	c.Assert(remaining, HasLen, 3)
+	c.Assert(remaining["1"].Status.Nodes, HasLen, 1)
+	c.Assert(remaining["2"].Status.Nodes, HasLen, 1)
+	_, ok := remaining["2"].Status.Nodes["node1"]
+	c.Assert(ok, Equals, true)
+	c.Assert(remaining["5"], Not(IsNil))
+}
+
+func (s *IdentityBackendSuite) TestUpdateKey(c *C) {
+	renewed := metav1.NewTime(time.Now().Add(-2 * ReferenceRefreshInterval))
+	client := fake.NewSimpleClientset(&v2.CiliumIdentity{
+		ObjectMeta:     metav1.ObjectMeta{Name: "1"},
+		SecurityLabels: testKey("foo").GetAsMap(),
+		Status: v2.IdentityStatus{Nodes: map[string]metav1.Time{
+			"node1": renewed,
+		}},
+	})
+
+	backend, err := NewCRDBackend("node1", client, testKey(""))
+	c.Assert(err, IsNil)
+
+	creates := func() int {
+		n := 0
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "create" {
+				n++
+			}
+		}
+		return n
+	}
+
+	// an existing CiliumIdentity is only renewed
+	c.Assert(backend.UpdateKey(idpool.ID(1), testKey("foo"), false), IsNil)
+	c.Assert(creates(), Equals, 0)
+	identity, err := client.CiliumV2().CiliumIdentities().Get("1", metav1.GetOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(identity.Status.Nodes["node1"].After(renewed.Time), Equals, true)
+
+	// a missing CiliumIdentity is re-created
+	c.Assert(client.CiliumV2().CiliumIdentities().Delete("1", &metav1.DeleteOptions{}), IsNil)
+	c.Assert(backend.UpdateKey(idpool.ID(1), testKey("foo"), false), IsNil)
+	c.Assert(creates(), Equals, 1)
+	identity, err = client.CiliumV2().CiliumIdentities().Get("1", metav1.GetOptions{})
+	c.Assert(err, IsNil)
+	c.Assert(identity.SecurityLabels, DeepEquals, testKey("foo").GetAsMap())
+	_, ok := identity.Status.Nodes["node1"]
+	c.Assert(ok, Equals, true)
+
+	// a CiliumIdentity allocated to a different key is not taken over
+	c.Assert(backend.UpdateKey(idpool.ID(1), testKey("bar"), true), Not(IsNil))
+}
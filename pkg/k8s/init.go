@@ -131,10 +131,18 @@ func Init() error {
 			}
 		}
 
+		if option.Config.IPAM == option.IPAMClusterPool {
+			if err := useCiliumNodeAllocation(nodeName); err != nil {
+				return fmt.Errorf("unable to use pod CIDRs allocated by cilium-operator: %s", err)
+			}
+		}
+
 		// Annotate addresses will occur later since the user might
 		// want to specify them manually
 	} else if option.Config.K8sRequireIPv4PodCIDR || option.Config.K8sRequireIPv6PodCIDR {
 		return fmt.Errorf("node name must be specified via environment variable '%s' to retrieve Kubernetes PodCIDR range", EnvNodeNameSpec)
+	} else if option.Config.IPAM == option.IPAMClusterPool {
+		return fmt.Errorf("node name must be specified via environment variable '%s' to use %s IPAM", EnvNodeNameSpec, option.IPAMClusterPool)
 	}
 
 	return nil
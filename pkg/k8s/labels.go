@@ -96,6 +96,12 @@ func isInjectedWithIstioSidecarProxy(scopedLog *logrus.Entry, pod *corev1.Pod) b
 
 // GetPodLabels returns the labels of a pod
 func GetPodLabels(namespace, podName string) (map[string]string, error) {
+	k8sLabels, _, err := GetPodMetadata(namespace, podName)
+	return k8sLabels, err
+}
+
+// GetPodMetadata returns the labels and annotations of a pod
+func GetPodMetadata(namespace, podName string) (map[string]string, map[string]string, error) {
 	scopedLog := log.WithFields(logrus.Fields{
 		logfields.K8sNamespace: namespace,
 		logfields.K8sPodName:   podName,
@@ -104,13 +110,13 @@ func GetPodLabels(namespace, podName string) (map[string]string, error) {
 
 	result, err := Client().CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Also get all labels from the namespace where the pod is running
 	k8sNs, err := Client().CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	k8sLabels := result.GetLabels()
@@ -139,5 +145,5 @@ func GetPodLabels(namespace, podName string) (map[string]string, error) {
 
 	k8sLabels[k8sConst.PolicyLabelCluster] = option.Config.ClusterName
 
-	return k8sLabels, nil
+	return k8sLabels, result.GetAnnotations(), nil
 }
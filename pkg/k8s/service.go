@@ -17,6 +17,7 @@ package k8s
 import (
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 
 	"github.com/cilium/cilium/pkg/annotation"
@@ -91,18 +92,71 @@ func ParseService(svc *v1.Service) (ServiceID, *Service) {
 	svcInfo.IncludeExternal = getAnnotationIncludeExternal(svc)
 	svcInfo.Shared = getAnnotationShared(svc)
 
-	// FIXME: Add support for
-	//  - NodePort
 	for _, port := range svc.Spec.Ports {
 		p := loadbalancer.NewFEPort(loadbalancer.L4Type(port.Protocol), uint16(port.Port))
 		if _, ok := svcInfo.Ports[loadbalancer.FEPortName(port.Name)]; !ok {
 			svcInfo.Ports[loadbalancer.FEPortName(port.Name)] = p
+			if port.NodePort != 0 {
+				if svcInfo.NodePorts == nil {
+					svcInfo.NodePorts = map[loadbalancer.FEPortName]uint16{}
+				}
+				svcInfo.NodePorts[loadbalancer.FEPortName(port.Name)] = uint16(port.NodePort)
+			}
+		}
+	}
+
+	svcInfo.ExternalIPs = parseIPs(scopedLog, svc.Spec.ExternalIPs)
+
+	if svc.Spec.Type == v1.ServiceTypeLoadBalancer {
+		lbIPs := make([]string, 0, len(svc.Status.LoadBalancer.Ingress))
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				lbIPs = append(lbIPs, ingress.IP)
+			}
+		}
+		svcInfo.LoadBalancerIPs = parseIPs(scopedLog, lbIPs)
+	}
+
+	if value, ok := svc.ObjectMeta.Annotations[annotation.ServiceLBAlgorithm]; ok {
+		alg, err := loadbalancer.ParseSVCLBAlgorithm(value)
+		if err != nil {
+			scopedLog.WithError(err).Warningf("Ignoring invalid %s annotation", annotation.ServiceLBAlgorithm)
+		}
+		svcInfo.LBAlgorithm = alg
+	}
+
+	if svc.Spec.ExternalTrafficPolicy == v1.ServiceExternalTrafficPolicyTypeLocal {
+		svcInfo.ExternalTrafficPolicyLocal = true
+		svcInfo.HealthCheckNodePort = uint16(svc.Spec.HealthCheckNodePort)
+	}
+
+	if svc.Spec.SessionAffinity == v1.ServiceAffinityClientIP {
+		svcInfo.SessionAffinity = true
+		svcInfo.SessionAffinityTimeoutSec = uint32(v1.DefaultClientIPServiceAffinitySeconds)
+		if cfg := svc.Spec.SessionAffinityConfig; cfg != nil && cfg.ClientIP != nil &&
+			cfg.ClientIP.TimeoutSeconds != nil && *cfg.ClientIP.TimeoutSeconds > 0 {
+			svcInfo.SessionAffinityTimeoutSec = uint32(*cfg.ClientIP.TimeoutSeconds)
 		}
 	}
 
 	return svcID, svcInfo
 }
 
+// parseIPs parses the given list of IP addresses, invalid addresses are
+// skipped.
+func parseIPs(scopedLog *logrus.Entry, ips []string) []net.IP {
+	var parsed []net.IP
+	for _, ipStr := range ips {
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			scopedLog.WithField(logfields.IPAddr, ipStr).Warn("Ignoring invalid service IP")
+			continue
+		}
+		parsed = append(parsed, ip)
+	}
+	return parsed
+}
+
 // ServiceID identities the Kubernetes service
 type ServiceID struct {
 	Name      string `json:"serviceName,omitempty"`
@@ -127,7 +181,40 @@ type Service struct {
 	// Shared is true when the service should be exposed/shared to other clusters
 	Shared bool
 
-	Ports    map[loadbalancer.FEPortName]*loadbalancer.FEPort
+	Ports map[loadbalancer.FEPortName]*loadbalancer.FEPort
+
+	// NodePorts maps the name of a frontend port to the port on which the
+	// service is exposed on every node address
+	NodePorts map[loadbalancer.FEPortName]uint16
+
+	// ExternalIPs is the list of external IPs the service is exposed on
+	ExternalIPs []net.IP
+
+	// LoadBalancerIPs is the list of ingress IPs of the load balancer
+	// fronting the service
+	LoadBalancerIPs []net.IP
+
+	// SessionAffinity is true when requests of a client must be
+	// forwarded to the same backend
+	SessionAffinity bool
+
+	// SessionAffinityTimeoutSec is the number of seconds after which the
+	// backend selected for a client is forgotten
+	SessionAffinityTimeoutSec uint32
+
+	// ExternalTrafficPolicyLocal is true when traffic to the NodePort,
+	// external IPs and load balancer IPs of the service must only be
+	// forwarded to backends running on the local node
+	ExternalTrafficPolicyLocal bool
+
+	// HealthCheckNodePort is the port on which the number of local
+	// backends is reported to external load balancers
+	HealthCheckNodePort uint16
+
+	// LBAlgorithm is the backend selection algorithm requested for the
+	// service via annotation
+	LBAlgorithm loadbalancer.SVCLBAlgorithm
+
 	Labels   map[string]string
 	Selector map[string]string
 }
@@ -163,6 +250,13 @@ func (s *Service) DeepEquals(o *Service) bool {
 	}
 	if s.IsHeadless == o.IsHeadless &&
 		s.FrontendIP.Equal(o.FrontendIP) &&
+		ipsEqual(s.ExternalIPs, o.ExternalIPs) &&
+		ipsEqual(s.LoadBalancerIPs, o.LoadBalancerIPs) &&
+		s.SessionAffinity == o.SessionAffinity &&
+		s.SessionAffinityTimeoutSec == o.SessionAffinityTimeoutSec &&
+		s.ExternalTrafficPolicyLocal == o.ExternalTrafficPolicyLocal &&
+		s.HealthCheckNodePort == o.HealthCheckNodePort &&
+		s.LBAlgorithm == o.LBAlgorithm &&
 		comparator.MapStringEquals(s.Labels, o.Labels) &&
 		comparator.MapStringEquals(s.Selector, o.Selector) {
 
@@ -179,11 +273,32 @@ func (s *Service) DeepEquals(o *Service) bool {
 				return false
 			}
 		}
+
+		if len(s.NodePorts) != len(o.NodePorts) {
+			return false
+		}
+		for portName, nodePort := range s.NodePorts {
+			if oNodePort, ok := o.NodePorts[portName]; !ok || nodePort != oNodePort {
+				return false
+			}
+		}
 		return true
 	}
 	return false
 }
 
+func ipsEqual(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // NewService returns a new Service with the Ports map initialized.
 func NewService(ip net.IP, headless bool, labels map[string]string, selector map[string]string) *Service {
 	return &Service{
@@ -207,6 +322,67 @@ func (s *Service) UniquePorts() map[uint16]bool {
 	return uniqPorts
 }
 
+// Frontend is a frontend address of a service in addition to its cluster IP
+type Frontend struct {
+	loadbalancer.L3n4Addr
+
+	// PortName is the name of the service port the frontend translates to
+	PortName loadbalancer.FEPortName
+
+	// Type is the type of the frontend
+	Type loadbalancer.SVCType
+}
+
+// ExtraFrontends returns the frontends of the service in addition to the
+// cluster IP frontends: a NodePort frontend for each of the given node
+// addresses, and a frontend for each of the external IPs and load balancer
+// ingress IPs. Only addresses of the same family as the cluster IP are
+// considered. As with UniquePorts, frontends are unique by IP and port
+// number regardless of the L4 protocol.
+func (s *Service) ExtraFrontends(nodeAddrs []net.IP) []Frontend {
+	if s.IsHeadless || s.FrontendIP == nil {
+		return nil
+	}
+
+	portNames := make([]string, 0, len(s.Ports))
+	for portName := range s.Ports {
+		portNames = append(portNames, string(portName))
+	}
+	sort.Strings(portNames)
+
+	isIPv4 := s.FrontendIP.To4() != nil
+	frontends := []Frontend{}
+	seen := map[string]bool{}
+	add := func(ips []net.IP, portName loadbalancer.FEPortName, port *loadbalancer.L4Addr, svcType loadbalancer.SVCType) {
+		for _, ip := range ips {
+			if ip == nil || (ip.To4() != nil) != isIPv4 {
+				continue
+			}
+			fe := Frontend{
+				L3n4Addr: loadbalancer.L3n4Addr{IP: ip, L4Addr: *port},
+				PortName: portName,
+				Type:     svcType,
+			}
+			if id := fe.String(); !seen[id] {
+				seen[id] = true
+				frontends = append(frontends, fe)
+			}
+		}
+	}
+
+	for _, name := range portNames {
+		portName := loadbalancer.FEPortName(name)
+		port := s.Ports[portName]
+		if nodePort, ok := s.NodePorts[portName]; ok {
+			add(nodeAddrs, portName, loadbalancer.NewL4Addr(port.Protocol, nodePort), loadbalancer.SVCTypeNodePort)
+		}
+		add(s.ExternalIPs, portName, port.L4Addr, loadbalancer.SVCTypeExternalIPs)
+		add(s.LoadBalancerIPs, portName, port.L4Addr, loadbalancer.SVCTypeLoadBalancer)
+	}
+
+	return frontends
+}
+
 // NewClusterService returns the service.ClusterService representing a
 // Kubernetes Service
 func NewClusterService(id ServiceID, k8sService *Service, k8sEndpoints *Endpoints) service.ClusterService {
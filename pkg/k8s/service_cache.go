@@ -365,8 +365,9 @@ func (s *ServiceCache) ListMissingIngresses(required versioned.Map, host net.IP)
 }
 
 // UniqueServiceFrontends returns all services known to the service cache as a map, indexed by
-// the string representation of a loadbalancer.L3n4Addr
-func (s *ServiceCache) UniqueServiceFrontends() map[string]struct{} {
+// the string representation of a loadbalancer.L3n4Addr. NodePort frontends are
+// included for each of the given node addresses.
+func (s *ServiceCache) UniqueServiceFrontends(nodeAddrs []net.IP) map[string]struct{} {
 	uniqueFrontends := make(map[string]struct{})
 
 	s.mutex.RLock()
@@ -381,6 +382,10 @@ func (s *ServiceCache) UniqueServiceFrontends() map[string]struct{} {
 
 			uniqueFrontends[address.StringWithProtocol()] = struct{}{}
 		}
+
+		for _, fe := range svc.ExtraFrontends(nodeAddrs) {
+			uniqueFrontends[fe.StringWithProtocol()] = struct{}{}
+		}
 	}
 
 	return uniqueFrontends
@@ -401,6 +406,12 @@ func (s *ServiceCache) correlateEndpoints(id ServiceID) (*Endpoints, bool) {
 		for ip, e := range localEndpoints.Backends {
 			endpoints.Backends[ip] = e
 		}
+		for ip, nodeName := range localEndpoints.NodeNames {
+			if endpoints.NodeNames == nil {
+				endpoints.NodeNames = map[string]string{}
+			}
+			endpoints.NodeNames[ip] = nodeName
+		}
 	}
 
 	svc, hasExternalService := s.services[id]
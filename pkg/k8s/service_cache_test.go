@@ -85,12 +85,23 @@ func (s *K8sSuite) TestGetUniqueServiceFrontends(c *check.C) {
 		svcID2: &endpoints,
 	}
 
-	frontends := cache.UniqueServiceFrontends()
+	frontends := cache.UniqueServiceFrontends(nil)
 	c.Assert(frontends, checker.DeepEquals, map[string]struct{}{
 		"1.1.1.1:10/TCP": {},
 		"1.1.1.1:20/TCP": {},
 		"2.2.2.2:20/UDP": {},
 	})
+
+	cache.services[svcID2].NodePorts = map[loadbalancer.FEPortName]uint16{
+		loadbalancer.FEPortName("bar"): 30000,
+	}
+	frontends = cache.UniqueServiceFrontends([]net.IP{net.ParseIP("4.4.4.4")})
+	c.Assert(frontends, checker.DeepEquals, map[string]struct{}{
+		"1.1.1.1:10/TCP":    {},
+		"1.1.1.1:20/TCP":    {},
+		"2.2.2.2:20/UDP":    {},
+		"4.4.4.4:30000/UDP": {},
+	})
 }
 
 func (s *K8sSuite) TestServiceCache(c *check.C) {
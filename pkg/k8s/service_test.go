@@ -110,6 +110,127 @@ func (s *K8sSuite) TestParseService(c *check.C) {
 		Labels:     map[string]string{"foo": "bar"},
 		Ports:      map[loadbalancer.FEPortName]*loadbalancer.FEPort{},
 	})
+
+	k8sSvc = &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: "10.0.0.1",
+			Type:      v1.ServiceTypeLoadBalancer,
+			Ports: []v1.ServicePort{
+				{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, NodePort: 31000},
+			},
+			ExternalIPs: []string{"192.168.0.1", "invalid"},
+		},
+		Status: v1.ServiceStatus{
+			LoadBalancer: v1.LoadBalancerStatus{
+				Ingress: []v1.LoadBalancerIngress{
+					{IP: "172.16.0.1"},
+					{Hostname: "lb.example.com"},
+				},
+			},
+		},
+	}
+
+	id, svc = ParseService(k8sSvc)
+	c.Assert(id, checker.DeepEquals, ServiceID{Namespace: "bar", Name: "foo"})
+	c.Assert(svc, checker.DeepEquals, &Service{
+		FrontendIP: net.ParseIP("10.0.0.1"),
+		Ports: map[loadbalancer.FEPortName]*loadbalancer.FEPort{
+			loadbalancer.FEPortName("http"): loadbalancer.NewFEPort(loadbalancer.TCP, 80),
+		},
+		NodePorts: map[loadbalancer.FEPortName]uint16{
+			loadbalancer.FEPortName("http"): 31000,
+		},
+		ExternalIPs:     []net.IP{net.ParseIP("192.168.0.1")},
+		LoadBalancerIPs: []net.IP{net.ParseIP("172.16.0.1")},
+	})
+
+	k8sSvc = &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP:       "10.0.0.1",
+			Type:            v1.ServiceTypeClusterIP,
+			SessionAffinity: v1.ServiceAffinityClientIP,
+		},
+	}
+
+	id, svc = ParseService(k8sSvc)
+	c.Assert(id, checker.DeepEquals, ServiceID{Namespace: "bar", Name: "foo"})
+	c.Assert(svc, checker.DeepEquals, &Service{
+		FrontendIP:                net.ParseIP("10.0.0.1"),
+		Ports:                     map[loadbalancer.FEPortName]*loadbalancer.FEPort{},
+		SessionAffinity:           true,
+		SessionAffinityTimeoutSec: uint32(v1.DefaultClientIPServiceAffinitySeconds),
+	})
+
+	timeout := int32(60)
+	k8sSvc.Spec.SessionAffinityConfig = &v1.SessionAffinityConfig{
+		ClientIP: &v1.ClientIPConfig{TimeoutSeconds: &timeout},
+	}
+	_, svc = ParseService(k8sSvc)
+	c.Assert(svc.SessionAffinity, check.Equals, true)
+	c.Assert(svc.SessionAffinityTimeoutSec, check.Equals, uint32(60))
+
+	k8sSvc.Spec.SessionAffinity = v1.ServiceAffinityNone
+	_, svc = ParseService(k8sSvc)
+	c.Assert(svc.SessionAffinity, check.Equals, false)
+	c.Assert(svc.SessionAffinityTimeoutSec, check.Equals, uint32(0))
+
+	k8sSvc.Spec.Type = v1.ServiceTypeNodePort
+	k8sSvc.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeLocal
+	k8sSvc.Spec.HealthCheckNodePort = 32000
+	_, svc = ParseService(k8sSvc)
+	c.Assert(svc.ExternalTrafficPolicyLocal, check.Equals, true)
+	c.Assert(svc.HealthCheckNodePort, check.Equals, uint16(32000))
+
+	k8sSvc.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyTypeCluster
+	_, svc = ParseService(k8sSvc)
+	c.Assert(svc.ExternalTrafficPolicyLocal, check.Equals, false)
+	c.Assert(svc.HealthCheckNodePort, check.Equals, uint16(0))
+}
+
+func (s *K8sSuite) TestServiceExtraFrontends(c *check.C) {
+	svc := NewService(net.ParseIP("10.0.0.1"), false, nil, nil)
+	svc.Ports[loadbalancer.FEPortName("http")] = loadbalancer.NewFEPort(loadbalancer.TCP, 80)
+	svc.Ports[loadbalancer.FEPortName("dns")] = loadbalancer.NewFEPort(loadbalancer.UDP, 53)
+	svc.NodePorts = map[loadbalancer.FEPortName]uint16{
+		loadbalancer.FEPortName("http"): 31000,
+	}
+	svc.ExternalIPs = []net.IP{net.ParseIP("192.168.0.1"), net.ParseIP("f00d::1")}
+	svc.LoadBalancerIPs = []net.IP{net.ParseIP("192.168.0.1")}
+
+	nodeAddrs := []net.IP{net.ParseIP("172.16.0.1"), net.ParseIP("10.1.0.1"), net.ParseIP("beef::1")}
+	c.Assert(svc.ExtraFrontends(nodeAddrs), checker.DeepEquals, []Frontend{
+		{
+			L3n4Addr: *loadbalancer.NewL3n4Addr(loadbalancer.UDP, net.ParseIP("192.168.0.1"), 53),
+			PortName: loadbalancer.FEPortName("dns"),
+			Type:     loadbalancer.SVCTypeExternalIPs,
+		},
+		{
+			L3n4Addr: *loadbalancer.NewL3n4Addr(loadbalancer.TCP, net.ParseIP("172.16.0.1"), 31000),
+			PortName: loadbalancer.FEPortName("http"),
+			Type:     loadbalancer.SVCTypeNodePort,
+		},
+		{
+			L3n4Addr: *loadbalancer.NewL3n4Addr(loadbalancer.TCP, net.ParseIP("10.1.0.1"), 31000),
+			PortName: loadbalancer.FEPortName("http"),
+			Type:     loadbalancer.SVCTypeNodePort,
+		},
+		{
+			L3n4Addr: *loadbalancer.NewL3n4Addr(loadbalancer.TCP, net.ParseIP("192.168.0.1"), 80),
+			PortName: loadbalancer.FEPortName("http"),
+			Type:     loadbalancer.SVCTypeExternalIPs,
+		},
+	})
+
+	svc.IsHeadless = true
+	c.Assert(svc.ExtraFrontends(nodeAddrs), check.HasLen, 0)
 }
 
 func (s *K8sSuite) TestIsK8ServiceExternal(c *check.C) {
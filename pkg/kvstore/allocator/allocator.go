@@ -17,9 +17,6 @@ package allocator
 import (
 	"errors"
 	"fmt"
-	"path"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/cilium/cilium/pkg/backoff"
@@ -64,6 +61,10 @@ const (
 // in parallel request the ID for keys and are guaranteed to retrieve the same
 // ID for an identical key.
 //
+// The storage of master and slave keys is implemented by a Backend. The
+// default backend stores the keys in the kvstore as described below, a
+// different backend can be provided with WithBackend().
+//
 // Slave keys:
 //   Slave keys are owned by individual nodes:
 //     - basePath/value/key1/node1 => 1001
@@ -130,23 +131,8 @@ type Allocator struct {
 	// keyType is an instance of the type to be used as allocator key.
 	keyType AllocatorKey
 
-	// basePrefix is the prefix in the kvstore that all keys share which
-	// are being managed by this allocator. The basePrefix typically
-	// consists of something like: "space/project/allocatorName"
-	basePrefix string
-
-	// idPrefix is the kvstore key prefix for all master keys. It is being
-	// derived from the basePrefix.
-	idPrefix string
-
-	// valuePrefix is the kvstore key prefix for all slave keys. It is
-	// being derived from the basePrefix.
-	valuePrefix string
-
-	// lockPrefix is the prefix to use for all kvstore locks. This prefix
-	// is different from the idPrefix and valuePrefix to simplify watching
-	// for ID and key changes.
-	lockPrefix string
+	// backend is the storage backend of master and slave keys
+	backend Backend
 
 	// min is the lower limit when allocating IDs. The allocator will never
 	// allocate an ID lesser than this value.
@@ -168,10 +154,6 @@ type Allocator struct {
 	// this is typical set to the node's IP address
 	suffix string
 
-	// lockless is true if allocation can be done lockless. This depends on
-	// the underlying kvstore backend
-	lockless bool
-
 	// backoffTemplate is the backoff configuration while allocating
 	backoffTemplate backoff.Exponential
 
@@ -204,11 +186,6 @@ type Allocator struct {
 	disableGC bool
 }
 
-func locklessCapability() bool {
-	required := kvstore.CapabilityCreateIfExists | kvstore.CapabilityDeleteOnZeroCount
-	return kvstore.GetCapabilities()&required == required
-}
-
 // AllocatorOption is the base type for allocator options
 type AllocatorOption func(*Allocator)
 
@@ -223,26 +200,18 @@ type AllocatorOption func(*Allocator)
 //  - WithSuffix(string) - customize the node specifix suffix to attach to keys
 //  - WithMin(id) - minimum ID to allocate (default: 1)
 //  - WithMax(id) - maximum ID to allocate (default max(uint64))
+//  - WithBackend(backend) - use a backend other than the kvstore
 //
 // After creation, IDs can be allocated with Allocate() and released with
 // Release()
 func NewAllocator(basePath string, typ AllocatorKey, opts ...AllocatorOption) (*Allocator, error) {
-	if kvstore.Client() == nil {
-		return nil, fmt.Errorf("kvstore client not configured")
-	}
-
 	a := &Allocator{
 		keyType:      typ,
-		basePrefix:   basePath,
-		idPrefix:     path.Join(basePath, "id"),
-		valuePrefix:  path.Join(basePath, "value"),
-		lockPrefix:   path.Join(basePath, "locks"),
 		min:          idpool.ID(1),
 		max:          idpool.ID(^uint64(0)),
 		localKeys:    newLocalKeys(),
 		stopGC:       make(chan struct{}, 0),
 		suffix:       uuid.NewUUID().String()[:10],
-		lockless:     locklessCapability(),
 		remoteCaches: map[*RemoteCache]struct{}{},
 		backoffTemplate: backoff.Exponential{
 			Min:    time.Duration(20) * time.Millisecond,
@@ -254,10 +223,18 @@ func NewAllocator(basePath string, typ AllocatorKey, opts ...AllocatorOption) (*
 		fn(a)
 	}
 
-	a.mainCache = newCache(kvstore.Client(), a.idPrefix)
+	if a.backend == nil {
+		if kvstore.Client() == nil {
+			return nil, fmt.Errorf("kvstore client not configured")
+		}
+
+		backend := newKVStoreBackend(basePath, a.suffix, typ, kvstore.Client())
+		// invalid prefixes are only deleted from the main cache
+		backend.deleteInvalidPrefixes = true
+		a.backend = backend
+	}
 
-	// invalid prefixes are only deleted from the main cache
-	a.mainCache.deleteInvalidPrefixes = true
+	a.mainCache = newCache(a, a.backend)
 
 	if a.suffix == "<nil>" {
 		return nil, errors.New("Allocator suffix is <nil> and unlikely unique")
@@ -273,7 +250,7 @@ func NewAllocator(basePath string, typ AllocatorKey, opts ...AllocatorOption) (*
 
 	a.idPool = idpool.NewIDPool(a.min, a.max)
 
-	a.initialListDone = a.mainCache.start(a)
+	a.initialListDone = a.mainCache.start()
 	if !a.disableGC {
 		go func() {
 			select {
@@ -332,6 +309,13 @@ func WithoutGC() AllocatorOption {
 	return func(a *Allocator) { a.disableGC = true }
 }
 
+// WithBackend sets the backend used to store master and slave keys. If not
+// set, the keys are stored in the kvstore below the base path passed to
+// NewAllocator().
+func WithBackend(backend Backend) AllocatorOption {
+	return func(a *Allocator) { a.backend = backend }
+}
+
 // Delete deletes an allocator and stops the garbage collector
 func (a *Allocator) Delete() {
 	close(a.stopGC)
@@ -347,15 +331,9 @@ func (a *Allocator) WaitForInitialSync() {
 	<-a.initialListDone
 }
 
-// lockPath locks a key in the scope of an allocator
-func (a *Allocator) lockPath(key string) (*kvstore.Lock, error) {
-	suffix := strings.TrimPrefix(key, a.basePrefix)
-	return kvstore.LockPath(path.Join(a.lockPrefix, suffix))
-}
-
 // DeleteAllKeys will delete all keys
 func (a *Allocator) DeleteAllKeys() {
-	kvstore.DeletePrefix(a.basePrefix)
+	a.backend.DeleteAllKeys()
 }
 
 // RangeFunc is the function called by RangeCache
@@ -373,14 +351,6 @@ func (a *Allocator) ForeachCache(cb RangeFunc) {
 	a.remoteCachesMutex.RUnlock()
 }
 
-func invalidKey(key, prefix string, deleteInvalid bool) {
-	log.WithFields(logrus.Fields{fieldKey: key, fieldPrefix: prefix}).Warning("Found invalid key outside of prefix")
-
-	if deleteInvalid {
-		kvstore.Delete(key)
-	}
-}
-
 // Selects an available ID.
 // Returns a triple of the selected ID ORed with prefixMask,
 // the ID string and the originally selected ID.
@@ -394,17 +364,6 @@ func (a *Allocator) selectAvailableID() (idpool.ID, string, idpool.ID) {
 	return 0, "", 0
 }
 
-func (a *Allocator) createValueNodeKey(key string, newID idpool.ID) error {
-	// add a new key /value/<key>/<node> to account for the reference
-	// The key is protected with a TTL/lease and will expire after LeaseTTL
-	valueKey := path.Join(a.valuePrefix, key, a.suffix)
-	if err := kvstore.Update(valueKey, []byte(newID.String()), true); err != nil {
-		return fmt.Errorf("unable to create value-node key '%s': %s", valueKey, err)
-	}
-
-	return nil
-}
-
 // AllocatorKey is the interface to implement in order for a type to be used as
 // key for the allocator
 type AllocatorKey interface {
@@ -415,6 +374,14 @@ type AllocatorKey interface {
 	// original type
 	PutKey(v string) (AllocatorKey, error)
 
+	// GetAsMap must return the key in map representation. It is used by
+	// backends which cannot store the key in its string representation.
+	GetAsMap() map[string]string
+
+	// PutKeyFromMap must transform the key in map representation back
+	// into its original type
+	PutKeyFromMap(map[string]string) AllocatorKey
+
 	// String must return the key in human readable string representation
 	String() string
 }
@@ -423,7 +390,7 @@ func (a *Allocator) lockedAllocate(key AllocatorKey) (idpool.ID, bool, error) {
 	kvstore.Trace("Allocating key in kvstore", nil, logrus.Fields{fieldKey: key})
 
 	k := key.GetKey()
-	lock, err := a.lockPath(k)
+	lock, err := a.backend.Lock(key)
 	if err != nil {
 		return 0, false, err
 	}
@@ -445,7 +412,7 @@ func (a *Allocator) lockedAllocate(key AllocatorKey) (idpool.ID, bool, error) {
 			return 0, false, fmt.Errorf("unable to reserve local key '%s': %s", k, err)
 		}
 
-		if err = a.createValueNodeKey(k, value); err != nil {
+		if err = a.backend.AcquireReference(value, key); err != nil {
 			a.localKeys.release(k)
 			return 0, false, fmt.Errorf("unable to create slave key '%s': %s", k, err)
 		}
@@ -458,7 +425,7 @@ func (a *Allocator) lockedAllocate(key AllocatorKey) (idpool.ID, bool, error) {
 		return value, false, nil
 	}
 
-	id, strID, unmaskedID := a.selectAvailableID()
+	id, _, unmaskedID := a.selectAvailableID()
 	if id == 0 {
 		return 0, false, fmt.Errorf("no more available IDs in configured space")
 	}
@@ -494,20 +461,18 @@ func (a *Allocator) lockedAllocate(key AllocatorKey) (idpool.ID, bool, error) {
 		return 0, false, fmt.Errorf("master key already exists")
 	}
 
-	// create /id/<ID> and fail if it already exists
-	keyPath := path.Join(a.idPrefix, strID)
-	err = kvstore.CreateOnly(keyPath, []byte(k), false)
-	if err != nil {
+	// create the master key and fail if it already exists
+	if err = a.backend.AllocateID(id, key); err != nil {
 		// Creation failed. Another agent most likely beat us to allocting this
 		// ID, retry.
 		releaseKeyAndID()
-		return 0, false, fmt.Errorf("unable to create master key '%s': %s", keyPath, err)
+		return 0, false, err
 	}
 
 	// Notify pool that leased ID is now in-use.
 	a.idPool.Use(unmaskedID)
 
-	if err = a.createValueNodeKey(k, id); err != nil {
+	if err = a.backend.AcquireReference(id, key); err != nil {
 		// We will leak the master key here as the key has already been
 		// exposed and may be in use by other nodes. The garbage
 		// collector will release it again.
@@ -515,6 +480,11 @@ func (a *Allocator) lockedAllocate(key AllocatorKey) (idpool.ID, bool, error) {
 		return 0, false, fmt.Errorf("slave key creation failed '%s': %s", k, err)
 	}
 
+	// mark the key as verified in the local cache
+	if err := a.localKeys.verify(k); err != nil {
+		log.WithError(err).Error("BUG: Unable to verify local key")
+	}
+
 	return id, true, nil
 }
 
@@ -569,7 +539,7 @@ func (a *Allocator) Allocate(key AllocatorKey) (idpool.ID, bool, error) {
 		//
 		// To prevent the stale local ache
 		if attempt == allocAttemptsWatermark {
-			if err := a.mainCache.restart(a); err != nil {
+			if err := a.mainCache.restart(); err != nil {
 				log.WithError(err).Warning("Unable to clear and refill allocator cache")
 			}
 		}
@@ -590,21 +560,9 @@ func (a *Allocator) Get(key AllocatorKey) (idpool.ID, error) {
 	return a.GetNoCache(key)
 }
 
-// Get returns the ID which is allocated to a key in the kvstore
+// GetNoCache returns the ID which is allocated to a key in the backend
 func (a *Allocator) GetNoCache(key AllocatorKey) (idpool.ID, error) {
-	prefix := path.Join(a.valuePrefix, key.GetKey())
-	value, err := kvstore.GetPrefix(prefix)
-	kvstore.Trace("AllocateGet", err, logrus.Fields{fieldPrefix: prefix, fieldValue: value})
-	if err != nil || value == nil {
-		return 0, err
-	}
-
-	id, err := strconv.ParseUint(string(value), 10, 64)
-	if err != nil {
-		return idpool.NoID, fmt.Errorf("unable to parse value '%s': %s", value, err)
-	}
-
-	return idpool.ID(id), nil
+	return a.backend.Get(key)
 }
 
 // GetByID returns the key associated with an ID. Returns nil if no key is
@@ -614,12 +572,7 @@ func (a *Allocator) GetByID(id idpool.ID) (AllocatorKey, error) {
 		return key, nil
 	}
 
-	v, err := kvstore.Get(path.Join(a.idPrefix, id.String()))
-	if err != nil {
-		return nil, err
-	}
-
-	return a.keyType.PutKey(string(v))
+	return a.backend.GetByID(id)
 }
 
 // Release releases the use of an ID associated with the provided key. After
@@ -635,85 +588,24 @@ func (a *Allocator) Release(key AllocatorKey) (lastUse bool, err error) {
 	}
 
 	if lastUse {
-		valueKey := path.Join(a.valuePrefix, k, a.suffix)
-		if err := kvstore.Delete(valueKey); err != nil {
+		if err := a.backend.Release(key); err != nil {
 			log.WithError(err).WithFields(logrus.Fields{fieldKey: key}).Warning("Ignoring node specific ID")
 		}
-
-		// if a.lockless {
-		// FIXME: etcd 3.3 will make it possible to do a lockless
-		// cleanup of the ID and release it right away. For now we rely
-		// on the GC to kick in a release unused IDs.
-		// }
 	}
 
 	return
 }
 
-func (a *Allocator) runGC() error {
-	// fetch list of all /id/ keys
-	allocated, err := kvstore.ListPrefix(a.idPrefix)
-	if err != nil {
-		return fmt.Errorf("list failed: %s", err)
-	}
-
-	// iterate over /id/
-	for key, v := range allocated {
-		// if a.lockless {
-		// FIXME: Add DeleteOnZeroCount support
-		// }
-
-		lock, err := a.lockPath(key)
-		if err != nil {
-			log.WithError(err).WithField(fieldKey, key).Warning("allocator garbage collector was unable to lock key")
-			continue
-		}
-
-		// fetch list of all /value/<key> keys
-		valueKeyPrefix := path.Join(a.valuePrefix, string(v))
-		uses, err := kvstore.ListPrefix(valueKeyPrefix)
-		if err != nil {
-			log.WithError(err).WithField(fieldPrefix, valueKeyPrefix).Warning("allocator garbage collector was unable to list keys")
-			lock.Unlock()
-			continue
-		}
-
-		// if ID has no user, delete it
-		if len(uses) == 0 {
-			scopedLog := log.WithFields(logrus.Fields{
-				fieldKey: key,
-				fieldID:  path.Base(key),
-			})
-			if err := kvstore.Delete(key); err != nil {
-				scopedLog.WithError(err).Warning("Unable to delete unused allocator master key")
-			} else {
-				scopedLog.Info("Deleted unused allocator master key")
-			}
-		}
-
-		lock.Unlock()
-	}
-
-	return nil
-}
-
 func (a *Allocator) recreateMasterKey(id idpool.ID, value string, reliablyMissing bool) {
-	keyPath := path.Join(a.idPrefix, id.String())
-
-	// Use of CreateOnly() ensures that any existing potentially
-	// conflicting key is never overwritten.
-	err := kvstore.CreateOnly(keyPath, []byte(value), false)
-	if reliablyMissing || err == nil {
-		log.WithError(err).WithField(fieldKey, keyPath).Warning("Re-created potentially missing master key")
+	key, err := a.keyType.PutKey(value)
+	if err != nil {
+		log.WithError(err).WithField(fieldKey, value).Warning("Unable to unmarshal allocator key")
+		return
 	}
 
-	// Also re-create the slave key in case it has been deleted. This will
-	// ensure that the next garbage collection cycle of any participating
-	// node does not remove the master key again.
-	valueKey := path.Join(a.valuePrefix, value, a.suffix)
-	err = kvstore.CreateOnly(valueKey, []byte(id.String()), true)
-	if reliablyMissing || err == nil {
-		log.WithError(err).WithField(fieldKey, valueKey).Warning("Re-created potentially missing slave key")
+	if err := a.backend.UpdateKey(id, key, reliablyMissing); err != nil {
+		log.WithError(err).WithFields(logrus.Fields{fieldKey: key, fieldID: id}).
+			Warning("Unable to re-create potentially missing master key")
 	}
 }
 
@@ -738,15 +630,13 @@ func (a *Allocator) syncLocalKeys() error {
 func (a *Allocator) startGC() {
 	go func(a *Allocator) {
 		for {
-			if err := a.runGC(); err != nil {
-				log.WithError(err).WithFields(logrus.Fields{fieldPrefix: a.idPrefix}).
-					Warning("Unable to run allocator garbage collector")
+			if err := a.backend.RunGC(); err != nil {
+				log.WithError(err).Warning("Unable to run allocator garbage collector")
 			}
 
 			select {
 			case <-a.stopGC:
-				log.WithFields(logrus.Fields{fieldPrefix: a.idPrefix}).
-					Debug("Stopped garbage collector")
+				log.Debug("Stopped garbage collector")
 				return
 			case <-time.After(gcInterval):
 			}
@@ -757,14 +647,12 @@ func (a *Allocator) startGC() {
 	go func(a *Allocator) {
 		for {
 			if err := a.syncLocalKeys(); err != nil {
-				log.WithError(err).WithFields(logrus.Fields{fieldPrefix: a.idPrefix}).
-					Warning("Unable to run local key sync routine")
+				log.WithError(err).Warning("Unable to run local key sync routine")
 			}
 
 			select {
 			case <-a.stopGC:
-				log.WithFields(logrus.Fields{fieldPrefix: a.idPrefix}).
-					Debug("Stopped master key sync routine")
+				log.Debug("Stopped master key sync routine")
 				return
 			case <-time.After(localKeySyncInterval):
 			}
@@ -802,7 +690,7 @@ type RemoteCache struct {
 // function.
 func (a *Allocator) WatchRemoteKVStore(backend kvstore.BackendOperations, prefix string) *RemoteCache {
 	rc := &RemoteCache{
-		cache:     newCache(backend, path.Join(prefix, "id")),
+		cache:     newCache(a, newKVStoreBackend(prefix, a.suffix, a.keyType, backend)),
 		allocator: a,
 	}
 
@@ -810,7 +698,7 @@ func (a *Allocator) WatchRemoteKVStore(backend kvstore.BackendOperations, prefix
 	a.remoteCaches[rc] = struct{}{}
 	a.remoteCachesMutex.Unlock()
 
-	rc.cache.start(a)
+	rc.cache.start()
 
 	return rc
 }
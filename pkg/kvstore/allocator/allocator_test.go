@@ -76,6 +76,16 @@ func (t TestType) String() string { return string(t) }
 func (t TestType) PutKey(v string) (AllocatorKey, error) {
 	return TestType(v), nil
 }
+func (t TestType) GetAsMap() map[string]string {
+	return map[string]string{string(t): string(t)}
+}
+func (t TestType) PutKeyFromMap(m map[string]string) AllocatorKey {
+	for _, v := range m {
+		return TestType(v)
+	}
+
+	panic("empty map")
+}
 
 func randomTestName() string {
 	return testutils.RandomRuneWithPrefix(testPrefix, 12)
@@ -219,9 +229,9 @@ func testAllocator(c *C, maxID idpool.ID, allocatorName string, suffix string) {
 	}
 
 	// running the GC should not evict any entries
-	allocator.runGC()
+	allocator.backend.RunGC()
 
-	v, err := kvstore.ListPrefix(allocator.idPrefix)
+	v, err := kvstore.ListPrefix(path.Join(allocatorName, "id"))
 	c.Assert(err, IsNil)
 	c.Assert(len(v), Equals, int(maxID))
 
@@ -236,9 +246,9 @@ func testAllocator(c *C, maxID idpool.ID, allocatorName string, suffix string) {
 	}
 
 	// running the GC should evict all entries
-	allocator.runGC()
+	allocator.backend.RunGC()
 
-	v, err = kvstore.ListPrefix(allocator.idPrefix)
+	v, err = kvstore.ListPrefix(path.Join(allocatorName, "id"))
 	c.Assert(err, IsNil)
 	c.Assert(len(v), Equals, 0)
 
@@ -257,9 +267,10 @@ func (s *AllocatorSuite) TestKeyToID(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(a, Not(IsNil))
 
-	c.Assert(a.mainCache.keyToID(path.Join(allocatorName, "invalid"), false), Equals, idpool.NoID)
-	c.Assert(a.mainCache.keyToID(path.Join(a.idPrefix, "invalid"), false), Equals, idpool.NoID)
-	c.Assert(a.mainCache.keyToID(path.Join(a.idPrefix, "10"), false), Equals, idpool.ID(10))
+	backend := a.backend.(*kvstoreBackend)
+	c.Assert(backend.keyToID(path.Join(allocatorName, "invalid"), false), Equals, idpool.NoID)
+	c.Assert(backend.keyToID(path.Join(backend.idPrefix, "invalid"), false), Equals, idpool.NoID)
+	c.Assert(backend.keyToID(path.Join(backend.idPrefix, "10"), false), Equals, idpool.ID(10))
 }
 
 func (s *AllocatorSuite) TestRemoteCache(c *C) {
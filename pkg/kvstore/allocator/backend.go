@@ -0,0 +1,92 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package allocator
+
+import (
+	"github.com/cilium/cilium/pkg/idpool"
+)
+
+// Backend is the interface which must be implemented by the storage backend
+// of an Allocator. The Allocator implements the local reference counting and
+// the selection of IDs while the backend persists master keys and node
+// references and provides the coordination between all nodes sharing the
+// allocation space.
+type Backend interface {
+	// DeleteAllKeys deletes all master keys and node references managed
+	// by the backend
+	DeleteAllKeys()
+
+	// AllocateID creates the master key mapping id to key. The operation
+	// must fail if a master key for id already exists.
+	AllocateID(id idpool.ID, key AllocatorKey) error
+
+	// AcquireReference records that the local node is using id to
+	// represent key (slave key)
+	AcquireReference(id idpool.ID, key AllocatorKey) error
+
+	// Release removes the reference of the local node to key
+	Release(key AllocatorKey) error
+
+	// UpdateKey re-creates the master key and the reference of the local
+	// node for an id which is still in local use. reliablyMissing is true
+	// if the master key is known to have been deleted.
+	UpdateKey(id idpool.ID, key AllocatorKey, reliablyMissing bool) error
+
+	// Get returns the ID allocated to key without consulting the cache of
+	// the allocator. Returns idpool.NoID if no ID has been allocated.
+	Get(key AllocatorKey) (idpool.ID, error)
+
+	// GetByID returns the key associated with id. Returns nil if no key is
+	// associated with id.
+	GetByID(id idpool.ID) (AllocatorKey, error)
+
+	// Lock serializes the allocation of key across all nodes. The
+	// returned Unlocker must be used to release the lock again.
+	Lock(key AllocatorKey) (Unlocker, error)
+
+	// ListAndWatch reports all master keys to handler, calls
+	// handler.OnListDone() once the initial list is complete and then
+	// reports all changes until a value is received on stopChan.
+	ListAndWatch(handler CacheMutations, stopChan chan bool)
+
+	// RunGC deletes all master keys which are no longer referenced by
+	// any node
+	RunGC() error
+
+	// Status returns a human readable status of the backend
+	Status() (string, error)
+}
+
+// Unlocker releases a lock acquired with Backend.Lock()
+type Unlocker interface {
+	Unlock() error
+}
+
+// CacheMutations is the interface used by a Backend to report changes of the
+// master keys to the cache of the allocator
+type CacheMutations interface {
+	// OnListDone is called when the initial list of master keys has been
+	// reported
+	OnListDone()
+
+	// OnAdd is called when a master key has been created
+	OnAdd(id idpool.ID, key AllocatorKey)
+
+	// OnModify is called when a master key has been modified
+	OnModify(id idpool.ID, key AllocatorKey)
+
+	// OnDelete is called when a master key has been deleted
+	OnDelete(id idpool.ID, key AllocatorKey)
+}
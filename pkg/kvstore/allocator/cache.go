@@ -16,8 +16,6 @@ package allocator
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -35,9 +33,9 @@ type idMap map[idpool.ID]AllocatorKey
 type keyMap map[string]idpool.ID
 
 type cache struct {
-	backend  kvstore.BackendOperations
-	prefix   string
-	stopChan chan bool
+	allocator *Allocator
+	backend   Backend
+	stopChan  chan bool
 
 	// mutex protects all cache data structures
 	mutex lock.RWMutex
@@ -61,23 +59,23 @@ type cache struct {
 	// nextKeyCache follows the same logic as nextCache but for keyCache
 	nextKeyCache keyMap
 
+	// listDone is closed when the backend has reported the initial list
+	// of master keys
+	listDone waitChan
+
 	// stopWatchWg is a wait group that gets conditions added when a
 	// watcher is started with the conditions marked as done when the
 	// watcher has exited
 	stopWatchWg sync.WaitGroup
-
-	// deleteInvalid enables deletion of identities outside of the valid
-	// prefix
-	deleteInvalidPrefixes bool
 }
 
-func newCache(backend kvstore.BackendOperations, prefix string) cache {
+func newCache(a *Allocator, backend Backend) cache {
 	return cache{
-		backend:  backend,
-		prefix:   prefix,
-		cache:    idMap{},
-		keyCache: keyMap{},
-		stopChan: make(chan bool, 1),
+		allocator: a,
+		backend:   backend,
+		cache:     idMap{},
+		keyCache:  keyMap{},
+		stopChan:  make(chan bool, 1),
 	}
 }
 
@@ -87,40 +85,100 @@ func (c *cache) getLogger() *logrus.Entry {
 	status, err := c.backend.Status()
 
 	return log.WithFields(logrus.Fields{
-		"kvstoreStatus": status,
-		"kvstoreErr":    err,
-		"prefix":        c.prefix,
+		"backendStatus": status,
+		"backendErr":    err,
 	})
 }
 
-func (c *cache) restart(a *Allocator) error {
+func (c *cache) restart() error {
 	c.stop()
-	return c.startAndWait(a)
+	return c.startAndWait()
+}
+
+// OnListDone is called by the backend when the initial list of master keys
+// has been reported
+func (c *cache) OnListDone() {
+	c.mutex.Lock()
+	// nextCache is valid, point the live cache to it
+	c.cache = c.nextCache
+	c.keyCache = c.nextKeyCache
+	listDone := c.listDone
+	c.mutex.Unlock()
+	c.allocator.idPool.FinishRefresh()
+
+	// report that the list operation has been completed and the
+	// allocator is ready to use
+	close(listDone)
 }
 
-func (c *cache) keyToID(key string, deleteInvalid bool) idpool.ID {
-	if !strings.HasPrefix(key, c.prefix) {
-		invalidKey(key, c.prefix, deleteInvalid)
-		return idpool.NoID
+// OnAdd is called by the backend when a master key has been created
+func (c *cache) OnAdd(id idpool.ID, key AllocatorKey) {
+	c.mutex.Lock()
+	c.nextCache[id] = key
+	if key != nil {
+		c.nextKeyCache[key.GetKey()] = id
 	}
+	c.allocator.idPool.Remove(id)
+	c.mutex.Unlock()
+
+	c.emitEvent(kvstore.EventTypeCreate, id, key)
+}
 
-	suffix := strings.TrimPrefix(key, c.prefix)
-	if suffix[0] == '/' {
-		suffix = suffix[1:]
+// OnModify is called by the backend when a master key has been modified
+func (c *cache) OnModify(id idpool.ID, key AllocatorKey) {
+	c.mutex.Lock()
+	if k, ok := c.nextCache[id]; ok {
+		delete(c.nextKeyCache, k.GetKey())
 	}
 
-	id, err := strconv.ParseUint(suffix, 10, 64)
-	if err != nil {
-		invalidKey(key, c.prefix, deleteInvalid)
-		return idpool.NoID
+	c.nextCache[id] = key
+	if key != nil {
+		c.nextKeyCache[key.GetKey()] = id
 	}
+	c.mutex.Unlock()
 
-	return idpool.ID(id)
+	c.emitEvent(kvstore.EventTypeModify, id, key)
 }
 
-// start requests a LIST operation from the kvstore and starts watching the
-// prefix in a go subroutine.
-func (c *cache) start(a *Allocator) waitChan {
+// OnDelete is called by the backend when a master key has been deleted
+func (c *cache) OnDelete(id idpool.ID, key AllocatorKey) {
+	c.mutex.Lock()
+	c.onDeleteLocked(id)
+	c.mutex.Unlock()
+
+	c.emitEvent(kvstore.EventTypeDelete, id, key)
+}
+
+func (c *cache) onDeleteLocked(id idpool.ID) {
+	a := c.allocator
+	if a.enableMasterKeyProtection {
+		if value := a.localKeys.lookupID(id); value != "" {
+			a.recreateMasterKey(id, value, true)
+			return
+		}
+	}
+
+	if k, ok := c.nextCache[id]; ok && k != nil {
+		delete(c.nextKeyCache, k.GetKey())
+	}
+
+	delete(c.nextCache, id)
+	a.idPool.Insert(id)
+}
+
+func (c *cache) emitEvent(typ kvstore.EventType, id idpool.ID, key AllocatorKey) {
+	if c.allocator.events != nil {
+		c.allocator.events <- AllocatorEvent{
+			Typ: typ,
+			ID:  id,
+			Key: key,
+		}
+	}
+}
+
+// start requests a LIST operation from the backend and starts watching for
+// changes in a go subroutine.
+func (c *cache) start() waitChan {
 	listDone := make(waitChan)
 
 	logger := c.getLogger()
@@ -131,106 +189,14 @@ func (c *cache) start(a *Allocator) waitChan {
 	// start with a fresh nextCache
 	c.nextCache = idMap{}
 	c.nextKeyCache = keyMap{}
+	c.listDone = listDone
 	c.mutex.Unlock()
-	a.idPool.StartRefresh()
+	c.allocator.idPool.StartRefresh()
 
 	c.stopWatchWg.Add(1)
 
 	go func() {
-		watcher := c.backend.ListAndWatch(c.prefix, c.prefix, 512)
-
-		for {
-			select {
-			case event, ok := <-watcher.Events:
-				if !ok {
-					goto abort
-				}
-				if event.Typ == kvstore.EventTypeListDone {
-					c.mutex.Lock()
-					// nextCache is valid, point the live cache to it
-					c.cache = c.nextCache
-					c.keyCache = c.nextKeyCache
-					c.mutex.Unlock()
-					a.idPool.FinishRefresh()
-
-					// report that the list operation has
-					// been completed and the allocator is
-					// ready to use
-					close(listDone)
-					continue
-				}
-
-				id := c.keyToID(event.Key, c.deleteInvalidPrefixes)
-				if id != 0 {
-					c.mutex.Lock()
-
-					var key AllocatorKey
-
-					if len(event.Value) > 0 {
-						var err error
-						key, err = a.keyType.PutKey(string(event.Value))
-						if err != nil {
-							logger.WithError(err).WithField(fieldKey, event.Value).
-								Warning("Unable to unmarshal allocator key")
-						}
-					}
-					debugFields := logger.WithFields(logrus.Fields{fieldKey: key, fieldID: id})
-
-					switch event.Typ {
-					case kvstore.EventTypeCreate:
-						kvstore.Trace("Adding id to cache", nil, debugFields.Data)
-						c.nextCache[id] = key
-						if key != nil {
-							c.nextKeyCache[key.GetKey()] = id
-						}
-						a.idPool.Remove(id)
-
-					case kvstore.EventTypeModify:
-						kvstore.Trace("Modifying id in cache", nil, debugFields.Data)
-						if k, ok := c.nextCache[id]; ok {
-							delete(c.nextKeyCache, k.GetKey())
-						}
-
-						c.nextCache[id] = key
-						if key != nil {
-							c.nextKeyCache[key.GetKey()] = id
-						}
-
-					case kvstore.EventTypeDelete:
-						kvstore.Trace("Removing id from cache", nil, debugFields.Data)
-
-						if a.enableMasterKeyProtection {
-							if value := a.localKeys.lookupID(id); value != "" {
-								a.recreateMasterKey(id, value, true)
-								break
-							}
-						}
-
-						if k, ok := c.nextCache[id]; ok && k != nil {
-							delete(c.nextKeyCache, k.GetKey())
-						}
-
-						delete(c.nextCache, id)
-						a.idPool.Insert(id)
-					}
-					c.mutex.Unlock()
-
-					if a.events != nil {
-						a.events <- AllocatorEvent{
-							Typ: event.Typ,
-							ID:  idpool.ID(id),
-							Key: key,
-						}
-					}
-				}
-
-			case <-c.stopChan:
-				goto abort
-			}
-		}
-
-	abort:
-		watcher.Stop()
+		c.backend.ListAndWatch(c, c.stopChan)
 		// Signal that watcher is done
 		c.stopWatchWg.Done()
 	}()
@@ -238,8 +204,8 @@ func (c *cache) start(a *Allocator) waitChan {
 	return listDone
 }
 
-func (c *cache) startAndWait(a *Allocator) error {
-	listDone := c.start(a)
+func (c *cache) startAndWait() error {
+	listDone := c.start()
 
 	// Wait for watcher to be started and for list operation to succeed
 	select {
@@ -0,0 +1,330 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package allocator
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/idpool"
+	"github.com/cilium/cilium/pkg/kvstore"
+
+	"github.com/sirupsen/logrus"
+)
+
+// kvstoreBackend is the kvstore implementation of Backend. The keys are
+// stored as follows:
+//
+// Master keys:
+//   - basePath/id/1001 => key1
+//
+// Slave keys:
+//   - basePath/value/key1/node1 => 1001
+type kvstoreBackend struct {
+	// backend is the kvstore client the keys are stored in
+	backend kvstore.BackendOperations
+
+	// keyType is an instance of the type to be used as allocator key.
+	keyType AllocatorKey
+
+	// basePrefix is the prefix in the kvstore that all keys share which
+	// are being managed by this allocator. The basePrefix typically
+	// consists of something like: "space/project/allocatorName"
+	basePrefix string
+
+	// idPrefix is the kvstore key prefix for all master keys. It is being
+	// derived from the basePrefix.
+	idPrefix string
+
+	// valuePrefix is the kvstore key prefix for all slave keys. It is
+	// being derived from the basePrefix.
+	valuePrefix string
+
+	// lockPrefix is the prefix to use for all kvstore locks. This prefix
+	// is different from the idPrefix and valuePrefix to simplify watching
+	// for ID and key changes.
+	lockPrefix string
+
+	// suffix is the suffix attached to keys which must be node specific,
+	// this is typical set to the node's IP address
+	suffix string
+
+	// lockless is true if allocation can be done lockless. This depends on
+	// the underlying kvstore backend
+	lockless bool
+
+	// deleteInvalidPrefixes enables deletion of keys outside of the valid
+	// prefix
+	deleteInvalidPrefixes bool
+}
+
+func locklessCapability() bool {
+	required := kvstore.CapabilityCreateIfExists | kvstore.CapabilityDeleteOnZeroCount
+	return kvstore.GetCapabilities()&required == required
+}
+
+func newKVStoreBackend(basePath, suffix string, typ AllocatorKey, backend kvstore.BackendOperations) *kvstoreBackend {
+	return &kvstoreBackend{
+		backend:     backend,
+		keyType:     typ,
+		basePrefix:  basePath,
+		idPrefix:    path.Join(basePath, "id"),
+		valuePrefix: path.Join(basePath, "value"),
+		lockPrefix:  path.Join(basePath, "locks"),
+		suffix:      suffix,
+		lockless:    locklessCapability(),
+	}
+}
+
+// lockPath locks a key in the scope of an allocator
+func (k *kvstoreBackend) lockPath(key string) (*kvstore.Lock, error) {
+	suffix := strings.TrimPrefix(key, k.basePrefix)
+	return kvstore.LockPath(path.Join(k.lockPrefix, suffix))
+}
+
+// DeleteAllKeys will delete all keys
+func (k *kvstoreBackend) DeleteAllKeys() {
+	k.backend.DeletePrefix(k.basePrefix)
+}
+
+// AllocateID creates /id/<ID> and fails if it already exists
+func (k *kvstoreBackend) AllocateID(id idpool.ID, key AllocatorKey) error {
+	keyPath := path.Join(k.idPrefix, id.String())
+	if err := k.backend.CreateOnly(keyPath, []byte(key.GetKey()), false); err != nil {
+		return fmt.Errorf("unable to create master key '%s': %s", keyPath, err)
+	}
+
+	return nil
+}
+
+// AcquireReference adds a new key /value/<key>/<node> to account for the
+// reference. The key is protected with a TTL/lease and will expire after
+// LeaseTTL.
+func (k *kvstoreBackend) AcquireReference(id idpool.ID, key AllocatorKey) error {
+	valueKey := path.Join(k.valuePrefix, key.GetKey(), k.suffix)
+	if err := k.backend.Update(valueKey, []byte(id.String()), true); err != nil {
+		return fmt.Errorf("unable to create value-node key '%s': %s", valueKey, err)
+	}
+
+	return nil
+}
+
+// Release deletes the node specific value key to remove the global reference
+// mark
+func (k *kvstoreBackend) Release(key AllocatorKey) error {
+	// if k.lockless {
+	// FIXME: etcd 3.3 will make it possible to do a lockless
+	// cleanup of the ID and release it right away. For now we rely
+	// on the GC to kick in a release unused IDs.
+	// }
+
+	valueKey := path.Join(k.valuePrefix, key.GetKey(), k.suffix)
+	return k.backend.Delete(valueKey)
+}
+
+// UpdateKey re-creates the master key and the slave key of the local node
+func (k *kvstoreBackend) UpdateKey(id idpool.ID, key AllocatorKey, reliablyMissing bool) error {
+	value := key.GetKey()
+	keyPath := path.Join(k.idPrefix, id.String())
+
+	// Use of CreateOnly() ensures that any existing potentially
+	// conflicting key is never overwritten.
+	err := k.backend.CreateOnly(keyPath, []byte(value), false)
+	if reliablyMissing || err == nil {
+		log.WithError(err).WithField(fieldKey, keyPath).Warning("Re-created potentially missing master key")
+	}
+
+	// Also re-create the slave key in case it has been deleted. This will
+	// ensure that the next garbage collection cycle of any participating
+	// node does not remove the master key again.
+	valueKey := path.Join(k.valuePrefix, value, k.suffix)
+	err = k.backend.CreateOnly(valueKey, []byte(id.String()), true)
+	if reliablyMissing || err == nil {
+		log.WithError(err).WithField(fieldKey, valueKey).Warning("Re-created potentially missing slave key")
+	}
+
+	return nil
+}
+
+// Get returns the ID which is allocated to a key in the kvstore
+func (k *kvstoreBackend) Get(key AllocatorKey) (idpool.ID, error) {
+	prefix := path.Join(k.valuePrefix, key.GetKey())
+	value, err := k.backend.GetPrefix(prefix)
+	kvstore.Trace("AllocateGet", err, logrus.Fields{fieldPrefix: prefix, fieldValue: value})
+	if err != nil || value == nil {
+		return 0, err
+	}
+
+	id, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return idpool.NoID, fmt.Errorf("unable to parse value '%s': %s", value, err)
+	}
+
+	return idpool.ID(id), nil
+}
+
+// GetByID returns the key associated with an ID in the kvstore
+func (k *kvstoreBackend) GetByID(id idpool.ID) (AllocatorKey, error) {
+	v, err := k.backend.Get(path.Join(k.idPrefix, id.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	return k.keyType.PutKey(string(v))
+}
+
+// Lock locks the slave keys of key
+func (k *kvstoreBackend) Lock(key AllocatorKey) (Unlocker, error) {
+	return k.lockPath(key.GetKey())
+}
+
+func (k *kvstoreBackend) invalidKey(key, prefix string, deleteInvalid bool) {
+	log.WithFields(logrus.Fields{fieldKey: key, fieldPrefix: prefix}).Warning("Found invalid key outside of prefix")
+
+	if deleteInvalid {
+		k.backend.Delete(key)
+	}
+}
+
+func (k *kvstoreBackend) keyToID(key string, deleteInvalid bool) idpool.ID {
+	if !strings.HasPrefix(key, k.idPrefix) {
+		k.invalidKey(key, k.idPrefix, deleteInvalid)
+		return idpool.NoID
+	}
+
+	suffix := strings.TrimPrefix(key, k.idPrefix)
+	if suffix[0] == '/' {
+		suffix = suffix[1:]
+	}
+
+	id, err := strconv.ParseUint(suffix, 10, 64)
+	if err != nil {
+		k.invalidKey(key, k.idPrefix, deleteInvalid)
+		return idpool.NoID
+	}
+
+	return idpool.ID(id)
+}
+
+// ListAndWatch requests a LIST operation from the kvstore and watches the
+// master key prefix until stopped
+func (k *kvstoreBackend) ListAndWatch(handler CacheMutations, stopChan chan bool) {
+	logger := log.WithField(fieldPrefix, k.idPrefix)
+	watcher := k.backend.ListAndWatch(k.idPrefix, k.idPrefix, 512)
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				goto abort
+			}
+			if event.Typ == kvstore.EventTypeListDone {
+				handler.OnListDone()
+				continue
+			}
+
+			id := k.keyToID(event.Key, k.deleteInvalidPrefixes)
+			if id != 0 {
+				var key AllocatorKey
+
+				if len(event.Value) > 0 {
+					var err error
+					key, err = k.keyType.PutKey(string(event.Value))
+					if err != nil {
+						logger.WithError(err).WithField(fieldKey, event.Value).
+							Warning("Unable to unmarshal allocator key")
+					}
+				}
+				debugFields := logger.WithFields(logrus.Fields{fieldKey: key, fieldID: id})
+
+				switch event.Typ {
+				case kvstore.EventTypeCreate:
+					kvstore.Trace("Adding id to cache", nil, debugFields.Data)
+					handler.OnAdd(id, key)
+
+				case kvstore.EventTypeModify:
+					kvstore.Trace("Modifying id in cache", nil, debugFields.Data)
+					handler.OnModify(id, key)
+
+				case kvstore.EventTypeDelete:
+					kvstore.Trace("Removing id from cache", nil, debugFields.Data)
+					handler.OnDelete(id, key)
+				}
+			}
+
+		case <-stopChan:
+			goto abort
+		}
+	}
+
+abort:
+	watcher.Stop()
+}
+
+// RunGC deletes all master keys which are no longer referenced by any slave
+// key
+func (k *kvstoreBackend) RunGC() error {
+	// fetch list of all /id/ keys
+	allocated, err := k.backend.ListPrefix(k.idPrefix)
+	if err != nil {
+		return fmt.Errorf("list failed: %s", err)
+	}
+
+	// iterate over /id/
+	for key, v := range allocated {
+		// if k.lockless {
+		// FIXME: Add DeleteOnZeroCount support
+		// }
+
+		lock, err := k.lockPath(key)
+		if err != nil {
+			log.WithError(err).WithField(fieldKey, key).Warning("allocator garbage collector was unable to lock key")
+			continue
+		}
+
+		// fetch list of all /value/<key> keys
+		valueKeyPrefix := path.Join(k.valuePrefix, string(v))
+		uses, err := k.backend.ListPrefix(valueKeyPrefix)
+		if err != nil {
+			log.WithError(err).WithField(fieldPrefix, valueKeyPrefix).Warning("allocator garbage collector was unable to list keys")
+			lock.Unlock()
+			continue
+		}
+
+		// if ID has no user, delete it
+		if len(uses) == 0 {
+			scopedLog := log.WithFields(logrus.Fields{
+				fieldKey: key,
+				fieldID:  path.Base(key),
+			})
+			if err := k.backend.Delete(key); err != nil {
+				scopedLog.WithError(err).Warning("Unable to delete unused allocator master key")
+			} else {
+				scopedLog.Info("Deleted unused allocator master key")
+			}
+		}
+
+		lock.Unlock()
+	}
+
+	return nil
+}
+
+// Status returns the status of the kvstore connection
+func (k *kvstoreBackend) Status() (string, error) {
+	return k.backend.Status()
+}
@@ -64,6 +64,9 @@ const (
 var (
 	// LabelHealth is the label used for health.
 	LabelHealth = Labels{IDNameHealth: NewLabel(IDNameHealth, "", LabelSourceReserved)}
+
+	// LabelHost is the label used for the host endpoint.
+	LabelHost = Labels{IDNameHost: NewLabel(IDNameHost, "", LabelSourceReserved)}
 )
 
 const (
@@ -192,6 +195,16 @@ func (l Labels) GetFromSource(source string) Labels {
 	return lbls
 }
 
+// StringMap converts Labels into a map of "source:key" to value. The result
+// can be converted back into Labels with Map2Labels(m, "").
+func (l Labels) StringMap() map[string]string {
+	o := make(map[string]string, len(l))
+	for _, v := range l {
+		o[v.Source+":"+v.Key] = v.Value
+	}
+	return o
+}
+
 // NewLabel returns a new label from the given key, value and source. If source is empty,
 // the default value will be LabelSourceUnspec. If key starts with '$', the source
 // will be overwritten with LabelSourceReserved. If key contains ':', the value
@@ -80,6 +80,16 @@ func (s *LabelsSuite) TestMap2Labels(c *C) {
 	c.Assert(m, checker.DeepEquals, lbls)
 }
 
+func (s *LabelsSuite) TestStringMap(c *C) {
+	m := lbls.StringMap()
+	c.Assert(m["unspec:foo"], Equals, "bar")
+	c.Assert(Map2Labels(m, ""), checker.DeepEquals, lbls)
+
+	reserved := Labels{"host": NewLabel("host", "", LabelSourceReserved)}
+	c.Assert(reserved.StringMap(), checker.DeepEquals, map[string]string{"reserved:host": ""})
+	c.Assert(Map2Labels(reserved.StringMap(), ""), checker.DeepEquals, reserved)
+}
+
 func (s *LabelsSuite) TestMergeLabels(c *C) {
 	to := Labels{
 		"key1": NewLabel("key1", "value1", "source1"),
@@ -18,6 +18,7 @@ import (
 	"crypto/sha512"
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 
 	"github.com/cilium/cilium/api/v1/models"
@@ -48,6 +49,51 @@ const (
 // L4Type name.
 type L4Type string
 
+// SVCType is a type of a service.
+type SVCType string
+
+const (
+	// SVCTypeNone is the type of services not created from Kubernetes,
+	// e.g. via the REST API.
+	SVCTypeNone = SVCType("NONE")
+	// SVCTypeClusterIP is the type of a service reachable via its cluster IP.
+	SVCTypeClusterIP = SVCType("ClusterIP")
+	// SVCTypeNodePort is the type of a service reachable via a node port
+	// on any of the node addresses.
+	SVCTypeNodePort = SVCType("NodePort")
+	// SVCTypeExternalIPs is the type of a service reachable via one of
+	// its external IPs.
+	SVCTypeExternalIPs = SVCType("ExternalIPs")
+	// SVCTypeLoadBalancer is the type of a service reachable via the
+	// ingress IP of its load balancer.
+	SVCTypeLoadBalancer = SVCType("LoadBalancer")
+)
+
+// SVCLBAlgorithm is the algorithm used to select the backend of a new
+// connection to a service.
+type SVCLBAlgorithm string
+
+const (
+	// SVCLBAlgorithmDefault selects the algorithm configured for the agent
+	SVCLBAlgorithmDefault = SVCLBAlgorithm("")
+	// SVCLBAlgorithmRandom selects backends based on the packet hash
+	SVCLBAlgorithmRandom = SVCLBAlgorithm("random")
+	// SVCLBAlgorithmMaglev selects backends using Maglev consistent
+	// hashing, which keeps the selection stable across nodes and
+	// minimizes disruption when backends are added or removed
+	SVCLBAlgorithmMaglev = SVCLBAlgorithm("maglev")
+)
+
+// ParseSVCLBAlgorithm parses the given string into a SVCLBAlgorithm. An
+// empty string maps to SVCLBAlgorithmDefault.
+func ParseSVCLBAlgorithm(s string) (SVCLBAlgorithm, error) {
+	switch alg := SVCLBAlgorithm(strings.ToLower(s)); alg {
+	case SVCLBAlgorithmDefault, SVCLBAlgorithmRandom, SVCLBAlgorithmMaglev:
+		return alg, nil
+	}
+	return SVCLBAlgorithmDefault, fmt.Errorf("unknown load balancing algorithm %q", s)
+}
+
 // FEPortName is the name of the frontend's port.
 type FEPortName string
 
@@ -64,11 +110,86 @@ func (lbbe *LBBackEnd) String() string {
 	return fmt.Sprintf("%s, weight: %d", lbbe.L3n4Addr.String(), lbbe.Weight)
 }
 
+// AssignBackendSlots returns the given backends in the order of the datapath
+// backend slots they are to be installed in. The datapath refers to backends
+// by slot, e.g. in the session affinity map, so the backends of prev which
+// are still part of backends keep their slot. The slots of removed backends
+// are reused by new backends, or else filled with the backends of the last
+// slots. The remaining new backends are appended in sorted order.
+func AssignBackendSlots(prev, backends []LBBackEnd) []LBBackEnd {
+	remaining := make(map[string]LBBackEnd, len(backends))
+	for _, be := range backends {
+		remaining[be.L3n4Addr.String()] = be
+	}
+
+	slots := make([]LBBackEnd, len(prev))
+	used := make([]bool, len(prev))
+	for i, be := range prev {
+		key := be.L3n4Addr.String()
+		if newBe, ok := remaining[key]; ok {
+			slots[i] = newBe
+			used[i] = true
+			delete(remaining, key)
+		}
+	}
+
+	added := make([]string, 0, len(remaining))
+	for key := range remaining {
+		added = append(added, key)
+	}
+	sort.Strings(added)
+
+	last := len(slots) - 1
+	for i := range slots {
+		if used[i] {
+			continue
+		}
+		if len(added) > 0 {
+			slots[i] = remaining[added[0]]
+			used[i] = true
+			added = added[1:]
+			continue
+		}
+		for last > i && !used[last] {
+			last--
+		}
+		if last <= i {
+			break
+		}
+		slots[i] = slots[last]
+		used[i] = true
+		used[last] = false
+	}
+
+	result := make([]LBBackEnd, 0, len(backends))
+	for i := range slots {
+		if used[i] {
+			result = append(result, slots[i])
+		}
+	}
+	for _, key := range added {
+		result = append(result, remaining[key])
+	}
+	return result
+}
+
 // LBSVC is essentially used for the REST API.
 type LBSVC struct {
 	Sha256 string
 	FE     L3n4AddrID
 	BES    []LBBackEnd
+	Type   SVCType
+
+	// SessionAffinity is true when connections from the same client IP
+	// must be sent to the same backend
+	SessionAffinity bool
+
+	// SessionAffinityTimeoutSec is the number of seconds after the last
+	// connection of a client after which its affinity expires
+	SessionAffinityTimeoutSec uint32
+
+	// LBAlgorithm is the backend selection algorithm of the service
+	LBAlgorithm SVCLBAlgorithm
 }
 
 func (s *LBSVC) GetModel() *models.Service {
@@ -87,6 +208,12 @@ func (s *LBSVC) GetModel() *models.Service {
 		spec.BackendAddresses[i] = be.GetBackendModel()
 	}
 
+	if s.Type != "" && s.Type != SVCTypeNone {
+		spec.Flags = &models.ServiceSpecFlags{
+			Type: string(s.Type),
+		}
+	}
+
 	return &models.Service{
 		Spec: spec,
 		Status: &models.ServiceStatus{
@@ -17,8 +17,11 @@
 package loadbalancer
 
 import (
+	"net"
 	"testing"
 
+	"github.com/cilium/cilium/pkg/checker"
+
 	"gopkg.in/check.v1"
 )
 
@@ -225,3 +228,33 @@ func TestFEPort_Equals(t *testing.T) {
 		})
 	}
 }
+
+func (s *TypesSuite) TestAssignBackendSlots(c *check.C) {
+	be := func(ip string) LBBackEnd {
+		return *NewLBBackEnd(TCP, net.ParseIP(ip), 80, 0)
+	}
+	a, b, c1, d, e := be("10.0.0.1"), be("10.0.0.2"), be("10.0.0.3"), be("10.0.0.4"), be("10.0.0.5")
+
+	// New backends are sorted
+	c.Assert(AssignBackendSlots(nil, []LBBackEnd{c1, a, b}), checker.DeepEquals, []LBBackEnd{a, b, c1})
+
+	// Unchanged backends keep their slots regardless of the order given
+	c.Assert(AssignBackendSlots([]LBBackEnd{c1, a, b}, []LBBackEnd{a, b, c1}), checker.DeepEquals, []LBBackEnd{c1, a, b})
+
+	// Added backends are appended
+	c.Assert(AssignBackendSlots([]LBBackEnd{c1, a}, []LBBackEnd{e, a, d, c1}), checker.DeepEquals, []LBBackEnd{c1, a, d, e})
+
+	// The slot of a removed backend is reused by a new backend
+	c.Assert(AssignBackendSlots([]LBBackEnd{a, b, c1}, []LBBackEnd{a, c1, d}), checker.DeepEquals, []LBBackEnd{a, d, c1})
+
+	// The slot of a removed backend is filled with the backend of the last slot
+	c.Assert(AssignBackendSlots([]LBBackEnd{a, b, c1, d}, []LBBackEnd{a, c1, d}), checker.DeepEquals, []LBBackEnd{a, d, c1})
+	c.Assert(AssignBackendSlots([]LBBackEnd{a, b, c1, d, e}, []LBBackEnd{b, e}), checker.DeepEquals, []LBBackEnd{e, b})
+	c.Assert(AssignBackendSlots([]LBBackEnd{a, b, c1}, []LBBackEnd{a, b}), checker.DeepEquals, []LBBackEnd{a, b})
+	c.Assert(AssignBackendSlots([]LBBackEnd{a, b}, nil), check.HasLen, 0)
+
+	// The weight of a backend is updated in place
+	weighted := b
+	weighted.Weight = 10
+	c.Assert(AssignBackendSlots([]LBBackEnd{a, b}, []LBBackEnd{weighted, a}), checker.DeepEquals, []LBBackEnd{a, weighted})
+}
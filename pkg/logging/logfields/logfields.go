@@ -81,6 +81,9 @@ const (
 	// DNSName is a FQDN or not fully qualified name intended for DNS lookups
 	DNSName = "dnsName"
 
+	// DNSQueryType is the type of a DNS query, e.g. A or AAAA
+	DNSQueryType = "dnsQueryType"
+
 	// IPAddr is an IPV4 or IPv6 address
 	IPAddr = "ipAddr"
 
@@ -144,6 +147,9 @@ const (
 	// V6Prefix is a IPv6 subnet/CIDR prefix
 	V6Prefix = "v6Prefix"
 
+	// CIDR is a subnet/CIDR prefix of either address family
+	CIDR = "cidr"
+
 	// Interface is an interface id/name on the system
 	Interface = "interface"
 
@@ -174,6 +180,10 @@ const (
 	// CiliumNetworkPolicyName is the name of a CiliumNetworkPolicy
 	CiliumNetworkPolicyName = "ciliumNetworkPolicyName"
 
+	// CiliumClusterwideNetworkPolicyName is the name of a
+	// CiliumClusterwideNetworkPolicy
+	CiliumClusterwideNetworkPolicyName = "ciliumClusterwideNetworkPolicyName"
+
 	// BPFMapKey is a key from a BPF map
 	BPFMapKey = "bpfMapKey"
 
@@ -320,4 +330,7 @@ const (
 
 	// PIDFile is a string value for the path to a file containing a PID.
 	PIDFile = "pidfile"
+
+	// SPI is the security parameter index of an IPsec key
+	SPI = "spi"
 )
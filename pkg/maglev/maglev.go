@@ -0,0 +1,88 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package maglev implements the lookup table generation of Maglev consistent
+// hashing as described in "Maglev: A Fast and Reliable Software Network Load
+// Balancer" (Eisenbud et al., NSDI 2016).
+package maglev
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// getOffsetAndSkip returns the offset and skip of the permutation of the
+// backend with the given name in a lookup table of size m
+func getOffsetAndSkip(backend string, m uint64) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(backend))
+	h1 := h.Sum64()
+
+	// The second hash is derived from the first one so that offset and
+	// skip are independent
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], h1)
+	h.Write(seed[:])
+	h2 := h.Sum64()
+
+	offset := h1 % m
+	skip := (h2 % (m - 1)) + 1
+
+	return offset, skip
+}
+
+// GetLookupTable returns the Maglev lookup table of size m for the given
+// backends. Each entry of the table is the index of a backend in backends.
+// m must be a prime number larger than the number of backends for all
+// backends to be assigned an equal share of the table. The table only
+// depends on the names and order of the backends, all nodes therefore
+// compute the same table for the same list of backends.
+//
+// Returns nil if backends is empty.
+func GetLookupTable(backends []string, m uint64) []int {
+	if len(backends) == 0 {
+		return nil
+	}
+
+	offsets := make([]uint64, len(backends))
+	skips := make([]uint64, len(backends))
+	for i, backend := range backends {
+		offsets[i], skips[i] = getOffsetAndSkip(backend, m)
+	}
+
+	table := make([]int, m)
+	for i := range table {
+		table[i] = -1
+	}
+
+	// next[i] is the position in the permutation of backend i which is
+	// to be tried next
+	next := make([]uint64, len(backends))
+
+	for filled := uint64(0); ; {
+		for i := range backends {
+			c := (offsets[i] + next[i]*skips[i]) % m
+			for table[c] >= 0 {
+				next[i]++
+				c = (offsets[i] + next[i]*skips[i]) % m
+			}
+			table[c] = i
+			next[i]++
+			filled++
+			if filled == m {
+				return table
+			}
+		}
+	}
+}
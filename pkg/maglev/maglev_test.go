@@ -0,0 +1,94 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package maglev
+
+import (
+	"fmt"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type MaglevSuite struct{}
+
+var _ = Suite(&MaglevSuite{})
+
+const testTableSize = 251
+
+func backendNames(n int) []string {
+	backends := make([]string, n)
+	for i := range backends {
+		backends[i] = fmt.Sprintf("10.0.0.%d:80", i+1)
+	}
+	return backends
+}
+
+func (s *MaglevSuite) TestEmpty(c *C) {
+	c.Assert(GetLookupTable(nil, testTableSize), IsNil)
+}
+
+func (s *MaglevSuite) TestDistribution(c *C) {
+	backends := backendNames(5)
+	table := GetLookupTable(backends, testTableSize)
+	c.Assert(len(table), Equals, testTableSize)
+
+	counts := make([]int, len(backends))
+	for _, b := range table {
+		c.Assert(b >= 0 && b < len(backends), Equals, true)
+		counts[b]++
+	}
+
+	// Each backend gets its share of the table, give or take one entry
+	for _, count := range counts {
+		c.Assert(count >= testTableSize/len(backends), Equals, true)
+		c.Assert(count <= testTableSize/len(backends)+1, Equals, true)
+	}
+
+	// The table is deterministic
+	c.Assert(GetLookupTable(backends, testTableSize), DeepEquals, table)
+}
+
+func (s *MaglevSuite) TestMinimalDisruption(c *C) {
+	backends := backendNames(10)
+	before := GetLookupTable(backends, testTableSize)
+
+	// Remove the 4th backend
+	removed := 3
+	remaining := append(append([]string{}, backends[:removed]...), backends[removed+1:]...)
+	after := GetLookupTable(remaining, testTableSize)
+
+	moved := 0
+	for i := range before {
+		if before[i] == removed {
+			// Entries of the removed backend must be reassigned
+			continue
+		}
+		if backends[before[i]] != remaining[after[i]] {
+			moved++
+		}
+	}
+
+	// Only the entries of the removed backend are expected to move, with
+	// a small number of collateral changes
+	c.Assert(moved <= testTableSize/len(backends), Equals, true,
+		Commentf("%d entries of remaining backends moved", moved))
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package egressmap
+
+import (
+	"fmt"
+	"net"
+	"unsafe"
+
+	"github.com/cilium/cilium/common/types"
+	"github.com/cilium/cilium/pkg/bpf"
+)
+
+const (
+	// MapName is the name of the egress map.
+	MapName = "cilium_egress_v4"
+
+	// MaxEntries is the maximum number of pod and destination prefix pairs
+	// which can be steered through a gateway node.
+	MaxEntries = 16384
+)
+
+var (
+	// Egress is the map of pod and destination prefix pairs steered through
+	// a gateway node.
+	Egress = bpf.NewMap(
+		MapName,
+		bpf.BPF_MAP_TYPE_LPM_TRIE,
+		int(unsafe.Sizeof(Key{})),
+		int(unsafe.Sizeof(Value{})),
+		MaxEntries,
+		bpf.BPF_F_NO_PREALLOC, 0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			k, v := Key{}, Value{}
+
+			if err := bpf.ConvertKeyValue(key, value, &k, &v); err != nil {
+				return nil, nil, err
+			}
+			return &k, &v, nil
+		}).WithCache()
+)
+
+// Key must be in sync with struct egress_key in <bpf/lib/common.h>
+type Key struct {
+	// Prefixlen is the number of bits of SourceIP and DestIP which must
+	// match, SourceIP is always matched in full.
+	Prefixlen uint32
+	SourceIP  types.IPv4
+	DestIP    types.IPv4
+}
+
+// Value must be in sync with struct egress_info in <bpf/lib/common.h>
+type Value struct {
+	EgressIP       types.IPv4
+	TunnelEndpoint types.IPv4
+}
+
+// sourcePrefixBits is the number of bits of the key always matched in full.
+const sourcePrefixBits = uint32(unsafe.Sizeof(Key{}.SourceIP)) * 8
+
+// NewKey returns the key matching the traffic from sourceIP to the
+// destination prefix dest.
+func NewKey(sourceIP net.IP, dest *net.IPNet) Key {
+	k := Key{}
+	ones, _ := dest.Mask.Size()
+	k.Prefixlen = sourcePrefixBits + uint32(ones)
+	copy(k.SourceIP[:], sourceIP.To4())
+	copy(k.DestIP[:], dest.IP.To4().Mask(dest.Mask))
+	return k
+}
+
+// NewValue returns the value steering the traffic through the node at
+// tunnelEndpoint, where it is masqueraded with egressIP.
+func NewValue(egressIP, tunnelEndpoint net.IP) Value {
+	v := Value{}
+	copy(v.EgressIP[:], egressIP.To4())
+	copy(v.TunnelEndpoint[:], tunnelEndpoint.To4())
+	return v
+}
+
+// String converts the key into a human readable string format
+func (k *Key) String() string {
+	return fmt.Sprintf("%s %s/%d", k.SourceIP, k.DestIP, k.Prefixlen-sourcePrefixBits)
+}
+
+// GetKeyPtr returns the unsafe pointer to the BPF key
+func (k *Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
+
+// NewValue returns a new empty instance of the structure representing the BPF
+// map value
+func (k *Key) NewValue() bpf.MapValue { return &Value{} }
+
+// String converts the value into a human readable string format
+func (v *Value) String() string {
+	return fmt.Sprintf("%s %s", v.EgressIP, v.TunnelEndpoint)
+}
+
+// GetValuePtr returns the unsafe pointer to the BPF value.
+func (v *Value) GetValuePtr() unsafe.Pointer { return unsafe.Pointer(v) }
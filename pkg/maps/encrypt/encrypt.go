@@ -0,0 +1,83 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypt
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/cilium/cilium/pkg/bpf"
+)
+
+const (
+	// MapName is the name of the encryption state map
+	MapName = "cilium_encrypt_state"
+
+	// MaxEntries is the maximum number of entries in the map
+	MaxEntries = 1
+)
+
+// EncryptKey is the key of the encryption state map
+type EncryptKey struct {
+	Key uint32
+}
+
+// EncryptValue must match 'struct encrypt_config' in "bpf/lib/common.h".
+type EncryptValue struct {
+	EncryptKey uint8
+}
+
+// String returns the SPI stored in the value
+func (v EncryptValue) String() string { return fmt.Sprintf("%d", v.EncryptKey) }
+
+// GetValuePtr returns the unsafe pointer to the BPF value
+func (v *EncryptValue) GetValuePtr() unsafe.Pointer { return unsafe.Pointer(v) }
+
+// String returns the index of the entry
+func (k EncryptKey) String() string { return fmt.Sprintf("%d", k.Key) }
+
+// GetKeyPtr returns the unsafe pointer to the BPF key
+func (k *EncryptKey) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
+
+// NewValue returns a new empty instance of the structure representing the BPF
+// map value
+func (k EncryptKey) NewValue() bpf.MapValue { return &EncryptValue{} }
+
+var encryptMap = bpf.NewMap(MapName,
+	bpf.MapTypeArray,
+	int(unsafe.Sizeof(EncryptKey{})),
+	int(unsafe.Sizeof(EncryptValue{})),
+	MaxEntries,
+	0, 0,
+	func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+		k, v := EncryptKey{}, EncryptValue{}
+
+		if err := bpf.ConvertKeyValue(key, value, &k, &v); err != nil {
+			return nil, nil, err
+		}
+		return &k, &v, nil
+	})
+
+// MapCreate opens the encryption state map, creating it if needed
+func MapCreate() error {
+	_, err := encryptMap.OpenOrCreate()
+	return err
+}
+
+// MapUpdateContext stores the SPI of the key which the datapath uses to mark
+// packets for encryption
+func MapUpdateContext(spi uint8) error {
+	return encryptMap.Update(&EncryptKey{Key: 0}, &EncryptValue{EncryptKey: spi})
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lbmap
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/cilium/cilium/common/types"
+	"github.com/cilium/cilium/pkg/bpf"
+	"github.com/cilium/cilium/pkg/byteorder"
+	"github.com/cilium/cilium/pkg/option"
+)
+
+var (
+	// Affinity4Map is the BPF map holding the backend selected for each
+	// IPv4 client of a service with session affinity
+	Affinity4Map = bpf.NewMap("cilium_lb4_affinity",
+		bpf.MapTypeHash,
+		int(unsafe.Sizeof(Affinity4Key{})),
+		int(unsafe.Sizeof(AffinityValue{})),
+		MaxEntries,
+		0, 0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			k, v := Affinity4Key{}, AffinityValue{}
+
+			if err := bpf.ConvertKeyValue(key, value, &k, &v); err != nil {
+				return nil, nil, err
+			}
+
+			return k.ToNetwork(), &v, nil
+		})
+	// Affinity6Map is the BPF map holding the backend selected for each
+	// IPv6 client of a service with session affinity
+	Affinity6Map = bpf.NewMap("cilium_lb6_affinity",
+		bpf.MapTypeHash,
+		int(unsafe.Sizeof(Affinity6Key{})),
+		int(unsafe.Sizeof(AffinityValue{})),
+		MaxEntries,
+		0, 0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			k, v := Affinity6Key{}, AffinityValue{}
+
+			if err := bpf.ConvertKeyValue(key, value, &k, &v); err != nil {
+				return nil, nil, err
+			}
+
+			return k.ToNetwork(), &v, nil
+		})
+)
+
+// AffinityKey is the interface describing protocol independent key for
+// session affinity maps.
+type AffinityKey interface {
+	bpf.MapKey
+
+	// Returns the BPF map matching the key type
+	Map() *bpf.Map
+
+	// ToNetwork converts fields to network byte order.
+	ToNetwork() AffinityKey
+}
+
+// Affinity4Key must match 'struct lb4_affinity_key' in "bpf/lib/common.h".
+type Affinity4Key struct {
+	ClientIP types.IPv4
+	RevNatID uint16
+	Pad      uint16
+}
+
+func (k Affinity4Key) NewValue() bpf.MapValue     { return &AffinityValue{} }
+func (k *Affinity4Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
+func (k *Affinity4Key) Map() *bpf.Map             { return Affinity4Map }
+
+func (k *Affinity4Key) String() string {
+	return fmt.Sprintf("%s (%d)", k.ClientIP, k.RevNatID)
+}
+
+// ToNetwork converts Affinity4Key to network byte order.
+func (k *Affinity4Key) ToNetwork() AffinityKey {
+	n := *k
+	n.RevNatID = byteorder.HostToNetwork(n.RevNatID).(uint16)
+	return &n
+}
+
+// Affinity6Key must match 'struct lb6_affinity_key' in "bpf/lib/common.h".
+type Affinity6Key struct {
+	ClientIP types.IPv6
+	RevNatID uint16
+	Pad      uint16
+}
+
+func (k Affinity6Key) NewValue() bpf.MapValue     { return &AffinityValue{} }
+func (k *Affinity6Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
+func (k *Affinity6Key) Map() *bpf.Map             { return Affinity6Map }
+
+func (k *Affinity6Key) String() string {
+	return fmt.Sprintf("%s (%d)", k.ClientIP, k.RevNatID)
+}
+
+// ToNetwork converts Affinity6Key to network byte order.
+func (k *Affinity6Key) ToNetwork() AffinityKey {
+	n := *k
+	n.RevNatID = byteorder.HostToNetwork(n.RevNatID).(uint16)
+	return &n
+}
+
+// AffinityValue must match 'struct lb_affinity_val' in "bpf/lib/common.h".
+type AffinityValue struct {
+	// Lifetime is the expiry of the entry in seconds of monotonic time
+	Lifetime uint32
+	// Slave is the backend slot selected for the client
+	Slave uint16
+	Pad   uint16
+}
+
+func (v *AffinityValue) GetValuePtr() unsafe.Pointer { return unsafe.Pointer(v) }
+
+func (v *AffinityValue) String() string {
+	return fmt.Sprintf("slave=%d expires=%d", v.Slave, v.Lifetime)
+}
+
+// gcAffinity removes all entries of m which have expired at time tsec, and
+// returns the number of entries removed.
+func gcAffinity(m *bpf.Map, tsec uint32) int {
+	expired := []AffinityKey{}
+	err := m.DumpWithCallback(func(key bpf.MapKey, value bpf.MapValue) {
+		if value.(*AffinityValue).Lifetime < tsec {
+			expired = append(expired, key.(AffinityKey).ToNetwork())
+		}
+	})
+	if err != nil {
+		return 0
+	}
+
+	deleted := 0
+	for _, key := range expired {
+		if err := m.Delete(key); err == nil {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// GCAffinity garbage collects session affinity entries whose lifetime has
+// expired.
+func GCAffinity() error {
+	time, err := bpf.GetMtime()
+	if err != nil {
+		return err
+	}
+	tsec := uint32(time / 1000000000)
+
+	deleted := 0
+	if option.Config.EnableIPv4 {
+		deleted += gcAffinity(Affinity4Map, tsec)
+	}
+	if option.Config.EnableIPv6 {
+		deleted += gcAffinity(Affinity6Map, tsec)
+	}
+	if deleted > 0 {
+		log.WithField("count", deleted).Debug("Evicted entries from session affinity table")
+	}
+	return nil
+}
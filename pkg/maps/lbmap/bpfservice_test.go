@@ -210,3 +210,17 @@ func (b *LBMapTestSuite) TestGetBackends(c *C) {
 	c.Assert(backends[0], checker.DeepEquals, b1)
 	c.Assert(backends[1], checker.DeepEquals, b2)
 }
+
+func (b *LBMapTestSuite) TestAffinityTimeout(c *C) {
+	v4 := NewService4Value(2, net.ParseIP("0.0.0.0"), 0, 1, 0)
+	c.Assert(v4.GetAffinityTimeout(), Equals, uint32(0))
+	v4.SetAffinityTimeout(10800)
+	c.Assert(v4.GetAffinityTimeout(), Equals, uint32(10800))
+	c.Assert(v4.GetCount(), Equals, 2)
+
+	v6 := NewService6Value(2, net.ParseIP("::"), 0, 1, 0)
+	c.Assert(v6.GetAffinityTimeout(), Equals, uint32(0))
+	v6.SetAffinityTimeout(60)
+	c.Assert(v6.GetAffinityTimeout(), Equals, uint32(60))
+	c.Assert(v6.GetCount(), Equals, 2)
+}
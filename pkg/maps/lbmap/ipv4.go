@@ -85,6 +85,7 @@ type Service4Key struct {
 func (k Service4Key) IsIPv6() bool               { return false }
 func (k Service4Key) Map() *bpf.Map              { return Service4Map }
 func (k Service4Key) RRMap() *bpf.Map            { return RRSeq4Map }
+func (k Service4Key) MaglevMap() *bpf.Map        { return Maglev4Map }
 func (k Service4Key) NewValue() bpf.MapValue     { return &Service4Value{} }
 func (k *Service4Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
 func (k *Service4Key) GetPort() uint16           { return k.Port }
@@ -162,6 +163,18 @@ func (s *Service4Value) SetRevNat(id int)            { s.RevNat = uint16(id) }
 func (s *Service4Value) SetWeight(weight uint16)     { s.Weight = weight }
 func (s *Service4Value) GetWeight() uint16           { return s.Weight }
 
+// SetAffinityTimeout sets the session affinity timeout of the master service
+// in seconds. The timeout is stored in place of the backend address.
+func (s *Service4Value) SetAffinityTimeout(timeout uint32) {
+	byteorder.Native.PutUint32(s.Address[:], timeout)
+}
+
+// GetAffinityTimeout returns the session affinity timeout of the master
+// service in seconds.
+func (s *Service4Value) GetAffinityTimeout() uint32 {
+	return byteorder.Native.Uint32(s.Address[:])
+}
+
 func (s *Service4Value) SetAddress(ip net.IP) error {
 	ip4 := ip.To4()
 	if ip4 == nil {
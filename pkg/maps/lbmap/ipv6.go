@@ -99,6 +99,7 @@ func NewService6Key(ip net.IP, port uint16, slave uint16) *Service6Key {
 func (k Service6Key) IsIPv6() bool               { return true }
 func (k Service6Key) Map() *bpf.Map              { return Service6Map }
 func (k Service6Key) RRMap() *bpf.Map            { return RRSeq6Map }
+func (k Service6Key) MaglevMap() *bpf.Map        { return Maglev6Map }
 func (k Service6Key) NewValue() bpf.MapValue     { return &Service6Value{} }
 func (k *Service6Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
 func (k *Service6Key) GetPort() uint16           { return k.Port }
@@ -162,6 +163,18 @@ func (s *Service6Value) RevNatKey() RevNatKey        { return &RevNat6Key{s.RevN
 func (s *Service6Value) SetWeight(weight uint16)     { s.Weight = weight }
 func (s *Service6Value) GetWeight() uint16           { return s.Weight }
 
+// SetAffinityTimeout sets the session affinity timeout of the master service
+// in seconds. The timeout is stored in place of the backend address.
+func (s *Service6Value) SetAffinityTimeout(timeout uint32) {
+	byteorder.Native.PutUint32(s.Address[:4], timeout)
+}
+
+// GetAffinityTimeout returns the session affinity timeout of the master
+// service in seconds.
+func (s *Service6Value) GetAffinityTimeout() uint32 {
+	return byteorder.Native.Uint32(s.Address[:4])
+}
+
 func (s *Service6Value) SetAddress(ip net.IP) error {
 	if ip.To4() != nil {
 		return fmt.Errorf("Not an IPv6 address")
@@ -43,6 +43,10 @@ const (
 	maxFrontEnds = 256
 	// MaxSeq is used by daemon for generating bpf define LB_RR_MAX_SEQ.
 	MaxSeq = 31
+	// NodePortMaxEntries is the maximum number of entries in the maps of
+	// connections to service frontends received on the native device,
+	// two per connection
+	NodePortMaxEntries = 262144
 )
 
 var (
@@ -64,6 +68,9 @@ type ServiceKey interface {
 	// Returns the BPF Weighted Round Robin map matching the key type
 	RRMap() *bpf.Map
 
+	// Returns the BPF Maglev lookup table map matching the key type
+	MaglevMap() *bpf.Map
+
 	// Returns a RevNatValue matching a ServiceKey
 	RevNatValue() RevNatValue
 
@@ -111,6 +118,12 @@ type ServiceValue interface {
 	// Get Weight
 	GetWeight() uint16
 
+	// Set session affinity timeout in seconds (master only)
+	SetAffinityTimeout(uint32)
+
+	// Get session affinity timeout in seconds (master only)
+	GetAffinityTimeout() uint32
+
 	// ToNetwork converts fields to network byte order.
 	ToNetwork() ServiceValue
 
@@ -161,6 +174,10 @@ func deleteServiceLocked(key ServiceKey) error {
 		return err
 	}
 	err = lookupAndDeleteServiceWeights(key)
+	if err != nil {
+		return err
+	}
+	err = lookupAndDeleteMaglevTable(key)
 	if err == nil {
 		cache.delete(key)
 	}
@@ -339,17 +356,26 @@ func updateWrrSeq(fe ServiceKey, weights []uint16) error {
 	return updateServiceWeights(fe, svcRRSeq)
 }
 
-func updateMasterService(fe ServiceKey, nbackends int, nonZeroWeights uint16) error {
+func updateMasterService(fe ServiceKey, nbackends int, nonZeroWeights uint16, revNATID int, affinityTimeoutSec uint32) error {
 	fe.SetBackend(0)
 	zeroValue := fe.NewValue().(ServiceValue)
 	zeroValue.SetCount(nbackends)
 	zeroValue.SetWeight(nonZeroWeights)
+	// The service ID is required in the master to key the session affinity
+	// map in the datapath.
+	zeroValue.SetRevNat(revNATID)
+	zeroValue.SetAffinityTimeout(affinityTimeoutSec)
 
 	return updateService(fe, zeroValue)
 }
 
-// UpdateService adds or updates the given service in the bpf maps
-func UpdateService(fe ServiceKey, backends []ServiceValue, addRevNAT bool, revNATID int) error {
+// UpdateService adds or updates the given service in the bpf maps. If
+// affinityTimeoutSec is not 0, connections from the same client IP are sent to
+// the same backend until no new connection has been made for
+// affinityTimeoutSec seconds. If maglev is set, backends of new connections
+// are selected with Maglev consistent hashing.
+func UpdateService(fe ServiceKey, backends []ServiceValue, addRevNAT bool, revNATID int,
+	affinityTimeoutSec uint32, maglev bool) error {
 	var (
 		weights         []uint16
 		nNonZeroWeights uint16
@@ -407,7 +433,7 @@ func UpdateService(fe ServiceKey, backends []ServiceValue, addRevNAT bool, revNA
 		}()
 	}
 
-	err = updateMasterService(fe, len(besValues), nNonZeroWeights)
+	err = updateMasterService(fe, len(besValues), nNonZeroWeights, revNATID, affinityTimeoutSec)
 	if err != nil {
 		return fmt.Errorf("unable to update service %+v: %s", fe, err)
 	}
@@ -417,6 +443,15 @@ func UpdateService(fe ServiceKey, backends []ServiceValue, addRevNAT bool, revNA
 		return fmt.Errorf("unable to update service weights for %s with value %+v: %s", fe.String(), weights, err)
 	}
 
+	if maglev {
+		err = updateMaglevTable(fe, besValues)
+	} else {
+		err = lookupAndDeleteMaglevTable(fe)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to update Maglev lookup table of service %s: %s", fe.String(), err)
+	}
+
 	// Remove old backends that are no longer needed
 	for i := len(besValues) + 1; i <= existingCount; i++ {
 		fe.SetBackend(i)
@@ -598,14 +633,20 @@ func DumpServiceMapsToUserspace(includeMasterBackend bool) (loadbalancer.SVCMap,
 	newSVCList := []*loadbalancer.LBSVC{}
 	errors := []error{}
 	idCache := map[string]loadbalancer.ServiceID{}
+	affinityCache := map[string]uint32{}
 
 	parseSVCEntries := func(key bpf.MapKey, value bpf.MapValue) {
 		svcKey := key.(ServiceKey)
+		svcValue := value.(ServiceValue)
+		// The session affinity timeout is only stored in the master
+		if svcKey.GetBackend() == 0 {
+			fe := serviceKey2L3n4Addr(svcKey)
+			affinityCache[fe.String()] = svcValue.GetAffinityTimeout()
+		}
 		//It's the frontend service so we don't add this one
 		if svcKey.GetBackend() == 0 && !includeMasterBackend {
 			return
 		}
-		svcValue := value.(ServiceValue)
 
 		scopedLog := log.WithFields(logrus.Fields{
 			logfields.BPFMapKey:   svcKey,
@@ -643,22 +684,61 @@ func DumpServiceMapsToUserspace(includeMasterBackend bool) (loadbalancer.SVCMap,
 		}
 	}
 
+	maglevCache := map[string]struct{}{}
+	for _, m := range maglevMaps() {
+		frontends, err := dumpMaglevFrontends(m)
+		if err != nil {
+			// The map does not exist if no service uses Maglev
+			log.WithError(err).Debug("Unable to dump Maglev lookup tables")
+			continue
+		}
+		for fe := range frontends {
+			maglevCache[fe] = struct{}{}
+		}
+	}
+
 	// serviceKeynValue2FEnBE() cannot fill in the service ID reliably as
 	// not all BPF map entries contain the service ID. Do a pass over all
 	// parsed entries and fill in the service ID
 	for i := range newSVCList {
 		newSVCList[i].FE.ID = idCache[newSVCList[i].FE.String()]
+		setAffinityTimeout(newSVCList[i], affinityCache[newSVCList[i].FE.String()])
+		setLBAlgorithm(newSVCList[i], maglevCache)
 	}
 
 	// Do the same for the svcMap
 	for key, svc := range newSVCMap {
 		svc.FE.ID = idCache[svc.FE.String()]
+		setAffinityTimeout(&svc, affinityCache[svc.FE.String()])
+		setLBAlgorithm(&svc, maglevCache)
 		newSVCMap[key] = svc
 	}
 
 	return newSVCMap, newSVCList, errors
 }
 
+func setAffinityTimeout(svc *loadbalancer.LBSVC, timeoutSec uint32) {
+	svc.SessionAffinity = timeoutSec != 0
+	svc.SessionAffinityTimeoutSec = timeoutSec
+}
+
+func setLBAlgorithm(svc *loadbalancer.LBSVC, maglevFrontends map[string]struct{}) {
+	if _, ok := maglevFrontends[svc.FE.String()]; ok {
+		svc.LBAlgorithm = loadbalancer.SVCLBAlgorithmMaglev
+	}
+}
+
+func maglevMaps() []*bpf.Map {
+	maps := []*bpf.Map{}
+	if option.Config.EnableIPv4 {
+		maps = append(maps, Maglev4Map)
+	}
+	if option.Config.EnableIPv6 {
+		maps = append(maps, Maglev6Map)
+	}
+	return maps
+}
+
 // DumpRevNATMapsToUserspace dumps the contents of both the IPv6 and IPv4
 // revNAT BPF maps, and stores the contents of said dumps in a RevNATMap.
 // Returns the errors that occurred while dumping the maps.
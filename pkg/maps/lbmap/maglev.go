@@ -0,0 +1,175 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lbmap
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"unsafe"
+
+	"github.com/cilium/cilium/pkg/bpf"
+	"github.com/cilium/cilium/pkg/maglev"
+	"github.com/cilium/cilium/pkg/option"
+)
+
+const (
+	// MaglevTableSize is the size of the Maglev lookup table of each
+	// service. It must be a prime number. It is used by the daemon for
+	// generating bpf define LB_MAGLEV_LUT_SIZE.
+	MaglevTableSize = 16381
+)
+
+var (
+	// Maglev4Map is the BPF map holding the Maglev lookup tables of IPv4
+	// services
+	Maglev4Map *bpf.Map
+	// Maglev6Map is the BPF map holding the Maglev lookup tables of IPv6
+	// services
+	Maglev6Map *bpf.Map
+)
+
+func init() {
+	InitMaglevMaps(option.LBMaglevMapMaxEntriesDefault)
+}
+
+// InitMaglevMaps sets up the Maglev maps to hold the lookup tables of up to
+// maxEntries services each. Each entry takes MaglevTableSize*2 bytes (~32KiB)
+// of kernel memory, which is preallocated.
+func InitMaglevMaps(maxEntries int) {
+	Maglev4Map = bpf.NewMap("cilium_lb4_maglev",
+		bpf.MapTypeHash,
+		int(unsafe.Sizeof(Service4Key{})),
+		int(unsafe.Sizeof(MaglevValue{})),
+		maxEntries,
+		0, 0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			svcKey, svcVal := Service4Key{}, MaglevValue{}
+
+			if err := bpf.ConvertKeyValue(key, value, &svcKey, &svcVal); err != nil {
+				return nil, nil, err
+			}
+
+			return svcKey.ToNetwork(), &svcVal, nil
+		})
+	Maglev6Map = bpf.NewMap("cilium_lb6_maglev",
+		bpf.MapTypeHash,
+		int(unsafe.Sizeof(Service6Key{})),
+		int(unsafe.Sizeof(MaglevValue{})),
+		maxEntries,
+		0, 0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			svcKey, svcVal := Service6Key{}, MaglevValue{}
+
+			if err := bpf.ConvertKeyValue(key, value, &svcKey, &svcVal); err != nil {
+				return nil, nil, err
+			}
+
+			return svcKey.ToNetwork(), &svcVal, nil
+		})
+}
+
+// MaglevValue must match 'struct lb_maglev_lut' in "bpf/lib/common.h".
+type MaglevValue struct {
+	// Backends maps the hash of a connection to a backend slot of the
+	// service
+	Backends [MaglevTableSize]uint16
+}
+
+func (v *MaglevValue) GetValuePtr() unsafe.Pointer { return unsafe.Pointer(v) }
+
+func (v *MaglevValue) String() string {
+	counts := map[uint16]int{}
+	for _, slave := range v.Backends {
+		counts[slave]++
+	}
+	return fmt.Sprintf("entries per slave=%v", counts)
+}
+
+// backendName returns the name of the given backend used to derive its
+// Maglev permutation. It only depends on the backend address so that all
+// nodes compute the same table for the same set of backends.
+func backendName(be ServiceValue) string {
+	switch v := be.(type) {
+	case *Service4Value:
+		return net.JoinHostPort(v.Address.String(), strconv.Itoa(int(v.Port)))
+	case *Service6Value:
+		return net.JoinHostPort(v.Address.String(), strconv.Itoa(int(v.Port)))
+	}
+	return be.String()
+}
+
+// generateMaglevTable generates the Maglev lookup table for the given
+// backends, where backends[i] is the backend in slot i+1 of the service.
+// Backends occupying multiple slots are only assigned to their first slot.
+func generateMaglevTable(backends []ServiceValue) *MaglevValue {
+	slots := map[string]uint16{}
+	names := []string{}
+	for i, be := range backends {
+		name := backendName(be)
+		if _, ok := slots[name]; !ok {
+			// Slave 0 is reserved for the master slot
+			slots[name] = uint16(i + 1)
+			names = append(names, name)
+		}
+	}
+
+	// The lookup table depends on the order of the backends, sort them
+	// to be independent from the slot allocation of this node.
+	sort.Strings(names)
+
+	value := &MaglevValue{}
+	for i, b := range maglev.GetLookupTable(names, MaglevTableSize) {
+		value.Backends[i] = slots[names[b]]
+	}
+	return value
+}
+
+// updateMaglevTable updates cilium_lb6_maglev or cilium_lb4_maglev bpf maps
+// with the lookup table of the given backends.
+func updateMaglevTable(fe ServiceKey, backends []ServiceValue) error {
+	if len(backends) == 0 {
+		return lookupAndDeleteMaglevTable(fe)
+	}
+
+	if _, err := fe.MaglevMap().OpenOrCreate(); err != nil {
+		return err
+	}
+
+	return fe.MaglevMap().Update(fe.ToNetwork(), generateMaglevTable(backends))
+}
+
+// lookupAndDeleteMaglevTable deletes entry from cilium_lb6_maglev or
+// cilium_lb4_maglev
+func lookupAndDeleteMaglevTable(key ServiceKey) error {
+	_, err := key.MaglevMap().Lookup(key.ToNetwork())
+	if err != nil {
+		// Ignore if entry is not found.
+		return nil
+	}
+
+	return key.MaglevMap().Delete(key.ToNetwork())
+}
+
+// dumpMaglevFrontends returns the set of frontends which have a Maglev
+// lookup table
+func dumpMaglevFrontends(m *bpf.Map) (map[string]struct{}, error) {
+	frontends := map[string]struct{}{}
+	err := m.DumpWithCallback(func(key bpf.MapKey, _ bpf.MapValue) {
+		frontends[serviceKey2L3n4Addr(key.(ServiceKey)).String()] = struct{}{}
+	})
+	return frontends, err
+}
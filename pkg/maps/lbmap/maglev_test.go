@@ -0,0 +1,55 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package lbmap
+
+import (
+	"net"
+
+	. "gopkg.in/check.v1"
+)
+
+func (b *LBMapTestSuite) TestGenerateMaglevTable(c *C) {
+	b1 := createBackend(c, "2.2.2.2", 80, 1)
+	b2 := createBackend(c, "3.3.3.3", 80, 1)
+	b3 := createBackend(c, "4.4.4.4", 80, 1)
+
+	// Slot 3 is a hole filled with a duplicate of b1
+	table := generateMaglevTable([]ServiceValue{b1, b2, b1, b3})
+	counts := map[uint16]int{}
+	for _, slave := range table.Backends {
+		counts[slave]++
+	}
+	c.Assert(len(counts), Equals, 3)
+	c.Assert(counts[1] > 0, Equals, true)
+	c.Assert(counts[2] > 0, Equals, true)
+	c.Assert(counts[3], Equals, 0)
+	c.Assert(counts[4] > 0, Equals, true)
+
+	// A node with a different slot allocation selects the same backends
+	other := generateMaglevTable([]ServiceValue{b3, b2, b1})
+	slotToBackend := map[uint16]string{1: "4.4.4.4", 2: "3.3.3.3", 3: "2.2.2.2"}
+	ourSlots := map[uint16]string{1: "2.2.2.2", 2: "3.3.3.3", 4: "4.4.4.4"}
+	for i := range table.Backends {
+		c.Assert(ourSlots[table.Backends[i]], Equals, slotToBackend[other.Backends[i]])
+	}
+}
+
+func (b *LBMapTestSuite) TestBackendName(c *C) {
+	c.Assert(backendName(createBackend(c, "2.2.2.2", 80, 1)), Equals, "2.2.2.2:80")
+	v6 := NewService6Value(0, net.ParseIP("f00d::1"), 8080, 1, 0)
+	c.Assert(backendName(v6), Equals, "[f00d::1]:8080")
+}
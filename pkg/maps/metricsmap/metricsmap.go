@@ -199,6 +199,12 @@ func SyncMetricsMap() error {
 	return nil
 }
 
+// PossibleCPUs returns the number of possible CPUs, which is the number of
+// values stored for each key of a per-CPU BPF map.
+func PossibleCPUs() int {
+	return possibleCpus
+}
+
 // getNumPossibleCPUs returns a total number of possible CPUS, i.e. CPUs that
 // have been allocated resources and can be brought online if they are present.
 // The number is retrieved by parsing /sys/device/system/cpu/possible.
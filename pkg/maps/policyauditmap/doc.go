@@ -0,0 +1,19 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policyauditmap represents the BPF policy audit map in the BPF
+// programs. It is implemented as a hash table containing the number of packets
+// per endpoint and direction which would have been dropped by policy if the
+// endpoint was not running in policy audit mode.
+package policyauditmap
@@ -0,0 +1,132 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policyauditmap
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/cilium/cilium/pkg/bpf"
+	"github.com/cilium/cilium/pkg/maps/metricsmap"
+)
+
+const (
+	// MapName is the name of the policy audit map.
+	MapName = "cilium_policy_audit"
+
+	// MaxEntries is the maximum number of keys that can be present in the
+	// policy audit map.
+	MaxEntries = 16384
+
+	// dirIngress and dirEgress values should match with
+	// METRIC_INGRESS and METRIC_EGRESS in bpf/lib/common.h
+	dirIngress = 1
+	dirEgress  = 2
+)
+
+var (
+	// PolicyAudit is the map of packets per endpoint and direction which
+	// would have been dropped by policy.
+	PolicyAudit = bpf.NewMap(
+		MapName,
+		bpf.BPF_MAP_TYPE_PERCPU_HASH,
+		int(unsafe.Sizeof(Key{})),
+		int(unsafe.Sizeof(Value{})),
+		MaxEntries,
+		0, 0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			k, v := Key{}, Value{}
+
+			if err := bpf.ConvertKeyValue(key, value, &k, &v); err != nil {
+				return nil, nil, err
+			}
+			return &k, &v, nil
+		})
+)
+
+// Key must be in sync with struct policy_audit_key in <bpf/lib/common.h>
+type Key struct {
+	EndpointID uint16
+	Dir        uint8
+	Pad        uint8
+}
+
+// Value must be in sync with struct policy_audit_value in <bpf/lib/common.h>
+type Value struct {
+	Count uint64
+	Bytes uint64
+}
+
+// String converts the key into a human readable string format
+func (k *Key) String() string {
+	return fmt.Sprintf("endpoint:%d dir:%d", k.EndpointID, k.Dir)
+}
+
+// GetKeyPtr returns the unsafe pointer to the BPF key
+func (k *Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
+
+// NewValue returns a new empty instance of the structure representing the BPF
+// map value
+func (k *Key) NewValue() bpf.MapValue { return &Value{} }
+
+// String converts the value into a human readable string format
+func (v *Value) String() string {
+	return fmt.Sprintf("count:%d bytes:%d", v.Count, v.Bytes)
+}
+
+// GetValuePtr returns the unsafe pointer to the BPF value.
+func (v *Value) GetValuePtr() unsafe.Pointer { return unsafe.Pointer(v) }
+
+// lookup returns the sum of the per-CPU values stored for the given key. A
+// missing key is reported as a zero value.
+func lookup(key *Key) Value {
+	var sum Value
+
+	values := make([]Value, metricsmap.PossibleCPUs())
+	if len(values) == 0 {
+		return sum
+	}
+
+	if err := bpf.LookupElement(PolicyAudit.GetFd(), key.GetKeyPtr(), unsafe.Pointer(&values[0])); err != nil {
+		return sum
+	}
+
+	for _, v := range values {
+		sum.Count += v.Count
+		sum.Bytes += v.Bytes
+	}
+	return sum
+}
+
+// GetAuditedDrops returns the number of packets to (ingress) and from (egress)
+// the endpoint which would have been dropped by policy.
+func GetAuditedDrops(endpointID uint16) (ingress, egress uint64) {
+	ingress = lookup(&Key{EndpointID: endpointID, Dir: dirIngress}).Count
+	egress = lookup(&Key{EndpointID: endpointID, Dir: dirEgress}).Count
+	return
+}
+
+// DeleteEndpoint removes the policy audit counters of the endpoint.
+func DeleteEndpoint(endpointID uint16) []error {
+	var errors []error
+	for _, dir := range []uint8{dirIngress, dirEgress} {
+		err, errno := PolicyAudit.DeleteWithErrno(&Key{EndpointID: endpointID, Dir: dir})
+		if err != nil && errno != syscall.ENOENT {
+			errors = append(errors, err)
+		}
+	}
+	return errors
+}
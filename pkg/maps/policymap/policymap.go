@@ -56,30 +56,77 @@ type PolicyMap struct {
 	mutex lock.Mutex
 }
 
+// PolicyEntryFlags is a bitmask of flags of a PolicyEntry. It must match the
+// bitfield in policy_entry in bpf/lib/common.h.
+type PolicyEntryFlags uint8
+
+const (
+	// PolicyEntryFlagDeny is set for entries which deny the traffic matched
+	// by the corresponding key.
+	PolicyEntryFlagDeny PolicyEntryFlags = 1 << iota
+)
+
 func (pe *PolicyEntry) String() string {
+	if pe.IsDeny() {
+		return fmt.Sprintf("deny %d %d", pe.Packets, pe.Bytes)
+	}
 	return fmt.Sprintf("%d %d %d", pe.ProxyPort, pe.Packets, pe.Bytes)
 }
 
 // PolicyKey represents a key in the BPF policy map for an endpoint. It must
 // match the layout of policy_key in bpf/lib/common.h.
 type PolicyKey struct {
-	Identity         uint32
-	DestPort         uint16 // In network byte-order
-	Nexthdr          uint8
+	Identity uint32
+	DestPort uint16 // In network byte-order
+	Nexthdr  uint8
+	// TrafficDirection holds the egress and dport_wildcard bitfields of
+	// policy_key. Use GetDirection() and GetDestPortWildcard() to access
+	// them.
 	TrafficDirection uint8
 }
 
+const (
+	// policyKeyDirectionMask is the mask of the traffic direction in
+	// PolicyKey.TrafficDirection.
+	policyKeyDirectionMask = 0x1
+	// policyKeyWildcardShift is the offset of the number of wildcarded
+	// port bits in PolicyKey.TrafficDirection.
+	policyKeyWildcardShift = 1
+	// policyKeyWildcardMask is the mask of the number of wildcarded port
+	// bits in PolicyKey.TrafficDirection, after shifting.
+	policyKeyWildcardMask = 0xf
+)
+
+// NewKey returns a PolicyKey for identity `id` sending traffic in direction
+// `trafficDirection` to destination port `dport` over protocol `proto`,
+// ignoring the `dportWildcard` low-order bits of `dport`. It is assumed that
+// `dport` is in host byte-order, and so is the returned key.
+func NewKey(id uint32, dport uint16, dportWildcard uint8, proto uint8, trafficDirection uint8) PolicyKey {
+	return PolicyKey{
+		Identity:         id,
+		DestPort:         dport,
+		Nexthdr:          proto,
+		TrafficDirection: trafficDirection&policyKeyDirectionMask | (dportWildcard&policyKeyWildcardMask)<<policyKeyWildcardShift,
+	}
+}
+
 // PolicyEntry represents an entry in the BPF policy map for an endpoint. It must
 // match the layout of policy_entry in bpf/lib/common.h.
 type PolicyEntry struct {
 	ProxyPort uint16 // In network byte-order
-	Pad0      uint16
+	Flags     PolicyEntryFlags
+	Pad0      uint8
 	Pad1      uint16
 	Pad2      uint16
 	Packets   uint64
 	Bytes     uint64
 }
 
+// IsDeny returns true if the entry denies the traffic matched by its key.
+func (pe *PolicyEntry) IsDeny() bool {
+	return pe.Flags&PolicyEntryFlagDeny != 0
+}
+
 func (pe *PolicyEntry) Add(oPe PolicyEntry) {
 	pe.Packets += oPe.Packets
 	pe.Bytes += oPe.Bytes
@@ -108,10 +155,18 @@ func (p PolicyEntriesDump) Less(i, j int) bool {
 
 func (key *PolicyKey) String() string {
 
-	trafficDirectionString := (trafficdirection.TrafficDirection)(key.TrafficDirection).String()
+	trafficDirectionString := (trafficdirection.TrafficDirection)(key.GetDirection()).String()
+	if wildcard := key.GetDestPortWildcard(); wildcard != 0 {
+		dport := uint32(byteorder.NetworkToHost(key.DestPort).(uint16))
+		return fmt.Sprintf("%s: %d %d-%d/%d", trafficDirectionString, key.Identity, dport, dport+1<<wildcard-1, key.Nexthdr)
+	}
 	if key.DestPort != 0 {
 		return fmt.Sprintf("%s: %d %d/%d", trafficDirectionString, key.Identity, byteorder.NetworkToHost(key.DestPort), key.Nexthdr)
 	}
+	if key.Nexthdr != 0 {
+		// Port 0 matches all ports of the protocol
+		return fmt.Sprintf("%s: %d ANY/%d", trafficDirectionString, key.Identity, key.Nexthdr)
+	}
 	return fmt.Sprintf("%s: %d", trafficDirectionString, key.Identity)
 }
 
@@ -133,7 +188,13 @@ func (key *PolicyKey) GetProto() uint8 {
 
 // GetDirection returns the traffic direction for key.
 func (key *PolicyKey) GetDirection() uint8 {
-	return key.TrafficDirection
+	return key.TrafficDirection & policyKeyDirectionMask
+}
+
+// GetDestPortWildcard returns the number of low-order bits of the port in key
+// which are ignored, so that key matches a block of ports.
+func (key *PolicyKey) GetDestPortWildcard() uint8 {
+	return key.TrafficDirection >> policyKeyWildcardShift & policyKeyWildcardMask
 }
 
 // ToHost returns a copy of key with fields converted from network byte-order
@@ -163,23 +224,39 @@ func (key *PolicyKey) ToNetwork() PolicyKey {
 // AllowKey pushes an entry into the PolicyMap for the given PolicyKey k.
 // Returns an error if the update of the PolicyMap fails.
 func (pm *PolicyMap) AllowKey(k PolicyKey, proxyPort uint16) error {
-	return pm.Allow(k.Identity, k.DestPort, u8proto.U8proto(k.Nexthdr), trafficdirection.TrafficDirection(k.TrafficDirection), proxyPort)
+	key := k.ToNetwork()
+	entry := PolicyEntry{ProxyPort: byteorder.HostToNetwork(proxyPort).(uint16)}
+	return bpf.UpdateElement(pm.Fd, unsafe.Pointer(&key), unsafe.Pointer(&entry), 0)
 }
 
 // Allow pushes an entry into the PolicyMap to allow traffic in the given
 // `trafficDirection` for identity `id` with destination port `dport` over
 // protocol `proto`. It is assumed that `dport` and `proxyPort` are in host byte-order.
 func (pm *PolicyMap) Allow(id uint32, dport uint16, proto u8proto.U8proto, trafficDirection trafficdirection.TrafficDirection, proxyPort uint16) error {
-	key := PolicyKey{Identity: id, DestPort: byteorder.HostToNetwork(dport).(uint16), Nexthdr: uint8(proto), TrafficDirection: trafficDirection.Uint8()}
-	entry := PolicyEntry{ProxyPort: byteorder.HostToNetwork(proxyPort).(uint16)}
+	return pm.AllowKey(NewKey(id, dport, 0, uint8(proto), trafficDirection.Uint8()), proxyPort)
+}
+
+// DenyKey pushes an entry into the PolicyMap which denies the traffic for the
+// given PolicyKey k. Returns an error if the update of the PolicyMap fails.
+func (pm *PolicyMap) DenyKey(k PolicyKey) error {
+	key := k.ToNetwork()
+	entry := PolicyEntry{Flags: PolicyEntryFlagDeny}
 	return bpf.UpdateElement(pm.Fd, unsafe.Pointer(&key), unsafe.Pointer(&entry), 0)
 }
 
+// Deny pushes an entry into the PolicyMap to deny traffic in the given
+// `trafficDirection` for identity `id` with destination port `dport` over
+// protocol `proto`. It is assumed that `dport` is in host byte-order.
+func (pm *PolicyMap) Deny(id uint32, dport uint16, proto u8proto.U8proto, trafficDirection trafficdirection.TrafficDirection) error {
+	return pm.DenyKey(NewKey(id, dport, 0, uint8(proto), trafficDirection.Uint8()))
+}
+
 // Exists determines whether PolicyMap currently contains an entry that
 // allows traffic in `trafficDirection` for identity `id` with destination port
 // `dport`over protocol `proto`. It is assumed that `dport` is in host byte-order.
 func (pm *PolicyMap) Exists(id uint32, dport uint16, proto u8proto.U8proto, trafficDirection trafficdirection.TrafficDirection) bool {
-	key := PolicyKey{Identity: id, DestPort: byteorder.HostToNetwork(dport).(uint16), Nexthdr: uint8(proto), TrafficDirection: trafficDirection.Uint8()}
+	k := NewKey(id, dport, 0, uint8(proto), trafficDirection.Uint8())
+	key := k.ToNetwork()
 	var entry PolicyEntry
 	return bpf.LookupElement(pm.Fd, unsafe.Pointer(&key), unsafe.Pointer(&entry)) == nil
 }
@@ -187,7 +264,8 @@ func (pm *PolicyMap) Exists(id uint32, dport uint16, proto u8proto.U8proto, traf
 // DeleteKey deletes the key-value pair from the given PolicyMap with PolicyKey
 // k. Returns an error if deletion from the PolicyMap fails.
 func (pm *PolicyMap) DeleteKey(k PolicyKey) error {
-	return pm.Delete(k.Identity, k.DestPort, u8proto.U8proto(k.Nexthdr), trafficdirection.TrafficDirection(k.TrafficDirection))
+	key := k.ToNetwork()
+	return bpf.DeleteElement(pm.Fd, unsafe.Pointer(&key))
 }
 
 // Delete removes an entry from the PolicyMap for identity `id`
@@ -195,8 +273,7 @@ func (pm *PolicyMap) DeleteKey(k PolicyKey) error {
 // over protocol `proto`. It is assumed that `dport` is in host byte-order.
 // Returns an error if the deletion did not succeed.
 func (pm *PolicyMap) Delete(id uint32, dport uint16, proto u8proto.U8proto, trafficDirection trafficdirection.TrafficDirection) error {
-	key := PolicyKey{Identity: id, DestPort: byteorder.HostToNetwork(dport).(uint16), Nexthdr: uint8(proto), TrafficDirection: trafficDirection.Uint8()}
-	return bpf.DeleteElement(pm.Fd, unsafe.Pointer(&key))
+	return pm.DeleteKey(NewKey(id, dport, 0, uint8(proto), trafficDirection.Uint8()))
 }
 
 // DeleteEntry removes an entry from the PolicyMap. It can be used in
@@ -145,3 +145,19 @@ func (pm *PolicyMapTestSuite) TestPolicyEntriesDump_Less(c *C) {
 		c.Assert(got, Equals, tt.want, Commentf("Test Name: %s", tt.name))
 	}
 }
+
+func (pm *PolicyMapTestSuite) TestPolicyKeyDestPortWildcard(c *C) {
+	key := NewKey(100, 32000, 8, 6, trafficdirection.Egress.Uint8())
+	c.Assert(key.GetDirection(), Equals, trafficdirection.Egress.Uint8())
+	c.Assert(key.GetDestPortWildcard(), Equals, uint8(8))
+	c.Assert(key.TrafficDirection, Equals, uint8(0x11))
+
+	networkKey := key.ToNetwork()
+	c.Assert(networkKey.String(), Equals, "Egress: 100 32000-32255/6")
+
+	key = NewKey(100, 80, 0, 6, trafficdirection.Ingress.Uint8())
+	c.Assert(key.GetDirection(), Equals, trafficdirection.Ingress.Uint8())
+	c.Assert(key.GetDestPortWildcard(), Equals, uint8(0))
+	networkKey = key.ToNetwork()
+	c.Assert(networkKey.String(), Equals, "Ingress: 100 80/6")
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throttlemap
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/cilium/pkg/bpf"
+)
+
+const (
+	// MapName is the name of the throttle map.
+	MapName = "cilium_throttle"
+
+	// MaxEntries is the maximum number of endpoints which can have their
+	// egress bandwidth limited.
+	MaxEntries = 16384
+)
+
+var (
+	// Throttle is the map of egress bandwidth limits per endpoint.
+	Throttle = bpf.NewMap(
+		MapName,
+		bpf.BPF_MAP_TYPE_HASH,
+		int(unsafe.Sizeof(Key{})),
+		int(unsafe.Sizeof(Value{})),
+		MaxEntries,
+		0, 0,
+		func(key []byte, value []byte) (bpf.MapKey, bpf.MapValue, error) {
+			k, v := Key{}, Value{}
+
+			if err := bpf.ConvertKeyValue(key, value, &k, &v); err != nil {
+				return nil, nil, err
+			}
+			return &k, &v, nil
+		})
+)
+
+// Key must be in sync with struct throttle_key in <bpf/lib/common.h>
+type Key struct {
+	EndpointID uint16
+	Pad        uint16
+}
+
+// Value must be in sync with struct throttle_value in <bpf/lib/common.h>
+type Value struct {
+	// Rate is the egress bandwidth limit in bytes per second
+	Rate uint64
+	// TimeLast is the departure time of the last packet in nanoseconds,
+	// maintained by the datapath
+	TimeLast uint64
+	// TimeHorizonDrop is the maximum delay of a packet in nanoseconds
+	TimeHorizonDrop uint64
+}
+
+// String converts the key into a human readable string format
+func (k *Key) String() string {
+	return fmt.Sprintf("endpoint:%d", k.EndpointID)
+}
+
+// GetKeyPtr returns the unsafe pointer to the BPF key
+func (k *Key) GetKeyPtr() unsafe.Pointer { return unsafe.Pointer(k) }
+
+// NewValue returns a new empty instance of the structure representing the BPF
+// map value
+func (k *Key) NewValue() bpf.MapValue { return &Value{} }
+
+// String converts the value into a human readable string format
+func (v *Value) String() string {
+	return fmt.Sprintf("rate:%d last:%d horizon:%d", v.Rate, v.TimeLast, v.TimeHorizonDrop)
+}
+
+// GetValuePtr returns the unsafe pointer to the BPF value.
+func (v *Value) GetValuePtr() unsafe.Pointer { return unsafe.Pointer(v) }
+
+// Update sets the egress bandwidth limit of the endpoint to rate bytes per
+// second. Packets which would have to be delayed by more than horizon to
+// comply with the limit are dropped.
+func Update(endpointID uint16, rate uint64, horizon time.Duration) error {
+	return Throttle.Update(&Key{EndpointID: endpointID}, &Value{
+		Rate:            rate,
+		TimeHorizonDrop: uint64(horizon.Nanoseconds()),
+	})
+}
+
+// DeleteEndpoint removes the egress bandwidth limit of the endpoint.
+func DeleteEndpoint(endpointID uint16) error {
+	err, errno := Throttle.DeleteWithErrno(&Key{EndpointID: endpointID})
+	if err != nil && errno != syscall.ENOENT {
+		return err
+	}
+	return nil
+}
@@ -57,6 +57,9 @@ var errors = map[uint8]string{
 	163: "Unknown connection tracking state",
 	164: "Local host is unreachable",
 	165: "No configuration available to perform policy decision",
+	166: "Policy denied by denylist",
+	167: "Egress bandwidth limit exceeded",
+	168: "No IPsec key configured",
 }
 
 // DropReason prints the drop reason in a human readable string
@@ -17,6 +17,8 @@ package monitor
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/cilium/cilium/pkg/monitor/api"
 )
 
 const (
@@ -53,6 +55,7 @@ const (
 	TraceFromHost
 	TraceFromStack
 	TraceFromOverlay
+	TracePolicyAudit
 )
 
 var traceObsPoints = map[uint8]string{
@@ -66,6 +69,7 @@ var traceObsPoints = map[uint8]string{
 	TraceFromHost:    "from-host",
 	TraceFromStack:   "from-stack",
 	TraceFromOverlay: "from-overlay",
+	TracePolicyAudit: "policy-audit",
 }
 
 func obsPoint(obsPoint uint8) string {
@@ -97,6 +101,15 @@ func connState(reason uint8) string {
 	return fmt.Sprintf("%d", reason)
 }
 
+// state returns the connection state of the traced packet. For policy audit
+// notifications, the reason carries the drop reason which was ignored.
+func (n *TraceNotify) state() string {
+	if n.ObsPoint == TracePolicyAudit {
+		return "would-drop (" + api.DropReason(n.Reason) + ")"
+	}
+	return connState(n.Reason)
+}
+
 func (n *TraceNotify) traceSummary() string {
 	switch n.ObsPoint {
 	case TraceToLxc:
@@ -119,6 +132,8 @@ func (n *TraceNotify) traceSummary() string {
 		return "<- stack"
 	case TraceFromOverlay:
 		return "<- overlay"
+	case TracePolicyAudit:
+		return "?? policy audit"
 	default:
 		return "unknown trace"
 	}
@@ -128,13 +143,13 @@ func (n *TraceNotify) traceSummary() string {
 func (n *TraceNotify) DumpInfo(data []byte) {
 	fmt.Printf("%s flow %#x identity %d->%d state %s ifindex %s: %s\n",
 		n.traceSummary(), n.Hash, n.SrcLabel, n.DstLabel,
-		connState(n.Reason), ifname(int(n.Ifindex)), GetConnectionSummary(data[TraceNotifyLen:]))
+		n.state(), ifname(int(n.Ifindex)), GetConnectionSummary(data[TraceNotifyLen:]))
 }
 
 // DumpVerbose prints the trace notification in human readable form
 func (n *TraceNotify) DumpVerbose(dissect bool, data []byte, prefix string) {
 	fmt.Printf("%s MARK %#x FROM %d %s: %d bytes (%d captured), state %s",
-		prefix, n.Hash, n.Source, obsPoint(n.ObsPoint), n.OrigLen, n.CapLen, connState(n.Reason))
+		prefix, n.Hash, n.Source, obsPoint(n.ObsPoint), n.OrigLen, n.CapLen, n.state())
 
 	if n.Ifindex != 0 {
 		fmt.Printf(", interface %s", ifname(int(n.Ifindex)))
@@ -199,7 +214,7 @@ func TraceNotifyToVerbose(n *TraceNotify) TraceNotifyVerbose {
 		Type:             "trace",
 		Mark:             fmt.Sprintf("%#x", n.Hash),
 		Ifindex:          ifname(int(n.Ifindex)),
-		State:            connState(n.Reason),
+		State:            n.state(),
 		ObservationPoint: obsPoint(n.ObsPoint),
 		TraceSummary:     n.traceSummary(),
 		Source:           n.Source,
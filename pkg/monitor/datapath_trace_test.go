@@ -0,0 +1,46 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package monitor
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *MonitorSuite) TestTraceNotifyToVerbose(c *C) {
+	tn := &TraceNotify{
+		ObsPoint: TraceToLxc,
+		Reason:   TraceReasonCtEstablished,
+		SrcLabel: 1000,
+		DstLabel: 2000,
+		DstID:    42,
+	}
+	v := TraceNotifyToVerbose(tn)
+	c.Assert(v.ObservationPoint, Equals, "to-endpoint")
+	c.Assert(v.State, Equals, "established")
+
+	// Policy audit notifications carry the ignored drop reason
+	tn = &TraceNotify{
+		ObsPoint: TracePolicyAudit,
+		Reason:   133,
+		SrcLabel: 1000,
+		DstLabel: 2000,
+	}
+	v = TraceNotifyToVerbose(tn)
+	c.Assert(v.ObservationPoint, Equals, "policy-audit")
+	c.Assert(v.TraceSummary, Equals, "?? policy audit")
+	c.Assert(v.State, Equals, "would-drop (Policy denied (L3))")
+}
@@ -0,0 +1,220 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observer
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/cilium/cilium/api/v1/flow"
+)
+
+// FilterFunc returns true if the flow matches the filter.
+type FilterFunc func(f *flow.Flow) bool
+
+// FilterFuncs is a list of filters, each built from a single flow.FlowFilter.
+type FilterFuncs []FilterFunc
+
+// MatchOne returns true if at least one of the filters matches the flow. An
+// empty list of filters matches every flow.
+func (fs FilterFuncs) MatchOne(f *flow.Flow) bool {
+	if len(fs) == 0 {
+		return true
+	}
+	for _, filter := range fs {
+		if filter(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchNone returns true if none of the filters match the flow.
+func (fs FilterFuncs) MatchNone(f *flow.Flow) bool {
+	for _, filter := range fs {
+		if filter(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns true if the flow is matched by the whitelist and not matched
+// by the blacklist.
+func Apply(whitelist, blacklist FilterFuncs, f *flow.Flow) bool {
+	return whitelist.MatchOne(f) && blacklist.MatchNone(f)
+}
+
+// BuildFilterList builds a filter function for each of the given flow filters.
+func BuildFilterList(filters []*flow.FlowFilter) (FilterFuncs, error) {
+	fs := make(FilterFuncs, 0, len(filters))
+	for _, ff := range filters {
+		f, err := BuildFilter(ff)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	return fs, nil
+}
+
+// BuildFilter builds a filter function matching flows which match all the
+// non-empty fields of ff.
+func BuildFilter(ff *flow.FlowFilter) (FilterFunc, error) {
+	var fs []FilterFunc
+
+	if len(ff.GetSourceIp()) > 0 {
+		f, err := filterByIP(ff.GetSourceIp(), (*flow.IP).GetSource)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	if len(ff.GetDestinationIp()) > 0 {
+		f, err := filterByIP(ff.GetDestinationIp(), (*flow.IP).GetDestination)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	if len(ff.GetSourcePod()) > 0 {
+		fs = append(fs, filterByPod(ff.GetSourcePod(), (*flow.Flow).GetSource))
+	}
+	if len(ff.GetDestinationPod()) > 0 {
+		fs = append(fs, filterByPod(ff.GetDestinationPod(), (*flow.Flow).GetDestination))
+	}
+	if len(ff.GetSourceIdentity()) > 0 {
+		fs = append(fs, filterByIdentity(ff.GetSourceIdentity(), (*flow.Flow).GetSource))
+	}
+	if len(ff.GetDestinationIdentity()) > 0 {
+		fs = append(fs, filterByIdentity(ff.GetDestinationIdentity(), (*flow.Flow).GetDestination))
+	}
+	if len(ff.GetVerdict()) > 0 {
+		fs = append(fs, filterByVerdict(ff.GetVerdict()))
+	}
+	if len(ff.GetType()) > 0 {
+		fs = append(fs, filterByType(ff.GetType()))
+	}
+
+	return func(f *flow.Flow) bool {
+		for _, filter := range fs {
+			if !filter(f) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// filterByIP matches flows whose IP returned by getIP is equal to one of the
+// given IPs or contained in one of the given CIDRs.
+func filterByIP(ips []string, getIP func(*flow.IP) string) (FilterFunc, error) {
+	nets := make([]*net.IPNet, 0, len(ips))
+	for _, s := range ips {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", s)
+			}
+			bits := net.IPv6len * 8
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = net.IPv4len * 8
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %s", s, err)
+		}
+		nets = append(nets, ipnet)
+	}
+
+	return func(f *flow.Flow) bool {
+		ip := net.ParseIP(getIP(f.GetIP()))
+		if ip == nil {
+			return false
+		}
+		for _, ipnet := range nets {
+			if ipnet.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// filterByPod matches flows whose endpoint returned by getEndpoint has a pod
+// name starting with one of the given prefixes. A prefix of the form
+// "namespace/prefix" additionally requires the namespace to match.
+func filterByPod(pods []string, getEndpoint func(*flow.Flow) *flow.Endpoint) FilterFunc {
+	return func(f *flow.Flow) bool {
+		ep := getEndpoint(f)
+		if ep.GetPodName() == "" {
+			return false
+		}
+		for _, pod := range pods {
+			prefix := pod
+			if i := strings.Index(pod, "/"); i >= 0 {
+				if pod[:i] != ep.GetNamespace() {
+					continue
+				}
+				prefix = pod[i+1:]
+			}
+			if strings.HasPrefix(ep.GetPodName(), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// filterByIdentity matches flows whose endpoint returned by getEndpoint has
+// one of the given security identities.
+func filterByIdentity(identities []uint64, getEndpoint func(*flow.Flow) *flow.Endpoint) FilterFunc {
+	return func(f *flow.Flow) bool {
+		id := getEndpoint(f).GetIdentity()
+		for _, identity := range identities {
+			if identity == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func filterByVerdict(verdicts []flow.Verdict) FilterFunc {
+	return func(f *flow.Flow) bool {
+		for _, verdict := range verdicts {
+			if verdict == f.GetVerdict() {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func filterByType(types []flow.FlowType) FilterFunc {
+	return func(f *flow.Flow) bool {
+		for _, typ := range types {
+			if typ == f.GetType() {
+				return true
+			}
+		}
+		return false
+	}
+}
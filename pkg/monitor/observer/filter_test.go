@@ -0,0 +1,167 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package observer
+
+import (
+	"github.com/cilium/cilium/api/v1/flow"
+
+	. "gopkg.in/check.v1"
+)
+
+var (
+	flowFrontend = &flow.Flow{
+		Verdict: flow.Verdict_FORWARDED,
+		Type:    flow.FlowType_L3_L4,
+		IP:      &flow.IP{Source: "10.0.0.1", Destination: "10.0.1.1"},
+		Source: &flow.Endpoint{
+			Identity:  1000,
+			Namespace: "default",
+			PodName:   "frontend-5d8f",
+		},
+		Destination: &flow.Endpoint{
+			Identity:  2000,
+			Namespace: "kube-system",
+			PodName:   "coredns-6f4f",
+		},
+	}
+	flowDropped = &flow.Flow{
+		Verdict: flow.Verdict_DROPPED,
+		Type:    flow.FlowType_L3_L4,
+		IP:      &flow.IP{Source: "f00d::1", Destination: "192.168.1.1"},
+		Source:  &flow.Endpoint{Identity: 2},
+		Destination: &flow.Endpoint{
+			Identity:  1000,
+			Namespace: "default",
+			PodName:   "frontend-5d8f",
+		},
+	}
+	flowL7 = &flow.Flow{
+		Verdict:     flow.Verdict_FORWARDED,
+		Type:        flow.FlowType_L7,
+		Source:      &flow.Endpoint{},
+		Destination: &flow.Endpoint{},
+	}
+)
+
+func (s *ObserverSuite) TestFilter(c *C) {
+	tests := []struct {
+		name     string
+		filter   *flow.FlowFilter
+		frontend bool
+		dropped  bool
+		l7       bool
+	}{
+		{
+			name:     "empty filter",
+			filter:   &flow.FlowFilter{},
+			frontend: true, dropped: true, l7: true,
+		},
+		{
+			name:     "source IP",
+			filter:   &flow.FlowFilter{SourceIp: []string{"10.0.0.1", "f00d::2"}},
+			frontend: true,
+		},
+		{
+			name:    "source CIDR",
+			filter:  &flow.FlowFilter{SourceIp: []string{"f00d::/64"}},
+			dropped: true,
+		},
+		{
+			name:     "destination CIDR",
+			filter:   &flow.FlowFilter{DestinationIp: []string{"10.0.0.0/16", "192.168.1.1"}},
+			frontend: true, dropped: true,
+		},
+		{
+			name:     "pod prefix in any namespace",
+			filter:   &flow.FlowFilter{DestinationPod: []string{"coredns", "frontend"}},
+			frontend: true, dropped: true,
+		},
+		{
+			name:    "pod prefix in namespace",
+			filter:  &flow.FlowFilter{DestinationPod: []string{"default/"}},
+			dropped: true,
+		},
+		{
+			name:   "pod prefix in wrong namespace",
+			filter: &flow.FlowFilter{SourcePod: []string{"kube-system/frontend"}},
+		},
+		{
+			name:    "source identity",
+			filter:  &flow.FlowFilter{SourceIdentity: []uint64{2, 3}},
+			dropped: true,
+		},
+		{
+			name:     "destination identity",
+			filter:   &flow.FlowFilter{DestinationIdentity: []uint64{2000}},
+			frontend: true,
+		},
+		{
+			name:     "verdict",
+			filter:   &flow.FlowFilter{Verdict: []flow.Verdict{flow.Verdict_FORWARDED}},
+			frontend: true, l7: true,
+		},
+		{
+			name:   "type",
+			filter: &flow.FlowFilter{Type: []flow.FlowType{flow.FlowType_L7}},
+			l7:     true,
+		},
+		{
+			name: "all fields must match",
+			filter: &flow.FlowFilter{
+				DestinationPod: []string{"default/frontend"},
+				Verdict:        []flow.Verdict{flow.Verdict_FORWARDED},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		f, err := BuildFilter(tt.filter)
+		c.Assert(err, IsNil, Commentf(tt.name))
+		c.Assert(f(flowFrontend), Equals, tt.frontend, Commentf(tt.name))
+		c.Assert(f(flowDropped), Equals, tt.dropped, Commentf(tt.name))
+		c.Assert(f(flowL7), Equals, tt.l7, Commentf(tt.name))
+	}
+}
+
+func (s *ObserverSuite) TestFilterInvalid(c *C) {
+	_, err := BuildFilter(&flow.FlowFilter{SourceIp: []string{"10.0.0.300"}})
+	c.Assert(err, Not(IsNil))
+
+	_, err = BuildFilterList([]*flow.FlowFilter{{}, {DestinationIp: []string{"10.0.0.0/33"}}})
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *ObserverSuite) TestApply(c *C) {
+	dropped, err := BuildFilterList([]*flow.FlowFilter{{Verdict: []flow.Verdict{flow.Verdict_DROPPED}}})
+	c.Assert(err, IsNil)
+	l7, err := BuildFilterList([]*flow.FlowFilter{{Type: []flow.FlowType{flow.FlowType_L7}}})
+	c.Assert(err, IsNil)
+
+	// Empty lists match everything
+	c.Assert(Apply(nil, nil, flowFrontend), Equals, true)
+
+	// Whitelist filters are ORed
+	whitelist := append(dropped, l7...)
+	c.Assert(Apply(whitelist, nil, flowFrontend), Equals, false)
+	c.Assert(Apply(whitelist, nil, flowDropped), Equals, true)
+	c.Assert(Apply(whitelist, nil, flowL7), Equals, true)
+
+	// The blacklist takes precedence
+	c.Assert(Apply(whitelist, l7, flowL7), Equals, false)
+	c.Assert(Apply(nil, dropped, flowDropped), Equals, false)
+	c.Assert(Apply(nil, dropped, flowFrontend), Equals, true)
+}
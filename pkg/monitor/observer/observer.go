@@ -0,0 +1,87 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer decodes the events of the node monitor into structured
+// flows, keeps the most recent flows in a ring buffer and serves them through
+// the flow.Observer gRPC service.
+package observer
+
+import (
+	"context"
+	"time"
+
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/monitor/payload"
+)
+
+var log = logging.DefaultLogger.WithField(logfields.LogSubsys, "flow-observer")
+
+// event is a monitor payload along with the time it was received
+type event struct {
+	payload   *payload.Payload
+	timestamp time.Time
+}
+
+// Observer decodes monitor payloads into flows and stores them in a ring
+// buffer. It implements the flow.ObserverServer interface.
+type Observer struct {
+	ring   *Ring
+	parser *Parser
+	queue  chan event
+}
+
+// NewObserver returns an observer keeping up to bufferSize flows decoded by
+// parser. Up to queueSize payloads are queued for decoding, any further
+// payloads are dropped until the queue drains.
+func NewObserver(bufferSize, queueSize int, parser *Parser) *Observer {
+	return &Observer{
+		ring:   NewRing(bufferSize),
+		parser: parser,
+		queue:  make(chan event, queueSize),
+	}
+}
+
+// Start decodes queued payloads into flows until ctx is cancelled. It is
+// intended to be a goroutine.
+func (o *Observer) Start(ctx context.Context) {
+	log.WithField("capacity", o.ring.Cap()).Info("Starting flow observer")
+	defer log.Info("Stopped flow observer")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-o.queue:
+			f, err := o.parser.Decode(e.payload, e.timestamp)
+			switch {
+			case err == ErrUnsupportedEvent:
+				continue
+			case err != nil:
+				log.WithError(err).Debug("Unable to decode flow")
+				continue
+			}
+			o.ring.Write(f)
+		}
+	}
+}
+
+// Enqueue queues the payload for decoding. It never blocks.
+func (o *Observer) Enqueue(pl *payload.Payload) {
+	select {
+	case o.queue <- event{payload: pl, timestamp: time.Now()}:
+	default:
+		log.Debug("Flow observer queue is full, dropping message")
+	}
+}
@@ -0,0 +1,390 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/flow"
+	"github.com/cilium/cilium/pkg/byteorder"
+	"github.com/cilium/cilium/pkg/monitor"
+	monitorAPI "github.com/cilium/cilium/pkg/monitor/api"
+	"github.com/cilium/cilium/pkg/monitor/payload"
+	"github.com/cilium/cilium/pkg/proxy/accesslog"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	// namespaceLabelPrefix is the prefix of the identity label holding the
+	// namespace of a pod
+	namespaceLabelPrefix = "k8s:io.kubernetes.pod.namespace="
+)
+
+var (
+	// ErrUnsupportedEvent is returned by Parser.Decode for payloads which
+	// do not represent a flow, such as debug messages or agent events.
+	ErrUnsupportedEvent = errors.New("event does not represent a flow")
+)
+
+// Parser decodes monitor payloads into flows. A Parser is not safe for
+// concurrent use.
+type Parser struct {
+	resolver Resolver
+	nodeName string
+
+	eth     layers.Ethernet
+	ip4     layers.IPv4
+	ip6     layers.IPv6
+	icmp4   layers.ICMPv4
+	icmp6   layers.ICMPv6
+	tcp     layers.TCP
+	udp     layers.UDP
+	decoded []gopacket.LayerType
+	packet  *gopacket.DecodingLayerParser
+}
+
+// NewParser returns a new parser for flows observed on the node nodeName.
+// If resolver is not nil, it is used to add pod names and labels to the
+// flow endpoints.
+func NewParser(resolver Resolver, nodeName string) *Parser {
+	p := &Parser{
+		resolver: resolver,
+		nodeName: nodeName,
+		decoded:  []gopacket.LayerType{},
+	}
+	p.packet = gopacket.NewDecodingLayerParser(layers.LayerTypeEthernet,
+		&p.eth, &p.ip4, &p.ip6, &p.icmp4, &p.icmp6, &p.tcp, &p.udp)
+	return p
+}
+
+// Decode decodes the payload, received at time ts, into a flow. It returns
+// ErrUnsupportedEvent if the payload does not represent a flow.
+func (p *Parser) Decode(pl *payload.Payload, ts time.Time) (*flow.Flow, error) {
+	if pl.Type != payload.EventSample || len(pl.Data) == 0 {
+		return nil, ErrUnsupportedEvent
+	}
+
+	var (
+		f   *flow.Flow
+		err error
+	)
+	switch pl.Data[0] {
+	case monitorAPI.MessageTypeDrop, monitorAPI.MessageTypeTrace:
+		f, err = p.decodeL34(pl.Data, ts)
+	case monitorAPI.MessageTypeAccessLog:
+		f, err = p.decodeL7(pl.Data, ts)
+	default:
+		return nil, ErrUnsupportedEvent
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.NodeName = p.nodeName
+	p.resolveEndpoint(f.Source)
+	p.resolveEndpoint(f.Destination)
+
+	return f, nil
+}
+
+// decodeL34 decodes a drop or trace notification and the packet headers
+// captured with it.
+func (p *Parser) decodeL34(data []byte, ts time.Time) (*flow.Flow, error) {
+	var (
+		srcID, dstID       uint16
+		srcLabel, dstLabel uint32
+		packet             []byte
+	)
+
+	f := &flow.Flow{Type: flow.FlowType_L3_L4}
+
+	switch data[0] {
+	case monitorAPI.MessageTypeDrop:
+		dn := monitor.DropNotify{}
+		if err := binary.Read(bytes.NewReader(data), byteorder.Native, &dn); err != nil {
+			return nil, fmt.Errorf("unable to decode drop notification: %s", err)
+		}
+		srcID, dstID = dn.Source, uint16(dn.DstID)
+		srcLabel, dstLabel = dn.SrcLabel, dn.DstLabel
+		f.Verdict = flow.Verdict_DROPPED
+		f.DropReason = uint32(dn.SubType)
+		f.Summary = "drop (" + monitorAPI.DropReason(dn.SubType) + ")"
+		if len(data) > monitor.DropNotifyLen {
+			packet = data[monitor.DropNotifyLen:]
+		}
+
+	case monitorAPI.MessageTypeTrace:
+		tn := monitor.TraceNotify{}
+		if err := binary.Read(bytes.NewReader(data), byteorder.Native, &tn); err != nil {
+			return nil, fmt.Errorf("unable to decode trace notification: %s", err)
+		}
+		srcID, dstID = tn.Source, tn.DstID
+		srcLabel, dstLabel = tn.SrcLabel, tn.DstLabel
+		f.Verdict = flow.Verdict_FORWARDED
+		if tn.ObsPoint == monitor.TracePolicyAudit {
+			f.Verdict = flow.Verdict_AUDIT
+			f.DropReason = uint32(tn.Reason)
+		}
+		f.Summary = monitor.TraceNotifyToVerbose(&tn).ObservationPoint
+		if len(data) > monitor.TraceNotifyLen {
+			packet = data[monitor.TraceNotifyLen:]
+		}
+	}
+
+	timestamp, err := ptypes.TimestampProto(ts)
+	if err != nil {
+		return nil, err
+	}
+	f.Time = timestamp
+
+	// The event source is the endpoint whose program emitted the event. It
+	// is only the source of the flow if the packet is not being delivered to
+	// that same endpoint.
+	f.Source = &flow.Endpoint{Identity: uint64(srcLabel)}
+	if srcID != dstID {
+		f.Source.ID = uint64(srcID)
+	}
+	f.Destination = &flow.Endpoint{ID: uint64(dstID), Identity: uint64(dstLabel)}
+
+	if len(packet) > 0 {
+		p.decodePacket(f, packet)
+	}
+
+	return f, nil
+}
+
+// decodePacket fills in the layer 3 and layer 4 information of f from the
+// packet headers.
+func (p *Parser) decodePacket(f *flow.Flow, packet []byte) {
+	// Errors are expected for truncated packets or unsupported layers, the
+	// successfully decoded layers are still used.
+	p.packet.DecodeLayers(packet, &p.decoded)
+
+	for _, typ := range p.decoded {
+		switch typ {
+		case layers.LayerTypeIPv4:
+			f.IP = &flow.IP{
+				Source:      p.ip4.SrcIP.String(),
+				Destination: p.ip4.DstIP.String(),
+				IpVersion:   flow.IPVersion_IPv4,
+			}
+		case layers.LayerTypeIPv6:
+			f.IP = &flow.IP{
+				Source:      p.ip6.SrcIP.String(),
+				Destination: p.ip6.DstIP.String(),
+				IpVersion:   flow.IPVersion_IPv6,
+			}
+		case layers.LayerTypeTCP:
+			f.L4 = &flow.Layer4{
+				Protocol:        flow.L4Protocol_TCP,
+				SourcePort:      uint32(p.tcp.SrcPort),
+				DestinationPort: uint32(p.tcp.DstPort),
+			}
+			if flags := tcpFlags(&p.tcp); flags != "" {
+				f.Summary += " TCP Flags: " + flags
+			}
+		case layers.LayerTypeUDP:
+			f.L4 = &flow.Layer4{
+				Protocol:        flow.L4Protocol_UDP,
+				SourcePort:      uint32(p.udp.SrcPort),
+				DestinationPort: uint32(p.udp.DstPort),
+			}
+		case layers.LayerTypeICMPv4:
+			f.L4 = &flow.Layer4{Protocol: flow.L4Protocol_ICMPv4}
+			f.Summary += " ICMPv4 " + p.icmp4.TypeCode.String()
+		case layers.LayerTypeICMPv6:
+			f.L4 = &flow.Layer4{Protocol: flow.L4Protocol_ICMPv6}
+			f.Summary += " ICMPv6 " + p.icmp6.TypeCode.String()
+		}
+	}
+}
+
+func tcpFlags(tcp *layers.TCP) string {
+	var flags []string
+	if tcp.SYN {
+		flags = append(flags, "SYN")
+	}
+	if tcp.ACK {
+		flags = append(flags, "ACK")
+	}
+	if tcp.RST {
+		flags = append(flags, "RST")
+	}
+	if tcp.FIN {
+		flags = append(flags, "FIN")
+	}
+	return strings.Join(flags, ", ")
+}
+
+// decodeL7 decodes a proxy access log record.
+func (p *Parser) decodeL7(data []byte, ts time.Time) (*flow.Flow, error) {
+	lr := monitor.LogRecordNotify{}
+	if err := gob.NewDecoder(bytes.NewReader(data[1:])).Decode(&lr); err != nil {
+		return nil, fmt.Errorf("unable to decode access log record: %s", err)
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, lr.Timestamp); err == nil {
+		ts = t
+	}
+	timestamp, err := ptypes.TimestampProto(ts)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &flow.Flow{
+		Time: timestamp,
+		Type: flow.FlowType_L7,
+		Source: &flow.Endpoint{
+			ID:       lr.SourceEndpoint.ID,
+			Identity: lr.SourceEndpoint.Identity,
+			Labels:   lr.SourceEndpoint.Labels,
+		},
+		Destination: &flow.Endpoint{
+			ID:       lr.DestinationEndpoint.ID,
+			Identity: lr.DestinationEndpoint.Identity,
+			Labels:   lr.DestinationEndpoint.Labels,
+		},
+		L7: &flow.Layer7{},
+	}
+
+	switch lr.Verdict {
+	case accesslog.VerdictForwarded:
+		f.Verdict = flow.Verdict_FORWARDED
+	case accesslog.VerdictDenied:
+		f.Verdict = flow.Verdict_DROPPED
+	case accesslog.VerdictError:
+		f.Verdict = flow.Verdict_ERROR
+	}
+	if lr.DropReason != nil {
+		f.DropReason = uint32(*lr.DropReason)
+	}
+
+	switch lr.IPVersion {
+	case accesslog.VersionIPv4:
+		f.IP = &flow.IP{
+			Source:      lr.SourceEndpoint.IPv4,
+			Destination: lr.DestinationEndpoint.IPv4,
+			IpVersion:   flow.IPVersion_IPv4,
+		}
+	case accesslog.VersionIPV6:
+		f.IP = &flow.IP{
+			Source:      lr.SourceEndpoint.IPv6,
+			Destination: lr.DestinationEndpoint.IPv6,
+			IpVersion:   flow.IPVersion_IPv6,
+		}
+	}
+
+	f.L4 = &flow.Layer4{
+		SourcePort:      uint32(lr.SourceEndpoint.Port),
+		DestinationPort: uint32(lr.DestinationEndpoint.Port),
+	}
+	switch lr.TransportProtocol {
+	case 6:
+		f.L4.Protocol = flow.L4Protocol_TCP
+	case 17:
+		f.L4.Protocol = flow.L4Protocol_UDP
+	}
+
+	switch lr.Type {
+	case accesslog.TypeRequest:
+		f.L7.Type = flow.L7FlowType_REQUEST
+	case accesslog.TypeResponse:
+		f.L7.Type = flow.L7FlowType_RESPONSE
+	case accesslog.TypeSample:
+		f.L7.Type = flow.L7FlowType_SAMPLE
+	}
+
+	switch {
+	case lr.HTTP != nil:
+		f.L7.Protocol = "http"
+		f.L7.Http = &flow.HTTP{
+			Code:     uint32(lr.HTTP.Code),
+			Method:   lr.HTTP.Method,
+			Protocol: lr.HTTP.Protocol,
+		}
+		if lr.HTTP.URL != nil {
+			f.L7.Http.Url = lr.HTTP.URL.String()
+		}
+		f.Summary = fmt.Sprintf("%s %s %s", lr.HTTP.Protocol, lr.HTTP.Method, f.L7.Http.Url)
+		if lr.Type == accesslog.TypeResponse {
+			f.Summary += fmt.Sprintf(" => %d", lr.HTTP.Code)
+		}
+
+	case lr.Kafka != nil:
+		f.L7.Protocol = "kafka"
+		f.L7.Kafka = &flow.Kafka{
+			ErrorCode:     int32(lr.Kafka.ErrorCode),
+			ApiVersion:    int32(lr.Kafka.APIVersion),
+			ApiKey:        lr.Kafka.APIKey,
+			CorrelationId: lr.Kafka.CorrelationID,
+			Topic:         lr.Kafka.Topic.Topic,
+		}
+		f.Summary = fmt.Sprintf("Kafka %s topic %s", lr.Kafka.APIKey, lr.Kafka.Topic.Topic)
+		if lr.Type == accesslog.TypeResponse {
+			f.Summary += fmt.Sprintf(" => %d", lr.Kafka.ErrorCode)
+		}
+
+	case lr.DNS != nil:
+		f.L7.Protocol = "dns"
+		f.L7.Dns = &flow.DNS{
+			Query:  lr.DNS.Query,
+			Ttl:    lr.DNS.TTL,
+			Cnames: lr.DNS.CNAMEs,
+		}
+		for _, ip := range lr.DNS.IPs {
+			f.L7.Dns.Ips = append(f.L7.Dns.Ips, ip.String())
+		}
+		f.Summary = "DNS " + lr.DNS.Query
+		if len(f.L7.Dns.Ips) > 0 {
+			f.Summary += " => " + strings.Join(f.L7.Dns.Ips, ",")
+		}
+
+	case lr.L7 != nil:
+		f.L7.Protocol = lr.L7.Proto
+		f.Summary = lr.L7.Proto
+	}
+
+	return f, nil
+}
+
+// resolveEndpoint adds the pod name, namespace and labels to ep.
+func (p *Parser) resolveEndpoint(ep *flow.Endpoint) {
+	if p.resolver != nil {
+		if ep.ID != 0 {
+			ep.Namespace, ep.PodName, _ = p.resolver.GetEndpointPodName(ep.ID)
+		}
+		if len(ep.Labels) == 0 && ep.Identity != 0 {
+			ep.Labels, _ = p.resolver.GetIdentityLabels(ep.Identity)
+		}
+	}
+
+	if ep.Namespace == "" {
+		for _, l := range ep.Labels {
+			if strings.HasPrefix(l, namespaceLabelPrefix) {
+				ep.Namespace = strings.TrimPrefix(l, namespaceLabelPrefix)
+				break
+			}
+		}
+	}
+}
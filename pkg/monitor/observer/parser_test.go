@@ -0,0 +1,226 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package observer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/flow"
+	"github.com/cilium/cilium/pkg/byteorder"
+	"github.com/cilium/cilium/pkg/monitor"
+	monitorAPI "github.com/cilium/cilium/pkg/monitor/api"
+	"github.com/cilium/cilium/pkg/monitor/payload"
+	"github.com/cilium/cilium/pkg/proxy/accesslog"
+
+	"github.com/golang/protobuf/ptypes"
+	. "gopkg.in/check.v1"
+)
+
+// Ether(src="01:23:45:67:89:ab", dst="02:33:45:67:89:ab")/IP(src="1.2.3.4",dst="5.6.7.8")/TCP(sport=80,dport=443)
+var tcpSYN = []byte{2, 51, 69, 103, 137, 171, 1, 35, 69, 103, 137, 171, 8, 0, 69, 0, 0, 40, 0, 1, 0, 0, 64, 6, 106, 188, 1, 2, 3, 4, 5, 6, 7, 8, 0, 80, 1, 187, 0, 0, 0, 0, 0, 0, 0, 0, 80, 2, 32, 0, 125, 196, 0, 0}
+
+type fakeResolver struct {
+	pods       map[uint64]string
+	identities map[uint64][]string
+}
+
+func (r *fakeResolver) GetEndpointPodName(id uint64) (string, string, bool) {
+	pod, ok := r.pods[id]
+	return "default", pod, ok
+}
+
+func (r *fakeResolver) GetIdentityLabels(id uint64) ([]string, bool) {
+	labels, ok := r.identities[id]
+	return labels, ok
+}
+
+var resolver = &fakeResolver{
+	pods: map[uint64]string{
+		1234: "frontend",
+	},
+	identities: map[uint64][]string{
+		2000: {"k8s:app=backend", "k8s:io.kubernetes.pod.namespace=prod"},
+	},
+}
+
+func eventPayload(c *C, notification interface{}, packet []byte) *payload.Payload {
+	buf := &bytes.Buffer{}
+	c.Assert(binary.Write(buf, byteorder.Native, notification), IsNil)
+	buf.Write(packet)
+	return &payload.Payload{Data: buf.Bytes(), Type: payload.EventSample}
+}
+
+func (s *ObserverSuite) TestDecodeUnsupported(c *C) {
+	p := NewParser(nil, "node")
+
+	_, err := p.Decode(&payload.Payload{Type: payload.RecordLost}, time.Now())
+	c.Assert(err, Equals, ErrUnsupportedEvent)
+
+	_, err = p.Decode(&payload.Payload{Data: []byte{monitorAPI.MessageTypeDebug}, Type: payload.EventSample}, time.Now())
+	c.Assert(err, Equals, ErrUnsupportedEvent)
+}
+
+func (s *ObserverSuite) TestDecodeDrop(c *C) {
+	p := NewParser(resolver, "node")
+
+	dn := monitor.DropNotify{
+		Type:     monitorAPI.MessageTypeDrop,
+		SubType:  133,
+		Source:   1234,
+		SrcLabel: 1000,
+		DstLabel: 2000,
+	}
+	ts := time.Unix(1500000000, 0)
+	f, err := p.Decode(eventPayload(c, &dn, tcpSYN), ts)
+	c.Assert(err, IsNil)
+
+	c.Assert(f.Type, Equals, flow.FlowType_L3_L4)
+	c.Assert(f.Verdict, Equals, flow.Verdict_DROPPED)
+	c.Assert(f.DropReason, Equals, uint32(133))
+	c.Assert(f.NodeName, Equals, "node")
+	c.Assert(f.Summary, Equals, "drop (Policy denied (L3)) TCP Flags: SYN")
+
+	t, err := ptypes.Timestamp(f.Time)
+	c.Assert(err, IsNil)
+	c.Assert(t.Equal(ts), Equals, true)
+
+	c.Assert(f.IP, DeepEquals, &flow.IP{Source: "1.2.3.4", Destination: "5.6.7.8", IpVersion: flow.IPVersion_IPv4})
+	c.Assert(f.L4, DeepEquals, &flow.Layer4{Protocol: flow.L4Protocol_TCP, SourcePort: 80, DestinationPort: 443})
+
+	c.Assert(f.Source.ID, Equals, uint64(1234))
+	c.Assert(f.Source.Identity, Equals, uint64(1000))
+	c.Assert(f.Source.Namespace, Equals, "default")
+	c.Assert(f.Source.PodName, Equals, "frontend")
+
+	c.Assert(f.Destination.ID, Equals, uint64(0))
+	c.Assert(f.Destination.Identity, Equals, uint64(2000))
+	c.Assert(f.Destination.Labels, DeepEquals, []string{"k8s:app=backend", "k8s:io.kubernetes.pod.namespace=prod"})
+	c.Assert(f.Destination.Namespace, Equals, "prod")
+	c.Assert(f.Destination.PodName, Equals, "")
+}
+
+func (s *ObserverSuite) TestDecodeTrace(c *C) {
+	p := NewParser(nil, "node")
+
+	// Packets delivered to the endpoint which emitted the event have no
+	// source endpoint
+	tn := monitor.TraceNotify{
+		Type:     monitorAPI.MessageTypeTrace,
+		ObsPoint: monitor.TraceToLxc,
+		Source:   1234,
+		DstID:    1234,
+	}
+	f, err := p.Decode(eventPayload(c, &tn, tcpSYN), time.Now())
+	c.Assert(err, IsNil)
+	c.Assert(f.Verdict, Equals, flow.Verdict_FORWARDED)
+	c.Assert(f.Summary, Equals, "to-endpoint TCP Flags: SYN")
+	c.Assert(f.Source.ID, Equals, uint64(0))
+	c.Assert(f.Destination.ID, Equals, uint64(1234))
+
+	tn = monitor.TraceNotify{
+		Type:     monitorAPI.MessageTypeTrace,
+		ObsPoint: monitor.TracePolicyAudit,
+		Reason:   133,
+		Source:   1234,
+	}
+	f, err = p.Decode(eventPayload(c, &tn, nil), time.Now())
+	c.Assert(err, IsNil)
+	c.Assert(f.Verdict, Equals, flow.Verdict_AUDIT)
+	c.Assert(f.DropReason, Equals, uint32(133))
+	c.Assert(f.Source.ID, Equals, uint64(1234))
+	c.Assert(f.IP, IsNil)
+	c.Assert(f.L4, IsNil)
+}
+
+func (s *ObserverSuite) TestDecodeL7(c *C) {
+	p := NewParser(resolver, "node")
+
+	lr := monitor.LogRecordNotify{
+		LogRecord: accesslog.LogRecord{
+			Type:      accesslog.TypeResponse,
+			Timestamp: "2019-05-01T10:00:00.000000001Z",
+			SourceEndpoint: accesslog.EndpointInfo{
+				ID:       1234,
+				IPv4:     "10.0.0.1",
+				Port:     41000,
+				Identity: 1000,
+				Labels:   []string{"k8s:app=frontend"},
+			},
+			DestinationEndpoint: accesslog.EndpointInfo{
+				IPv4:     "10.0.1.1",
+				Port:     53,
+				Identity: 2000,
+			},
+			IPVersion:         accesslog.VersionIPv4,
+			Verdict:           accesslog.VerdictForwarded,
+			TransportProtocol: 17,
+			DNS: &accesslog.LogRecordDNS{
+				Query: "cilium.io.",
+				IPs:   []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("2.2.2.2")},
+				TTL:   30,
+			},
+		},
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteByte(monitorAPI.MessageTypeAccessLog)
+	c.Assert(gob.NewEncoder(buf).Encode(lr), IsNil)
+
+	f, err := p.Decode(&payload.Payload{Data: buf.Bytes(), Type: payload.EventSample}, time.Now())
+	c.Assert(err, IsNil)
+
+	c.Assert(f.Type, Equals, flow.FlowType_L7)
+	c.Assert(f.Verdict, Equals, flow.Verdict_FORWARDED)
+	c.Assert(f.Summary, Equals, "DNS cilium.io. => 1.1.1.1,2.2.2.2")
+
+	t, err := ptypes.Timestamp(f.Time)
+	c.Assert(err, IsNil)
+	c.Assert(t.Nanosecond(), Equals, 1)
+
+	c.Assert(f.IP, DeepEquals, &flow.IP{Source: "10.0.0.1", Destination: "10.0.1.1", IpVersion: flow.IPVersion_IPv4})
+	c.Assert(f.L4, DeepEquals, &flow.Layer4{Protocol: flow.L4Protocol_UDP, SourcePort: 41000, DestinationPort: 53})
+	c.Assert(f.L7.Type, Equals, flow.L7FlowType_RESPONSE)
+	c.Assert(f.L7.Protocol, Equals, "dns")
+	c.Assert(f.L7.Dns, DeepEquals, &flow.DNS{Query: "cilium.io.", Ips: []string{"1.1.1.1", "2.2.2.2"}, Ttl: 30})
+
+	// Labels carried by the record are not overwritten
+	c.Assert(f.Source.Labels, DeepEquals, []string{"k8s:app=frontend"})
+	c.Assert(f.Source.PodName, Equals, "frontend")
+	c.Assert(f.Destination.Namespace, Equals, "prod")
+
+	lr.HTTP, lr.DNS = &accesslog.LogRecordHTTP{
+		Code:     403,
+		Method:   "GET",
+		URL:      &url.URL{Scheme: "http", Host: "backend", Path: "/private"},
+		Protocol: "HTTP/1.1",
+	}, nil
+	lr.Verdict = accesslog.VerdictDenied
+	buf = &bytes.Buffer{}
+	buf.WriteByte(monitorAPI.MessageTypeAccessLog)
+	c.Assert(gob.NewEncoder(buf).Encode(lr), IsNil)
+
+	f, err = p.Decode(&payload.Payload{Data: buf.Bytes(), Type: payload.EventSample}, time.Now())
+	c.Assert(err, IsNil)
+	c.Assert(f.Verdict, Equals, flow.Verdict_DROPPED)
+	c.Assert(f.Summary, Equals, "HTTP/1.1 GET http://backend/private => 403")
+	c.Assert(f.L7.Protocol, Equals, "http")
+	c.Assert(f.L7.Http, DeepEquals, &flow.HTTP{Code: 403, Method: "GET", Url: "http://backend/private", Protocol: "HTTP/1.1"})
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cilium/cilium/pkg/client"
+	"github.com/cilium/cilium/pkg/lock"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+const (
+	// resolverCacheTTL is the duration for which endpoint and identity
+	// lookups are cached, including failed lookups.
+	resolverCacheTTL = time.Minute
+
+	// resolverCacheSize is the maximum number of endpoints and identities
+	// cached each. The least recently used entries are evicted first, so
+	// that deleted endpoints and identities do not accumulate.
+	resolverCacheSize = 4096
+)
+
+// Resolver provides the metadata used to enrich flows.
+type Resolver interface {
+	// GetEndpointPodName returns the namespace and pod name of the local
+	// endpoint with the given ID.
+	GetEndpointPodName(id uint64) (namespace, podName string, ok bool)
+
+	// GetIdentityLabels returns the labels of the given security identity.
+	GetIdentityLabels(id uint64) (labels []string, ok bool)
+}
+
+type podEntry struct {
+	namespace string
+	podName   string
+	ok        bool
+	expires   time.Time
+}
+
+type labelsEntry struct {
+	labels  []string
+	ok      bool
+	expires time.Time
+}
+
+// AgentResolver is a Resolver which retrieves endpoints and identities from
+// the cilium-agent API and caches up to resolverCacheSize of them each for
+// resolverCacheTTL.
+type AgentResolver struct {
+	mutex lock.Mutex
+
+	client     *client.Client
+	pods       *simplelru.LRU
+	identities *simplelru.LRU
+}
+
+// NewAgentResolver returns a Resolver querying the cilium-agent API through c.
+func NewAgentResolver(c *client.Client) *AgentResolver {
+	// NewLRU only fails for a non-positive size
+	pods, _ := simplelru.NewLRU(resolverCacheSize, nil)
+	identities, _ := simplelru.NewLRU(resolverCacheSize, nil)
+	return &AgentResolver{
+		client:     c,
+		pods:       pods,
+		identities: identities,
+	}
+}
+
+// GetEndpointPodName returns the namespace and pod name of the local endpoint
+// with the given ID.
+func (r *AgentResolver) GetEndpointPodName(id uint64) (string, string, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	if v, ok := r.pods.Get(id); ok {
+		if e := v.(podEntry); now.Before(e.expires) {
+			return e.namespace, e.podName, e.ok
+		}
+	}
+
+	e := podEntry{expires: now.Add(resolverCacheTTL)}
+	ep, err := r.client.EndpointGet(strconv.FormatUint(id, 10))
+	if err == nil && ep.Status != nil && ep.Status.ExternalIdentifiers != nil {
+		// The pod name is reported as "namespace/pod"
+		podName := ep.Status.ExternalIdentifiers.PodName
+		if i := strings.Index(podName, "/"); i >= 0 {
+			e.namespace, e.podName = podName[:i], podName[i+1:]
+		} else {
+			e.podName = podName
+		}
+		e.ok = e.podName != ""
+	}
+	r.pods.Add(id, e)
+
+	return e.namespace, e.podName, e.ok
+}
+
+// GetIdentityLabels returns the labels of the given security identity.
+func (r *AgentResolver) GetIdentityLabels(id uint64) ([]string, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	if v, ok := r.identities.Get(id); ok {
+		if e := v.(labelsEntry); now.Before(e.expires) {
+			return e.labels, e.ok
+		}
+	}
+
+	e := labelsEntry{expires: now.Add(resolverCacheTTL)}
+	identity, err := r.client.IdentityGet(strconv.FormatUint(id, 10))
+	if err == nil && identity != nil {
+		e.labels, e.ok = identity.Labels, true
+	}
+	r.identities.Add(id, e)
+
+	return e.labels, e.ok
+}
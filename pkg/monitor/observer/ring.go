@@ -0,0 +1,124 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observer
+
+import (
+	"context"
+
+	"github.com/cilium/cilium/api/v1/flow"
+	"github.com/cilium/cilium/pkg/lock"
+)
+
+// Ring is a fixed size ring buffer of flows. Each flow written to the ring is
+// assigned a monotonically increasing position which readers use to keep
+// track of the flows they have already consumed.
+type Ring struct {
+	mutex lock.RWMutex
+
+	buf []*flow.Flow
+	// write is the position the next flow will be written at
+	write uint64
+	// notify is closed and replaced whenever a flow is written
+	notify chan struct{}
+}
+
+// NewRing returns a ring buffer holding up to capacity flows.
+func NewRing(capacity int) *Ring {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Ring{
+		buf:    make([]*flow.Flow, capacity),
+		notify: make(chan struct{}),
+	}
+}
+
+// Cap returns the capacity of the ring.
+func (r *Ring) Cap() int {
+	return len(r.buf)
+}
+
+// Len returns the number of flows currently held by the ring.
+func (r *Ring) Len() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if r.write < uint64(len(r.buf)) {
+		return int(r.write)
+	}
+	return len(r.buf)
+}
+
+// Write adds f to the ring, overwriting the oldest flow if the ring is full,
+// and wakes up all readers waiting for new flows.
+func (r *Ring) Write(f *flow.Flow) {
+	r.mutex.Lock()
+	r.buf[r.write%uint64(len(r.buf))] = f
+	r.write++
+	close(r.notify)
+	r.notify = make(chan struct{})
+	r.mutex.Unlock()
+}
+
+// readLocked returns the flows between position from and the current write
+// position, skipping the flows which have already been overwritten. The
+// returned position is the one to continue reading from.
+func (r *Ring) readLocked(from uint64) ([]*flow.Flow, uint64) {
+	if oldest := r.oldestLocked(); from < oldest {
+		from = oldest
+	}
+	flows := make([]*flow.Flow, 0, r.write-from)
+	for pos := from; pos < r.write; pos++ {
+		flows = append(flows, r.buf[pos%uint64(len(r.buf))])
+	}
+	return flows, r.write
+}
+
+func (r *Ring) oldestLocked() uint64 {
+	if r.write < uint64(len(r.buf)) {
+		return 0
+	}
+	return r.write - uint64(len(r.buf))
+}
+
+// ReadAll returns all flows currently held by the ring, oldest first, and the
+// position to continue reading new flows from.
+func (r *Ring) ReadAll() ([]*flow.Flow, uint64) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.readLocked(0)
+}
+
+// ReadFrom returns all flows written at or after position from, oldest first,
+// and the position to continue reading from. If no such flow has been written
+// yet, ReadFrom blocks until one is written or ctx is cancelled. Flows which
+// have been overwritten before being read are skipped.
+func (r *Ring) ReadFrom(ctx context.Context, from uint64) ([]*flow.Flow, uint64, error) {
+	for {
+		r.mutex.RLock()
+		if from < r.write {
+			flows, next := r.readLocked(from)
+			r.mutex.RUnlock()
+			return flows, next, nil
+		}
+		notify := r.notify
+		r.mutex.RUnlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return nil, from, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package observer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/flow"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type ObserverSuite struct{}
+
+var _ = Suite(&ObserverSuite{})
+
+func summaries(flows []*flow.Flow) []string {
+	s := make([]string, 0, len(flows))
+	for _, f := range flows {
+		s = append(s, f.Summary)
+	}
+	return s
+}
+
+func (s *ObserverSuite) TestRing(c *C) {
+	r := NewRing(3)
+	c.Assert(r.Cap(), Equals, 3)
+	c.Assert(r.Len(), Equals, 0)
+
+	flows, next := r.ReadAll()
+	c.Assert(flows, HasLen, 0)
+	c.Assert(next, Equals, uint64(0))
+
+	r.Write(&flow.Flow{Summary: "a"})
+	r.Write(&flow.Flow{Summary: "b"})
+	c.Assert(r.Len(), Equals, 2)
+
+	flows, next = r.ReadAll()
+	c.Assert(summaries(flows), DeepEquals, []string{"a", "b"})
+	c.Assert(next, Equals, uint64(2))
+
+	// Writing past the capacity overwrites the oldest flows
+	r.Write(&flow.Flow{Summary: "c"})
+	r.Write(&flow.Flow{Summary: "d"})
+	c.Assert(r.Len(), Equals, 3)
+
+	flows, _ = r.ReadAll()
+	c.Assert(summaries(flows), DeepEquals, []string{"b", "c", "d"})
+
+	// Reading from a position which has been overwritten skips the lost
+	// flows
+	flows, next, err := r.ReadFrom(context.Background(), 0)
+	c.Assert(err, IsNil)
+	c.Assert(summaries(flows), DeepEquals, []string{"b", "c", "d"})
+	c.Assert(next, Equals, uint64(4))
+
+	flows, next, err = r.ReadFrom(context.Background(), 3)
+	c.Assert(err, IsNil)
+	c.Assert(summaries(flows), DeepEquals, []string{"d"})
+	c.Assert(next, Equals, uint64(4))
+}
+
+func (s *ObserverSuite) TestRingReadFromBlocks(c *C) {
+	r := NewRing(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, next, err := r.ReadFrom(ctx, 0)
+	c.Assert(err, Equals, context.DeadlineExceeded)
+	c.Assert(next, Equals, uint64(0))
+
+	done := make(chan []*flow.Flow)
+	go func() {
+		flows, _, _ := r.ReadFrom(context.Background(), 0)
+		done <- flows
+	}()
+	r.Write(&flow.Flow{Summary: "a"})
+
+	select {
+	case flows := <-done:
+		c.Assert(summaries(flows), DeepEquals, []string{"a"})
+	case <-time.After(5 * time.Second):
+		c.Fatal("ReadFrom did not return after a flow was written")
+	}
+}
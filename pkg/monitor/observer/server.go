@@ -0,0 +1,73 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package observer
+
+import (
+	"github.com/cilium/cilium/api/v1/flow"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetFlows sends the flows stored in the ring buffer which match the request
+// filters, limited to the most recent req.Number flows if set. If req.Follow
+// is set, it then continues sending matching flows as they are observed until
+// the client goes away.
+func (o *Observer) GetFlows(req *flow.GetFlowsRequest, stream flow.Observer_GetFlowsServer) error {
+	whitelist, err := BuildFilterList(req.GetWhitelist())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid whitelist: %s", err)
+	}
+	blacklist, err := BuildFilterList(req.GetBlacklist())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid blacklist: %s", err)
+	}
+
+	flows, next := o.ring.ReadAll()
+	matched := make([]*flow.Flow, 0, len(flows))
+	for _, f := range flows {
+		if Apply(whitelist, blacklist, f) {
+			matched = append(matched, f)
+		}
+	}
+	if n := req.GetNumber(); n > 0 && uint64(len(matched)) > n {
+		matched = matched[uint64(len(matched))-n:]
+	}
+	for _, f := range matched {
+		if err := stream.Send(&flow.GetFlowsResponse{Flow: f}); err != nil {
+			return err
+		}
+	}
+
+	if !req.GetFollow() {
+		return nil
+	}
+
+	ctx := stream.Context()
+	for {
+		flows, next, err = o.ring.ReadFrom(ctx, next)
+		if err != nil {
+			return err
+		}
+		for _, f := range flows {
+			if !Apply(whitelist, blacklist, f) {
+				continue
+			}
+			if err := stream.Send(&flow.GetFlowsResponse{Flow: f}); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package observer
+
+import (
+	"context"
+
+	"github.com/cilium/cilium/api/v1/flow"
+
+	"google.golang.org/grpc"
+	. "gopkg.in/check.v1"
+)
+
+type fakeGetFlowsServer struct {
+	grpc.ServerStream
+	ctx   context.Context
+	flows []*flow.Flow
+}
+
+func (s *fakeGetFlowsServer) Send(resp *flow.GetFlowsResponse) error {
+	s.flows = append(s.flows, resp.Flow)
+	return nil
+}
+
+func (s *fakeGetFlowsServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *ObserverSuite) TestGetFlows(c *C) {
+	o := NewObserver(10, 10, NewParser(nil, "node"))
+	for _, f := range []*flow.Flow{
+		{Summary: "a", Verdict: flow.Verdict_DROPPED},
+		{Summary: "b", Verdict: flow.Verdict_FORWARDED},
+		{Summary: "c", Verdict: flow.Verdict_DROPPED},
+		{Summary: "d", Verdict: flow.Verdict_DROPPED},
+	} {
+		o.ring.Write(f)
+	}
+
+	stream := &fakeGetFlowsServer{ctx: context.Background()}
+	err := o.GetFlows(&flow.GetFlowsRequest{}, stream)
+	c.Assert(err, IsNil)
+	c.Assert(summaries(stream.flows), DeepEquals, []string{"a", "b", "c", "d"})
+
+	// The number of flows is applied after filtering
+	stream = &fakeGetFlowsServer{ctx: context.Background()}
+	err = o.GetFlows(&flow.GetFlowsRequest{
+		Number:    2,
+		Whitelist: []*flow.FlowFilter{{Verdict: []flow.Verdict{flow.Verdict_DROPPED}}},
+	}, stream)
+	c.Assert(err, IsNil)
+	c.Assert(summaries(stream.flows), DeepEquals, []string{"c", "d"})
+
+	stream = &fakeGetFlowsServer{ctx: context.Background()}
+	err = o.GetFlows(&flow.GetFlowsRequest{
+		Blacklist: []*flow.FlowFilter{{SourceIp: []string{"invalid"}}},
+	}, stream)
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *ObserverSuite) TestGetFlowsFollow(c *C) {
+	o := NewObserver(10, 10, NewParser(nil, "node"))
+	o.ring.Write(&flow.Flow{Summary: "a"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakeGetFlowsServer{ctx: ctx}
+	done := make(chan error)
+	go func() {
+		done <- o.GetFlows(&flow.GetFlowsRequest{
+			Follow:    true,
+			Blacklist: []*flow.FlowFilter{{Verdict: []flow.Verdict{flow.Verdict_DROPPED}}},
+		}, stream)
+	}()
+
+	o.ring.Write(&flow.Flow{Summary: "b", Verdict: flow.Verdict_DROPPED})
+	o.ring.Write(&flow.Flow{Summary: "c"})
+	cancel()
+
+	c.Assert(<-done, Equals, context.Canceled)
+	c.Assert(summaries(stream.flows), DeepEquals, []string{"a", "c"})
+}
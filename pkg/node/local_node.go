@@ -15,6 +15,7 @@
 package node
 
 import (
+	"net"
 	"sync"
 	"time"
 
@@ -47,6 +48,7 @@ func ConfigureLocalNode() error {
 }
 
 func configureLocalNode() {
+	clusterConf.Lock()
 	localNode = Node{
 		Name:    nodeName,
 		Cluster: option.Config.ClusterName,
@@ -59,11 +61,15 @@ func configureLocalNode() {
 		},
 		IPv4AllocCIDR: GetIPv4AllocRange(),
 		IPv6AllocCIDR: GetIPv6AllocRange(),
-		IPv4HealthIP:  GetIPv4HealthIP(),
-		IPv6HealthIP:  GetIPv6HealthIP(),
-		ClusterID:     option.Config.ClusterID,
-		Source:        FromAgentLocal,
+		// Secondary ranges may have been added before the local
+		// node is configured
+		IPv4SecondaryAllocCIDRs: localNode.IPv4SecondaryAllocCIDRs,
+		IPv4HealthIP:            GetIPv4HealthIP(),
+		IPv6HealthIP:            GetIPv6HealthIP(),
+		ClusterID:               option.Config.ClusterID,
+		Source:                  FromAgentLocal,
 	}
+	clusterConf.Unlock()
 
 	UpdateNode(&localNode, TunnelRoute, nil)
 
@@ -83,6 +89,25 @@ func configureLocalNode() {
 	}()
 }
 
+// AddIPv4SecondaryAllocCIDR adds an additional IPv4 allocation range to the
+// local node. The routes and IPsec configuration of the range are installed
+// and the range is propagated to the other nodes.
+func AddIPv4SecondaryAllocCIDR(cidr *net.IPNet) {
+	clusterConf.Lock()
+	cidrs := make([]*net.IPNet, 0, len(localNode.IPv4SecondaryAllocCIDRs)+1)
+	cidrs = append(cidrs, localNode.IPv4SecondaryAllocCIDRs...)
+	localNode.IPv4SecondaryAllocCIDRs = append(cidrs, cidr)
+	if option.Config.EnableIPSec {
+		for _, n := range clusterConf.nodes {
+			upsertIPSecEndpoint(n)
+		}
+	}
+	clusterConf.replaceHostRoutes()
+	clusterConf.Unlock()
+
+	NotifyLocalNodeUpdated()
+}
+
 // NodeRegistrar is an interface which allows for propagating information
 // about nodes to remote stores.
 type NodeRegistrar interface {
@@ -90,6 +115,21 @@ type NodeRegistrar interface {
 	UpdateLocalKeySync(n *Node) error
 }
 
+// NoOpNodeRegistrar is a NodeRegistrar which does not propagate the local
+// node. It is used when no kvstore is configured, in which case nodes learn
+// about each other from the Kubernetes node resources.
+type NoOpNodeRegistrar struct{}
+
+// RegisterNode does nothing
+func (NoOpNodeRegistrar) RegisterNode(n *Node) error {
+	return nil
+}
+
+// UpdateLocalKeySync does nothing
+func (NoOpNodeRegistrar) UpdateLocalKeySync(n *Node) error {
+	return nil
+}
+
 // NotifyLocalNodeUpdated Update local node information in the key-value
 // storage
 func NotifyLocalNodeUpdated() {
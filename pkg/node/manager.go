@@ -21,6 +21,7 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/cilium/cilium/pkg/datapath/ipsec"
 	routeUtils "github.com/cilium/cilium/pkg/datapath/route"
 	"github.com/cilium/cilium/pkg/defaults"
 	"github.com/cilium/cilium/pkg/lock"
@@ -74,6 +75,7 @@ var clusterConf = &clusterConfiguation{
 func (cc *clusterConfiguation) addAuxPrefix(prefix *net.IPNet) {
 	cc.Lock()
 	cc.auxPrefixes = append(cc.auxPrefixes, prefix)
+	cc.replaceHostRoutes()
 	cc.Unlock()
 }
 
@@ -157,16 +159,23 @@ func (cc *clusterConfiguation) replaceHostRoutes() {
 			// Otherwise it is only required when running in
 			// tunneling mode
 			if n.IsLocal() || option.Config.Tunnel != option.TunnelDisabled {
-				replaceNodeRoute(n.IPv4AllocCIDR, cc.mtuConfig)
+				for _, cidr := range n.GetIPv4AllocCIDRs() {
+					replaceNodeRoute(cidr, cc.mtuConfig)
+				}
 				replaceNodeRoute(n.IPv6AllocCIDR, cc.mtuConfig)
 			} else {
-				deleteNodeRoute(n.IPv4AllocCIDR)
+				for _, cidr := range n.GetIPv4AllocCIDRs() {
+					deleteNodeRoute(cidr)
+				}
 				deleteNodeRoute(n.IPv6AllocCIDR)
 			}
 		}
 	} else {
 		replaceNodeRoute(GetIPv4AllocRange(), cc.mtuConfig)
 		replaceNodeRoute(GetIPv6AllocRange(), cc.mtuConfig)
+		for _, cidr := range localNode.IPv4SecondaryAllocCIDRs {
+			replaceNodeRoute(cidr, cc.mtuConfig)
+		}
 	}
 
 	for _, prefix := range cc.auxPrefixes {
@@ -194,8 +203,8 @@ func InstallHostRoutes(mtuConfig mtu.Configuration) {
 }
 
 // AddAuxPrefix adds additional prefixes for which routes should be installed
-// that point to the Cilium network. This function does not directly install
-// the route but schedules it for addition by InstallHostRoutes
+// that point to the Cilium network. If the host device has not been
+// initialized yet, the route is scheduled for addition by InstallHostRoutes
 func AddAuxPrefix(prefix *net.IPNet) {
 	clusterConf.addAuxPrefix(prefix)
 }
@@ -210,6 +219,24 @@ func tunnelCIDRDeletionRequired(oldCIDR, newCIDR *net.IPNet) bool {
 	return oldCIDR != nil && newCIDR != nil && !oldCIDR.IP.Equal(newCIDR.IP)
 }
 
+// removedCIDRs returns the CIDRs of oldCIDRs which are not part of newCIDRs
+func removedCIDRs(oldCIDRs, newCIDRs []*net.IPNet) []*net.IPNet {
+	removed := []*net.IPNet{}
+	for _, oldCIDR := range oldCIDRs {
+		found := false
+		for _, newCIDR := range newCIDRs {
+			if oldCIDR.String() == newCIDR.String() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, oldCIDR)
+		}
+	}
+	return removed
+}
+
 func updateTunnelMapping(n *Node, ip *net.IPNet) {
 	if ip == nil {
 		return
@@ -228,6 +255,73 @@ func updateTunnelMapping(n *Node, ip *net.IPNet) {
 	}
 }
 
+// upsertIPSecEndpoint installs the xfrm states and policies to encrypt
+// traffic between the allocation ranges of the local node and the allocation
+// ranges of node n
+func upsertIPSecEndpoint(n *Node) {
+	if n.IsLocal() {
+		return
+	}
+
+	if option.Config.EnableIPv4 && n.IPv4AllocCIDR != nil {
+		if remoteIP := n.GetNodeIP(false); remoteIP != nil {
+			localCIDRs := append([]*net.IPNet{GetIPv4AllocRange()}, localNode.IPv4SecondaryAllocCIDRs...)
+			for _, localCIDR := range localCIDRs {
+				for _, remoteCIDR := range n.GetIPv4AllocCIDRs() {
+					err := ipsec.UpsertIPSecEndpoint(localCIDR, remoteCIDR, GetExternalIPv4(), remoteIP)
+					if err != nil {
+						log.WithError(err).WithFields(logrus.Fields{
+							logfields.IPAddr:   remoteIP,
+							logfields.V4Prefix: remoteCIDR,
+						}).Error("Unable to enable IPsec encryption towards node")
+					}
+				}
+			}
+		}
+	}
+
+	if option.Config.EnableIPv6 && n.IPv6AllocCIDR != nil {
+		if remoteIP := n.GetNodeIP(true); remoteIP != nil {
+			err := ipsec.UpsertIPSecEndpoint(GetIPv6AllocRange(), n.IPv6AllocCIDR, GetIPv6(), remoteIP)
+			if err != nil {
+				log.WithError(err).WithFields(logrus.Fields{
+					logfields.IPAddr:   remoteIP,
+					logfields.V6Prefix: n.IPv6AllocCIDR,
+				}).Error("Unable to enable IPsec encryption towards node")
+			}
+		}
+	}
+}
+
+// deleteIPSecEndpoint removes the xfrm states and policies used to encrypt
+// traffic between the local node and node n
+func deleteIPSecEndpoint(n *Node) {
+	if n.IsLocal() {
+		return
+	}
+
+	for _, remoteIP := range []net.IP{n.GetNodeIP(false), n.GetNodeIP(true)} {
+		if remoteIP == nil {
+			continue
+		}
+		if err := ipsec.DeleteIPSecEndpoint(remoteIP); err != nil {
+			log.WithError(err).WithField(logfields.IPAddr, remoteIP).
+				Warning("Unable to remove IPsec configuration of node")
+		}
+	}
+}
+
+// ReplaceIPSecEndpoints re-installs the xfrm states and policies of all known
+// nodes. It must be called after the IPsec keys have changed.
+func ReplaceIPSecEndpoints() {
+	clusterConf.RLock()
+	defer clusterConf.RUnlock()
+
+	for _, n := range clusterConf.nodes {
+		upsertIPSecEndpoint(n)
+	}
+}
+
 // UpdateNode updates the new node in the nodes' map with the given identity.
 // When using DirectRoute RouteType the field ownAddr should contain the IPv6
 // address of the interface that can reach the other nodes.
@@ -250,6 +344,28 @@ func UpdateNode(n *Node, routesTypes RouteType, ownAddr net.IP) {
 		}
 	}
 
+	clusterConf.updateNode(oldNode, n, routesTypes, ownAddr)
+}
+
+// SetIPv4SecondaryAllocCIDRs updates the secondary IPv4 allocation ranges of
+// the remote node with the given identity. Unknown nodes are ignored.
+func SetIPv4SecondaryAllocCIDRs(ni Identity, cidrs []*net.IPNet) {
+	clusterConf.Lock()
+	defer clusterConf.Unlock()
+
+	oldNode, ok := clusterConf.nodes[ni]
+	if !ok || oldNode.IsLocal() {
+		return
+	}
+
+	n := *oldNode
+	n.IPv4SecondaryAllocCIDRs = cidrs
+	clusterConf.updateNode(oldNode, &n, TunnelRoute, nil)
+}
+
+// updateNode installs the routes, tunnel mappings and IPsec configuration of
+// node n, replacing those of oldNode if not nil. cc must be locked.
+func (cc *clusterConfiguation) updateNode(oldNode, n *Node, routesTypes RouteType, ownAddr net.IP) {
 	if (routesTypes & TunnelRoute) != 0 {
 		// FIXME if PodCIDR is empty retrieve the CIDR from the KVStore
 		log.WithFields(logrus.Fields{
@@ -262,15 +378,18 @@ func UpdateNode(n *Node, routesTypes RouteType, ownAddr net.IP) {
 		// changed its CIDR range, a new entry in the map is created.
 		// The old entry is removed in the next step to ensure that the
 		// update appears atomic in the datapath.
-		updateTunnelMapping(n, n.IPv4AllocCIDR)
+		for _, cidr := range n.GetIPv4AllocCIDRs() {
+			updateTunnelMapping(n, cidr)
+		}
 		updateTunnelMapping(n, n.IPv6AllocCIDR)
 
 		// Handle the case when the CIDR range of the node has changed
 		// or the node no longer announce a CIDR range and remove the
 		// entry in the tunnel map
-		if oldNodeExists {
-			if tunnelCIDRDeletionRequired(oldNode.IPv4AllocCIDR, n.IPv4AllocCIDR) {
-				deleteTunnelMapping(oldNode.IPv4AllocCIDR)
+		if oldNode != nil {
+			for _, cidr := range removedCIDRs(oldNode.GetIPv4AllocCIDRs(), n.GetIPv4AllocCIDRs()) {
+				deleteTunnelMapping(cidr)
+				deleteNodeRoute(cidr)
 			}
 
 			if tunnelCIDRDeletionRequired(oldNode.IPv6AllocCIDR, n.IPv6AllocCIDR) {
@@ -283,8 +402,18 @@ func UpdateNode(n *Node, routesTypes RouteType, ownAddr net.IP) {
 		updateIPRoute(oldNode, n, ownAddr)
 	}
 
-	clusterConf.nodes[ni] = n
-	clusterConf.replaceHostRoutes()
+	if option.Config.EnableIPSec {
+		// Remove the configuration towards the old node IPs before
+		// installing the new one if the node has changed its address
+		if oldNode != nil && (!oldNode.GetNodeIP(false).Equal(n.GetNodeIP(false)) ||
+			!oldNode.GetNodeIP(true).Equal(n.GetNodeIP(true))) {
+			deleteIPSecEndpoint(oldNode)
+		}
+		upsertIPSecEndpoint(n)
+	}
+
+	cc.nodes[n.Identity()] = n
+	cc.replaceHostRoutes()
 }
 
 // DeleteNode remove the node from the nodes' maps and / or the L3 routes to
@@ -301,16 +430,23 @@ func DeleteNode(ni Identity, routesTypes RouteType) {
 				logfields.V6Prefix: n.IPv6AllocCIDR,
 			}).Debug("bpf: Removing tunnel endpoint")
 
-			deleteTunnelMapping(n.IPv4AllocCIDR)
+			for _, cidr := range n.GetIPv4AllocCIDRs() {
+				deleteTunnelMapping(cidr)
+			}
 			deleteTunnelMapping(n.IPv6AllocCIDR)
 
 			// Always delete routes when in tunnel mode as well.
-			deleteNodeRoute(n.IPv4AllocCIDR)
+			for _, cidr := range n.GetIPv4AllocCIDRs() {
+				deleteNodeRoute(cidr)
+			}
 			deleteNodeRoute(n.IPv6AllocCIDR)
 		}
 		if (routesTypes & DirectRoute) != 0 {
 			deleteIPRoute(n)
 		}
+		if option.Config.EnableIPSec {
+			deleteIPSecEndpoint(n)
+		}
 		delete(clusterConf.nodes, ni)
 		clusterConf.replaceHostRoutes()
 	}
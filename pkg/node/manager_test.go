@@ -45,3 +45,18 @@ func (s *NodeSuite) TestTunnelCIDRDeletionRequired(c *C) {
 	c.Assert(tunnelCIDRDeletionRequired(c1, c2), Equals, true)    // c1 -> c2
 	c.Assert(tunnelCIDRDeletionRequired(c2, nil), Equals, true)   // c2 -> disabled
 }
+
+func (s *NodeSuite) TestRemovedCIDRs(c *C) {
+	_, c1, err := net.ParseCIDR("10.1.0.0/16")
+	c.Assert(err, IsNil)
+	_, c2, err := net.ParseCIDR("10.2.0.0/16")
+	c.Assert(err, IsNil)
+	_, c3, err := net.ParseCIDR("10.3.0.0/16")
+	c.Assert(err, IsNil)
+
+	c.Assert(removedCIDRs(nil, nil), HasLen, 0)
+	c.Assert(removedCIDRs(nil, []*net.IPNet{c1}), HasLen, 0)
+	c.Assert(removedCIDRs([]*net.IPNet{c1, c2}, []*net.IPNet{c2, c1}), HasLen, 0)
+	c.Assert(removedCIDRs([]*net.IPNet{c1, c2}, []*net.IPNet{c1, c3}), DeepEquals, []*net.IPNet{c2})
+	c.Assert(removedCIDRs([]*net.IPNet{c1, c2}, nil), DeepEquals, []*net.IPNet{c1, c2})
+}
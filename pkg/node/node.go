@@ -54,6 +54,11 @@ type Node struct {
 	// allocates IPs for local endpoints from
 	IPv6AllocCIDR *net.IPNet
 
+	// IPv4SecondaryAllocCIDRs are additional IPv4 address pools out of
+	// which the node allocates IPs for local endpoints once IPv4AllocCIDR
+	// is exhausted
+	IPv4SecondaryAllocCIDRs []*net.IPNet
+
 	// dev contains the device name to where the IPv6 traffic should be send
 	dev string
 
@@ -85,6 +90,22 @@ func (n *Node) Fullname() string {
 	return n.Name
 }
 
+// GetIPv4AllocCIDRs returns the primary and all secondary IPv4 allocation
+// ranges of the node. Secondary ranges equal to the primary one are omitted.
+func (n *Node) GetIPv4AllocCIDRs() []*net.IPNet {
+	cidrs := make([]*net.IPNet, 0, 1+len(n.IPv4SecondaryAllocCIDRs))
+	if n.IPv4AllocCIDR != nil {
+		cidrs = append(cidrs, n.IPv4AllocCIDR)
+	}
+	for _, cidr := range n.IPv4SecondaryAllocCIDRs {
+		if n.IPv4AllocCIDR != nil && cidr.String() == n.IPv4AllocCIDR.String() {
+			continue
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs
+}
+
 // Address is a node address which contains an IP and the address type.
 type Address struct {
 	Type addressing.AddressType
@@ -289,6 +310,15 @@ func (n *Node) PublicAttrEquals(o *Node) bool {
 			return false
 		}
 
+		if len(n.IPv4SecondaryAllocCIDRs) != len(o.IPv4SecondaryAllocCIDRs) {
+			return false
+		}
+		for i := range n.IPv4SecondaryAllocCIDRs {
+			if n.IPv4SecondaryAllocCIDRs[i].String() != o.IPv4SecondaryAllocCIDRs[i].String() {
+				return false
+			}
+		}
+
 		return true
 	}
 
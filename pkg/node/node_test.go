@@ -264,3 +264,29 @@ func (s *NodeSuite) TestPublicAttrEquals(c *C) {
 		c.Assert(got, Equals, tt.want)
 	}
 }
+
+func (s *NodeSuite) TestGetIPv4AllocCIDRs(c *C) {
+	_, c1, err := net.ParseCIDR("10.1.0.0/16")
+	c.Assert(err, IsNil)
+	_, c2, err := net.ParseCIDR("10.2.0.0/16")
+	c.Assert(err, IsNil)
+	_, c3, err := net.ParseCIDR("10.3.0.0/16")
+	c.Assert(err, IsNil)
+
+	n := Node{}
+	c.Assert(n.GetIPv4AllocCIDRs(), HasLen, 0)
+
+	n.IPv4SecondaryAllocCIDRs = []*net.IPNet{c2}
+	c.Assert(n.GetIPv4AllocCIDRs(), DeepEquals, []*net.IPNet{c2})
+
+	// A secondary range equal to the primary one is only returned once
+	n.IPv4AllocCIDR = c1
+	n.IPv4SecondaryAllocCIDRs = []*net.IPNet{c1, c2, c3}
+	c.Assert(n.GetIPv4AllocCIDRs(), DeepEquals, []*net.IPNet{c1, c2, c3})
+
+	o := n
+	o.IPv4SecondaryAllocCIDRs = []*net.IPNet{c1, c2}
+	c.Assert(n.PublicAttrEquals(&o), Equals, false)
+	o.IPv4SecondaryAllocCIDRs = []*net.IPNet{c1, c2, c3}
+	c.Assert(n.PublicAttrEquals(&o), Equals, true)
+}
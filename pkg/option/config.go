@@ -307,6 +307,79 @@ const (
 	// DisableK8sServices disables east-west K8s load balancing by cilium
 	DisableK8sServices = "disable-k8s-services"
 
+	// LBAlgorithm is the default backend selection algorithm of services
+	LBAlgorithm = "lb-algorithm"
+
+	// LBAlgorithmRandom selects service backends based on the packet hash
+	LBAlgorithmRandom = "random"
+
+	// LBAlgorithmMaglev selects service backends using Maglev consistent
+	// hashing
+	LBAlgorithmMaglev = "maglev"
+
+	// LBMaglevMapMaxEntriesName is the name of the option to set the
+	// maximum number of services using Maglev on each IP family
+	LBMaglevMapMaxEntriesName = "bpf-lb-maglev-map-max"
+
+	// LBMaglevMapMaxEntriesDefault is the default maximum number of
+	// services using Maglev. Each entry holds a lookup table of 32KiB,
+	// resulting in 8MiB of memory per IP family.
+	LBMaglevMapMaxEntriesDefault = 256
+
+	// EnableHealthCheckNodePort enables the health check NodePort of
+	// services with externalTrafficPolicy=Local
+	EnableHealthCheckNodePort = "enable-health-check-nodeport"
+
+	// EnableIPSecName enables IPsec encryption of traffic between nodes
+	EnableIPSecName = "enable-ipsec"
+
+	// IPSecKeyFileName is the path to the file holding the IPsec keys
+	IPSecKeyFileName = "ipsec-key-file"
+
+	// EnableHostFirewallName enables enforcement of policies selecting the
+	// local node
+	EnableHostFirewallName = "enable-host-firewall"
+
+	// HostFirewallAuditName enables policy audit mode on the host endpoint
+	HostFirewallAuditName = "host-firewall-audit"
+
+	// EnableEgressGatewayName enables steering traffic through gateway
+	// nodes according to CiliumEgressNATPolicies
+	EnableEgressGatewayName = "enable-egress-gateway"
+
+	// EnableBandwidthManagerName enables shaping of the egress traffic of
+	// endpoints according to their bandwidth annotations
+	EnableBandwidthManagerName = "enable-bandwidth-manager"
+
+	// EnableNodePortName enables the translation of traffic received on
+	// the native device for NodePort, externalIPs and LoadBalancer
+	// frontends
+	EnableNodePortName = "enable-node-port"
+
+	// IPAMName is the IP address management mode of the agent
+	IPAMName = "ipam"
+
+	// IPAMHostScope allocates pod IPs out of the node CIDR derived from
+	// the Kubernetes node resource or the agent configuration
+	IPAMHostScope = "host-scope"
+
+	// IPAMClusterPool allocates pod IPs out of node CIDRs which are
+	// allocated by cilium-operator out of cluster-wide pools
+	IPAMClusterPool = "cluster-pool"
+
+	// IdentityAllocationModeName is the backend used to allocate
+	// security identities
+	IdentityAllocationModeName = "identity-allocation-mode"
+
+	// IdentityAllocationModeKVstore allocates security identities in the
+	// kvstore
+	IdentityAllocationModeKVstore = "kvstore"
+
+	// IdentityAllocationModeCRD allocates security identities as
+	// CiliumIdentity custom resources. In this mode, the kvstore is
+	// optional.
+	IdentityAllocationModeCRD = "crd"
+
 	// MaxCtrlIntervalName and MaxCtrlIntervalNameEnv allow configuration
 	// of MaxControllerInterval.
 	MaxCtrlIntervalName = "max-controller-interval"
@@ -331,6 +404,9 @@ const (
 	MonitorQueueSizeName    = "monitor-queue-size"
 	MonitorQueueSizeNameEnv = "CILIUM_MONITOR_QUEUE_SIZE"
 
+	// FlowBufferSizeName is the name of the option FlowBufferSize
+	FlowBufferSizeName = "flow-buffer-size"
+
 	//FQDNRejectResponseCode is the name for the option for dns-proxy reject response code
 	FQDNRejectResponseCode = "tofqdns-dns-reject-response-code"
 
@@ -450,6 +526,46 @@ type DaemonConfig struct {
 
 	Tunnel string // Tunnel mode
 
+	// LBAlgorithm is the default backend selection algorithm of services
+	LBAlgorithm string
+
+	// LBMaglevMapMaxEntries is the maximum number of services using
+	// Maglev in each of the IPv4/IPv6 Maglev maps
+	LBMaglevMapMaxEntries int
+
+	// EnableIPSec enables IPsec encryption of traffic between nodes
+	EnableIPSec bool
+
+	// IPSecKeyFile is the path to the file holding the IPsec keys
+	IPSecKeyFile string
+
+	// EnableHostFirewall enables enforcement of policies selecting the
+	// local node on the native device
+	EnableHostFirewall bool
+
+	// HostFirewallAudit enables policy audit mode on the host endpoint
+	HostFirewallAudit bool
+
+	// EnableEgressGateway enables steering traffic through gateway nodes
+	// according to CiliumEgressNATPolicies
+	EnableEgressGateway bool
+
+	// EnableBandwidthManager enables shaping of the egress traffic of
+	// endpoints on the native device according to their bandwidth
+	// annotations
+	EnableBandwidthManager bool
+
+	// EnableNodePort enables the translation of traffic received on the
+	// native device for NodePort, externalIPs and LoadBalancer frontends
+	EnableNodePort bool
+
+	// IPAM is the IP address management mode of the agent
+	IPAM string
+
+	// IdentityAllocationMode is the backend used to allocate security
+	// identities
+	IdentityAllocationMode string
+
 	DryMode bool // Do not create BPF maps, devices, ..
 
 	// RestoreState enables restoring the state from previous running daemons.
@@ -601,6 +717,10 @@ type DaemonConfig struct {
 	// MonitorQueueSize is the size of the monitor event queue
 	MonitorQueueSize int
 
+	// FlowBufferSize is the number of flows kept by the flow observer of
+	// the node monitor, the observer is disabled if zero
+	FlowBufferSize int
+
 	// CLI options
 
 	BPFRoot                           string
@@ -614,6 +734,7 @@ type DaemonConfig struct {
 	DebugVerbose                      []string
 	DisableConntrack                  bool
 	DisableK8sServices                bool
+	EnableHealthCheckNodePort         bool
 	DockerEndpoint                    string
 	EnablePolicy                      string
 	EnableTracing                     bool
@@ -687,6 +808,13 @@ func (c *DaemonConfig) IsLBEnabled() bool {
 	return c.LBInterface != ""
 }
 
+// KVStoreEnabled returns true if a kvstore is configured. Without a kvstore,
+// identities, node information and IP to identity mappings are exchanged via
+// Kubernetes resources.
+func (c *DaemonConfig) KVStoreEnabled() bool {
+	return c.KVStore != ""
+}
+
 // GetNodeConfigPath returns the full path of the NodeConfigFile.
 func (c *DaemonConfig) GetNodeConfigPath() string {
 	return filepath.Join(c.GetGlobalsDir(), common.NodeConfigFile)
@@ -768,6 +896,93 @@ func (c *DaemonConfig) Validate() error {
 		return fmt.Errorf("invalid tunnel mode '%s', valid modes = {%s}", c.Tunnel, GetTunnelModes())
 	}
 
+	switch c.LBAlgorithm {
+	case LBAlgorithmRandom, LBAlgorithmMaglev:
+	default:
+		return fmt.Errorf("invalid load balancing algorithm '%s', valid algorithms = {%s, %s}",
+			c.LBAlgorithm, LBAlgorithmRandom, LBAlgorithmMaglev)
+	}
+
+	// The Maglev maps are keyed by service frontend and thus cannot hold
+	// more entries than the service maps (~2GiB of lookup tables)
+	maglevMapMax := 1 << 16
+	if c.LBMaglevMapMaxEntries < 1 || c.LBMaglevMapMaxEntries > maglevMapMax {
+		return fmt.Errorf("specified Maglev map size %d must be in range 1..%d",
+			c.LBMaglevMapMaxEntries, maglevMapMax)
+	}
+
+	if c.EnableIPSec && c.IPSecKeyFile == "" {
+		return fmt.Errorf("option --%s requires --%s to be set",
+			EnableIPSecName, IPSecKeyFileName)
+	}
+
+	if c.EnableHostFirewall {
+		if c.Device == "" || c.Device == "undefined" {
+			return fmt.Errorf("option --%s requires --%s to be set",
+				EnableHostFirewallName, Device)
+		}
+		if c.IsLBEnabled() {
+			return fmt.Errorf("option --%s cannot be used in combination with --%s",
+				EnableHostFirewallName, LB)
+		}
+	}
+
+	if c.EnableEgressGateway {
+		if c.Tunnel == TunnelDisabled {
+			return fmt.Errorf("option --%s requires tunneling",
+				EnableEgressGatewayName)
+		}
+		if !c.EnableIPv4 {
+			return fmt.Errorf("option --%s requires IPv4 support",
+				EnableEgressGatewayName)
+		}
+	}
+
+	if c.EnableBandwidthManager {
+		if c.Device == "" || c.Device == "undefined" {
+			return fmt.Errorf("option --%s requires --%s to be set",
+				EnableBandwidthManagerName, Device)
+		}
+	}
+
+	if c.EnableNodePort {
+		if c.Device == "" || c.Device == "undefined" {
+			return fmt.Errorf("option --%s requires --%s to be set",
+				EnableNodePortName, Device)
+		}
+	}
+
+	switch c.IPAM {
+	case IPAMHostScope, IPAMClusterPool:
+	default:
+		return fmt.Errorf("invalid IPAM mode '%s', valid modes = {%s, %s}",
+			c.IPAM, IPAMHostScope, IPAMClusterPool)
+	}
+
+	switch c.IdentityAllocationMode {
+	case IdentityAllocationModeKVstore, IdentityAllocationModeCRD:
+	default:
+		return fmt.Errorf("invalid identity allocation mode '%s', valid modes = {%s, %s}",
+			c.IdentityAllocationMode, IdentityAllocationModeKVstore, IdentityAllocationModeCRD)
+	}
+
+	if !c.KVStoreEnabled() {
+		switch {
+		case c.IdentityAllocationMode != IdentityAllocationModeCRD:
+			return fmt.Errorf("option --%s is required unless --%s=%s is set",
+				KVStore, IdentityAllocationModeName, IdentityAllocationModeCRD)
+		case c.DisableCiliumEndpointCRD:
+			return fmt.Errorf("option --%s requires --%s to be set",
+				DisableCiliumEndpointCRDName, KVStore)
+		case c.IsLBEnabled():
+			return fmt.Errorf("option --%s requires --%s to be set",
+				LB, KVStore)
+		case c.ClusterMeshConfig != "":
+			return fmt.Errorf("option --%s requires --%s to be set",
+				ClusterMeshConfigName, KVStore)
+		}
+	}
+
 	if c.ClusterID < ClusterIDMin || c.ClusterID > ClusterIDMax {
 		return fmt.Errorf("invalid cluster id %d: must be in range %d..%d",
 			c.ClusterID, ClusterIDMin, ClusterIDMax)
@@ -818,6 +1033,7 @@ func (c *DaemonConfig) Populate() {
 	c.DevicePreFilter = viper.GetString(PrefilterDevice)
 	c.DisableCiliumEndpointCRD = viper.GetBool(DisableCiliumEndpointCRDName)
 	c.DisableK8sServices = viper.GetBool(DisableK8sServices)
+	c.EnableHealthCheckNodePort = viper.GetBool(EnableHealthCheckNodePort)
 	c.DockerEndpoint = viper.GetString(Docker)
 	c.EnablePolicy = strings.ToLower(viper.GetString(EnablePolicy))
 	c.EnableTracing = viper.GetBool(EnableTracing)
@@ -853,6 +1069,7 @@ func (c *DaemonConfig) Populate() {
 	c.ModePreFilter = viper.GetString(PrefilterMode)
 	c.MonitorAggregation = viper.GetString(MonitorAggregationName)
 	c.MonitorQueueSize = viper.GetInt(MonitorQueueSizeName)
+	c.FlowBufferSize = viper.GetInt(FlowBufferSizeName)
 	c.MTU = viper.GetInt(MTUName)
 	c.NAT46Range = viper.GetString(NAT46Range)
 	c.PProf = viper.GetBool(PProf)
@@ -868,6 +1085,17 @@ func (c *DaemonConfig) Populate() {
 	c.SockopsEnable = viper.GetBool(SockopsEnableName)
 	c.TracePayloadlen = viper.GetInt(TracePayloadlen)
 	c.Tunnel = viper.GetString(TunnelName)
+	c.LBAlgorithm = viper.GetString(LBAlgorithm)
+	c.LBMaglevMapMaxEntries = viper.GetInt(LBMaglevMapMaxEntriesName)
+	c.EnableIPSec = viper.GetBool(EnableIPSecName)
+	c.IPSecKeyFile = viper.GetString(IPSecKeyFileName)
+	c.EnableHostFirewall = viper.GetBool(EnableHostFirewallName)
+	c.HostFirewallAudit = viper.GetBool(HostFirewallAuditName)
+	c.EnableEgressGateway = viper.GetBool(EnableEgressGatewayName)
+	c.EnableBandwidthManager = viper.GetBool(EnableBandwidthManagerName)
+	c.EnableNodePort = viper.GetBool(EnableNodePortName)
+	c.IPAM = viper.GetString(IPAMName)
+	c.IdentityAllocationMode = viper.GetString(IdentityAllocationModeName)
 	c.Version = viper.GetString(Version)
 	c.Workloads = viper.GetStringSlice(ContainerRuntime)
 
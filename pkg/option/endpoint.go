@@ -25,6 +25,7 @@ var (
 		TraceNotify:         &specTraceNotify,
 		MonitorAggregation:  &specMonitorAggregation,
 		NAT46:               &specNAT46,
+		PolicyAuditMode:     &specPolicyAuditMode,
 	}
 )
 
@@ -0,0 +1,107 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// cassandraQueryActions maps the query actions understood by the Cassandra
+// parser to whether the action can be restricted to a table.
+var cassandraQueryActions = map[string]bool{
+	"select":                   true,
+	"delete":                   true,
+	"insert":                   true,
+	"update":                   true,
+	"create-table":             true,
+	"drop-table":               true,
+	"alter-table":              true,
+	"truncate-table":           true,
+	"use":                      true,
+	"create-keyspace":          true,
+	"alter-keyspace":           true,
+	"drop-keyspace":            true,
+	"drop-index":               false,
+	"create-index":             false,
+	"create-materialized-view": false,
+	"drop-materialized-view":   false,
+	"create-role":              false,
+	"alter-role":               false,
+	"drop-role":                false,
+	"grant-role":               false,
+	"revoke-role":              false,
+	"list-roles":               false,
+	"grant-permission":         false,
+	"revoke-permission":        false,
+	"list-permissions":         false,
+	"create-user":              false,
+	"alter-user":               false,
+	"drop-user":                false,
+	"list-users":               false,
+	"create-function":          false,
+	"drop-function":            false,
+	"create-aggregate":         false,
+	"drop-aggregate":           false,
+	"create-type":              false,
+	"alter-type":               false,
+	"drop-type":                false,
+	"create-trigger":           false,
+	"drop-trigger":             false,
+}
+
+// PortRuleCassandra is a list of Cassandra protocol constraints. All fields
+// are optional, if all fields are empty or missing, the rule will match all
+// Cassandra messages.
+type PortRuleCassandra struct {
+	// QueryAction is the action of the query, e.g. "select", "insert" or
+	// "create-table". If omitted or empty, all actions are allowed.
+	//
+	// +optional
+	QueryAction string `json:"queryAction,omitempty"`
+
+	// QueryTable is an extended POSIX regex matched against the table or
+	// keyspace the query applies to, in the format "keyspace.table". If
+	// omitted or empty, all tables are allowed.
+	//
+	// QueryTable may only be used with query actions which apply to a
+	// table or keyspace.
+	//
+	// +optional
+	QueryTable string `json:"queryTable,omitempty"`
+}
+
+// Sanitize sanitizes Cassandra rules. It ensures that the query action is
+// known to the Cassandra parser and that the query table is a valid regex
+// which can be used with the query action.
+func (c *PortRuleCassandra) Sanitize() error {
+	if c.QueryAction != "" {
+		withTable, ok := cassandraQueryActions[c.QueryAction]
+		if !ok {
+			return fmt.Errorf("invalid Cassandra query action %q", c.QueryAction)
+		}
+		if !withTable && c.QueryTable != "" {
+			return fmt.Errorf("Cassandra query action %q is not compatible with a query table", c.QueryAction)
+		}
+	}
+
+	if c.QueryTable != "" {
+		if _, err := regexp.Compile(c.QueryTable); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -60,6 +60,17 @@ type EgressRule struct {
 	// +optional
 	ToPorts []PortRule `json:"toPorts,omitempty"`
 
+	// ICMPs is a list of ICMP rule identified by type number and code
+	// which the endpoint subject to the rule is allowed to send.
+	// Combining ICMPs and ToPorts in the same rule is not supported.
+	//
+	// Example:
+	// Any endpoint with the label "app=httpd" is only allowed to send
+	// ICMPv6 neighbor solicitations (type 135).
+	//
+	// +optional
+	ICMPs []ICMPRule `json:"icmps,omitempty"`
+
 	// ToCIDR is a list of IP blocks which the endpoint subject to the rule
 	// is allowed to initiate connections. Only connections destined for
 	// outside of the cluster and not targeting the host will be subject
@@ -191,3 +202,76 @@ func (e *EgressRule) CreateDerivative() (*EgressRule, error) {
 	newRule.ToGroups = nil
 	return newRule, nil
 }
+
+// EgressDenyRule contains all rule types which can be applied at egress, i.e.
+// network traffic that originates inside the endpoint and exits the endpoint
+// selected by the endpointSelector, and which must be denied.
+//
+// - All members of this structure are optional. If omitted or empty, the
+//   member will have no effect on the rule.
+//
+// - Deny rules take precedence over allow rules. Traffic matching an
+//   EgressDenyRule is dropped even if it is allowed by an EgressRule.
+//
+// - If ToPorts is omitted, all traffic to the selected peers is denied.
+//   At least one peer or port must be specified.
+type EgressDenyRule struct {
+	// ToEndpoints is a list of endpoints identified by an EndpointSelector to
+	// which the endpoints subject to the rule are not allowed to communicate.
+	//
+	// Example:
+	// Any endpoint with the label "role=frontend" can not communicate with any
+	// endpoint carrying the label "role=backend".
+	//
+	// +optional
+	ToEndpoints []EndpointSelector `json:"toEndpoints,omitempty"`
+
+	// ToPorts is a list of destination ports identified by port number and
+	// protocol which the endpoint subject to the rule is not allowed to
+	// connect to.
+	//
+	// Example:
+	// Any endpoint with the label "role=frontend" is not allowed to initiate
+	// connections to destination port 8080/tcp
+	//
+	// +optional
+	ToPorts []PortDenyRule `json:"toPorts,omitempty"`
+
+	// ToCIDR is a list of IP blocks which the endpoint subject to the rule
+	// is not allowed to initiate connections to. This will match on the
+	// destination IP address of outgoing connections.
+	//
+	// Example:
+	// Any endpoint with the label "app=database-proxy" is not allowed to
+	// initiate connections to 10.2.3.0/24
+	//
+	// +optional
+	ToCIDR CIDRSlice `json:"toCIDR,omitempty"`
+
+	// ToCIDRSet is a list of IP blocks which the endpoint subject to the rule
+	// is not allowed to initiate connections to, along with a list of subnets
+	// contained within their corresponding IP block to which traffic should
+	// not be denied.
+	//
+	// Example:
+	// Any endpoint with the label "app=database-proxy" is not allowed to
+	// initiate connections to 10.2.3.0/24 except to IPs in subnet 10.2.3.0/28.
+	//
+	// +optional
+	ToCIDRSet CIDRRuleSlice `json:"toCIDRSet,omitempty"`
+
+	// ToEntities is a list of special entities to which the endpoint subject
+	// to the rule is not allowed to initiate connections. Supported entities
+	// are `world`, `cluster` and `host`
+	//
+	// +optional
+	ToEntities EntitySlice `json:"toEntities,omitempty"`
+}
+
+// GetDestinationEndpointSelectors returns a slice of endpoints selectors
+// covering all L3 destination selectors of the egress deny rule
+func (e *EgressDenyRule) GetDestinationEndpointSelectors() EndpointSelectorSlice {
+	res := append(e.ToEndpoints, e.ToEntities.GetAsEndpointSelectors()...)
+	res = append(res, e.ToCIDR.GetAsEndpointSelectors()...)
+	return append(res, e.ToCIDRSet.GetAsEndpointSelectors()...)
+}
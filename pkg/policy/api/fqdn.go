@@ -17,8 +17,11 @@ package api
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/cilium/cilium/pkg/fqdn/matchpattern"
+
+	"github.com/miekg/dns"
 )
 
 var (
@@ -69,10 +72,33 @@ func (s *FQDNSelector) sanitize() error {
 }
 
 // PortRuleDNS is a list of allowed DNS lookups.
-type PortRuleDNS FQDNSelector
+type PortRuleDNS struct {
+	// MatchName matches literal DNS names. See FQDNSelector.MatchName.
+	MatchName string `json:"matchName,omitempty"`
+
+	// MatchPattern allows using wildcards to match DNS names. See
+	// FQDNSelector.MatchPattern.
+	MatchPattern string `json:"matchPattern,omitempty"`
+
+	// MatchQueryTypes restricts the allowed query types, e.g. "A", "AAAA" or
+	// "CNAME". Queries of other types, such as "TXT" or "ANY", are rejected.
+	// All query types are allowed when empty.
+	//
+	// +optional
+	MatchQueryTypes []string `json:"matchQueryTypes,omitempty"`
+
+	// ResponseCIDRs restricts the IPs which may be returned in A and AAAA
+	// records of responses. Records pointing outside of these CIDRs, e.g. to
+	// private ranges as used in DNS rebinding, are removed from the response.
+	// All IPs are allowed when empty.
+	//
+	// +optional
+	ResponseCIDRs []CIDR `json:"responseCIDRs,omitempty"`
+}
 
 // Sanitize checks that the matchName in the portRule can be compiled as a
-// regex. It does not check that a DNS name is a valid DNS name.
+// regex. It does not check that a DNS name is a valid DNS name. The query
+// types and response CIDRs must be valid.
 func (r *PortRuleDNS) Sanitize() error {
 	if len(r.MatchName) > 0 && !allowedMatchNameChars.MatchString(r.MatchName) {
 		return fmt.Errorf("Invalid characters in MatchName: \"%s\". Only 0-9, a-z, A-Z and . and - characters are allowed", r.MatchName)
@@ -81,6 +107,19 @@ func (r *PortRuleDNS) Sanitize() error {
 	if len(r.MatchPattern) > 0 && !allowedPatternChars.MatchString(r.MatchPattern) {
 		return fmt.Errorf("Invalid characters in MatchPattern: \"%s\". Only 0-9, a-z, A-Z and ., - and * characters are allowed", r.MatchPattern)
 	}
+
+	for _, qtype := range r.MatchQueryTypes {
+		if _, ok := dns.StringToType[strings.ToUpper(qtype)]; !ok {
+			return fmt.Errorf("Invalid DNS query type in MatchQueryTypes: \"%s\"", qtype)
+		}
+	}
+
+	for _, cidr := range r.ResponseCIDRs {
+		if _, err := cidr.sanitize(); err != nil {
+			return fmt.Errorf("Invalid CIDR in ResponseCIDRs: %s", err)
+		}
+	}
+
 	return matchpattern.Validate(r.MatchPattern)
 }
 
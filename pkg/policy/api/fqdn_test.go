@@ -60,6 +60,8 @@ func (s *PolicyAPITestSuite) TestPortRuleDNSSanitize(c *C) {
 		{MatchPattern: "*cilium.io"},
 		{MatchPattern: "cilium.io"},
 		{MatchName: "cilium.io", MatchPattern: "*cilium.io"},
+		{MatchName: "cilium.io", MatchQueryTypes: []string{"A", "aaaa"}},
+		{MatchName: "cilium.io", ResponseCIDRs: []CIDR{"10.0.0.0/8", "f00d::/64", "192.168.1.1"}},
 	} {
 		err := accept.Sanitize()
 		c.Assert(err, IsNil, Commentf("PortRuleDNS %+v was rejected but it should be valid", accept))
@@ -70,6 +72,8 @@ func (s *PolicyAPITestSuite) TestPortRuleDNSSanitize(c *C) {
 		{MatchPattern: "[a-z]*.cilium.io."},
 		{MatchName: "a{1,2}.cilium.io.", MatchPattern: "*cilium.io"},
 		{MatchName: "a{1,2}.cilium.io.", MatchPattern: "[a-z]*.cilium.io."},
+		{MatchName: "cilium.io", MatchQueryTypes: []string{"NOTATYPE"}},
+		{MatchName: "cilium.io", ResponseCIDRs: []CIDR{"10.0.0.0/33"}},
 	} {
 		err := reject.Sanitize()
 		c.Assert(err, Not(IsNil), Commentf("PortRuleDNS %+v was accepted but it should be invalid", reject))
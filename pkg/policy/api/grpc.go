@@ -0,0 +1,74 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	// grpcMethodNameRegex matches valid protobuf identifiers. Service
+	// names may additionally contain dots to separate the package name.
+	grpcMethodNameRegex  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	grpcServiceNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+)
+
+// PortRuleGRPC is a gRPC service and optional method which is allowed to be
+// called. gRPC requests are HTTP/2 POST requests to the path
+// "/<service>/<method>", so these rules are enforced as HTTP rules matching
+// the request path.
+type PortRuleGRPC struct {
+	// Service is the fully qualified name of a gRPC service, including
+	// the protobuf package name, e.g. "helloworld.Greeter".
+	Service string `json:"service"`
+
+	// Method is the name of a method of the gRPC service, e.g. "SayHello".
+	//
+	// If omitted or empty, all methods of the service are allowed.
+	//
+	// +optional
+	Method string `json:"method,omitempty"`
+}
+
+// Path returns the HTTP/2 request path of calls to the method, or the path
+// prefix of calls to all methods of the service if no method is set.
+func (g *PortRuleGRPC) Path() string {
+	return "/" + g.Service + "/" + g.Method
+}
+
+// IsPathPrefix returns true if Path() must be matched as a prefix of the
+// request path rather than exactly.
+func (g *PortRuleGRPC) IsPathPrefix() bool {
+	return g.Method == ""
+}
+
+// Sanitize sanitizes gRPC rules. It ensures that the service and method are
+// valid protobuf names. If the rule is invalid, returns an error.
+func (g *PortRuleGRPC) Sanitize() error {
+	if g.Service == "" {
+		return fmt.Errorf("gRPC service must be specified")
+	}
+
+	if !grpcServiceNameRegex.MatchString(g.Service) {
+		return fmt.Errorf("invalid gRPC service name %q", g.Service)
+	}
+
+	if g.Method != "" && !grpcMethodNameRegex.MatchString(g.Method) {
+		return fmt.Errorf("invalid gRPC method name %q", g.Method)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strconv"
+)
+
+const (
+	// IPv4Family is the ICMP family for ICMP over IPv4
+	IPv4Family = "IPv4"
+	// IPv6Family is the ICMP family for ICMPv6
+	IPv6Family = "IPv6"
+
+	// ICMPCodeAny is the code used in the datapath to match all codes of
+	// an ICMP type. Code 255 can therefore not be matched explicitly.
+	ICMPCodeAny = 0xff
+)
+
+// ICMPRule is a list of ICMP fields.
+type ICMPRule struct {
+	// Fields is a list of ICMP fields.
+	//
+	// +optional
+	Fields []ICMPField `json:"fields,omitempty"`
+}
+
+// ICMPField is a ICMP field.
+type ICMPField struct {
+	// Family is a IP address version.
+	// Currently, we support `IPv4` and `IPv6`.
+	// `IPv4` is set as default.
+	//
+	// +optional
+	Family string `json:"family,omitempty"`
+
+	// Type is a ICMP-type.
+	// It should be 0-255 (8bit).
+	Type uint8 `json:"type"`
+
+	// Code is a ICMP-code of the given Type. If omitted, all codes of the
+	// ICMP-type match. It should be 0-254 (8bit).
+	//
+	// +optional
+	Code *uint8 `json:"code,omitempty"`
+}
+
+// Protocol returns the L4 protocol of the ICMP field.
+func (i ICMPField) Protocol() L4Proto {
+	if i.Family == IPv6Family {
+		return ProtoICMPv6
+	}
+	return ProtoICMP
+}
+
+// PortProtocol returns the ICMP field as an L4 port/protocol. The datapath
+// matches ICMP messages in place of the destination port, which is set to
+// the ICMP type in the upper and the ICMP code in the lower 8 bits, or
+// ICMPCodeAny if all codes of the type match.
+func (i ICMPField) PortProtocol() PortProtocol {
+	code := uint16(ICMPCodeAny)
+	if i.Code != nil {
+		code = uint16(*i.Code)
+	}
+	return PortProtocol{
+		Port:     strconv.FormatUint(uint64(uint16(i.Type)<<8|code), 10),
+		Protocol: i.Protocol(),
+	}
+}
@@ -67,6 +67,17 @@ type IngressRule struct {
 	// +optional
 	ToPorts []PortRule `json:"toPorts,omitempty"`
 
+	// ICMPs is a list of ICMP rule identified by type number and code
+	// which the endpoint subject to the rule is allowed to receive.
+	// Combining ICMPs and ToPorts in the same rule is not supported.
+	//
+	// Example:
+	// Any endpoint with the label "app=httpd" can only accept incoming
+	// ICMP echo requests (type 8).
+	//
+	// +optional
+	ICMPs []ICMPRule `json:"icmps,omitempty"`
+
 	// FromCIDR is a list of IP blocks which the endpoint subject to the
 	// rule is allowed to receive connections from. Only connections which
 	// do *not* originate from the cluster or from the local host are subject
@@ -120,3 +131,79 @@ func (i *IngressRule) GetSourceEndpointSelectors() EndpointSelectorSlice {
 func (i *IngressRule) IsLabelBased() bool {
 	return len(i.FromRequires)+len(i.FromCIDR)+len(i.FromCIDRSet) == 0
 }
+
+// IngressDenyRule contains all rule types which can be applied at ingress,
+// i.e. network traffic that originates outside of the endpoint and is
+// entering the endpoint selected by the endpointSelector, and which must be
+// denied.
+//
+// - All members of this structure are optional. If omitted or empty, the
+//   member will have no effect on the rule.
+//
+// - Deny rules take precedence over allow rules. Traffic matching an
+//   IngressDenyRule is dropped even if it is allowed by an IngressRule.
+//
+// - If ToPorts is omitted, all traffic from the selected peers is denied.
+//   At least one peer or port must be specified.
+type IngressDenyRule struct {
+	// FromEndpoints is a list of endpoints identified by an
+	// EndpointSelector which are not allowed to communicate with the
+	// endpoint subject to the rule.
+	//
+	// Example:
+	// Any endpoint with the label "role=backend" cannot be consumed by any
+	// endpoint carrying the label "role=frontend".
+	//
+	// +optional
+	FromEndpoints []EndpointSelector `json:"fromEndpoints,omitempty"`
+
+	// ToPorts is a list of destination ports identified by port number and
+	// protocol which the endpoint subject to the rule is not allowed to
+	// receive connections on.
+	//
+	// Example:
+	// Any endpoint with the label "app=httpd" can not accept incoming
+	// connections on port 80/tcp.
+	//
+	// +optional
+	ToPorts []PortDenyRule `json:"toPorts,omitempty"`
+
+	// FromCIDR is a list of IP blocks which the endpoint subject to the
+	// rule is not allowed to receive connections from. This will match on
+	// the source IP address of incoming connections.
+	//
+	// Example:
+	// Any endpoint with the label "app=my-legacy-pet" is not allowed to
+	// receive connections from 10.3.9.1
+	//
+	// +optional
+	FromCIDR CIDRSlice `json:"fromCIDR,omitempty"`
+
+	// FromCIDRSet is a list of IP blocks which the endpoint subject to the
+	// rule is not allowed to receive connections from, along with a list of
+	// subnets contained within their corresponding IP block from which
+	// traffic should not be denied.
+	//
+	// Example:
+	// Any endpoint with the label "app=my-legacy-pet" is not allowed to
+	// receive connections from 10.0.0.0/8 except from IPs in subnet
+	// 10.96.0.0/12.
+	//
+	// +optional
+	FromCIDRSet CIDRRuleSlice `json:"fromCIDRSet,omitempty"`
+
+	// FromEntities is a list of special entities which the endpoint subject
+	// to the rule is not allowed to receive connections from. Supported
+	// entities are `world`, `cluster` and `host`
+	//
+	// +optional
+	FromEntities EntitySlice `json:"fromEntities,omitempty"`
+}
+
+// GetSourceEndpointSelectors returns a slice of endpoints selectors covering
+// all L3 source selectors of the ingress deny rule
+func (i *IngressDenyRule) GetSourceEndpointSelectors() EndpointSelectorSlice {
+	res := append(i.FromEndpoints, i.FromEntities.GetAsEndpointSelectors()...)
+	res = append(res, i.FromCIDR.GetAsEndpointSelectors()...)
+	return append(res, i.FromCIDRSet.GetAsEndpointSelectors()...)
+}
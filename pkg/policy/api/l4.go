@@ -14,6 +14,10 @@
 
 package api
 
+import (
+	"fmt"
+)
+
 // L4Proto is a layer 4 protocol name
 type L4Proto string
 
@@ -21,15 +25,28 @@ const (
 	ProtoTCP L4Proto = "TCP"
 	ProtoUDP L4Proto = "UDP"
 	ProtoAny L4Proto = "ANY"
+
+	// ProtoICMP and ProtoICMPv6 are only used internally for L4 policy
+	// derived from ICMP rules, they cannot be specified in PortProtocol.
+	ProtoICMP   L4Proto = "ICMP"
+	ProtoICMPv6 L4Proto = "ICMPV6"
 )
 
 // PortProtocol specifies an L4 port with an optional transport protocol
 type PortProtocol struct {
-	// Port is an L4 port number. For now the string will be strictly
-	// parsed as a single uint16. In the future, this field may support
-	// ranges in the form "1024-2048
+	// Port is an L4 port number. The string will be strictly parsed as a
+	// single uint16. If EndPort is set, Port is the first port of the range.
 	Port string `json:"port"`
 
+	// EndPort is the last L4 port number of a port range starting at Port.
+	// If set, the port/protocol applies to all ports in the inclusive range
+	// Port-EndPort, e.g. "30000" to 32767 for the NodePort range. EndPort
+	// must not be smaller than Port. Port ranges cannot be combined with
+	// L7 rules.
+	//
+	// +optional
+	EndPort int32 `json:"endPort,omitempty"`
+
 	// Protocol is the L4 protocol. If omitted or empty, any protocol
 	// matches. Accepted values: "TCP", "UDP", ""/"ANY"
 	//
@@ -39,6 +56,14 @@ type PortProtocol struct {
 	Protocol L4Proto `json:"protocol,omitempty"`
 }
 
+// String returns the port/protocol in the form "{<port>[-<endPort>] <protocol>}".
+func (p PortProtocol) String() string {
+	if p.EndPort != 0 {
+		return fmt.Sprintf("{%s-%d %s}", p.Port, p.EndPort, p.Protocol)
+	}
+	return fmt.Sprintf("{%s %s}", p.Port, p.Protocol)
+}
+
 // PortRule is a list of ports/protocol combinations with optional Layer 7
 // rules which must be met.
 type PortRule struct {
@@ -59,6 +84,16 @@ type PortRule struct {
 	Rules *L7Rules `json:"rules,omitempty"`
 }
 
+// PortDenyRule is a list of ports/protocol that should be used for deny
+// policies. This structure lacks the L7Rules since it's not supported in deny
+// policies.
+type PortDenyRule struct {
+	// Ports is a list of L4 port/protocol
+	//
+	// +optional
+	Ports []PortProtocol `json:"ports,omitempty"`
+}
+
 // L7Rules is a union of port level rule types. Mixing of different port
 // level rule types is disallowed, so exactly one of the following must be set.
 // If none are specified, then no additional port level rules are applied.
@@ -78,6 +113,21 @@ type L7Rules struct {
 	// +optional
 	DNS []PortRuleDNS `json:"dns,omitempty"`
 
+	// gRPC-specific rules.
+	//
+	// +optional
+	GRPC []PortRuleGRPC `json:"grpc,omitempty"`
+
+	// Cassandra-specific rules.
+	//
+	// +optional
+	Cassandra []PortRuleCassandra `json:"cassandra,omitempty"`
+
+	// Memcached-specific rules.
+	//
+	// +optional
+	Memcached []PortRuleMemcached `json:"memcached,omitempty"`
+
 	// Name of the L7 protocol for which the Key-value pair rules apply
 	//
 	// +optional
@@ -95,10 +145,12 @@ func (rules *L7Rules) Len() int {
 	if rules == nil {
 		return 0
 	}
-	return len(rules.HTTP) + len(rules.Kafka) + len(rules.DNS) + len(rules.L7)
+	return len(rules.HTTP) + len(rules.Kafka) + len(rules.DNS) + len(rules.GRPC) +
+		len(rules.Cassandra) + len(rules.Memcached) + len(rules.L7)
 }
 
 // IsEmpty returns whether the `L7Rules` is nil or contains nil rules.
 func (rules *L7Rules) IsEmpty() bool {
-	return rules == nil || (rules.HTTP == nil && rules.Kafka == nil && rules.DNS == nil && rules.L7 == nil)
+	return rules == nil || (rules.HTTP == nil && rules.Kafka == nil && rules.DNS == nil && rules.GRPC == nil &&
+		rules.Cassandra == nil && rules.Memcached == nil && rules.L7 == nil)
 }
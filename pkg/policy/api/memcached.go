@@ -0,0 +1,93 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// memcachedCommands is the set of commands and command groups understood by
+// the memcached parser. It must match the keys of memcache.MemcacheOpCodeMap
+// in proxylib, which is asserted by TestMemcachedCommandsMatchParser.
+var memcachedCommands = map[string]struct{}{
+	"add": {}, "set": {}, "replace": {}, "append": {}, "prepend": {},
+	"cas": {}, "incr": {}, "decr": {}, "storage": {}, "get": {},
+	"delete": {}, "touch": {}, "gat": {}, "writeGroup": {}, "slabs": {},
+	"lru": {}, "lru_crawler": {}, "watch": {}, "stats": {},
+	"flush_all": {}, "cache_memlimit": {}, "version": {}, "misbehave": {},
+	"quit": {}, "noop": {}, "verbosity": {}, "sasl-list-mechs": {},
+	"sasl-auth": {}, "sasl-step": {}, "rget": {}, "rset": {}, "rsetq": {},
+	"rappend": {}, "rappendq": {}, "rprepend": {}, "rprependq": {},
+	"rdelete": {}, "rdeleteq": {}, "rincr": {}, "rincrq": {}, "rdecr": {},
+	"rdecrq": {}, "set-vbucket": {}, "get-vbucket": {}, "del-vbucket": {},
+	"tap-connect": {}, "tap-mutation": {}, "tap-delete": {}, "tap-flush": {},
+	"tap-opaque": {}, "tap-vbucket-set": {}, "tap-checkpoint-start": {},
+	"tap-checkpoint-end": {},
+}
+
+// PortRuleMemcached is a list of memcached protocol constraints. All fields
+// are optional, if all fields are empty or missing, the rule will match all
+// memcached requests. Both the text and the binary memcached protocols are
+// supported.
+type PortRuleMemcached struct {
+	// Command is the name of a memcached command, e.g. "get" or "set", or
+	// of a group of commands, e.g. "storage" or "writeGroup". If omitted or
+	// empty, all commands are allowed.
+	//
+	// +optional
+	Command string `json:"command,omitempty"`
+
+	// KeyExact is the key which must be accessed by the command.
+	//
+	// +optional
+	KeyExact string `json:"keyExact,omitempty"`
+
+	// KeyPrefix is a prefix of the keys which may be accessed by the
+	// command.
+	//
+	// +optional
+	KeyPrefix string `json:"keyPrefix,omitempty"`
+
+	// KeyRegex is an extended POSIX regex matched against the keys which
+	// may be accessed by the command.
+	//
+	// +optional
+	KeyRegex string `json:"keyRegex,omitempty"`
+}
+
+// Sanitize sanitizes memcached rules. It ensures that the command is known to
+// the memcached parser, that keys are only matched for a command and that the
+// key regex is valid.
+func (m *PortRuleMemcached) Sanitize() error {
+	if m.Command == "" {
+		if m.KeyExact != "" || m.KeyPrefix != "" || m.KeyRegex != "" {
+			return fmt.Errorf("memcached key may only be specified together with a command")
+		}
+		return nil
+	}
+
+	if _, ok := memcachedCommands[m.Command]; !ok {
+		return fmt.Errorf("invalid memcached command %q", m.Command)
+	}
+
+	if m.KeyRegex != "" {
+		if _, err := regexp.Compile(m.KeyRegex); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -15,11 +15,14 @@
 package api
 
 import (
+	"encoding/json"
+
 	"github.com/cilium/cilium/pkg/labels"
 )
 
 // Rule is a policy rule which must be applied to all endpoints which match the
-// labels contained in the endpointSelector
+// labels contained in the endpointSelector, or to the host endpoints of all
+// nodes which match the labels contained in the nodeSelector.
 //
 // Each rule is split into an ingress section which contains all rules
 // applicable at ingress, and an egress section applicable at egress. For rule
@@ -29,10 +32,27 @@ import (
 //
 // Either ingress, egress, or both can be provided. If both ingress and egress
 // are omitted, the rule has no effect.
+//
+// The IngressDeny and EgressDeny sections explicitly deny traffic. Traffic
+// matching a deny section is dropped even if it is allowed by an Ingress or
+// Egress section of this or any other rule.
 type Rule struct {
 	// EndpointSelector selects all endpoints which should be subject to
-	// this rule. Cannot be empty.
-	EndpointSelector EndpointSelector `json:"endpointSelector"`
+	// this rule. EndpointSelector and NodeSelector cannot be both empty and
+	// are mutually exclusive.
+	//
+	// +optional
+	EndpointSelector EndpointSelector `json:"endpointSelector,omitempty"`
+
+	// NodeSelector selects all nodes which should be subject to this rule.
+	// The rule is then enforced on the host endpoint of every selected node.
+	// EndpointSelector and NodeSelector cannot be both empty and are
+	// mutually exclusive. Nodes are not namespaced, so a NodeSelector is
+	// only accepted in a CiliumClusterwideNetworkPolicy or in policies
+	// imported through the agent API, and rejected in a CiliumNetworkPolicy.
+	//
+	// +optional
+	NodeSelector EndpointSelector `json:"nodeSelector,omitempty"`
 
 	// Ingress is a list of IngressRule which are enforced at ingress.
 	// If omitted or empty, this rule does not apply at ingress.
@@ -46,6 +66,22 @@ type Rule struct {
 	// +optional
 	Egress []EgressRule `json:"egress,omitempty"`
 
+	// IngressDeny is a list of IngressDenyRule which are enforced at ingress.
+	// Any rule inserted here will be denied regardless of the allowed ingress
+	// rules in the 'ingress' field.
+	// If omitted or empty, this rule does not apply at ingress.
+	//
+	// +optional
+	IngressDeny []IngressDenyRule `json:"ingressDeny,omitempty"`
+
+	// EgressDeny is a list of EgressDenyRule which are enforced at egress.
+	// Any rule inserted here will be denied regardless of the allowed egress
+	// rules in the 'egress' field.
+	// If omitted or empty, this rule does not apply at egress.
+	//
+	// +optional
+	EgressDeny []EgressDenyRule `json:"egressDeny,omitempty"`
+
 	// Labels is a list of optional strings which can be used to
 	// re-identify the rule or to store metadata. It is possible to lookup
 	// or delete strings based on labels. Labels are not required to be
@@ -62,6 +98,41 @@ type Rule struct {
 	Description string `json:"description,omitempty"`
 }
 
+// MarshalJSON returns the JSON encoding of the rule. It is overwritten to
+// omit whichever of EndpointSelector and NodeSelector is unset, as an empty
+// EndpointSelector is otherwise encoded as a wildcard selector.
+func (r Rule) MarshalJSON() ([]byte, error) {
+	type common struct {
+		Ingress     []IngressRule     `json:"ingress,omitempty"`
+		Egress      []EgressRule      `json:"egress,omitempty"`
+		IngressDeny []IngressDenyRule `json:"ingressDeny,omitempty"`
+		EgressDeny  []EgressDenyRule  `json:"egressDeny,omitempty"`
+		Labels      labels.LabelArray `json:"labels,omitempty"`
+		Description string            `json:"description,omitempty"`
+	}
+
+	ruleCommon := common{
+		Ingress:     r.Ingress,
+		Egress:      r.Egress,
+		IngressDeny: r.IngressDeny,
+		EgressDeny:  r.EgressDeny,
+		Labels:      r.Labels,
+		Description: r.Description,
+	}
+
+	if r.NodeSelector.LabelSelector != nil && r.EndpointSelector.LabelSelector == nil {
+		return json.Marshal(struct {
+			NodeSelector EndpointSelector `json:"nodeSelector"`
+			common
+		}{r.NodeSelector, ruleCommon})
+	}
+
+	return json.Marshal(struct {
+		EndpointSelector EndpointSelector `json:"endpointSelector"`
+		common
+	}{r.EndpointSelector, ruleCommon})
+}
+
 // RequiresDerivative it return true if the rule has a derivative rule.
 func (r *Rule) RequiresDerivative() bool {
 	for _, rule := range r.Egress {
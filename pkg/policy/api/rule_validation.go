@@ -16,6 +16,7 @@ package api
 
 import (
 	"fmt"
+	"math"
 	"net"
 	"strconv"
 	"strings"
@@ -24,7 +25,8 @@ import (
 )
 
 const (
-	maxPorts = 40
+	maxPorts      = 40
+	maxICMPFields = 40
 	// MaxCIDRPrefixLengths is used to prevent compile failures at runtime.
 	MaxCIDRPrefixLengths = 40
 )
@@ -45,12 +47,23 @@ func (r Rule) Sanitize() error {
 		}
 	}
 
-	if r.EndpointSelector.LabelSelector == nil {
-		return fmt.Errorf("rule cannot have nil EndpointSelector")
+	hasEndpointSelector := r.EndpointSelector.LabelSelector != nil
+	hasNodeSelector := r.NodeSelector.LabelSelector != nil
+	switch {
+	case !hasEndpointSelector && !hasNodeSelector:
+		return fmt.Errorf("rule must have one of EndpointSelector or NodeSelector")
+	case hasEndpointSelector && hasNodeSelector:
+		return fmt.Errorf("rule cannot have both EndpointSelector and NodeSelector")
 	}
 
-	if err := r.EndpointSelector.sanitize(); err != nil {
-		return err
+	if hasEndpointSelector {
+		if err := r.EndpointSelector.sanitize(); err != nil {
+			return err
+		}
+	} else {
+		if err := r.NodeSelector.sanitize(); err != nil {
+			return err
+		}
 	}
 
 	for i := range r.Ingress {
@@ -65,6 +78,18 @@ func (r Rule) Sanitize() error {
 		}
 	}
 
+	for i := range r.IngressDeny {
+		if err := r.IngressDeny[i].sanitize(); err != nil {
+			return err
+		}
+	}
+
+	for i := range r.EgressDeny {
+		if err := r.EgressDeny[i].sanitize(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -93,6 +118,13 @@ func (i *IngressRule) sanitize() error {
 		if l3Members[member] > 0 && len(i.ToPorts) > 0 && !l3DependentL4Support[member] {
 			return fmt.Errorf("Combining %s and ToPorts is not supported yet", member)
 		}
+		if l3Members[member] > 0 && len(i.ICMPs) > 0 && !l3DependentL4Support[member] {
+			return fmt.Errorf("Combining %s and ICMPs is not supported yet", member)
+		}
+	}
+
+	if len(i.ToPorts) > 0 && len(i.ICMPs) > 0 {
+		return fmt.Errorf("Combining ToPorts and ICMPs is not supported yet")
 	}
 
 	for _, es := range i.FromEndpoints {
@@ -113,6 +145,12 @@ func (i *IngressRule) sanitize() error {
 		}
 	}
 
+	for n := range i.ICMPs {
+		if err := i.ICMPs[n].sanitize(); err != nil {
+			return err
+		}
+	}
+
 	prefixLengths := map[int]exists{}
 	for n := range i.FromCIDR {
 		prefixLength, err := i.FromCIDR[n].sanitize()
@@ -178,6 +216,10 @@ func (e *EgressRule) sanitize() error {
 		}
 	}
 
+	if len(e.ToPorts) > 0 && len(e.ICMPs) > 0 {
+		return fmt.Errorf("Combining ToPorts and ICMPs is not supported yet")
+	}
+
 	for _, es := range e.ToEndpoints {
 		if err := es.sanitize(); err != nil {
 			return err
@@ -196,6 +238,12 @@ func (e *EgressRule) sanitize() error {
 		}
 	}
 
+	for i := range e.ICMPs {
+		if err := e.ICMPs[i].sanitize(); err != nil {
+			return err
+		}
+	}
+
 	prefixLengths := map[int]exists{}
 	for i := range e.ToCIDR {
 		prefixLength, err := e.ToCIDR[i].sanitize()
@@ -235,6 +283,143 @@ func (e *EgressRule) sanitize() error {
 	return nil
 }
 
+// hasMembers returns true if any of the given rule members is set. A deny
+// rule without peers and ports would deny all traffic while having no
+// selector to be traced with.
+func hasMembers(members map[string]int) bool {
+	for _, n := range members {
+		if n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *IngressDenyRule) sanitize() error {
+	l3Members := map[string]int{
+		"FromEndpoints": len(i.FromEndpoints),
+		"FromCIDR":      len(i.FromCIDR),
+		"FromCIDRSet":   len(i.FromCIDRSet),
+		"FromEntities":  len(i.FromEntities),
+	}
+
+	for m1 := range l3Members {
+		for m2 := range l3Members {
+			if m2 != m1 && l3Members[m1] > 0 && l3Members[m2] > 0 {
+				return fmt.Errorf("Combining %s and %s is not supported yet", m1, m2)
+			}
+		}
+	}
+
+	if !hasMembers(l3Members) && len(i.ToPorts) == 0 {
+		return fmt.Errorf("ingress deny rule must select peers or ports")
+	}
+
+	for _, es := range i.FromEndpoints {
+		if err := es.sanitize(); err != nil {
+			return err
+		}
+	}
+
+	for n := range i.ToPorts {
+		if err := i.ToPorts[n].sanitize(); err != nil {
+			return err
+		}
+	}
+
+	prefixLengths := map[int]exists{}
+	for n := range i.FromCIDR {
+		prefixLength, err := i.FromCIDR[n].sanitize()
+		if err != nil {
+			return err
+		}
+		prefixLengths[prefixLength] = exists{}
+	}
+
+	for n := range i.FromCIDRSet {
+		prefixLength, err := i.FromCIDRSet[n].sanitize()
+		if err != nil {
+			return err
+		}
+		prefixLengths[prefixLength] = exists{}
+	}
+
+	for _, fromEntity := range i.FromEntities {
+		_, ok := EntitySelectorMapping[fromEntity]
+		if !ok {
+			return fmt.Errorf("unsupported entity: %s", fromEntity)
+		}
+	}
+
+	if l := len(prefixLengths); l > MaxCIDRPrefixLengths {
+		return fmt.Errorf("too many ingress deny CIDR prefix lengths %d/%d", l, MaxCIDRPrefixLengths)
+	}
+
+	return nil
+}
+
+func (e *EgressDenyRule) sanitize() error {
+	l3Members := map[string]int{
+		"ToCIDR":      len(e.ToCIDR),
+		"ToCIDRSet":   len(e.ToCIDRSet),
+		"ToEndpoints": len(e.ToEndpoints),
+		"ToEntities":  len(e.ToEntities),
+	}
+
+	for m1 := range l3Members {
+		for m2 := range l3Members {
+			if m2 != m1 && l3Members[m1] > 0 && l3Members[m2] > 0 {
+				return fmt.Errorf("Combining %s and %s is not supported yet", m1, m2)
+			}
+		}
+	}
+
+	if !hasMembers(l3Members) && len(e.ToPorts) == 0 {
+		return fmt.Errorf("egress deny rule must select peers or ports")
+	}
+
+	for _, es := range e.ToEndpoints {
+		if err := es.sanitize(); err != nil {
+			return err
+		}
+	}
+
+	for i := range e.ToPorts {
+		if err := e.ToPorts[i].sanitize(); err != nil {
+			return err
+		}
+	}
+
+	prefixLengths := map[int]exists{}
+	for i := range e.ToCIDR {
+		prefixLength, err := e.ToCIDR[i].sanitize()
+		if err != nil {
+			return err
+		}
+		prefixLengths[prefixLength] = exists{}
+	}
+	for i := range e.ToCIDRSet {
+		prefixLength, err := e.ToCIDRSet[i].sanitize()
+		if err != nil {
+			return err
+		}
+		prefixLengths[prefixLength] = exists{}
+	}
+
+	for _, toEntity := range e.ToEntities {
+		_, ok := EntitySelectorMapping[toEntity]
+		if !ok {
+			return fmt.Errorf("unsupported entity: %s", toEntity)
+		}
+	}
+
+	if l := len(prefixLengths); l > MaxCIDRPrefixLengths {
+		return fmt.Errorf("too many egress deny CIDR prefix lengths %d/%d", l, MaxCIDRPrefixLengths)
+	}
+
+	return nil
+}
+
 // Sanitize sanitizes Kafka rules
 // TODO we need to add support to check
 // wildcard and prefix/suffix later on.
@@ -313,6 +498,33 @@ func (pr *L7Rules) sanitize() error {
 		}
 	}
 
+	if pr.GRPC != nil {
+		nTypes++
+		for i := range pr.GRPC {
+			if err := pr.GRPC[i].Sanitize(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if pr.Cassandra != nil {
+		nTypes++
+		for i := range pr.Cassandra {
+			if err := pr.Cassandra[i].Sanitize(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if pr.Memcached != nil {
+		nTypes++
+		for i := range pr.Memcached {
+			if err := pr.Memcached[i].Sanitize(); err != nil {
+				return err
+			}
+		}
+	}
+
 	if pr.L7 != nil && pr.L7Proto == "" {
 		return fmt.Errorf("'l7' may only be specified when a 'l7proto' is also specified")
 	}
@@ -340,6 +552,10 @@ func (pr *PortRule) sanitize() error {
 			return err
 		}
 
+		if !pr.Rules.IsEmpty() && pr.Ports[i].EndPort != 0 {
+			return fmt.Errorf("L7 rules cannot be applied to port ranges")
+		}
+
 		// DNS L7 rules can be TCP, UDP or ANY, all others are TCP only.
 		switch {
 		case pr.Rules.IsEmpty(), pr.Rules != nil && len(pr.Rules.DNS) > 0:
@@ -358,6 +574,46 @@ func (pr *PortRule) sanitize() error {
 	return nil
 }
 
+func (pr *PortDenyRule) sanitize() error {
+	if len(pr.Ports) > maxPorts {
+		return fmt.Errorf("too many ports, the max is %d", maxPorts)
+	}
+	for i := range pr.Ports {
+		if err := pr.Ports[i].sanitize(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ir *ICMPRule) sanitize() error {
+	if len(ir.Fields) > maxICMPFields {
+		return fmt.Errorf("too many ICMP fields, the max is %d", maxICMPFields)
+	}
+	for i := range ir.Fields {
+		if err := ir.Fields[i].sanitize(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *ICMPField) sanitize() error {
+	switch f.Family {
+	case "":
+		f.Family = IPv4Family
+	case IPv4Family, IPv6Family:
+	default:
+		return fmt.Errorf("invalid ICMP family %q, must be { %s | %s }", f.Family, IPv4Family, IPv6Family)
+	}
+
+	if f.Code != nil && *f.Code == ICMPCodeAny {
+		return fmt.Errorf("ICMP code %d is reserved to match all codes, omit the code instead", ICMPCodeAny)
+	}
+
+	return nil
+}
+
 func (pp *PortProtocol) sanitize() error {
 	if pp.Port == "" {
 		return fmt.Errorf("Port must be specified")
@@ -372,6 +628,15 @@ func (pp *PortProtocol) sanitize() error {
 		return fmt.Errorf("Port cannot be 0")
 	}
 
+	if pp.EndPort != 0 {
+		if pp.EndPort < 0 || pp.EndPort > math.MaxUint16 {
+			return fmt.Errorf("EndPort %d is out of range", pp.EndPort)
+		}
+		if uint64(pp.EndPort) < p {
+			return fmt.Errorf("EndPort %d cannot be smaller than Port %d", pp.EndPort, p)
+		}
+	}
+
 	pp.Protocol, err = ParseL4Proto(string(pp.Protocol))
 	if err != nil {
 		return err
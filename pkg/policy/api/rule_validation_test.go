@@ -17,7 +17,10 @@
 package api
 
 import (
+	"encoding/json"
+
 	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/proxylib/memcached"
 
 	. "gopkg.in/check.v1"
 
@@ -452,3 +455,342 @@ func (s *PolicyAPITestSuite) TestInvalidEndpointSelectors(c *C) {
 	c.Assert(err, Not(IsNil))
 
 }
+
+func (s *PolicyAPITestSuite) TestDenyRulesSanitize(c *C) {
+	validDenyRule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		IngressDeny: []IngressDenyRule{
+			{
+				FromEndpoints: []EndpointSelector{WildcardEndpointSelector},
+				ToPorts: []PortDenyRule{{
+					Ports: []PortProtocol{
+						{Port: "80", Protocol: ProtoTCP},
+					},
+				}},
+			},
+		},
+		EgressDeny: []EgressDenyRule{
+			{
+				ToCIDR: []CIDR{"10.0.0.0/8"},
+			},
+		},
+	}
+	c.Assert(validDenyRule.Sanitize(), IsNil)
+
+	// Combining L3 members is not supported, same as for allow rules.
+	invalidL3Rule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		IngressDeny: []IngressDenyRule{
+			{
+				FromEndpoints: []EndpointSelector{WildcardEndpointSelector},
+				FromCIDR:      []CIDR{"10.0.0.0/8"},
+			},
+		},
+	}
+	c.Assert(invalidL3Rule.Sanitize(), Not(IsNil))
+
+	invalidPortRule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		EgressDeny: []EgressDenyRule{
+			{
+				ToPorts: []PortDenyRule{{
+					Ports: []PortProtocol{
+						{Port: "70000", Protocol: ProtoTCP},
+					},
+				}},
+			},
+		},
+	}
+	c.Assert(invalidPortRule.Sanitize(), Not(IsNil))
+
+	invalidEntityRule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		EgressDeny: []EgressDenyRule{
+			{
+				ToEntities: []Entity{"unknown-entity"},
+			},
+		},
+	}
+	c.Assert(invalidEntityRule.Sanitize(), Not(IsNil))
+
+	// A deny rule without peers and ports would deny all traffic while
+	// policy trace considers it a no-op, it is thus rejected.
+	emptyIngressRule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		IngressDeny:      []IngressDenyRule{{}},
+	}
+	c.Assert(emptyIngressRule.Sanitize(), Not(IsNil))
+
+	emptyEgressRule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		EgressDeny:       []EgressDenyRule{{}},
+	}
+	c.Assert(emptyEgressRule.Sanitize(), Not(IsNil))
+}
+
+func (s *PolicyAPITestSuite) TestPortRangeSanitize(c *C) {
+	rangeRule := func(port string, endPort int32, rules *L7Rules) Rule {
+		return Rule{
+			EndpointSelector: WildcardEndpointSelector,
+			Ingress: []IngressRule{
+				{
+					FromEndpoints: []EndpointSelector{WildcardEndpointSelector},
+					ToPorts: []PortRule{{
+						Ports: []PortProtocol{
+							{Port: port, EndPort: endPort, Protocol: ProtoTCP},
+						},
+						Rules: rules,
+					}},
+				},
+			},
+		}
+	}
+
+	validRule := rangeRule("30000", 32767, nil)
+	c.Assert(validRule.Sanitize(), IsNil)
+
+	validRule = rangeRule("80", 80, nil)
+	c.Assert(validRule.Sanitize(), IsNil)
+
+	validRule = rangeRule("1", 65535, nil)
+	c.Assert(validRule.Sanitize(), IsNil)
+
+	validRule = rangeRule("2", 65535, nil)
+	c.Assert(validRule.Sanitize(), IsNil)
+
+	invalidRule := rangeRule("8080", 80, nil)
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	invalidRule = rangeRule("80", 65536, nil)
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	invalidRule = rangeRule("80", -1, nil)
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	invalidRule = rangeRule("80", 90, &L7Rules{
+		HTTP: []PortRuleHTTP{{Method: "GET"}},
+	})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+}
+
+func (s *PolicyAPITestSuite) TestICMPRulesSanitize(c *C) {
+	code := uint8(0)
+	validICMPRule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		Ingress: []IngressRule{
+			{
+				FromEndpoints: []EndpointSelector{WildcardEndpointSelector},
+				ICMPs: []ICMPRule{{
+					Fields: []ICMPField{
+						{Type: 8, Code: &code},
+						{Family: IPv6Family, Type: 135},
+					},
+				}},
+			},
+		},
+	}
+	c.Assert(validICMPRule.Sanitize(), IsNil)
+	// The family defaults to IPv4
+	c.Assert(validICMPRule.Ingress[0].ICMPs[0].Fields[0].Family, Equals, IPv4Family)
+
+	invalidFamilyRule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		Egress: []EgressRule{
+			{
+				ICMPs: []ICMPRule{{
+					Fields: []ICMPField{{Family: "IPv5", Type: 8}},
+				}},
+			},
+		},
+	}
+	c.Assert(invalidFamilyRule.Sanitize(), Not(IsNil))
+
+	code = ICMPCodeAny
+	invalidCodeRule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		Egress: []EgressRule{
+			{
+				ICMPs: []ICMPRule{{
+					Fields: []ICMPField{{Type: 3, Code: &code}},
+				}},
+			},
+		},
+	}
+	c.Assert(invalidCodeRule.Sanitize(), Not(IsNil))
+
+	invalidPortsRule := Rule{
+		EndpointSelector: WildcardEndpointSelector,
+		Ingress: []IngressRule{
+			{
+				ToPorts: []PortRule{{
+					Ports: []PortProtocol{{Port: "80", Protocol: ProtoTCP}},
+				}},
+				ICMPs: []ICMPRule{{
+					Fields: []ICMPField{{Type: 8}},
+				}},
+			},
+		},
+	}
+	c.Assert(invalidPortsRule.Sanitize(), Not(IsNil))
+}
+
+func (s *PolicyAPITestSuite) TestGRPCRulesSanitize(c *C) {
+	grpcRule := func(rules ...PortRuleGRPC) Rule {
+		return Rule{
+			EndpointSelector: WildcardEndpointSelector,
+			Ingress: []IngressRule{
+				{
+					FromEndpoints: []EndpointSelector{WildcardEndpointSelector},
+					ToPorts: []PortRule{{
+						Ports: []PortProtocol{
+							{Port: "50051", Protocol: ProtoTCP},
+						},
+						Rules: &L7Rules{GRPC: rules},
+					}},
+				},
+			},
+		}
+	}
+
+	validRule := grpcRule(
+		PortRuleGRPC{Service: "helloworld.Greeter", Method: "SayHello"},
+		PortRuleGRPC{Service: "grpc.health.v1.Health"},
+	)
+	c.Assert(validRule.Sanitize(), IsNil)
+
+	invalidRule := grpcRule(PortRuleGRPC{Method: "SayHello"})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	invalidRule = grpcRule(PortRuleGRPC{Service: "helloworld/Greeter"})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	invalidRule = grpcRule(PortRuleGRPC{Service: "helloworld.Greeter", Method: "Say.Hello"})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	// gRPC rules may not be mixed with other L7 rule types.
+	invalidRule = grpcRule(PortRuleGRPC{Service: "helloworld.Greeter"})
+	invalidRule.Ingress[0].ToPorts[0].Rules.HTTP = []PortRuleHTTP{{Method: "GET"}}
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+}
+
+func (s *PolicyAPITestSuite) TestKeyValuePairRulesSanitize(c *C) {
+	l7Rule := func(rules L7Rules) Rule {
+		return Rule{
+			EndpointSelector: WildcardEndpointSelector,
+			Ingress: []IngressRule{
+				{
+					FromEndpoints: []EndpointSelector{WildcardEndpointSelector},
+					ToPorts: []PortRule{{
+						Ports: []PortProtocol{
+							{Port: "9042", Protocol: ProtoTCP},
+						},
+						Rules: &rules,
+					}},
+				},
+			},
+		}
+	}
+
+	validRule := l7Rule(L7Rules{Cassandra: []PortRuleCassandra{
+		{QueryAction: "select", QueryTable: "deathstar\\..*"},
+		{QueryAction: "create-role"},
+		{},
+	}})
+	c.Assert(validRule.Sanitize(), IsNil)
+
+	invalidRule := l7Rule(L7Rules{Cassandra: []PortRuleCassandra{{QueryAction: "explode"}}})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	invalidRule = l7Rule(L7Rules{Cassandra: []PortRuleCassandra{{QueryAction: "create-role", QueryTable: "system.*"}}})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	invalidRule = l7Rule(L7Rules{Cassandra: []PortRuleCassandra{{QueryTable: "(deathstar"}}})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	validRule = l7Rule(L7Rules{Memcached: []PortRuleMemcached{
+		{Command: "get", KeyExact: "alliance"},
+		{Command: "writeGroup", KeyPrefix: "empire/"},
+		{Command: "delete", KeyRegex: "^tmp-[0-9]+$"},
+		{},
+	}})
+	c.Assert(validRule.Sanitize(), IsNil)
+
+	invalidRule = l7Rule(L7Rules{Memcached: []PortRuleMemcached{{Command: "flush"}}})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	validRule = l7Rule(L7Rules{Memcached: []PortRuleMemcached{
+		{Command: "lru_crawler"},
+		{Command: "flush_all"},
+		{Command: "cache_memlimit"},
+	}})
+	c.Assert(validRule.Sanitize(), IsNil)
+
+	invalidRule = l7Rule(L7Rules{Memcached: []PortRuleMemcached{{KeyExact: "alliance"}}})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	invalidRule = l7Rule(L7Rules{Memcached: []PortRuleMemcached{{Command: "get", KeyRegex: "[alliance"}}})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	// Typed rules may not be mixed with other L7 rule types.
+	invalidRule = l7Rule(L7Rules{
+		Cassandra: []PortRuleCassandra{{QueryAction: "select"}},
+		Memcached: []PortRuleMemcached{{Command: "get"}},
+	})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+
+	invalidRule = l7Rule(L7Rules{
+		Cassandra: []PortRuleCassandra{{QueryAction: "select"}},
+		L7Proto:   "cassandra",
+		L7:        []PortRuleL7{{"query_action": "insert"}},
+	})
+	c.Assert(invalidRule.Sanitize(), Not(IsNil))
+}
+
+// This test ensures that a rule selects either endpoints or nodes, but not both.
+func (s *PolicyAPITestSuite) TestNodeSelector(c *C) {
+	nodeRule := Rule{
+		NodeSelector: NewESFromLabels(labels.ParseSelectLabel("role=worker")),
+		Ingress: []IngressRule{
+			{
+				FromEntities: []Entity{EntityCluster},
+			},
+		},
+	}
+	c.Assert(nodeRule.Sanitize(), IsNil)
+
+	// The unset EndpointSelector must not turn into a wildcard selector
+	// when the rule is encoded and decoded again.
+	b, err := json.Marshal(nodeRule)
+	c.Assert(err, IsNil)
+	var decoded Rule
+	c.Assert(json.Unmarshal(b, &decoded), IsNil)
+	c.Assert(decoded.EndpointSelector.LabelSelector, IsNil)
+	c.Assert(decoded.Sanitize(), IsNil)
+
+	nodeRule.EndpointSelector = WildcardEndpointSelector
+	c.Assert(nodeRule.Sanitize(), Not(IsNil))
+
+	nodeRule.EndpointSelector = EndpointSelector{}
+	nodeRule.NodeSelector = EndpointSelector{}
+	c.Assert(nodeRule.Sanitize(), Not(IsNil))
+
+	nodeRule.NodeSelector = NewESFromMatchRequirements(nil, []metav1.LabelSelectorRequirement{{
+		Key:      "role",
+		Operator: "Invalid",
+		Values:   []string{"worker"},
+	}})
+	c.Assert(nodeRule.Sanitize(), Not(IsNil))
+}
+
+// TestMemcachedCommandsMatchParser ensures that exactly the commands enforced
+// by the memcached parser are accepted in memcached rules.
+func (s *PolicyAPITestSuite) TestMemcachedCommandsMatchParser(c *C) {
+	for command := range memcache.MemcacheOpCodeMap {
+		_, ok := memcachedCommands[command]
+		c.Assert(ok, Equals, true, Commentf("memcached parser command %q is not accepted in rules", command))
+	}
+	for command := range memcachedCommands {
+		_, ok := memcache.MemcacheOpCodeMap[command]
+		c.Assert(ok, Equals, true, Commentf("memcached rule command %q is not known to the parser", command))
+	}
+}
@@ -121,8 +121,12 @@ func (n EndpointSelector) HasKeyPrefix(prefix string) bool {
 }
 
 // HasKey checks if the endpoint selector contains the given key in
-// its MatchLabels map or in its MatchExpressions slice.
+// its MatchLabels map or in its MatchExpressions slice. An empty selector
+// contains no keys.
 func (n EndpointSelector) HasKey(key string) bool {
+	if n.LabelSelector == nil {
+		return false
+	}
 	if _, ok := n.MatchLabels[key]; ok {
 		return true
 	}
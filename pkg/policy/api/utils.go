@@ -69,6 +69,57 @@ func (d *PortRuleDNS) Exists(rules L7Rules) bool {
 	return false
 }
 
+// Exists returns true if the gRPC rule already exists in the list of rules
+func (g *PortRuleGRPC) Exists(rules L7Rules) bool {
+	for _, existingRule := range rules.GRPC {
+		if g.Equal(existingRule) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Equal returns true if both gRPC rules are equal
+func (g *PortRuleGRPC) Equal(o PortRuleGRPC) bool {
+	return g.Service == o.Service && g.Method == o.Method
+}
+
+// Exists returns true if the Cassandra rule already exists in the list of
+// rules
+func (c *PortRuleCassandra) Exists(rules L7Rules) bool {
+	for _, existingRule := range rules.Cassandra {
+		if c.Equal(existingRule) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Equal returns true if both Cassandra rules are equal
+func (c *PortRuleCassandra) Equal(o PortRuleCassandra) bool {
+	return c.QueryAction == o.QueryAction && c.QueryTable == o.QueryTable
+}
+
+// Exists returns true if the memcached rule already exists in the list of
+// rules
+func (m *PortRuleMemcached) Exists(rules L7Rules) bool {
+	for _, existingRule := range rules.Memcached {
+		if m.Equal(existingRule) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Equal returns true if both memcached rules are equal
+func (m *PortRuleMemcached) Equal(o PortRuleMemcached) bool {
+	return m.Command == o.Command && m.KeyExact == o.KeyExact &&
+		m.KeyPrefix == o.KeyPrefix && m.KeyRegex == o.KeyRegex
+}
+
 // Equal returns true if both rules are equal
 func (k *PortRuleKafka) Equal(o PortRuleKafka) bool {
 	return k.APIVersion == o.APIVersion && k.APIKey == o.APIKey &&
@@ -88,7 +139,21 @@ func (h *PortRuleL7) Exists(rules L7Rules) bool {
 
 // Equal returns true if both rules are equal
 func (d *PortRuleDNS) Equal(o PortRuleDNS) bool {
-	return d != nil && d.MatchName == o.MatchName && d.MatchPattern == o.MatchPattern
+	if d == nil || d.MatchName != o.MatchName || d.MatchPattern != o.MatchPattern ||
+		len(d.MatchQueryTypes) != len(o.MatchQueryTypes) || len(d.ResponseCIDRs) != len(o.ResponseCIDRs) {
+		return false
+	}
+	for i := range d.MatchQueryTypes {
+		if d.MatchQueryTypes[i] != o.MatchQueryTypes[i] {
+			return false
+		}
+	}
+	for i := range d.ResponseCIDRs {
+		if d.ResponseCIDRs[i] != o.ResponseCIDRs[i] {
+			return false
+		}
+	}
+	return true
 }
 
 // Equal returns true if both L7 rules are equal
@@ -119,6 +119,53 @@ func (in CIDRSlice) DeepCopy() CIDRSlice {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressDenyRule) DeepCopyInto(out *EgressDenyRule) {
+	*out = *in
+	if in.ToEndpoints != nil {
+		in, out := &in.ToEndpoints, &out.ToEndpoints
+		*out = make([]EndpointSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ToPorts != nil {
+		in, out := &in.ToPorts, &out.ToPorts
+		*out = make([]PortDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ToCIDR != nil {
+		in, out := &in.ToCIDR, &out.ToCIDR
+		*out = make(CIDRSlice, len(*in))
+		copy(*out, *in)
+	}
+	if in.ToCIDRSet != nil {
+		in, out := &in.ToCIDRSet, &out.ToCIDRSet
+		*out = make(CIDRRuleSlice, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ToEntities != nil {
+		in, out := &in.ToEntities, &out.ToEntities
+		*out = make(EntitySlice, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressDenyRule.
+func (in *EgressDenyRule) DeepCopy() *EgressDenyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressDenyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EgressRule) DeepCopyInto(out *EgressRule) {
 	*out = *in
@@ -143,6 +190,13 @@ func (in *EgressRule) DeepCopyInto(out *EgressRule) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ICMPs != nil {
+		in, out := &in.ICMPs, &out.ICMPs
+		*out = make([]ICMPRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.ToCIDR != nil {
 		in, out := &in.ToCIDR, &out.ToCIDR
 		*out = make(CIDRSlice, len(*in))
@@ -302,6 +356,97 @@ func (in FQDNSelectorSlice) DeepCopy() FQDNSelectorSlice {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ICMPField) DeepCopyInto(out *ICMPField) {
+	*out = *in
+	if in.Code != nil {
+		in, out := &in.Code, &out.Code
+		*out = new(byte)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICMPField.
+func (in *ICMPField) DeepCopy() *ICMPField {
+	if in == nil {
+		return nil
+	}
+	out := new(ICMPField)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ICMPRule) DeepCopyInto(out *ICMPRule) {
+	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]ICMPField, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICMPRule.
+func (in *ICMPRule) DeepCopy() *ICMPRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ICMPRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressDenyRule) DeepCopyInto(out *IngressDenyRule) {
+	*out = *in
+	if in.FromEndpoints != nil {
+		in, out := &in.FromEndpoints, &out.FromEndpoints
+		*out = make([]EndpointSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ToPorts != nil {
+		in, out := &in.ToPorts, &out.ToPorts
+		*out = make([]PortDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FromCIDR != nil {
+		in, out := &in.FromCIDR, &out.FromCIDR
+		*out = make(CIDRSlice, len(*in))
+		copy(*out, *in)
+	}
+	if in.FromCIDRSet != nil {
+		in, out := &in.FromCIDRSet, &out.FromCIDRSet
+		*out = make(CIDRRuleSlice, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FromEntities != nil {
+		in, out := &in.FromEntities, &out.FromEntities
+		*out = make(EntitySlice, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressDenyRule.
+func (in *IngressDenyRule) DeepCopy() *IngressDenyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressDenyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressRule) DeepCopyInto(out *IngressRule) {
 	*out = *in
@@ -326,6 +471,13 @@ func (in *IngressRule) DeepCopyInto(out *IngressRule) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ICMPs != nil {
+		in, out := &in.ICMPs, &out.ICMPs
+		*out = make([]ICMPRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.FromCIDR != nil {
 		in, out := &in.FromCIDR, &out.FromCIDR
 		*out = make(CIDRSlice, len(*in))
@@ -429,6 +581,23 @@ func (in *L7Rules) DeepCopyInto(out *L7Rules) {
 	if in.DNS != nil {
 		in, out := &in.DNS, &out.DNS
 		*out = make([]PortRuleDNS, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GRPC != nil {
+		in, out := &in.GRPC, &out.GRPC
+		*out = make([]PortRuleGRPC, len(*in))
+		copy(*out, *in)
+	}
+	if in.Cassandra != nil {
+		in, out := &in.Cassandra, &out.Cassandra
+		*out = make([]PortRuleCassandra, len(*in))
+		copy(*out, *in)
+	}
+	if in.Memcached != nil {
+		in, out := &in.Memcached, &out.Memcached
+		*out = make([]PortRuleMemcached, len(*in))
 		copy(*out, *in)
 	}
 	if in.L7 != nil {
@@ -457,6 +626,27 @@ func (in *L7Rules) DeepCopy() *L7Rules {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortDenyRule) DeepCopyInto(out *PortDenyRule) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]PortProtocol, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortDenyRule.
+func (in *PortDenyRule) DeepCopy() *PortDenyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PortDenyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PortProtocol) DeepCopyInto(out *PortProtocol) {
 	*out = *in
@@ -499,9 +689,35 @@ func (in *PortRule) DeepCopy() *PortRule {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortRuleCassandra) DeepCopyInto(out *PortRuleCassandra) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortRuleCassandra.
+func (in *PortRuleCassandra) DeepCopy() *PortRuleCassandra {
+	if in == nil {
+		return nil
+	}
+	out := new(PortRuleCassandra)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PortRuleDNS) DeepCopyInto(out *PortRuleDNS) {
 	*out = *in
+	if in.MatchQueryTypes != nil {
+		in, out := &in.MatchQueryTypes, &out.MatchQueryTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResponseCIDRs != nil {
+		in, out := &in.ResponseCIDRs, &out.ResponseCIDRs
+		*out = make([]CIDR, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -515,6 +731,22 @@ func (in *PortRuleDNS) DeepCopy() *PortRuleDNS {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortRuleGRPC) DeepCopyInto(out *PortRuleGRPC) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortRuleGRPC.
+func (in *PortRuleGRPC) DeepCopy() *PortRuleGRPC {
+	if in == nil {
+		return nil
+	}
+	out := new(PortRuleGRPC)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PortRuleHTTP) DeepCopyInto(out *PortRuleHTTP) {
 	*out = *in
@@ -584,10 +816,27 @@ func (in PortRuleL7) DeepCopy() PortRuleL7 {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortRuleMemcached) DeepCopyInto(out *PortRuleMemcached) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortRuleMemcached.
+func (in *PortRuleMemcached) DeepCopy() *PortRuleMemcached {
+	if in == nil {
+		return nil
+	}
+	out := new(PortRuleMemcached)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Rule) DeepCopyInto(out *Rule) {
 	*out = *in
 	in.EndpointSelector.DeepCopyInto(&out.EndpointSelector)
+	in.NodeSelector.DeepCopyInto(&out.NodeSelector)
 	if in.Ingress != nil {
 		in, out := &in.Ingress, &out.Ingress
 		*out = make([]IngressRule, len(*in))
@@ -602,6 +851,20 @@ func (in *Rule) DeepCopyInto(out *Rule) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.IngressDeny != nil {
+		in, out := &in.IngressDeny, &out.IngressDeny
+		*out = make([]IngressDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EgressDeny != nil {
+		in, out := &in.EgressDeny, &out.EgressDeny
+		*out = make([]EgressDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	out.Labels = in.Labels.DeepCopy()
 	return
 }
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 
@@ -88,11 +89,18 @@ const (
 	ParserTypeKafka L7ParserType = "kafka"
 	// ParserTypeDNS specifies a DNS parser type
 	ParserTypeDNS L7ParserType = "dns"
+	// ParserTypeCassandra specifies the Cassandra parser of proxylib
+	ParserTypeCassandra L7ParserType = "cassandra"
+	// ParserTypeMemcached specifies the memcached parser of proxylib
+	ParserTypeMemcached L7ParserType = "memcache"
 )
 
 type L4Filter struct {
 	// Port is the destination port to allow
 	Port int `json:"port"`
+	// EndPort is the last destination port of a port range starting at
+	// Port, or 0 if the filter only applies to Port.
+	EndPort int `json:"endPort,omitempty"`
 	// Protocol is the L4 protocol to allow or NONE
 	Protocol api.L4Proto `json:"protocol"`
 	// U8Proto is the Protocol in numeric format, or 0 for NONE
@@ -115,6 +123,9 @@ type L4Filter struct {
 	Ingress bool `json:"-"`
 	// The rule labels of this Filter
 	DerivedFromRules labels.LabelArrayList `json:"-"`
+	// IsDeny is true if the filter denies the traffic it selects rather
+	// than allowing it.
+	IsDeny bool `json:"deny,omitempty"`
 }
 
 // AllowsAllAtL3 returns whether this L4Filter applies to all endpoints at L3.
@@ -122,26 +133,98 @@ func (l4 *L4Filter) AllowsAllAtL3() bool {
 	return l4.allowsAllAtL3
 }
 
-// ToKeys converts filter into a list of Keys.
+// portRange returns the first and last destination port the filter applies
+// to. Both are equal if the filter does not specify a port range.
+func (l4 *L4Filter) portRange() (uint16, uint16) {
+	if l4.EndPort > l4.Port {
+		return uint16(l4.Port), uint16(l4.EndPort)
+	}
+	return uint16(l4.Port), uint16(l4.Port)
+}
+
+// coversPort returns true if the filter applies to the given destination
+// port. A filter with port 0 applies to all ports.
+func (l4 *L4Filter) coversPort(port uint16) bool {
+	first, last := l4.portRange()
+	return first == 0 || (port >= first && port <= last)
+}
+
+// coversAllPorts returns true if the filter is a port range spanning all
+// ports of its protocol.
+func (l4 *L4Filter) coversAllPorts() bool {
+	first, last := l4.portRange()
+	return first <= 1 && last == math.MaxUint16
+}
+
+// portBlockBits is the granularity of the port blocks which port ranges are
+// split into. It must match POLICY_PORT_BLOCK_BITS in bpf/lib/policy.h.
+const portBlockBits = 4
+
+// portBlock is a block of 1 << wildcard ports starting at port, which is
+// aligned to the size of the block.
+type portBlock struct {
+	port     uint16
+	wildcard uint8
+}
+
+// portBlocks splits the port range first-last into the smallest number of
+// port blocks, each spanning 1 << n ports where n is a multiple of
+// portBlockBits below 16.
+func portBlocks(first, last uint16) []portBlock {
+	var blocks []portBlock
+	// Iterate with a wider type so that a range ending at 65535 does not
+	// overflow.
+	for port := uint32(first); port <= uint32(last); {
+		var wildcard uint8
+		for wildcard+portBlockBits < 16 {
+			size := uint32(1) << (wildcard + portBlockBits)
+			if port%size != 0 || port+size-1 > uint32(last) {
+				break
+			}
+			wildcard += portBlockBits
+		}
+		blocks = append(blocks, portBlock{port: uint16(port), wildcard: wildcard})
+		port += 1 << wildcard
+	}
+	return blocks
+}
+
+// ToKeys converts filter into a list of Keys. Port ranges are split into
+// aligned blocks of ports, which are converted into one key per block and
+// selected identity, with the low-order bits of the port which vary within
+// the block wildcarded. Ranges spanning all ports are converted into a single
+// key with port 0 per selected identity instead, which the datapath matches
+// against all ports of the protocol.
 func (l4 *L4Filter) ToKeys(direction trafficdirection.TrafficDirection, identityCache cache.IdentityCache, deniedIdentities cache.IdentityCache) []Key {
-	keysToAdd := []Key{}
-	port := uint16(l4.Port)
 	proto := uint8(l4.U8Proto)
+	first, last := l4.portRange()
+	if l4.coversAllPorts() {
+		first, last = 0, 0
+	}
+	blocks := portBlocks(first, last)
 
+	var identities []identity.NumericIdentity
 	for _, sel := range l4.Endpoints {
-		identities := getSecurityIdentities(identityCache, &sel)
-		for _, id := range identities {
+		for _, id := range getSecurityIdentities(identityCache, &sel) {
 			if _, identityIsDenied := deniedIdentities[id]; !identityIsDenied {
-				srcID := id.Uint32()
-				keyToAdd := Key{
-					Identity: srcID,
-					// NOTE: Port is in host byte-order!
-					DestPort:         port,
-					Nexthdr:          proto,
-					TrafficDirection: direction.Uint8(),
-				}
-				keysToAdd = append(keysToAdd, keyToAdd)
+				identities = append(identities, id)
+			}
+		}
+	}
+
+	keysToAdd := make([]Key, 0, len(identities)*len(blocks))
+	for _, id := range identities {
+		srcID := id.Uint32()
+		for _, block := range blocks {
+			keyToAdd := Key{
+				Identity: srcID,
+				// NOTE: Port is in host byte-order!
+				DestPort:         block.port,
+				DestPortWildcard: block.wildcard,
+				Nexthdr:          proto,
+				TrafficDirection: direction.Uint8(),
 			}
+			keysToAdd = append(keysToAdd, keyToAdd)
 		}
 	}
 	return keysToAdd
@@ -158,6 +241,9 @@ func (l7 L7DataMap) GetRelevantRules(identity *identity.Identity) api.L7Rules {
 				rules.HTTP = append(rules.HTTP, endpointRules.HTTP...)
 				rules.Kafka = append(rules.Kafka, endpointRules.Kafka...)
 				rules.DNS = append(rules.DNS, endpointRules.DNS...)
+				rules.GRPC = append(rules.GRPC, endpointRules.GRPC...)
+				rules.Cassandra = append(rules.Cassandra, endpointRules.Cassandra...)
+				rules.Memcached = append(rules.Memcached, endpointRules.Memcached...)
 				rules.L7Proto = endpointRules.L7Proto
 				rules.L7 = append(rules.L7, endpointRules.L7...)
 			}
@@ -169,6 +255,9 @@ func (l7 L7DataMap) GetRelevantRules(identity *identity.Identity) api.L7Rules {
 		rules.HTTP = append(rules.HTTP, r.HTTP...)
 		rules.Kafka = append(rules.Kafka, r.Kafka...)
 		rules.DNS = append(rules.DNS, r.DNS...)
+		rules.GRPC = append(rules.GRPC, r.GRPC...)
+		rules.Cassandra = append(rules.Cassandra, r.Cassandra...)
+		rules.Memcached = append(rules.Memcached, r.Memcached...)
 		rules.L7Proto = r.L7Proto // XXX
 		rules.L7 = append(rules.L7, r.L7...)
 	}
@@ -191,6 +280,18 @@ func (l7 L7DataMap) addRulesForEndpoints(rules api.L7Rules, endpoints []api.Endp
 	}
 }
 
+// l4PolicyMapKey returns the key of the L4Filter for the given port and
+// protocol in an L4PolicyMap, e.g. "80/TCP" or "30000-32767/TCP" for a port
+// range.
+func l4PolicyMapKey(port api.PortProtocol, protocol api.L4Proto) string {
+	// already validated via PortRule.Validate()
+	p, _ := strconv.ParseUint(port.Port, 0, 16)
+	if uint64(port.EndPort) > p {
+		return fmt.Sprintf("%s-%d/%s", port.Port, port.EndPort, protocol)
+	}
+	return port.Port + "/" + string(protocol)
+}
+
 // CreateL4Filter creates a filter for L4 policy that applies to the specified
 // endpoints and port/protocol, with reference to the original rules that the
 // filter is derived from. This filter may be associated with a series of L7
@@ -200,6 +301,10 @@ func CreateL4Filter(peerEndpoints api.EndpointSelectorSlice, rule api.PortRule,
 
 	// already validated via PortRule.Validate()
 	p, _ := strconv.ParseUint(port.Port, 0, 16)
+	endPort := 0
+	if uint64(port.EndPort) > p {
+		endPort = int(port.EndPort)
+	}
 	// already validated via L4Proto.Validate()
 	u8p, _ := u8proto.ParseProtocol(string(protocol))
 
@@ -212,6 +317,7 @@ func CreateL4Filter(peerEndpoints api.EndpointSelectorSlice, rule api.PortRule,
 
 	l4 := L4Filter{
 		Port:             int(p),
+		EndPort:          endPort,
 		Protocol:         protocol,
 		U8Proto:          u8p,
 		L7RulesPerEp:     make(L7DataMap),
@@ -223,10 +329,15 @@ func CreateL4Filter(peerEndpoints api.EndpointSelectorSlice, rule api.PortRule,
 
 	if protocol == api.ProtoTCP && rule.Rules != nil {
 		switch {
-		case len(rule.Rules.HTTP) > 0:
+		case len(rule.Rules.HTTP) > 0, len(rule.Rules.GRPC) > 0:
+			// gRPC is carried over HTTP/2 and enforced by the HTTP parser.
 			l4.L7Parser = ParserTypeHTTP
 		case len(rule.Rules.Kafka) > 0:
 			l4.L7Parser = ParserTypeKafka
+		case len(rule.Rules.Cassandra) > 0:
+			l4.L7Parser = ParserTypeCassandra
+		case len(rule.Rules.Memcached) > 0:
+			l4.L7Parser = ParserTypeMemcached
 		case rule.Rules.L7Proto != "":
 			l4.L7Parser = (L7ParserType)(rule.Rules.L7Proto)
 		}
@@ -277,6 +388,18 @@ func CreateL4EgressFilter(toEndpoints api.EndpointSelectorSlice, rule api.PortRu
 	return CreateL4Filter(toEndpoints, rule, port, protocol, ruleLabels, false)
 }
 
+// CreateL4DenyFilter creates a filter for L4 policy that denies traffic
+// between the specified endpoints and port/protocol, with reference to the
+// original rules that the filter is derived from. Deny filters never carry L7
+// rules.
+func CreateL4DenyFilter(peerEndpoints api.EndpointSelectorSlice, port api.PortProtocol,
+	protocol api.L4Proto, ruleLabels labels.LabelArray, ingress bool) L4Filter {
+
+	filter := CreateL4Filter(peerEndpoints, api.PortRule{}, port, protocol, ruleLabels, ingress)
+	filter.IsDeny = true
+	return filter
+}
+
 // IsRedirect returns true if the L4 filter contains a port redirection
 func (l4 *L4Filter) IsRedirect() bool {
 	return l4.L7Parser != ParserTypeNone
@@ -352,47 +475,109 @@ func (l4 L4PolicyMap) containsAllL3L4(labels labels.LabelArray, ports []*models.
 	}
 
 	for _, l4Ctx := range ports {
-		lwrProtocol := l4Ctx.Protocol
-		switch lwrProtocol {
-		case "", models.PortProtocolANY:
-			tcpPort := fmt.Sprintf("%d/TCP", l4Ctx.Port)
-			tcpFilter, tcpmatch := l4[tcpPort]
-			if tcpmatch {
-				tcpmatch = tcpFilter.matchesLabels(labels)
-			}
-			udpPort := fmt.Sprintf("%d/UDP", l4Ctx.Port)
-			udpFilter, udpmatch := l4[udpPort]
-			if udpmatch {
-				udpmatch = udpFilter.matchesLabels(labels)
-			}
-			if !tcpmatch && !udpmatch {
-				return api.Denied
-			}
-		default:
-			port := fmt.Sprintf("%d/%s", l4Ctx.Port, lwrProtocol)
-			filter, match := l4[port]
-			if !match || !filter.matchesLabels(labels) {
-				return api.Denied
+		first, last := tracePortRange(l4Ctx)
+		// Every port of a traced port range must be allowed.
+		for port := uint32(first); port <= uint32(last); port++ {
+			switch l4Ctx.Protocol {
+			case "", models.PortProtocolANY:
+				if !l4.matchesL3L4(labels, uint16(port), api.ProtoTCP) &&
+					!l4.matchesL3L4(labels, uint16(port), api.ProtoUDP) {
+					return api.Denied
+				}
+			default:
+				if !l4.matchesL3L4(labels, uint16(port), api.L4Proto(l4Ctx.Protocol)) {
+					return api.Denied
+				}
 			}
 		}
 	}
 	return api.Allowed
 }
 
+// tracePortRange returns the first and last port of the given traced port.
+func tracePortRange(port *models.Port) (uint16, uint16) {
+	if port.EndPort > port.Port {
+		return port.Port, port.EndPort
+	}
+	return port.Port, port.Port
+}
+
+// matchesL3L4 returns true if the L4PolicyMap contains a filter for the port
+// and protocol which selects `labels`. Filters for the exact port are looked up
+// directly, port range filters are found by iterating over the map.
+func (l4 L4PolicyMap) matchesL3L4(labels labels.LabelArray, port uint16, proto api.L4Proto) bool {
+	if filter, ok := l4[fmt.Sprintf("%d/%s", port, proto)]; ok && filter.matchesLabels(labels) {
+		return true
+	}
+	for _, filter := range l4 {
+		if filter.EndPort == 0 || filter.Protocol != proto {
+			continue
+		}
+		if filter.coversPort(port) && filter.matchesLabels(labels) {
+			return true
+		}
+	}
+	return false
+}
+
 type L4Policy struct {
 	Ingress L4PolicyMap
 	Egress  L4PolicyMap
 
+	// IngressDeny and EgressDeny contain the filters derived from deny
+	// rules. They take precedence over the filters in Ingress and Egress.
+	IngressDeny L4PolicyMap
+	EgressDeny  L4PolicyMap
+
 	// Revision is the repository revision used to generate this policy.
 	Revision uint64
 }
 
 func NewL4Policy() *L4Policy {
 	return &L4Policy{
-		Ingress:  L4PolicyMap{},
-		Egress:   L4PolicyMap{},
-		Revision: 0,
+		Ingress:     L4PolicyMap{},
+		Egress:      L4PolicyMap{},
+		IngressDeny: L4PolicyMap{},
+		EgressDeny:  L4PolicyMap{},
+		Revision:    0,
+	}
+}
+
+// containsAnyL3L4 checks if the L4PolicyMap contains any of the L4 ports in
+// `ports` for a filter which selects `labels`. It is used to evaluate deny
+// filters, for which a single matching port is sufficient to deny the traffic.
+// Returns api.Denied if a matching filter is found, otherwise api.Undecided.
+func (l4 L4PolicyMap) containsAnyL3L4(labels labels.LabelArray, ports []*models.Port) api.Decision {
+	for _, l4Ctx := range ports {
+		var protos []api.L4Proto
+		switch l4Ctx.Protocol {
+		case "", models.PortProtocolANY:
+			protos = []api.L4Proto{api.ProtoTCP, api.ProtoUDP}
+		default:
+			protos = []api.L4Proto{api.L4Proto(l4Ctx.Protocol)}
+		}
+		first, last := tracePortRange(l4Ctx)
+		for port := uint32(first); port <= uint32(last); port++ {
+			for _, proto := range protos {
+				if l4.matchesL3L4(labels, uint16(port), proto) {
+					return api.Denied
+				}
+			}
+		}
 	}
+	return api.Undecided
+}
+
+// IngressDeniesContext checks if the receiver's ingress deny L4Policy
+// contains any of the `dPorts` for the `labels` in the context.
+func (l4 *L4PolicyMap) IngressDeniesContext(ctx *SearchContext) api.Decision {
+	return l4.containsAnyL3L4(ctx.From, ctx.DPorts)
+}
+
+// EgressDeniesContext checks if the receiver's egress deny L4Policy contains
+// any of the `dPorts` for the `labels` in the context.
+func (l4 *L4PolicyMap) EgressDeniesContext(ctx *SearchContext) api.Decision {
+	return l4.containsAnyL3L4(ctx.To, ctx.DPorts)
 }
 
 // IngressCoversContext checks if the receiver's ingress L4Policy contains
@@ -415,7 +600,8 @@ func (l4 *L4Policy) HasRedirect() bool {
 // RequiresConntrack returns true if if the L4 configuration requires
 // connection tracking to be enabled.
 func (l4 *L4Policy) RequiresConntrack() bool {
-	return l4 != nil && (len(l4.Ingress) > 0 || len(l4.Egress) > 0)
+	return l4 != nil && (len(l4.Ingress) > 0 || len(l4.Egress) > 0 ||
+		len(l4.IngressDeny) > 0 || len(l4.EgressDeny) > 0)
 }
 
 func (l4 *L4Policy) GetModel() *models.L4Policy {
@@ -430,6 +616,12 @@ func (l4 *L4Policy) GetModel() *models.L4Policy {
 			DerivedFromRules: v.DerivedFromRules.GetModel(),
 		})
 	}
+	for _, v := range l4.IngressDeny {
+		ingress = append(ingress, &models.PolicyRule{
+			Rule:             v.MarshalIndent(),
+			DerivedFromRules: v.DerivedFromRules.GetModel(),
+		})
+	}
 
 	egress := []*models.PolicyRule{}
 	for _, v := range l4.Egress {
@@ -438,6 +630,12 @@ func (l4 *L4Policy) GetModel() *models.L4Policy {
 			DerivedFromRules: v.DerivedFromRules.GetModel(),
 		})
 	}
+	for _, v := range l4.EgressDeny {
+		egress = append(egress, &models.PolicyRule{
+			Rule:             v.MarshalIndent(),
+			DerivedFromRules: v.DerivedFromRules.GetModel(),
+		})
+	}
 
 	return &models.L4Policy{
 		Ingress: ingress,
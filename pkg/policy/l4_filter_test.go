@@ -1362,3 +1362,67 @@ func (ds *PolicyTestSuite) TestAllowingLocalhostShadowsL7(c *C) {
 	c.Assert(state.selectedRules, Equals, 0)
 	c.Assert(state.matchedRules, Equals, 0)
 }
+
+// Case 13: typed key-value pair rules of the same protocol are merged, rules
+// of different protocols on the same port conflict.
+func (ds *PolicyTestSuite) TestMergeKeyValuePairRules(c *C) {
+	cassandraRule := func(rules ...api.PortRuleCassandra) api.IngressRule {
+		return api.IngressRule{
+			FromEndpoints: []api.EndpointSelector{api.WildcardEndpointSelector},
+			ToPorts: []api.PortRule{{
+				Ports: []api.PortProtocol{
+					{Port: "9042", Protocol: api.ProtoTCP},
+				},
+				Rules: &api.L7Rules{Cassandra: rules},
+			}},
+		}
+	}
+
+	rule := &rule{
+		Rule: api.Rule{
+			EndpointSelector: endpointSelectorA,
+			Ingress: []api.IngressRule{
+				cassandraRule(api.PortRuleCassandra{QueryAction: "select", QueryTable: "deathstar.*"}),
+				cassandraRule(
+					api.PortRuleCassandra{QueryAction: "select", QueryTable: "deathstar.*"},
+					api.PortRuleCassandra{QueryAction: "insert", QueryTable: "attendance.daily_records"},
+				),
+			},
+		}}
+	c.Assert(rule.Sanitize(), IsNil)
+
+	buffer := new(bytes.Buffer)
+	ctxToA := SearchContext{To: labelsA, Trace: TRACE_VERBOSE}
+	ctxToA.Logging = logging.NewLogBackend(buffer, "", 0)
+	c.Log(buffer)
+
+	state := traceState{}
+	res, err := rule.resolveL4IngressPolicy(&ctxToA, &state, NewL4Policy(), nil)
+	c.Assert(err, IsNil)
+	c.Assert(res, Not(IsNil))
+
+	filter := res.Ingress["9042/TCP"]
+	c.Assert(filter.L7Parser, Equals, ParserTypeCassandra)
+	c.Assert(filter.L7RulesPerEp[api.WildcardEndpointSelector].Cassandra, checker.DeepEquals, []api.PortRuleCassandra{
+		{QueryAction: "select", QueryTable: "deathstar.*"},
+		{QueryAction: "insert", QueryTable: "attendance.daily_records"},
+	})
+
+	rule.Ingress = append(rule.Ingress, api.IngressRule{
+		FromEndpoints: []api.EndpointSelector{api.WildcardEndpointSelector},
+		ToPorts: []api.PortRule{{
+			Ports: []api.PortProtocol{
+				{Port: "9042", Protocol: api.ProtoTCP},
+			},
+			Rules: &api.L7Rules{
+				Memcached: []api.PortRuleMemcached{{Command: "get"}},
+			},
+		}},
+	})
+	c.Assert(rule.Sanitize(), IsNil)
+
+	state = traceState{}
+	res, err = rule.resolveL4IngressPolicy(&ctxToA, &state, NewL4Policy(), nil)
+	c.Assert(err, Not(IsNil))
+	c.Assert(res, IsNil)
+}
@@ -21,8 +21,11 @@ import (
 
 	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/pkg/checker"
+	"github.com/cilium/cilium/pkg/identity"
+	"github.com/cilium/cilium/pkg/identity/cache"
 	"github.com/cilium/cilium/pkg/labels"
 	"github.com/cilium/cilium/pkg/policy/api"
+	"github.com/cilium/cilium/pkg/policy/trafficdirection"
 	"github.com/kr/pretty"
 
 	. "gopkg.in/check.v1"
@@ -246,3 +249,106 @@ func (s *PolicyTestSuite) TestJSONMarshal(c *C) {
 		c.Assert(model.Ingress[i].Rule, Equals, expectedIngress[i])
 	}
 }
+
+func (s *PolicyTestSuite) TestPortBlocks(c *C) {
+	c.Assert(portBlocks(0, 0), checker.DeepEquals, []portBlock{{port: 0}})
+	c.Assert(portBlocks(80, 80), checker.DeepEquals, []portBlock{{port: 80}})
+	c.Assert(portBlocks(14, 33), checker.DeepEquals, []portBlock{
+		{port: 14}, {port: 15}, {port: 16, wildcard: 4}, {port: 32}, {port: 33},
+	})
+	c.Assert(portBlocks(0x100, 0x1fff), checker.DeepEquals, []portBlock{
+		{port: 0x100, wildcard: 8}, {port: 0x200, wildcard: 8}, {port: 0x300, wildcard: 8},
+		{port: 0x400, wildcard: 8}, {port: 0x500, wildcard: 8}, {port: 0x600, wildcard: 8},
+		{port: 0x700, wildcard: 8}, {port: 0x800, wildcard: 8}, {port: 0x900, wildcard: 8},
+		{port: 0xa00, wildcard: 8}, {port: 0xb00, wildcard: 8}, {port: 0xc00, wildcard: 8},
+		{port: 0xd00, wildcard: 8}, {port: 0xe00, wildcard: 8}, {port: 0xf00, wildcard: 8},
+		{port: 0x1000, wildcard: 12},
+	})
+	c.Assert(portBlocks(0xfff0, 0xffff), checker.DeepEquals, []portBlock{{port: 0xfff0, wildcard: 4}})
+}
+
+func (s *PolicyTestSuite) TestL4FilterPortRange(c *C) {
+	fooSelector := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	tuple := api.PortProtocol{Port: "30000", EndPort: 30009, Protocol: api.ProtoTCP}
+	portrule := api.PortRule{Ports: []api.PortProtocol{tuple}}
+
+	c.Assert(l4PolicyMapKey(tuple, tuple.Protocol), Equals, "30000-30009/TCP")
+
+	filter := CreateL4IngressFilter([]api.EndpointSelector{fooSelector}, nil, portrule, tuple, tuple.Protocol, nil)
+	c.Assert(filter.Port, Equals, 30000)
+	c.Assert(filter.EndPort, Equals, 30009)
+
+	identityCache := cache.IdentityCache{
+		identity.NumericIdentity(100): labels.ParseSelectLabelArray("foo"),
+		identity.NumericIdentity(101): labels.ParseSelectLabelArray("bar"),
+	}
+	keys := filter.ToKeys(trafficdirection.Ingress, identityCache, nil)
+	c.Assert(len(keys), Equals, 10)
+	for i, key := range keys {
+		c.Assert(key.Identity, Equals, uint32(100))
+		c.Assert(key.DestPort, Equals, uint16(30000+i))
+	}
+
+	// A range ending at the last port must not overflow.
+	tuple = api.PortProtocol{Port: "65534", EndPort: 65535, Protocol: api.ProtoUDP}
+	filter = CreateL4EgressFilter([]api.EndpointSelector{fooSelector}, api.PortRule{}, tuple, tuple.Protocol, nil)
+	c.Assert(len(filter.ToKeys(trafficdirection.Egress, identityCache, nil)), Equals, 2)
+	tuple = api.PortProtocol{Port: "2", EndPort: 65535, Protocol: api.ProtoUDP}
+	filter = CreateL4EgressFilter([]api.EndpointSelector{fooSelector}, api.PortRule{}, tuple, tuple.Protocol, nil)
+	keys = filter.ToKeys(trafficdirection.Egress, identityCache, nil)
+	c.Assert(len(keys), Equals, 14+15+15+15)
+	c.Assert(keys[len(keys)-1], Equals, Key{Identity: 100, DestPort: 0xf000, DestPortWildcard: 12, Nexthdr: 17, TrafficDirection: trafficdirection.Egress.Uint8()})
+
+	// The NodePort range is split into aligned blocks of ports.
+	tuple = api.PortProtocol{Port: "30000", EndPort: 32767, Protocol: api.ProtoTCP}
+	filter = CreateL4IngressFilter([]api.EndpointSelector{fooSelector}, nil, api.PortRule{}, tuple, tuple.Protocol, nil)
+	keys = filter.ToKeys(trafficdirection.Ingress, identityCache, nil)
+	c.Assert(len(keys), Equals, 1+12+10)
+	c.Assert(keys[0], Equals, Key{Identity: 100, DestPort: 30000, DestPortWildcard: 4, Nexthdr: 6, TrafficDirection: trafficdirection.Ingress.Uint8()})
+	c.Assert(keys[1], Equals, Key{Identity: 100, DestPort: 30016, DestPortWildcard: 4, Nexthdr: 6, TrafficDirection: trafficdirection.Ingress.Uint8()})
+	c.Assert(keys[13], Equals, Key{Identity: 100, DestPort: 30208, DestPortWildcard: 8, Nexthdr: 6, TrafficDirection: trafficdirection.Ingress.Uint8()})
+	c.Assert(keys[22], Equals, Key{Identity: 100, DestPort: 32512, DestPortWildcard: 8, Nexthdr: 6, TrafficDirection: trafficdirection.Ingress.Uint8()})
+
+	// A range spanning all ports is a single key per identity, regardless
+	// of the number of selected identities.
+	identityCache = cache.IdentityCache{}
+	for i := 0; i < 64; i++ {
+		identityCache[identity.NumericIdentity(100+i)] = labels.ParseSelectLabelArray("foo")
+	}
+	tuple = api.PortProtocol{Port: "1", EndPort: 65535, Protocol: api.ProtoTCP}
+	filter = CreateL4EgressFilter([]api.EndpointSelector{fooSelector}, api.PortRule{}, tuple, tuple.Protocol, nil)
+	keys = filter.ToKeys(trafficdirection.Egress, identityCache, nil)
+	c.Assert(len(keys), Equals, 64)
+	for _, key := range keys {
+		c.Assert(key.DestPort, Equals, uint16(0))
+		c.Assert(key.Nexthdr, Equals, uint8(6))
+	}
+
+	// An EndPort equal to the port is the same as a single port.
+	tuple = api.PortProtocol{Port: "80", EndPort: 80, Protocol: api.ProtoTCP}
+	c.Assert(l4PolicyMapKey(tuple, tuple.Protocol), Equals, "80/TCP")
+
+	policy := L4PolicyMap{
+		"30000-30009/TCP": CreateL4IngressFilter([]api.EndpointSelector{fooSelector}, nil, portrule,
+			portrule.Ports[0], api.ProtoTCP, nil),
+	}
+	fooLabels := labels.ParseSelectLabelArray("foo")
+	c.Assert(policy.containsAllL3L4(fooLabels, []*models.Port{
+		{Port: 30005, Protocol: models.PortProtocolTCP},
+	}), Equals, api.Allowed)
+	c.Assert(policy.containsAllL3L4(fooLabels, []*models.Port{
+		{Port: 30000, EndPort: 30009, Protocol: models.PortProtocolANY},
+	}), Equals, api.Allowed)
+	c.Assert(policy.containsAllL3L4(fooLabels, []*models.Port{
+		{Port: 30005, EndPort: 30010, Protocol: models.PortProtocolTCP},
+	}), Equals, api.Denied)
+	c.Assert(policy.containsAllL3L4(labels.ParseSelectLabelArray("bar"), []*models.Port{
+		{Port: 30005, Protocol: models.PortProtocolTCP},
+	}), Equals, api.Denied)
+	c.Assert(policy.containsAnyL3L4(fooLabels, []*models.Port{
+		{Port: 29990, EndPort: 30000, Protocol: models.PortProtocolTCP},
+	}), Equals, api.Denied)
+	c.Assert(policy.containsAnyL3L4(fooLabels, []*models.Port{
+		{Port: 30010, Protocol: models.PortProtocolTCP},
+	}), Equals, api.Undecided)
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/cilium/cilium/pkg/identity/cache"
 	"github.com/cilium/cilium/pkg/option"
 	"github.com/cilium/cilium/pkg/policy/trafficdirection"
+	"github.com/cilium/cilium/pkg/u8proto"
 )
 
 var (
@@ -45,8 +46,13 @@ type Key struct {
 	// Identity is the numeric identity to / from which traffic is allowed.
 	Identity uint32
 	// DestPort is the port at L4 to / from which traffic is allowed, in
-	// host-byte order.
+	// host-byte order. Port 0 with a non-zero Nexthdr matches all ports of
+	// the protocol.
 	DestPort uint16
+	// DestPortWildcard is the number of low-order bits of DestPort which
+	// are ignored, so that the key matches a block of 1 << DestPortWildcard
+	// ports starting at DestPort.
+	DestPortWildcard uint8
 	// NextHdr is the protocol which is allowed.
 	Nexthdr uint8
 	// TrafficDirection indicates in which direction Identity is allowed
@@ -61,6 +67,10 @@ type MapStateEntry struct {
 	// If 0 (default), there is no proxy redirection for the corresponding
 	// Key.
 	ProxyPort uint16
+
+	// IsDeny is true when the policy should be denying the traffic
+	// specified in this entry.
+	IsDeny bool
 }
 
 // DetermineAllowFromWorld determines whether world should be allowed to
@@ -103,3 +113,106 @@ func (keys MapState) AllowAllIdentities(identityCache cache.IdentityCache, direc
 		keys[keyToAdd] = MapStateEntry{}
 	}
 }
+
+// DenyKey inserts a deny entry for the given key into keys, overriding any
+// allow entry for the same key. A key without port and protocol denies all
+// traffic for the identity in the given direction, a key with port 0 denies
+// all ports of its protocol, and a key with a port wildcard denies a block of
+// ports. All other entries covered by the key are removed, as the datapath
+// would otherwise match the more specific entries first. Once the key is
+// covered by another deny entry, it is redundant and is not inserted.
+func (keys MapState) DenyKey(key Key) {
+	if keys.Denies(key) {
+		return
+	}
+
+	if key.DestPort == 0 || key.DestPortWildcard != 0 {
+		for k := range keys {
+			if key.covers(k) {
+				delete(keys, k)
+			}
+		}
+	}
+
+	keys[key] = MapStateEntry{IsDeny: true}
+}
+
+// Denies returns true if keys contains a deny entry for key or for any key
+// covering it, i.e. the L3 key of its identity, the key for all ports of its
+// protocol, or the key of a port block containing its port.
+func (keys MapState) Denies(key Key) bool {
+	for _, k := range key.coveringKeys() {
+		if entry, ok := keys[k]; ok && entry.IsDeny {
+			return true
+		}
+	}
+	return false
+}
+
+// coveringKeys returns key and all keys matching a superset of the traffic
+// matched by key.
+func (key Key) coveringKeys() []Key {
+	l3Key := Key{
+		Identity:         key.Identity,
+		TrafficDirection: key.TrafficDirection,
+	}
+	if key == l3Key {
+		return []Key{key}
+	}
+	covering := []Key{key, l3Key}
+	if isICMPKey(key) || key.DestPort == 0 {
+		return covering
+	}
+
+	allPortsKey := l3Key
+	allPortsKey.Nexthdr = key.Nexthdr
+	covering = append(covering, allPortsKey)
+	for wildcard := key.DestPortWildcard + portBlockBits; wildcard < 16; wildcard += portBlockBits {
+		blockKey := key
+		blockKey.DestPort &^= 1<<wildcard - 1
+		blockKey.DestPortWildcard = wildcard
+		covering = append(covering, blockKey)
+	}
+	return covering
+}
+
+// covers returns true if key matches a superset of the traffic matched by
+// other.
+func (key Key) covers(other Key) bool {
+	for _, k := range other.coveringKeys() {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// isICMPKey returns true if key matches ICMP or ICMPv6 traffic, for which
+// the port carries the ICMP type and code. Port 0 is a regular ICMP type and
+// code in this case rather than a wildcard.
+func isICMPKey(key Key) bool {
+	return key.Nexthdr == uint8(u8proto.ICMP) || key.Nexthdr == uint8(u8proto.ICMPv6)
+}
+
+// allows returns true if keys contains an allow entry for key.
+func (keys MapState) allows(key Key) bool {
+	entry, ok := keys[key]
+	return ok && !entry.IsDeny
+}
+
+// DiffAllowed compares the allow entries of keys with the allow entries of
+// newKeys. It returns the keys which are allowed in newKeys but not in keys,
+// and the keys which are allowed in keys but no longer in newKeys.
+func (keys MapState) DiffAllowed(newKeys MapState) (allowed, denied []Key) {
+	for key := range newKeys {
+		if newKeys.allows(key) && !keys.allows(key) {
+			allowed = append(allowed, key)
+		}
+	}
+	for key := range keys {
+		if keys.allows(key) && !newKeys.allows(key) {
+			denied = append(denied, key)
+		}
+	}
+	return
+}
@@ -60,13 +60,18 @@ type traceState struct {
 	// unsatisfied
 	constrainedRules int
 
+	// deniedRules is the number of deny rules that have denied traffic
+	deniedRules int
+
 	// ruleID is the rule ID currently being evaluated
 	ruleID int
 }
 
 func (state *traceState) trace(rules ruleSlice, ctx *SearchContext) {
 	ctx.PolicyTrace("%d/%d rules selected\n", state.selectedRules, len(rules))
-	if state.constrainedRules > 0 {
+	if state.deniedRules > 0 {
+		ctx.PolicyTrace("Found deny rule\n")
+	} else if state.constrainedRules > 0 {
 		ctx.PolicyTrace("Found unsatisfied FromRequires constraint\n")
 	} else if state.matchedRules > 0 {
 		ctx.PolicyTrace("Found allow rule\n")
@@ -103,10 +108,13 @@ func (p *Repository) AllowsIngressLabelAccess(ctx *SearchContext) api.Decision {
 	return decision
 }
 
-func wildcardL3L4Rule(proto api.L4Proto, port int, endpoints api.EndpointSelectorSlice,
+func wildcardL3L4Rule(proto api.L4Proto, port, endPort int, endpoints api.EndpointSelectorSlice,
 	ruleLabels labels.LabelArray, l4Policy L4PolicyMap) {
+	if endPort < port {
+		endPort = port
+	}
 	for k, filter := range l4Policy {
-		if proto != filter.Protocol || (port != 0 && port != filter.Port) {
+		if proto != filter.Protocol || (port != 0 && (filter.Port < port || filter.Port > endPort)) {
 			continue
 		}
 		switch filter.L7Parser {
@@ -137,6 +145,20 @@ func wildcardL3L4Rule(proto api.L4Proto, port int, endpoints api.EndpointSelecto
 					DNS: []api.PortRuleDNS{rule},
 				}
 			}
+		case ParserTypeCassandra:
+			// Wildcard at L7 all the endpoints allowed at L3 or L4.
+			for _, sel := range endpoints {
+				filter.L7RulesPerEp[sel] = api.L7Rules{
+					Cassandra: []api.PortRuleCassandra{{}},
+				}
+			}
+		case ParserTypeMemcached:
+			// Wildcard at L7 all the endpoints allowed at L3 or L4.
+			for _, sel := range endpoints {
+				filter.L7RulesPerEp[sel] = api.L7Rules{
+					Memcached: []api.PortRuleMemcached{{}},
+				}
+			}
 		default:
 			// Wildcard at L7 all the endpoints allowed at L3 or L4.
 			for _, sel := range endpoints {
@@ -200,6 +222,30 @@ func (p *Repository) ResolveCIDRPolicy(ctx *SearchContext) *CIDRPolicy {
 	return p.rules.resolveCIDRPolicy(ctx)
 }
 
+func (p *Repository) deniesL4Egress(ctx *SearchContext) api.Decision {
+	result, err := p.rules.resolveL4EgressPolicy(ctx, p.revision)
+	if err != nil {
+		log.WithError(err).Warn("Evaluation error while resolving L4 egress deny policy")
+		return api.Undecided
+	}
+
+	verdict := result.EgressDeny.EgressDeniesContext(ctx)
+	ctx.PolicyTrace("L4 egress deny verdict: %s", verdict.String())
+	return verdict
+}
+
+func (p *Repository) deniesL4Ingress(ctx *SearchContext) api.Decision {
+	result, err := p.rules.resolveL4IngressPolicy(ctx, p.revision)
+	if err != nil {
+		log.WithError(err).Warn("Evaluation error while resolving L4 ingress deny policy")
+		return api.Undecided
+	}
+
+	verdict := result.IngressDeny.IngressDeniesContext(ctx)
+	ctx.PolicyTrace("L4 ingress deny verdict: %s", verdict.String())
+	return verdict
+}
+
 func (p *Repository) allowsL4Egress(ctx *SearchContext) api.Decision {
 	egressL4Policy, err := p.ResolveL4EgressPolicy(ctx)
 	if err != nil {
@@ -246,6 +292,15 @@ func (p *Repository) AllowsIngressRLocked(ctx *SearchContext) api.Decision {
 	ctx.PolicyTrace("Tracing %s\n", ctx.String())
 	decision := p.CanReachIngressRLocked(ctx)
 	ctx.PolicyTrace("Label verdict: %s", decision.String())
+
+	// Deny rules take precedence over allow rules at L3 and L4.
+	if decision == api.Denied {
+		return decision
+	}
+	if len(ctx.DPorts) != 0 && p.rules.hasIngressDeny() && p.deniesL4Ingress(ctx) == api.Denied {
+		return api.Denied
+	}
+
 	if decision == api.Allowed {
 		ctx.PolicyTrace("L4 ingress policies skipped")
 		return decision
@@ -272,6 +327,14 @@ func (p *Repository) AllowsEgressRLocked(egressCtx *SearchContext) api.Decision
 	egressDecision := p.CanReachEgressRLocked(egressCtx)
 	egressCtx.PolicyTrace("Egress label verdict: %s", egressDecision.String())
 
+	// Deny rules take precedence over allow rules at L3 and L4.
+	if egressDecision == api.Denied {
+		return egressDecision
+	}
+	if len(egressCtx.DPorts) != 0 && p.rules.hasEgressDeny() && p.deniesL4Egress(egressCtx) == api.Denied {
+		return api.Denied
+	}
+
 	if egressDecision == api.Allowed {
 		egressCtx.PolicyTrace("L4 egress policies skipped")
 		return egressDecision
@@ -379,6 +442,28 @@ func (p *Repository) AddListLocked(rules api.Rules) uint64 {
 	return p.revision
 }
 
+// CopyWithRulesRLocked returns a new repository at the same revision as p
+// which contains a copy of all rules in p followed by the given rules. This
+// allows to compute the policy which would result from adding rules without
+// modifying p. Policy metrics are not updated. Expects that the rule list has
+// already been sanitized.
+//
+// Must be called with p.Mutex held for reading.
+func (p *Repository) CopyWithRulesRLocked(rules api.Rules) *Repository {
+	newList := make(ruleSlice, 0, len(p.rules)+len(rules))
+	for _, r := range p.rules {
+		newList = append(newList, &rule{Rule: *r.Rule.DeepCopy()})
+	}
+	for i := range rules {
+		newList = append(newList, &rule{Rule: *rules[i].DeepCopy()})
+	}
+
+	return &Repository{
+		rules:    newList,
+		revision: p.revision,
+	}
+}
+
 // AddList inserts a rule into the policy repository.
 func (p *Repository) AddList(rules api.Rules) uint64 {
 	p.Mutex.Lock()
@@ -450,12 +535,12 @@ func (p *Repository) GetRulesMatching(labels labels.LabelArray) (ingressMatch bo
 	ingressMatch = false
 	egressMatch = false
 	for _, r := range p.rules {
-		rulesMatch := r.EndpointSelector.Matches(labels)
+		rulesMatch := r.matches(labels)
 		if rulesMatch {
-			if len(r.Ingress) > 0 {
+			if len(r.Ingress) > 0 || len(r.IngressDeny) > 0 {
 				ingressMatch = true
 			}
-			if len(r.Egress) > 0 {
+			if len(r.Egress) > 0 || len(r.EgressDeny) > 0 {
 				egressMatch = true
 			}
 		}
@@ -477,12 +562,12 @@ func (p *Repository) getMatchingRules(labels labels.LabelArray) (ingressMatch bo
 	ingressMatch = false
 	egressMatch = false
 	for _, r := range p.rules {
-		rulesMatch := r.EndpointSelector.Matches(labels)
+		rulesMatch := r.matches(labels)
 		if rulesMatch {
-			if len(r.Ingress) > 0 {
+			if len(r.Ingress) > 0 || len(r.IngressDeny) > 0 {
 				ingressMatch = true
 			}
-			if len(r.Egress) > 0 {
+			if len(r.Egress) > 0 || len(r.EgressDeny) > 0 {
 				egressMatch = true
 			}
 			matchingRules = append(matchingRules, r)
@@ -611,10 +696,10 @@ func (p *Repository) ResolvePolicy(id uint16, labels labels.LabelArray, policyOw
 
 		calculatedPolicy.CIDRPolicy.Ingress = newCIDRIngressPolicy.Ingress
 		calculatedPolicy.L4Policy.Ingress = newL4IngressPolicy.Ingress
+		calculatedPolicy.L4Policy.IngressDeny = newL4IngressPolicy.IngressDeny
 
 		for identity, labels := range identityCache {
 			ingressCtx.From = labels
-			egressCtx.To = labels
 
 			ingressAccess := matchingRules.canReachIngressRLocked(&ingressCtx)
 			if ingressAccess == api.Allowed {
@@ -644,6 +729,7 @@ func (p *Repository) ResolvePolicy(id uint16, labels labels.LabelArray, policyOw
 
 		calculatedPolicy.CIDRPolicy.Egress = newCIDREgressPolicy.Egress
 		calculatedPolicy.L4Policy.Egress = newL4EgressPolicy.Egress
+		calculatedPolicy.L4Policy.EgressDeny = newL4EgressPolicy.EgressDeny
 
 		for identity, labels := range identityCache {
 			egressCtx.To = labels
@@ -664,10 +750,19 @@ func (p *Repository) ResolvePolicy(id uint16, labels labels.LabelArray, policyOw
 		calculatedPolicy.PolicyMapState.AllowAllIdentities(identityCache, trafficdirection.Egress)
 	}
 
+	if visibility := policyOwner.GetVisibilityPolicy(); visibility != nil {
+		matchingRules.addVisibilityFilters(&ingressCtx, true, ingressEnabled, visibility.Ingress, calculatedPolicy.L4Policy.Ingress)
+		matchingRules.addVisibilityFilters(&egressCtx, false, egressEnabled, visibility.Egress, calculatedPolicy.L4Policy.Egress)
+	}
+
 	calculatedPolicy.computeDesiredL4PolicyMapEntries(identityCache)
 	calculatedPolicy.PolicyMapState.DetermineAllowLocalhost(calculatedPolicy.L4Policy)
 	calculatedPolicy.PolicyMapState.DetermineAllowFromWorld()
 
+	// Deny entries are computed last so that they take precedence over all
+	// of the allow entries computed above.
+	calculatedPolicy.computeDesiredL4PolicyMapDenyEntries(identityCache)
+
 	return calculatedPolicy, nil
 }
 
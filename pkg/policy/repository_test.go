@@ -23,10 +23,13 @@ import (
 
 	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/pkg/checker"
+	"github.com/cilium/cilium/pkg/identity"
+	"github.com/cilium/cilium/pkg/identity/cache"
 	k8sConst "github.com/cilium/cilium/pkg/k8s/apis/cilium.io"
 	"github.com/cilium/cilium/pkg/labels"
 	"github.com/cilium/cilium/pkg/option"
 	"github.com/cilium/cilium/pkg/policy/api"
+	"github.com/cilium/cilium/pkg/policy/trafficdirection"
 
 	"github.com/op/go-logging"
 	. "gopkg.in/check.v1"
@@ -240,6 +243,56 @@ func (ds *PolicyTestSuite) TestComputePolicyEnforcementAndRules(c *C) {
 
 }
 
+func (ds *PolicyTestSuite) TestGetRulesMatchingNodeSelector(c *C) {
+	repo := NewPolicyRepository()
+
+	workerSelectLabel := labels.ParseSelectLabel("role=worker")
+	hostLabelArray := labels.LabelArray{
+		labels.ParseLabel("reserved:host"),
+		labels.ParseLabel("k8s:role=worker"),
+	}
+	podLabelArray := labels.LabelArray{labels.ParseLabel("k8s:role=worker")}
+
+	nodeRule := api.Rule{
+		NodeSelector: api.NewESFromLabels(workerSelectLabel),
+		Ingress: []api.IngressRule{
+			{
+				FromEntities: []api.Entity{api.EntityCluster},
+			},
+		},
+	}
+	_, err := repo.Add(nodeRule)
+	c.Assert(err, IsNil)
+
+	// Rules with a NodeSelector only select the host endpoint.
+	ing, egr := repo.GetRulesMatching(hostLabelArray)
+	c.Assert(ing, Equals, true)
+	c.Assert(egr, Equals, false)
+	ing, egr = repo.GetRulesMatching(podLabelArray)
+	c.Assert(ing, Equals, false)
+	c.Assert(egr, Equals, false)
+
+	// Rules with an EndpointSelector never select the host endpoint, not
+	// even if the selector is a wildcard.
+	endpointRule := api.Rule{
+		EndpointSelector: api.WildcardEndpointSelector,
+		Egress: []api.EgressRule{
+			{
+				ToEntities: []api.Entity{api.EntityCluster},
+			},
+		},
+	}
+	_, err = repo.Add(endpointRule)
+	c.Assert(err, IsNil)
+
+	ing, egr = repo.GetRulesMatching(hostLabelArray)
+	c.Assert(ing, Equals, true)
+	c.Assert(egr, Equals, false)
+	ing, egr = repo.GetRulesMatching(podLabelArray)
+	c.Assert(ing, Equals, false)
+	c.Assert(egr, Equals, true)
+}
+
 func (ds *PolicyTestSuite) TestAddSearchDelete(c *C) {
 	repo := NewPolicyRepository()
 
@@ -535,6 +588,375 @@ func (ds *PolicyTestSuite) TestCanReachIngress(c *C) {
 	}), Equals, api.Denied)
 }
 
+func (ds *PolicyTestSuite) TestCanReachIngressDeny(c *C) {
+	repo := NewPolicyRepository()
+
+	fooSelector := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	barSelector := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+	bazSelector := api.NewESFromLabels(labels.ParseSelectLabel("baz"))
+
+	// bar allows everything from foo and baz, but denies all traffic from
+	// baz and port 80 from foo.
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: barSelector,
+		Ingress: []api.IngressRule{
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector, bazSelector},
+			},
+		},
+		IngressDeny: []api.IngressDenyRule{
+			{
+				FromEndpoints: []api.EndpointSelector{bazSelector},
+			},
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortDenyRule{{
+					Ports: []api.PortProtocol{{Port: "80", Protocol: api.ProtoTCP}},
+				}},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	repo.Mutex.RLock()
+	defer repo.Mutex.RUnlock()
+
+	// baz=>bar is denied at L3 even though it is allowed
+	c.Assert(repo.CanReachIngressRLocked(&SearchContext{
+		From: labels.ParseSelectLabelArray("baz"),
+		To:   labels.ParseSelectLabelArray("bar"),
+	}), Equals, api.Denied)
+
+	// foo=>bar is allowed at L3
+	c.Assert(repo.AllowsIngressRLocked(&SearchContext{
+		From: labels.ParseSelectLabelArray("foo"),
+		To:   labels.ParseSelectLabelArray("bar"),
+	}), Equals, api.Allowed)
+
+	// foo=>bar:80/TCP is denied at L4
+	c.Assert(repo.AllowsIngressRLocked(&SearchContext{
+		From:   labels.ParseSelectLabelArray("foo"),
+		To:     labels.ParseSelectLabelArray("bar"),
+		DPorts: []*models.Port{{Port: 80, Protocol: models.PortProtocolTCP}},
+	}), Equals, api.Denied)
+
+	// foo=>bar:8080/TCP is allowed
+	c.Assert(repo.AllowsIngressRLocked(&SearchContext{
+		From:   labels.ParseSelectLabelArray("foo"),
+		To:     labels.ParseSelectLabelArray("bar"),
+		DPorts: []*models.Port{{Port: 8080, Protocol: models.PortProtocolTCP}},
+	}), Equals, api.Allowed)
+}
+
+func (ds *PolicyTestSuite) TestResolvePolicyDeny(c *C) {
+	repo := NewPolicyRepository()
+
+	fooSelector := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	barSelector := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+	bazSelector := api.NewESFromLabels(labels.ParseSelectLabel("baz"))
+
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: barSelector,
+		Ingress: []api.IngressRule{
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector, bazSelector},
+			},
+		},
+		IngressDeny: []api.IngressDenyRule{
+			{
+				FromEndpoints: []api.EndpointSelector{bazSelector},
+			},
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortDenyRule{{
+					Ports: []api.PortProtocol{{Port: "80", Protocol: api.ProtoTCP}},
+				}},
+			},
+		},
+		Egress: []api.EgressRule{
+			{
+				ToEndpoints: []api.EndpointSelector{fooSelector},
+			},
+		},
+		EgressDeny: []api.EgressDenyRule{
+			{
+				ToEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortDenyRule{{
+					Ports: []api.PortProtocol{{Port: "53", Protocol: api.ProtoAny}},
+				}},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	fooIdentity := identity.NumericIdentity(1000)
+	bazIdentity := identity.NumericIdentity(1001)
+	idCache := cache.IdentityCache{
+		fooIdentity: labels.ParseSelectLabelArray("foo"),
+		bazIdentity: labels.ParseSelectLabelArray("baz"),
+	}
+
+	repo.Mutex.RLock()
+	policy, err := repo.ResolvePolicy(1, labels.ParseSelectLabelArray("bar"), DummyOwner{}, idCache)
+	repo.Mutex.RUnlock()
+	c.Assert(err, IsNil)
+
+	ingress := trafficdirection.Ingress.Uint8()
+	egress := trafficdirection.Egress.Uint8()
+
+	// foo is allowed at L3, but denied on port 80/TCP
+	c.Assert(policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), TrafficDirection: ingress}], Equals, MapStateEntry{})
+	c.Assert(policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), DestPort: 80, Nexthdr: 6, TrafficDirection: ingress}], Equals, MapStateEntry{IsDeny: true})
+
+	// baz is denied at L3, and no allow entries remain for it
+	for key, entry := range policy.PolicyMapState {
+		if key.Identity == bazIdentity.Uint32() && key.TrafficDirection == ingress {
+			c.Assert(key, Equals, Key{Identity: bazIdentity.Uint32(), TrafficDirection: ingress})
+			c.Assert(entry, Equals, MapStateEntry{IsDeny: true})
+		}
+	}
+	_, ok := policy.PolicyMapState[Key{Identity: bazIdentity.Uint32(), TrafficDirection: ingress}]
+	c.Assert(ok, Equals, true)
+
+	// foo is allowed at egress, except for port 53 on any protocol
+	c.Assert(policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), TrafficDirection: egress}], Equals, MapStateEntry{})
+	c.Assert(policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), DestPort: 53, Nexthdr: 6, TrafficDirection: egress}], Equals, MapStateEntry{IsDeny: true})
+	c.Assert(policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), DestPort: 53, Nexthdr: 17, TrafficDirection: egress}], Equals, MapStateEntry{IsDeny: true})
+}
+
+func (ds *PolicyTestSuite) TestResolveL4EgressDenyPeer(c *C) {
+	repo := NewPolicyRepository()
+
+	fooSelector := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	barSelector := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: barSelector,
+		EgressDeny: []api.EgressDenyRule{
+			{
+				ToEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortDenyRule{{
+					Ports: []api.PortProtocol{{Port: "53", Protocol: api.ProtoUDP}},
+				}},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	repo.Mutex.RLock()
+	defer repo.Mutex.RUnlock()
+
+	// Deny rules towards other peers must not be part of the result
+	toBaz, err := repo.rules.resolveL4EgressPolicy(&SearchContext{
+		From: labels.ParseSelectLabelArray("bar"),
+		To:   labels.ParseSelectLabelArray("baz"),
+	}, repo.GetRevision())
+	c.Assert(err, IsNil)
+	c.Assert(toBaz.EgressDeny, HasLen, 0)
+
+	toFoo, err := repo.rules.resolveL4EgressPolicy(&SearchContext{
+		From: labels.ParseSelectLabelArray("bar"),
+		To:   labels.ParseSelectLabelArray("foo"),
+	}, repo.GetRevision())
+	c.Assert(err, IsNil)
+	c.Assert(toFoo.EgressDeny, HasLen, 1)
+}
+
+func (ds *PolicyTestSuite) TestResolvePolicyWidePortRange(c *C) {
+	repo := NewPolicyRepository()
+
+	fooSelector := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	barSelector := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: barSelector,
+		Ingress: []api.IngressRule{
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortRule{{
+					Ports: []api.PortProtocol{{Port: "1", EndPort: 65535, Protocol: api.ProtoTCP}},
+				}},
+			},
+		},
+		IngressDeny: []api.IngressDenyRule{
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortDenyRule{{
+					Ports: []api.PortProtocol{{Port: "22", Protocol: api.ProtoTCP}},
+				}},
+			},
+		},
+		Egress: []api.EgressRule{
+			{
+				ToEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortRule{{
+					Ports: []api.PortProtocol{{Port: "53", Protocol: api.ProtoUDP}},
+				}},
+			},
+		},
+		EgressDeny: []api.EgressDenyRule{
+			{
+				ToEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortDenyRule{{
+					Ports: []api.PortProtocol{{Port: "1", EndPort: 65535, Protocol: api.ProtoUDP}},
+				}},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	idCache := cache.IdentityCache{}
+	for i := 0; i < 16; i++ {
+		idCache[identity.NumericIdentity(1000+i)] = labels.ParseSelectLabelArray("foo")
+	}
+
+	repo.Mutex.RLock()
+	policy, err := repo.ResolvePolicy(1, labels.ParseSelectLabelArray("bar"), DummyOwner{}, idCache)
+	repo.Mutex.RUnlock()
+	c.Assert(err, IsNil)
+
+	ingress := trafficdirection.Ingress.Uint8()
+	egress := trafficdirection.Egress.Uint8()
+
+	// Per identity: the all ports allow and port 22 deny at ingress, and
+	// the all ports deny which replaces the port 53 allow at egress.
+	c.Assert(policy.PolicyMapState, HasLen, 3*len(idCache))
+	for id := range idCache {
+		c.Assert(policy.PolicyMapState[Key{Identity: id.Uint32(), Nexthdr: 6, TrafficDirection: ingress}], Equals, MapStateEntry{})
+		c.Assert(policy.PolicyMapState[Key{Identity: id.Uint32(), DestPort: 22, Nexthdr: 6, TrafficDirection: ingress}], Equals, MapStateEntry{IsDeny: true})
+		c.Assert(policy.PolicyMapState[Key{Identity: id.Uint32(), Nexthdr: 17, TrafficDirection: egress}], Equals, MapStateEntry{IsDeny: true})
+	}
+}
+
+func (ds *PolicyTestSuite) TestResolvePolicyNodePortRange(c *C) {
+	repo := NewPolicyRepository()
+
+	fooSelector := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	barSelector := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: barSelector,
+		Ingress: []api.IngressRule{
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortRule{{
+					Ports: []api.PortProtocol{
+						{Port: "30000", EndPort: 32767, Protocol: api.ProtoTCP},
+						{Port: "32001", Protocol: api.ProtoTCP},
+					},
+				}},
+			},
+		},
+		IngressDeny: []api.IngressDenyRule{
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortDenyRule{{
+					Ports: []api.PortProtocol{
+						{Port: "30005", Protocol: api.ProtoTCP},
+						{Port: "32000", EndPort: 32255, Protocol: api.ProtoTCP},
+					},
+				}},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	idCache := cache.IdentityCache{
+		identity.NumericIdentity(1000): labels.ParseSelectLabelArray("foo"),
+	}
+
+	repo.Mutex.RLock()
+	policy, err := repo.ResolvePolicy(1, labels.ParseSelectLabelArray("bar"), DummyOwner{}, idCache)
+	repo.Mutex.RUnlock()
+	c.Assert(err, IsNil)
+
+	ingress := trafficdirection.Ingress.Uint8()
+	blockKey := func(port uint16, wildcard uint8) Key {
+		return Key{Identity: 1000, DestPort: port, DestPortWildcard: wildcard, Nexthdr: 6, TrafficDirection: ingress}
+	}
+
+	// The range is allowed as 23 blocks of ports. Port 30005 is denied
+	// within the first block, and the deny of 32000-32255 replaces the
+	// block allowing it, as well as the allow of port 32001 it covers.
+	ingressKeys := 0
+	for key := range policy.PolicyMapState {
+		if key.TrafficDirection == ingress {
+			ingressKeys++
+		}
+	}
+	c.Assert(ingressKeys, Equals, 24)
+	c.Assert(policy.PolicyMapState[blockKey(30000, 4)], Equals, MapStateEntry{})
+	c.Assert(policy.PolicyMapState[blockKey(30005, 0)], Equals, MapStateEntry{IsDeny: true})
+	c.Assert(policy.PolicyMapState[blockKey(32000, 8)], Equals, MapStateEntry{IsDeny: true})
+	c.Assert(policy.PolicyMapState[blockKey(32512, 8)], Equals, MapStateEntry{})
+	_, ok := policy.PolicyMapState[blockKey(32001, 0)]
+	c.Assert(ok, Equals, false)
+	c.Assert(policy.PolicyMapState.Denies(blockKey(32001, 0)), Equals, true)
+	c.Assert(policy.PolicyMapState.Denies(blockKey(32256, 0)), Equals, false)
+}
+
+func (ds *PolicyTestSuite) TestResolvePolicyICMP(c *C) {
+	repo := NewPolicyRepository()
+
+	fooSelector := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	barSelector := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+
+	unreachableCode := uint8(4)
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: barSelector,
+		Ingress: []api.IngressRule{
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+				ICMPs: []api.ICMPRule{{
+					Fields: []api.ICMPField{{Type: 8}},
+				}},
+			},
+		},
+		Egress: []api.EgressRule{
+			{
+				ToEndpoints: []api.EndpointSelector{fooSelector},
+				ICMPs: []api.ICMPRule{{
+					Fields: []api.ICMPField{
+						{Family: api.IPv6Family, Type: 135},
+						{Type: 3, Code: &unreachableCode},
+					},
+				}},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	fooIdentity := identity.NumericIdentity(1000)
+	idCache := cache.IdentityCache{
+		fooIdentity: labels.ParseSelectLabelArray("foo"),
+	}
+
+	repo.Mutex.RLock()
+	policy, err := repo.ResolvePolicy(1, labels.ParseSelectLabelArray("bar"), DummyOwner{}, idCache)
+	repo.Mutex.RUnlock()
+	c.Assert(err, IsNil)
+
+	ingress := trafficdirection.Ingress.Uint8()
+	egress := trafficdirection.Egress.Uint8()
+
+	// ICMP rules do not allow traffic at L3
+	_, ok := policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), TrafficDirection: ingress}]
+	c.Assert(ok, Equals, false)
+
+	// Echo requests with any code
+	_, ok = policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), DestPort: 8<<8 | api.ICMPCodeAny, Nexthdr: 1, TrafficDirection: ingress}]
+	c.Assert(ok, Equals, true)
+
+	// Neighbor solicitations with any code
+	_, ok = policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), DestPort: 135<<8 | api.ICMPCodeAny, Nexthdr: 58, TrafficDirection: egress}]
+	c.Assert(ok, Equals, true)
+
+	// Port unreachable only with the given code
+	_, ok = policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), DestPort: 3<<8 | 4, Nexthdr: 1, TrafficDirection: egress}]
+	c.Assert(ok, Equals, true)
+	c.Assert(len(policy.PolicyMapState), Equals, 3)
+}
+
 func (ds *PolicyTestSuite) TestCanReachEgress(c *C) {
 	repo := NewPolicyRepository()
 
@@ -1931,3 +2353,64 @@ Label verdict: undecided
 	repo.Mutex.RUnlock()
 	c.Assert(verdict, Equals, api.Allowed)
 }
+
+func (ds *PolicyTestSuite) TestCopyWithRulesRLocked(c *C) {
+	repo := NewPolicyRepository()
+
+	fooSelector := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	barSelector := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+	bazSelector := api.NewESFromLabels(labels.ParseSelectLabel("baz"))
+
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: barSelector,
+		Ingress: []api.IngressRule{
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	candidate := api.Rules{
+		{
+			EndpointSelector: barSelector,
+			Ingress: []api.IngressRule{
+				{
+					FromEndpoints: []api.EndpointSelector{bazSelector},
+				},
+			},
+			IngressDeny: []api.IngressDenyRule{
+				{
+					FromEndpoints: []api.EndpointSelector{fooSelector},
+				},
+			},
+		},
+	}
+	c.Assert(candidate[0].Sanitize(), IsNil)
+
+	fooIdentity := identity.NumericIdentity(1000)
+	bazIdentity := identity.NumericIdentity(1001)
+	idCache := cache.IdentityCache{
+		fooIdentity: labels.ParseSelectLabelArray("foo"),
+		bazIdentity: labels.ParseSelectLabelArray("baz"),
+	}
+
+	repo.Mutex.RLock()
+	preview := repo.CopyWithRulesRLocked(candidate)
+	current, err := repo.ResolvePolicy(1, labels.ParseSelectLabelArray("bar"), DummyOwner{}, idCache)
+	repo.Mutex.RUnlock()
+	c.Assert(err, IsNil)
+
+	// The original repository must not be modified
+	c.Assert(repo.NumRules(), Equals, 1)
+	c.Assert(preview.NumRules(), Equals, 2)
+	c.Assert(preview.GetRevision(), Equals, repo.GetRevision())
+
+	desired, err := preview.ResolvePolicy(1, labels.ParseSelectLabelArray("bar"), DummyOwner{}, idCache)
+	c.Assert(err, IsNil)
+
+	ingress := trafficdirection.Ingress.Uint8()
+	allowed, denied := current.PolicyMapState.DiffAllowed(desired.PolicyMapState)
+	c.Assert(allowed, DeepEquals, []Key{{Identity: bazIdentity.Uint32(), TrafficDirection: ingress}})
+	c.Assert(denied, DeepEquals, []Key{{Identity: fooIdentity.Uint32(), TrafficDirection: ingress}})
+}
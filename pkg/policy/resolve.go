@@ -69,6 +69,10 @@ type EndpointPolicy struct {
 // PolicyOwner is anything which consumes a EndpointPolicy.
 type PolicyOwner interface {
 	LookupRedirectPort(l4 *L4Filter) uint16
+
+	// GetVisibilityPolicy returns the ports for which L7 visibility is
+	// requested, or nil if none.
+	GetVisibilityPolicy() *VisibilityPolicy
 }
 
 func getSecurityIdentities(labelsMap cache.IdentityCache, selector *api.EndpointSelector) []identity.NumericIdentity {
@@ -120,6 +124,27 @@ func (p *EndpointPolicy) computeDirectionL4PolicyMapEntries(identityCache cache.
 	}
 }
 
+// computeDesiredL4PolicyMapDenyEntries inserts deny entries into the
+// PolicyMapState for all deny filters in the L4Policy. Deny entries override
+// any allow entries for the same keys, so this must be called after all
+// allow entries have been computed.
+func (p *EndpointPolicy) computeDesiredL4PolicyMapDenyEntries(identityCache cache.IdentityCache) {
+
+	if p.L4Policy == nil {
+		return
+	}
+	p.computeDirectionL4PolicyMapDenyEntries(identityCache, p.L4Policy.IngressDeny, trafficdirection.Ingress)
+	p.computeDirectionL4PolicyMapDenyEntries(identityCache, p.L4Policy.EgressDeny, trafficdirection.Egress)
+}
+
+func (p *EndpointPolicy) computeDirectionL4PolicyMapDenyEntries(identityCache cache.IdentityCache, l4PolicyMap L4PolicyMap, direction trafficdirection.TrafficDirection) {
+	for _, filter := range l4PolicyMap {
+		for _, keyFromFilter := range filter.ToKeys(direction, identityCache, nil) {
+			p.PolicyMapState.DenyKey(keyFromFilter)
+		}
+	}
+}
+
 // Realizes copies the fields from desired into p. It assumes that the fields in
 // desired are not modified after this function is called.
 func (p *EndpointPolicy) Realizes(desired *EndpointPolicy) {
@@ -91,6 +91,10 @@ func (d DummyOwner) LookupRedirectPort(l4 *L4Filter) uint16 {
 	return 0
 }
 
+func (d DummyOwner) GetVisibilityPolicy() *VisibilityPolicy {
+	return nil
+}
+
 func (ds *PolicyTestSuite) BenchmarkRegeneratePolicyRules(c *C) {
 	c.ResetTimer()
 	for i := 0; i < c.N; i++ {
@@ -30,7 +30,28 @@ type rule struct {
 }
 
 func (r *rule) String() string {
-	return fmt.Sprintf("%v", r.EndpointSelector)
+	return fmt.Sprintf("%v", *r.getSelector())
+}
+
+// getSelector returns the selector which selects the subjects of the rule,
+// i.e. the NodeSelector for rules applying to host endpoints and the
+// EndpointSelector otherwise.
+func (r *rule) getSelector() *api.EndpointSelector {
+	if r.NodeSelector.LabelSelector != nil {
+		return &r.NodeSelector
+	}
+	return &r.EndpointSelector
+}
+
+// matches returns true if the rule applies to the endpoint with the given
+// labels. Rules with a NodeSelector only apply to the host endpoint, rules
+// with an EndpointSelector never apply to the host endpoint.
+func (r *rule) matches(lbls labels.LabelArray) bool {
+	isHost := lbls.Has(labels.LabelSourceReservedKeyPrefix + labels.IDNameHost)
+	if r.NodeSelector.LabelSelector != nil {
+		return isHost && r.NodeSelector.Matches(lbls)
+	}
+	return !isHost && r.EndpointSelector.Matches(lbls)
 }
 
 func mergeL4Port(ctx *SearchContext, endpoints []api.EndpointSelector, existingFilter, filterToMerge *L4Filter) error {
@@ -62,8 +83,10 @@ func mergeL4Port(ctx *SearchContext, endpoints []api.EndpointSelector, existingF
 	for hash, newL7Rules := range filterToMerge.L7RulesPerEp {
 		if ep, ok := existingFilter.L7RulesPerEp[hash]; ok {
 			switch {
-			case len(newL7Rules.HTTP) > 0:
-				if len(ep.Kafka) > 0 || len(ep.DNS) > 0 || ep.L7Proto != "" {
+			case len(newL7Rules.HTTP) > 0 || len(newL7Rules.GRPC) > 0:
+				// gRPC rules are enforced as HTTP rules, so they
+				// can be merged with each other.
+				if len(ep.Kafka) > 0 || len(ep.DNS) > 0 || len(ep.Cassandra) > 0 || len(ep.Memcached) > 0 || ep.L7Proto != "" {
 					ctx.PolicyTrace("   Merge conflict: mismatching L7 rule types.\n")
 					return fmt.Errorf("Cannot merge conflicting L7 rule types")
 				}
@@ -73,8 +96,13 @@ func mergeL4Port(ctx *SearchContext, endpoints []api.EndpointSelector, existingF
 						ep.HTTP = append(ep.HTTP, newRule)
 					}
 				}
+				for _, newRule := range newL7Rules.GRPC {
+					if !newRule.Exists(ep) {
+						ep.GRPC = append(ep.GRPC, newRule)
+					}
+				}
 			case len(newL7Rules.Kafka) > 0:
-				if len(ep.HTTP) > 0 || len(ep.DNS) > 0 || ep.L7Proto != "" {
+				if len(ep.HTTP) > 0 || len(ep.GRPC) > 0 || len(ep.DNS) > 0 || len(ep.Cassandra) > 0 || len(ep.Memcached) > 0 || ep.L7Proto != "" {
 					ctx.PolicyTrace("   Merge conflict: mismatching L7 rule types.\n")
 					return fmt.Errorf("Cannot merge conflicting L7 rule types")
 				}
@@ -84,8 +112,30 @@ func mergeL4Port(ctx *SearchContext, endpoints []api.EndpointSelector, existingF
 						ep.Kafka = append(ep.Kafka, newRule)
 					}
 				}
+			case len(newL7Rules.Cassandra) > 0:
+				if len(ep.HTTP) > 0 || len(ep.GRPC) > 0 || len(ep.Kafka) > 0 || len(ep.DNS) > 0 || len(ep.Memcached) > 0 || ep.L7Proto != "" {
+					ctx.PolicyTrace("   Merge conflict: mismatching L7 rule types.\n")
+					return fmt.Errorf("Cannot merge conflicting L7 rule types")
+				}
+
+				for _, newRule := range newL7Rules.Cassandra {
+					if !newRule.Exists(ep) {
+						ep.Cassandra = append(ep.Cassandra, newRule)
+					}
+				}
+			case len(newL7Rules.Memcached) > 0:
+				if len(ep.HTTP) > 0 || len(ep.GRPC) > 0 || len(ep.Kafka) > 0 || len(ep.DNS) > 0 || len(ep.Cassandra) > 0 || ep.L7Proto != "" {
+					ctx.PolicyTrace("   Merge conflict: mismatching L7 rule types.\n")
+					return fmt.Errorf("Cannot merge conflicting L7 rule types")
+				}
+
+				for _, newRule := range newL7Rules.Memcached {
+					if !newRule.Exists(ep) {
+						ep.Memcached = append(ep.Memcached, newRule)
+					}
+				}
 			case newL7Rules.L7Proto != "":
-				if len(ep.Kafka) > 0 || len(ep.HTTP) > 0 || len(ep.DNS) > 0 || (ep.L7Proto != "" && ep.L7Proto != newL7Rules.L7Proto) {
+				if len(ep.Kafka) > 0 || len(ep.HTTP) > 0 || len(ep.GRPC) > 0 || len(ep.DNS) > 0 || len(ep.Cassandra) > 0 || len(ep.Memcached) > 0 || (ep.L7Proto != "" && ep.L7Proto != newL7Rules.L7Proto) {
 					ctx.PolicyTrace("   Merge conflict: mismatching L7 rule types.\n")
 					return fmt.Errorf("Cannot merge conflicting L7 rule types")
 				}
@@ -99,7 +149,7 @@ func mergeL4Port(ctx *SearchContext, endpoints []api.EndpointSelector, existingF
 					}
 				}
 			case len(newL7Rules.DNS) > 0:
-				if len(ep.HTTP) > 0 || len(ep.Kafka) > 0 || len(ep.L7) > 0 {
+				if len(ep.HTTP) > 0 || len(ep.GRPC) > 0 || len(ep.Kafka) > 0 || len(ep.Cassandra) > 0 || len(ep.Memcached) > 0 || len(ep.L7) > 0 {
 					ctx.PolicyTrace("   Merge conflict: mismatching L7 rule types.\n")
 					return fmt.Errorf("Cannot merge conflicting L7 rule types")
 				}
@@ -134,7 +184,7 @@ func mergeL4Port(ctx *SearchContext, endpoints []api.EndpointSelector, existingF
 func mergeL4IngressPort(ctx *SearchContext, endpoints []api.EndpointSelector, endpointsWithL3Override []api.EndpointSelector, r api.PortRule, p api.PortProtocol,
 	proto api.L4Proto, ruleLabels labels.LabelArray, resMap L4PolicyMap) (int, error) {
 
-	key := p.Port + "/" + string(proto)
+	key := l4PolicyMapKey(p, proto)
 	existingFilter, ok := resMap[key]
 	if !ok {
 		resMap[key] = CreateL4IngressFilter(endpoints, endpointsWithL3Override, r, p, proto, ruleLabels)
@@ -154,7 +204,7 @@ func mergeL4IngressPort(ctx *SearchContext, endpoints []api.EndpointSelector, en
 }
 
 func mergeL4Ingress(ctx *SearchContext, rule api.IngressRule, ruleLabels labels.LabelArray, resMap L4PolicyMap) (int, error) {
-	if len(rule.ToPorts) == 0 {
+	if len(rule.ToPorts) == 0 && len(rule.ICMPs) == 0 {
 		ctx.PolicyTrace("    No L4 %s rules\n", trafficdirection.Ingress)
 		return 0, nil
 	}
@@ -196,6 +246,15 @@ func mergeL4Ingress(ctx *SearchContext, rule api.IngressRule, ruleLabels labels.
 			for _, l7 := range r.Rules.Kafka {
 				ctx.PolicyTrace("        %+v\n", l7)
 			}
+			for _, l7 := range r.Rules.GRPC {
+				ctx.PolicyTrace("        %+v\n", l7)
+			}
+			for _, l7 := range r.Rules.Cassandra {
+				ctx.PolicyTrace("        %+v\n", l7)
+			}
+			for _, l7 := range r.Rules.Memcached {
+				ctx.PolicyTrace("        %+v\n", l7)
+			}
 			for _, l7 := range r.Rules.L7 {
 				ctx.PolicyTrace("        %+v\n", l7)
 			}
@@ -224,6 +283,18 @@ func mergeL4Ingress(ctx *SearchContext, rule api.IngressRule, ruleLabels labels.
 		}
 	}
 
+	for _, icmp := range rule.ICMPs {
+		for _, f := range icmp.Fields {
+			ctx.PolicyTrace("    Allows %s %s type %d from endpoints %v\n", trafficdirection.Ingress, f.Protocol(), f.Type, fromEndpoints)
+			p := f.PortProtocol()
+			cnt, err := mergeL4IngressPort(ctx, fromEndpoints, endpointsWithL3Override, api.PortRule{}, p, p.Protocol, ruleLabels, resMap)
+			if err != nil {
+				return found, err
+			}
+			found += cnt
+		}
+	}
+
 	return found, nil
 }
 
@@ -239,7 +310,7 @@ func (state *traceState) unSelectRule(ctx *SearchContext, labels labels.LabelArr
 // resolveL4IngressPolicy determines whether (TODO ianvernon)
 func (r *rule) resolveL4IngressPolicy(ctx *SearchContext, state *traceState, result *L4Policy, requirements []v1.LabelSelectorRequirement) (*L4Policy, error) {
 	if !ctx.rulesSelect {
-		if !r.EndpointSelector.Matches(ctx.To) {
+		if !r.matches(ctx.To) {
 			state.unSelectRule(ctx, ctx.To, r)
 			return nil, nil
 		}
@@ -280,6 +351,12 @@ func (r *rule) resolveL4IngressPolicy(ctx *SearchContext, state *traceState, res
 		}
 	}
 
+	for _, ingressDenyRule := range r.IngressDeny {
+		if cnt := mergeL4IngressDeny(ctx, ingressDenyRule, r.Rule.Labels.DeepCopy(), result.IngressDeny); cnt > 0 {
+			found += cnt
+		}
+	}
+
 	if found > 0 {
 		return result, nil
 	}
@@ -287,6 +364,93 @@ func (r *rule) resolveL4IngressPolicy(ctx *SearchContext, state *traceState, res
 	return nil, nil
 }
 
+// mergeL4DenyPort merges all deny rules which share the same port & protocol
+// that select a given set of endpoints into the L4Filter mapped to by the
+// specified port and protocol in resMap.
+func mergeL4DenyPort(ctx *SearchContext, endpoints []api.EndpointSelector, p api.PortProtocol,
+	proto api.L4Proto, ruleLabels labels.LabelArray, ingress bool, resMap L4PolicyMap) int {
+
+	key := l4PolicyMapKey(p, proto)
+	existingFilter, ok := resMap[key]
+	if !ok {
+		resMap[key] = CreateL4DenyFilter(endpoints, p, proto, ruleLabels, ingress)
+		return 1
+	}
+
+	filterToMerge := CreateL4DenyFilter(endpoints, p, proto, ruleLabels, ingress)
+
+	// Deny filters carry no L7 rules, so merging cannot fail.
+	mergeL4Port(ctx, endpoints, &existingFilter, &filterToMerge)
+	existingFilter.DerivedFromRules = append(existingFilter.DerivedFromRules, ruleLabels)
+	resMap[key] = existingFilter
+	return 1
+}
+
+// mergeL4DenyPorts merges the ports of the given deny rules into resMap. If no
+// ports are provided, a single filter denying all ports and protocols is
+// merged, which results in an L3-only deny.
+func mergeL4DenyPorts(ctx *SearchContext, endpoints api.EndpointSelectorSlice, toPorts []api.PortDenyRule,
+	ruleLabels labels.LabelArray, ingress bool, resMap L4PolicyMap) int {
+
+	if len(toPorts) == 0 {
+		return mergeL4DenyPort(ctx, endpoints, api.PortProtocol{Port: "0", Protocol: api.ProtoAny},
+			api.ProtoAny, ruleLabels, ingress, resMap)
+	}
+
+	found := 0
+	for _, r := range toPorts {
+		for _, p := range r.Ports {
+			if p.Protocol != api.ProtoAny {
+				found += mergeL4DenyPort(ctx, endpoints, p, p.Protocol, ruleLabels, ingress, resMap)
+			} else {
+				found += mergeL4DenyPort(ctx, endpoints, p, api.ProtoTCP, ruleLabels, ingress, resMap)
+				found += mergeL4DenyPort(ctx, endpoints, p, api.ProtoUDP, ruleLabels, ingress, resMap)
+			}
+		}
+	}
+	return found
+}
+
+func mergeL4IngressDeny(ctx *SearchContext, rule api.IngressDenyRule, ruleLabels labels.LabelArray, resMap L4PolicyMap) int {
+	fromEndpoints := rule.GetSourceEndpointSelectors()
+
+	if ctx.From != nil && len(fromEndpoints) > 0 {
+		if !fromEndpoints.Matches(ctx.From) {
+			ctx.PolicyTrace("    Labels %s not found", ctx.From)
+			return 0
+		}
+	}
+
+	if len(rule.ToPorts) == 0 {
+		ctx.PolicyTrace("    Denies %s all ports from endpoints %v\n", trafficdirection.Ingress, fromEndpoints)
+	}
+	for _, r := range rule.ToPorts {
+		ctx.PolicyTrace("    Denies %s port %v from endpoints %v\n", trafficdirection.Ingress, r.Ports, fromEndpoints)
+	}
+
+	return mergeL4DenyPorts(ctx, fromEndpoints, rule.ToPorts, ruleLabels, true, resMap)
+}
+
+func mergeL4EgressDeny(ctx *SearchContext, rule api.EgressDenyRule, ruleLabels labels.LabelArray, resMap L4PolicyMap) int {
+	toEndpoints := rule.GetDestinationEndpointSelectors()
+
+	if ctx.To != nil && len(toEndpoints) > 0 {
+		if !toEndpoints.Matches(ctx.To) {
+			ctx.PolicyTrace("    Labels %s not found", ctx.To)
+			return 0
+		}
+	}
+
+	if len(rule.ToPorts) == 0 {
+		ctx.PolicyTrace("    Denies %s all ports to endpoints %v\n", trafficdirection.Egress, toEndpoints)
+	}
+	for _, r := range rule.ToPorts {
+		ctx.PolicyTrace("    Denies %s port %v to endpoints %v\n", trafficdirection.Egress, r.Ports, toEndpoints)
+	}
+
+	return mergeL4DenyPorts(ctx, toEndpoints, rule.ToPorts, ruleLabels, false, resMap)
+}
+
 // ********************** CIDR POLICY **********************
 
 // mergeCIDR inserts all of the CIDRs in ipRules to resMap. Returns the number
@@ -311,7 +475,7 @@ func mergeCIDR(ctx *SearchContext, dir string, ipRules []api.CIDR, ruleLabels la
 func (r *rule) resolveCIDRPolicy(ctx *SearchContext, state *traceState, result *CIDRPolicy) *CIDRPolicy {
 	// Don't select rule if it doesn't apply to the given context.
 	if !ctx.rulesSelect {
-		if !r.EndpointSelector.Matches(ctx.To) {
+		if !r.matches(ctx.To) {
 			state.unSelectRule(ctx, ctx.To, r)
 			return nil
 		}
@@ -328,7 +492,7 @@ func (r *rule) resolveCIDRPolicy(ctx *SearchContext, state *traceState, result *
 
 		// CIDR + L4 rules are handled via mergeL4Ingress(),
 		// skip them here.
-		if len(allCIDRs) > 0 && len(ingressRule.ToPorts) > 0 {
+		if len(allCIDRs) > 0 && (len(ingressRule.ToPorts) > 0 || len(ingressRule.ICMPs) > 0) {
 			continue
 		}
 
@@ -369,13 +533,32 @@ func (r *rule) resolveCIDRPolicy(ctx *SearchContext, state *traceState, result *
 func (r *rule) canReachIngress(ctx *SearchContext, state *traceState) api.Decision {
 
 	if !ctx.rulesSelect {
-		if !r.EndpointSelector.Matches(ctx.To) {
+		if !r.matches(ctx.To) {
 			state.unSelectRule(ctx, ctx.To, r)
 			return api.Undecided
 		}
 	}
 
 	state.selectRule(ctx, r)
+
+	// Deny rules without port restrictions always take precedence over any
+	// allow rule. Deny rules with port restrictions are evaluated at the L4
+	// policy stage.
+	for _, r := range r.IngressDeny {
+		if len(r.ToPorts) > 0 {
+			continue
+		}
+		for _, sel := range r.GetSourceEndpointSelectors() {
+			ctx.PolicyTrace("    Denies from labels %+v", sel)
+			if sel.Matches(ctx.From) {
+				ctx.PolicyTrace("-     Found all required labels\n")
+				state.deniedRules++
+				return api.Denied
+			}
+			ctx.PolicyTrace("      Labels %v not found\n", ctx.From)
+		}
+	}
+
 	for _, r := range r.Ingress {
 		for _, sel := range r.FromRequires {
 			ctx.PolicyTrace("    Requires from labels %+v", sel)
@@ -395,7 +578,7 @@ func (r *rule) canReachIngress(ctx *SearchContext, state *traceState) api.Decisi
 			ctx.PolicyTrace("    Allows from labels %+v", sel)
 			if sel.Matches(ctx.From) {
 				ctx.PolicyTrace("      Found all required labels")
-				if len(r.ToPorts) == 0 {
+				if len(r.ToPorts) == 0 && len(r.ICMPs) == 0 {
 					ctx.PolicyTrace("+       No L4 restrictions\n")
 					state.matchedRules++
 					return api.Allowed
@@ -418,7 +601,7 @@ func (r *rule) canReachIngress(ctx *SearchContext, state *traceState) api.Decisi
 func (r *rule) canReachEgress(ctx *SearchContext, state *traceState) api.Decision {
 
 	if !ctx.rulesSelect {
-		if !r.EndpointSelector.Matches(ctx.From) {
+		if !r.matches(ctx.From) {
 			state.unSelectRule(ctx, ctx.From, r)
 			return api.Undecided
 		}
@@ -426,6 +609,24 @@ func (r *rule) canReachEgress(ctx *SearchContext, state *traceState) api.Decisio
 
 	state.selectRule(ctx, r)
 
+	// Deny rules without port restrictions always take precedence over any
+	// allow rule. Deny rules with port restrictions are evaluated at the L4
+	// policy stage.
+	for _, r := range r.EgressDeny {
+		if len(r.ToPorts) > 0 {
+			continue
+		}
+		for _, sel := range r.GetDestinationEndpointSelectors() {
+			ctx.PolicyTrace("    Denies to labels %+v", sel)
+			if sel.Matches(ctx.To) {
+				ctx.PolicyTrace("-     Found all required labels\n")
+				state.deniedRules++
+				return api.Denied
+			}
+			ctx.PolicyTrace("      Labels %v not found\n", ctx.To)
+		}
+	}
+
 	for _, r := range r.Egress {
 		for _, sel := range r.ToRequires {
 			ctx.PolicyTrace("    Requires from labels %+v", sel)
@@ -445,7 +646,7 @@ func (r *rule) canReachEgress(ctx *SearchContext, state *traceState) api.Decisio
 			ctx.PolicyTrace("    Allows to labels %+v", sel)
 			if sel.Matches(ctx.To) {
 				ctx.PolicyTrace("      Found all required labels")
-				if len(r.ToPorts) == 0 {
+				if len(r.ToPorts) == 0 && len(r.ICMPs) == 0 {
 					ctx.PolicyTrace("+       No L4 restrictions\n")
 					state.matchedRules++
 					return api.Allowed
@@ -461,7 +662,7 @@ func (r *rule) canReachEgress(ctx *SearchContext, state *traceState) api.Decisio
 }
 
 func mergeL4Egress(ctx *SearchContext, rule api.EgressRule, ruleLabels labels.LabelArray, resMap L4PolicyMap) (int, error) {
-	if len(rule.ToPorts) == 0 {
+	if len(rule.ToPorts) == 0 && len(rule.ICMPs) == 0 {
 		ctx.PolicyTrace("    No L4 %s rules\n", trafficdirection.Egress)
 		return 0, nil
 	}
@@ -481,6 +682,15 @@ func mergeL4Egress(ctx *SearchContext, rule api.EgressRule, ruleLabels labels.La
 			for _, l7 := range r.Rules.Kafka {
 				ctx.PolicyTrace("        %+v\n", l7)
 			}
+			for _, l7 := range r.Rules.GRPC {
+				ctx.PolicyTrace("        %+v\n", l7)
+			}
+			for _, l7 := range r.Rules.Cassandra {
+				ctx.PolicyTrace("        %+v\n", l7)
+			}
+			for _, l7 := range r.Rules.Memcached {
+				ctx.PolicyTrace("        %+v\n", l7)
+			}
 			for _, l7 := range r.Rules.L7 {
 				ctx.PolicyTrace("        %+v\n", l7)
 			}
@@ -509,6 +719,18 @@ func mergeL4Egress(ctx *SearchContext, rule api.EgressRule, ruleLabels labels.La
 		}
 	}
 
+	for _, icmp := range rule.ICMPs {
+		for _, f := range icmp.Fields {
+			ctx.PolicyTrace("    Allows %s %s type %d to endpoints %v\n", trafficdirection.Egress, f.Protocol(), f.Type, toEndpoints)
+			p := f.PortProtocol()
+			cnt, err := mergeL4EgressPort(ctx, toEndpoints, api.PortRule{}, p, p.Protocol, ruleLabels, resMap)
+			if err != nil {
+				return found, err
+			}
+			found += cnt
+		}
+	}
+
 	return found, nil
 }
 
@@ -520,7 +742,7 @@ func mergeL4Egress(ctx *SearchContext, rule api.EgressRule, ruleLabels labels.La
 func mergeL4EgressPort(ctx *SearchContext, endpoints []api.EndpointSelector, r api.PortRule, p api.PortProtocol,
 	proto api.L4Proto, ruleLabels labels.LabelArray, resMap L4PolicyMap) (int, error) {
 
-	key := p.Port + "/" + string(proto)
+	key := l4PolicyMapKey(p, proto)
 	existingFilter, ok := resMap[key]
 	if !ok {
 		resMap[key] = CreateL4EgressFilter(endpoints, r, p, proto, ruleLabels)
@@ -541,7 +763,7 @@ func mergeL4EgressPort(ctx *SearchContext, endpoints []api.EndpointSelector, r a
 
 func (r *rule) resolveL4EgressPolicy(ctx *SearchContext, state *traceState, result *L4Policy, requirements []v1.LabelSelectorRequirement) (*L4Policy, error) {
 	if !ctx.rulesSelect {
-		if !r.EndpointSelector.Matches(ctx.From) {
+		if !r.matches(ctx.From) {
 			state.unSelectRule(ctx, ctx.From, r)
 			return nil, nil
 		}
@@ -581,6 +803,12 @@ func (r *rule) resolveL4EgressPolicy(ctx *SearchContext, state *traceState, resu
 		}
 	}
 
+	for _, egressDenyRule := range r.EgressDeny {
+		if cnt := mergeL4EgressDeny(ctx, egressDenyRule, r.Rule.Labels.DeepCopy(), result.EgressDeny); cnt > 0 {
+			found += cnt
+		}
+	}
+
 	if found > 0 {
 		return result, nil
 	}
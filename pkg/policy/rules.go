@@ -58,7 +58,7 @@ func (rules ruleSlice) wildcardL3L4Rules(ctx *SearchContext, ingress bool, l4Pol
 	for _, r := range rules {
 		if ingress {
 			if !ctx.rulesSelect {
-				if !r.EndpointSelector.Matches(ctx.To) {
+				if !r.matches(ctx.To) {
 					continue
 				}
 			}
@@ -72,9 +72,9 @@ func (rules ruleSlice) wildcardL3L4Rules(ctx *SearchContext, ingress bool, l4Pol
 				ruleLabels := r.Rule.Labels.DeepCopy()
 
 				// L3-only rule.
-				if len(rule.ToPorts) == 0 {
-					wildcardL3L4Rule(api.ProtoTCP, 0, fromEndpoints, ruleLabels, l4Policy)
-					wildcardL3L4Rule(api.ProtoUDP, 0, fromEndpoints, ruleLabels, l4Policy)
+				if len(rule.ToPorts) == 0 && len(rule.ICMPs) == 0 {
+					wildcardL3L4Rule(api.ProtoTCP, 0, 0, fromEndpoints, ruleLabels, l4Policy)
+					wildcardL3L4Rule(api.ProtoUDP, 0, 0, fromEndpoints, ruleLabels, l4Policy)
 				} else {
 					for _, toPort := range rule.ToPorts {
 						// L3/L4-only rule
@@ -82,7 +82,7 @@ func (rules ruleSlice) wildcardL3L4Rules(ctx *SearchContext, ingress bool, l4Pol
 							for _, p := range toPort.Ports {
 								// Already validated via PortRule.Validate().
 								port, _ := strconv.ParseUint(p.Port, 0, 16)
-								wildcardL3L4Rule(p.Protocol, int(port), fromEndpoints, ruleLabels, l4Policy)
+								wildcardL3L4Rule(p.Protocol, int(port), int(p.EndPort), fromEndpoints, ruleLabels, l4Policy)
 							}
 						}
 					}
@@ -90,7 +90,7 @@ func (rules ruleSlice) wildcardL3L4Rules(ctx *SearchContext, ingress bool, l4Pol
 			}
 		} else {
 			if !ctx.rulesSelect {
-				if !r.EndpointSelector.Matches(ctx.From) {
+				if !r.matches(ctx.From) {
 					continue
 				}
 			}
@@ -104,9 +104,9 @@ func (rules ruleSlice) wildcardL3L4Rules(ctx *SearchContext, ingress bool, l4Pol
 				ruleLabels := r.Rule.Labels.DeepCopy()
 
 				// L3-only rule.
-				if len(rule.ToPorts) == 0 {
-					wildcardL3L4Rule(api.ProtoTCP, 0, toEndpoints, ruleLabels, l4Policy)
-					wildcardL3L4Rule(api.ProtoUDP, 0, toEndpoints, ruleLabels, l4Policy)
+				if len(rule.ToPorts) == 0 && len(rule.ICMPs) == 0 {
+					wildcardL3L4Rule(api.ProtoTCP, 0, 0, toEndpoints, ruleLabels, l4Policy)
+					wildcardL3L4Rule(api.ProtoUDP, 0, 0, toEndpoints, ruleLabels, l4Policy)
 				} else {
 					for _, toPort := range rule.ToPorts {
 						// L3/L4-only rule
@@ -114,7 +114,7 @@ func (rules ruleSlice) wildcardL3L4Rules(ctx *SearchContext, ingress bool, l4Pol
 							for _, p := range toPort.Ports {
 								// Already validated via PortRule.Validate().
 								port, _ := strconv.ParseUint(p.Port, 0, 16)
-								wildcardL3L4Rule(p.Protocol, int(port), toEndpoints, ruleLabels, l4Policy)
+								wildcardL3L4Rule(p.Protocol, int(port), int(p.EndPort), toEndpoints, ruleLabels, l4Policy)
 							}
 						}
 					}
@@ -140,7 +140,7 @@ func (rules ruleSlice) resolveL4IngressPolicy(ctx *SearchContext, revision uint6
 	if !ctx.skipL4RequirementsAggregation {
 		for _, r := range rules {
 			for _, ingressRule := range r.Ingress {
-				if r.EndpointSelector.Matches(ctx.To) {
+				if r.matches(ctx.To) {
 					for _, requirement := range ingressRule.FromRequires {
 						requirements = append(requirements, requirement.ConvertToLabelSelectorRequirementSlice()...)
 					}
@@ -182,7 +182,7 @@ func (rules ruleSlice) resolveL4EgressPolicy(ctx *SearchContext, revision uint64
 	if !ctx.skipL4RequirementsAggregation {
 		for _, r := range rules {
 			for _, egressRule := range r.Egress {
-				if r.EndpointSelector.Matches(ctx.From) {
+				if r.matches(ctx.From) {
 					for _, requirement := range egressRule.ToRequires {
 						requirements = append(requirements, requirement.ConvertToLabelSelectorRequirementSlice()...)
 					}
@@ -252,3 +252,25 @@ egressLoop:
 
 	return egressDecision
 }
+
+// hasIngressDeny returns true if any of the rules contains an ingress deny
+// section.
+func (rules ruleSlice) hasIngressDeny() bool {
+	for _, r := range rules {
+		if len(r.IngressDeny) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasEgressDeny returns true if any of the rules contains an egress deny
+// section.
+func (rules ruleSlice) hasEgressDeny() bool {
+	for _, r := range rules {
+		if len(r.EgressDeny) > 0 {
+			return true
+		}
+	}
+	return false
+}
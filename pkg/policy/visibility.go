@@ -0,0 +1,200 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/policy/api"
+	"github.com/cilium/cilium/pkg/u8proto"
+)
+
+// VisibilityMetadata requests the traffic to a port to be redirected to the
+// proxy for visibility only, i.e. without restricting it at L7.
+type VisibilityMetadata struct {
+	// Parser is the L7 parser of the proxy the traffic is redirected to
+	Parser L7ParserType
+
+	// Port is the destination port of the traffic
+	Port uint16
+
+	// Proto is the L4 protocol of the traffic, TCP or UDP
+	Proto api.L4Proto
+
+	// Ingress is true if the traffic is received by the endpoint, false if
+	// it is sent by the endpoint
+	Ingress bool
+}
+
+// key returns the key of the L4Filter of the port in an L4PolicyMap
+func (v *VisibilityMetadata) key() string {
+	return strconv.Itoa(int(v.Port)) + "/" + string(v.Proto)
+}
+
+// VisibilityPolicy is the set of ports of an endpoint for which L7
+// visibility is requested.
+type VisibilityPolicy struct {
+	Ingress []VisibilityMetadata
+	Egress  []VisibilityMetadata
+}
+
+// visibilityParsers maps the L7 protocols accepted in a visibility policy to
+// their parser and the L4 protocols they may be carried over.
+var visibilityParsers = map[string]struct {
+	parser L7ParserType
+	protos []api.L4Proto
+}{
+	"http":  {ParserTypeHTTP, []api.L4Proto{api.ProtoTCP}},
+	"kafka": {ParserTypeKafka, []api.L4Proto{api.ProtoTCP}},
+	"dns":   {ParserTypeDNS, []api.L4Proto{api.ProtoTCP, api.ProtoUDP}},
+}
+
+// parseVisibilityMetadata parses a single "<Direction/Port/L4Proto/L7Proto>"
+// entry of a visibility policy.
+func parseVisibilityMetadata(entry string) (VisibilityMetadata, error) {
+	v := VisibilityMetadata{}
+
+	if !strings.HasPrefix(entry, "<") || !strings.HasSuffix(entry, ">") {
+		return v, fmt.Errorf("%q must be enclosed in angle brackets", entry)
+	}
+	fields := strings.Split(entry[1:len(entry)-1], "/")
+	if len(fields) != 4 {
+		return v, fmt.Errorf("%q must be of the form <{Ingress|Egress}/{Port}/{TCP|UDP}/{HTTP|Kafka|DNS}>", entry)
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "ingress":
+		v.Ingress = true
+	case "egress":
+		v.Ingress = false
+	default:
+		return v, fmt.Errorf("invalid traffic direction %q in %q", fields[0], entry)
+	}
+
+	port, err := strconv.ParseUint(fields[1], 10, 16)
+	if err != nil || port == 0 {
+		return v, fmt.Errorf("invalid port %q in %q", fields[1], entry)
+	}
+	v.Port = uint16(port)
+
+	proto, err := api.ParseL4Proto(fields[2])
+	if err != nil || proto == api.ProtoAny {
+		return v, fmt.Errorf("invalid L4 protocol %q in %q", fields[2], entry)
+	}
+	v.Proto = proto
+
+	p, ok := visibilityParsers[strings.ToLower(fields[3])]
+	if !ok {
+		return v, fmt.Errorf("unsupported L7 protocol %q in %q", fields[3], entry)
+	}
+	supported := false
+	for _, proto := range p.protos {
+		supported = supported || proto == v.Proto
+	}
+	if !supported {
+		return v, fmt.Errorf("L7 protocol %q cannot be carried over %s in %q", fields[3], v.Proto, entry)
+	}
+	v.Parser = p.parser
+
+	return v, nil
+}
+
+// NewVisibilityPolicy parses a visibility policy from its annotation form,
+// a comma-separated list of "<{Ingress|Egress}/{Port}/{TCP|UDP}/{L7Proto}>"
+// entries, e.g. "<Ingress/80/TCP/HTTP>,<Egress/53/UDP/DNS>".
+func NewVisibilityPolicy(anno string) (*VisibilityPolicy, error) {
+	vp := &VisibilityPolicy{}
+	parsers := map[bool]map[string]L7ParserType{true: {}, false: {}}
+
+	for _, entry := range strings.Split(anno, ",") {
+		v, err := parseVisibilityMetadata(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, err
+		}
+
+		if parser, ok := parsers[v.Ingress][v.key()]; ok {
+			if parser != v.Parser {
+				return nil, fmt.Errorf("conflicting L7 protocols %s and %s for %s", parser, v.Parser, v.key())
+			}
+			continue
+		}
+		parsers[v.Ingress][v.key()] = v.Parser
+
+		if v.Ingress {
+			vp.Ingress = append(vp.Ingress, v)
+		} else {
+			vp.Egress = append(vp.Egress, v)
+		}
+	}
+
+	return vp, nil
+}
+
+// addVisibilityFilters adds to l4Policy a filter redirecting to the proxy
+// each port of visibility which the policy allows. The filters allow at L7
+// all traffic allowed at L3/L4 so that the visibility policy never changes
+// which traffic is allowed. If enforced is false, no policy applies in the
+// direction and all traffic is redirected. Ports already redirected to the
+// proxy by L7 rules are left untouched.
+func (rules ruleSlice) addVisibilityFilters(ctx *SearchContext, ingress, enforced bool, visibility []VisibilityMetadata, l4Policy L4PolicyMap) {
+	for _, v := range visibility {
+		key := v.key()
+		if existing, ok := l4Policy[key]; ok && existing.IsRedirect() {
+			continue
+		}
+
+		// Already validated by NewVisibilityPolicy()
+		u8p, _ := u8proto.ParseProtocol(string(v.Proto))
+		filter := L4Filter{
+			Port:         int(v.Port),
+			Protocol:     v.Proto,
+			U8Proto:      u8p,
+			L7Parser:     v.Parser,
+			L7RulesPerEp: make(L7DataMap),
+			Ingress:      ingress,
+		}
+		filters := L4PolicyMap{key: filter}
+
+		if !enforced {
+			wildcardL3L4Rule(v.Proto, int(v.Port), 0, api.EndpointSelectorSlice{api.WildcardEndpointSelector}, nil, filters)
+		} else {
+			// Wildcard at L7 the peers allowed on the port by L4 rules,
+			// including CIDR-based ones, as well as the peers allowed by
+			// L3-only rules.
+			var peers api.EndpointSelectorSlice
+			for _, f := range l4Policy {
+				if f.IsRedirect() || !f.coversPort(v.Port) ||
+					(f.Protocol != v.Proto && f.Protocol != api.ProtoAny) {
+					continue
+				}
+				peers = append(peers, f.Endpoints...)
+				filter.DerivedFromRules = append(filter.DerivedFromRules, f.DerivedFromRules...)
+			}
+			filters[key] = filter
+			wildcardL3L4Rule(v.Proto, int(v.Port), 0, peers, nil, filters)
+			rules.wildcardL3L4Rules(ctx, ingress, filters)
+		}
+
+		filter = filters[key]
+		// Nothing is allowed on the port, no redirect is needed.
+		if len(filter.Endpoints) == 0 {
+			continue
+		}
+		filter.allowsAllAtL3 = filter.Endpoints.SelectsAllEndpoints()
+		l4Policy[key] = filter
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package policy
+
+import (
+	"github.com/cilium/cilium/pkg/checker"
+	"github.com/cilium/cilium/pkg/identity"
+	"github.com/cilium/cilium/pkg/identity/cache"
+	"github.com/cilium/cilium/pkg/labels"
+	"github.com/cilium/cilium/pkg/policy/api"
+	"github.com/cilium/cilium/pkg/policy/trafficdirection"
+
+	. "gopkg.in/check.v1"
+)
+
+const visibilityProxyPort = 4242
+
+// visibilityOwner allocates the same proxy port to all redirects
+type visibilityOwner struct {
+	visibility *VisibilityPolicy
+}
+
+func (v visibilityOwner) LookupRedirectPort(l4 *L4Filter) uint16 {
+	if l4.IsRedirect() {
+		return visibilityProxyPort
+	}
+	return 0
+}
+
+func (v visibilityOwner) GetVisibilityPolicy() *VisibilityPolicy {
+	return v.visibility
+}
+
+func (ds *PolicyTestSuite) TestNewVisibilityPolicy(c *C) {
+	vp, err := NewVisibilityPolicy("<Ingress/80/TCP/HTTP>, <Egress/53/UDP/DNS>,<Egress/9092/TCP/Kafka>,<Ingress/80/TCP/HTTP>")
+	c.Assert(err, IsNil)
+	c.Assert(vp, checker.DeepEquals, &VisibilityPolicy{
+		Ingress: []VisibilityMetadata{
+			{Parser: ParserTypeHTTP, Port: 80, Proto: api.ProtoTCP, Ingress: true},
+		},
+		Egress: []VisibilityMetadata{
+			{Parser: ParserTypeDNS, Port: 53, Proto: api.ProtoUDP},
+			{Parser: ParserTypeKafka, Port: 9092, Proto: api.ProtoTCP},
+		},
+	})
+
+	for _, anno := range []string{
+		"",
+		"Ingress/80/TCP/HTTP",
+		"<Ingress/80/TCP>",
+		"<Inbound/80/TCP/HTTP>",
+		"<Ingress/0/TCP/HTTP>",
+		"<Ingress/65536/TCP/HTTP>",
+		"<Ingress/80/ANY/HTTP>",
+		"<Ingress/80/UDP/HTTP>",
+		"<Ingress/80/TCP/SMTP>",
+		"<Ingress/80/TCP/HTTP>,<Ingress/80/TCP/Kafka>",
+	} {
+		_, err := NewVisibilityPolicy(anno)
+		c.Assert(err, Not(IsNil), Commentf("annotation %q", anno))
+	}
+}
+
+func (ds *PolicyTestSuite) TestResolvePolicyVisibility(c *C) {
+	repo := NewPolicyRepository()
+
+	fooSelector := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	barSelector := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+	bazSelector := api.NewESFromLabels(labels.ParseSelectLabel("baz"))
+
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: barSelector,
+		Ingress: []api.IngressRule{
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortRule{{
+					Ports: []api.PortProtocol{{Port: "80", Protocol: api.ProtoTCP}},
+				}},
+			},
+			{
+				FromEndpoints: []api.EndpointSelector{bazSelector},
+			},
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortRule{{
+					Ports: []api.PortProtocol{{Port: "8080", Protocol: api.ProtoTCP}},
+					Rules: &api.L7Rules{
+						HTTP: []api.PortRuleHTTP{{Method: "GET"}},
+					},
+				}},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	fooIdentity := identity.NumericIdentity(1000)
+	bazIdentity := identity.NumericIdentity(1001)
+	quxIdentity := identity.NumericIdentity(1002)
+	idCache := cache.IdentityCache{
+		fooIdentity: labels.ParseSelectLabelArray("foo"),
+		bazIdentity: labels.ParseSelectLabelArray("baz"),
+		quxIdentity: labels.ParseSelectLabelArray("qux"),
+	}
+
+	vp, err := NewVisibilityPolicy("<Ingress/80/TCP/HTTP>,<Ingress/8080/TCP/Kafka>,<Ingress/443/TCP/HTTP>,<Egress/53/UDP/DNS>")
+	c.Assert(err, IsNil)
+
+	repo.Mutex.RLock()
+	policy, err := repo.ResolvePolicy(1, labels.ParseSelectLabelArray("bar"), visibilityOwner{vp}, idCache)
+	repo.Mutex.RUnlock()
+	c.Assert(err, IsNil)
+
+	ingress := trafficdirection.Ingress.Uint8()
+	egress := trafficdirection.Egress.Uint8()
+
+	// The traffic allowed on port 80 at L3/L4 and L3 is allowed at L7
+	filter, ok := policy.L4Policy.Ingress["80/TCP"]
+	c.Assert(ok, Equals, true)
+	c.Assert(filter.L7Parser, Equals, ParserTypeHTTP)
+	c.Assert(filter.L7RulesPerEp[fooSelector], checker.DeepEquals, api.L7Rules{HTTP: []api.PortRuleHTTP{{}}})
+	c.Assert(filter.L7RulesPerEp[bazSelector], checker.DeepEquals, api.L7Rules{HTTP: []api.PortRuleHTTP{{}}})
+	c.Assert(policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), DestPort: 80, Nexthdr: 6, TrafficDirection: ingress}],
+		Equals, MapStateEntry{ProxyPort: visibilityProxyPort})
+	c.Assert(policy.PolicyMapState[Key{Identity: bazIdentity.Uint32(), DestPort: 80, Nexthdr: 6, TrafficDirection: ingress}],
+		Equals, MapStateEntry{ProxyPort: visibilityProxyPort})
+	_, ok = policy.PolicyMapState[Key{Identity: quxIdentity.Uint32(), DestPort: 80, Nexthdr: 6, TrafficDirection: ingress}]
+	c.Assert(ok, Equals, false)
+
+	// The L7 rules of port 8080 take precedence over the visibility policy
+	filter = policy.L4Policy.Ingress["8080/TCP"]
+	c.Assert(filter.L7Parser, Equals, ParserTypeHTTP)
+	c.Assert(filter.L7RulesPerEp[fooSelector], checker.DeepEquals, api.L7Rules{HTTP: []api.PortRuleHTTP{{Method: "GET"}}})
+
+	// Port 443 is only allowed for baz at L3
+	filter = policy.L4Policy.Ingress["443/TCP"]
+	c.Assert(filter.Endpoints, checker.DeepEquals, api.EndpointSelectorSlice{bazSelector})
+	_, ok = policy.PolicyMapState[Key{Identity: fooIdentity.Uint32(), DestPort: 443, Nexthdr: 6, TrafficDirection: ingress}]
+	c.Assert(ok, Equals, false)
+
+	// No policy applies at egress, all traffic to port 53 is redirected
+	filter = policy.L4Policy.Egress["53/UDP"]
+	c.Assert(filter.L7Parser, Equals, ParserTypeDNS)
+	c.Assert(filter.AllowsAllAtL3(), Equals, true)
+	for id := range idCache {
+		c.Assert(policy.PolicyMapState[Key{Identity: id.Uint32(), DestPort: 53, Nexthdr: 17, TrafficDirection: egress}],
+			Equals, MapStateEntry{ProxyPort: visibilityProxyPort})
+	}
+}
+
+func (ds *PolicyTestSuite) TestResolvePolicyVisibilityDenied(c *C) {
+	repo := NewPolicyRepository()
+
+	fooSelector := api.NewESFromLabels(labels.ParseSelectLabel("foo"))
+	barSelector := api.NewESFromLabels(labels.ParseSelectLabel("bar"))
+
+	_, err := repo.Add(api.Rule{
+		EndpointSelector: barSelector,
+		Ingress: []api.IngressRule{
+			{
+				FromEndpoints: []api.EndpointSelector{fooSelector},
+				ToPorts: []api.PortRule{{
+					Ports: []api.PortProtocol{{Port: "8080", Protocol: api.ProtoTCP}},
+				}},
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+
+	idCache := cache.IdentityCache{
+		identity.NumericIdentity(1000): labels.ParseSelectLabelArray("foo"),
+	}
+
+	vp, err := NewVisibilityPolicy("<Ingress/80/TCP/HTTP>")
+	c.Assert(err, IsNil)
+
+	repo.Mutex.RLock()
+	policy, err := repo.ResolvePolicy(1, labels.ParseSelectLabelArray("bar"), visibilityOwner{vp}, idCache)
+	repo.Mutex.RUnlock()
+	c.Assert(err, IsNil)
+
+	// Nothing is allowed on port 80, no redirect is created
+	_, ok := policy.L4Policy.Ingress["80/TCP"]
+	c.Assert(ok, Equals, false)
+}
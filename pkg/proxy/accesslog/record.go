@@ -219,6 +219,14 @@ type LogRecordHTTP struct {
 
 	// Headers are all HTTP headers present in the request
 	Headers http.Header
+
+	// GRPCService is the fully qualified gRPC service name of the request,
+	// if the request is a gRPC call
+	GRPCService string `json:",omitempty"`
+
+	// GRPCMethod is the gRPC method name of the request, if the request is
+	// a gRPC call
+	GRPCMethod string `json:",omitempty"`
 }
 
 // KafkaTopic contains the topic for requests
@@ -265,6 +273,14 @@ type LogRecordDNS struct {
 	// to the IPs.
 	// This field is filled only for DNS responses with CNAMEs to IP data.
 	CNAMEs []string `json:"CNAMEs,omitempty"`
+
+	// QueryType is the type of the query, e.g. A or AAAA
+	QueryType string `json:"QueryType,omitempty"`
+
+	// RejectedIPs are the IPs which have been removed from this response
+	// because they are not allowed by policy.
+	// This field is filled only for DNS responses.
+	RejectedIPs []net.IP `json:"RejectedIPs,omitempty"`
 }
 
 // LogRecordL7 contains the generic L7 portion of a log record
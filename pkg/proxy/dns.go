@@ -21,8 +21,10 @@ import (
 	"github.com/cilium/cilium/pkg/completion"
 	"github.com/cilium/cilium/pkg/fqdn/dnsproxy"
 	"github.com/cilium/cilium/pkg/fqdn/matchpattern"
+	"github.com/cilium/cilium/pkg/ip"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/policy"
+	"github.com/cilium/cilium/pkg/policy/api"
 	"github.com/cilium/cilium/pkg/proxy/logger"
 	"github.com/cilium/cilium/pkg/revert"
 	"github.com/miekg/dns"
@@ -46,46 +48,56 @@ type dnsRedirect struct {
 type dnsConfiguration struct {
 }
 
-// setRules replaces old l7 rules of a redirect with new ones.
-func (dr *dnsRedirect) setRules(wg *completion.WaitGroup, newRules policy.L7DataMap) error {
-	var toRemove, toAdd []string
+// allowedPatterns converts the DNS rules in rules into the patterns allowed
+// by the DNS proxy.
+func allowedPatterns(rules policy.L7DataMap) []dnsproxy.AllowedPattern {
+	var patterns []dnsproxy.AllowedPattern
 
-	for _, rule := range dr.currentRules {
+	for _, rule := range rules {
 		for _, dnsRule := range rule.DNS {
+			restriction := dnsRestriction(dnsRule)
 			if len(dnsRule.MatchName) > 0 {
 				dnsName := strings.ToLower(dns.Fqdn(dnsRule.MatchName))
 				dnsNameAsRE := matchpattern.ToRegexp(dnsName)
-				toRemove = append(toRemove, dnsNameAsRE)
+				patterns = append(patterns, dnsproxy.AllowedPattern{Pattern: dnsNameAsRE, Restriction: restriction})
 			}
 			if len(dnsRule.MatchPattern) > 0 {
 				dnsPattern := matchpattern.Sanitize(dnsRule.MatchPattern)
 				dnsPatternAsRE := matchpattern.ToRegexp(dnsPattern)
-				toRemove = append(toRemove, dnsPatternAsRE)
+				patterns = append(patterns, dnsproxy.AllowedPattern{Pattern: dnsPatternAsRE, Restriction: restriction})
 			}
 		}
 	}
 
-	for _, rule := range dr.redirect.rules {
-		for _, dnsRule := range rule.DNS {
-			if len(dnsRule.MatchName) > 0 {
-				dnsName := strings.ToLower(dns.Fqdn(dnsRule.MatchName))
-				dnsNameAsRE := matchpattern.ToRegexp(dnsName)
-				toAdd = append(toAdd, dnsNameAsRE)
-			}
-			if len(dnsRule.MatchPattern) > 0 {
-				dnsPattern := matchpattern.Sanitize(dnsRule.MatchPattern)
-				dnsPatternAsRE := matchpattern.ToRegexp(dnsPattern)
-				toAdd = append(toAdd, dnsPatternAsRE)
-			}
+	return patterns
+}
+
+// dnsRestriction returns the query types and response CIDRs dnsRule is
+// restricted to. Assumes that validation already occurred on dnsRule.
+func dnsRestriction(dnsRule api.PortRuleDNS) dnsproxy.Restriction {
+	var restriction dnsproxy.Restriction
+	for _, qtype := range dnsRule.MatchQueryTypes {
+		if t, ok := dns.StringToType[strings.ToUpper(qtype)]; ok {
+			restriction.QueryTypes = append(restriction.QueryTypes, t)
 		}
 	}
+	if len(dnsRule.ResponseCIDRs) > 0 {
+		restriction.ResponseCIDRs, _ = ip.ParseCIDRs(api.CIDRSlice(dnsRule.ResponseCIDRs).StringSlice())
+	}
+	return restriction
+}
+
+// setRules replaces old l7 rules of a redirect with new ones.
+func (dr *dnsRedirect) setRules(wg *completion.WaitGroup, newRules policy.L7DataMap) error {
+	toRemove := allowedPatterns(dr.currentRules)
+	toAdd := allowedPatterns(dr.redirect.rules)
 
 	log.WithFields(logrus.Fields{
 		"add":                toAdd,
 		"remove":             toRemove,
 		logfields.EndpointID: dr.redirect.endpointID,
 	}).Debug("DNS Proxy updating matchNames in allowed list during UpdateRules")
-	DefaultDNSProxy.UpdateAllowed(toAdd, toRemove, fmt.Sprintf("%d", dr.redirect.endpointID))
+	DefaultDNSProxy.UpdateAllowedPatterns(toAdd, toRemove, fmt.Sprintf("%d", dr.redirect.endpointID))
 	dr.currentRules = copyRules(dr.redirect.rules)
 
 	return nil
@@ -105,17 +117,7 @@ func (dr *dnsRedirect) UpdateRules(wg *completion.WaitGroup, l4 *policy.L4Filter
 
 // Close the redirect.
 func (dr *dnsRedirect) Close(wg *completion.WaitGroup) (revert.FinalizeFunc, revert.RevertFunc) {
-	for _, rule := range dr.currentRules {
-		for _, dnsRule := range rule.DNS {
-			dnsName := strings.ToLower(dns.Fqdn(dnsRule.MatchName))
-			dnsNameAsRE := matchpattern.ToRegexp(dnsName)
-			DefaultDNSProxy.RemoveAllowed(dnsNameAsRE, fmt.Sprintf("%d", dr.redirect.endpointID))
-
-			dnsPattern := matchpattern.Sanitize(dnsRule.MatchPattern)
-			dnsPatternAsRE := matchpattern.ToRegexp(dnsPattern)
-			DefaultDNSProxy.RemoveAllowed(dnsPatternAsRE, fmt.Sprintf("%d", dr.redirect.endpointID))
-		}
-	}
+	DefaultDNSProxy.UpdateAllowedPatterns(nil, allowedPatterns(dr.currentRules), fmt.Sprintf("%d", dr.redirect.endpointID))
 	dr.currentRules = nil
 	return func() {}, nil
 }
@@ -0,0 +1,194 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	"github.com/sirupsen/logrus"
+)
+
+var log = logging.DefaultLogger.WithField(logfields.LogSubsys, "service-healthserver")
+
+// Service is the health state of a service reported on its health check
+// NodePort
+type Service struct {
+	Namespace      string
+	Name           string
+	LocalEndpoints int
+}
+
+// healthResponse is the response of a health check request. It matches the
+// format used by kube-proxy.
+type healthResponse struct {
+	Service struct {
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	} `json:"service"`
+	LocalEndpoints int `json:"localEndpoints"`
+}
+
+// healthHTTPServer serves the health state of a single service
+type healthHTTPServer interface {
+	updateService(Service)
+	shutdown()
+}
+
+// ServiceHealthServer serves the number of node-local backends of services
+// with externalTrafficPolicy=Local on their health check NodePort. External
+// load balancers use it to only send traffic to nodes running a backend of
+// the service.
+type ServiceHealthServer struct {
+	mutex lock.Mutex
+
+	// ports maps the namespace/name of a service to its health check port
+	ports map[string]uint16
+
+	// servers maps a health check port to the server listening on it
+	servers map[uint16]healthHTTPServer
+
+	newServer func(port uint16, svc Service) (healthHTTPServer, error)
+}
+
+// New returns a new ServiceHealthServer
+func New() *ServiceHealthServer {
+	return &ServiceHealthServer{
+		ports:     map[string]uint16{},
+		servers:   map[uint16]healthHTTPServer{},
+		newServer: newHTTPServer,
+	}
+}
+
+func serviceKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// UpsertService starts or updates the health check server of the service
+// with the given namespace and name on the given port. If the health check
+// port of the service changed, the server on the previous port is stopped.
+func (s *ServiceHealthServer) UpsertService(namespace, name string, localEndpoints int, port uint16) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := serviceKey(namespace, name)
+	svc := Service{Namespace: namespace, Name: name, LocalEndpoints: localEndpoints}
+
+	if oldPort, ok := s.ports[key]; ok && oldPort != port {
+		s.deleteLocked(key)
+	}
+
+	if srv, ok := s.servers[port]; ok {
+		srv.updateService(svc)
+		s.ports[key] = port
+		return
+	}
+
+	srv, err := s.newServer(port, svc)
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{
+			logfields.K8sSvcName:   name,
+			logfields.K8sNamespace: namespace,
+			logfields.Port:         port,
+		}).Warning("Unable to start health check server")
+		return
+	}
+	s.servers[port] = srv
+	s.ports[key] = port
+}
+
+// DeleteService stops the health check server of the service with the given
+// namespace and name, if any
+func (s *ServiceHealthServer) DeleteService(namespace, name string) {
+	s.mutex.Lock()
+	s.deleteLocked(serviceKey(namespace, name))
+	s.mutex.Unlock()
+}
+
+func (s *ServiceHealthServer) deleteLocked(key string) {
+	port, ok := s.ports[key]
+	if !ok {
+		return
+	}
+	if srv, ok := s.servers[port]; ok {
+		srv.shutdown()
+		delete(s.servers, port)
+	}
+	delete(s.ports, key)
+}
+
+type httpHealthServer struct {
+	http.Server
+
+	mutex lock.RWMutex
+	svc   Service
+}
+
+func newHTTPServer(port uint16, svc Service) (healthHTTPServer, error) {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &httpHealthServer{svc: svc}
+	srv.Server.Handler = srv
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).WithField(logfields.Port, port).
+				Error("Health check server stopped unexpectedly")
+		}
+	}()
+
+	return srv, nil
+}
+
+func (h *httpHealthServer) updateService(svc Service) {
+	h.mutex.Lock()
+	h.svc = svc
+	h.mutex.Unlock()
+}
+
+func (h *httpHealthServer) shutdown() {
+	h.Server.Close()
+}
+
+// ServeHTTP reports StatusOK if the service has at least one local backend
+// and StatusServiceUnavailable otherwise
+func (h *httpHealthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.RLock()
+	svc := h.svc
+	h.mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if svc.LocalEndpoints == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	resp := healthResponse{LocalEndpoints: svc.LocalEndpoints}
+	resp.Service.Namespace = svc.Namespace
+	resp.Service.Name = svc.Name
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.WithError(err).Debug("Unable to write health check response")
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package healthserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type HealthServerSuite struct{}
+
+var _ = Suite(&HealthServerSuite{})
+
+type fakeServer struct {
+	svc      Service
+	shutDown bool
+}
+
+func (f *fakeServer) updateService(svc Service) { f.svc = svc }
+func (f *fakeServer) shutdown()                 { f.shutDown = true }
+
+func newFakeServiceHealthServer() (*ServiceHealthServer, map[uint16]*fakeServer) {
+	started := map[uint16]*fakeServer{}
+	s := New()
+	s.newServer = func(port uint16, svc Service) (healthHTTPServer, error) {
+		f := &fakeServer{svc: svc}
+		started[port] = f
+		return f, nil
+	}
+	return s, started
+}
+
+func (h *HealthServerSuite) TestUpsertDeleteService(c *C) {
+	s, started := newFakeServiceHealthServer()
+
+	s.UpsertService("default", "svc", 1, 32000)
+	c.Assert(len(started), Equals, 1)
+	c.Assert(started[32000].svc, Equals, Service{Namespace: "default", Name: "svc", LocalEndpoints: 1})
+
+	s.UpsertService("default", "svc", 0, 32000)
+	c.Assert(len(started), Equals, 1)
+	c.Assert(started[32000].svc.LocalEndpoints, Equals, 0)
+
+	// Changing the port stops the server on the old port
+	s.UpsertService("default", "svc", 2, 32001)
+	c.Assert(started[32000].shutDown, Equals, true)
+	c.Assert(started[32001].svc.LocalEndpoints, Equals, 2)
+	c.Assert(len(s.servers), Equals, 1)
+
+	s.DeleteService("default", "svc")
+	c.Assert(started[32001].shutDown, Equals, true)
+	c.Assert(len(s.servers), Equals, 0)
+	c.Assert(len(s.ports), Equals, 0)
+
+	// Deleting an unknown service is a no-op
+	s.DeleteService("default", "unknown")
+}
+
+func (h *HealthServerSuite) TestServeHTTP(c *C) {
+	srv := &httpHealthServer{svc: Service{Namespace: "default", Name: "svc"}}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	c.Assert(rec.Code, Equals, http.StatusServiceUnavailable)
+
+	srv.updateService(Service{Namespace: "default", Name: "svc", LocalEndpoints: 2})
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	c.Assert(rec.Code, Equals, http.StatusOK)
+	c.Assert(rec.Header().Get("Content-Type"), Equals, "application/json")
+
+	resp := healthResponse{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &resp), IsNil)
+	c.Assert(resp.Service.Namespace, Equals, "default")
+	c.Assert(resp.Service.Name, Equals, "svc")
+	c.Assert(resp.LocalEndpoints, Equals, 2)
+}
@@ -1,4 +1,4 @@
-// Copyright 2018 Authors of Cilium
+// Copyright 2018-2019 Authors of Cilium
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -17,25 +17,23 @@ package sockops
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strconv"
-	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/cilium/cilium/api/v1/models"
 	"github.com/cilium/cilium/pkg/bpf"
 	"github.com/cilium/cilium/pkg/datapath/loader"
 	"github.com/cilium/cilium/pkg/defaults"
+	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/logging"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	"github.com/cilium/cilium/pkg/mountinfo"
 	"github.com/cilium/cilium/pkg/option"
 
-	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
 )
 
 var (
@@ -45,20 +43,22 @@ var (
 	// Only mount a single instance
 	cgrpMountOnce sync.Once
 
-	// Default prefix for map objects
-	mapPrefix = defaults.DefaultMapPrefix
-
 	contextTimeout = 5 * time.Minute
+
+	statusMutex lock.RWMutex
+	status      = models.Status{State: models.StatusStateDisabled}
 )
 
 const (
 	cSockops = "bpf_sockops.c"
 	oSockops = "bpf_sockops.o"
 	eSockops = "bpf_sockops"
+	sSockops = "sockops"
 
 	cIPC = "bpf_redir.c"
 	oIPC = "bpf_redir.o"
 	eIPC = "bpf_redir"
+	sIPC = "sk_msg"
 
 	sockMap = "sock_ops_map"
 )
@@ -134,209 +134,73 @@ func CheckOrMountCgrpFS(mapRoot string) {
 	})
 }
 
-// BPF programs and sockmaps working on cgroups
-func bpftoolMapAttach(progID string, mapID string) error {
-	prog := "bpftool"
-
-	args := []string{"prog", "attach", "id", progID, "msg_verdict", "id", mapID}
-	log.WithFields(logrus.Fields{
-		"bpftool": prog,
-		"args":    args,
-	}).Debug("Map Attach BPF Object:")
-	_, err := exec.Command(prog, args...).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("Failed to attach prog(%s) to map(%s): %s", progID, mapID, err)
-	}
-	return nil
+// progPath returns the path at which the program with the given name is
+// pinned so that it can be detached again after an agent restart.
+func progPath(name string) string {
+	return filepath.Join(bpf.GetMapRoot(), name)
 }
 
-// #bpftool cgroup attach $cgrp sock_ops /sys/fs/bpf/$bpfObject
-func bpftoolAttach(bpfObject string) error {
-	prog := "bpftool"
-	bpffs := bpf.GetMapRoot() + "/" + bpfObject
-	cgrp := cgroupRoot
-
-	args := []string{"cgroup", "attach", cgrp, "sock_ops", "pinned", bpffs}
-	log.WithFields(logrus.Fields{
-		"bpftool": prog,
-		"args":    args,
-	}).Debug("Attach BPF Object:")
-	_, err := exec.Command(prog, args...).CombinedOutput()
+// loadPinProg loads the program in section of the compiled object and pins it
+// under name. The returned collection must be closed by the caller.
+func loadPinProg(object, section, name string, progType bpf.ProgType) (*bpf.Collection, error) {
+	obj, err := bpf.ParseELF(filepath.Join(option.Config.StateDir, object))
 	if err != nil {
-		return fmt.Errorf("Failed to attach %s: %s", bpfObject, err)
+		return nil, err
 	}
-	return nil
-}
 
-// #bpftool cgroup detach $cgrp sock_ops /sys/fs/bpf/$bpfObject
-func bpftoolDetach(bpfObject string) error {
-	prog := "bpftool"
-	bpffs := bpf.GetMapRoot() + "/" + bpfObject
-	cgrp := cgroupRoot
-
-	args := []string{"cgroup", "detach", cgrp, "sock_ops", "pinned", bpffs}
-	log.WithFields(logrus.Fields{
-		"bpftool": prog,
-		"args":    args,
-	}).Debug("Detach BPF Object:")
-	_, err := exec.Command(prog, args...).CombinedOutput()
+	coll, err := bpf.LoadCollection(obj, progType, section)
 	if err != nil {
-		return fmt.Errorf("Failed to detach %s: %s", bpfObject, err)
+		return nil, err
 	}
-	return nil
-
-}
 
-// #bpftool prog load $bpfObject /sys/fs/bpf/sockops
-func bpftoolLoad(bpfObject string, bpfFsFile string) error {
-	sockopsMaps := [...]string{
-		"cilium_lxc",
-		"cilium_ipcache",
-		"cilium_metric",
-		"cilium_events",
-		"sock_ops_map",
-		"cilium_ep_to_policy",
-		"cilium_proxy4", "cilium_proxy6",
-		"cilium_lb6_reverse_nat", "cilium_lb4_reverse_nat",
-		"cilium_lb6_services", "cilium_lb4_services",
-		"cilium_lb6_rr_seq", "cilium_lb4_seq",
-		"cilium_lb6_rr_seq", "cilium_lb4_seq",
+	os.Remove(progPath(name))
+	if err := bpf.ObjPin(coll.Programs[section], progPath(name)); err != nil {
+		coll.Close()
+		coll.FinalizeMigration(false)
+		return nil, err
 	}
 
-	prog := "bpftool"
-	var mapArgList []string
-	bpffs := bpf.GetMapRoot() + "/" + bpfFsFile
-
-	maps, err := ioutil.ReadDir(bpf.GetMapRoot() + "/tc/globals/")
-	if err != nil {
-		return err
-	}
-
-	for _, f := range maps {
-		// Ignore all backing files
-		if strings.HasPrefix(f.Name(), "..") {
-			continue
-		}
-
-		use := func() bool {
-			for _, n := range sockopsMaps {
-				if f.Name() == n {
-					return true
-				}
-			}
-			return false
-		}()
-
-		if !use {
-			continue
-		}
-
-		mapString := []string{"map", "name", f.Name(), "pinned", bpf.GetMapRoot() + "/tc/globals/" + f.Name()}
-		mapArgList = append(mapArgList, mapString...)
-	}
-
-	args := []string{"-m", "prog", "load", bpfObject, bpffs}
-	args = append(args, mapArgList...)
-	log.WithFields(logrus.Fields{
-		"bpftool": prog,
-		"args":    args,
-	}).Debug("Load BPF Object:")
-	_, err = exec.Command(prog, args...).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("Failed to load %s: %s", bpfObject, err)
-	}
-	return nil
+	return coll, nil
 }
 
-// #rm $bpfObject
-func bpftoolUnload(bpfObject string) {
-	bpffs := bpf.GetMapRoot() + "/" + bpfObject
-
-	os.Remove(bpffs)
+// unloadProg removes the pin of the program with the given name, the kernel
+// releases the program once it is no longer attached.
+func unloadProg(name string) {
+	os.Remove(progPath(name))
 }
 
-// #bpftool prog show pinned /sys/fs/bpf/
-func bpftoolGetProgID(progName string) (string, error) {
-	bpffs := bpf.GetMapRoot() + "/" + progName
-	prog := "bpftool"
-
-	args := []string{"prog", "show", "pinned", bpffs}
-	log.WithFields(logrus.Fields{
-		"bpftool": prog,
-		"args":    args,
-	}).Debug("GetProgID:")
-	output, err := exec.Command(prog, args...).CombinedOutput()
+// openCgroup returns a file descriptor for the cgroup2 root
+func openCgroup() (int, error) {
+	fd, err := unix.Open(cgroupRoot, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
 	if err != nil {
-		return "", fmt.Errorf("Failed to load %s: %s", progName, err)
+		return 0, fmt.Errorf("unable to open cgroup %s: %s", cgroupRoot, err)
 	}
-
-	// Scrap the prog_id out of the bpftool output after libbpf is dual licensed
-	// we will use programatic API.
-	s := strings.Fields(string(output))
-	if s[0] == "" {
-		return "", fmt.Errorf("Failed to find prog %s: %s", progName, err)
-	}
-	progID := strings.Split(s[0], ":")
-	return progID[0], nil
+	return fd, nil
 }
 
-// #bpftool prog show pinned /sys/fs/bpf/bpf_sockops
-// #bpftool map show id 21
-func bpftoolGetMapID(progName string, mapName string) (int, error) {
-	bpffs := bpf.GetMapRoot() + "/" + progName
-	prog := "bpftool"
-
-	args := []string{"prog", "show", "pinned", bpffs}
-	log.WithFields(logrus.Fields{
-		"bpftool": prog,
-		"args":    args,
-	}).Debug("GetMapID:")
-	output, err := exec.Command(prog, args...).CombinedOutput()
+// detachProg detaches the pinned program with the given name from the target
+// returned by openTarget.
+func detachProg(name string, openTarget func() (int, error), attachType uint32) error {
+	progFD, err := bpf.ObjGet(progPath(name))
 	if err != nil {
-		return 0, fmt.Errorf("Failed to load %s: %s", progName, err)
-	}
-
-	// Find the mapID out of the bpftool output
-	s := strings.Fields(string(output))
-	for i := range s {
-		if s[i] == "map_ids" {
-			id := strings.Split(s[i+1], ",")
-			for j := range id {
-				args := []string{"map", "show", "id", id[j]}
-				output, err := exec.Command(prog, args...).CombinedOutput()
-				if err != nil {
-					return 0, err
-				}
-
-				if strings.Contains(string(output), mapName) {
-					mapID, _ := strconv.Atoi(id[j])
-					return mapID, nil
-				}
-			}
-			break
+		if os.IsNotExist(err) {
+			return nil
 		}
+		return err
 	}
-	return 0, nil
-}
+	defer bpf.ObjClose(progFD)
 
-// #bpftool map pin id map_id /sys/fs/bpf/tc/globals
-func bpftoolPinMapID(mapName string, mapID int) error {
-	bpffs := bpf.GetMapRoot()
-	globals := bpffs + "/" + mapPrefix + "/"
-	mapFile := globals + mapName
-	prog := "bpftool"
-
-	args := []string{"map", "pin", "id", strconv.Itoa(mapID), mapFile}
-	log.WithFields(logrus.Fields{
-		"bpftool": prog,
-		"args":    args,
-	}).Debug("Map pin:")
-	_, err := exec.Command(prog, args...).CombinedOutput()
+	targetFD, err := openTarget()
 	if err != nil {
-		return fmt.Errorf("Failed to pin map %d(%s): %s", mapID, mapName, err)
+		return err
 	}
+	defer unix.Close(targetFD)
 
-	return nil
+	return bpf.ProgDetach(progFD, targetFD, attachType)
+}
+
+func openSockMap() (int, error) {
+	return bpf.ObjGet(bpf.MapPath(sockMap))
 }
 
 // #clang ... | llc ...
@@ -354,104 +218,71 @@ func bpfCompileProg(src string, dst string) error {
 	return nil
 }
 
-func bpfLoadMapProg(object string, load string) error {
-	sockops := object
-	sockopsObj := option.Config.StateDir + "/" + sockops
-	sockopsLoad := load
-
-	err := bpftoolLoad(sockopsObj, sockopsLoad)
-	if err != nil {
-		return err
-	}
-
-	progID, err := bpftoolGetProgID(load)
-	if err != nil {
-		return err
-	}
-
-	_mapID, err := bpftoolGetMapID("bpf_sockops", sockMap)
-	mapID := strconv.Itoa(_mapID)
-	if err != nil {
-		return err
-	}
-
-	err = bpftoolMapAttach(progID, mapID)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 // SkmsgEnable will compile and attach the SK_MSG programs to the
 // sockmap. After this all sockets added to the sock_ops_map will
 // have sendmsg/sendfile calls running through BPF program.
 func SkmsgEnable() error {
-	err := bpfCompileProg(cIPC, oIPC)
-	if err != nil {
-		log.Error(err)
+	if err := bpfCompileProg(cIPC, oIPC); err != nil {
 		return err
 	}
 
-	err = bpfLoadMapProg(oIPC, eIPC)
+	coll, err := loadPinProg(oIPC, sIPC, eIPC, bpf.ProgTypeSkMsg)
 	if err != nil {
-		log.Error(err)
 		return err
 	}
-	log.Info("Sockmsg Enabled, bpf_redir loaded")
-	return nil
-}
+	defer coll.Close()
 
-// SkmsgDisable "unloads" the SK_MSG program. This simply deletes
-// the file associated with the program.
-func SkmsgDisable() {
-	bpftoolUnload(eIPC)
-	log.Info("Sockmsg Disabled.")
-}
-
-// First user of sockops root is sockops load programs so we ensure the sockops
-// root path no longer changes.
-func bpfLoadAttachProg(object string, load string, mapName string) (int, int, error) {
-	sockopsObj := option.Config.StateDir + "/" + object
-	mapID := 0
-
-	err := bpftoolLoad(sockopsObj, load)
-	if err != nil {
-		return 0, 0, err
+	mapFD, err := openSockMap()
+	if err == nil {
+		defer bpf.ObjClose(mapFD)
+		err = bpf.ProgAttach(coll.Programs[sIPC], mapFD, bpf.BPF_SK_MSG_VERDICT)
 	}
-	err = bpftoolAttach(load)
+	coll.FinalizeMigration(err == nil)
 	if err != nil {
-		return 0, 0, err
+		unloadProg(eIPC)
+		return fmt.Errorf("unable to attach %s to %s: %s", eIPC, sockMap, err)
 	}
 
-	if mapName != "" {
-		mapID, err = bpftoolGetMapID(load, mapName)
-		if err != nil {
-			return 0, mapID, err
-		}
+	log.Info("Sockmsg Enabled, bpf_redir loaded")
+	return nil
+}
 
-		err = bpftoolPinMapID(mapName, mapID)
-		if err != nil {
-			return 0, mapID, err
-		}
+// SkmsgDisable detaches the SK_MSG program from the sockmap and unloads it.
+func SkmsgDisable() {
+	if err := detachProg(eIPC, openSockMap, bpf.BPF_SK_MSG_VERDICT); err != nil {
+		log.WithError(err).Debug("Unable to detach bpf_redir")
 	}
-	return 0, mapID, nil
+	unloadProg(eIPC)
+	log.Info("Sockmsg Disabled.")
 }
 
 // SockmapEnable will compile sockops programs and attach the sockops programs
 // to the cgroup. After this all TCP connect events will be filtered by a BPF
 // sockops program.
 func SockmapEnable() error {
-	err := bpfCompileProg(cSockops, oSockops)
-	if err != nil {
-		log.Error(err)
+	if err := bpfCompileProg(cSockops, oSockops); err != nil {
 		return err
 	}
-	progID, mapID, err := bpfLoadAttachProg(oSockops, eSockops, sockMap)
+
+	coll, err := loadPinProg(oSockops, sSockops, eSockops, bpf.ProgTypeSockOps)
 	if err != nil {
-		log.Error(err)
 		return err
 	}
-	log.Infof("Sockmap Enabled: bpf_sockops prog_id %d and map_id %d loaded", progID, mapID)
+	defer coll.Close()
+
+	cgroupFD, err := openCgroup()
+	if err == nil {
+		defer unix.Close(cgroupFD)
+		err = bpf.ProgAttach(coll.Programs[sSockops], cgroupFD, bpf.BPF_CGROUP_SOCK_OPS)
+	}
+	coll.FinalizeMigration(err == nil)
+	if err != nil {
+		unloadProg(eSockops)
+		os.Remove(bpf.MapPath(sockMap))
+		return fmt.Errorf("unable to attach %s to %s: %s", eSockops, cgroupRoot, err)
+	}
+
+	log.Infof("Sockmap Enabled: bpf_sockops attached to %s", cgroupRoot)
 	return nil
 }
 
@@ -459,9 +290,51 @@ func SockmapEnable() error {
 // all the programs and maps associated with it. Here "unload" just means
 // deleting the file associated with the map.
 func SockmapDisable() {
-	mapName := mapPrefix + "/" + sockMap
-	bpftoolDetach(eSockops)
-	bpftoolUnload(eSockops)
-	bpftoolUnload(mapName)
+	if err := detachProg(eSockops, openCgroup, bpf.BPF_CGROUP_SOCK_OPS); err != nil {
+		log.WithError(err).Debug("Unable to detach bpf_sockops")
+	}
+	unloadProg(eSockops)
+	os.Remove(bpf.MapPath(sockMap))
 	log.Info("Sockmap disabled.")
 }
+
+// Enable attaches the sockops program to the cgroup and the SK_MSG program to
+// the sockmap. If any step fails, all programs attached so far are removed
+// again. The outcome is reported by Status.
+func Enable() error {
+	err := SockmapEnable()
+	if err == nil {
+		if err = SkmsgEnable(); err != nil {
+			SockmapDisable()
+		}
+	}
+
+	if err != nil {
+		setStatus(models.StatusStateFailure, err.Error())
+		return err
+	}
+
+	setStatus(models.StatusStateOk, fmt.Sprintf("Attached to cgroup %s", cgroupRoot))
+	return nil
+}
+
+// Disable removes all sockops and SK_MSG programs.
+func Disable() {
+	SkmsgDisable()
+	SockmapDisable()
+	setStatus(models.StatusStateDisabled, "")
+}
+
+func setStatus(state, msg string) {
+	statusMutex.Lock()
+	status = models.Status{State: state, Msg: msg}
+	statusMutex.Unlock()
+}
+
+// Status returns the state of sockmap acceleration
+func Status() *models.Status {
+	statusMutex.RLock()
+	defer statusMutex.RUnlock()
+	s := status
+	return &s
+}
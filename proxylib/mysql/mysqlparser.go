@@ -0,0 +1,617 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+
+	. "github.com/cilium/cilium/proxylib/proxylib"
+	"github.com/cilium/cilium/proxylib/sqlstmt"
+
+	"github.com/cilium/proxy/go/cilium/api"
+	log "github.com/sirupsen/logrus"
+)
+
+//
+// MySQL client/server protocol Parser
+//
+// Spec: https://dev.mysql.com/doc/dev/mysql-server/latest/PAGE_PROTOCOL.html
+//
+// The handshake response of the client is matched on the login user and the
+// default schema. COM_QUERY and COM_STMT_PREPARE statements are in addition
+// matched on the statement action and the tables the statement refers to (see
+// package sqlstmt). Examples:
+// user = 'app', schema = 'shop'
+// user = 'app', action = 'select', table = '^(shop\.)?orders$'
+//
+// Changing the default schema with COM_INIT_DB or a 'use' statement, and
+// changing the user with COM_CHANGE_USER, is matched like a login. Other
+// commands are allowed once the handshake has been allowed. A query with
+// multiple statements is allowed only if all statements are allowed.
+//
+// Denied requests are answered with an ERR packet. After a denied handshake
+// all data from the client is dropped. The SSL capability is removed from the
+// server greeting, as the proxy can't enforce policy on encrypted
+// connections, so clients requiring SSL will fail to connect.
+
+// MySQLRule matches against MySQL requests
+type MySQLRule struct {
+	userExact          string
+	schemaExact        string
+	actionExact        string
+	tableRegexCompiled *regexp.Regexp
+}
+
+// mysqlRequest is the request data passed to MySQLRule.Matches()
+type mysqlRequest struct {
+	user   string
+	schema string
+	login  bool
+	action string
+	tables []string
+}
+
+const (
+	mysqlHdrLen        = 4
+	mysqlMaxPayloadLen = 1<<24 - 1
+
+	// capability flags
+	clientConnectWithDB              = 0x00000008
+	clientProtocol41                 = 0x00000200
+	clientSSL                        = 0x00000800
+	clientSecureConnection           = 0x00008000
+	clientPluginAuthLenencClientData = 0x00200000
+
+	// commands
+	comInitDB      = 0x02
+	comQuery       = 0x03
+	comChangeUser  = 0x11
+	comStmtPrepare = 0x16
+
+	// first byte of response packets
+	okPacket  = 0x00
+	errPacket = 0xff
+
+	// error codes
+	erDBAccessDenied    = 1044
+	erAccessDenied      = 1045
+	erTableAccessDenied = 1142
+)
+
+type mysqlPhase int
+
+const (
+	phaseGreeting  mysqlPhase = iota // waiting for the server greeting
+	phaseHandshake                   // waiting for the handshake response
+	phaseAuth                        // authentication in progress
+	phaseCommand                     // command phase
+	phaseRejected                    // handshake denied
+)
+
+// mysqlActions are the statement actions rules may match on
+var mysqlActions = map[string]struct{}{
+	"alter": {}, "analyze": {}, "begin": {}, "binlog": {}, "cache": {},
+	"call": {}, "change": {}, "check": {}, "checksum": {}, "commit": {},
+	"create": {}, "deallocate": {}, "delete": {}, "describe": {}, "do": {},
+	"drop": {}, "execute": {}, "explain": {}, "flush": {}, "grant": {},
+	"handler": {}, "help": {}, "insert": {}, "install": {}, "kill": {},
+	"load": {}, "lock": {}, "optimize": {}, "prepare": {}, "purge": {},
+	"release": {}, "rename": {}, "repair": {}, "replace": {}, "reset": {},
+	"revoke": {}, "rollback": {}, "savepoint": {}, "select": {}, "set": {},
+	"show": {}, "shutdown": {}, "start": {}, "stop": {}, "truncate": {},
+	"uninstall": {}, "unlock": {}, "update": {}, "use": {}, "xa": {},
+}
+
+// Matches returns true if the MySQLRule matches the request
+func (rule *MySQLRule) Matches(data interface{}) bool {
+	req, ok := data.(mysqlRequest)
+	if !ok {
+		log.Warning("Matches() called with type other than mysqlRequest")
+		return false
+	}
+	log.Debugf("Policy Match test for user '%s', schema '%s', action '%s', tables %v",
+		req.user, req.schema, req.action, req.tables)
+
+	if rule.userExact != "" && rule.userExact != req.user {
+		log.Debugf("MySQLRule: user mismatch %s, %s", rule.userExact, req.user)
+		return false
+	}
+	if rule.schemaExact != "" && rule.schemaExact != req.schema {
+		log.Debugf("MySQLRule: schema mismatch %s, %s", rule.schemaExact, req.schema)
+		return false
+	}
+	if req.login {
+		return true
+	}
+	if rule.actionExact != "" && rule.actionExact != req.action {
+		log.Debugf("MySQLRule: action mismatch %s, %s", rule.actionExact, req.action)
+		return false
+	}
+	if rule.tableRegexCompiled != nil {
+		if len(req.tables) == 0 {
+			log.Debugf("MySQLRule: no tables to match '%v'", rule.tableRegexCompiled)
+			return false
+		}
+		for _, table := range req.tables {
+			if !rule.tableRegexCompiled.MatchString(table) {
+				log.Debugf("MySQLRule: table mismatch '%v', '%s'", rule.tableRegexCompiled, table)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MySQLRuleParser parses protobuf L7 rules to enforcement objects
+// May panic
+func MySQLRuleParser(rule *cilium.PortNetworkPolicyRule) []L7NetworkPolicyRule {
+	var rules []L7NetworkPolicyRule
+	l7Rules := rule.GetL7Rules()
+	if l7Rules == nil {
+		return rules
+	}
+	for _, l7Rule := range l7Rules.GetL7Rules() {
+		var mr MySQLRule
+		for k, v := range l7Rule.Rule {
+			switch k {
+			case "user":
+				mr.userExact = v
+			case "schema":
+				mr.schemaExact = v
+			case "action":
+				mr.actionExact = strings.ToLower(v)
+			case "table":
+				if v != "" {
+					mr.tableRegexCompiled = regexp.MustCompile(v)
+				}
+			default:
+				ParseError(fmt.Sprintf("Unsupported key: %s", k), rule)
+			}
+		}
+		if mr.actionExact != "" {
+			if _, ok := mysqlActions[mr.actionExact]; !ok {
+				ParseError(fmt.Sprintf("Unable to parse L7 mysql rule with invalid action: '%s'", mr.actionExact), rule)
+			}
+		}
+		log.Debugf("Parsed MySQLRule: %v", mr)
+		rules = append(rules, &mr)
+	}
+	return rules
+}
+
+// errPacketMsg returns an ERR packet with the given sequence id
+func errPacketMsg(seq byte, code uint16, sqlState, message string) []byte {
+	msg := []byte{0, 0, 0, seq, errPacket, byte(code), byte(code >> 8), '#'}
+	msg = append(msg, sqlState...)
+	msg = append(msg, message...)
+	putPayloadLen(msg, len(msg)-mysqlHdrLen)
+	return msg
+}
+
+func putPayloadLen(packet []byte, length int) {
+	packet[0] = byte(length)
+	packet[1] = byte(length >> 8)
+	packet[2] = byte(length >> 16)
+}
+
+func payloadLen(packet []byte) int {
+	return int(packet[0]) | int(packet[1])<<8 | int(packet[2])<<16
+}
+
+// cString returns the null terminated string at the beginning of 'data' and
+// the remaining data, or false if 'data' has no null terminator
+func cString(data []byte) (string, []byte, bool) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", nil, false
+	}
+	return string(data[:end]), data[end+1:], true
+}
+
+// lenEncInt returns the length encoded integer at the beginning of 'data'
+// and the remaining data, or false if 'data' is too short
+func lenEncInt(data []byte) (uint64, []byte, bool) {
+	if len(data) == 0 {
+		return 0, nil, false
+	}
+	var n int
+	switch data[0] {
+	case 0xfc:
+		n = 2
+	case 0xfd:
+		n = 3
+	case 0xfe:
+		n = 8
+	default:
+		return uint64(data[0]), data[1:], true
+	}
+	if len(data) < 1+n {
+		return 0, nil, false
+	}
+	var value uint64
+	for i := n; i > 0; i-- {
+		value = value<<8 | uint64(data[i])
+	}
+	return value, data[1+n:], true
+}
+
+type MySQLParserFactory struct{}
+
+var mysqlParserFactory *MySQLParserFactory
+
+func init() {
+	log.Info("init(): Registering mysqlParserFactory")
+	RegisterParserFactory("mysql", mysqlParserFactory)
+	RegisterL7RuleParser("mysql", MySQLRuleParser)
+}
+
+type MySQLParser struct {
+	connection *Connection
+
+	phase mysqlPhase
+
+	// set when the rewritten server greeting has been injected
+	greetingInjected bool
+
+	user   string
+	schema string
+
+	// user and schema to switch to once the server accepts the
+	// current command
+	pendingUser   string
+	pendingSchema string
+	pending       bool
+
+	// set while the client sends a request larger than a single packet
+	continuation   bool
+	continuationOp OpType
+}
+
+func (pf *MySQLParserFactory) Create(connection *Connection) Parser {
+	log.Debugf("MySQLParserFactory: Create: %v", connection)
+
+	return &MySQLParser{connection: connection}
+}
+
+func (p *MySQLParser) logEntry(user, schema, action string, tables []string) *cilium.LogEntry_GenericL7 {
+	fields := map[string]string{
+		"user":   user,
+		"schema": schema,
+	}
+	if action != "" {
+		fields["action"] = action
+	}
+	if len(tables) > 0 {
+		fields["tables"] = strings.Join(tables, ", ")
+	}
+	return &cilium.LogEntry_GenericL7{
+		GenericL7: &cilium.L7LogEntry{
+			Proto:  "mysql",
+			Fields: fields,
+		},
+	}
+}
+
+func (p *MySQLParser) OnData(reply, endStream bool, dataArray [][]byte) (OpType, int) {
+	// inefficient, but simple for now
+	data := bytes.Join(dataArray, []byte{})
+
+	if len(data) == 0 {
+		return NOP, 0
+	}
+	if !reply && p.phase == phaseRejected {
+		return DROP, len(data)
+	}
+	if len(data) < mysqlHdrLen {
+		needs := mysqlHdrLen - len(data)
+		log.Debugf("Did not receive full header, need %d more bytes", needs)
+		return MORE, needs
+	}
+	length := payloadLen(data) + mysqlHdrLen
+
+	if reply {
+		return p.onReply(data, length)
+	}
+
+	if p.continuation {
+		// the remainder of a request is passed or dropped as the
+		// first packet
+		p.continuation = payloadLen(data) == mysqlMaxPayloadLen
+		return p.continuationOp, length
+	}
+
+	op, n := p.onRequest(data, length)
+	if (op == PASS || op == DROP) && payloadLen(data) == mysqlMaxPayloadLen {
+		p.continuation = true
+		p.continuationOp = op
+	}
+	return op, n
+}
+
+// needPacket returns MORE if 'data' does not hold the full packet
+func needPacket(data []byte, length int) (OpType, int) {
+	if len(data) < length {
+		needs := length - len(data)
+		log.Debugf("Did not receive full packet, need %d more bytes", needs)
+		return MORE, needs
+	}
+	return PASS, length
+}
+
+// onReply handles the packets sent by the server
+func (p *MySQLParser) onReply(data []byte, length int) (OpType, int) {
+	switch p.phase {
+	case phaseGreeting:
+		if op, n := needPacket(data, length); op != PASS {
+			return op, n
+		}
+		return p.onGreeting(data[:length])
+	case phaseAuth:
+		if len(data) <= mysqlHdrLen {
+			return MORE, 1
+		}
+		switch data[mysqlHdrLen] {
+		case okPacket:
+			p.commitPending()
+			p.phase = phaseCommand
+		case errPacket:
+			p.pending = false
+			if p.user != "" {
+				// failed COM_CHANGE_USER, the server closes
+				// the connection
+				p.phase = phaseCommand
+			}
+		}
+	case phaseCommand:
+		if p.pending && data[3] == 1 {
+			if len(data) <= mysqlHdrLen {
+				return MORE, 1
+			}
+			if data[mysqlHdrLen] == okPacket {
+				p.commitPending()
+			}
+			p.pending = false
+		}
+	}
+	return PASS, length
+}
+
+func (p *MySQLParser) commitPending() {
+	if p.pending {
+		p.user = p.pendingUser
+		p.schema = p.pendingSchema
+		p.pending = false
+	}
+}
+
+// onGreeting removes the SSL capability from the server greeting
+func (p *MySQLParser) onGreeting(packet []byte) (OpType, int) {
+	payload := packet[mysqlHdrLen:]
+	if payload[0] == errPacket {
+		return PASS, len(packet)
+	}
+	if p.greetingInjected {
+		p.phase = phaseHandshake
+		return DROP, len(packet)
+	}
+	versionEnd := bytes.IndexByte(payload[1:], 0)
+	if versionEnd < 0 {
+		log.Error("Invalid server greeting")
+		return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+	}
+	// protocol version, server version, connection id, auth plugin data, filler
+	capsOffset := mysqlHdrLen + 1 + versionEnd + 1 + 4 + 8 + 1
+	if len(packet) < capsOffset+2 {
+		log.Error("Invalid server greeting")
+		return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+	}
+	caps := binary.LittleEndian.Uint16(packet[capsOffset:])
+	if caps&clientSSL == 0 {
+		p.phase = phaseHandshake
+		return PASS, len(packet)
+	}
+	greeting := make([]byte, len(packet))
+	copy(greeting, packet)
+	binary.LittleEndian.PutUint16(greeting[capsOffset:], caps&^clientSSL)
+	p.greetingInjected = true
+	return INJECT, p.connection.Inject(true, greeting)
+}
+
+// onRequest handles the packets sent by the client
+func (p *MySQLParser) onRequest(data []byte, length int) (OpType, int) {
+	op := PASS
+	switch p.phase {
+	case phaseGreeting, phaseHandshake:
+		if op, n := needPacket(data, length); op != PASS {
+			return op, n
+		}
+		op = p.onHandshakeResponse(data[:length])
+	case phaseCommand:
+		if data[3] != 0 {
+			// not a command, e.g. the contents of a local file
+			break
+		}
+		if len(data) <= mysqlHdrLen {
+			return MORE, 1
+		}
+		switch data[mysqlHdrLen] {
+		case comQuery, comStmtPrepare, comInitDB, comChangeUser:
+			if op, n := needPacket(data, length); op != PASS {
+				return op, n
+			}
+			op = p.onCommand(data[:length])
+		}
+	}
+	if op == ERROR {
+		return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+	}
+	return op, length
+}
+
+// deny injects an ERR packet for the denied request
+func (p *MySQLParser) deny(seq byte, code uint16, sqlState string) {
+	p.connection.Inject(true, errPacketMsg(seq+1, code, sqlState, "Access denied by policy"))
+}
+
+// login matches a login of 'user' to 'schema' against the policy
+func (p *MySQLParser) login(user, schema string) bool {
+	req := mysqlRequest{user: user, schema: schema, login: true}
+	allowed := p.connection.Matches(req)
+	entryType := cilium.EntryType_Request
+	if !allowed {
+		entryType = cilium.EntryType_Denied
+	}
+	p.connection.Log(entryType, p.logEntry(user, schema, "", nil))
+	return allowed
+}
+
+// onHandshakeResponse matches the login user and default schema
+func (p *MySQLParser) onHandshakeResponse(packet []byte) OpType {
+	payload := packet[mysqlHdrLen:]
+	if len(payload) < 32 {
+		log.Error("Invalid handshake response")
+		return ERROR
+	}
+	caps := binary.LittleEndian.Uint32(payload)
+	if caps&clientProtocol41 == 0 {
+		log.Error("Unsupported protocol version")
+		return ERROR
+	}
+	if len(payload) == 32 && caps&clientSSL != 0 {
+		log.Error("SSL request received after SSL capability was removed")
+		return ERROR
+	}
+
+	// capabilities, max packet size, character set, filler
+	user, rest, ok := cString(payload[32:])
+	if !ok {
+		log.Error("Invalid handshake response")
+		return ERROR
+	}
+	rest, ok = skipAuthResponse(rest, caps)
+	if !ok {
+		log.Error("Invalid handshake response")
+		return ERROR
+	}
+	schema := ""
+	if caps&clientConnectWithDB != 0 {
+		schema, _, _ = cString(rest)
+	}
+
+	if !p.login(user, schema) {
+		p.phase = phaseRejected
+		p.deny(packet[3], erAccessDenied, "28000")
+		return DROP
+	}
+	p.pendingUser, p.pendingSchema, p.pending = user, schema, true
+	p.phase = phaseAuth
+	return PASS
+}
+
+// skipAuthResponse skips the authentication response in a handshake
+// response or COM_CHANGE_USER
+func skipAuthResponse(data []byte, caps uint32) ([]byte, bool) {
+	switch {
+	case caps&clientPluginAuthLenencClientData != 0:
+		n, rest, ok := lenEncInt(data)
+		if !ok || uint64(len(rest)) < n {
+			return nil, false
+		}
+		return rest[n:], true
+	case caps&clientSecureConnection != 0:
+		if len(data) < 1 || len(data) < 1+int(data[0]) {
+			return nil, false
+		}
+		return data[1+int(data[0]):], true
+	}
+	_, rest, ok := cString(data)
+	return rest, ok
+}
+
+// onCommand matches the commands subject to policy
+func (p *MySQLParser) onCommand(packet []byte) OpType {
+	seq := packet[3]
+	payload := packet[mysqlHdrLen:]
+	switch payload[0] {
+	case comInitDB:
+		schema := string(payload[1:])
+		if !p.login(p.user, schema) {
+			p.deny(seq, erDBAccessDenied, "42000")
+			return DROP
+		}
+		p.pendingUser, p.pendingSchema, p.pending = p.user, schema, true
+		return PASS
+	case comChangeUser:
+		user, rest, ok := cString(payload[1:])
+		if !ok {
+			log.Error("Invalid COM_CHANGE_USER")
+			return ERROR
+		}
+		rest, ok = skipAuthResponse(rest, clientSecureConnection)
+		if !ok {
+			log.Error("Invalid COM_CHANGE_USER")
+			return ERROR
+		}
+		schema, _, _ := cString(rest)
+		if !p.login(user, schema) {
+			p.deny(seq, erAccessDenied, "28000")
+			return DROP
+		}
+		p.pendingUser, p.pendingSchema, p.pending = user, schema, true
+		p.phase = phaseAuth
+		return PASS
+	}
+
+	// COM_QUERY, COM_STMT_PREPARE
+	if len(payload) == mysqlMaxPayloadLen {
+		log.Error("Statements larger than a single packet are not supported")
+		return ERROR
+	}
+	allowed := true
+	schema := p.schema
+	for _, statement := range sqlstmt.Classify(string(payload[1:]), sqlstmt.MySQL) {
+		if statement.Action == "use" && statement.Schema != "" {
+			if !p.login(p.user, statement.Schema) {
+				allowed = false
+			}
+			schema = statement.Schema
+			continue
+		}
+		req := mysqlRequest{
+			user:   p.user,
+			schema: schema,
+			action: statement.Action,
+			tables: statement.Tables,
+		}
+		entryType := cilium.EntryType_Request
+		if !p.connection.Matches(req) {
+			allowed = false
+			entryType = cilium.EntryType_Denied
+		}
+		p.connection.Log(entryType, p.logEntry(p.user, schema, statement.Action, statement.Tables))
+	}
+	if !allowed {
+		p.deny(seq, erTableAccessDenied, "42000")
+		return DROP
+	}
+	if schema != p.schema {
+		p.pendingUser, p.pendingSchema, p.pending = p.user, schema, true
+	}
+	return PASS
+}
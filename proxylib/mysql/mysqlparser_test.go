@@ -0,0 +1,284 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package mysql
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/cilium/cilium/proxylib/accesslog"
+	"github.com/cilium/cilium/proxylib/proxylib"
+	"github.com/cilium/cilium/proxylib/test"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type MySQLSuite struct {
+	logServer *test.AccessLogServer
+	ins       *proxylib.Instance
+}
+
+var _ = Suite(&MySQLSuite{})
+
+// Set up access log server and Library instance for all the test cases
+func (s *MySQLSuite) SetUpSuite(c *C) {
+	s.logServer = test.StartAccessLogServer("access_log.sock", 10)
+	c.Assert(s.logServer, Not(IsNil))
+	s.ins = proxylib.NewInstance("node1", accesslog.NewClient(s.logServer.Path))
+	c.Assert(s.ins, Not(IsNil))
+}
+
+func (s *MySQLSuite) checkAccessLogs(c *C, expPasses, expDrops int) {
+	passes, drops := s.logServer.Clear()
+	c.Check(passes, Equals, expPasses, Commentf("Unxpected number of passed access log messages"))
+	c.Check(drops, Equals, expDrops, Commentf("Unxpected number of denied access log messages"))
+}
+
+func (s *MySQLSuite) TearDownTest(c *C) {
+	s.logServer.Clear()
+}
+
+func (s *MySQLSuite) TearDownSuite(c *C) {
+	s.logServer.Close()
+}
+
+const (
+	// MySQL 5.7 server greeting with the SSL capability
+	greetingHex = "4a0000000a352e372e323500080000003a2f6b1c4d5e6f7000ffff210200ffc1150000000000000000000011223344556677889900aabb006d7973716c5f6e61746976655f70617373776f726400"
+	// server greeting with the SSL capability removed
+	greetingNoSSLHex = "4a0000000a352e372e323500080000003a2f6b1c4d5e6f7000fff7210200ffc1150000000000000000000011223344556677889900aabb006d7973716c5f6e61746976655f70617373776f726400"
+	// handshake response of user 'app' with default schema 'shop'
+	handshakeAppHex = "5400000109a22b00000000012100000000000000000000000000000000000000000000006170700014000102030405060708090a0b0c0d0e0f1011121373686f70006d7973716c5f6e61746976655f70617373776f726400"
+	// handshake response of user 'root' with default schema 'shop'
+	handshakeRootHex = "5500000109a22b0000000001210000000000000000000000000000000000000000000000726f6f740014000102030405060708090a0b0c0d0e0f1011121373686f70006d7973716c5f6e61746976655f70617373776f726400"
+	// OK packet after authentication
+	authOKHex = "0700000200000002000000"
+	// OK packet in response to a command
+	okHex = "0700000100000002000000"
+)
+
+const mysqlPolicy = `
+		name: "mp1"
+		policy: 2
+		ingress_per_port_policies: <
+		  port: 80
+		  rules: <
+		    remote_policies: 1
+		    l7_proto: "mysql"
+		    l7_rules: <
+		      l7_rules: <
+			rule: <
+			  key: "user"
+			  value: "app"
+			>
+			rule: <
+			  key: "schema"
+			  value: "shop"
+			>
+			rule: <
+			  key: "action"
+			  value: "SELECT"
+			>
+			rule: <
+			  key: "table"
+			  value: "^orders$"
+			>
+		      >
+		      l7_rules: <
+			rule: <
+			  key: "user"
+			  value: "app"
+			>
+			rule: <
+			  key: "schema"
+			  value: "inventory"
+			>
+		      >
+		    >
+		  >
+		>
+		`
+
+// util function used for MySQL tests, as we have recorded packets as hex
+// strings
+func hexData(c *C, dataHex ...string) [][]byte {
+	data := make([][]byte, 0, len(dataHex))
+	for i := range dataHex {
+		dataRaw, err := hex.DecodeString(dataHex[i])
+		c.Assert(err, IsNil)
+		data = append(data, dataRaw)
+	}
+	return data
+}
+
+// command returns a command packet with sequence id 0
+func command(cmd byte, arg string) []byte {
+	packet := append([]byte{0, 0, 0, 0, cmd}, arg...)
+	putPayloadLen(packet, len(packet)-mysqlHdrLen)
+	return packet
+}
+
+// startConnection returns a new connection for which the handshake of
+// user 'app' has completed
+func (s *MySQLSuite) startConnection(c *C) *proxylib.Connection {
+	s.ins.CheckInsertPolicyText(c, "1", []string{mysqlPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "mysql", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "mp1")
+
+	data := hexData(c, greetingHex)
+	conn.CheckOnDataOK(c, true, false, &data, hexData(c, greetingNoSSLHex)[0],
+		proxylib.INJECT, len(data[0]),
+		proxylib.DROP, len(data[0]))
+
+	data = hexData(c, handshakeAppHex)
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(data[0]))
+
+	data = hexData(c, authOKHex)
+	conn.CheckOnDataOK(c, true, false, &data, []byte{},
+		proxylib.PASS, len(data[0]))
+	s.checkAccessLogs(c, 1, 0)
+	return conn
+}
+
+func (s *MySQLSuite) TestMySQLOnDataPartialPacket(c *C) {
+	s.ins.CheckInsertPolicyText(c, "1", []string{mysqlPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "mysql", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "mp1")
+
+	data := hexData(c, greetingHex[:6])
+	conn.CheckOnDataOK(c, true, false, &data, []byte{},
+		proxylib.MORE, 1)
+
+	data = hexData(c, greetingHex[:40])
+	conn.CheckOnDataOK(c, true, false, &data, []byte{},
+		proxylib.MORE, len(greetingHex)/2-20)
+}
+
+func (s *MySQLSuite) TestMySQLOnDataHandshakeDenied(c *C) {
+	s.ins.CheckInsertPolicyText(c, "1", []string{mysqlPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "mysql", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "mp1")
+
+	data := hexData(c, greetingHex)
+	conn.CheckOnDataOK(c, true, false, &data, hexData(c, greetingNoSSLHex)[0],
+		proxylib.INJECT, len(data[0]),
+		proxylib.DROP, len(data[0]))
+
+	data = hexData(c, handshakeRootHex)
+	conn.CheckOnDataOK(c, false, false, &data,
+		errPacketMsg(2, erAccessDenied, "28000", "Access denied by policy"),
+		proxylib.DROP, len(data[0]))
+
+	// everything after a denied handshake is dropped
+	query := command(comQuery, "SELECT 1")
+	data = [][]byte{query}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.DROP, len(query))
+	s.checkAccessLogs(c, 0, 1)
+}
+
+func (s *MySQLSuite) TestMySQLOnDataQuery(c *C) {
+	conn := s.startConnection(c)
+
+	query := command(comQuery, "SELECT * FROM orders WHERE id = 1")
+	data := [][]byte{query}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(query))
+
+	denied := errPacketMsg(1, erTableAccessDenied, "42000", "Access denied by policy")
+	for _, q := range []string{
+		"DROP TABLE orders",
+		"SELECT * FROM customers",
+		"SELECT * FROM orders; DELETE FROM orders",
+		"SELECT LOAD_FILE('/etc/passwd')",
+		"WITH x AS (DELETE FROM orders) SELECT * FROM x",
+	} {
+		query = command(comQuery, q)
+		data = [][]byte{query}
+		conn.CheckOnDataOK(c, false, false, &data, denied,
+			proxylib.DROP, len(query))
+	}
+
+	prepare := command(comStmtPrepare, "UPDATE orders SET total = ?")
+	data = [][]byte{prepare}
+	conn.CheckOnDataOK(c, false, false, &data, denied,
+		proxylib.DROP, len(prepare))
+
+	ping := command(0x0e, "")
+	data = [][]byte{ping}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(ping))
+	s.checkAccessLogs(c, 2, 6)
+}
+
+func (s *MySQLSuite) TestMySQLOnDataChangeSchema(c *C) {
+	conn := s.startConnection(c)
+
+	initDB := command(comInitDB, "mysql")
+	data := [][]byte{initDB}
+	conn.CheckOnDataOK(c, false, false, &data,
+		errPacketMsg(1, erDBAccessDenied, "42000", "Access denied by policy"),
+		proxylib.DROP, len(initDB))
+
+	initDB = command(comInitDB, "inventory")
+	data = [][]byte{initDB}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(initDB))
+	data = hexData(c, okHex)
+	conn.CheckOnDataOK(c, true, false, &data, []byte{},
+		proxylib.PASS, len(data[0]))
+	s.checkAccessLogs(c, 1, 1)
+
+	// all statements are allowed in schema 'inventory'
+	query := command(comQuery, "DROP TABLE stock")
+	data = [][]byte{query}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(query))
+
+	// 'use' switches the schema for the following statements
+	query = command(comQuery, "USE shop; DROP TABLE orders")
+	data = [][]byte{query}
+	conn.CheckOnDataOK(c, false, false, &data,
+		errPacketMsg(1, erTableAccessDenied, "42000", "Access denied by policy"),
+		proxylib.DROP, len(query))
+	s.checkAccessLogs(c, 2, 1)
+}
+
+func (s *MySQLSuite) TestMySQLRuleParserInvalidAction(c *C) {
+	err := s.ins.InsertPolicyText("1", []string{`
+		name: "mp2"
+		policy: 2
+		ingress_per_port_policies: <
+		  port: 80
+		  rules: <
+		    l7_proto: "mysql"
+		    l7_rules: <
+		      l7_rules: <
+			rule: <
+			  key: "action"
+			  value: "destroy"
+			>
+		      >
+		    >
+		  >
+		>
+		`}, "update")
+	c.Assert(err, Not(IsNil))
+}
@@ -0,0 +1,566 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+
+	. "github.com/cilium/cilium/proxylib/proxylib"
+	"github.com/cilium/cilium/proxylib/sqlstmt"
+
+	"github.com/cilium/proxy/go/cilium/api"
+	log "github.com/sirupsen/logrus"
+)
+
+//
+// PostgreSQL frontend/backend protocol v3 Parser
+//
+// Spec: https://www.postgresql.org/docs/current/protocol.html
+//
+// The startup message is matched on the user and database, statements sent
+// via the simple query protocol ('Query' messages) and the extended query
+// protocol ('Parse' messages) are in addition matched on the statement action
+// and the tables the statement refers to (see package sqlstmt). Examples:
+// user = 'app', database = 'shop'
+// user = 'app', action = 'select', table = '^(public\.)?orders$'
+//
+// A query message with multiple statements is allowed only if all statements
+// are allowed. 'FunctionCall' messages, which call server functions by OID
+// and thus can't be matched on actions and tables, are always denied. The
+// remaining messages of the extended query, COPY and authentication flows are
+// allowed once the startup message has been allowed. Connections sending any
+// other message are closed.
+//
+// A denied startup message is answered with a FATAL error, after which all
+// data from the client is dropped. A denied query message is answered with an
+// error followed by 'ReadyForQuery', as if the server had rejected the query.
+// After a denied 'Parse' message all messages are dropped until the next
+// 'Sync', and the error is returned right before the 'ReadyForQuery' the
+// server sends for the 'Sync', as the server would do if the 'Parse' failed.
+//
+// Replies are parsed only to keep track of which request each
+// 'ReadyForQuery' belongs to, so that errors for denied requests can be
+// injected in order. SSL and GSSAPI encryption requests are declined, as the
+// proxy can't enforce policy on encrypted connections.
+
+// PostgresRule matches against PostgreSQL requests
+type PostgresRule struct {
+	userExact          string
+	databaseExact      string
+	actionExact        string
+	tableRegexCompiled *regexp.Regexp
+}
+
+// postgresRequest is the request data passed to PostgresRule.Matches()
+type postgresRequest struct {
+	user     string
+	database string
+	startup  bool
+	action   string
+	tables   []string
+}
+
+const (
+	pgHdrLen        = 5
+	pgMaxStartupLen = 10000   // MAX_STARTUP_PACKET_LENGTH in the server
+	pgMaxMessageLen = 1 << 30 // 1 GB, maximum length of a field value
+
+	pgProtocolVersion3  = 3 << 16
+	pgCancelRequestCode = 80877102
+	pgSSLRequestCode    = 80877103
+	pgGSSENCRequestCode = 80877104
+)
+
+// pgFrontendMessages are the message types sent by the client after the
+// startup message which are passed without matching them against the policy,
+// as they only refer to statements matched in a preceding 'Parse' or 'Query'
+// message, or are part of the authentication or COPY flows.
+var pgFrontendMessages = map[byte]struct{}{
+	'B': {}, // Bind
+	'C': {}, // Close
+	'D': {}, // Describe
+	'E': {}, // Execute
+	'H': {}, // Flush
+	'S': {}, // Sync
+	'X': {}, // Terminate
+	'c': {}, // CopyDone
+	'd': {}, // CopyData
+	'f': {}, // CopyFail
+	'p': {}, // PasswordMessage, SASL and GSSAPI responses
+}
+
+// pgActions are the statement actions rules may match on
+var pgActions = map[string]struct{}{
+	"abort": {}, "alter": {}, "analyze": {}, "begin": {}, "call": {},
+	"checkpoint": {}, "close": {}, "cluster": {}, "comment": {}, "commit": {},
+	"copy": {}, "create": {}, "deallocate": {}, "declare": {}, "delete": {},
+	"discard": {}, "do": {}, "drop": {}, "end": {}, "execute": {},
+	"explain": {}, "fetch": {}, "grant": {}, "import": {}, "insert": {},
+	"listen": {}, "load": {}, "lock": {}, "move": {}, "notify": {},
+	"prepare": {}, "reassign": {}, "refresh": {}, "reindex": {},
+	"release": {}, "reset": {}, "revoke": {}, "rollback": {}, "savepoint": {},
+	"security": {}, "select": {}, "set": {}, "show": {}, "start": {},
+	"truncate": {}, "unlisten": {}, "update": {}, "vacuum": {},
+}
+
+// Matches returns true if the PostgresRule matches the request
+func (rule *PostgresRule) Matches(data interface{}) bool {
+	req, ok := data.(postgresRequest)
+	if !ok {
+		log.Warning("Matches() called with type other than postgresRequest")
+		return false
+	}
+	log.Debugf("Policy Match test for user '%s', database '%s', action '%s', tables %v",
+		req.user, req.database, req.action, req.tables)
+
+	if rule.userExact != "" && rule.userExact != req.user {
+		log.Debugf("PostgresRule: user mismatch %s, %s", rule.userExact, req.user)
+		return false
+	}
+	if rule.databaseExact != "" && rule.databaseExact != req.database {
+		log.Debugf("PostgresRule: database mismatch %s, %s", rule.databaseExact, req.database)
+		return false
+	}
+	if req.startup {
+		return true
+	}
+	if rule.actionExact != "" && rule.actionExact != req.action {
+		log.Debugf("PostgresRule: action mismatch %s, %s", rule.actionExact, req.action)
+		return false
+	}
+	if rule.tableRegexCompiled != nil {
+		if len(req.tables) == 0 {
+			log.Debugf("PostgresRule: no tables to match '%v'", rule.tableRegexCompiled)
+			return false
+		}
+		for _, table := range req.tables {
+			if !rule.tableRegexCompiled.MatchString(table) {
+				log.Debugf("PostgresRule: table mismatch '%v', '%s'", rule.tableRegexCompiled, table)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// PostgresRuleParser parses protobuf L7 rules to enforcement objects
+// May panic
+func PostgresRuleParser(rule *cilium.PortNetworkPolicyRule) []L7NetworkPolicyRule {
+	var rules []L7NetworkPolicyRule
+	l7Rules := rule.GetL7Rules()
+	if l7Rules == nil {
+		return rules
+	}
+	for _, l7Rule := range l7Rules.GetL7Rules() {
+		var pr PostgresRule
+		for k, v := range l7Rule.Rule {
+			switch k {
+			case "user":
+				pr.userExact = v
+			case "database":
+				pr.databaseExact = v
+			case "action":
+				pr.actionExact = strings.ToLower(v)
+			case "table":
+				if v != "" {
+					pr.tableRegexCompiled = regexp.MustCompile(v)
+				}
+			default:
+				ParseError(fmt.Sprintf("Unsupported key: %s", k), rule)
+			}
+		}
+		if pr.actionExact != "" {
+			if _, ok := pgActions[pr.actionExact]; !ok {
+				ParseError(fmt.Sprintf("Unable to parse L7 postgres rule with invalid action: '%s'", pr.actionExact), rule)
+			}
+		}
+		log.Debugf("Parsed PostgresRule: %v", pr)
+		rules = append(rules, &pr)
+	}
+	return rules
+}
+
+// errorResponse returns an 'ErrorResponse' message
+func errorResponse(severity, code, message string) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('E')
+	buf.Write([]byte{0, 0, 0, 0}) // length, filled in below
+	for _, field := range []struct {
+		t     byte
+		value string
+	}{{'S', severity}, {'V', severity}, {'C', code}, {'M', message}} {
+		buf.WriteByte(field.t)
+		buf.WriteString(field.value)
+		buf.WriteByte(0)
+	}
+	buf.WriteByte(0)
+	msg := buf.Bytes()
+	binary.BigEndian.PutUint32(msg[1:5], uint32(len(msg)-1))
+	return msg
+}
+
+// readyForQuery returns a 'ReadyForQuery' message with the given
+// transaction status
+func readyForQuery(status byte) []byte {
+	return []byte{'Z', 0, 0, 0, 5, status}
+}
+
+var (
+	// DeniedMsg is sent if policy denies a statement. Exported for tests
+	DeniedMsg = errorResponse("ERROR", "42501", "access denied by policy")
+
+	// StartupDeniedMsg is sent if policy denies the startup message. Exported for tests
+	StartupDeniedMsg = errorResponse("FATAL", "28000", "access denied by policy")
+
+	// sslDeclinedMsg declines SSL and GSSAPI encryption requests
+	sslDeclinedMsg = []byte{'N'}
+)
+
+// cString returns the null terminated string at the beginning of 'data' and
+// the remaining data, or false if 'data' has no null terminator
+func cString(data []byte) (string, []byte, bool) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", nil, false
+	}
+	return string(data[:end]), data[end+1:], true
+}
+
+type PostgresParserFactory struct{}
+
+var postgresParserFactory *PostgresParserFactory
+
+func init() {
+	log.Info("init(): Registering postgresParserFactory")
+	RegisterParserFactory("postgres", postgresParserFactory)
+	RegisterL7RuleParser("postgres", PostgresRuleParser)
+}
+
+// replyIntent is queued for each request which is answered with a
+// 'ReadyForQuery' message
+type replyIntent struct {
+	// denied requests are not sent to the server, DeniedMsg is
+	// injected followed by a 'ReadyForQuery'
+	denied bool
+	// DeniedMsg is injected before the server's 'ReadyForQuery'
+	deniedBeforeReady bool
+}
+
+type PostgresParser struct {
+	connection *Connection
+
+	started  bool   // startup message has been allowed
+	rejected bool   // startup message has been denied
+	user     string // user and database from the startup message
+	database string
+
+	// true while messages are dropped up to the next 'Sync'
+	skipUntilSync bool
+
+	replyQueue []*replyIntent
+
+	// last transaction status reported by the server
+	txStatus byte
+}
+
+func (pf *PostgresParserFactory) Create(connection *Connection) Parser {
+	log.Debugf("PostgresParserFactory: Create: %v", connection)
+
+	return &PostgresParser{connection: connection, txStatus: 'I'}
+}
+
+func (p *PostgresParser) logEntry(action string, tables []string) *cilium.LogEntry_GenericL7 {
+	fields := map[string]string{
+		"user":     p.user,
+		"database": p.database,
+	}
+	if action != "" {
+		fields["action"] = action
+	}
+	if len(tables) > 0 {
+		fields["tables"] = strings.Join(tables, ", ")
+	}
+	return &cilium.LogEntry_GenericL7{
+		GenericL7: &cilium.L7LogEntry{
+			Proto:  "postgres",
+			Fields: fields,
+		},
+	}
+}
+
+func (p *PostgresParser) OnData(reply, endStream bool, dataArray [][]byte) (OpType, int) {
+	if reply {
+		injected := p.injectFromQueue()
+		if injected > 0 {
+			return INJECT, injected
+		}
+		if len(dataArray) == 0 {
+			return NOP, 0
+		}
+	}
+
+	// inefficient, but simple for now
+	data := bytes.Join(dataArray, []byte{})
+
+	if reply {
+		return p.onReply(data)
+	}
+	if p.rejected {
+		if len(data) == 0 {
+			return NOP, 0
+		}
+		return DROP, len(data)
+	}
+	if !p.started {
+		return p.onStartup(data)
+	}
+	return p.onRequest(data)
+}
+
+// onStartup handles the messages without a type byte sent by the client
+// before the startup message has been allowed
+func (p *PostgresParser) onStartup(data []byte) (OpType, int) {
+	if len(data) < 8 {
+		needs := 8 - len(data)
+		log.Debugf("Did not receive full startup header, need %d more bytes", needs)
+		return MORE, needs
+	}
+	length := int(binary.BigEndian.Uint32(data[0:4]))
+	if length < 8 || length > pgMaxStartupLen {
+		log.Errorf("Invalid startup message length %d", length)
+		return ERROR, int(ERROR_INVALID_FRAME_LENGTH)
+	}
+	code := binary.BigEndian.Uint32(data[4:8])
+	switch code {
+	case pgSSLRequestCode, pgGSSENCRequestCode:
+		log.Debugf("Declining encryption request %d", code)
+		p.connection.Inject(true, sslDeclinedMsg)
+		return DROP, length
+	case pgCancelRequestCode:
+		return PASS, length
+	}
+	if code>>16 != pgProtocolVersion3>>16 {
+		log.Errorf("Unsupported protocol version %d.%d", code>>16, code&0xffff)
+		return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+	}
+	if len(data) < length {
+		return MORE, length - len(data)
+	}
+
+	params := data[8:length]
+	for len(params) > 1 {
+		key, rest, ok := cString(params)
+		if !ok {
+			break
+		}
+		value, rest, ok := cString(rest)
+		if !ok {
+			log.Error("Invalid startup message parameter")
+			return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+		}
+		switch key {
+		case "user":
+			p.user = value
+		case "database":
+			p.database = value
+		}
+		params = rest
+	}
+	if p.database == "" {
+		p.database = p.user
+	}
+
+	req := postgresRequest{user: p.user, database: p.database, startup: true}
+	if !p.connection.Matches(req) {
+		p.rejected = true
+		p.connection.Inject(true, StartupDeniedMsg)
+		p.connection.Log(cilium.EntryType_Denied, p.logEntry("", nil))
+		return DROP, length
+	}
+	p.started = true
+	// the server answers with 'ReadyForQuery' once authenticated
+	p.replyQueue = append(p.replyQueue, &replyIntent{})
+	p.connection.Log(cilium.EntryType_Request, p.logEntry("", nil))
+	return PASS, length
+}
+
+// checkQuery matches each statement in 'query' against the policy, returning
+// true if all statements are allowed
+func (p *PostgresParser) checkQuery(query string) bool {
+	allowed := true
+	for _, statement := range sqlstmt.Classify(query, sqlstmt.PostgreSQL) {
+		req := postgresRequest{
+			user:     p.user,
+			database: p.database,
+			action:   statement.Action,
+			tables:   statement.Tables,
+		}
+		entryType := cilium.EntryType_Request
+		if !p.connection.Matches(req) {
+			allowed = false
+			entryType = cilium.EntryType_Denied
+		}
+		p.connection.Log(entryType, p.logEntry(statement.Action, statement.Tables))
+	}
+	return allowed
+}
+
+// onRequest handles the messages sent by the client after the startup
+// message has been allowed
+func (p *PostgresParser) onRequest(data []byte) (OpType, int) {
+	if len(data) < pgHdrLen {
+		needs := pgHdrLen - len(data)
+		log.Debugf("Did not receive full header, need %d more bytes", needs)
+		return MORE, needs
+	}
+	msgType := data[0]
+	length := int(binary.BigEndian.Uint32(data[1:5])) + 1
+	if length < pgHdrLen || length > pgMaxMessageLen {
+		log.Errorf("Invalid message length %d", length)
+		return ERROR, int(ERROR_INVALID_FRAME_LENGTH)
+	}
+
+	switch msgType {
+	case 'Q', 'P', 'F':
+	default:
+		if _, ok := pgFrontendMessages[msgType]; !ok {
+			log.Errorf("Unsupported message type '%c'", msgType)
+			return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+		}
+	}
+
+	switch msgType {
+	case 'Q', 'P':
+		if len(data) < length {
+			needs := length - len(data)
+			log.Debugf("Did not receive full message, need %d more bytes", needs)
+			return MORE, needs
+		}
+	case 'S':
+		intent := &replyIntent{}
+		if p.skipUntilSync {
+			p.skipUntilSync = false
+			intent.deniedBeforeReady = true
+		}
+		p.replyQueue = append(p.replyQueue, intent)
+		return PASS, length
+	case 'H':
+		// 'Flush' is passed so that the server sends the replies
+		// to the messages before the denied 'Parse'
+		return PASS, length
+	}
+
+	if p.skipUntilSync {
+		return DROP, length
+	}
+
+	switch msgType {
+	case 'Q':
+		query, _, ok := cString(data[pgHdrLen:length])
+		if !ok {
+			log.Error("Invalid query message")
+			return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+		}
+		if p.checkQuery(query) {
+			p.replyQueue = append(p.replyQueue, &replyIntent{})
+			return PASS, length
+		}
+		return p.denyRequest(length)
+	case 'F':
+		p.connection.Log(cilium.EntryType_Denied, p.logEntry("function-call", nil))
+		return p.denyRequest(length)
+	case 'P':
+		_, rest, ok := cString(data[pgHdrLen:length])
+		if !ok {
+			log.Error("Invalid parse message")
+			return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+		}
+		query, _, ok := cString(rest)
+		if !ok {
+			log.Error("Invalid parse message")
+			return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+		}
+		if p.checkQuery(query) {
+			return PASS, length
+		}
+		p.skipUntilSync = true
+		return DROP, length
+	}
+	return PASS, length
+}
+
+// denyRequest drops a 'Query' or 'FunctionCall' message of 'length' bytes,
+// which the server would have answered with an error followed by
+// 'ReadyForQuery'
+func (p *PostgresParser) denyRequest(length int) (OpType, int) {
+	if len(p.replyQueue) == 0 {
+		p.connection.Inject(true, DeniedMsg)
+		p.connection.Inject(true, readyForQuery(p.txStatus))
+	} else {
+		p.replyQueue = append(p.replyQueue, &replyIntent{denied: true})
+	}
+	return DROP, length
+}
+
+// onReply handles the messages sent by the server
+func (p *PostgresParser) onReply(data []byte) (OpType, int) {
+	if len(data) < pgHdrLen {
+		needs := pgHdrLen - len(data)
+		log.Debugf("Did not receive full header, need %d more bytes", needs)
+		return MORE, needs
+	}
+	msgType := data[0]
+	length := int(binary.BigEndian.Uint32(data[1:5])) + 1
+	if length < pgHdrLen || length > pgMaxMessageLen {
+		log.Errorf("Invalid reply length %d", length)
+		return ERROR, int(ERROR_INVALID_FRAME_LENGTH)
+	}
+	if msgType != 'Z' {
+		return PASS, length
+	}
+
+	if len(p.replyQueue) > 0 && p.replyQueue[0].deniedBeforeReady {
+		p.replyQueue[0].deniedBeforeReady = false
+		return INJECT, p.connection.Inject(true, DeniedMsg)
+	}
+	if length != pgHdrLen+1 {
+		log.Errorf("Invalid ReadyForQuery length %d", length)
+		return ERROR, int(ERROR_INVALID_FRAME_LENGTH)
+	}
+	if len(data) < length {
+		return MORE, length - len(data)
+	}
+	p.txStatus = data[pgHdrLen]
+	if len(p.replyQueue) > 0 {
+		p.replyQueue = p.replyQueue[1:]
+	}
+	return PASS, length
+}
+
+// injectFromQueue injects the replies for the denied requests at the head of
+// the reply queue, returning the number of bytes injected
+func (p *PostgresParser) injectFromQueue() int {
+	injected := 0
+	for len(p.replyQueue) > 0 && p.replyQueue[0].denied {
+		injected += p.connection.Inject(true, DeniedMsg)
+		injected += p.connection.Inject(true, readyForQuery(p.txStatus))
+		p.replyQueue = p.replyQueue[1:]
+	}
+	return injected
+}
@@ -0,0 +1,337 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package postgres
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/cilium/cilium/proxylib/accesslog"
+	"github.com/cilium/cilium/proxylib/proxylib"
+	"github.com/cilium/cilium/proxylib/test"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type PostgresSuite struct {
+	logServer *test.AccessLogServer
+	ins       *proxylib.Instance
+}
+
+var _ = Suite(&PostgresSuite{})
+
+// Set up access log server and Library instance for all the test cases
+func (s *PostgresSuite) SetUpSuite(c *C) {
+	s.logServer = test.StartAccessLogServer("access_log.sock", 10)
+	c.Assert(s.logServer, Not(IsNil))
+	s.ins = proxylib.NewInstance("node1", accesslog.NewClient(s.logServer.Path))
+	c.Assert(s.ins, Not(IsNil))
+}
+
+func (s *PostgresSuite) checkAccessLogs(c *C, expPasses, expDrops int) {
+	passes, drops := s.logServer.Clear()
+	c.Check(passes, Equals, expPasses, Commentf("Unxpected number of passed access log messages"))
+	c.Check(drops, Equals, expDrops, Commentf("Unxpected number of denied access log messages"))
+}
+
+func (s *PostgresSuite) TearDownTest(c *C) {
+	s.logServer.Clear()
+}
+
+func (s *PostgresSuite) TearDownSuite(c *C) {
+	s.logServer.Close()
+}
+
+const postgresPolicy = `
+		name: "pp1"
+		policy: 2
+		ingress_per_port_policies: <
+		  port: 80
+		  rules: <
+		    remote_policies: 1
+		    l7_proto: "postgres"
+		    l7_rules: <
+		      l7_rules: <
+			rule: <
+			  key: "user"
+			  value: "app"
+			>
+			rule: <
+			  key: "action"
+			  value: "select"
+			>
+			rule: <
+			  key: "table"
+			  value: "^(public\\.)?orders$"
+			>
+		      >
+		      l7_rules: <
+			rule: <
+			  key: "user"
+			  value: "app"
+			>
+			rule: <
+			  key: "action"
+			  value: "BEGIN"
+			>
+		      >
+		    >
+		  >
+		>
+		`
+
+// message returns a frontend or backend message of type 't'
+func message(t byte, payload ...string) []byte {
+	msg := []byte{t, 0, 0, 0, 0}
+	for _, p := range payload {
+		msg = append(msg, p...)
+	}
+	binary.BigEndian.PutUint32(msg[1:5], uint32(len(msg)-1))
+	return msg
+}
+
+// startupMessage returns a protocol v3 startup message with the given
+// parameter names and values
+func startupMessage(params ...string) []byte {
+	msg := []byte{0, 0, 0, 0, 0, 3, 0, 0}
+	for _, p := range params {
+		msg = append(msg, p...)
+		msg = append(msg, 0)
+	}
+	msg = append(msg, 0)
+	binary.BigEndian.PutUint32(msg[0:4], uint32(len(msg)))
+	return msg
+}
+
+func query(q string) []byte {
+	return message('Q', q, "\x00")
+}
+
+func parse(q string) []byte {
+	return message('P', "\x00", q, "\x00", "\x00\x00")
+}
+
+func concat(msgs ...[]byte) []byte {
+	var data []byte
+	for _, msg := range msgs {
+		data = append(data, msg...)
+	}
+	return data
+}
+
+// startConnection returns a new connection for which the startup message
+// and authentication has completed
+func (s *PostgresSuite) startConnection(c *C, user string) *proxylib.Connection {
+	s.ins.CheckInsertPolicyText(c, "1", []string{postgresPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "postgres", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "pp1")
+
+	startup := startupMessage("user", user, "database", "shop")
+	data := [][]byte{startup}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(startup),
+		proxylib.MORE, pgHdrLen)
+
+	authOk := message('R', "\x00\x00\x00\x00")
+	ready := message('Z', "I")
+	data = [][]byte{concat(authOk, ready)}
+	conn.CheckOnDataOK(c, true, false, &data, []byte{},
+		proxylib.PASS, len(authOk),
+		proxylib.PASS, len(ready))
+	s.checkAccessLogs(c, 1, 0)
+	return conn
+}
+
+func (s *PostgresSuite) TestPostgresOnDataPartialStartup(c *C) {
+	s.ins.CheckInsertPolicyText(c, "1", []string{postgresPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "postgres", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "pp1")
+
+	data := [][]byte{{0, 0, 0}}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.MORE, 5)
+
+	startup := startupMessage("user", "app")
+	data = [][]byte{startup[:10]}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.MORE, len(startup)-10)
+	s.checkAccessLogs(c, 0, 0)
+}
+
+func (s *PostgresSuite) TestPostgresOnDataSSLRequest(c *C) {
+	s.ins.CheckInsertPolicyText(c, "1", []string{postgresPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "postgres", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "pp1")
+
+	sslRequest := []byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}
+	data := [][]byte{sslRequest}
+	conn.CheckOnDataOK(c, false, false, &data, []byte("N"),
+		proxylib.DROP, len(sslRequest),
+		proxylib.MORE, 8)
+}
+
+func (s *PostgresSuite) TestPostgresOnDataStartupDenied(c *C) {
+	s.ins.CheckInsertPolicyText(c, "1", []string{postgresPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "postgres", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "pp1")
+
+	startup := startupMessage("user", "admin", "database", "shop")
+	q := query("SELECT 1")
+	data := [][]byte{concat(startup, q)}
+	conn.CheckOnDataOK(c, false, false, &data, StartupDeniedMsg,
+		proxylib.DROP, len(startup),
+		proxylib.DROP, len(q))
+	s.checkAccessLogs(c, 0, 1)
+}
+
+func (s *PostgresSuite) TestPostgresOnDataSimpleQuery(c *C) {
+	conn := s.startConnection(c, "app")
+
+	selectOrders := query("SELECT id, total FROM orders o WHERE o.id = 'x;y'")
+	data := [][]byte{selectOrders}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(selectOrders),
+		proxylib.MORE, pgHdrLen)
+	s.checkAccessLogs(c, 1, 0)
+
+	rowDesc := message('T', "\x00\x00")
+	complete := message('C', "SELECT 0\x00")
+	ready := message('Z', "I")
+	data = [][]byte{concat(rowDesc, complete, ready)}
+	conn.CheckOnDataOK(c, true, false, &data, []byte{},
+		proxylib.PASS, len(rowDesc),
+		proxylib.PASS, len(complete),
+		proxylib.PASS, len(ready))
+
+	// DDL and other tables are denied
+	for _, q := range []string{
+		"DROP TABLE orders",
+		"SELECT * FROM orders JOIN customers ON orders.cid = customers.id",
+		"select 1; delete from orders",
+		"SELECT pg_read_file('/etc/passwd')",
+		"WITH x AS (DELETE FROM orders RETURNING *) SELECT * FROM x",
+	} {
+		msg := query(q)
+		data = [][]byte{msg}
+		conn.CheckOnDataOK(c, false, false, &data, concat(DeniedMsg, readyForQuery('I')),
+			proxylib.DROP, len(msg),
+			proxylib.MORE, pgHdrLen)
+	}
+	s.checkAccessLogs(c, 0, 6)
+}
+
+func (s *PostgresSuite) TestPostgresOnDataPipelinedQuery(c *C) {
+	conn := s.startConnection(c, "app")
+
+	begin := query("BEGIN")
+	update := query("UPDATE orders SET total = 0")
+	data := [][]byte{concat(begin, update)}
+	// reply to UPDATE is queued behind the reply to BEGIN
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(begin),
+		proxylib.DROP, len(update),
+		proxylib.MORE, pgHdrLen)
+	s.checkAccessLogs(c, 1, 1)
+
+	complete := message('C', "BEGIN\x00")
+	ready := message('Z', "T")
+	data = [][]byte{concat(complete, ready)}
+	conn.CheckOnDataOK(c, true, false, &data, concat(DeniedMsg, readyForQuery('T')),
+		proxylib.PASS, len(complete),
+		proxylib.PASS, len(ready),
+		proxylib.INJECT, len(DeniedMsg)+len(ready))
+}
+
+func (s *PostgresSuite) TestPostgresOnDataExtendedQuery(c *C) {
+	conn := s.startConnection(c, "app")
+
+	p := parse("DELETE FROM orders WHERE id = $1")
+	bind := message('B', "\x00\x00\x00\x00\x00\x00\x00\x00")
+	execute := message('E', "\x00\x00\x00\x00\x00")
+	sync := message('S')
+	data := [][]byte{concat(p, bind, execute, sync)}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.DROP, len(p),
+		proxylib.DROP, len(bind),
+		proxylib.DROP, len(execute),
+		proxylib.PASS, len(sync),
+		proxylib.MORE, pgHdrLen)
+	s.checkAccessLogs(c, 0, 1)
+
+	// error is injected before the server's reply to 'Sync'
+	ready := message('Z', "I")
+	data = [][]byte{ready}
+	conn.CheckOnDataOK(c, true, false, &data, DeniedMsg,
+		proxylib.INJECT, len(DeniedMsg),
+		proxylib.PASS, len(ready))
+
+	p = parse("SELECT * FROM public.orders")
+	data = [][]byte{concat(p, bind, execute, sync)}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(p),
+		proxylib.PASS, len(bind),
+		proxylib.PASS, len(execute),
+		proxylib.PASS, len(sync),
+		proxylib.MORE, pgHdrLen)
+	s.checkAccessLogs(c, 1, 0)
+}
+
+func (s *PostgresSuite) TestPostgresOnDataFunctionCallDenied(c *C) {
+	conn := s.startConnection(c, "app")
+
+	// FunctionCall of pg_read_file(text) by OID
+	call := message('F', "\x00\x00\x0b\x3c", "\x00\x00", "\x00\x01",
+		"\x00\x00\x00\x0b", "/etc/passwd", "\x00\x00")
+	data := [][]byte{call}
+	conn.CheckOnDataOK(c, false, false, &data, concat(DeniedMsg, readyForQuery('I')),
+		proxylib.DROP, len(call),
+		proxylib.MORE, pgHdrLen)
+	s.checkAccessLogs(c, 0, 1)
+}
+
+func (s *PostgresSuite) TestPostgresOnDataUnknownMessage(c *C) {
+	conn := s.startConnection(c, "app")
+
+	unknown := message('V', "\x00")
+	data := [][]byte{unknown}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE))
+	s.checkAccessLogs(c, 0, 0)
+}
+
+func (s *PostgresSuite) TestPostgresRuleParserInvalidAction(c *C) {
+	err := s.ins.InsertPolicyText("1", []string{`
+		name: "pp2"
+		policy: 2
+		ingress_per_port_policies: <
+		  port: 80
+		  rules: <
+		    l7_proto: "postgres"
+		    l7_rules: <
+		      l7_rules: <
+			rule: <
+			  key: "action"
+			  value: "destroy"
+			>
+		      >
+		    >
+		  >
+		>
+		`}, "update")
+	c.Assert(err, Not(IsNil))
+}
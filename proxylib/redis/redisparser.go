@@ -0,0 +1,495 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	. "github.com/cilium/cilium/proxylib/proxylib"
+
+	"github.com/cilium/proxy/go/cilium/api"
+	log "github.com/sirupsen/logrus"
+)
+
+//
+// Redis (RESP) Parser
+//
+// Spec: https://redis.io/topics/protocol
+//
+// Requests are either RESP arrays of bulk strings or inline commands. Rules
+// match on the command name (case-insensitive) and on the keys accessed by the
+// command. Examples:
+// command = 'GET', keyPrefix = 'public:'
+// command = 'HGETALL', keyRegex = '^user:[0-9]+$'
+// command = 'PING'
+//
+// A rule with a key match only allows commands with at least one key, all of
+// which must match. Keys are located in the command arguments based on the
+// key positions of the command (see commandKeys), commands without known key
+// positions never match rules with a key match.
+//
+// Replies are parsed only to keep track of which request each reply belongs
+// to, so that the error reply for a denied request can be injected in order
+// when requests are pipelined. Once a connection enters the pub/sub or monitor
+// mode, replies are no longer associated with requests and are passed as-is.
+
+// RedisRule matches against Redis requests
+type RedisRule struct {
+	commandExact     string
+	keyExact         string
+	keyPrefix        string
+	keyRegexCompiled *regexp.Regexp
+}
+
+// redisRequest is the request data passed to RedisRule.Matches()
+type redisRequest struct {
+	command string
+	keys    []string
+}
+
+const (
+	redisMaxBulkLen   = 512 * 1024 * 1024 // 512 MB, per spec
+	redisMaxArrayLen  = 1024 * 1024
+	redisMaxInlineLen = 64 * 1024
+)
+
+var crlf = []byte("\r\n")
+
+// DeniedMsg is sent as the reply of a request denied by policy. Exported for tests
+var DeniedMsg = []byte("-NOPERM access denied by policy\r\n")
+
+func (rule *RedisRule) hasKeyMatch() bool {
+	return rule.keyExact != "" || rule.keyPrefix != "" || rule.keyRegexCompiled != nil
+}
+
+func (rule *RedisRule) matchKey(key string) bool {
+	if rule.keyExact != "" && rule.keyExact != key {
+		return false
+	}
+	if rule.keyPrefix != "" && !strings.HasPrefix(key, rule.keyPrefix) {
+		return false
+	}
+	if rule.keyRegexCompiled != nil && !rule.keyRegexCompiled.MatchString(key) {
+		return false
+	}
+	return true
+}
+
+// Matches returns true if the RedisRule matches the request
+func (rule *RedisRule) Matches(data interface{}) bool {
+	req, ok := data.(redisRequest)
+	if !ok {
+		log.Warning("Matches() called with type other than redisRequest")
+		return false
+	}
+	log.Debugf("Policy Match test for command '%s', keys %v", req.command, req.keys)
+
+	if rule.commandExact != "" && rule.commandExact != req.command {
+		log.Debugf("RedisRule: command mismatch %s, %s", rule.commandExact, req.command)
+		return false
+	}
+	if !rule.hasKeyMatch() {
+		return true
+	}
+	if len(req.keys) == 0 {
+		log.Debugf("RedisRule: no keys in command %s", req.command)
+		return false
+	}
+	for _, key := range req.keys {
+		if !rule.matchKey(key) {
+			log.Debugf("RedisRule: key mismatch '%s'", key)
+			return false
+		}
+	}
+	return true
+}
+
+// RedisRuleParser parses protobuf L7 rules to enforcement objects
+// May panic
+func RedisRuleParser(rule *cilium.PortNetworkPolicyRule) []L7NetworkPolicyRule {
+	var rules []L7NetworkPolicyRule
+	l7Rules := rule.GetL7Rules()
+	if l7Rules == nil {
+		return rules
+	}
+	for _, l7Rule := range l7Rules.GetL7Rules() {
+		var rr RedisRule
+		for k, v := range l7Rule.Rule {
+			switch k {
+			case "command":
+				rr.commandExact = strings.ToUpper(v)
+			case "keyExact":
+				rr.keyExact = v
+			case "keyPrefix":
+				rr.keyPrefix = v
+			case "keyRegex":
+				if v != "" {
+					rr.keyRegexCompiled = regexp.MustCompile(v)
+				}
+			default:
+				ParseError(fmt.Sprintf("Unsupported key: %s", k), rule)
+			}
+		}
+		if strings.ContainsAny(rr.commandExact, " \t\r\n") {
+			ParseError(fmt.Sprintf("Invalid redis command: '%s'", rr.commandExact), rule)
+		}
+		log.Debugf("Parsed RedisRule: %v", rr)
+		rules = append(rules, &rr)
+	}
+	return rules
+}
+
+// keySpec describes the positions of the keys in the arguments of a command,
+// where the command name itself is at position 0.
+type keySpec struct {
+	// first and last are the positions of the first and last key, negative
+	// values of last count back from the end of the arguments. step is the
+	// distance between two keys.
+	first, last, step int
+	// numKeys is the position of the argument holding the number of keys
+	// which directly follow it, 0 if there is no such argument.
+	numKeys int
+}
+
+// keys returns the keys in 'args' according to the key spec
+func (s keySpec) keys(args [][]byte) []string {
+	var keys []string
+	if s.first > 0 {
+		last := s.last
+		if last < 0 {
+			last += len(args)
+		}
+		for i := s.first; i <= last && i < len(args); i += s.step {
+			keys = append(keys, string(args[i]))
+		}
+	}
+	if s.numKeys > 0 && s.numKeys < len(args) {
+		n, err := strconv.Atoi(string(args[s.numKeys]))
+		if err == nil {
+			for i := s.numKeys + 1; i <= s.numKeys+n && i < len(args); i++ {
+				keys = append(keys, string(args[i]))
+			}
+		}
+	}
+	return keys
+}
+
+// commandKeys maps command names to the positions of their keys
+var commandKeys = map[string]keySpec{}
+
+func init() {
+	specs := map[keySpec][]string{
+		{first: 1, last: 1, step: 1}: {
+			"APPEND", "BITCOUNT", "BITFIELD", "BITPOS", "DECR", "DECRBY", "DUMP",
+			"EXPIRE", "EXPIREAT", "GEOADD", "GEODIST", "GEOHASH", "GEOPOS",
+			"GEORADIUS", "GEORADIUSBYMEMBER", "GET", "GETBIT", "GETRANGE",
+			"GETSET", "HDEL", "HEXISTS", "HGET", "HGETALL", "HINCRBY",
+			"HINCRBYFLOAT", "HKEYS", "HLEN", "HMGET", "HMSET", "HSCAN", "HSET",
+			"HSETNX", "HSTRLEN", "HVALS", "INCR", "INCRBY", "INCRBYFLOAT",
+			"LINDEX", "LINSERT", "LLEN", "LPOP", "LPUSH", "LPUSHX", "LRANGE",
+			"LREM", "LSET", "LTRIM", "PERSIST", "PEXPIRE", "PEXPIREAT", "PFADD",
+			"PSETEX", "PTTL", "RESTORE", "RPOP", "RPUSH", "RPUSHX", "SADD",
+			"SCARD", "SET", "SETBIT", "SETEX", "SETNX", "SETRANGE", "SISMEMBER",
+			"SMEMBERS", "SORT", "SPOP", "SRANDMEMBER", "SREM", "SSCAN", "STRLEN",
+			"TTL", "TYPE", "XACK", "XADD", "XCLAIM", "XDEL", "XLEN", "XPENDING",
+			"XRANGE", "XREVRANGE", "XTRIM", "ZADD", "ZCARD", "ZCOUNT", "ZINCRBY",
+			"ZLEXCOUNT", "ZPOPMAX", "ZPOPMIN", "ZRANGE", "ZRANGEBYLEX",
+			"ZRANGEBYSCORE", "ZRANK", "ZREM", "ZREMRANGEBYLEX",
+			"ZREMRANGEBYRANK", "ZREMRANGEBYSCORE", "ZREVRANGE",
+			"ZREVRANGEBYLEX", "ZREVRANGEBYSCORE", "ZREVRANK", "ZSCAN", "ZSCORE",
+		},
+		{first: 1, last: -1, step: 1}: {
+			"DEL", "EXISTS", "MGET", "PFCOUNT", "PFMERGE", "SDIFF", "SDIFFSTORE",
+			"SINTER", "SINTERSTORE", "SUNION", "SUNIONSTORE", "TOUCH", "UNLINK",
+			"WATCH",
+		},
+		{first: 1, last: -1, step: 2}: {
+			"MSET", "MSETNX",
+		},
+		{first: 1, last: 2, step: 1}: {
+			"BRPOPLPUSH", "RENAME", "RENAMENX", "RPOPLPUSH", "SMOVE",
+		},
+		{first: 1, last: -2, step: 1}: {
+			"BLPOP", "BRPOP", "BZPOPMAX", "BZPOPMIN",
+		},
+		{first: 2, last: -1, step: 1}: {
+			"BITOP",
+		},
+		{first: 1, last: 1, step: 1, numKeys: 2}: {
+			"ZINTERSTORE", "ZUNIONSTORE",
+		},
+		{numKeys: 2}: {
+			"EVAL", "EVALSHA",
+		},
+	}
+	for spec, commands := range specs {
+		for _, command := range commands {
+			commandKeys[command] = spec
+		}
+	}
+}
+
+// requestKeys returns the keys accessed by the command in 'args'
+func requestKeys(command string, args [][]byte) []string {
+	spec, ok := commandKeys[command]
+	if !ok {
+		return nil
+	}
+	return spec.keys(args)
+}
+
+// parseLength parses the length in a RESP array or bulk string header
+func parseLength(line []byte, max int) (int, error) {
+	n, err := strconv.Atoi(string(line))
+	if err != nil {
+		return 0, fmt.Errorf("invalid length '%s'", string(line))
+	}
+	if n < -1 || n > max {
+		return 0, fmt.Errorf("length %d out of range", n)
+	}
+	return n, nil
+}
+
+// parseRequest parses the request at the beginning of 'data'. Returns the
+// command arguments and the length of the request in bytes, or the number of
+// additional bytes needed if 'data' does not hold a complete request.
+func parseRequest(data []byte) (args [][]byte, length int, more int, err error) {
+	if data[0] != '*' {
+		// Inline command, terminated by a newline
+		lineEnd := bytes.IndexByte(data, '\n')
+		if lineEnd < 0 {
+			if len(data) > redisMaxInlineLen {
+				return nil, 0, 0, fmt.Errorf("inline request too long")
+			}
+			return nil, 0, 1, nil
+		}
+		return bytes.Fields(data[:lineEnd]), lineEnd + 1, 0, nil
+	}
+
+	lineEnd := bytes.Index(data, crlf)
+	if lineEnd < 0 {
+		return nil, 0, 1, nil
+	}
+	n, err := parseLength(data[1:lineEnd], redisMaxArrayLen)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	length = lineEnd + 2
+	for i := 0; i < n; i++ {
+		if length >= len(data) {
+			return nil, 0, 1, nil
+		}
+		if data[length] != '$' {
+			return nil, 0, 0, fmt.Errorf("invalid request argument type '%c'", data[length])
+		}
+		lineEnd = bytes.Index(data[length:], crlf)
+		if lineEnd < 0 {
+			return nil, 0, 1, nil
+		}
+		argLen, err := parseLength(data[length+1:length+lineEnd], redisMaxBulkLen)
+		if err != nil || argLen < 0 {
+			return nil, 0, 0, fmt.Errorf("invalid request argument length")
+		}
+		start := length + lineEnd + 2
+		end := start + argLen + 2
+		if end > len(data) {
+			return nil, 0, end - len(data), nil
+		}
+		if !bytes.Equal(data[end-2:end], crlf) {
+			return nil, 0, 0, fmt.Errorf("request argument not terminated by CRLF")
+		}
+		args = append(args, data[start:end-2])
+		length = end
+	}
+	return args, length, 0, nil
+}
+
+// parseReply returns the length of the reply at the beginning of 'data', or
+// the number of additional bytes needed if 'data' does not hold a complete
+// reply.
+func parseReply(data []byte) (length int, more int, err error) {
+	if len(data) == 0 {
+		return 0, 1, nil
+	}
+	lineEnd := bytes.Index(data, crlf)
+	if lineEnd < 0 {
+		return 0, 1, nil
+	}
+	length = lineEnd + 2
+	switch data[0] {
+	case '+', '-', ':':
+		return length, 0, nil
+	case '$':
+		n, err := parseLength(data[1:lineEnd], redisMaxBulkLen)
+		if err != nil {
+			return 0, 0, err
+		}
+		if n < 0 {
+			return length, 0, nil // nil bulk string
+		}
+		end := length + n + 2
+		if end > len(data) {
+			return 0, end - len(data), nil
+		}
+		if !bytes.Equal(data[end-2:end], crlf) {
+			return 0, 0, fmt.Errorf("bulk string not terminated by CRLF")
+		}
+		return end, 0, nil
+	case '*':
+		n, err := parseLength(data[1:lineEnd], redisMaxArrayLen)
+		if err != nil {
+			return 0, 0, err
+		}
+		for i := 0; i < n; i++ {
+			elemLen, more, err := parseReply(data[length:])
+			if err != nil || more > 0 {
+				return 0, more, err
+			}
+			length += elemLen
+		}
+		return length, 0, nil
+	}
+	return 0, 0, fmt.Errorf("invalid reply type '%c'", data[0])
+}
+
+type RedisParserFactory struct{}
+
+var redisParserFactory *RedisParserFactory
+
+func init() {
+	log.Info("init(): Registering redisParserFactory")
+	RegisterParserFactory("redis", redisParserFactory)
+	RegisterL7RuleParser("redis", RedisRuleParser)
+}
+
+type RedisParser struct {
+	connection *Connection
+
+	// replyQueue holds one entry for each request with a pending reply,
+	// true if the request was denied and the reply is to be injected.
+	replyQueue []bool
+
+	// set to true when a command is observed after which the server
+	// pushes replies not associated with any request
+	pushMode bool
+}
+
+func (pf *RedisParserFactory) Create(connection *Connection) Parser {
+	log.Debugf("RedisParserFactory: Create: %v", connection)
+
+	return &RedisParser{connection: connection}
+}
+
+func (p *RedisParser) OnData(reply, endStream bool, dataArray [][]byte) (OpType, int) {
+	if reply {
+		injected := p.injectFromQueue()
+		if injected > 0 {
+			return INJECT, injected
+		}
+		if len(dataArray) == 0 {
+			return NOP, 0
+		}
+	}
+
+	// inefficient, but simple for now
+	data := bytes.Join(dataArray, []byte{})
+	if len(data) == 0 {
+		return MORE, 1
+	}
+
+	if reply {
+		length, more, err := parseReply(data)
+		if err != nil {
+			log.Errorf("Invalid redis reply: %s", err)
+			return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+		}
+		if more > 0 {
+			log.Debugf("Did not receive full reply, need %d more bytes", more)
+			return MORE, more
+		}
+		if !p.pushMode && len(p.replyQueue) > 0 {
+			p.replyQueue = p.replyQueue[1:]
+		}
+		return PASS, length
+	}
+
+	args, length, more, err := parseRequest(data)
+	if err != nil {
+		log.Errorf("Invalid redis request: %s", err)
+		return ERROR, int(ERROR_INVALID_FRAME_TYPE)
+	}
+	if more > 0 {
+		log.Debugf("Did not receive full request, need %d more bytes", more)
+		return MORE, more
+	}
+	if len(args) == 0 {
+		// Redis ignores empty requests, and does not reply to them
+		return PASS, length
+	}
+
+	req := redisRequest{command: strings.ToUpper(string(args[0]))}
+	req.keys = requestKeys(req.command, args)
+
+	logEntry := &cilium.LogEntry_GenericL7{
+		GenericL7: &cilium.L7LogEntry{
+			Proto: "redis",
+			Fields: map[string]string{
+				"command": req.command,
+				"keys":    strings.Join(req.keys, ", "),
+			},
+		},
+	}
+
+	if p.connection.Matches(req) {
+		switch req.command {
+		case "SUBSCRIBE", "PSUBSCRIBE", "MONITOR":
+			p.pushMode = true
+		}
+		if !p.pushMode {
+			p.replyQueue = append(p.replyQueue, false)
+		}
+		p.connection.Log(cilium.EntryType_Request, logEntry)
+		return PASS, length
+	}
+
+	if p.pushMode || len(p.replyQueue) == 0 {
+		p.connection.Inject(true, DeniedMsg)
+	} else {
+		p.replyQueue = append(p.replyQueue, true)
+	}
+	p.connection.Log(cilium.EntryType_Denied, logEntry)
+	return DROP, length
+}
+
+// injectFromQueue injects the replies for the denied requests at the head of
+// the reply queue, returning the number of bytes injected
+func (p *RedisParser) injectFromQueue() int {
+	injected := 0
+	for _, denied := range p.replyQueue {
+		if !denied {
+			break
+		}
+		p.connection.Inject(true, DeniedMsg)
+		injected++
+	}
+	if injected > 0 {
+		p.replyQueue = p.replyQueue[injected:]
+	}
+	return injected * len(DeniedMsg)
+}
@@ -0,0 +1,261 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package redis
+
+import (
+	"testing"
+
+	"github.com/cilium/cilium/proxylib/accesslog"
+	"github.com/cilium/cilium/proxylib/proxylib"
+	"github.com/cilium/cilium/proxylib/test"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type RedisSuite struct {
+	logServer *test.AccessLogServer
+	ins       *proxylib.Instance
+}
+
+var _ = Suite(&RedisSuite{})
+
+// Set up access log server and Library instance for all the test cases
+func (s *RedisSuite) SetUpSuite(c *C) {
+	s.logServer = test.StartAccessLogServer("access_log.sock", 10)
+	c.Assert(s.logServer, Not(IsNil))
+	s.ins = proxylib.NewInstance("node1", accesslog.NewClient(s.logServer.Path))
+	c.Assert(s.ins, Not(IsNil))
+}
+
+func (s *RedisSuite) checkAccessLogs(c *C, expPasses, expDrops int) {
+	passes, drops := s.logServer.Clear()
+	c.Check(passes, Equals, expPasses, Commentf("Unxpected number of passed access log messages"))
+	c.Check(drops, Equals, expDrops, Commentf("Unxpected number of denied access log messages"))
+}
+
+func (s *RedisSuite) TearDownTest(c *C) {
+	s.logServer.Clear()
+}
+
+func (s *RedisSuite) TearDownSuite(c *C) {
+	s.logServer.Close()
+}
+
+const redisPolicy = `
+		name: "rp1"
+		policy: 2
+		ingress_per_port_policies: <
+		  port: 80
+		  rules: <
+		    remote_policies: 1
+		    l7_proto: "redis"
+		    l7_rules: <
+		      l7_rules: <
+			rule: <
+			  key: "command"
+			  value: "get"
+			>
+			rule: <
+			  key: "keyPrefix"
+			  value: "public:"
+			>
+		      >
+		      l7_rules: <
+			rule: <
+			  key: "command"
+			  value: "MGET"
+			>
+			rule: <
+			  key: "keyRegex"
+			  value: "^public:[0-9]+$"
+			>
+		      >
+		      l7_rules: <
+			rule: <
+			  key: "command"
+			  value: "PING"
+			>
+		      >
+		    >
+		  >
+		>
+		`
+
+func (s *RedisSuite) TestRedisOnDataPartialRequest(c *C) {
+	s.ins.CheckInsertPolicyText(c, "1", []string{redisPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "redis", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "rp1")
+
+	data := [][]byte{[]byte("*2\r\n$3\r\nGET\r\n$10\r\npublic")}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.MORE, 6)
+
+	data = [][]byte{[]byte("*2\r\n$3")}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.MORE, 1)
+	s.checkAccessLogs(c, 0, 0)
+}
+
+func (s *RedisSuite) TestRedisOnDataAllowDeny(c *C) {
+	s.ins.CheckInsertPolicyText(c, "1", []string{redisPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "redis", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "rp1")
+
+	getSecret := "*2\r\n$3\r\nGET\r\n$8\r\nsecret:1\r\n"
+	data := [][]byte{[]byte(getSecret)}
+	conn.CheckOnDataOK(c, false, false, &data, DeniedMsg,
+		proxylib.DROP, len(getSecret),
+		proxylib.MORE, 1)
+
+	flush := "*1\r\n$8\r\nFLUSHALL\r\n"
+	data = [][]byte{[]byte(flush)}
+	conn.CheckOnDataOK(c, false, false, &data, DeniedMsg,
+		proxylib.DROP, len(flush),
+		proxylib.MORE, 1)
+
+	// Command names are case-insensitive
+	get := "*2\r\n$3\r\nget\r\n$8\r\npublic:1\r\n"
+	data = [][]byte{[]byte(get)}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(get),
+		proxylib.MORE, 1)
+	s.checkAccessLogs(c, 1, 2)
+}
+
+func (s *RedisSuite) TestRedisOnDataMultipleKeys(c *C) {
+	s.ins.CheckInsertPolicyText(c, "1", []string{redisPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "redis", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "rp1")
+
+	mget := "*3\r\n$4\r\nMGET\r\n$8\r\npublic:1\r\n$8\r\npublic:2\r\n"
+	data := [][]byte{[]byte(mget)}
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(mget),
+		proxylib.MORE, 1)
+
+	mgetReply := "*2\r\n$1\r\na\r\n$1\r\nb\r\n"
+	data = [][]byte{[]byte(mgetReply)}
+	conn.CheckOnDataOK(c, true, false, &data, []byte{},
+		proxylib.PASS, len(mgetReply))
+
+	// All keys must match the rule
+	mgetMixed := "*3\r\n$4\r\nMGET\r\n$8\r\npublic:1\r\n$8\r\npublic:x\r\n"
+	data = [][]byte{[]byte(mgetMixed)}
+	conn.CheckOnDataOK(c, false, false, &data, DeniedMsg,
+		proxylib.DROP, len(mgetMixed),
+		proxylib.MORE, 1)
+	s.checkAccessLogs(c, 1, 1)
+}
+
+func (s *RedisSuite) TestRedisOnDataInline(c *C) {
+	s.ins.CheckInsertPolicyText(c, "1", []string{redisPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "redis", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "rp1")
+
+	ping := "ping\r\n"
+	keys := "KEYS *\r\n"
+	data := [][]byte{[]byte(ping + keys)}
+	// Reply to KEYS is queued behind the reply to PING
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(ping),
+		proxylib.DROP, len(keys),
+		proxylib.MORE, 1)
+	s.checkAccessLogs(c, 1, 1)
+}
+
+func (s *RedisSuite) TestRedisOnDataPipelined(c *C) {
+	s.ins.CheckInsertPolicyText(c, "1", []string{redisPolicy})
+	conn := s.ins.CheckNewConnectionOK(c, "redis", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "rp1")
+
+	get := "*2\r\n$3\r\nGET\r\n$8\r\npublic:1\r\n"
+	del := "*2\r\n$3\r\nDEL\r\n$8\r\npublic:1\r\n"
+	ping := "*1\r\n$4\r\nPING\r\n"
+	data := [][]byte{[]byte(get + del + ping)}
+	// DEL is denied while the reply to GET is pending, so its reply
+	// is injected only after the reply to GET has been passed.
+	conn.CheckOnDataOK(c, false, false, &data, []byte{},
+		proxylib.PASS, len(get),
+		proxylib.DROP, len(del),
+		proxylib.PASS, len(ping),
+		proxylib.MORE, 1)
+	s.checkAccessLogs(c, 2, 1)
+
+	getReply := "$5\r\nhello\r\n"
+	pingReply := "+PONG\r\n"
+	data = [][]byte{[]byte(getReply + pingReply)}
+	conn.CheckOnDataOK(c, true, false, &data, DeniedMsg,
+		proxylib.PASS, len(getReply),
+		proxylib.INJECT, len(DeniedMsg),
+		proxylib.PASS, len(pingReply))
+}
+
+func (s *RedisSuite) TestRedisOnDataReplies(c *C) {
+	conn := s.ins.CheckNewConnectionOK(c, "redis", true, 1, 2, "1.1.1.1:34567", "2.2.2.2:80", "no-policy")
+
+	array := "*3\r\n:1\r\n$-1\r\n*1\r\n-ERR nested\r\n"
+	data := [][]byte{[]byte(array)}
+	conn.CheckOnDataOK(c, true, false, &data, []byte{},
+		proxylib.PASS, len(array))
+
+	data = [][]byte{[]byte("*2\r\n$5\r\nhel")}
+	conn.CheckOnDataOK(c, true, false, &data, []byte{},
+		proxylib.MORE, 4)
+
+	data = [][]byte{[]byte("?what\r\n")}
+	conn.CheckOnData(c, true, false, &data, proxylib.OK, []byte{},
+		proxylib.ERROR, int(proxylib.ERROR_INVALID_FRAME_TYPE))
+}
+
+func (s *RedisSuite) TestRedisKeySpec(c *C) {
+	args := func(strs ...string) [][]byte {
+		res := make([][]byte, 0, len(strs))
+		for _, str := range strs {
+			res = append(res, []byte(str))
+		}
+		return res
+	}
+	c.Assert(requestKeys("GET", args("GET", "a")), DeepEquals, []string{"a"})
+	c.Assert(requestKeys("MSET", args("MSET", "a", "1", "b", "2")), DeepEquals, []string{"a", "b"})
+	c.Assert(requestKeys("BLPOP", args("BLPOP", "a", "b", "0")), DeepEquals, []string{"a", "b"})
+	c.Assert(requestKeys("ZUNIONSTORE", args("ZUNIONSTORE", "d", "2", "a", "b", "WEIGHTS", "1", "2")),
+		DeepEquals, []string{"d", "a", "b"})
+	c.Assert(requestKeys("EVAL", args("EVAL", "script", "1", "a", "arg")), DeepEquals, []string{"a"})
+	c.Assert(requestKeys("FLUSHALL", args("FLUSHALL")), IsNil)
+}
+
+func (s *RedisSuite) TestRedisRuleParserInvalidKey(c *C) {
+	err := s.ins.InsertPolicyText("1", []string{`
+		name: "rp2"
+		policy: 2
+		ingress_per_port_policies: <
+		  port: 80
+		  rules: <
+		    l7_proto: "redis"
+		    l7_rules: <
+		      l7_rules: <
+			rule: <
+			  key: "table"
+			  value: "foo"
+			>
+		      >
+		    >
+		  >
+		>
+		`}, "update")
+	c.Assert(err, Not(IsNil))
+}
@@ -0,0 +1,494 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlstmt classifies SQL statements for the database protocol parsers.
+//
+// Statements are not parsed, but tokenized just enough to find the statement
+// action (the leading keyword, e.g. 'select' or 'create') and the names
+// following the keywords which introduce a table, e.g. 'from', 'join',
+// 'into' and 'table'. Table names are returned as written in the statement,
+// optionally schema qualified, with unquoted identifiers folded to lower
+// case. Misclassifications, e.g. for 'extract(year from ts)', only ever add
+// names to the tables of a statement.
+package sqlstmt
+
+import (
+	"strings"
+)
+
+// Dialect selects the lexical rules of a SQL dialect
+type Dialect int
+
+const (
+	// PostgreSQL quotes identifiers with double quotes and supports dollar
+	// quoted strings and nested comments
+	PostgreSQL Dialect = iota
+	// MySQL quotes identifiers with backticks, supports backslash escapes
+	// in all strings and executes the contents of '/*! */' comments
+	MySQL
+)
+
+// Statement is a classified SQL statement
+type Statement struct {
+	// Action is the lower case leading keyword of the statement
+	Action string
+	// Tables are the names of the tables the statement refers to
+	Tables []string
+	// Schema is the name of the schema a 'use' statement switches to
+	Schema string
+}
+
+// Classify splits 'query' into statements separated by semicolons and
+// classifies each of them. Empty statements are omitted. A 'with' query
+// containing data-modifying statements, e.g.
+// 'with x as (delete from a returning *) select * from x', takes the action
+// of the first of them, and each one with a different action is returned as
+// an additional statement following the query.
+func Classify(query string, dialect Dialect) []Statement {
+	var statements []Statement
+	for _, tokens := range splitStatements(tokenize(query, dialect)) {
+		action, tables := classifyStatement(tokens)
+		statement := Statement{Action: action, Tables: tables}
+		if action == "use" && len(tokens) > 1 {
+			statement.Schema, _ = parseName(tokens, 1)
+		}
+		var nested []Statement
+		if tokens[0].isWord("with", "explain") {
+			for _, body := range modifyingSubStatements(tokens) {
+				action, tables := classifyStatement(body)
+				if statement.Action == "select" {
+					statement.Action = action
+				}
+				if action != statement.Action {
+					nested = append(nested, Statement{Action: action, Tables: tables})
+				}
+			}
+		}
+		statements = append(statements, statement)
+		statements = append(statements, nested...)
+	}
+	return statements
+}
+
+type tokenKind int
+
+const (
+	tokenWord    tokenKind = iota // unquoted keyword or identifier, lower case
+	tokenIdent                    // quoted identifier
+	tokenLiteral                  // string or numeric literal, parameter
+	tokenPunct                    // any other single character
+)
+
+type sqlToken struct {
+	kind tokenKind
+	text string
+}
+
+func (t sqlToken) isWord(words ...string) bool {
+	if t.kind != tokenWord {
+		return false
+	}
+	for _, w := range words {
+		if t.text == w {
+			return true
+		}
+	}
+	return false
+}
+
+func (t sqlToken) isPunct(c byte) bool {
+	return t.kind == tokenPunct && t.text[0] == c
+}
+
+func (t sqlToken) isName() bool {
+	return t.kind == tokenWord || t.kind == tokenIdent
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWordChar(c byte) bool {
+	return isWordStart(c) || c == '$' || (c >= '0' && c <= '9')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// skipQuoted returns the position after the closing 'quote' for a quoted
+// string starting at 'i', or the end of 'query' if the quote is not closed.
+// Doubled quotes are part of the string, as are quotes escaped with a
+// backslash if 'escapes' is true.
+func skipQuoted(query string, i int, quote byte, escapes bool) int {
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// quotedIdent returns the identifier quoted with 'quote' starting at 'i' and
+// the position after it
+func quotedIdent(query string, i int, quote byte) (string, int) {
+	end := skipQuoted(query, i, quote, false)
+	ident := strings.TrimSuffix(query[i+1:end], string(quote))
+	return strings.Replace(ident, string([]byte{quote, quote}), string(quote), -1), end
+}
+
+// dollarTag returns the tag of the dollar quote starting at 'i', e.g. "$$"
+// or "$body$", or an empty string if there is none
+func dollarTag(query string, i int) string {
+	j := i + 1
+	if j < len(query) && isDigit(query[j]) {
+		return "" // positional parameter
+	}
+	for j < len(query) && query[j] != '$' {
+		if !isWordChar(query[j]) {
+			return ""
+		}
+		j++
+	}
+	if j >= len(query) {
+		return ""
+	}
+	return query[i : j+1]
+}
+
+// skipLine returns the position after the end of the line at 'i'
+func skipLine(query string, i int) int {
+	if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+		return i + end + 1
+	}
+	return len(query)
+}
+
+// skipComment returns the position after the block comment starting at 'i'
+func skipComment(query string, i int, nested bool) int {
+	depth := 0
+	for i < len(query) {
+		if strings.HasPrefix(query[i:], "/*") {
+			if depth == 0 || nested {
+				depth++
+			}
+			i += 2
+		} else if strings.HasPrefix(query[i:], "*/") {
+			depth--
+			i += 2
+			if depth == 0 {
+				break
+			}
+		} else {
+			i++
+		}
+	}
+	return i
+}
+
+// tokenize splits 'query' into tokens, skipping whitespace and comments
+func tokenize(query string, dialect Dialect) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			// MySQL requires a space or control character after '--'
+			if dialect == MySQL && i+2 < len(query) && query[i+2] > ' ' {
+				tokens = append(tokens, sqlToken{kind: tokenPunct, text: "-"})
+				i++
+				break
+			}
+			i = skipLine(query, i)
+		case c == '#' && dialect == MySQL:
+			i = skipLine(query, i)
+		case c == '/' && strings.HasPrefix(query[i:], "/*!") && dialect == MySQL:
+			// executable comment, the contents are part of the
+			// statement if the server version is at least the
+			// optional version number
+			i += 3
+			for i < len(query) && isDigit(query[i]) {
+				i++
+			}
+		case c == '*' && strings.HasPrefix(query[i:], "*/") && dialect == MySQL:
+			// end of an executable comment
+			i += 2
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipComment(query, i, dialect == PostgreSQL)
+		case c == '\'' || (c == '"' && dialect == MySQL):
+			i = skipQuoted(query, i, c, dialect == MySQL)
+			tokens = append(tokens, sqlToken{kind: tokenLiteral, text: "'"})
+		case c == '"' || (c == '`' && dialect == MySQL):
+			var ident string
+			ident, i = quotedIdent(query, i, c)
+			tokens = append(tokens, sqlToken{kind: tokenIdent, text: ident})
+		case c == '$' && dialect == PostgreSQL:
+			tag := dollarTag(query, i)
+			if tag == "" {
+				j := i + 1
+				for j < len(query) && isDigit(query[j]) {
+					j++
+				}
+				tokens = append(tokens, sqlToken{kind: tokenLiteral, text: query[i:j]})
+				i = j
+				break
+			}
+			if end := strings.Index(query[i+len(tag):], tag); end >= 0 {
+				i += len(tag) + end + len(tag)
+			} else {
+				i = len(query)
+			}
+			tokens = append(tokens, sqlToken{kind: tokenLiteral, text: "$"})
+		case isWordStart(c):
+			j := i + 1
+			for j < len(query) && isWordChar(query[j]) {
+				j++
+			}
+			word := strings.ToLower(query[i:j])
+			if j < len(query) && query[j] == '\'' && (word == "e" || word == "x" || word == "b" || word == "n") {
+				// prefixed string constant, only PostgreSQL's E''
+				// and all MySQL strings support escapes
+				i = skipQuoted(query, j, '\'', word == "e" || dialect == MySQL)
+				tokens = append(tokens, sqlToken{kind: tokenLiteral, text: "'"})
+				break
+			}
+			tokens = append(tokens, sqlToken{kind: tokenWord, text: word})
+			i = j
+		case isDigit(c):
+			j := i + 1
+			for j < len(query) && (isWordChar(query[j]) || query[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: tokenLiteral, text: query[i:j]})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{kind: tokenPunct, text: query[i : i+1]})
+			i++
+		}
+	}
+	return tokens
+}
+
+// splitStatements splits tokens into statements separated by semicolons,
+// omitting empty statements
+func splitStatements(tokens []sqlToken) [][]sqlToken {
+	var statements [][]sqlToken
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i == len(tokens) || tokens[i].isPunct(';') {
+			if i > start {
+				statements = append(statements, tokens[start:i])
+			}
+			start = i + 1
+		}
+	}
+	return statements
+}
+
+// clauseKeywords terminate a table reference, i.e., they can't be an alias
+// or a table name
+var clauseKeywords = map[string]struct{}{
+	"cross": {}, "except": {}, "fetch": {}, "for": {}, "from": {}, "full": {},
+	"group": {}, "having": {}, "inner": {}, "intersect": {}, "join": {},
+	"left": {}, "limit": {}, "natural": {}, "offset": {}, "on": {},
+	"order": {}, "returning": {}, "right": {}, "set": {}, "straight_join": {},
+	"tablesample": {}, "union": {}, "using": {}, "values": {}, "where": {},
+	"window": {},
+}
+
+func (t sqlToken) isClauseKeyword() bool {
+	if t.kind != tokenWord {
+		return false
+	}
+	_, ok := clauseKeywords[t.text]
+	return ok
+}
+
+// parseName returns the possibly qualified name starting at tokens[i] and
+// the position after it, or an empty name if tokens[i] is not a name
+func parseName(tokens []sqlToken, i int) (string, int) {
+	if i >= len(tokens) || !tokens[i].isName() || tokens[i].isClauseKeyword() {
+		return "", i
+	}
+	name := tokens[i].text
+	i++
+	for i+1 < len(tokens) && tokens[i].isPunct('.') && tokens[i+1].isName() {
+		name += "." + tokens[i+1].text
+		i += 2
+	}
+	return name, i
+}
+
+// tableKeywords are followed by table names. Keywords which are followed by
+// lists of table names map to true.
+var tableKeywords = map[string]bool{
+	"from":     true,
+	"join":     false,
+	"into":     false,
+	"update":   true,
+	"delete":   true,
+	"table":    true,
+	"tables":   true,
+	"truncate": true,
+	"copy":     false,
+	"lock":     true,
+	"view":     true,
+	"describe": false,
+	"desc":     false,
+}
+
+// leadingTableKeywords are followed by table names only at the start of
+// a statement
+var leadingTableKeywords = map[string]struct{}{
+	"copy": {}, "delete": {}, "desc": {}, "describe": {}, "lock": {},
+	"truncate": {},
+}
+
+// dmlActions are the actions of statements wrapped by 'with' or 'explain'
+var dmlActions = []string{"select", "insert", "update", "delete", "replace"}
+
+// modifyingActions are the actions of data-modifying statements which can
+// be nested in parentheses in a 'with' query
+var modifyingActions = []string{"insert", "update", "delete", "replace"}
+
+// modifyingSubStatements returns the tokens of the data-modifying statements
+// nested in parentheses, e.g. the bodies of common table expressions.
+// 'insert(' and 'replace(' are function calls.
+func modifyingSubStatements(tokens []sqlToken) [][]sqlToken {
+	var bodies [][]sqlToken
+	for i := 1; i+1 < len(tokens); i++ {
+		if !tokens[i-1].isPunct('(') || !tokens[i].isWord(modifyingActions...) || tokens[i+1].isPunct('(') {
+			continue
+		}
+		end, depth := i, 1
+		for ; end < len(tokens); end++ {
+			if tokens[end].isPunct('(') {
+				depth++
+			} else if tokens[end].isPunct(')') {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		bodies = append(bodies, tokens[i:end])
+	}
+	return bodies
+}
+
+// classifyStatement returns the action of a statement and the names of the
+// tables it refers to
+func classifyStatement(tokens []sqlToken) (action string, tables []string) {
+	if len(tokens) == 0 {
+		return "", nil
+	}
+	if tokens[0].kind == tokenWord {
+		action = tokens[0].text
+	}
+	switch action {
+	case "with", "explain":
+		// classify by the wrapped statement
+		depth := 0
+		for _, t := range tokens[1:] {
+			if t.isPunct('(') {
+				depth++
+			} else if t.isPunct(')') {
+				depth--
+			} else if depth == 0 && t.isWord(dmlActions...) {
+				action = t.text
+				break
+			}
+		}
+		if action == "with" {
+			action = "select"
+		}
+	case "table", "values":
+		action = "select"
+	case "desc":
+		action = "describe"
+	}
+
+	seen := map[string]struct{}{}
+	addTable := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			tables = append(tables, name)
+		}
+	}
+
+	for i, t := range tokens {
+		if t.kind != tokenWord {
+			continue
+		}
+		isList, ok := tableKeywords[t.text]
+		if !ok {
+			continue
+		}
+		if _, leading := leadingTableKeywords[t.text]; leading && i > 0 {
+			continue
+		}
+		// 'for [no key] update [of ...]' is a row locking clause,
+		// 'on conflict ... do update' and 'on duplicate key update'
+		// update the insert target
+		if t.text == "update" && i > 0 && tokens[i-1].isWord("for", "key", "do") {
+			continue
+		}
+		j := i + 1
+		for {
+			for j < len(tokens) && tokens[j].isWord("only", "lateral", "if", "not", "exists", "table", "tables") {
+				j++
+			}
+			name, next := parseName(tokens, j)
+			if name == "" {
+				// not a table, e.g. a subquery
+				break
+			}
+			if t.isWord("from", "join") && next < len(tokens) && tokens[next].isPunct('(') {
+				// function call
+				break
+			}
+			addTable(name)
+			if !isList {
+				break
+			}
+			// skip an alias
+			if next < len(tokens) && tokens[next].isWord("as") {
+				next++
+			}
+			if next < len(tokens) && tokens[next].isName() && !tokens[next].isClauseKeyword() {
+				next++
+			}
+			if next >= len(tokens) || !tokens[next].isPunct(',') {
+				break
+			}
+			j = next + 1
+		}
+	}
+	return action, tables
+}
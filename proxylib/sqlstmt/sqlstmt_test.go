@@ -0,0 +1,111 @@
+// Copyright 2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !privileged_tests
+
+package sqlstmt
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+// Hook up gocheck into the "go test" runner.
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type SQLStmtSuite struct{}
+
+var _ = Suite(&SQLStmtSuite{})
+
+type classifyTestCase struct {
+	query  string
+	action string
+	tables []string
+}
+
+func checkClassify(c *C, dialect Dialect, testCases []classifyTestCase) {
+	for _, tc := range testCases {
+		statements := Classify(tc.query, dialect)
+		c.Assert(statements, HasLen, 1, Commentf("query: %s", tc.query))
+		c.Assert(statements[0].Action, Equals, tc.action, Commentf("query: %s", tc.query))
+		c.Assert(statements[0].Tables, DeepEquals, tc.tables, Commentf("query: %s", tc.query))
+	}
+}
+
+func (s *SQLStmtSuite) TestClassifyPostgreSQL(c *C) {
+	checkClassify(c, PostgreSQL, []classifyTestCase{
+		{"SELECT 1", "select", nil},
+		{"select * from a, b as x, c y where a.id = 1", "select", []string{"a", "b", "c"}},
+		{`SELECT * FROM "Sales"."Orders" o LEFT JOIN items i ON i.oid = o.id`, "select", []string{"Sales.Orders", "items"}},
+		{"SELECT * FROM generate_series(1, 10)", "select", nil},
+		{"SELECT * FROM t FOR UPDATE", "select", []string{"t"}},
+		{"SELECT 'from secret' /* from /* nested */ hidden */ -- from comment\n FROM t", "select", []string{"t"}},
+		{"SELECT $tag$ from secret $tag$, $1 FROM t", "select", []string{"t"}},
+		{"INSERT INTO orders (id) VALUES (1) ON CONFLICT (id) DO UPDATE SET id = 2", "insert", []string{"orders"}},
+		{"UPDATE ONLY orders SET total = 0", "update", []string{"orders"}},
+		{"DELETE FROM orders USING items", "delete", []string{"orders"}},
+		{"WITH x AS (SELECT * FROM a) DELETE FROM b", "delete", []string{"a", "b"}},
+		{"EXPLAIN ANALYZE UPDATE t SET x = 1", "update", []string{"t"}},
+		{"CREATE TABLE IF NOT EXISTS t (id int)", "create", []string{"t"}},
+		{"DROP TABLE a, b CASCADE", "drop", []string{"a", "b"}},
+		{"TRUNCATE TABLE a", "truncate", []string{"a"}},
+		{"TABLE orders", "select", []string{"orders"}},
+		{"BEGIN", "begin", nil},
+	})
+
+	c.Assert(Classify("select 1; ; select ';'", PostgreSQL), HasLen, 2)
+}
+
+func (s *SQLStmtSuite) TestClassifyWritableCTE(c *C) {
+	checkClassify(c, PostgreSQL, []classifyTestCase{
+		{"WITH x AS (UPDATE orders SET paid = true RETURNING *) SELECT 1", "update", []string{"orders"}},
+		{"WITH x AS (DELETE FROM orders RETURNING *) SELECT * FROM x", "delete", []string{"orders", "x"}},
+		{"WITH x AS (INSERT INTO orders VALUES (1) RETURNING id) SELECT * FROM x", "insert", []string{"orders", "x"}},
+		{"WITH x AS (SELECT replace(note, 'a', 'b') FROM orders) SELECT * FROM x", "select", []string{"orders", "x"}},
+		{"EXPLAIN ANALYZE WITH x AS (DELETE FROM orders) SELECT 1", "delete", []string{"orders"}},
+	})
+
+	c.Assert(Classify("WITH a AS (DELETE FROM x RETURNING id), b AS (SELECT 1 FROM y) UPDATE z SET id = 0", PostgreSQL), DeepEquals, []Statement{
+		{Action: "update", Tables: []string{"x", "y", "z"}},
+		{Action: "delete", Tables: []string{"x"}},
+	})
+}
+
+func (s *SQLStmtSuite) TestClassifyMySQL(c *C) {
+	checkClassify(c, MySQL, []classifyTestCase{
+		{"SELECT * FROM `shop`.`orders` WHERE note = \"from x\" # from comment", "select", []string{"shop.orders"}},
+		{"SELECT 'it\\'s from x' FROM t -- from comment", "select", []string{"t"}},
+		{"SELECT 1--1 FROM t", "select", []string{"t"}},
+		{"SELECT 1 /*!50000 FROM secret */", "select", []string{"secret"}},
+		{"INSERT INTO t (a) VALUES (1) ON DUPLICATE KEY UPDATE a = 2", "insert", []string{"t"}},
+		{"REPLACE INTO t VALUES (1)", "replace", []string{"t"}},
+		{"UPDATE a, b SET a.x = b.x", "update", []string{"a", "b"}},
+		{"DELETE a, b FROM a JOIN b ON a.id = b.id", "delete", []string{"a", "b"}},
+		{"LOCK TABLES a READ, b WRITE", "lock", []string{"a", "b"}},
+		{"DESC orders", "describe", []string{"orders"}},
+		{"SELECT * FROM t ORDER BY a DESC", "select", []string{"t"}},
+		{"USE shop", "use", nil},
+		{"WITH x AS (SELECT * FROM a) UPDATE b JOIN x ON b.id = x.id SET b.v = 1", "update", []string{"a", "b", "x"}},
+		{"WITH x AS (DELETE FROM orders) SELECT * FROM x", "delete", []string{"orders", "x"}},
+		{"WITH x AS (SELECT insert(note, 1, 2, 'ab') FROM orders) SELECT * FROM x", "select", []string{"orders", "x"}},
+	})
+
+	c.Assert(Classify("/*!40101 USE `Shop` */; DROP TABLE t", MySQL), DeepEquals, []Statement{
+		{Action: "use", Schema: "Shop"},
+		{Action: "drop", Tables: []string{"t"}},
+	})
+}
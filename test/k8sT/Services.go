@@ -225,6 +225,66 @@ var _ = Describe("K8sServicesTest", func() {
 		})
 	})
 
+	Context("with externalTrafficPolicy=Local", func() {
+		var (
+			echoYAML    = helpers.ManifestGet("echoserver-local.yaml")
+			nodePortDS  = "cilium-ds-patch-node-port.yaml"
+			echoService = "echoserver-local"
+			kubectlK8s2 *helpers.Kubectl
+		)
+
+		BeforeAll(func() {
+			kubectlK8s2 = helpers.CreateKubectl(helpers.K8s2VMName(), logger)
+
+			err := kubectl.CiliumInstall(nodePortDS, helpers.CiliumConfigMapPatch)
+			Expect(err).To(BeNil(), "Cilium cannot be installed with %s", nodePortDS)
+			ExpectCiliumReady(kubectl)
+
+			res := kubectl.Apply(echoYAML)
+			res.ExpectSuccess("Unable to apply %s", echoYAML)
+		})
+
+		AfterAll(func() {
+			// Explicitly ignore result of deletion of resources to avoid incomplete
+			// teardown if any step fails.
+			_ = kubectl.Delete(echoYAML)
+			ExpectAllPodsTerminated(kubectl)
+
+			err := kubectl.CiliumInstall(helpers.CiliumDefaultDSPatch, helpers.CiliumConfigMapPatch)
+			Expect(err).To(BeNil(), "Cilium cannot be installed")
+			ExpectCiliumReady(kubectl)
+		})
+
+		It("Preserves the client source IP of external NodePort traffic", func() {
+			err := kubectl.WaitforPods(helpers.DefaultNamespace, "-l zgroup=echoserverLocal", helpers.HelperTimeout)
+			Expect(err).Should(BeNil())
+
+			var data v1.Service
+			err = kubectl.Get(helpers.DefaultNamespace, "service "+echoService).Unmarshal(&data)
+			Expect(err).Should(BeNil(), "Can not retrieve service %s", echoService)
+
+			// The backend runs on k8s1, so requests from the k8s2 host to
+			// the NodePort of k8s1 are received on its native device and
+			// must reach the backend with the address of k8s2 as source.
+			url := fmt.Sprintf("http://%s",
+				net.JoinHostPort(helpers.K8s1Ip, fmt.Sprintf("%d", data.Spec.Ports[0].NodePort)))
+			By("Making ten HTTP requests from k8s2 to %q", url)
+			for i := 1; i <= 10; i++ {
+				res := kubectlK8s2.Exec(helpers.CurlFail(url))
+				res.ExpectSuccess("k8s2 host can not connect to service %q", url)
+				res.ExpectContains(fmt.Sprintf("client_address=%s", helpers.K8s2Ip),
+					"Client source IP is not preserved for service %q", url)
+			}
+
+			By("Checking the health check NodePort of k8s1")
+			url = fmt.Sprintf("http://%s",
+				net.JoinHostPort(helpers.K8s1Ip, fmt.Sprintf("%d", data.Spec.HealthCheckNodePort)))
+			res := kubectlK8s2.Exec(helpers.CurlFail(url))
+			res.ExpectSuccess("k8s2 host can not connect to health check NodePort %q", url)
+			res.ExpectContains(`"localEndpoints":1`, "Unexpected number of local endpoints")
+		})
+	})
+
 	//TODO: Check service with IPV6
 
 	Context("External services", func() {